@@ -164,8 +164,11 @@ func (hs *HTTPServer) registerRoutes() {
 	r.Post("/api/user/signup/step2", routing.Wrap(hs.SignUpStep2))
 
 	// invited
-	r.Get("/api/user/invite/:code", routing.Wrap(hs.GetInviteInfoByCode))
-	r.Post("/api/user/invite/complete", routing.Wrap(hs.CompleteInvite))
+	r.Get("/api/user/invite/:code", routing.Wrap(hs.withInviteTimeout(hs.GetInviteInfoByCode)))
+	r.Post("/api/user/invite/complete", routing.Wrap(hs.withInviteCorrelationID(hs.withInviteTimeout(hs.CompleteInvite))))
+
+	// invites from the Slack slash command, authenticated by the request signature
+	r.Post("/api/integrations/slack/invite", quota("user"), routing.Wrap(hs.SlackInviteCommand))
 
 	// reset password
 	r.Get("/user/password/send-reset-email", reqNotSignedIn, hs.Index)
@@ -198,6 +201,16 @@ func (hs *HTTPServer) registerRoutes() {
 			userRoute.Post("/using/:id", routing.Wrap(hs.UserSetUsingOrg))
 			userRoute.Get("/orgs", routing.Wrap(hs.GetSignedInUserOrgList))
 			userRoute.Get("/teams", routing.Wrap(hs.GetSignedInUserTeamList))
+			userRoute.Get("/org-invites", routing.Wrap(hs.withInviteTimeout(hs.GetSignedInUserOrgInvites)))
+			userRoute.Post("/org-invites/:inviteId/accept", routing.Wrap(hs.withInviteTimeout(hs.AcceptSignedInUserOrgInvite)))
+			userRoute.Post("/org-invites/:inviteId/decline", routing.Wrap(hs.withInviteTimeout(hs.DeclineSignedInUserOrgInvite)))
+			userRoute.Post("/org-invites/link", routing.Wrap(hs.withInviteTimeout(hs.LinkSignedInUserOrgInvite)))
+			userRoute.Get("/data-export", routing.Wrap(hs.GetSignedInUserDataExport))
+			userRoute.Get("/onboarding", routing.Wrap(hs.GetSignedInUserOnboarding))
+			userRoute.Get("/invite-notifications", routing.Wrap(hs.GetSignedInUserInviteNotifications))
+			userRoute.Post("/invite-notifications/read", routing.Wrap(hs.MarkSignedInUserInviteNotificationsRead))
+			userRoute.Get("/invite-notifications/preferences", routing.Wrap(hs.GetSignedInUserInviteNotificationPreferences))
+			userRoute.Put("/invite-notifications/preferences", routing.Wrap(hs.UpdateSignedInUserInviteNotificationPreferences))
 
 			userRoute.Get("/stars", routing.Wrap(hs.GetStars))
 			userRoute.Post("/stars/dashboard/:id", routing.Wrap(hs.StarDashboard))
@@ -275,9 +288,36 @@ func (hs *HTTPServer) registerRoutes() {
 			orgRoute.Delete("/users/:userId", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgUsersRemove, userIDScope)), routing.Wrap(hs.RemoveOrgUserForCurrentOrg))
 
 			// invites
-			orgRoute.Get("/invites", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgUsersAdd)), routing.Wrap(hs.GetPendingOrgInvites))
-			orgRoute.Post("/invites", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgUsersAdd)), quota("user"), routing.Wrap(hs.AddOrgInvite))
-			orgRoute.Patch("/invites/:code/revoke", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgUsersAdd)), routing.Wrap(hs.RevokeInvite))
+			orgRoute.Get("/invites", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgInvitesRead)), routing.Wrap(hs.withInviteTimeout(hs.GetPendingOrgInvites)))
+			orgRoute.Get("/invites/audit-report", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgInvitesRead)), routing.Wrap(hs.GetOrgInviteAuditReport))
+			orgRoute.Post("/invites/audit-report/verify", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgInvitesRead)), routing.Wrap(hs.VerifyOrgInviteAuditReport))
+			orgRoute.Get("/invites/health", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgUsersAdd)), routing.Wrap(hs.GetOrgInvitesHealth))
+			orgRoute.Post("/invites/validate", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgUsersAdd)), routing.Wrap(hs.withInviteTimeout(hs.ValidateOrgInvites)))
+			orgRoute.Post("/invites/test-email", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgUsersAdd)), routing.Wrap(hs.withInviteTimeout(hs.SendTestOrgInviteEmail)))
+			orgRoute.Get("/invites/onboarding-event", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgUsersAdd)), routing.Wrap(hs.GetOrgInviteOnboardingEvent))
+			orgRoute.Put("/invites/onboarding-event", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgsWrite)), routing.Wrap(hs.UpdateOrgInviteOnboardingEvent))
+			orgRoute.Get("/invites/join-rules", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgUsersAdd)), routing.Wrap(hs.GetOrgInviteJoinRules))
+			orgRoute.Put("/invites/join-rules", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgsWrite)), routing.Wrap(hs.UpdateOrgInviteJoinRules))
+			orgRoute.Post("/invites/join-rules/evaluate", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgUsersAdd)), routing.Wrap(hs.EvaluateOrgInviteJoinRules))
+			orgRoute.Get("/invites/domains", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgUsersAdd)), routing.Wrap(hs.GetOrgInviteDomains))
+			orgRoute.Post("/invites/domains", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgsWrite)), routing.Wrap(hs.AddOrgInviteDomain))
+			orgRoute.Put("/invites/domains/:domain", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgsWrite)), routing.Wrap(hs.UpdateOrgInviteDomain))
+			orgRoute.Post("/invites/domains/:domain/verify", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgsWrite)), routing.Wrap(hs.VerifyOrgInviteDomain))
+			orgRoute.Delete("/invites/domains/:domain", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgsWrite)), routing.Wrap(hs.DeleteOrgInviteDomain))
+			orgRoute.Post("/invites/bulk", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgUsersAdd)), quota("user"), routing.Wrap(hs.withInviteTimeout(hs.AddOrgInvitesBulk)))
+			orgRoute.Post("/invites", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgUsersAdd)), quota("user"), routing.Wrap(hs.withInviteCorrelationID(hs.withInviteTimeout(hs.AddOrgInvite))))
+			orgRoute.Get("/invites/:code/history", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgInvitesRead)), routing.Wrap(hs.GetOrgInviteHistory))
+			orgRoute.Patch("/invites/:code/revoke", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgUsersAdd)), routing.Wrap(hs.withInviteTimeout(hs.RevokeInvite)))
+
+			// SCIM provisioning
+			orgRoute.Group("/scim/v2/Users", func(scimRoute routing.RouteRegister) {
+				scimRoute.Get("/", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgUsersRead)), routing.Wrap(hs.GetSCIMUsers))
+				scimRoute.Post("/", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgUsersAdd)), quota("user"), routing.Wrap(hs.CreateSCIMUser))
+				scimRoute.Get("/:id", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgUsersRead)), routing.Wrap(hs.GetSCIMUser))
+				scimRoute.Put("/:id", authorize(reqOrgAdmin, ac.EvalAll(ac.EvalPermission(ac.ActionOrgUsersWrite), ac.EvalPermission(ac.ActionOrgUsersRemove))), routing.Wrap(hs.ReplaceSCIMUser))
+				scimRoute.Patch("/:id", authorize(reqOrgAdmin, ac.EvalAll(ac.EvalPermission(ac.ActionOrgUsersWrite), ac.EvalPermission(ac.ActionOrgUsersRemove))), routing.Wrap(hs.PatchSCIMUser))
+				scimRoute.Delete("/:id", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgUsersRemove)), routing.Wrap(hs.DeleteSCIMUser))
+			})
 
 			// prefs
 			orgRoute.Get("/preferences", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgsPreferencesRead)), routing.Wrap(hs.GetOrgPreferences))
@@ -300,6 +340,23 @@ func (hs *HTTPServer) registerRoutes() {
 				)
 			}
 			orgRoute.Get("/users/lookup", authorize(reqOrgAdminDashOrFolderAdminOrTeamAdmin, lookupEvaluator()), routing.Wrap(hs.GetOrgUsersForCurrentOrgLookup))
+			orgRoute.Post("/invites/share", authorize(reqOrgAdminDashOrFolderAdminOrTeamAdmin, ac.EvalAny(
+				ac.EvalPermission(dashboards.ActionFoldersPermissionsWrite),
+				ac.EvalPermission(dashboards.ActionDashboardsPermissionsWrite),
+			)), routing.Wrap(hs.withInviteTimeout(hs.ShareResourceByEmail)))
+			orgRoute.Post("/invites/share/viewer-token", authorize(reqOrgAdminDashOrFolderAdminOrTeamAdmin, ac.EvalAny(
+				ac.EvalPermission(dashboards.ActionFoldersPermissionsWrite),
+				ac.EvalPermission(dashboards.ActionDashboardsPermissionsWrite),
+			)), quota("user"), routing.Wrap(hs.withInviteTimeout(hs.ShareViewerToken)))
+		})
+
+		// current org invites addressed by ID
+		apiRoute.Group("/v2/org/invites", func(invitesRoute routing.RouteRegister) {
+			invitesRoute.Get("/", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgInvitesRead)), routing.Wrap(hs.withInviteTimeout(hs.SearchOrgInvitesV2)))
+			invitesRoute.Get("/:inviteId", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgInvitesRead)), routing.Wrap(hs.withInviteTimeout(hs.GetOrgInviteV2)))
+			invitesRoute.Get("/:inviteId/link", authorize(reqOrgAdmin, ac.EvalAll(ac.EvalPermission(ac.ActionOrgInvitesRead), ac.EvalPermission(ac.ActionOrgUsersAdd))), routing.Wrap(hs.withInviteTimeout(hs.GetOrgInviteLinkV2)))
+			invitesRoute.Get("/:inviteId/delivery", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgInvitesRead)), routing.Wrap(hs.withInviteTimeout(hs.GetOrgInviteDeliveryV2)))
+			invitesRoute.Patch("/:inviteId", authorize(reqOrgAdmin, ac.EvalPermission(ac.ActionOrgUsersAdd)), routing.Wrap(hs.withInviteTimeout(hs.UpdateOrgInviteV2)))
 		})
 
 		// create new org
@@ -584,6 +641,9 @@ func (hs *HTTPServer) registerRoutes() {
 		}
 		adminRoute.Get("/stats", authorize(reqGrafanaAdmin, ac.EvalPermission(ac.ActionServerStatsRead)), routing.Wrap(hs.AdminGetStats))
 		adminRoute.Post("/pause-all-alerts", reqGrafanaAdmin, routing.Wrap(hs.PauseAllAlerts(setting.AlertingEnabled)))
+		adminRoute.Get("/email-suppressions", reqGrafanaAdmin, routing.Wrap(hs.GetEmailSuppressions))
+		adminRoute.Post("/email-suppressions", reqGrafanaAdmin, routing.Wrap(hs.AddEmailSuppression))
+		adminRoute.Delete("/email-suppressions/:email", reqGrafanaAdmin, routing.Wrap(hs.RemoveEmailSuppression))
 
 		if hs.ThumbService != nil && hs.Features.IsEnabled(featuremgmt.FlagDashboardPreviewsAdmin) {
 			adminRoute.Post("/crawler/start", reqGrafanaAdmin, routing.Wrap(hs.ThumbService.StartCrawler))
@@ -634,6 +694,8 @@ func (hs *HTTPServer) registerRoutes() {
 		adminUserRoute.Post("/:id/logout", authorize(reqGrafanaAdmin, ac.EvalPermission(ac.ActionUsersLogout, userIDScope)), routing.Wrap(hs.AdminLogoutUser))
 		adminUserRoute.Get("/:id/auth-tokens", authorize(reqGrafanaAdmin, ac.EvalPermission(ac.ActionUsersAuthTokenList, userIDScope)), routing.Wrap(hs.AdminGetUserAuthTokens))
 		adminUserRoute.Post("/:id/revoke-auth-token", authorize(reqGrafanaAdmin, ac.EvalPermission(ac.ActionUsersAuthTokenUpdate, userIDScope)), routing.Wrap(hs.AdminRevokeUserAuthToken))
+		adminUserRoute.Get("/:id/data-export", authorize(reqGrafanaAdmin, ac.EvalPermission(ac.ActionUsersRead, userIDScope)), routing.Wrap(hs.AdminGetUserDataExport))
+		adminUserRoute.Post("/:id/erase-data", authorize(reqGrafanaAdmin, ac.EvalPermission(ac.ActionUsersWrite, userIDScope)), routing.Wrap(hs.AdminEraseUserData))
 	})
 
 	// rendering
@@ -17,6 +17,7 @@ import (
 	"github.com/grafana/grafana/pkg/api/response"
 	"github.com/grafana/grafana/pkg/api/routing"
 	"github.com/grafana/grafana/pkg/infra/fs"
+	"github.com/grafana/grafana/pkg/infra/kvstore"
 	"github.com/grafana/grafana/pkg/infra/localcache"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/infra/remotecache"
@@ -41,6 +42,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/licensing"
 	"github.com/grafana/grafana/pkg/services/login/loginservice"
 	"github.com/grafana/grafana/pkg/services/login/logintest"
+	"github.com/grafana/grafana/pkg/services/onboarding/onboardingtest"
 	"github.com/grafana/grafana/pkg/services/org"
 	"github.com/grafana/grafana/pkg/services/org/orgtest"
 	"github.com/grafana/grafana/pkg/services/preference/preftest"
@@ -55,6 +57,8 @@ import (
 	"github.com/grafana/grafana/pkg/services/team"
 	"github.com/grafana/grafana/pkg/services/team/teamimpl"
 	"github.com/grafana/grafana/pkg/services/team/teamtest"
+	"github.com/grafana/grafana/pkg/services/temp_user/invitetest"
+	"github.com/grafana/grafana/pkg/services/temp_user/tempusertest"
 	"github.com/grafana/grafana/pkg/services/user"
 	"github.com/grafana/grafana/pkg/services/user/usertest"
 	"github.com/grafana/grafana/pkg/setting"
@@ -361,6 +365,21 @@ func setupHTTPServerWithCfg(t *testing.T, useFakeAccessControl bool, cfg *settin
 	return setupHTTPServerWithCfgDb(t, useFakeAccessControl, cfg, db, db, featuremgmt.WithFeatures(), options...)
 }
 
+// setupInviteHTTPServer sets up an API test server whose invites are stored
+// in the test database and returns the invitetest.Stack backing them.
+func setupInviteHTTPServer(t *testing.T, options ...APITestServerOption) (accessControlScenarioContext, *invitetest.Stack) {
+	return setupInviteHTTPServerWithCfg(t, setting.NewCfg(), options...)
+}
+
+func setupInviteHTTPServerWithCfg(t *testing.T, cfg *setting.Cfg, options ...APITestServerOption) (accessControlScenarioContext, *invitetest.Stack) {
+	t.Helper()
+
+	sc := setupHTTPServerWithCfg(t, true, cfg, options...)
+	stack := invitetest.NewWithStore(sc.db)
+	sc.hs.tempUserService = stack.TempUsers
+	return sc, stack
+}
+
 func setupHTTPServerWithCfgDb(
 	t *testing.T, useFakeAccessControl bool, cfg *setting.Cfg, db *sqlstore.SQLStore,
 	store sqlstore.Store, features *featuremgmt.FeatureManager, options ...APITestServerOption,
@@ -410,6 +429,7 @@ func setupHTTPServerWithCfgDb(
 		AccessControl:          ac,
 		accesscontrolService:   acService,
 		teamPermissionsService: teamPermissionService,
+		roleRestrictions:       ossaccesscontrol.ProvideRoleRestrictionService(),
 		searchUsersService:     searchusers.ProvideUsersService(filters.ProvideOSSSearchUserFilter(), usertest.NewUserServiceFake()),
 		DashboardService: dashboardservice.ProvideDashboardService(
 			cfg, dashboardsStore, nil, features,
@@ -420,6 +440,12 @@ func setupHTTPServerWithCfgDb(
 		orgService:        orgtest.NewOrgServiceFake(),
 		teamService:       teamService,
 		annotationsRepo:   annotationstest.NewFakeAnnotationsRepo(),
+		onboardingService: onboardingtest.NewFakeService(),
+		tempUserService:   tempusertest.NewFakeTempUserService(),
+		kvStore:           kvstore.ProvideService(db),
+		pluginStore:       &fakePluginStore{},
+		PluginSettings:    &fakePluginSettings{},
+		log:               log.NewNopLogger(),
 	}
 
 	for _, o := range options {
@@ -491,6 +517,7 @@ func SetupAPITestServer(t *testing.T, opts ...APITestServerOption) *webtest.Serv
 		AccessControl:      accesscontrolmock.New().WithDisabled(),
 		Features:           featuremgmt.WithFeatures(),
 		searchUsersService: &searchusers.OSSService{},
+		onboardingService:  onboardingtest.NewFakeService(),
 	}
 
 	for _, opt := range opts {
@@ -26,6 +26,7 @@ import (
 	dashver "github.com/grafana/grafana/pkg/services/dashboardversion"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/services/guardian"
+	"github.com/grafana/grafana/pkg/services/onboarding"
 	"github.com/grafana/grafana/pkg/services/org"
 	pref "github.com/grafana/grafana/pkg/services/preference"
 	"github.com/grafana/grafana/pkg/services/star"
@@ -225,6 +226,8 @@ func (hs *HTTPServer) GetDashboard(c *models.ReqContext) response.Response {
 		Meta:      meta,
 	}
 
+	hs.recordOnboardingEvent(c.Req.Context(), c.OrgID, c.UserID, onboarding.EventDashboardViewed, 0)
+
 	c.TimeRequest(metrics.MApiDashboardGet)
 	return response.JSON(http.StatusOK, dto)
 }
@@ -32,6 +32,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/guardian"
 	"github.com/grafana/grafana/pkg/services/libraryelements"
 	"github.com/grafana/grafana/pkg/services/live"
+	"github.com/grafana/grafana/pkg/services/onboarding/onboardingtest"
 	"github.com/grafana/grafana/pkg/services/org"
 	pref "github.com/grafana/grafana/pkg/services/preference"
 	"github.com/grafana/grafana/pkg/services/preference/preftest"
@@ -63,6 +64,7 @@ func TestGetHomeDashboard(t *testing.T) {
 		preferenceService:       prefService,
 		dashboardVersionService: dashboardVersionService,
 		Coremodels:              registry.NewBase(),
+		onboardingService:       onboardingtest.NewFakeService(),
 	}
 
 	tests := []struct {
@@ -148,6 +150,7 @@ func TestDashboardAPIEndpoint(t *testing.T) {
 			DashboardService:        dashboardService,
 			dashboardVersionService: fakeDashboardVersionService,
 			Coremodels:              registry.NewBase(),
+			onboardingService:       onboardingtest.NewFakeService(),
 		}
 
 		setUp := func() {
@@ -270,6 +273,7 @@ func TestDashboardAPIEndpoint(t *testing.T) {
 			dashboardVersionService: fakeDashboardVersionService,
 			Features:                featuremgmt.WithFeatures(),
 			Coremodels:              registry.NewBase(),
+			onboardingService:       onboardingtest.NewFakeService(),
 		}
 
 		setUp := func() {
@@ -913,6 +917,7 @@ func TestDashboardAPIEndpoint(t *testing.T) {
 				DashboardService:             dashboardService,
 				Features:                     featuremgmt.WithFeatures(),
 				Coremodels:                   registry.NewBase(),
+				onboardingService:            onboardingtest.NewFakeService(),
 			}
 			hs.callGetDashboard(sc)
 
@@ -965,9 +970,10 @@ func getDashboardShouldReturn200WithConfig(t *testing.T, sc *scenarioContext, pr
 			cfg, dashboardStore, nil, features,
 			folderPermissions, dashboardPermissions, ac,
 		),
-		DashboardService: dashboardService,
-		Features:         featuremgmt.WithFeatures(),
-		Coremodels:       registry.NewBase(),
+		DashboardService:  dashboardService,
+		Features:          featuremgmt.WithFeatures(),
+		Coremodels:        registry.NewBase(),
+		onboardingService: onboardingtest.NewFakeService(),
 	}
 
 	hs.callGetDashboard(sc)
@@ -1034,6 +1040,7 @@ func postDashboardScenario(t *testing.T, desc string, url string, routePattern s
 			folderService:         folderService,
 			Features:              featuremgmt.WithFeatures(),
 			Coremodels:            registry.NewBase(),
+			onboardingService:     onboardingtest.NewFakeService(),
 		}
 
 		sc := setupScenarioContext(t, url)
@@ -1067,6 +1074,7 @@ func postDiffScenario(t *testing.T, desc string, url string, routePattern string
 			dashboardVersionService: fakeDashboardVersionService,
 			Features:                featuremgmt.WithFeatures(),
 			Coremodels:              registry.NewBase(),
+			onboardingService:       onboardingtest.NewFakeService(),
 		}
 
 		sc := setupScenarioContext(t, url)
@@ -1106,6 +1114,7 @@ func restoreDashboardVersionScenario(t *testing.T, desc string, url string, rout
 			Features:                featuremgmt.WithFeatures(),
 			dashboardVersionService: fakeDashboardVersionService,
 			Coremodels:              registry.NewBase(),
+			onboardingService:       onboardingtest.NewFakeService(),
 		}
 
 		sc := setupScenarioContext(t, url)
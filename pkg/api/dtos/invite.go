@@ -1,12 +1,89 @@
 package dtos
 
-import "github.com/grafana/grafana/pkg/services/org"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/org"
+)
 
 type AddInviteForm struct {
-	LoginOrEmail string       `json:"loginOrEmail" binding:"Required"`
-	Name         string       `json:"name"`
-	Role         org.RoleType `json:"role" binding:"Required"`
-	SendEmail    bool         `json:"sendEmail"`
+	LoginOrEmail string `json:"loginOrEmail" binding:"Required"`
+	Name         string `json:"name"`
+	SendEmail    bool   `json:"sendEmail"`
+	// Role defaults to the default invite role of the organization
+	Role org.RoleType `json:"role"`
+	// Teams the invitee joins when the invite is accepted, defaults to the default invite teams
+	// of the organization. An empty list joins no team.
+	Teams []int64 `json:"teams"`
+	// Delivery defaults to email, manually delivered invites can't be emailed
+	Delivery models.InviteDelivery `json:"delivery"`
+	// EmailMatch restricts the email new users can complete the invite with, defaults to any
+	EmailMatch models.InviteEmailMatch `json:"emailMatch"`
+	// AllowDowngrade lets invites to members of the organization lower their role to Role
+	AllowDowngrade bool `json:"allowDowngrade"`
+	// ExternalID identifies the invitee in another system, e.g. the employee number in an HR
+	// system. It is set on the user who accepts the invite, unless the user already has one.
+	ExternalID string `json:"externalId"`
+	// Orgs are the other organizations the invitee joins with the invite, and their role in each.
+	// Only Grafana admins can invite to several organizations.
+	Orgs []InviteOrg `json:"orgs"`
+	// AccessExpires is set for viewer tokens, see ShareViewerTokenForm
+	AccessExpires *time.Time `json:"-"`
+}
+
+// InviteOrg is another organization an invite adds the invitee to.
+type InviteOrg struct {
+	OrgID int64        `json:"orgId"`
+	Role  org.RoleType `json:"role"`
+}
+
+// InviteLink is the link of an invite to hand over to the invitee, for manually delivered invites.
+type InviteLink struct {
+	Message  string `json:"message,omitempty"`
+	InviteID int64  `json:"inviteId"`
+	Email    string `json:"email"`
+	Code     string `json:"code"`
+	URL      string `json:"url"`
+}
+
+// UserOrgInvite is a pending invite of the signed in user. It has no code, invites are accepted
+// and declined with their ID by the user they target.
+type UserOrgInvite struct {
+	ID             int64        `json:"id"`
+	OrgID          int64        `json:"orgId"`
+	OrgName        string       `json:"orgName"`
+	Email          string       `json:"email"`
+	Role           org.RoleType `json:"role"`
+	InvitedByLogin string       `json:"invitedByLogin"`
+	InvitedByEmail string       `json:"invitedByEmail"`
+	InvitedByName  string       `json:"invitedByName"`
+	Created        time.Time    `json:"createdOn"`
+}
+
+// QueuedInvite is returned when an invite was saved but its email is queued, because the email
+// backend is saturated.
+type QueuedInvite struct {
+	Message  string `json:"message"`
+	InviteID int64  `json:"inviteId"`
+	// UserID is set when an existing user was invited
+	UserID int64 `json:"userId,omitempty"`
+	// QueuePosition is the number of emails to send up to the invite email
+	QueuePosition     int       `json:"queuePosition"`
+	EstimatedDelivery time.Time `json:"estimatedDelivery"`
+}
+
+// InviteDelivery is the delivery state of the email of an invite: queued, sent, failed or not_sent
+// for invites which weren't emailed.
+type InviteDelivery struct {
+	State             string     `json:"state"`
+	QueuePosition     int        `json:"queuePosition,omitempty"`
+	EstimatedDelivery *time.Time `json:"estimatedDelivery,omitempty"`
+	SentAt            *time.Time `json:"sentAt,omitempty"`
+	Error             string     `json:"error,omitempty"`
 }
 
 type InviteInfo struct {
@@ -14,6 +91,8 @@ type InviteInfo struct {
 	Name      string `json:"name"`
 	Username  string `json:"username"`
 	InvitedBy string `json:"invitedBy"`
+	// EmailMatch tells which emails the invite can be completed with
+	EmailMatch models.InviteEmailMatch `json:"emailMatch"`
 }
 
 type CompleteInviteForm struct {
@@ -23,4 +102,279 @@ type CompleteInviteForm struct {
 	Username        string `json:"username"`
 	Password        string `json:"password"`
 	ConfirmPassword string `json:"confirmPassword"`
+	CaptchaToken    string `json:"captchaToken"`
+}
+
+// InviteConflict is returned with a 409 when an invite is completed with the email of an existing
+// user, who can sign in and link the invite to their account with the token.
+type InviteConflict struct {
+	Message   string    `json:"message"`
+	LinkToken string    `json:"linkToken"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	LoginURL  string    `json:"loginUrl"`
+}
+
+type LinkInviteForm struct {
+	LinkToken string `json:"linkToken" binding:"Required"`
+}
+
+type InvitesSMTPHealth struct {
+	Enabled   bool      `json:"enabled"`
+	Connected bool      `json:"connected"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+type InvitesHealth struct {
+	SMTP            InvitesSMTPHealth `json:"smtp"`
+	PendingInvites  int               `json:"pendingInvites"`
+	EmailQueueDepth int               `json:"emailQueueDepth"`
+	LastEmailSentAt *time.Time        `json:"lastEmailSentAt"`
+}
+
+// InviteOnboardingEvent is an onboarding session attached as a calendar event to invite emails.
+type InviteOnboardingEvent struct {
+	Enabled         bool      `json:"enabled"`
+	Title           string    `json:"title"`
+	Description     string    `json:"description"`
+	Start           time.Time `json:"start"`
+	DurationMinutes int       `json:"durationMinutes"`
+	MeetingURL      string    `json:"meetingUrl"`
+}
+
+// Kinds of invite join rules.
+const (
+	// InviteJoinRuleDomain matches the emails of a domain, e.g. sre.company.com
+	InviteJoinRuleDomain = "domain"
+	// InviteJoinRuleRegex matches the emails matching a regular expression in RE2 syntax, emails
+	// are lower-cased first
+	InviteJoinRuleRegex = "regex"
+)
+
+// InviteJoinRule places the users joining the organization with an invite sent to a matching
+// email in teams, and gives them at least its role.
+type InviteJoinRule struct {
+	Match   string       `json:"match"`
+	Pattern string       `json:"pattern"`
+	Role    org.RoleType `json:"role,omitempty"`
+	Teams   []int64      `json:"teams,omitempty"`
+}
+
+type InviteJoinRules struct {
+	Rules []InviteJoinRule `json:"rules"`
+}
+
+// EvaluateInviteJoinRulesForm tells what the invite join rules give an email, without applying them.
+type EvaluateInviteJoinRulesForm struct {
+	Email string `json:"email" binding:"Required"`
+	// Rules are evaluated instead of the rules of the organization, to try them before saving them
+	Rules []InviteJoinRule `json:"rules"`
+}
+
+// InviteJoinRulesEvaluation is what the invite join rules give the users joining with an invite:
+// the teams of all the matching rules and the highest of their roles.
+type InviteJoinRulesEvaluation struct {
+	// Matched are the indexes of the matching rules
+	Matched []int        `json:"matched"`
+	Role    org.RoleType `json:"role,omitempty"`
+	Teams   []int64      `json:"teams"`
+}
+
+// InviteDomain is an email domain an organization claims. Once the organization proved it owns
+// the domain with a DNS TXT record, invites to its emails get the exemptions it enables.
+type InviteDomain struct {
+	Domain string `json:"domain"`
+	// RecordName is the name of the TXT record proving the ownership of the domain
+	RecordName string `json:"recordName"`
+	// RecordValue is the value the TXT record must have
+	RecordValue string     `json:"recordValue"`
+	Verified    bool       `json:"verified"`
+	VerifiedAt  *time.Time `json:"verifiedAt,omitempty"`
+	Created     time.Time  `json:"created"`
+	InviteDomainOptions
+}
+
+// InviteDomainOptions are the exemptions invites to the emails of a verified domain get.
+type InviteDomainOptions struct {
+	// AutoApprove adds existing users to the organization right away, instead of leaving them a
+	// pending invite to accept
+	AutoApprove bool `json:"autoApprove"`
+	// AutoComplete completes the pending invites of users signing in with SSO
+	AutoComplete bool `json:"autoComplete"`
+	// ExemptFromPolicy lets new users be invited when invite_requires_sign_up would prevent it,
+	// and complete their invite with another email of the domain when email verification is
+	// enabled
+	ExemptFromPolicy bool `json:"exemptFromPolicy"`
+}
+
+type InviteDomains struct {
+	Domains []InviteDomain `json:"domains"`
+}
+
+type AddInviteDomainForm struct {
+	Domain string `json:"domain" binding:"Required"`
+	InviteDomainOptions
+}
+
+// PatchOrgInviteForm updates an invite, fields which are not set are left unchanged.
+type PatchOrgInviteForm struct {
+	Name   *string                `json:"name"`
+	Role   *org.RoleType          `json:"role"`
+	Status *models.TempUserStatus `json:"status"`
+	// Email corrects the address of a pending invite. The invite gets a new link, which is
+	// emailed to the new address if the invite had been emailed.
+	Email *string `json:"email"`
+}
+
+// ShareInviteForm shares a dashboard or folder with someone by email, who is invited to the
+// organization if they aren't a member.
+type ShareInviteForm struct {
+	Email string `json:"email" binding:"Required"`
+	// Name of the invitee, for people without an account
+	Name string `json:"name"`
+	// ResourceKind is dashboard or folder
+	ResourceKind string `json:"resourceKind" binding:"Required"`
+	ResourceUID  string `json:"resourceUid" binding:"Required"`
+	// Permission is View or Edit, defaults to View
+	Permission string `json:"permission"`
+	// SendEmail emails new invites
+	SendEmail bool `json:"sendEmail"`
+}
+
+// ShareViewerTokenForm shares a dashboard or folder with someone outside of the organization for
+// a few days. The invitee joins the organization as a viewer when accepting the token, and is
+// removed from it once the days are over.
+type ShareViewerTokenForm struct {
+	Email string `json:"email" binding:"Required"`
+	// Name of the invitee, for people without an account
+	Name string `json:"name"`
+	// ResourceKind is dashboard or folder
+	ResourceKind string `json:"resourceKind" binding:"Required"`
+	ResourceUID  string `json:"resourceUid" binding:"Required"`
+	// Days the membership lasts, counted from the creation of the token
+	Days int `json:"days" binding:"Required"`
+	// SendEmail emails the token
+	SendEmail bool `json:"sendEmail"`
+}
+
+// ValidateInvitesForm is a batch of invites, e.g. the rows of a CSV file, checked without
+// creating them. Each invite has the fields of AddInviteForm, it is decoded on its own so that
+// invalid values are reported with the row.
+type ValidateInvitesForm struct {
+	Invites []json.RawMessage `json:"invites"`
+}
+
+// MaxValidatedInvites is the maximum number of invites of a ValidateInvitesForm.
+const MaxValidatedInvites = 1000
+
+// Validate only checks the size of the batch, the invites are checked row by row.
+func (f *ValidateInvitesForm) Validate() error {
+	if len(f.Invites) == 0 {
+		return errors.New("no invites to validate")
+	}
+	if len(f.Invites) > MaxValidatedInvites {
+		return fmt.Errorf("at most %d invites can be validated at once", MaxValidatedInvites)
+	}
+	return nil
+}
+
+// InvitesValidation reports whether each invite of a batch can be created.
+type InvitesValidation struct {
+	// Valid tells whether all the invites can be created
+	Valid bool                  `json:"valid"`
+	Rows  []InviteRowValidation `json:"rows"`
+}
+
+// InviteRowValidation reports whether an invite of a batch can be created, and why not.
+type InviteRowValidation struct {
+	// Row is the position of the invite in the batch, counted from 1
+	Row          int          `json:"row"`
+	LoginOrEmail string       `json:"loginOrEmail"`
+	Role         org.RoleType `json:"role,omitempty"`
+	// ExistingUser tells whether the invitee already has an account
+	ExistingUser bool     `json:"existingUser"`
+	Errors       []string `json:"errors,omitempty"`
+	// Warnings don't prevent creating the invite
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// BulkInvitesForm is a batch of invites created at once, either all of them or none. Each invite
+// has the fields of AddInviteForm, it is decoded on its own so that invalid values are reported
+// with the row.
+type BulkInvitesForm struct {
+	Invites []json.RawMessage `json:"invites"`
+}
+
+// MaxBulkInvites is the maximum number of invites of a BulkInvitesForm.
+const MaxBulkInvites = 500
+
+// Validate only checks the size of the batch, the invites are checked row by row.
+func (f *BulkInvitesForm) Validate() error {
+	if len(f.Invites) == 0 {
+		return errors.New("no invites to create")
+	}
+	if len(f.Invites) > MaxBulkInvites {
+		return fmt.Errorf("at most %d invites can be created at once", MaxBulkInvites)
+	}
+	return nil
+}
+
+// BulkInvites reports the invites of a batch, and why they couldn't be created.
+type BulkInvites struct {
+	// Created tells whether the invites were created, either all of them are or none is
+	Created bool            `json:"created"`
+	Rows    []BulkInviteRow `json:"rows"`
+}
+
+type BulkInviteRow struct {
+	InviteRowValidation
+	// Message tells what creating the invite did, e.g. that an existing user was added
+	Message string `json:"message,omitempty"`
+}
+
+type AddEmailSuppressionForm struct {
+	Email string `json:"email" binding:"Required"`
+	// Reason is unsubscribed, bounced or manual
+	Reason string `json:"reason" binding:"Required"`
+}
+
+// InviteAuditReport is the JSON format of the audit report of the memberships given by invites.
+type InviteAuditReport struct {
+	OrgID int64     `json:"orgId"`
+	From  time.Time `json:"from"`
+	To    time.Time `json:"to"`
+	// ApproverUserID is set when the report only covers the invites created by that user
+	ApproverUserID int64                      `json:"approverUserId,omitempty"`
+	Entries        []*models.InviteAuditEntry `json:"entries"`
+}
+
+type VerifyInviteAuditReportForm struct {
+	// Report is the body of the report, exactly as it was downloaded
+	Report    string `json:"report" binding:"Required"`
+	Signature string `json:"signature" binding:"Required"`
+}
+
+type InviteAuditReportVerification struct {
+	Valid bool `json:"valid"`
+}
+
+// InviteCompletedHook is the body of the invite completed hook of app plugins, see
+// plugins.Hooks.InviteCompleted.
+type InviteCompletedHook struct {
+	UserID int64                     `json:"userId"`
+	OrgID  int64                     `json:"orgId"`
+	Role   org.RoleType              `json:"role"`
+	Invite InviteCompletedHookInvite `json:"invite"`
+}
+
+type InviteCompletedHookInvite struct {
+	ID         int64  `json:"id"`
+	Email      string `json:"email"`
+	Name       string `json:"name"`
+	ExternalID string `json:"externalId,omitempty"`
+	// InvitedBy is the login of the inviter, empty for invites created with an API key
+	InvitedBy string    `json:"invitedBy,omitempty"`
+	Created   time.Time `json:"created"`
+	// AccessExpires is set for viewer tokens, the membership ends at that time
+	AccessExpires *time.Time `json:"accessExpires,omitempty"`
 }
@@ -13,6 +13,8 @@ type Prefs struct {
 	Locale           string                      `json:"locale"`
 	Navbar           pref.NavbarPreference       `json:"navbar,omitempty"`
 	QueryHistory     pref.QueryHistoryPreference `json:"queryHistory,omitempty"`
+	// Invites are the invite defaults, only returned for organizations
+	Invites *pref.InvitesPreference `json:"invites,omitempty"`
 }
 
 // swagger:model
@@ -29,6 +31,8 @@ type UpdatePrefsCmd struct {
 	Navbar       *pref.NavbarPreference       `json:"navbar,omitempty"`
 	QueryHistory *pref.QueryHistoryPreference `json:"queryHistory,omitempty"`
 	Locale       string                       `json:"locale"`
+	// Invites are the defaults of the invites of the organization, they can't be set for users or teams
+	Invites *pref.InvitesPreference `json:"invites,omitempty"`
 }
 
 // swagger:model
@@ -45,4 +49,6 @@ type PatchPrefsCmd struct {
 	Navbar           *pref.NavbarPreference       `json:"navbar,omitempty"`
 	QueryHistory     *pref.QueryHistoryPreference `json:"queryHistory,omitempty"`
 	HomeDashboardUID *string                      `json:"homeDashboardUID,omitempty"`
+	// Invites are the defaults of the invites of the organization, they can't be set for users or teams
+	Invites *pref.InvitesPreference `json:"invites,omitempty"`
 }
@@ -1,7 +1,12 @@
 package dtos
 
+import (
+	"github.com/grafana/grafana/pkg/models"
+)
+
 type SignUpForm struct {
-	Email string `json:"email" binding:"Required"`
+	Email        string `json:"email" binding:"Required"`
+	CaptchaToken string `json:"captchaToken"`
 }
 
 type SignUpStep2Form struct {
@@ -11,6 +16,8 @@ type SignUpStep2Form struct {
 	Password string `json:"password"`
 	Code     string `json:"code"`
 	OrgName  string `json:"orgName"`
+
+	CaptchaToken string `json:"captchaToken"`
 }
 
 type AdminCreateUserForm struct {
@@ -44,3 +51,18 @@ type UserLookupDTO struct {
 	Login     string `json:"login"`
 	AvatarURL string `json:"avatarUrl"`
 }
+
+// UserDataExport is the personal data Grafana keeps about a user, for data subject access requests.
+type UserDataExport struct {
+	UserID int64  `json:"userId"`
+	Login  string `json:"login"`
+	Email  string `json:"email"`
+	Name   string `json:"name"`
+	// Invites are the invites and sign ups sent by the user or to their email, in all organizations
+	Invites []*models.TempUserDTO `json:"invites"`
+}
+
+// UserDataErasure tells how many records the personal data of the user was erased from.
+type UserDataErasure struct {
+	Erased int64 `json:"erased"`
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+// swagger:route GET /admin/email-suppressions admin getEmailSuppressions
+//
+// List the suppressed emails.
+//
+// Invites aren't emailed to the addresses on the suppression list, whose owner opted out of
+// Grafana emails or to which emails hard-bounced.
+//
+// Responses:
+// 200: getEmailSuppressionsResponse
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (hs *HTTPServer) GetEmailSuppressions(c *models.ReqContext) response.Response {
+	query := models.GetEmailSuppressionsQuery{}
+	if err := hs.tempUserService.GetEmailSuppressions(c.Req.Context(), &query); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get the suppressed emails", err)
+	}
+	return response.JSON(http.StatusOK, query.Result)
+}
+
+// swagger:route POST /admin/email-suppressions admin addEmailSuppression
+//
+// Suppress an email.
+//
+// Adds the email to the suppression list, or changes the reason it is on it.
+//
+// Responses:
+// 200: addEmailSuppressionResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (hs *HTTPServer) AddEmailSuppression(c *models.ReqContext) response.Response {
+	form := dtos.AddEmailSuppressionForm{}
+	if err := web.Bind(c.Req, &form); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	cmd := models.AddEmailSuppressionCommand{Email: form.Email, Reason: form.Reason}
+	if err := hs.tempUserService.AddEmailSuppression(c.Req.Context(), &cmd); err != nil {
+		if errors.Is(err, models.ErrInvalidEmailSuppression) {
+			return response.Error(http.StatusBadRequest, err.Error(), err)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to suppress the email", err)
+	}
+	return response.JSON(http.StatusOK, cmd.Result)
+}
+
+// swagger:route DELETE /admin/email-suppressions/{email} admin removeEmailSuppression
+//
+// Remove an email from the suppression list.
+//
+// Responses:
+// 200: okResponse
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (hs *HTTPServer) RemoveEmailSuppression(c *models.ReqContext) response.Response {
+	cmd := models.RemoveEmailSuppressionCommand{Email: web.Params(c.Req)[":email"]}
+	if err := hs.tempUserService.RemoveEmailSuppression(c.Req.Context(), &cmd); err != nil {
+		if errors.Is(err, models.ErrEmailSuppressionNotFound) {
+			return response.Error(http.StatusNotFound, err.Error(), err)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to remove the email from the suppression list", err)
+	}
+	return response.Success("Email removed from the suppression list")
+}
+
+// emailSuppressionError returns why invites can't be emailed to the address, or an empty string
+// when they can.
+func (hs *HTTPServer) emailSuppressionError(ctx context.Context, email string) (string, error) {
+	query := models.GetEmailSuppressionQuery{Email: email}
+	err := hs.tempUserService.GetEmailSuppression(ctx, &query)
+	if errors.Is(err, models.ErrEmailSuppressionNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s is on the email suppression list (%s), invite it without sending an email", email, query.Result.Reason), nil
+}
+
+// checkEmailSuppression returns an error response when the invite email can't be sent to the address.
+func (hs *HTTPServer) checkEmailSuppression(ctx context.Context, email string) response.Response {
+	msg, err := hs.emailSuppressionError(ctx, email)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to check the email suppression list", err)
+	}
+	if msg != "" {
+		return response.Error(http.StatusPreconditionFailed, msg, nil)
+	}
+	return nil
+}
+
+// swagger:parameters addEmailSuppression
+type AddEmailSuppressionParams struct {
+	// in:body
+	// required:true
+	Body dtos.AddEmailSuppressionForm `json:"body"`
+}
+
+// swagger:parameters removeEmailSuppression
+type RemoveEmailSuppressionParams struct {
+	// in:path
+	// required:true
+	Email string `json:"email"`
+}
+
+// swagger:response getEmailSuppressionsResponse
+type GetEmailSuppressionsResponse struct {
+	// in: body
+	Body []*models.EmailSuppression `json:"body"`
+}
+
+// swagger:response addEmailSuppressionResponse
+type AddEmailSuppressionResponse struct {
+	// in: body
+	Body models.EmailSuppression `json:"body"`
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/temp_user/invitetest"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/services/user/usertest"
+)
+
+func TestEmailSuppressions(t *testing.T) {
+	setup := func(t *testing.T, signedInUser user.SignedInUser) (accessControlScenarioContext, *invitetest.Stack) {
+		sc, stack := setupInviteHTTPServer(t)
+		sc.hs.userService = &slackInviteUserService{&usertest.FakeUserService{}}
+		setInitCtxSignedInUser(sc.initCtx, signedInUser)
+		setAccessControlPermissions(sc.acmock, []accesscontrol.Permission{{Action: accesscontrol.ActionOrgUsersAdd}}, sc.initCtx.OrgID)
+		return sc, stack
+	}
+	serverAdmin := user.SignedInUser{UserID: testUserID, OrgID: 1, OrgRole: org.RoleAdmin, Login: testUserLogin, IsGrafanaAdmin: true}
+
+	t.Run("server admins manage the suppression list", func(t *testing.T) {
+		sc, _ := setup(t, serverAdmin)
+
+		response := callAPI(sc.server, http.MethodPost, "/api/admin/email-suppressions", strings.NewReader(`{"email": "Bounced@example.com", "reason": "bounced"}`), t)
+		require.Equal(t, http.StatusOK, response.Code)
+		response = callAPI(sc.server, http.MethodPost, "/api/admin/email-suppressions", strings.NewReader(`{"email": "bounced@example.com", "reason": "spam"}`), t)
+		require.Equal(t, http.StatusBadRequest, response.Code)
+
+		response = callAPI(sc.server, http.MethodGet, "/api/admin/email-suppressions", nil, t)
+		require.Equal(t, http.StatusOK, response.Code)
+		var suppressions []*models.EmailSuppression
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &suppressions))
+		require.Len(t, suppressions, 1)
+		assert.Equal(t, "bounced@example.com", suppressions[0].Email)
+		assert.Equal(t, models.EmailSuppressionBounced, suppressions[0].Reason)
+
+		response = callAPI(sc.server, http.MethodDelete, "/api/admin/email-suppressions/bounced@example.com", nil, t)
+		require.Equal(t, http.StatusOK, response.Code)
+		response = callAPI(sc.server, http.MethodDelete, "/api/admin/email-suppressions/bounced@example.com", nil, t)
+		require.Equal(t, http.StatusNotFound, response.Code)
+	})
+
+	t.Run("org admins can't manage the suppression list", func(t *testing.T) {
+		sc, _ := setup(t, user.SignedInUser{UserID: testUserID, OrgID: 1, OrgRole: org.RoleAdmin, Login: testUserLogin})
+		response := callAPI(sc.server, http.MethodGet, "/api/admin/email-suppressions", nil, t)
+		require.Equal(t, http.StatusForbidden, response.Code)
+	})
+
+	t.Run("invites aren't emailed to suppressed emails", func(t *testing.T) {
+		sc, stack := setup(t, serverAdmin)
+		suppress := models.AddEmailSuppressionCommand{Email: "opted.out@example.com", Reason: models.EmailSuppressionUnsubscribed}
+		require.NoError(t, stack.TempUsers.AddEmailSuppression(context.Background(), &suppress))
+
+		response := callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(`{"loginOrEmail": "Opted.Out@example.com", "role": "Viewer", "sendEmail": true}`), t)
+		require.Equal(t, http.StatusPreconditionFailed, response.Code)
+		assert.Contains(t, response.Body.String(), "suppression list (unsubscribed)")
+
+		response = callAPI(sc.server, http.MethodPost, "/api/org/invites/validate", strings.NewReader(`{"invites": [{"loginOrEmail": "opted.out@example.com", "role": "Viewer", "sendEmail": true}]}`), t)
+		require.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(t, response.Body.String(), "suppression list (unsubscribed)")
+
+		assert.Empty(t, stack.Pending(t, 1))
+
+		// the invite can still be delivered another way
+		response = callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(`{"loginOrEmail": "opted.out@example.com", "role": "Viewer"}`), t)
+		require.Equal(t, http.StatusOK, response.Code)
+	})
+}
@@ -203,6 +203,13 @@ func (hs *HTTPServer) getFrontendSettingsMap(c *models.ReqContext) (map[string]i
 		jsonObj["geomapDisableCustomBaseLayer"] = true
 	}
 
+	if hs.Cfg.Captcha.Enabled {
+		jsonObj["captcha"] = map[string]interface{}{
+			"provider": hs.Cfg.Captcha.Provider,
+			"siteKey":  hs.Cfg.Captcha.SiteKey,
+		}
+	}
+
 	return jsonObj, nil
 }
 
@@ -40,6 +40,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/alerting"
 	"github.com/grafana/grafana/pkg/services/annotations"
 	"github.com/grafana/grafana/pkg/services/apikey"
+	"github.com/grafana/grafana/pkg/services/captcha"
 	"github.com/grafana/grafana/pkg/services/cleanup"
 	"github.com/grafana/grafana/pkg/services/comments"
 	"github.com/grafana/grafana/pkg/services/contexthandler"
@@ -65,6 +66,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/navtree"
 	"github.com/grafana/grafana/pkg/services/ngalert"
 	"github.com/grafana/grafana/pkg/services/notifications"
+	"github.com/grafana/grafana/pkg/services/onboarding"
 	"github.com/grafana/grafana/pkg/services/org"
 	"github.com/grafana/grafana/pkg/services/playlist"
 	"github.com/grafana/grafana/pkg/services/plugindashboards"
@@ -197,8 +199,15 @@ type HTTPServer struct {
 	orgService             org.Service
 	teamService            team.Service
 	accesscontrolService   accesscontrol.Service
+	roleRestrictions       accesscontrol.RoleRestrictionService
 	annotationsRepo        annotations.Repository
 	tagService             tag.Service
+	captchaService         captcha.Service
+	onboardingService      onboarding.Service
+	// nil unless the invite testing mode is enabled
+	inviteFaults *inviteFaults
+	// the idempotency keys of the requests in progress, see withIdempotencyKey
+	idempotencyInFlight sync.Map
 }
 
 type ServerOptions struct {
@@ -240,6 +249,8 @@ func ProvideHTTPServer(opts ServerOptions, cfg *setting.Cfg, routeRegister routi
 	loginAttemptService loginAttempt.Service, orgService org.Service, teamService team.Service,
 	accesscontrolService accesscontrol.Service, dashboardThumbsService dashboardThumbs.Service, navTreeService navtree.Service,
 	annotationRepo annotations.Repository, tagService tag.Service, searchv2HTTPService searchV2.SearchHTTPService,
+	captchaService captcha.Service, roleRestrictions accesscontrol.RoleRestrictionService,
+	onboardingService onboarding.Service,
 ) (*HTTPServer, error) {
 	web.Env = cfg.Env
 	m := web.New()
@@ -332,6 +343,7 @@ func ProvideHTTPServer(opts ServerOptions, cfg *setting.Cfg, routeRegister routi
 		PublicDashboardsApi:          publicDashboardsApi,
 		userService:                  userService,
 		tempUserService:              tempUserService,
+		roleRestrictions:             roleRestrictions,
 		dashboardThumbsService:       dashboardThumbsService,
 		loginAttemptService:          loginAttemptService,
 		orgService:                   orgService,
@@ -340,7 +352,11 @@ func ProvideHTTPServer(opts ServerOptions, cfg *setting.Cfg, routeRegister routi
 		accesscontrolService:         accesscontrolService,
 		annotationsRepo:              annotationRepo,
 		tagService:                   tagService,
+		captchaService:               captchaService,
+		onboardingService:            onboardingService,
 	}
+	hs.inviteFaults = newInviteFaults(cfg.InviteTesting, hs.log)
+	hs.tempUserService = hs.inviteFaults.wrapTempUserService(tempUserService)
 	if hs.Listener != nil {
 		hs.log.Debug("Using provided listener")
 	}
@@ -367,6 +383,7 @@ func (hs *HTTPServer) Run(ctx context.Context) error {
 	hs.context = ctx
 
 	hs.applyRoutes()
+	go hs.requeueInviteEmails(ctx)
 
 	// Remove any square brackets enclosing IPv6 addresses, a format we support for backwards compatibility
 	host := strings.TrimSuffix(strings.TrimPrefix(hs.Cfg.HTTPAddr, "["), "]")
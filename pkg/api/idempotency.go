@@ -0,0 +1,139 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+const (
+	idempotencyKeyHeader      = "Idempotency-Key"
+	idempotentReplayedHeader  = "Idempotent-Replayed"
+	idempotencyKeyMaxLength   = 255
+	idempotencyKeyTTL         = 24 * time.Hour
+	idempotencyInFlightTTL    = time.Minute
+	idempotencyCacheKeyPrefix = "idempotency-key"
+	idempotencyMaxBodySize    = 1 << 20
+)
+
+// idempotentResponse is the response stored for an idempotency key, so that it
+// can be returned again when the same request is retried. Its Status is 0 while
+// the request is in progress.
+type idempotentResponse struct {
+	RequestHash string
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+func init() {
+	remotecache.Register(idempotentResponse{})
+}
+
+// withIdempotencyKey runs handler at most once per Idempotency-Key header value.
+// Retrying a request with the same key returns the original response instead of
+// running the handler again, and fails with 409 while the original request is in
+// progress. Reusing a key for a different request is rejected. Requests without
+// the header and responses with a server error status are not recorded, so they
+// can be retried freely.
+//
+// Keys are reserved in the remote cache while their request is in progress. The
+// cache has no atomic insert, concurrent requests reaching different instances at
+// once may both run.
+func (hs *HTTPServer) withIdempotencyKey(c *models.ReqContext, scope string, handler func(c *models.ReqContext) response.Response) response.Response {
+	key := c.Req.Header.Get(idempotencyKeyHeader)
+	if key == "" || hs.RemoteCacheService == nil {
+		return handler(c)
+	}
+	if len(key) > idempotencyKeyMaxLength {
+		return response.Error(http.StatusBadRequest, fmt.Sprintf("%s header must not be longer than %d characters", idempotencyKeyHeader, idempotencyKeyMaxLength), nil)
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Resp, c.Req.Body, idempotencyMaxBodySize))
+	if err != nil {
+		if len(body) >= idempotencyMaxBodySize {
+			return response.Error(http.StatusRequestEntityTooLarge, fmt.Sprintf("Requests with an %s must not be larger than %d bytes", idempotencyKeyHeader, idempotencyMaxBodySize), err)
+		}
+		return response.Error(http.StatusBadRequest, "Failed to read request body", err)
+	}
+	c.Req.Body = io.NopCloser(bytes.NewReader(body))
+	requestHash := sha256.Sum256(body)
+
+	cacheKey := idempotencyCacheKey(c, scope, key)
+
+	if _, inFlight := hs.idempotencyInFlight.LoadOrStore(cacheKey, struct{}{}); inFlight {
+		return idempotencyKeyInProgress()
+	}
+	defer hs.idempotencyInFlight.Delete(cacheKey)
+
+	cached, err := hs.RemoteCacheService.Get(c.Req.Context(), cacheKey)
+	if err != nil && !errors.Is(err, remotecache.ErrCacheItemNotFound) {
+		return response.Error(http.StatusInternalServerError, "Failed to look up idempotency key", err)
+	}
+	if stored, ok := cached.(idempotentResponse); ok {
+		if stored.Status == 0 {
+			return idempotencyKeyInProgress()
+		}
+		if stored.RequestHash != hex.EncodeToString(requestHash[:]) {
+			return response.Error(http.StatusUnprocessableEntity, fmt.Sprintf("%s has already been used for a different request", idempotencyKeyHeader), nil)
+		}
+		header := http.Header{}
+		if stored.ContentType != "" {
+			header.Set("Content-Type", stored.ContentType)
+		}
+		header.Set(idempotentReplayedHeader, "true")
+		return response.CreateNormalResponse(header, stored.Body, stored.Status)
+	}
+
+	// the reservation expires on its own if the instance stops before the request completes
+	reservation := idempotentResponse{RequestHash: hex.EncodeToString(requestHash[:])}
+	if err := hs.RemoteCacheService.Set(c.Req.Context(), cacheKey, reservation, idempotencyInFlightTTL); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to reserve idempotency key", err)
+	}
+
+	resp := handler(c)
+	if resp.Status() >= http.StatusInternalServerError {
+		if err := hs.RemoteCacheService.Delete(c.Req.Context(), cacheKey); err != nil {
+			hs.log.Warn("Failed to release idempotency key", "scope", scope, "error", err)
+		}
+		return resp
+	}
+
+	stored := idempotentResponse{
+		RequestHash: reservation.RequestHash,
+		Status:      resp.Status(),
+		Body:        resp.Body(),
+	}
+	if normal, ok := resp.(*response.NormalResponse); ok {
+		stored.ContentType = normal.Header().Get("Content-Type")
+	}
+	if err := hs.RemoteCacheService.Set(c.Req.Context(), cacheKey, stored, idempotencyKeyTTL); err != nil {
+		hs.log.Warn("Failed to store idempotency key", "scope", scope, "error", err)
+	}
+
+	return resp
+}
+
+// idempotencyCacheKey returns the cache key of an idempotency key, which is per user or API key as
+// API keys have no user.
+func idempotencyCacheKey(c *models.ReqContext, scope, key string) string {
+	requester := fmt.Sprintf("user-%d", c.UserID)
+	if c.ApiKeyID != 0 {
+		requester = fmt.Sprintf("apikey-%d", c.ApiKeyID)
+	}
+	keyHash := sha256.Sum256([]byte(key))
+	return fmt.Sprintf("%s-%s-%d-%s-%s", idempotencyCacheKeyPrefix, scope, c.OrgID, requester, hex.EncodeToString(keyHash[:]))
+}
+
+func idempotencyKeyInProgress() response.Response {
+	return response.Error(http.StatusConflict, fmt.Sprintf("A request with the same %s is in progress, retry once it completes", idempotencyKeyHeader), nil)
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+var (
+	errInviteLoginFormDisabled = errors.New("new users can't be invited because the login form is disabled, enable [users] invite_with_login_form_disabled to invite them anyway")
+	errInviteSignUpDisabled    = errors.New("new users can't be invited because sign up is disabled and [users] invite_requires_sign_up is enabled")
+	errInviteUnverifiedEmail   = errors.New("email verification is enabled, the invite can only be completed with the invited email")
+	errInviteRoleAboveMax      = errors.New("the role is above the maximum role of invites")
+)
+
+// inviteRoleError is returned when the role of an invite is above the maximum role of invites of
+// its delivery.
+type inviteRoleError struct {
+	Delivery models.InviteDelivery
+	Role     org.RoleType
+	MaxRole  org.RoleType
+}
+
+func (e inviteRoleError) Error() string {
+	if e.Delivery == models.InviteDeliveryManual {
+		return fmt.Sprintf("join links can't invite with the %s role, the maximum role of join links is %s", e.Role, e.MaxRole)
+	}
+	return fmt.Sprintf("emailed invites can't invite with the %s role, the maximum role of emailed invites is %s", e.Role, e.MaxRole)
+}
+
+func (e inviteRoleError) Unwrap() error {
+	return errInviteRoleAboveMax
+}
+
+// Code identifies the error in API responses.
+func (e inviteRoleError) Code() string {
+	if e.Delivery == models.InviteDeliveryManual {
+		return "invite.link-role-above-max"
+	}
+	return "invite.email-role-above-max"
+}
+
+// invitePolicy decides how the login and sign up settings apply to invites of new users, so that
+// all the ways to invite them behave the same. Invites of existing users are not affected. The
+// invite specific settings take precedence over the general ones:
+//
+//   - with the login form disabled, new users can't be invited as they couldn't sign in with the
+//     password they choose, unless invite_with_login_form_disabled is enabled
+//   - invites add new users even when sign up is disabled, unless invite_requires_sign_up is enabled
+//   - with email verification enabled, invites can only be completed with the invited email, as
+//     other emails are not verified, unless invite_allow_unverified_email is enabled
+//
+// Join links, the invites handed over by the inviter, can leak more easily than emailed invites, so
+// the maximum role of each is set separately with invite_link_max_role and invite_email_max_role.
+// The maximum roles apply to the invites of existing users too.
+//
+// Completing an invite never creates an organization, whatever allow_org_create and
+// auto_assign_org are.
+//
+// Invites to the emails of a domain the organization verified, and exempted from the policy, are
+// not held by invite_requires_sign_up, and can be completed with any email of the domain.
+type invitePolicy struct {
+	disableLoginForm bool
+	allowSignUp      bool
+	verifyEmail      bool
+
+	withLoginFormDisabled bool
+	requiresSignUp        bool
+	allowUnverifiedEmail  bool
+	linkMaxRole           org.RoleType
+	emailMaxRole          org.RoleType
+
+	// exemptDomain is the verified domain of the invited email, when it is exempted
+	exemptDomain string
+}
+
+func newInvitePolicy(cfg *setting.Cfg) invitePolicy {
+	return invitePolicy{
+		disableLoginForm:      setting.DisableLoginForm,
+		allowSignUp:           setting.AllowUserSignUp,
+		verifyEmail:           setting.VerifyEmailEnabled,
+		withLoginFormDisabled: cfg.InviteWithLoginFormDisabled,
+		requiresSignUp:        cfg.InviteRequiresSignUp,
+		allowUnverifiedEmail:  cfg.InviteAllowUnverifiedEmail,
+		linkMaxRole:           org.RoleType(cfg.InviteLinkMaxRole),
+		emailMaxRole:          org.RoleType(cfg.InviteEmailMaxRole),
+	}
+}
+
+// canInviteNewUsers returns an error telling which setting prevents inviting new users.
+func (p invitePolicy) canInviteNewUsers() error {
+	if p.disableLoginForm && !p.withLoginFormDisabled {
+		return errInviteLoginFormDisabled
+	}
+	if p.requiresSignUp && !p.allowSignUp && p.exemptDomain == "" {
+		return errInviteSignUpDisabled
+	}
+	return nil
+}
+
+// canCompleteInvite returns an error telling which setting prevents completing the invite with
+// the email. The settings are checked again as they may have changed since the invite was sent.
+func (p invitePolicy) canCompleteInvite(invite *models.TempUserDTO, email string) error {
+	if err := p.canInviteNewUsers(); err != nil {
+		return err
+	}
+	if p.verifyEmail && !p.allowUnverifiedEmail && !strings.EqualFold(strings.TrimSpace(invite.Email), strings.TrimSpace(email)) {
+		if p.exemptDomain == "" || inviteEmailDomain(invite.Email) != p.exemptDomain || inviteEmailDomain(email) != p.exemptDomain {
+			return errInviteUnverifiedEmail
+		}
+	}
+	return nil
+}
+
+// canAssignRole returns an error when the role is above the maximum role of invites of the delivery.
+func (p invitePolicy) canAssignRole(delivery models.InviteDelivery, role org.RoleType) error {
+	maxRole := p.emailMaxRole
+	if delivery == models.InviteDeliveryManual {
+		maxRole = p.linkMaxRole
+	}
+	if maxRole != "" && !maxRole.Includes(role) {
+		return inviteRoleError{Delivery: delivery, Role: role, MaxRole: maxRole}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/org"
+)
+
+func TestInvitePolicy(t *testing.T) {
+	invite := &models.TempUserDTO{Email: "invitee@example.com"}
+
+	tests := []struct {
+		name          string
+		policy        invitePolicy
+		inviteErr     error
+		completeEmail string
+		completeErr   error
+	}{
+		{
+			name:          "invites add users when sign up is disabled",
+			policy:        invitePolicy{allowSignUp: false},
+			completeEmail: "colleague@example.com",
+		},
+		{
+			name:          "login form disabled",
+			policy:        invitePolicy{disableLoginForm: true},
+			inviteErr:     errInviteLoginFormDisabled,
+			completeEmail: "invitee@example.com",
+			completeErr:   errInviteLoginFormDisabled,
+		},
+		{
+			name:          "login form disabled overridden",
+			policy:        invitePolicy{disableLoginForm: true, withLoginFormDisabled: true},
+			completeEmail: "invitee@example.com",
+		},
+		{
+			name:          "sign up required by the invites",
+			policy:        invitePolicy{requiresSignUp: true},
+			inviteErr:     errInviteSignUpDisabled,
+			completeEmail: "invitee@example.com",
+			completeErr:   errInviteSignUpDisabled,
+		},
+		{
+			name:          "sign up required and allowed",
+			policy:        invitePolicy{requiresSignUp: true, allowSignUp: true},
+			completeEmail: "invitee@example.com",
+		},
+		{
+			name:          "login form takes precedence over sign up",
+			policy:        invitePolicy{disableLoginForm: true, requiresSignUp: true},
+			inviteErr:     errInviteLoginFormDisabled,
+			completeEmail: "invitee@example.com",
+			completeErr:   errInviteLoginFormDisabled,
+		},
+		{
+			name:          "email verification allows the invited email",
+			policy:        invitePolicy{verifyEmail: true},
+			completeEmail: " Invitee@example.com",
+		},
+		{
+			name:          "email verification rejects other emails",
+			policy:        invitePolicy{verifyEmail: true},
+			completeEmail: "colleague@example.com",
+			completeErr:   errInviteUnverifiedEmail,
+		},
+		{
+			name:          "verified domains are exempted from requiring sign up",
+			policy:        invitePolicy{requiresSignUp: true, exemptDomain: "example.com"},
+			completeEmail: "invitee@example.com",
+		},
+		{
+			name:          "verified domains are not exempted from the login form",
+			policy:        invitePolicy{disableLoginForm: true, exemptDomain: "example.com"},
+			inviteErr:     errInviteLoginFormDisabled,
+			completeEmail: "invitee@example.com",
+			completeErr:   errInviteLoginFormDisabled,
+		},
+		{
+			name:          "email verification allows other emails of a verified domain",
+			policy:        invitePolicy{verifyEmail: true, exemptDomain: "example.com"},
+			completeEmail: "Colleague@Example.com",
+		},
+		{
+			name:          "email verification rejects emails of other domains",
+			policy:        invitePolicy{verifyEmail: true, exemptDomain: "example.com"},
+			completeEmail: "invitee@other.com",
+			completeErr:   errInviteUnverifiedEmail,
+		},
+		{
+			name:          "email verification overridden",
+			policy:        invitePolicy{verifyEmail: true, allowUnverifiedEmail: true},
+			completeEmail: "colleague@example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorIs(t, tt.policy.canInviteNewUsers(), tt.inviteErr)
+			require.ErrorIs(t, tt.policy.canCompleteInvite(invite, tt.completeEmail), tt.completeErr)
+		})
+	}
+}
+
+func TestInvitePolicyMaxRoles(t *testing.T) {
+	policy := invitePolicy{linkMaxRole: org.RoleViewer, emailMaxRole: org.RoleEditor}
+
+	require.NoError(t, policy.canAssignRole(models.InviteDeliveryManual, org.RoleViewer))
+	require.NoError(t, policy.canAssignRole(models.InviteDeliveryEmail, org.RoleEditor))
+
+	err := policy.canAssignRole(models.InviteDeliveryManual, org.RoleEditor)
+	require.ErrorIs(t, err, errInviteRoleAboveMax)
+	require.Equal(t, inviteRoleError{Delivery: models.InviteDeliveryManual, Role: org.RoleEditor, MaxRole: org.RoleViewer}, err)
+	require.Equal(t, "invite.link-role-above-max", err.(inviteRoleError).Code())
+
+	err = policy.canAssignRole(models.InviteDeliveryEmail, org.RoleAdmin)
+	require.ErrorIs(t, err, errInviteRoleAboveMax)
+	require.Equal(t, "invite.email-role-above-max", err.(inviteRoleError).Code())
+
+	require.NoError(t, invitePolicy{}.canAssignRole(models.InviteDeliveryManual, org.RoleAdmin), "no maximum role by default")
+}
@@ -282,7 +282,7 @@ func (hs *HTTPServer) loginUserWithUser(user *user.User, c *models.ReqContext) e
 	}
 	c.UserToken = userToken
 
-	hs.log.Info("Successful Login", "User", user.Email)
+	hs.log.FromContext(ctx).Info("Successful Login", "User", user.Email)
 	cookies.WriteSessionCookie(c, hs.Cfg, userToken.UnhashedToken, hs.Cfg.LoginMaxLifetime)
 	return nil
 }
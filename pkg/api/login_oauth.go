@@ -235,6 +235,7 @@ func (hs *HTTPServer) OAuthLogin(ctx *models.ReqContext) {
 		hs.handleOAuthLoginErrorWithRedirect(ctx, loginInfo, err)
 		return
 	}
+	hs.completeSSOInvites(ctx, loginInfo.User)
 
 	// login
 	if err := hs.loginUserWithUser(loginInfo.User, ctx); err != nil {
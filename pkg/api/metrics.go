@@ -12,6 +12,7 @@ import (
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/services/datasources"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/onboarding"
 	"github.com/grafana/grafana/pkg/web"
 )
 
@@ -58,6 +59,7 @@ func (hs *HTTPServer) QueryMetricsV2(c *models.ReqContext) response.Response {
 	if err != nil {
 		return hs.handleQueryMetricsError(err)
 	}
+	hs.recordOnboardingEvent(c.Req.Context(), c.OrgID, c.UserID, onboarding.EventQueryRun, 0)
 	return hs.toJsonStreamingResponse(resp)
 }
 
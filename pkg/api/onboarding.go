@@ -0,0 +1,48 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/onboarding"
+)
+
+// swagger:route GET /user/onboarding signed_in_user getSignedInUserOnboarding
+//
+// Get the onboarding checklist of the actual user.
+//
+// Users who joined the current organization by accepting an invite get a checklist of their
+// first steps in it. The checklist of other users isn't active.
+//
+// Responses:
+// 200: getSignedInUserOnboardingResponse
+// 401: unauthorisedError
+// 500: internalServerError
+func (hs *HTTPServer) GetSignedInUserOnboarding(c *models.ReqContext) response.Response {
+	checklist, err := hs.onboardingService.GetChecklist(c.Req.Context(), &onboarding.GetChecklistQuery{OrgID: c.OrgID, UserID: c.UserID})
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get onboarding checklist", err)
+	}
+	return response.JSON(http.StatusOK, checklist)
+}
+
+// recordOnboardingEvent records a step of the onboarding checklist of a user. Failing to do so
+// mustn't fail the request the step was taken in.
+func (hs *HTTPServer) recordOnboardingEvent(ctx context.Context, orgID, userID int64, eventType onboarding.EventType, inviteID int64) {
+	if userID <= 0 {
+		return
+	}
+	cmd := &onboarding.RecordEventCommand{OrgID: orgID, UserID: userID, Type: eventType, InviteID: inviteID}
+	if err := hs.onboardingService.RecordEvent(ctx, cmd); err != nil {
+		hs.log.Warn("Failed to record onboarding event", "type", eventType, "orgId", orgID, "userId", userID, "error", err)
+	}
+}
+
+// swagger:response getSignedInUserOnboardingResponse
+type GetSignedInUserOnboardingResponse struct {
+	// The response message
+	// in: body
+	Body *onboarding.Checklist `json:"body"`
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/onboarding"
+	"github.com/grafana/grafana/pkg/services/onboarding/onboardingtest"
+)
+
+func TestGetSignedInUserOnboarding(t *testing.T) {
+	t.Run("returns the checklist of the user", func(t *testing.T) {
+		sc := setupHTTPServer(t, true)
+		setInitCtxSignedInUser(sc.initCtx, testEditorOrg1)
+		fake := onboardingtest.NewFakeService()
+		fake.ExpectedChecklist = &onboarding.Checklist{
+			Active: true,
+			Steps: []onboarding.ChecklistStep{
+				{Event: onboarding.EventInviteAccepted, Done: true},
+				{Event: onboarding.EventDashboardViewed},
+			},
+		}
+		sc.hs.onboardingService = fake
+
+		response := callAPI(sc.server, http.MethodGet, "/api/user/onboarding", nil, t)
+		require.Equal(t, http.StatusOK, response.Code)
+
+		var checklist onboarding.Checklist
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &checklist))
+		assert.Equal(t, *fake.ExpectedChecklist, checklist)
+	})
+
+	t.Run("fails when the checklist can't be loaded", func(t *testing.T) {
+		sc := setupHTTPServer(t, true)
+		setInitCtxSignedInUser(sc.initCtx, testEditorOrg1)
+		sc.hs.onboardingService = &onboardingtest.FakeService{ExpectedError: errors.New("boom")}
+
+		response := callAPI(sc.server, http.MethodGet, "/api/user/onboarding", nil, t)
+		assert.Equal(t, http.StatusInternalServerError, response.Code)
+	})
+}
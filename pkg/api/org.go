@@ -202,6 +202,7 @@ func (hs *HTTPServer) updateOrgHelper(ctx context.Context, form dtos.UpdateOrgFo
 		}
 		return response.Error(http.StatusInternalServerError, "Failed to update organization", err)
 	}
+	hs.tempUserService.InvalidateInviteOrgName(orgID)
 
 	return response.Success("Organization updated")
 }
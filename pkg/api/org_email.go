@@ -0,0 +1,36 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/notifications"
+	pref "github.com/grafana/grafana/pkg/services/preference"
+)
+
+// orgEmailTime returns the time for the emails of the organization, formatted in its default
+// timezone and locale. Organizations without preferences get the server defaults.
+func (hs *HTTPServer) orgEmailTime(ctx context.Context, orgID int64, t time.Time) notifications.EmailTime {
+	var timezone, locale string
+	preference, err := hs.preferenceService.GetWithDefaults(ctx, &pref.GetPreferenceWithDefaultsQuery{OrgID: orgID})
+	if err != nil {
+		hs.log.FromContext(ctx).Warn("Failed to get the preferences of the organization for an email", "orgId", orgID, "error", err)
+	} else if preference != nil {
+		timezone = preference.Timezone
+		if preference.JSONData != nil {
+			locale = preference.JSONData.Locale
+		}
+	}
+	return notifications.NewEmailTime(t, timezone, locale)
+}
+
+// inviteExpiry returns when the invite with the given code expires, see
+// setting.Cfg.UserInviteMaxLifetime. ok is false when the invite isn't found.
+func (hs *HTTPServer) inviteExpiry(ctx context.Context, code string) (expires time.Time, ok bool) {
+	query := models.GetTempUserByCodeQuery{Code: code}
+	if err := hs.tempUserService.GetTempUserByCode(ctx, &query); err != nil || query.Result == nil {
+		return time.Time{}, false
+	}
+	return query.Result.Created.Add(hs.Cfg.UserInviteMaxLifetime), true
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/ngalert"
+	"github.com/grafana/grafana/pkg/services/notifications"
+	pref "github.com/grafana/grafana/pkg/services/preference"
+	"github.com/grafana/grafana/pkg/services/preference/preftest"
+	"github.com/grafana/grafana/pkg/services/user/usertest"
+)
+
+func TestInviteEmailExpiry(t *testing.T) {
+	sc, stack := setupInviteHTTPServer(t)
+	sc.hs.Cfg.UserInviteMaxLifetime = 24 * time.Hour
+	sc.hs.kvStore = kvstore.ProvideService(sc.db)
+	sc.hs.userService = &slackInviteUserService{&usertest.FakeUserService{}}
+	sc.hs.preferenceService = &preftest.FakePreferenceService{ExpectedPreference: &pref.Preference{
+		Timezone: "Europe/Berlin",
+		JSONData: &pref.PreferenceJSONData{Locale: "de-DE"},
+	}}
+	sc.hs.AlertNG = &ngalert.AlertNG{NotificationService: stack.Mail}
+	setInitCtxSignedInOrgAdmin(sc.initCtx)
+	setAccessControlPermissions(sc.acmock, []accesscontrol.Permission{
+		{Action: accesscontrol.ActionOrgUsersAdd, Scope: accesscontrol.ScopeUsersAll},
+	}, sc.initCtx.OrgID)
+
+	response := callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(`{"loginOrEmail": "new.hire@example.com", "role": "Viewer", "sendEmail": true}`), t)
+	require.Equal(t, http.StatusOK, response.Code)
+
+	emails := stack.Mail.SentTo("new.hire@example.com")
+	require.Len(t, emails, 1)
+	expires, ok := emails[0].Data["InviteExpires"].(notifications.EmailTime)
+	require.True(t, ok, "invite emails carry the expiry of the invite")
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), expires.Time, time.Minute)
+	assert.Equal(t, "Europe/Berlin", expires.Location.String())
+	assert.Equal(t, "de-DE", expires.Locale)
+}
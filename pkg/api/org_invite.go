@@ -4,8 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/grafana/grafana/pkg/api/dtos"
 	"github.com/grafana/grafana/pkg/api/response"
@@ -13,9 +16,13 @@ import (
 	"github.com/grafana/grafana/pkg/infra/metrics"
 	"github.com/grafana/grafana/pkg/models"
 	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/notifications"
+	"github.com/grafana/grafana/pkg/services/onboarding"
+	"github.com/grafana/grafana/pkg/services/org"
 	"github.com/grafana/grafana/pkg/services/user"
 	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/util"
+	"github.com/grafana/grafana/pkg/util/proxyutil"
 	"github.com/grafana/grafana/pkg/web"
 )
 
@@ -23,46 +30,229 @@ import (
 //
 // Get pending invites.
 //
+// Invites can be filtered by how they are delivered with the `delivery` parameter, and with the
+// `invitedBy` parameter by whether they were created by automation, with an API key or by a
+// service account, or by users.
+// With the `invites:self` scope only the invites created by the signed in user are listed.
+//
+// The `ETag` header of the response changes whenever an invite of the list changes. When the
+// `If-None-Match` header matches it, 304 is returned without the invites.
+//
 // Responses:
 // 200: getPendingOrgInvitesResponse
+// 304: notModifiedResponse
+// 400: badRequestError
 // 401: unauthorisedError
 // 403: forbiddenError
 // 500: internalServerError
 func (hs *HTTPServer) GetPendingOrgInvites(c *models.ReqContext) response.Response {
-	query := models.GetTempUsersQuery{OrgId: c.OrgID, Status: models.TmpUserInvitePending}
+	delivery := models.InviteDelivery(c.Query("delivery"))
+	if delivery != "" && !delivery.IsValid() {
+		return response.Error(http.StatusBadRequest, "Invalid delivery", nil)
+	}
+	invitedBy := models.InviteCreator(c.Query("invitedBy"))
+	if invitedBy != "" && !invitedBy.IsValid() {
+		return response.Error(http.StatusBadRequest, "Invalid invitedBy, must be automation or user", nil)
+	}
+
+	query := models.GetTempUsersQuery{OrgId: c.OrgID, Status: models.TmpUserInvitePending, Delivery: delivery, InvitedBy: invitedBy}
+
+	canRead, err := hs.filterReadableInvites(c, &query)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to evaluate invite permissions", err)
+	}
+	if !canRead {
+		return response.Error(http.StatusForbidden, "Permission denied", nil)
+	}
 
 	if err := hs.tempUserService.GetTempUsersQuery(c.Req.Context(), &query); err != nil {
 		return response.Error(500, "Failed to get invites from db", err)
 	}
 
+	etag := invitesETag(query.Result)
+	if rsp := inviteNotModified(c, etag); rsp != nil {
+		return rsp
+	}
+
 	for _, invite := range query.Result {
 		invite.Url = setting.ToAbsUrl("invite/" + invite.Code)
 	}
 
-	return response.JSON(http.StatusOK, query.Result)
+	return response.JSON(http.StatusOK, query.Result).
+		SetHeader("ETag", etag).
+		SetHeader("Cache-Control", inviteCacheControl)
+}
+
+// filterReadableInvites restricts the query to the invites the user can read with the scope of
+// their org.invites:read permission, all of them or only the ones they created. It returns false
+// when the user can't read any invite.
+func (hs *HTTPServer) filterReadableInvites(c *models.ReqContext, query *models.GetTempUsersQuery) (bool, error) {
+	if hs.AccessControl.IsDisabled() {
+		return true, nil
+	}
+
+	canReadAll, err := hs.AccessControl.Evaluate(c.Req.Context(), c.SignedInUser, ac.EvalPermission(ac.ActionOrgInvitesRead, ac.ScopeInvitesAll))
+	if err != nil || canReadAll {
+		return canReadAll, err
+	}
+
+	canReadOwn, err := hs.AccessControl.Evaluate(c.Req.Context(), c.SignedInUser, ac.EvalPermission(ac.ActionOrgInvitesRead, ac.ScopeInvitesSelf))
+	if err != nil || !canReadOwn {
+		return false, err
+	}
+	query.InvitedByUserID = c.UserID
+	return true, nil
+}
+
+// swagger:route GET /org/invites/health org_invites getOrgInvitesHealth
+//
+// Get the health of the invite system.
+//
+// Reports SMTP connectivity, the number of pending invites in the current organization,
+// the depth of the email queue and when an email was last sent successfully.
+// The SMTP connectivity probe is cached for a minute.
+//
+// Responses:
+// 200: getOrgInvitesHealthResponse
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (hs *HTTPServer) GetOrgInvitesHealth(c *models.ReqContext) response.Response {
+	query := models.SearchTempUsersQuery{OrgID: c.OrgID, Status: models.TmpUserInvitePending, CountOnly: true}
+	if err := hs.tempUserService.SearchTempUsers(c.Req.Context(), &query); err != nil {
+		return response.Error(500, "Failed to get invites from db", err)
+	}
+
+	smtpStatus := hs.NotificationService.SMTPStatus()
+	health := dtos.InvitesHealth{
+		SMTP: dtos.InvitesSMTPHealth{
+			Enabled:   smtpStatus.Enabled,
+			Connected: smtpStatus.Connected,
+			Error:     smtpStatus.Error,
+			CheckedAt: smtpStatus.CheckedAt,
+		},
+		PendingInvites:  int(query.Result.TotalCount),
+		EmailQueueDepth: hs.NotificationService.MailQueueDepth(),
+	}
+
+	if lastSent := hs.NotificationService.LastEmailSentAt(); !lastSent.IsZero() {
+		health.LastEmailSentAt = &lastSent
+	}
+
+	return response.JSON(http.StatusOK, health)
+}
+
+// swagger:route POST /org/invites/test-email org_invites sendTestOrgInviteEmail
+//
+// Send a test invite email.
+//
+// Sends an invite email with a placeholder link to the signed in user and returns how the SMTP
+// server handled it, together with warnings when the DNS records of the sender domain likely
+// make receivers reject or quarantine the email.
+//
+// Responses:
+// 200: sendTestOrgInviteEmailResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 412: SMTPNotEnabledError
+// 500: internalServerError
+func (hs *HTTPServer) SendTestOrgInviteEmail(c *models.ReqContext) response.Response {
+	if !util.IsEmail(c.Email) {
+		return response.Error(http.StatusBadRequest, "The signed in user has no email address", nil)
+	}
+
+	cmd := models.SendEmailCommand{
+		To:        []string{c.Email},
+		Template:  "new_user_invite",
+		Multipart: true,
+		Data: map[string]interface{}{
+			"Name":      util.StringsFallback2(c.Name, c.Email),
+			"OrgName":   c.OrgName,
+			"Email":     c.Email,
+			"LinkUrl":   setting.ToAbsUrl("invite/test"),
+			"InvitedBy": util.StringsFallback3(c.Name, c.Email, c.Login),
+			// as for an invite created now
+			"InviteExpires": hs.orgEmailTime(c.Req.Context(), c.OrgID, time.Now().Add(hs.Cfg.UserInviteMaxLifetime)),
+		},
+	}
+
+	result, err := hs.NotificationService.SendTestEmail(c.Req.Context(), &cmd)
+	if err != nil {
+		if errors.Is(err, models.ErrSmtpNotEnabled) {
+			return response.Error(412, err.Error(), err)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to send test email", err)
+	}
+
+	return response.JSON(http.StatusOK, result)
 }
 
 // swagger:route POST /org/invites org_invites addOrgInvite
 //
 // Add invite.
 //
+// Requests can carry an `Idempotency-Key` header. Retrying a request with the same key
+// within 24 hours returns the original response without creating another invite or
+// sending another email, and fails with 409 while the original request is in progress.
+// Keys are per user or API key. Requests with a key must not be larger than 1 MiB.
+//
+// When the email backend is saturated the invite is saved and its email queued rather than
+// sent, and the response is 202 with the position of the email in the queue and a
+// `Retry-After` header. The delivery can be followed with the delivery endpoint of the invite.
+// Queued emails are sent after a restart as well. When too many emails are already queued the
+// request fails with 503.
+//
+// The role and the teams default to the invite defaults of the organization, set with the
+// `invites` org preference. Giving teams requires the permission to add members to them.
+//
+// Grafana admins can invite to other organizations with the same invite, listing them with the role
+// of the invitee in each in `orgs`. Accepting the invite joins all the organizations or none.
+//
+// Inviting a member of the organization fails with 412 and the current role of the member, unless
+// the invite lowers it and `allowDowngrade` is set. The role of the member is then changed, which
+// requires the permission to change the role of organization users.
+//
+// Invites aren't emailed to addresses on the email suppression list, asking to send the email
+// fails with 412.
+//
+// The invites an organization and an inviter can create per hour, or another window, can be limited
+// with the `invite_rate_limit_per_org` and `invite_rate_limit_per_inviter` settings. Exceeding a
+// limit fails with 429 and a `Retry-After` header telling when more invites can be created.
+// Retries with an `Idempotency-Key` aren't counted.
+//
 // Responses:
 // 200: okResponse
+// 202: addOrgInviteQueuedResponse
 // 400: badRequestError
 // 401: unauthorisedError
 // 403: forbiddenError
+// 409: conflictError
 // 412: SMTPNotEnabledError
+// 422: unprocessableEntityError
+// 429: tooManyRequestsError
 // 500: internalServerError
+// 503: serviceUnavailableError
 func (hs *HTTPServer) AddOrgInvite(c *models.ReqContext) response.Response {
+	return hs.withInviteDuplicateSubmission(c, func(c *models.ReqContext) response.Response {
+		return hs.withIdempotencyKey(c, "org-invite", func(c *models.ReqContext) response.Response {
+			return hs.withInviteRateLimit(c, hs.addOrgInvite)
+		})
+	})
+}
+
+func (hs *HTTPServer) addOrgInvite(c *models.ReqContext) response.Response {
 	inviteDto := dtos.AddInviteForm{}
 	if err := web.Bind(c.Req, &inviteDto); err != nil {
 		return response.Error(http.StatusBadRequest, "bad request data", err)
 	}
-	if !inviteDto.Role.IsValid() {
-		return response.Error(400, "Invalid role specified", nil)
-	}
-	if !c.OrgRole.Includes(inviteDto.Role) && !c.IsGrafanaAdmin {
-		return response.Error(http.StatusForbidden, "Cannot assign a role higher than user's role", nil)
+	return hs.createOrgInvite(c, inviteDto)
+}
+
+// createOrgInvite invites the user of the form to the organization, or adds them to it.
+func (hs *HTTPServer) createOrgInvite(c *models.ReqContext, inviteDto dtos.AddInviteForm) response.Response {
+	if rsp := hs.checkInviteForm(c, &inviteDto); rsp != nil {
+		return rsp
 	}
 
 	// first try get existing user
@@ -85,101 +275,693 @@ func (hs *HTTPServer) AddOrgInvite(c *models.ReqContext) response.Response {
 		return hs.inviteExistingUserToOrg(c, usr, &inviteDto)
 	}
 
-	if setting.DisableLoginForm {
-		return response.Error(400, "Cannot invite when login is disabled.", nil)
+	if err := hs.invitePolicy(c.Req.Context(), c.OrgID, inviteDto.LoginOrEmail).canInviteNewUsers(); err != nil {
+		return response.Error(http.StatusBadRequest, err.Error(), nil)
+	}
+
+	if inviteDto.SendEmail && util.IsEmail(inviteDto.LoginOrEmail) {
+		if rsp := hs.checkEmailSuppression(c.Req.Context(), inviteDto.LoginOrEmail); rsp != nil {
+			return rsp
+		}
+	}
+
+	cmd, rsp := hs.createNewUserInvite(c, &inviteDto)
+	if rsp != nil {
+		return rsp
+	}
+
+	// send invite email
+	if inviteDto.SendEmail && util.IsEmail(inviteDto.LoginOrEmail) {
+		queued, rsp := hs.sendNewUserInviteEmail(c, cmd.Email, cmd.Name, cmd.Code)
+		if rsp != nil {
+			return rsp
+		}
+		if queued != nil {
+			return queuedInviteResponse(fmt.Sprintf("Queued invite to %s", inviteDto.LoginOrEmail), cmd.Result.Id, 0, queued)
+		}
+
+		return response.Success(fmt.Sprintf("Sent invite to %s", inviteDto.LoginOrEmail))
+	}
+
+	if inviteDto.Delivery == models.InviteDeliveryManual {
+		link := newInviteLink(cmd.Result.Id, cmd.Result.Email, cmd.Result.Code)
+		link.Message = fmt.Sprintf("Created invite for %s", inviteDto.LoginOrEmail)
+		return response.JSON(http.StatusOK, link)
+	}
+
+	return response.Success(fmt.Sprintf("Created invite for %s", inviteDto.LoginOrEmail))
+}
+
+// checkInviteForm applies the invite defaults of the organization to the form and checks it,
+// including that the signed in user can assign its role. It returns an error response when the
+// invite can't be created.
+func (hs *HTTPServer) checkInviteForm(c *models.ReqContext, inviteDto *dtos.AddInviteForm) response.Response {
+	if rsp := hs.applyInviteDefaults(c, inviteDto); rsp != nil {
+		return rsp
+	}
+	if !inviteDto.Role.IsValid() {
+		return response.Error(400, "Invalid role specified", nil)
+	}
+	if inviteDto.Delivery == "" {
+		inviteDto.Delivery = models.InviteDeliveryEmail
+	}
+	if !inviteDto.Delivery.IsValid() {
+		return response.Error(http.StatusBadRequest, "Invalid delivery specified", nil)
+	}
+	if inviteDto.EmailMatch == "" {
+		inviteDto.EmailMatch = models.InviteEmailMatchAny
+	}
+	if !inviteDto.EmailMatch.IsValid() {
+		return response.Error(http.StatusBadRequest, "Invalid email match specified", nil)
+	}
+	if inviteDto.Delivery == models.InviteDeliveryManual && inviteDto.SendEmail {
+		return response.Error(http.StatusBadRequest, "Manually delivered invites can't be emailed", nil)
+	}
+	inviteDto.ExternalID = strings.TrimSpace(inviteDto.ExternalID)
+	if len(inviteDto.ExternalID) > maxInviteExternalIDLength {
+		return response.Error(http.StatusBadRequest, fmt.Sprintf("The external ID can't be longer than %d characters", maxInviteExternalIDLength), nil)
+	}
+	if !c.OrgRole.Includes(inviteDto.Role) && !c.IsGrafanaAdmin {
+		return response.Error(http.StatusForbidden, "Cannot assign a role higher than user's role", nil)
+	}
+	if rsp := hs.checkInviteRole(inviteDto.Delivery, inviteDto.Role); rsp != nil {
+		return rsp
+	}
+	if inviteDto.SendEmail && hs.inviteEmailBacklogFull() {
+		return mailBacklogFullResponse(nil)
+	}
+	if rsp := hs.checkInviteOrgs(c, inviteDto); rsp != nil {
+		return rsp
+	}
+	return hs.checkAssignableRole(c, inviteDto.Role)
+}
+
+// checkInviteRole returns an error response, with the code of the error, when the role is above the
+// maximum role of invites of the delivery. It returns nil when it isn't.
+func (hs *HTTPServer) checkInviteRole(delivery models.InviteDelivery, role org.RoleType) response.Response {
+	var roleErr inviteRoleError
+	if err := newInvitePolicy(hs.Cfg).canAssignRole(delivery, role); errors.As(err, &roleErr) {
+		return response.JSON(http.StatusBadRequest, util.DynMap{
+			"message": roleErr.Error(),
+			"code":    roleErr.Code(),
+			"maxRole": roleErr.MaxRole,
+		})
+	}
+	return nil
+}
+
+// checkAssignableRole returns an error response listing the roles which can be assigned in the
+// organization when role can't, e.g. because of role restrictions. It returns nil when it can.
+func (hs *HTTPServer) checkAssignableRole(c *models.ReqContext, role org.RoleType) response.Response {
+	assignable, allowed, err := hs.isAssignableRole(c.Req.Context(), c.OrgID, role)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get the assignable roles", err)
+	}
+	if !assignable {
+		return response.JSON(http.StatusBadRequest, util.DynMap{
+			"message":      fmt.Sprintf("The %s role can't be assigned in this organization", role),
+			"allowedRoles": allowed,
+		})
+	}
+	return nil
+}
+
+// isAssignableRole tells whether role can be assigned in the organization, and if not which roles can.
+func (hs *HTTPServer) isAssignableRole(ctx context.Context, orgID int64, role org.RoleType) (bool, []org.RoleType, error) {
+	allowed, err := hs.roleRestrictions.GetAssignableBasicRoles(ctx, orgID)
+	if err != nil {
+		return false, nil, err
+	}
+	if allowed == nil {
+		return true, nil, nil
+	}
+	for _, r := range allowed {
+		if r == role {
+			return true, allowed, nil
+		}
 	}
+	return false, allowed, nil
+}
 
+// createNewUserInvite creates the invite of a user that does not have an account yet.
+func (hs *HTTPServer) createNewUserInvite(c *models.ReqContext, inviteDto *dtos.AddInviteForm) (*models.CreateTempUserCommand, response.Response) {
 	cmd := models.CreateTempUserCommand{}
 	cmd.OrgId = c.OrgID
 	cmd.Email = inviteDto.LoginOrEmail
 	cmd.Name = inviteDto.Name
 	cmd.Status = models.TmpUserInvitePending
 	cmd.InvitedByUserId = c.UserID
-	cmd.Code, err = util.GetRandomString(30)
+	cmd.InvitedByApiKeyId = c.ApiKeyID
+	var err error
+	cmd.Code, err = hs.inviteFaults.newCode()
 	if err != nil {
-		return response.Error(500, "Could not generate random string", err)
+		return nil, response.Error(500, "Could not generate random string", err)
 	}
 	cmd.Role = inviteDto.Role
 	cmd.RemoteAddr = c.Req.RemoteAddr
+	cmd.Delivery = inviteDto.Delivery
+	cmd.EmailMatch = inviteDto.EmailMatch
+	cmd.UniquePending = hs.Cfg.UniquePendingInvites
+	cmd.AccessExpires = inviteDto.AccessExpires
+	cmd.ExternalId = inviteDto.ExternalID
 
-	if err := hs.tempUserService.CreateTempUser(c.Req.Context(), &cmd); err != nil {
+	if rsp := hs.saveInvite(c.Req.Context(), &cmd, inviteDto, fmt.Sprintf("%s has already been invited to organization", inviteDto.LoginOrEmail)); rsp != nil {
+		return nil, rsp
+	}
+	return &cmd, nil
+}
+
+// errInviteNotSaved rolls back an invite whose teams or organizations couldn't be saved.
+var errInviteNotSaved = errors.New("invite not saved")
+
+// saveInvite creates the invite with its teams and other organizations in a transaction, so that
+// an invite failing to save doesn't remain pending and block the next attempts. pendingExists is
+// the message of the response when the invitee has a pending invite already.
+func (hs *HTTPServer) saveInvite(ctx context.Context, cmd *models.CreateTempUserCommand, inviteDto *dtos.AddInviteForm, pendingExists string) response.Response {
+	var rsp response.Response
+	err := hs.SQLStore.InTransaction(ctx, func(ctx context.Context) error {
+		if err := hs.tempUserService.CreateTempUser(ctx, cmd); err != nil {
+			return err
+		}
+		if rsp = hs.addInviteTeams(ctx, cmd.OrgId, cmd.Result.Id, inviteDto.Teams); rsp != nil {
+			return errInviteNotSaved
+		}
+		if rsp = hs.addInviteOrgs(ctx, cmd.OrgId, cmd.Result.Id, inviteDto.Orgs); rsp != nil {
+			return errInviteNotSaved
+		}
+		return nil
+	})
+	switch {
+	case rsp != nil:
+		return rsp
+	case errors.Is(err, models.ErrTempUserPendingExists):
+		return response.Error(412, pendingExists, err)
+	case err != nil:
 		return response.Error(500, "Failed to save invite to database", err)
 	}
+	return nil
+}
 
-	// send invite email
-	if inviteDto.SendEmail && util.IsEmail(inviteDto.LoginOrEmail) {
-		emailCmd := models.SendEmailCommand{
-			To:       []string{inviteDto.LoginOrEmail},
-			Template: "new_user_invite",
-			Data: map[string]interface{}{
-				"Name":      util.StringsFallback2(cmd.Name, cmd.Email),
-				"OrgName":   c.OrgName,
-				"Email":     c.Email,
-				"LinkUrl":   setting.ToAbsUrl("invite/" + cmd.Code),
-				"InvitedBy": util.StringsFallback3(c.Name, c.Email, c.Login),
-			},
-		}
-
-		if err := hs.AlertNG.NotificationService.SendEmailCommandHandler(c.Req.Context(), &emailCmd); err != nil {
-			if errors.Is(err, models.ErrSmtpNotEnabled) {
-				return response.Error(412, err.Error(), err)
-			}
+// sendNewUserInviteEmail sends the email of the invite with the given code and
+// records that it was sent. When the email backend is saturated the email is queued
+// instead, and its delivery is returned. Organizations with an invite contact point
+// get the invite there instead of emailing it. It returns a response on failure.
+func (hs *HTTPServer) sendNewUserInviteEmail(c *models.ReqContext, email, name, code string) (*notifications.EmailDelivery, response.Response) {
+	emailCmd := hs.newUserInviteEmail(c.Req.Context(), requestInviteEmailSender(c), email, name, code)
+
+	if rsp, sent := hs.sendInviteToOrgContactPoint(c, &emailCmd, code); sent {
+		return nil, rsp
+	}
 
-			return response.Error(500, "Failed to send email invite", err)
+	if hs.inviteEmailBackendSaturated() {
+		return hs.queueInviteEmail(c, &emailCmd, code)
+	}
+
+	if err := hs.sendInviteEmailCommand(c.Req.Context(), &emailCmd); err != nil {
+		if errors.Is(err, models.ErrSmtpNotEnabled) {
+			return nil, response.Error(412, err.Error(), err)
 		}
 
-		emailSentCmd := models.UpdateTempUserWithEmailSentCommand{Code: cmd.Result.Code}
-		if err := hs.tempUserService.UpdateTempUserWithEmailSent(c.Req.Context(), &emailSentCmd); err != nil {
-			return response.Error(500, "Failed to update invite with email sent info", err)
+		return nil, response.Error(500, "Failed to send email invite", err)
+	}
+
+	emailSentCmd := models.UpdateTempUserWithEmailSentCommand{Code: code}
+	if err := hs.tempUserService.UpdateTempUserWithEmailSent(c.Req.Context(), &emailSentCmd); err != nil {
+		return nil, response.Error(500, "Failed to update invite with email sent info", err)
+	}
+
+	return nil, nil
+}
+
+// sendExistingUserInviteEmail tells an existing user about the invite with the given code and
+// records that it was sent. Like sendNewUserInviteEmail, it queues the email when the email
+// backend is saturated.
+func (hs *HTTPServer) sendExistingUserInviteEmail(c *models.ReqContext, user *user.User, code string) (*notifications.EmailDelivery, response.Response) {
+	emailCmd := existingUserInviteEmail(requestInviteEmailSender(c), user)
+
+	if rsp, sent := hs.sendInviteToOrgContactPoint(c, &emailCmd, code); sent {
+		return nil, rsp
+	}
+
+	if hs.inviteEmailBackendSaturated() {
+		return hs.queueInviteEmail(c, &emailCmd, code)
+	}
+
+	if err := hs.sendInviteEmailCommand(c.Req.Context(), &emailCmd); err != nil {
+		return nil, response.Error(500, "Failed to send email invited_to_org", err)
+	}
+
+	emailSentCmd := models.UpdateTempUserWithEmailSentCommand{Code: code}
+	if err := hs.tempUserService.UpdateTempUserWithEmailSent(c.Req.Context(), &emailSentCmd); err != nil {
+		return nil, response.Error(500, "Failed to update invite with email sent info", err)
+	}
+
+	return nil, nil
+}
+
+// inviteEmailSender is the organization and the inviter the email of an invite is sent on behalf of.
+type inviteEmailSender struct {
+	orgID        int64
+	orgName      string
+	inviterEmail string
+	invitedBy    string
+}
+
+// requestInviteEmailSender returns the sender of the emails of invites created by the signed in user.
+func requestInviteEmailSender(c *models.ReqContext) inviteEmailSender {
+	return inviteEmailSender{
+		orgID:        c.OrgID,
+		orgName:      c.OrgName,
+		inviterEmail: c.Email,
+		invitedBy:    util.StringsFallback3(c.Name, c.Email, c.Login),
+	}
+}
+
+// newUserInviteEmail returns the email of the invite with the given code for an invitee without
+// a Grafana account.
+func (hs *HTTPServer) newUserInviteEmail(ctx context.Context, sender inviteEmailSender, email, name, code string) models.SendEmailCommand {
+	emailCmd := models.SendEmailCommand{
+		To:        []string{email},
+		Template:  "new_user_invite",
+		Multipart: true,
+		Data: map[string]interface{}{
+			"Name":      util.StringsFallback2(name, email),
+			"OrgName":   sender.orgName,
+			"Email":     sender.inviterEmail,
+			"LinkUrl":   setting.ToAbsUrl("invite/" + code),
+			"InvitedBy": sender.invitedBy,
+		},
+		AttachedFiles: hs.inviteOnboardingAttachments(ctx, sender.orgID, code),
+	}
+	if expires, ok := hs.inviteExpiry(ctx, code); ok {
+		emailCmd.Data["InviteExpires"] = hs.orgEmailTime(ctx, sender.orgID, expires)
+	}
+	return emailCmd
+}
+
+// existingUserInviteEmail returns the email telling an existing user about an invite.
+func existingUserInviteEmail(sender inviteEmailSender, user *user.User) models.SendEmailCommand {
+	return models.SendEmailCommand{
+		To:        []string{user.Email},
+		Template:  "invited_to_org",
+		Multipart: true,
+		Data: map[string]interface{}{
+			"Name":      user.NameOrFallback(),
+			"OrgName":   sender.orgName,
+			"InvitedBy": sender.invitedBy,
+		},
+	}
+}
+
+// sendInviteEmailCommand sends the email of an invite, unless the invite testing mode fails it.
+func (hs *HTTPServer) sendInviteEmailCommand(ctx context.Context, emailCmd *models.SendEmailCommand) error {
+	if err := hs.inviteFaults.smtpFailure(); err != nil {
+		return err
+	}
+	return hs.AlertNG.NotificationService.SendEmailCommandHandler(ctx, emailCmd)
+}
+
+func (hs *HTTPServer) inviteEmailBackendSaturated() bool {
+	return hs.NotificationService != nil && hs.NotificationService.MailQueueSaturated()
+}
+
+func (hs *HTTPServer) inviteEmailBacklogFull() bool {
+	return hs.NotificationService != nil && hs.NotificationService.MailBacklogFull()
+}
+
+// mailBacklogFullResponse tells that the email of an invite can't be queued before other emails are sent.
+func mailBacklogFullResponse(err error) response.Response {
+	return response.Error(http.StatusServiceUnavailable, "Too many emails are waiting to be sent, try again later", err)
+}
+
+// queueInviteEmail queues the email of an invite behind the emails waiting to be sent, rather than
+// blocking the request until the mail queue has room. The invite is recorded as emailed once the
+// email is actually sent.
+func (hs *HTTPServer) queueInviteEmail(c *models.ReqContext, emailCmd *models.SendEmailCommand, code string) (*notifications.EmailDelivery, response.Response) {
+	delivery, err := hs.queueInviteEmailCommand(c.Req.Context(), emailCmd, code)
+	switch {
+	case errors.Is(err, models.ErrSmtpNotEnabled):
+		return nil, response.Error(412, err.Error(), err)
+	case errors.Is(err, notifications.ErrMailBacklogFull):
+		return nil, mailBacklogFullResponse(err)
+	case err != nil:
+		return nil, response.Error(500, "Failed to queue email invite", err)
+	}
+	return &delivery, nil
+}
+
+// queueInviteEmailCommand queues the email of an invite. As the mail backlog is kept in memory,
+// the invite is recorded as queued first, so that its email is queued again by
+// requeueInviteEmails if Grafana restarts before it is sent.
+func (hs *HTTPServer) queueInviteEmailCommand(ctx context.Context, emailCmd *models.SendEmailCommand, code string) (notifications.EmailDelivery, error) {
+	if err := hs.tempUserService.MarkTempUserEmailQueued(ctx, &models.MarkTempUserEmailQueuedCommand{Code: code}); err != nil {
+		return notifications.EmailDelivery{}, err
+	}
+	logger := hs.log.FromContext(ctx)
+	return hs.NotificationService.QueueEmail(ctx, emailCmd, inviteEmailKey(code), func(err error) {
+		if err != nil {
+			logger.Warn("Failed to send queued invite email", "error", err)
+			return
+		}
+		emailSentCmd := models.UpdateTempUserWithEmailSentCommand{Code: code}
+		if err := hs.tempUserService.UpdateTempUserWithEmailSent(context.Background(), &emailSentCmd); err != nil {
+			logger.Error("Failed to update invite with email sent info", "error", err)
 		}
+	})
+}
 
-		return response.Success(fmt.Sprintf("Sent invite to %s", inviteDto.LoginOrEmail))
+// requeueInviteEmails queues the emails of the pending invites which were queued but not sent
+// before Grafana restarted. Invites are emailed as if they were created now by their inviter.
+func (hs *HTTPServer) requeueInviteEmails(ctx context.Context) {
+	if hs.NotificationService == nil {
+		return
+	}
+	logger := hs.log.FromContext(ctx)
+	query := models.GetTempUsersQuery{Status: models.TmpUserInvitePending, Delivery: models.InviteDeliveryEmail, EmailQueued: true}
+	if err := hs.tempUserService.GetTempUsersQuery(ctx, &query); err != nil {
+		logger.Error("Failed to get the invites with queued emails", "error", err)
+		return
 	}
 
-	return response.Success(fmt.Sprintf("Created invite for %s", inviteDto.LoginOrEmail))
+	for _, invite := range query.Result {
+		sender := inviteEmailSender{
+			orgID:        invite.OrgId,
+			orgName:      invite.OrgName,
+			inviterEmail: invite.InvitedByEmail,
+			invitedBy:    util.StringsFallback3(invite.InvitedByName, invite.InvitedByEmail, invite.InvitedByLogin),
+		}
+		var emailCmd models.SendEmailCommand
+		usr, err := hs.userService.GetByLogin(ctx, &user.GetUserByLoginQuery{LoginOrEmail: invite.Email})
+		switch {
+		case err == nil:
+			emailCmd = existingUserInviteEmail(sender, usr)
+		case errors.Is(err, user.ErrUserNotFound):
+			emailCmd = hs.newUserInviteEmail(ctx, sender, invite.Email, invite.Name, invite.Code)
+		default:
+			logger.Error("Failed to get the invitee of a queued invite email", "inviteId", invite.Id, "error", err)
+			continue
+		}
+		if _, err := hs.queueInviteEmailCommand(ctx, &emailCmd, invite.Code); err != nil {
+			logger.Error("Failed to queue invite email again", "inviteId", invite.Id, "error", err)
+		}
+	}
+	if len(query.Result) > 0 {
+		logger.Info("Queued the invite emails left unsent", "count", len(query.Result))
+	}
+}
+
+// inviteEmailKey identifies the queued email of an invite, by code since a new code is generated
+// when an invite is sent to another address.
+func inviteEmailKey(code string) string {
+	return "invite:" + code
+}
+
+// queuedInviteResponse tells that an invite was saved and its email queued, with a Retry-After
+// header set to when the email is expected to be sent.
+func queuedInviteResponse(message string, inviteID, userID int64, delivery *notifications.EmailDelivery) response.Response {
+	return response.JSON(http.StatusAccepted, dtos.QueuedInvite{
+		Message:           message,
+		InviteID:          inviteID,
+		UserID:            userID,
+		QueuePosition:     delivery.Position,
+		EstimatedDelivery: delivery.EstimatedDelivery,
+	}).SetHeader("Retry-After", retryAfterSeconds(delivery.EstimatedDelivery))
+}
+
+func retryAfterSeconds(at time.Time) string {
+	seconds := int64(math.Ceil(time.Until(at).Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.FormatInt(seconds, 10)
 }
 
 func (hs *HTTPServer) inviteExistingUserToOrg(c *models.ReqContext, user *user.User, inviteDto *dtos.AddInviteForm) response.Response {
-	// user exists, add org role
-	createOrgUserCmd := models.AddOrgUserCommand{OrgId: c.OrgID, UserId: user.ID, Role: inviteDto.Role}
-	if err := hs.SQLStore.AddOrgUser(c.Req.Context(), &createOrgUserCmd); err != nil {
-		if errors.Is(err, models.ErrOrgUserAlreadyAdded) {
-			return response.Error(412, fmt.Sprintf("User %s is already added to organization", inviteDto.LoginOrEmail), err)
+	orgsQuery := models.GetUserOrgListQuery{UserId: user.ID}
+	if err := hs.SQLStore.GetUserOrgList(c.Req.Context(), &orgsQuery); err != nil {
+		return response.Error(500, "Failed to get user organizations", err)
+	}
+	for _, userOrg := range orgsQuery.Result {
+		if userOrg.OrgId == c.OrgID {
+			return hs.inviteExistingMember(c, user, userOrg.Role, inviteDto)
 		}
-		return response.Error(500, "Error while trying to create org user", err)
 	}
 
+	// existing users accept or decline the invite from within Grafana
+	inviteEmail := util.StringsFallback3(user.Email, user.Login, inviteDto.LoginOrEmail)
+	pendingQuery := models.GetTempUsersQuery{OrgId: c.OrgID, Email: inviteEmail, Status: models.TmpUserInvitePending}
+	if err := hs.tempUserService.GetTempUsersQuery(c.Req.Context(), &pendingQuery); err != nil {
+		return response.Error(500, "Failed to get invites from db", err)
+	}
+	if len(pendingQuery.Result) > 0 {
+		return response.Error(412, fmt.Sprintf("User %s has already been invited to organization", inviteDto.LoginOrEmail), nil)
+	}
 	if inviteDto.SendEmail && util.IsEmail(user.Email) {
-		emailCmd := models.SendEmailCommand{
-			To:       []string{user.Email},
-			Template: "invited_to_org",
-			Data: map[string]interface{}{
-				"Name":      user.NameOrFallback(),
-				"OrgName":   c.OrgName,
-				"InvitedBy": util.StringsFallback3(c.Name, c.Email, c.Login),
-			},
+		if rsp := hs.checkEmailSuppression(c.Req.Context(), user.Email); rsp != nil {
+			return rsp
 		}
+	}
+
+	cmd := models.CreateTempUserCommand{
+		OrgId:             c.OrgID,
+		Email:             inviteEmail,
+		Name:              user.Name,
+		Status:            models.TmpUserInvitePending,
+		InvitedByUserId:   c.UserID,
+		InvitedByApiKeyId: c.ApiKeyID,
+		Role:              inviteDto.Role,
+		RemoteAddr:        c.Req.RemoteAddr,
+		Delivery:          inviteDto.Delivery,
+		EmailMatch:        inviteDto.EmailMatch,
+		ExternalId:        inviteDto.ExternalID,
+		// guards against concurrent invites passing the check above
+		UniquePending: true,
+	}
+	var err error
+	cmd.Code, err = hs.inviteFaults.newCode()
+	if err != nil {
+		return response.Error(500, "Could not generate random string", err)
+	}
+	if rsp := hs.saveInvite(c.Req.Context(), &cmd, inviteDto, fmt.Sprintf("User %s has already been invited to organization", inviteDto.LoginOrEmail)); rsp != nil {
+		return rsp
+	}
+
+	// users of verified domains with auto-approval join right away, there is nothing to accept
+	approved, rsp := hs.autoApproveInvite(c, user, cmd.Result.Code)
+	if rsp != nil {
+		return rsp
+	}
+	if approved {
+		return response.JSON(http.StatusOK, util.DynMap{
+			"message": fmt.Sprintf("Existing Grafana user %s added to org %s", user.NameOrFallback(), c.OrgName),
+			"userId":  user.ID,
+		})
+	}
 
-		if err := hs.AlertNG.NotificationService.SendEmailCommandHandler(c.Req.Context(), &emailCmd); err != nil {
-			return response.Error(500, "Failed to send email invited_to_org", err)
+	if inviteDto.SendEmail && util.IsEmail(user.Email) {
+		queued, rsp := hs.sendExistingUserInviteEmail(c, user, cmd.Result.Code)
+		if rsp != nil {
+			return rsp
+		}
+		if queued != nil {
+			return queuedInviteResponse(fmt.Sprintf("Existing Grafana user %s invited to org %s, the email is queued", user.NameOrFallback(), c.OrgName), cmd.Result.Id, user.ID, queued)
 		}
 	}
 
 	return response.JSON(http.StatusOK, util.DynMap{
-		"message": fmt.Sprintf("Existing Grafana user %s added to org %s", user.NameOrFallback(), c.OrgName),
+		"message": fmt.Sprintf("Existing Grafana user %s invited to org %s", user.NameOrFallback(), c.OrgName),
 		"userId":  user.ID,
 	})
 }
 
+// inviteExistingMember handles invites to members of the organization. They don't change the role
+// of members unless they lower it and allow it explicitly, as careless invites demoted members.
+func (hs *HTTPServer) inviteExistingMember(c *models.ReqContext, usr *user.User, currentRole org.RoleType, inviteDto *dtos.AddInviteForm) response.Response {
+	if msg := memberInviteError(inviteDto, currentRole); msg != "" {
+		return response.JSON(http.StatusPreconditionFailed, util.DynMap{
+			"message":     msg,
+			"currentRole": currentRole,
+		})
+	}
+	canWrite, err := hs.canWriteOrgUser(c, usr.ID)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to evaluate permissions", err)
+	}
+	if !canWrite {
+		return response.Error(http.StatusForbidden, "Permission denied: not permitted to change the role of members of this organisation", nil)
+	}
+
+	cmd := models.UpdateOrgUserCommand{OrgId: c.OrgID, UserId: usr.ID, Role: inviteDto.Role}
+	if rsp := hs.updateOrgUserHelper(c, cmd); rsp.Status() != http.StatusOK {
+		return rsp
+	}
+	hs.log.Info("Downgraded the role of an organization member with an invite", "orgId", c.OrgID, "userId", usr.ID, "from", currentRole, "to", inviteDto.Role)
+	return response.JSON(http.StatusOK, util.DynMap{
+		"message":      fmt.Sprintf("Role of %s changed from %s to %s", usr.NameOrFallback(), currentRole, inviteDto.Role),
+		"userId":       usr.ID,
+		"previousRole": currentRole,
+	})
+}
+
+// memberInviteError returns why the invite to a member of the organization with currentRole fails,
+// or an empty string when it downgrades the member.
+func memberInviteError(inviteDto *dtos.AddInviteForm, currentRole org.RoleType) string {
+	if len(inviteDto.Orgs) > 0 {
+		return fmt.Sprintf("User %s is already a member of the organization, invites to several organizations are for users joining it", inviteDto.LoginOrEmail)
+	}
+	if currentRole == inviteDto.Role || !currentRole.Includes(inviteDto.Role) {
+		return fmt.Sprintf("User %s is already added to organization", inviteDto.LoginOrEmail)
+	}
+	if !inviteDto.AllowDowngrade {
+		return fmt.Sprintf("User %s is already a member of the organization with the %s role, set allowDowngrade to change it to %s", inviteDto.LoginOrEmail, currentRole, inviteDto.Role)
+	}
+	return ""
+}
+
+func (hs *HTTPServer) canWriteOrgUser(c *models.ReqContext, userID int64) (bool, error) {
+	userIDScope := ac.Scope("users", "id", strconv.FormatInt(userID, 10))
+	return hs.AccessControl.Evaluate(c.Req.Context(), c.SignedInUser, ac.EvalPermission(ac.ActionOrgUsersWrite, userIDScope))
+}
+
+// swagger:route GET /user/org-invites signed_in_user getSignedInUserOrgInvites
+//
+// Get pending organization invites of the actual User.
+//
+// Lists the invites to join an organization which target the email of the signed in user, once
+// the user has verified they own the email. Users who haven't can complete the invites with the
+// code which was emailed to them. The invites have no code, they are accepted and declined with
+// their ID.
+//
+// Responses:
+// 200: getSignedInUserOrgInvitesResponse
+// 401: unauthorisedError
+// 500: internalServerError
+func (hs *HTTPServer) GetSignedInUserOrgInvites(c *models.ReqContext) response.Response {
+	pending, rsp := hs.getSignedInUserPendingInvites(c)
+	if rsp != nil {
+		return rsp
+	}
+
+	invites := make([]dtos.UserOrgInvite, 0, len(pending))
+	for _, invite := range pending {
+		invites = append(invites, dtos.UserOrgInvite{
+			ID:             invite.Id,
+			OrgID:          invite.OrgId,
+			OrgName:        invite.OrgName,
+			Email:          invite.Email,
+			Role:           invite.Role,
+			InvitedByLogin: invite.InvitedByLogin,
+			InvitedByEmail: invite.InvitedByEmail,
+			InvitedByName:  invite.InvitedByName,
+			Created:        invite.Created,
+		})
+	}
+
+	return response.JSON(http.StatusOK, invites)
+}
+
+// swagger:route POST /user/org-invites/{invite_id}/accept signed_in_user acceptSignedInUserOrgInvite
+//
+// Accept an organization invite of the actual User.
+//
+// Responses:
+// 200: okResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 404: notFoundError
+// 500: internalServerError
+func (hs *HTTPServer) AcceptSignedInUserOrgInvite(c *models.ReqContext) response.Response {
+	invite, rsp := hs.getSignedInUserPendingInvite(c)
+	if rsp != nil {
+		return rsp
+	}
+	usr, err := hs.userService.GetByID(c.Req.Context(), &user.GetUserByIDQuery{ID: c.UserID})
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get user", err)
+	}
+
+	if ok, rsp := hs.applyUserInvite(c.Req.Context(), usr, invite, false, hs.inviteCompletion(c)); !ok {
+		return rsp
+	}
+
+	return response.Success(fmt.Sprintf("Joined organization %s", invite.OrgName))
+}
+
+// swagger:route POST /user/org-invites/{invite_id}/decline signed_in_user declineSignedInUserOrgInvite
+//
+// Decline an organization invite of the actual User.
+//
+// Responses:
+// 200: okResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 404: notFoundError
+// 500: internalServerError
+func (hs *HTTPServer) DeclineSignedInUserOrgInvite(c *models.ReqContext) response.Response {
+	invite, rsp := hs.getSignedInUserPendingInvite(c)
+	if rsp != nil {
+		return rsp
+	}
+
+	if ok, rsp := hs.updateTempUserStatus(c.Req.Context(), invite.Code, models.TmpUserDeclined); !ok {
+		return rsp
+	}
+
+	return response.Success("Invite declined")
+}
+
+// getSignedInUserPendingInvite returns the pending invite of the signed in user with the ID of the
+// request. Invites which do not exist or target somebody else are reported as not found.
+func (hs *HTTPServer) getSignedInUserPendingInvite(c *models.ReqContext) (*models.TempUserDTO, response.Response) {
+	inviteID, err := strconv.ParseInt(web.Params(c.Req)[":inviteId"], 10, 64)
+	if err != nil {
+		return nil, response.Error(http.StatusBadRequest, "inviteId is invalid", err)
+	}
+
+	invites, rsp := hs.getSignedInUserPendingInvites(c)
+	if rsp != nil {
+		return nil, rsp
+	}
+	for _, invite := range invites {
+		if invite.Id == inviteID {
+			return invite, nil
+		}
+	}
+	return nil, response.Error(http.StatusNotFound, "Invite not found", nil)
+}
+
+// getSignedInUserPendingInvites returns the pending invites targeting the email of the signed in
+// user. The email is only matched once the user has verified they own it, as users can change
+// their email to anything, and logins are never matched, as the login of a user can be the email
+// of someone else.
+func (hs *HTTPServer) getSignedInUserPendingInvites(c *models.ReqContext) ([]*models.TempUserDTO, response.Response) {
+	usr, err := hs.userService.GetByID(c.Req.Context(), &user.GetUserByIDQuery{ID: c.UserID})
+	if err != nil {
+		return nil, response.Error(http.StatusInternalServerError, "Failed to get user", err)
+	}
+	if !usr.EmailVerified || !util.IsEmail(usr.Email) {
+		return []*models.TempUserDTO{}, nil
+	}
+
+	query := models.GetTempUsersQuery{Email: usr.Email, Status: models.TmpUserInvitePending}
+	if err := hs.tempUserService.GetTempUsersQuery(c.Req.Context(), &query); err != nil {
+		return nil, response.Error(http.StatusInternalServerError, "Failed to get invites from db", err)
+	}
+	return query.Result, nil
+}
+
 // swagger:route DELETE /org/invites/{invitation_code}/revoke org_invites revokeInvite
 //
 // Revoke invite.
 //
+// Only pending invites can be revoked.
+//
 // Responses:
 // 200: okResponse
 // 401: unauthorisedError
 // 403: forbiddenError
 // 404: notFoundError
+// 409: conflictError
 // 500: internalServerError
 func (hs *HTTPServer) RevokeInvite(c *models.ReqContext) response.Response {
 	if ok, rsp := hs.updateTempUserStatus(c.Req.Context(), web.Params(c.Req)[":code"], models.TmpUserRevoked); !ok {
@@ -192,6 +974,8 @@ func (hs *HTTPServer) RevokeInvite(c *models.ReqContext) response.Response {
 // GetInviteInfoByCode gets a pending user invite corresponding to a certain code.
 // A response containing an InviteInfo object is returned if the invite is found.
 // If a (pending) invite is not found, 404 is returned.
+// The ETag of the response is the version of the invite, 304 is returned when it matches the
+// If-None-Match header.
 func (hs *HTTPServer) GetInviteInfoByCode(c *models.ReqContext) response.Response {
 	query := models.GetTempUserByCodeQuery{Code: web.Params(c.Req)[":code"]}
 	if err := hs.tempUserService.GetTempUserByCode(c.Req.Context(), &query); err != nil {
@@ -206,19 +990,43 @@ func (hs *HTTPServer) GetInviteInfoByCode(c *models.ReqContext) response.Respons
 		return response.Error(404, "Invite not found", nil)
 	}
 
+	if _, disabled := hs.Cfg.InviteTrackingDisabledOrgs[invite.OrgId]; !disabled {
+		// only tells admins the link was opened, so failing to record it doesn't fail the request
+		opened := models.MarkTempUserOpenedCommand{Code: invite.Code}
+		if err := hs.tempUserService.MarkTempUserOpened(c.Req.Context(), &opened); err != nil {
+			hs.log.FromContext(c.Req.Context()).Warn("Failed to record that the invite was opened", "error", err)
+		} else if opened.Opened {
+			// recording the first opening increments the version
+			invite.Version++
+		}
+	}
+
+	etag := inviteETag(invite)
+	if rsp := inviteNotModified(c, etag); rsp != nil {
+		return rsp
+	}
+
 	return response.JSON(http.StatusOK, dtos.InviteInfo{
-		Email:     invite.Email,
-		Name:      invite.Name,
-		Username:  invite.Email,
-		InvitedBy: util.StringsFallback3(invite.InvitedByName, invite.InvitedByLogin, invite.InvitedByEmail),
-	})
+		Email:      invite.Email,
+		Name:       invite.Name,
+		Username:   invite.Email,
+		InvitedBy:  util.StringsFallback3(invite.InvitedByName, invite.InvitedByLogin, invite.InvitedByEmail),
+		EmailMatch: invite.EmailMatch,
+	}).SetHeader("ETag", etag).SetHeader("Cache-Control", inviteCacheControl)
 }
 
 func (hs *HTTPServer) CompleteInvite(c *models.ReqContext) response.Response {
+	return hs.withInviteDuplicateSubmission(c, hs.completeInvite)
+}
+
+func (hs *HTTPServer) completeInvite(c *models.ReqContext) response.Response {
 	completeInvite := dtos.CompleteInviteForm{}
 	if err := web.Bind(c.Req, &completeInvite); err != nil {
 		return response.Error(http.StatusBadRequest, "bad request data", err)
 	}
+	if rsp := hs.verifyCaptcha(c, completeInvite.CaptchaToken); rsp != nil {
+		return rsp
+	}
 	query := models.GetTempUserByCodeQuery{Code: completeInvite.InviteCode}
 
 	if err := hs.tempUserService.GetTempUserByCode(c.Req.Context(), &query); err != nil {
@@ -232,6 +1040,16 @@ func (hs *HTTPServer) CompleteInvite(c *models.ReqContext) response.Response {
 	if invite.Status != models.TmpUserInvitePending {
 		return response.Error(412, fmt.Sprintf("Invite cannot be used in status %s", invite.Status), nil)
 	}
+	if !invite.EmailMatch.Matches(invite.Email, completeInvite.Email) {
+		hs.log.FromContext(c.Req.Context()).Warn("Rejected invite completion with another email", "inviteId", invite.Id, "emailMatch", invite.EmailMatch)
+		if invite.EmailMatch == models.InviteEmailMatchDomain {
+			return response.Error(http.StatusForbidden, "The invite can only be completed with an email of the invited domain", nil)
+		}
+		return response.Error(http.StatusForbidden, "The invite can only be completed with the invited email", nil)
+	}
+	if err := hs.invitePolicy(c.Req.Context(), invite.OrgId, invite.Email).canCompleteInvite(invite, completeInvite.Email); err != nil {
+		return response.Error(http.StatusForbidden, err.Error(), nil)
+	}
 
 	cmd := user.CreateUserCommand{
 		Email:        completeInvite.Email,
@@ -239,11 +1057,16 @@ func (hs *HTTPServer) CompleteInvite(c *models.ReqContext) response.Response {
 		Login:        completeInvite.Username,
 		Password:     completeInvite.Password,
 		SkipOrgSetup: true,
+		// the code of emailed invites proves owning the invited email
+		EmailVerified: invite.Delivery == models.InviteDeliveryEmail && invite.EmailSent && strings.EqualFold(invite.Email, completeInvite.Email),
 	}
 
 	usr, err := hs.Login.CreateUser(cmd)
 	if err != nil {
 		if errors.Is(err, user.ErrUserAlreadyExists) {
+			if rsp, ok := hs.inviteConflictResponse(c, invite, completeInvite.Email); ok {
+				return rsp
+			}
 			return response.Error(412, fmt.Sprintf("User with email '%s' or username '%s' already exists", completeInvite.Email, completeInvite.Username), err)
 		}
 
@@ -257,7 +1080,7 @@ func (hs *HTTPServer) CompleteInvite(c *models.ReqContext) response.Response {
 		return response.Error(500, "failed to publish event", err)
 	}
 
-	if ok, rsp := hs.applyUserInvite(c.Req.Context(), usr, invite, true); !ok {
+	if ok, rsp := hs.applyUserInvite(c.Req.Context(), usr, invite, true, hs.inviteCompletion(c)); !ok {
 		return rsp
 	}
 
@@ -276,27 +1099,81 @@ func (hs *HTTPServer) CompleteInvite(c *models.ReqContext) response.Response {
 }
 
 func (hs *HTTPServer) updateTempUserStatus(ctx context.Context, code string, status models.TempUserStatus) (bool, response.Response) {
-	// update temp user status
-	updateTmpUserCmd := models.UpdateTempUserStatusCommand{Code: code, Status: status}
-	if err := hs.tempUserService.UpdateTempUserStatus(ctx, &updateTmpUserCmd); err != nil {
-		return false, response.Error(500, "Failed to update invite status", err)
+	return hs.runUpdateTempUserStatus(ctx, &models.UpdateTempUserStatusCommand{Code: code, Status: status})
+}
+
+func (hs *HTTPServer) runUpdateTempUserStatus(ctx context.Context, cmd *models.UpdateTempUserStatusCommand) (bool, response.Response) {
+	if err := hs.tempUserService.UpdateTempUserStatus(ctx, cmd); err != nil {
+		return false, tempUserStatusErrorResponse(err)
 	}
 
 	return true, nil
 }
 
-func (hs *HTTPServer) applyUserInvite(ctx context.Context, usr *user.User, invite *models.TempUserDTO, setActive bool) (bool, response.Response) {
-	// add to org
-	addOrgUserCmd := models.AddOrgUserCommand{OrgId: invite.OrgId, UserId: usr.ID, Role: invite.Role}
-	if err := hs.SQLStore.AddOrgUser(ctx, &addOrgUserCmd); err != nil {
-		if !errors.Is(err, models.ErrOrgUserAlreadyAdded) {
-			return false, response.Error(500, "Error while trying to create org user", err)
-		}
+func tempUserStatusErrorResponse(err error) response.Response {
+	if errors.Is(err, models.ErrTempUserNotFound) {
+		return response.Error(http.StatusNotFound, "Invite not found", err)
+	}
+	if errors.Is(err, models.ErrTempUserInvalidTransition) {
+		return response.Error(http.StatusConflict, err.Error(), err)
+	}
+	return response.Error(500, "Failed to update invite status", err)
+}
+
+// applyUserInvite adds the user to the organization of the invite and completes it, completion
+// records where it was completed from when it isn't nil.
+func (hs *HTTPServer) applyUserInvite(ctx context.Context, usr *user.User, invite *models.TempUserDTO, setActive bool, completion *models.InviteCompletion) (bool, response.Response) {
+	// viewer tokens are expired by the cleanup job, which may not have run yet
+	if invite.AccessExpires != nil && time.Now().After(*invite.AccessExpires) {
+		return false, response.Error(http.StatusNotFound, "Invite not found", nil)
 	}
 
-	// update temp user status
-	if ok, rsp := hs.updateTempUserStatus(ctx, invite.Code, models.TmpUserCompleted); !ok {
-		return false, rsp
+	// add to org, viewer tokens only give read-only access whatever the role of the invite
+	role := invite.Role
+	if invite.AccessExpires != nil {
+		role = org.RoleViewer
+	}
+	joinRules := hs.inviteJoinRules(ctx, usr, invite)
+	if invite.AccessExpires == nil {
+		role = hs.raiseInviteRole(invite, role, joinRules.Role)
+	}
+	orgGrants, err := hs.getInviteOrgs(ctx, invite)
+	if err != nil {
+		return false, response.Error(500, "Failed to get the organizations of the invite", err)
+	}
+	if completion != nil {
+		completion.UserID = usr.ID
+		hs.flagInviteCompletionAnomaly(ctx, invite, completion)
+	}
+	addOrgUserCmd := models.AddOrgUserCommand{OrgId: invite.OrgId, UserId: usr.ID, Role: role}
+	statusCmd := models.UpdateTempUserStatusCommand{Code: invite.Code, Status: models.TmpUserCompleted, Completion: completion}
+	joined := true
+	// the user joins all the organizations of the invite and completes it, or none of it happens
+	// when the invite was completed or revoked concurrently
+	err = hs.SQLStore.InTransaction(ctx, func(ctx context.Context) error {
+		joined = true
+		if err := hs.SQLStore.AddOrgUser(ctx, &addOrgUserCmd); err != nil {
+			if !errors.Is(err, models.ErrOrgUserAlreadyAdded) {
+				return err
+			}
+			joined = false
+		}
+		if err := hs.joinInviteOrgs(ctx, usr, invite, orgGrants); err != nil {
+			return err
+		}
+
+		// viewer tokens only end the membership they gave
+		statusCmd.AccessUserID = 0
+		if invite.AccessExpires != nil && joined {
+			statusCmd.AccessUserID = usr.ID
+		}
+		return hs.tempUserService.UpdateTempUserStatus(ctx, &statusCmd)
+	})
+	if err != nil {
+		if errors.Is(err, models.ErrTempUserNotFound) || errors.Is(err, models.ErrTempUserInvalidTransition) {
+			return false, tempUserStatusErrorResponse(err)
+		}
+		return false, response.Error(500, "Error while trying to create org user", err)
 	}
 
 	if setActive {
@@ -306,16 +1183,92 @@ func (hs *HTTPServer) applyUserInvite(ctx context.Context, usr *user.User, invit
 		}
 	}
 
+	hs.applyInviteGrants(ctx, usr, invite)
+	hs.joinInviteRuleTeams(ctx, usr, invite, joinRules.Teams)
+	hs.applyInviteExternalID(ctx, usr, invite)
+	if joined {
+		hs.runInviteCompletedHooks(usr, invite, role)
+	}
+	hs.recordOnboardingEvent(ctx, invite.OrgId, usr.ID, onboarding.EventInviteAccepted, invite.Id)
+
 	return true, nil
 }
 
+// maxInviteExternalIDLength is the length of the external_id columns of invites and users.
+const maxInviteExternalIDLength = 190
+
+// applyInviteExternalID sets the external ID of the invite on the user who accepted it. Users keep
+// the external ID they already have, e.g. from the invite of another organization.
+func (hs *HTTPServer) applyInviteExternalID(ctx context.Context, usr *user.User, invite *models.TempUserDTO) {
+	if invite.ExternalId == "" {
+		return
+	}
+	logger := hs.log.FromContext(ctx)
+	cmd := user.SetExternalIDCommand{UserID: usr.ID, ExternalID: invite.ExternalId}
+	if err := hs.userService.SetExternalID(ctx, &cmd); err != nil {
+		logger.Warn("Failed to set the external ID of the invite on the user", "inviteId", invite.Id, "userId", usr.ID, "error", err)
+		return
+	}
+	if !cmd.Updated {
+		logger.Warn("Kept the external ID of the user who accepted an invite with another one", "inviteId", invite.Id, "userId", usr.ID)
+	}
+}
+
+// withInviteTimeout bounds the invite handlers by the configured invite_handler_timeout. Their
+// database queries and email sends give up when the request context is done, the resulting
+// errors are reported as 499 when the client closed the request and as 504 when it timed out.
+func (hs *HTTPServer) withInviteTimeout(handler func(c *models.ReqContext) response.Response) func(c *models.ReqContext) response.Response {
+	return func(c *models.ReqContext) response.Response {
+		ctx := c.Req.Context()
+		if timeout := hs.Cfg.InviteHandlerTimeout; timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+			c.Req = c.Req.WithContext(ctx)
+		}
+
+		rsp := handler(c)
+		if rsp == nil || rsp.Status() < http.StatusInternalServerError {
+			return rsp
+		}
+		switch {
+		case errors.Is(ctx.Err(), context.Canceled):
+			return response.Error(proxyutil.StatusClientClosedRequest, "Client closed the request", ctx.Err())
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			return response.Error(http.StatusGatewayTimeout, "Timed out processing the invite", ctx.Err())
+		}
+		return rsp
+	}
+}
+
 // swagger:parameters addOrgInvite
 type AddInviteParams struct {
+	// in:header
+	// required:false
+	IdempotencyKey string `json:"Idempotency-Key"`
+	// in:header
+	// required:false
+	CorrelationID string `json:"X-Grafana-Correlation-Id"`
 	// in:body
 	// required:true
 	Body dtos.AddInviteForm `json:"body"`
 }
 
+// swagger:parameters getPendingOrgInvites
+type GetPendingOrgInvitesParams struct {
+	// in:query
+	// required:false
+	// enum: email,manual
+	Delivery string `json:"delivery"`
+	// in:query
+	// required:false
+	// enum: automation,user
+	InvitedBy string `json:"invitedBy"`
+	// in:header
+	// required:false
+	IfNoneMatch string `json:"If-None-Match"`
+}
+
 // swagger:parameters revokeInvite
 type RevokeInviteParams struct {
 	// in:path
@@ -325,7 +1278,42 @@ type RevokeInviteParams struct {
 
 // swagger:response getPendingOrgInvitesResponse
 type GetPendingOrgInvitesResponse struct {
+	// in:header
+	ETag string `json:"ETag"`
 	// The response message
 	// in: body
 	Body []*models.TempUserDTO `json:"body"`
 }
+
+// swagger:response getOrgInvitesHealthResponse
+type GetOrgInvitesHealthResponse struct {
+	// in: body
+	Body dtos.InvitesHealth `json:"body"`
+}
+
+// swagger:response addOrgInviteQueuedResponse
+type AddOrgInviteQueuedResponse struct {
+	// in:header
+	RetryAfter string `json:"Retry-After"`
+	// in: body
+	Body dtos.QueuedInvite `json:"body"`
+}
+
+// swagger:response sendTestOrgInviteEmailResponse
+type SendTestOrgInviteEmailResponse struct {
+	// in: body
+	Body notifications.TestEmailResult `json:"body"`
+}
+
+// swagger:parameters acceptSignedInUserOrgInvite declineSignedInUserOrgInvite
+type SignedInUserOrgInviteParams struct {
+	// in:path
+	// required:true
+	InviteID int64 `json:"invite_id"`
+}
+
+// swagger:response getSignedInUserOrgInvitesResponse
+type GetSignedInUserOrgInvitesResponse struct {
+	// in: body
+	Body []dtos.UserOrgInvite `json:"body"`
+}
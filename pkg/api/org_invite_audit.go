@@ -0,0 +1,189 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+// inviteAuditSignatureHeader holds the hex encoded HMAC-SHA256 signature of invite audit reports.
+const inviteAuditSignatureHeader = "X-Grafana-Report-Signature"
+
+var inviteAuditCSVHeader = []string{
+	"invite_id", "email", "name", "external_id", "role", "access_expires", "created",
+	"approver_user_id", "approver_login", "approver_api_key_id", "approver_api_key_name",
+	"completed", "completed_user_id", "completed_login",
+	"completed_remote_addr", "completed_user_agent", "completed_country", "completed_anomaly",
+}
+
+// swagger:route GET /org/invites/audit-report org_invites getOrgInviteAuditReport
+//
+// Get the audit report of the memberships given by invites.
+//
+// Reports the invites of the current organization completed over a period, with who approved them,
+// the role they granted and where they were completed from, ordered by completion time. The report
+// is signed with the secret key of the server, the signature is returned in the
+// X-Grafana-Report-Signature header and can be checked with the verification endpoint.
+// With the `invites:self` scope only the invites created by the signed in user are reported.
+//
+// Responses:
+// 200: getOrgInviteAuditReportResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (hs *HTTPServer) GetOrgInviteAuditReport(c *models.ReqContext) response.Response {
+	from, err := time.Parse(time.RFC3339, c.Query("from"))
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "from must be an RFC 3339 time", err)
+	}
+	to, err := time.Parse(time.RFC3339, c.Query("to"))
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "to must be an RFC 3339 time", err)
+	}
+	if !from.Before(to) {
+		return response.Error(http.StatusBadRequest, "from must be before to", nil)
+	}
+	format := c.Query("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" {
+		return response.Error(http.StatusBadRequest, "format must be json or csv", nil)
+	}
+
+	readable := models.GetTempUsersQuery{}
+	canRead, err := hs.filterReadableInvites(c, &readable)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to evaluate invite permissions", err)
+	}
+	if !canRead {
+		return response.Error(http.StatusForbidden, "Permission denied", nil)
+	}
+
+	query := models.GetInviteAuditQuery{OrgID: c.OrgID, From: from.UTC(), To: to.UTC(), InvitedByUserID: readable.InvitedByUserID}
+	if err := hs.tempUserService.GetInviteAudit(c.Req.Context(), &query); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get the invite audit", err)
+	}
+
+	var body []byte
+	header := http.Header{}
+	if format == "csv" {
+		body, err = inviteAuditCSV(query.Result)
+		header.Set("Content-Type", "text/csv; charset=utf-8")
+	} else {
+		body, err = json.Marshal(dtos.InviteAuditReport{
+			OrgID: c.OrgID, From: query.From, To: query.To, ApproverUserID: query.InvitedByUserID, Entries: query.Result,
+		})
+		header.Set("Content-Type", "application/json")
+	}
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to encode the invite audit report", err)
+	}
+	header.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="invite-audit-%d-%s-%s.%s"`,
+		c.OrgID, query.From.Format("20060102T150405Z"), query.To.Format("20060102T150405Z"), format))
+	header.Set(inviteAuditSignatureHeader, hs.signInviteAuditReport(c.OrgID, body))
+	return response.CreateNormalResponse(header, body, http.StatusOK)
+}
+
+// swagger:route POST /org/invites/audit-report/verify org_invites verifyOrgInviteAuditReport
+//
+// Verify the signature of an invite audit report.
+//
+// The report must be the body returned by the audit report endpoint in the current organization,
+// unchanged.
+//
+// Responses:
+// 200: verifyOrgInviteAuditReportResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+func (hs *HTTPServer) VerifyOrgInviteAuditReport(c *models.ReqContext) response.Response {
+	form := dtos.VerifyInviteAuditReportForm{}
+	if err := web.Bind(c.Req, &form); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	valid := hmac.Equal([]byte(form.Signature), []byte(hs.signInviteAuditReport(c.OrgID, []byte(form.Report))))
+	return response.JSON(http.StatusOK, dtos.InviteAuditReportVerification{Valid: valid})
+}
+
+// signInviteAuditReport signs the report with the organization it was produced in, so that the
+// report of an organization doesn't verify in another one.
+func (hs *HTTPServer) signInviteAuditReport(orgID int64, report []byte) string {
+	mac := hmac.New(sha256.New, []byte(hs.Cfg.SecretKey))
+	_, _ = mac.Write([]byte(fmt.Sprintf("invite-audit:%d:", orgID)))
+	_, _ = mac.Write(report)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// inviteAuditCSV encodes the entries of the report as CSV, times are in UTC.
+func inviteAuditCSV(entries []*models.InviteAuditEntry) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(inviteAuditCSVHeader); err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		accessExpires := ""
+		if e.AccessExpires != nil {
+			accessExpires = e.AccessExpires.UTC().Format(time.RFC3339)
+		}
+		if err := w.Write([]string{
+			strconv.FormatInt(e.InviteID, 10), e.Email, e.Name, e.ExternalID, string(e.Role), accessExpires, e.Created.Format(time.RFC3339),
+			strconv.FormatInt(e.ApproverUserID, 10), e.ApproverLogin, strconv.FormatInt(e.ApproverAPIKeyID, 10), e.ApproverAPIKeyName,
+			e.Completed.Format(time.RFC3339), strconv.FormatInt(e.CompletedUserID, 10), e.CompletedLogin,
+			e.CompletedRemoteAddr, e.CompletedUserAgent, e.CompletedCountry, strconv.FormatBool(e.CompletedAnomaly),
+		}); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// swagger:parameters getOrgInviteAuditReport
+type GetOrgInviteAuditReportParams struct {
+	// Start of the period, as an RFC 3339 time
+	// in:query
+	// required:true
+	From string `json:"from"`
+	// End of the period, excluded, as an RFC 3339 time
+	// in:query
+	// required:true
+	To string `json:"to"`
+	// in:query
+	// required:false
+	// enum: json,csv
+	Format string `json:"format"`
+}
+
+// swagger:parameters verifyOrgInviteAuditReport
+type VerifyOrgInviteAuditReportParams struct {
+	// in:body
+	// required:true
+	Body dtos.VerifyInviteAuditReportForm `json:"body"`
+}
+
+// swagger:response getOrgInviteAuditReportResponse
+type GetOrgInviteAuditReportResponse struct {
+	// in: body
+	Body dtos.InviteAuditReport `json:"body"`
+}
+
+// swagger:response verifyOrgInviteAuditReportResponse
+type VerifyOrgInviteAuditReportResponse struct {
+	// in: body
+	Body dtos.InviteAuditReportVerification `json:"body"`
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/temp_user/tempusertest"
+)
+
+func TestOrgInviteAuditReport(t *testing.T) {
+	completed := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	entries := []*models.InviteAuditEntry{
+		{InviteID: 1, Email: "a@example.com", Name: "A, the first", Role: org.RoleEditor, Created: completed.Add(-time.Hour), ApproverUserID: 2, ApproverLogin: "admin", Completed: completed, CompletedUserID: 7, CompletedLogin: "a", CompletedCountry: "FR"},
+		{InviteID: 3, Email: "b@example.com", Role: org.RoleViewer, Created: completed.Add(-time.Hour), ApproverAPIKeyID: 4, ApproverAPIKeyName: "provisioning", Completed: completed.Add(time.Minute)},
+	}
+	setup := func(t *testing.T) accessControlScenarioContext {
+		sc := setupHTTPServer(t, true)
+		sc.hs.Cfg.SecretKey = "secret"
+		sc.hs.tempUserService = &tempusertest.FakeTempUserService{AuditEntries: entries}
+		setInitCtxSignedInOrgAdmin(sc.initCtx)
+		setAccessControlPermissions(sc.acmock, []accesscontrol.Permission{{Action: accesscontrol.ActionOrgInvitesRead, Scope: accesscontrol.ScopeInvitesAll}}, sc.initCtx.OrgID)
+		return sc
+	}
+	verify := func(t *testing.T, sc accessControlScenarioContext, report, signature string) bool {
+		t.Helper()
+		body, err := json.Marshal(dtos.VerifyInviteAuditReportForm{Report: report, Signature: signature})
+		require.NoError(t, err)
+		response := callAPI(sc.server, http.MethodPost, "/api/org/invites/audit-report/verify", strings.NewReader(string(body)), t)
+		require.Equal(t, http.StatusOK, response.Code)
+		var verification dtos.InviteAuditReportVerification
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &verification))
+		return verification.Valid
+	}
+
+	t.Run("reports are signed", func(t *testing.T) {
+		sc := setup(t)
+		response := callAPI(sc.server, http.MethodGet, "/api/org/invites/audit-report?from=2026-03-01T00:00:00Z&to=2026-04-01T00:00:00Z", nil, t)
+		require.Equal(t, http.StatusOK, response.Code)
+		var report dtos.InviteAuditReport
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &report))
+		assert.Equal(t, sc.initCtx.OrgID, report.OrgID)
+		require.Len(t, report.Entries, 2)
+		assert.Equal(t, "admin", report.Entries[0].ApproverLogin)
+
+		signature := response.Header().Get(inviteAuditSignatureHeader)
+		require.NotEmpty(t, signature)
+		assert.True(t, verify(t, sc, response.Body.String(), signature))
+		assert.False(t, verify(t, sc, strings.Replace(response.Body.String(), "Editor", "Admin", 1), signature))
+		assert.NotEqual(t, signature, sc.hs.signInviteAuditReport(sc.initCtx.OrgID+1, response.Body.Bytes()), "reports are bound to their org")
+
+		again := callAPI(sc.server, http.MethodGet, "/api/org/invites/audit-report?from=2026-03-01T00:00:00Z&to=2026-04-01T00:00:00Z", nil, t)
+		assert.Equal(t, response.Body.String(), again.Body.String(), "reports are deterministic")
+		assert.Equal(t, signature, again.Header().Get(inviteAuditSignatureHeader))
+	})
+
+	t.Run("reports only the invites created by the user with the self scope", func(t *testing.T) {
+		sc := setup(t)
+		sc.initCtx.UserID = 2
+		setAccessControlPermissions(sc.acmock, []accesscontrol.Permission{{Action: accesscontrol.ActionOrgInvitesRead, Scope: accesscontrol.ScopeInvitesSelf}}, sc.initCtx.OrgID)
+		response := callAPI(sc.server, http.MethodGet, "/api/org/invites/audit-report?from=2026-03-01T00:00:00Z&to=2026-04-01T00:00:00Z", nil, t)
+		require.Equal(t, http.StatusOK, response.Code)
+		var report dtos.InviteAuditReport
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &report))
+		assert.Equal(t, int64(2), report.ApproverUserID)
+		require.Len(t, report.Entries, 1)
+		assert.Equal(t, int64(1), report.Entries[0].InviteID)
+
+		sc.initCtx.UserID = 5
+		response = callAPI(sc.server, http.MethodGet, "/api/org/invites/audit-report?from=2026-03-01T00:00:00Z&to=2026-04-01T00:00:00Z&format=csv", nil, t)
+		require.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, strings.Join(inviteAuditCSVHeader, ","), strings.TrimSpace(response.Body.String()))
+	})
+
+	t.Run("reports can be exported as CSV", func(t *testing.T) {
+		sc := setup(t)
+		response := callAPI(sc.server, http.MethodGet, "/api/org/invites/audit-report?from=2026-03-01T00:00:00Z&to=2026-04-01T00:00:00Z&format=csv", nil, t)
+		require.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", response.Header().Get("Content-Type"))
+		lines := strings.Split(strings.TrimSpace(response.Body.String()), "\n")
+		require.Len(t, lines, 3)
+		assert.Equal(t, strings.Join(inviteAuditCSVHeader, ","), lines[0])
+		assert.Equal(t, `1,a@example.com,"A, the first",,Editor,,2026-03-02T09:00:00Z,2,admin,0,,2026-03-02T10:00:00Z,7,a,,,FR,false`, lines[1])
+		assert.True(t, verify(t, sc, response.Body.String(), response.Header().Get(inviteAuditSignatureHeader)))
+	})
+
+	t.Run("invalid periods and formats are rejected", func(t *testing.T) {
+		sc := setup(t)
+		for _, query := range []string{
+			"from=2026-03-01&to=2026-04-01T00:00:00Z",
+			"from=2026-04-01T00:00:00Z&to=2026-03-01T00:00:00Z",
+			"from=2026-03-01T00:00:00Z&to=2026-04-01T00:00:00Z&format=xlsx",
+		} {
+			response := callAPI(sc.server, http.MethodGet, "/api/org/invites/audit-report?"+query, nil, t)
+			assert.Equal(t, http.StatusBadRequest, response.Code, query)
+		}
+	})
+}
@@ -0,0 +1,317 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/util"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+// maxBulkInvitesCSVSize is the maximum size of the CSV files of bulk invites.
+const maxBulkInvitesCSVSize = 1 << 20
+
+// bulkInviteCSVColumns are the columns CSV files of bulk invites can have, named after the fields
+// of AddInviteForm. Teams are separated by semicolons.
+var bulkInviteCSVColumns = []string{"loginOrEmail", "name", "role", "sendEmail", "teams", "delivery", "emailMatch", "allowDowngrade", "externalId"}
+
+// bulkInviteCSVAliases are the other names of the columns, as exported by other tools.
+var bulkInviteCSVAliases = map[string]string{"email": "loginOrEmail", "login": "loginOrEmail"}
+
+// bulkInviteRowError stops the creation of a batch of invites at the row which couldn't be created.
+type bulkInviteRowError struct {
+	row    int
+	status int
+}
+
+func (e bulkInviteRowError) Error() string {
+	return fmt.Sprintf("invite of row %d failed with status %d", e.row, e.status)
+}
+
+// swagger:route POST /org/invites/bulk org_invites addOrgInvitesBulk
+//
+// Create a batch of invites, either all of them or none.
+//
+// The invites are given as JSON, or as a CSV file uploaded as the body of a text/csv request or
+// as the file field of a multipart form. The first line of the file names the columns after the
+// fields of the invites, e.g. loginOrEmail,name,role,sendEmail, teams are separated by
+// semicolons.
+//
+// Each invite is checked like by the validation of invites. When an invite can't be created,
+// none is and the response tells why for each row. Emails are only sent once all the invites are
+// created, an email which can't be sent is reported as a warning of its row.
+//
+// Each invite of the batch counts towards the invite rate limits, a batch which would exceed them
+// fails with 429 and none of its invites is created.
+//
+// Requests can carry an `Idempotency-Key` header, retrying a batch with the same key within 24
+// hours returns the original response without creating the invites or sending the emails again.
+//
+// Responses:
+// 200: addOrgInvitesBulkResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 409: conflictError
+// 422: addOrgInvitesBulkResponse
+// 429: tooManyRequestsError
+// 500: internalServerError
+func (hs *HTTPServer) AddOrgInvitesBulk(c *models.ReqContext) response.Response {
+	return hs.withIdempotencyKey(c, "org-invites-bulk", hs.addOrgInvitesBulk)
+}
+
+func (hs *HTTPServer) addOrgInvitesBulk(c *models.ReqContext) response.Response {
+	raws, rsp := bulkInvites(c)
+	if rsp != nil {
+		return rsp
+	}
+
+	result := dtos.BulkInvites{Rows: make([]dtos.BulkInviteRow, len(raws))}
+	forms := make([]dtos.AddInviteForm, len(raws))
+	valid := true
+	seen := map[string]int{}
+	for i, raw := range raws {
+		if err := json.Unmarshal(raw, &forms[i]); err != nil {
+			valid = false
+			result.Rows[i].Row = i + 1
+			result.Rows[i].Errors = []string{fmt.Sprintf("Invalid invite: %s", err)}
+			continue
+		}
+		row, rsp := hs.validateInviteRow(c, i+1, &forms[i], seen)
+		if rsp != nil {
+			return rsp
+		}
+		if len(row.Errors) > 0 {
+			valid = false
+		}
+		result.Rows[i].InviteRowValidation = row
+	}
+	if !valid {
+		return response.JSON(http.StatusUnprocessableEntity, result)
+	}
+	limit, rsp := hs.checkInviteRateLimit(c.Req.Context(), c.OrgID, inviteRateLimitInviter(c), len(forms))
+	if rsp != nil {
+		return rsp
+	}
+
+	req := c.Req
+	err := hs.SQLStore.InTransaction(req.Context(), func(ctx context.Context) error {
+		c.Req = req.WithContext(ctx)
+		defer func() { c.Req = req }()
+		for i, form := range forms {
+			// emails are sent once the invites are saved, not to send links to invites rolled back
+			form.SendEmail = false
+			rsp := hs.createOrgInvite(c, form)
+			if rsp.Status() != http.StatusOK {
+				result.Rows[i].Errors = append(result.Rows[i].Errors, responseMessage(rsp))
+				return bulkInviteRowError{row: i, status: rsp.Status()}
+			}
+			result.Rows[i].Message = responseMessage(rsp)
+		}
+		return nil
+	})
+	var rowErr bulkInviteRowError
+	if errors.As(err, &rowErr) {
+		for i := range result.Rows {
+			result.Rows[i].Message = ""
+		}
+		if rowErr.status >= http.StatusInternalServerError {
+			return response.JSON(http.StatusInternalServerError, result)
+		}
+		return response.JSON(http.StatusUnprocessableEntity, result)
+	}
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to create invites", err)
+	}
+
+	result.Created = true
+	hs.countInvites(c.Req.Context(), limit, len(forms))
+	for i := range forms {
+		if !forms[i].SendEmail {
+			continue
+		}
+		if warning := hs.sendBulkInviteEmail(c, &forms[i]); warning != "" {
+			result.Rows[i].Warnings = append(result.Rows[i].Warnings, warning)
+		}
+	}
+	return response.JSON(http.StatusOK, result)
+}
+
+// sendBulkInviteEmail sends the email of the pending invite of a batch, like adding the invite
+// alone would have. It returns a warning when the email can't be sent.
+func (hs *HTTPServer) sendBulkInviteEmail(c *models.ReqContext, form *dtos.AddInviteForm) string {
+	usr, err := hs.userService.GetByLogin(c.Req.Context(), &user.GetUserByLoginQuery{LoginOrEmail: form.LoginOrEmail})
+	if err != nil && !errors.Is(err, user.ErrUserNotFound) {
+		return fmt.Sprintf("The invite email was not sent: %s", err)
+	}
+	email := form.LoginOrEmail
+	if err == nil {
+		email = util.StringsFallback3(usr.Email, usr.Login, form.LoginOrEmail)
+	}
+	if !util.IsEmail(email) {
+		return ""
+	}
+
+	query := models.GetTempUsersQuery{OrgId: c.OrgID, Email: email, Status: models.TmpUserInvitePending}
+	if err := hs.tempUserService.GetTempUsersQuery(c.Req.Context(), &query); err != nil {
+		return fmt.Sprintf("The invite email was not sent: %s", err)
+	}
+	// members and auto-approved users have no invite to accept
+	var invite *models.TempUserDTO
+	for _, pending := range query.Result {
+		if invite == nil || pending.Id > invite.Id {
+			invite = pending
+		}
+	}
+	if invite == nil {
+		return ""
+	}
+
+	var rsp response.Response
+	if err == nil {
+		_, rsp = hs.sendExistingUserInviteEmail(c, usr, invite.Code)
+	} else {
+		_, rsp = hs.sendNewUserInviteEmail(c, email, form.Name, invite.Code)
+	}
+	if rsp != nil {
+		return fmt.Sprintf("The invite email was not sent: %s", responseMessage(rsp))
+	}
+	return ""
+}
+
+// bulkInvites returns the invites of the request, given as JSON or as a CSV file.
+func bulkInvites(c *models.ReqContext) ([]json.RawMessage, response.Response) {
+	mediaType, _, _ := mime.ParseMediaType(c.Req.Header.Get("Content-Type"))
+	var body io.Reader
+	switch mediaType {
+	case "text/csv":
+		body = http.MaxBytesReader(c.Resp, c.Req.Body, maxBulkInvitesCSVSize)
+	case "multipart/form-data":
+		if err := c.Req.ParseMultipartForm(maxBulkInvitesCSVSize); err != nil {
+			return nil, response.Error(http.StatusBadRequest, "Invalid multipart form", err)
+		}
+		file, _, err := c.Req.FormFile("file")
+		if err != nil {
+			return nil, response.Error(http.StatusBadRequest, "A CSV file is required in the file field", err)
+		}
+		defer func() { _ = file.Close() }()
+		body = io.LimitReader(file, maxBulkInvitesCSVSize)
+	default:
+		form := dtos.BulkInvitesForm{}
+		if err := web.Bind(c.Req, &form); err != nil {
+			return nil, response.Error(http.StatusBadRequest, "bad request data", err)
+		}
+		return form.Invites, nil
+	}
+
+	invites, err := parseBulkInvitesCSV(body)
+	if err != nil {
+		return nil, response.Error(http.StatusBadRequest, fmt.Sprintf("Invalid CSV file: %s", err), err)
+	}
+	form := dtos.BulkInvitesForm{Invites: invites}
+	if err := form.Validate(); err != nil {
+		return nil, response.Error(http.StatusBadRequest, err.Error(), err)
+	}
+	return form.Invites, nil
+}
+
+// parseBulkInvitesCSV converts the lines of a CSV file to invites. Values which can't be
+// converted are kept as strings, so that decoding the invite reports them with their row.
+func parseBulkInvitesCSV(r io.Reader) ([]json.RawMessage, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("the file is empty")
+		}
+		return nil, err
+	}
+	columns := make([]string, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		if column, ok := bulkInviteCSVAliases[strings.ToLower(name)]; ok {
+			name = column
+		}
+		for _, column := range bulkInviteCSVColumns {
+			if strings.EqualFold(name, column) {
+				columns[i] = column
+			}
+		}
+		if columns[i] == "" {
+			return nil, fmt.Errorf("unknown column %q, columns can be %s", name, strings.Join(bulkInviteCSVColumns, ", "))
+		}
+	}
+
+	var invites []json.RawMessage
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return invites, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		invite := map[string]interface{}{}
+		for i, value := range record {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				continue
+			}
+			invite[columns[i]] = bulkInviteCSVValue(columns[i], value)
+		}
+		raw, err := json.Marshal(invite)
+		if err != nil {
+			return nil, err
+		}
+		invites = append(invites, raw)
+	}
+}
+
+func bulkInviteCSVValue(column, value string) interface{} {
+	switch column {
+	case "sendEmail", "allowDowngrade":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	case "teams":
+		teams := []int64{}
+		for _, team := range strings.Split(value, ";") {
+			id, err := strconv.ParseInt(strings.TrimSpace(team), 10, 64)
+			if err != nil {
+				return value
+			}
+			teams = append(teams, id)
+		}
+		return teams
+	}
+	return value
+}
+
+// swagger:parameters addOrgInvitesBulk
+type AddOrgInvitesBulkParams struct {
+	// in:header
+	// required:false
+	IdempotencyKey string `json:"Idempotency-Key"`
+	// in:body
+	// required:true
+	Body dtos.BulkInvitesForm `json:"body"`
+}
+
+// swagger:response addOrgInvitesBulkResponse
+type AddOrgInvitesBulkResponse struct {
+	// in: body
+	Body dtos.BulkInvites `json:"body"`
+}
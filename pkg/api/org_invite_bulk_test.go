@@ -0,0 +1,180 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/ngalert"
+	"github.com/grafana/grafana/pkg/services/org"
+	tempuser "github.com/grafana/grafana/pkg/services/temp_user"
+	"github.com/grafana/grafana/pkg/services/temp_user/invitetest"
+	"github.com/grafana/grafana/pkg/services/user/usertest"
+)
+
+// failingTempUserService fails to save the invites to an email.
+type failingTempUserService struct {
+	tempuser.Service
+	email string
+}
+
+func (s *failingTempUserService) CreateTempUser(ctx context.Context, cmd *models.CreateTempUserCommand) error {
+	if cmd.Email == s.email {
+		return errors.New("database is gone")
+	}
+	return s.Service.CreateTempUser(ctx, cmd)
+}
+
+func TestParseBulkInvitesCSV(t *testing.T) {
+	invites, err := parseBulkInvitesCSV(strings.NewReader("LoginOrEmail, role, sendEmail, teams\nana@example.com,Editor,true,1;2\nbob@example.com,,maybe,\n"))
+	require.NoError(t, err)
+	require.Len(t, invites, 2)
+	assert.JSONEq(t, `{"loginOrEmail": "ana@example.com", "role": "Editor", "sendEmail": true, "teams": [1, 2]}`, string(invites[0]))
+	assert.JSONEq(t, `{"loginOrEmail": "bob@example.com", "sendEmail": "maybe"}`, string(invites[1]))
+
+	_, err = parseBulkInvitesCSV(strings.NewReader("email,password\nana@example.com,secret\n"))
+	assert.ErrorContains(t, err, `unknown column "password"`)
+	_, err = parseBulkInvitesCSV(strings.NewReader(""))
+	assert.Error(t, err)
+}
+
+func TestOrgInvitesBulk(t *testing.T) {
+	setup := func(t *testing.T) (accessControlScenarioContext, *invitetest.Stack) {
+		sc, stack := setupInviteHTTPServer(t)
+		sc.hs.AlertNG = &ngalert.AlertNG{NotificationService: stack.Mail}
+		sc.hs.userService = &slackInviteUserService{&usertest.FakeUserService{}}
+		setInitCtxSignedInOrgAdmin(sc.initCtx)
+		setAccessControlPermissions(sc.acmock, []accesscontrol.Permission{{Action: accesscontrol.ActionOrgUsersAdd}}, sc.initCtx.OrgID)
+		return sc, stack
+	}
+	bulk := func(t *testing.T, sc accessControlScenarioContext, contentType string, body []byte) (int, dtos.BulkInvites) {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, "/api/org/invites/bulk", bytes.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", contentType)
+		recorder := httptest.NewRecorder()
+		sc.server.ServeHTTP(recorder, req)
+		var result dtos.BulkInvites
+		if recorder.Code != http.StatusBadRequest {
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &result), recorder.Body.String())
+		}
+		return recorder.Code, result
+	}
+
+	t.Run("creates the invites and emails them", func(t *testing.T) {
+		sc, stack := setup(t)
+		code, result := bulk(t, sc, "application/json", []byte(`{"invites": [
+			{"loginOrEmail": "ana@example.com", "role": "Editor", "sendEmail": true},
+			{"loginOrEmail": "bob@example.com", "role": "Viewer"}
+		]}`))
+		require.Equal(t, http.StatusOK, code)
+		assert.True(t, result.Created)
+		require.Len(t, result.Rows, 2)
+		assert.Equal(t, "Created invite for ana@example.com", result.Rows[0].Message)
+		assert.Empty(t, result.Rows[0].Warnings)
+
+		pending := stack.Pending(t, sc.initCtx.OrgID)
+		require.Len(t, pending, 2)
+		assert.Len(t, stack.Mail.SentTo("ana@example.com"), 1)
+		assert.Empty(t, stack.Mail.SentTo("bob@example.com"))
+		inviteCode, err := stack.Mail.InviteCode("ana@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, org.RoleEditor, stack.Get(t, inviteCode).Role)
+		assert.True(t, stack.Get(t, inviteCode).EmailSent)
+	})
+
+	t.Run("creates nothing when an invite is invalid", func(t *testing.T) {
+		sc, stack := setup(t)
+		code, result := bulk(t, sc, "application/json", []byte(`{"invites": [
+			{"loginOrEmail": "ana@example.com", "role": "Viewer", "sendEmail": true},
+			{"loginOrEmail": "ana@example.com", "role": "Viewer"},
+			{"loginOrEmail": "bob@example.com", "role": "Owner"},
+			{"loginOrEmail": 42}
+		]}`))
+		require.Equal(t, http.StatusUnprocessableEntity, code)
+		assert.False(t, result.Created)
+		require.Len(t, result.Rows, 4)
+		assert.Empty(t, result.Rows[0].Errors)
+		assert.Contains(t, result.Rows[1].Errors, "ana@example.com is already invited by row 1")
+		assert.NotEmpty(t, result.Rows[2].Errors)
+		assert.NotEmpty(t, result.Rows[3].Errors)
+		assert.Empty(t, stack.Pending(t, sc.initCtx.OrgID))
+		assert.Empty(t, stack.Mail.Emails())
+	})
+
+	t.Run("retried batches return the original response", func(t *testing.T) {
+		sc, stack := setup(t)
+		sc.hs.RemoteCacheService = remotecache.NewFakeStore(t)
+		body := `{"invites": [{"loginOrEmail": "ana@example.com", "role": "Viewer", "sendEmail": true}]}`
+		send := func() *httptest.ResponseRecorder {
+			req, err := http.NewRequest(http.MethodPost, "/api/org/invites/bulk", strings.NewReader(body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Idempotency-Key", "batch-1")
+			recorder := httptest.NewRecorder()
+			sc.server.ServeHTTP(recorder, req)
+			return recorder
+		}
+
+		first := send()
+		require.Equal(t, http.StatusOK, first.Code, first.Body.String())
+		replay := send()
+		require.Equal(t, http.StatusOK, replay.Code)
+		assert.Equal(t, "true", replay.Header().Get("Idempotent-Replayed"))
+		assert.JSONEq(t, first.Body.String(), replay.Body.String())
+
+		assert.Len(t, stack.Pending(t, sc.initCtx.OrgID), 1)
+		assert.Len(t, stack.Mail.SentTo("ana@example.com"), 1)
+	})
+
+	t.Run("rolls back the invites when one can't be created", func(t *testing.T) {
+		sc, stack := setup(t)
+		sc.hs.tempUserService = &failingTempUserService{Service: stack.TempUsers, email: "bob@example.com"}
+		code, result := bulk(t, sc, "application/json", []byte(`{"invites": [
+			{"loginOrEmail": "ana@example.com", "role": "Viewer", "sendEmail": true},
+			{"loginOrEmail": "bob@example.com", "role": "Viewer", "sendEmail": true}
+		]}`))
+		require.Equal(t, http.StatusInternalServerError, code)
+		assert.False(t, result.Created)
+		assert.Empty(t, result.Rows[0].Message)
+		assert.Equal(t, []string{"Failed to save invite to database"}, result.Rows[1].Errors)
+		assert.Empty(t, stack.Pending(t, sc.initCtx.OrgID))
+		assert.Empty(t, stack.Mail.Emails())
+	})
+
+	t.Run("reads the invites from a CSV file", func(t *testing.T) {
+		sc, stack := setup(t)
+		csv := "loginOrEmail,name,role\nana@example.com,Ana,Editor\nbob@example.com,,Viewer\n"
+		code, result := bulk(t, sc, "text/csv", []byte(csv))
+		require.Equal(t, http.StatusOK, code)
+		assert.True(t, result.Created)
+		require.Len(t, stack.Pending(t, sc.initCtx.OrgID), 2)
+
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		file, err := writer.CreateFormFile("file", "invites.csv")
+		require.NoError(t, err)
+		_, err = file.Write([]byte("loginOrEmail,role\ncarl@example.com,Viewer\n"))
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+		code, _ = bulk(t, sc, writer.FormDataContentType(), body.Bytes())
+		require.Equal(t, http.StatusOK, code)
+		require.Len(t, stack.Pending(t, sc.initCtx.OrgID), 3)
+
+		code, _ = bulk(t, sc, "text/csv", []byte("loginOrEmail,password\nana@example.com,secret\n"))
+		assert.Equal(t, http.StatusBadRequest, code)
+	})
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+// inviteCacheControl lets browsers keep invites, which hold emails, but not shared caches. Browsers
+// revalidate them on every request with If-None-Match, so revoked invites are never shown.
+const inviteCacheControl = "private, no-cache"
+
+// invitesETag identifies a list of invites by the ID and version of each invite. Every update of an
+// invite increments its version, so the ETag changes whenever an invite of the list changes, is
+// added or leaves the list.
+func invitesETag(invites []*models.TempUserDTO) string {
+	hash := sha256.New()
+	for _, invite := range invites {
+		_, _ = fmt.Fprintf(hash, "%d:%d,", invite.Id, invite.Version)
+	}
+	return `W/"` + hex.EncodeToString(hash.Sum(nil))[:32] + `"`
+}
+
+// inviteNotModified returns a 304 response when the If-None-Match header of the request matches the
+// ETag, nil otherwise. ETags are compared with the weak comparison of RFC 7232.
+func inviteNotModified(c *models.ReqContext, etag string) response.Response {
+	ifNoneMatch := c.Req.Header.Get("If-None-Match")
+	if ifNoneMatch == "" {
+		return nil
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return response.Respond(http.StatusNotModified, []byte{}).
+				SetHeader("ETag", etag).
+				SetHeader("Cache-Control", inviteCacheControl)
+		}
+	}
+	return nil
+}
+
+// swagger:response notModifiedResponse
+type NotModifiedResponse struct {
+	// in:header
+	ETag string `json:"ETag"`
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/temp_user/invitetest"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestInvitesConditionalRequests(t *testing.T) {
+	setup := func(t *testing.T) (accessControlScenarioContext, *invitetest.Stack) {
+		sc, stack := setupInviteHTTPServer(t)
+		for i := 0; i < 2; i++ {
+			stack.Invite(fmt.Sprintf("invitee%d@example.com", i)).WithCode(fmt.Sprintf("invite-code-%d", i)).Create(t)
+		}
+		return sc, stack
+	}
+	get := func(t *testing.T, sc accessControlScenarioContext, url string, etag string) *httptest.ResponseRecorder {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		require.NoError(t, err)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		recorder := httptest.NewRecorder()
+		sc.server.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	t.Run("pending invites are not sent again until one changes", func(t *testing.T) {
+		sc, stack := setup(t)
+		setInitCtxSignedInViewer(sc.initCtx)
+		setAccessControlPermissions(sc.acmock, []accesscontrol.Permission{{Action: accesscontrol.ActionOrgInvitesRead, Scope: accesscontrol.ScopeInvitesAll}}, sc.initCtx.OrgID)
+
+		response := get(t, sc, "/api/org/invites", "")
+		require.Equal(t, http.StatusOK, response.Code)
+		etag := response.Header().Get("ETag")
+		require.NotEmpty(t, etag)
+		assert.Equal(t, inviteCacheControl, response.Header().Get("Cache-Control"))
+
+		response = get(t, sc, "/api/org/invites", `"other", `+etag)
+		assert.Equal(t, http.StatusNotModified, response.Code)
+		assert.Empty(t, response.Body.Bytes())
+		assert.Equal(t, etag, response.Header().Get("ETag"))
+
+		require.NoError(t, stack.TempUsers.UpdateTempUserWithEmailSent(context.Background(), &models.UpdateTempUserWithEmailSentCommand{Code: "invite-code-1"}))
+		response = get(t, sc, "/api/org/invites", etag)
+		require.Equal(t, http.StatusOK, response.Code)
+		changed := response.Header().Get("ETag")
+		assert.NotEqual(t, etag, changed)
+
+		stack.Revoke(t, "invite-code-0")
+		response = get(t, sc, "/api/org/invites", changed)
+		require.Equal(t, http.StatusOK, response.Code)
+		assert.NotEqual(t, changed, response.Header().Get("ETag"))
+	})
+
+	t.Run("the invite landing page is revalidated with the invite version", func(t *testing.T) {
+		sc, stack := setup(t)
+		// invitees are not signed in
+		sc.initCtx.SignedInUser = &user.SignedInUser{}
+
+		response := get(t, sc, "/api/user/invite/invite-code-0", "")
+		require.Equal(t, http.StatusOK, response.Code)
+		etag := response.Header().Get("ETag")
+		assert.Equal(t, inviteCacheControl, response.Header().Get("Cache-Control"))
+
+		// recording that the link was opened doesn't change the ETag returned with it
+		response = get(t, sc, "/api/user/invite/invite-code-0", etag)
+		assert.Equal(t, http.StatusNotModified, response.Code)
+		assert.Equal(t, etag, get(t, sc, "/api/user/invite/invite-code-0", "").Header().Get("ETag"))
+
+		stack.Revoke(t, "invite-code-0")
+		response = get(t, sc, "/api/user/invite/invite-code-0", etag)
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+}
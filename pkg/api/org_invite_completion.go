@@ -0,0 +1,83 @@
+package api
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/grafana/grafana/pkg/models"
+)
+
+const (
+	// inviteAnomalyMinCompletions is the number of invites an organization needs to have completed
+	// from known countries before completions from unusual countries are flagged
+	inviteAnomalyMinCompletions = 10
+	// inviteAnomalyMaxShare is the share of the completed invites of the organization under which
+	// completions from a country are flagged
+	inviteAnomalyMaxShare = 0.05
+	// maxInviteUserAgentLength is the length of the completed_user_agent column
+	maxInviteUserAgentLength = 255
+)
+
+var inviteCountryPattern = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// inviteCompletion returns the device and coarse location of the request completing an invite. The
+// country is read from the header configured with invite_country_header, which the proxy or CDN in
+// front of Grafana sets from the IP address of the client.
+func (hs *HTTPServer) inviteCompletion(c *models.ReqContext) *models.InviteCompletion {
+	completion := &models.InviteCompletion{
+		RemoteAddr: c.RemoteAddr(),
+		UserAgent:  truncateUserAgent(c.Req.UserAgent()),
+	}
+	if header := hs.Cfg.InviteCountryHeader; header != "" {
+		country := strings.ToUpper(strings.TrimSpace(c.Req.Header.Get(header)))
+		// XX is what CDNs send for unknown countries
+		if inviteCountryPattern.MatchString(country) && country != "XX" {
+			completion.Country = country
+		}
+	}
+	return completion
+}
+
+// flagInviteCompletionAnomaly flags the completion when the invites of the organization are rarely
+// completed from its country, so that security reviews of access grants can start with these.
+// Completions are not flagged when the countries of the organization can't be looked up.
+func (hs *HTTPServer) flagInviteCompletionAnomaly(ctx context.Context, invite *models.TempUserDTO, completion *models.InviteCompletion) {
+	if completion.Country == "" {
+		return
+	}
+	query := models.GetInviteCompletionCountriesQuery{OrgID: invite.OrgId}
+	if err := hs.tempUserService.GetInviteCompletionCountries(ctx, &query); err != nil {
+		hs.log.FromContext(ctx).Warn("Failed to get the countries invites were completed from", "orgId", invite.OrgId, "error", err)
+		return
+	}
+	completion.Anomaly = isInviteCompletionAnomaly(query.Result, completion.Country)
+	if completion.Anomaly {
+		hs.log.FromContext(ctx).Warn("Invite completed from an unusual country", "inviteId", invite.Id, "orgId", invite.OrgId, "country", completion.Country)
+	}
+}
+
+// isInviteCompletionAnomaly returns true when the country has less than inviteAnomalyMaxShare of
+// the completed invites, counted by country, of an organization with enough of them.
+func isInviteCompletionAnomaly(countries map[string]int64, country string) bool {
+	var total int64
+	for _, count := range countries {
+		total += count
+	}
+	if total < inviteAnomalyMinCompletions {
+		return false
+	}
+	return float64(countries[country]) < float64(total)*inviteAnomalyMaxShare
+}
+
+func truncateUserAgent(userAgent string) string {
+	if len(userAgent) <= maxInviteUserAgentLength {
+		return userAgent
+	}
+	userAgent = userAgent[:maxInviteUserAgentLength]
+	for !utf8.ValidString(userAgent) {
+		userAgent = userAgent[:len(userAgent)-1]
+	}
+	return userAgent
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/temp_user/tempusertest"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+func TestInviteCompletion(t *testing.T) {
+	completionOf := func(t *testing.T, countryHeader string, headers map[string]string) *models.InviteCompletion {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, "/api/user/invite/complete", nil)
+		require.NoError(t, err)
+		req.RemoteAddr = "192.0.2.10:51234"
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		cfg := setting.NewCfg()
+		cfg.InviteCountryHeader = countryHeader
+		hs := &HTTPServer{Cfg: cfg}
+		return hs.inviteCompletion(&models.ReqContext{Context: &web.Context{Req: req}})
+	}
+
+	t.Run("records the device and the country of the client", func(t *testing.T) {
+		completion := completionOf(t, "CF-IPCountry", map[string]string{"User-Agent": "Mozilla/5.0", "CF-IPCountry": "fr"})
+		assert.Equal(t, &models.InviteCompletion{RemoteAddr: "192.0.2.10", UserAgent: "Mozilla/5.0", Country: "FR"}, completion)
+	})
+
+	t.Run("ignores the country without a configured header", func(t *testing.T) {
+		completion := completionOf(t, "", map[string]string{"CF-IPCountry": "FR"})
+		assert.Empty(t, completion.Country)
+	})
+
+	t.Run("ignores unknown and invalid countries", func(t *testing.T) {
+		for _, country := range []string{"XX", "France", "F1"} {
+			completion := completionOf(t, "CF-IPCountry", map[string]string{"CF-IPCountry": country})
+			assert.Empty(t, completion.Country, country)
+		}
+	})
+
+	t.Run("truncates long user agents", func(t *testing.T) {
+		completion := completionOf(t, "", map[string]string{"User-Agent": strings.Repeat("é", 200)})
+		assert.LessOrEqual(t, len(completion.UserAgent), maxInviteUserAgentLength)
+		assert.True(t, utf8.ValidString(completion.UserAgent))
+	})
+}
+
+func TestFlagInviteCompletionAnomaly(t *testing.T) {
+	tempUserService := tempusertest.NewFakeTempUserService()
+	tempUserService.CompletionCountries = map[string]int64{"FR": 30, "DE": 10}
+	hs := &HTTPServer{tempUserService: tempUserService, log: log.NewNopLogger()}
+	invite := &models.TempUserDTO{Id: 1, OrgId: 1}
+
+	for _, tt := range []struct {
+		country string
+		anomaly bool
+	}{
+		{"FR", false},
+		{"DE", false},
+		{"BR", true},
+		{"", false},
+	} {
+		completion := &models.InviteCompletion{Country: tt.country}
+		hs.flagInviteCompletionAnomaly(context.Background(), invite, completion)
+		assert.Equal(t, tt.anomaly, completion.Anomaly, tt.country)
+	}
+}
+
+func TestIsInviteCompletionAnomaly(t *testing.T) {
+	// organizations need enough completed invites to tell what is unusual
+	assert.False(t, isInviteCompletionAnomaly(map[string]int64{"FR": 9}, "BR"))
+	assert.True(t, isInviteCompletionAnomaly(map[string]int64{"FR": 10}, "BR"))
+	assert.True(t, isInviteCompletionAnomaly(map[string]int64{"FR": 40, "BR": 1}, "BR"))
+	assert.False(t, isInviteCompletionAnomaly(map[string]int64{"FR": 38, "BR": 2}, "BR"))
+}
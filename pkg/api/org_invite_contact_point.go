@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/notifier"
+	pref "github.com/grafana/grafana/pkg/services/preference"
+)
+
+const (
+	// inviteAlertName is the alertname label of invite notifications, for contact point templates
+	// to tell them apart from alerts
+	inviteAlertName = "GrafanaInvite"
+	// inviteContactPointTimeout bounds the retries of invite notifications
+	inviteContactPointTimeout = 30 * time.Second
+)
+
+// inviteContactPointExists tells whether the organization has an alerting contact point with the name.
+var inviteContactPointExists = func(hs *HTTPServer, orgID int64, contactPoint string) (bool, error) {
+	am, err := hs.inviteAlertmanager(orgID)
+	if err != nil {
+		return false, err
+	}
+	return am.HasReceiver(contactPoint), nil
+}
+
+// notifyInviteContactPoint sends an invite notification to an alerting contact point of the organization.
+var notifyInviteContactPoint = func(ctx context.Context, hs *HTTPServer, orgID int64, contactPoint string, labels, annotations model.LabelSet) error {
+	am, err := hs.inviteAlertmanager(orgID)
+	if err != nil {
+		return err
+	}
+	return am.NotifyReceiver(ctx, contactPoint, labels, annotations)
+}
+
+func (hs *HTTPServer) inviteAlertmanager(orgID int64) (*notifier.Alertmanager, error) {
+	if hs.AlertNG == nil || hs.AlertNG.MultiOrgAlertmanager == nil {
+		return nil, errors.New("unified alerting is not enabled")
+	}
+	return hs.AlertNG.MultiOrgAlertmanager.AlertmanagerFor(orgID)
+}
+
+// checkInviteContactPoint returns an error response when the organization has no contact point
+// with the name.
+func (hs *HTTPServer) checkInviteContactPoint(orgID int64, contactPoint string) response.Response {
+	if contactPoint == "" {
+		return nil
+	}
+	exists, err := inviteContactPointExists(hs, orgID, contactPoint)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get the contact points", err)
+	}
+	if !exists {
+		return response.Error(http.StatusBadRequest, fmt.Sprintf("Contact point %s not found", contactPoint), nil)
+	}
+	return nil
+}
+
+// inviteContactPoint returns the contact point invite notifications of the organization are sent
+// to, empty when they are emailed.
+func (hs *HTTPServer) inviteContactPoint(ctx context.Context, orgID int64) (string, error) {
+	preference, err := hs.preferenceService.Get(ctx, &pref.GetPreferenceQuery{OrgID: orgID})
+	if err != nil {
+		return "", err
+	}
+	if preference == nil || preference.JSONData == nil {
+		return "", nil
+	}
+	return preference.JSONData.Invites.ContactPoint, nil
+}
+
+// sendInviteToOrgContactPoint sends the invite to the invite contact point of the organization
+// when it has one, sent tells whether it did.
+func (hs *HTTPServer) sendInviteToOrgContactPoint(c *models.ReqContext, emailCmd *models.SendEmailCommand, code string) (rsp response.Response, sent bool) {
+	contactPoint, err := hs.inviteContactPoint(c.Req.Context(), c.OrgID)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get the invite contact point", err), true
+	}
+	if contactPoint == "" {
+		return nil, false
+	}
+	return hs.sendInviteToContactPoint(c, contactPoint, emailCmd, code), true
+}
+
+// sendInviteToContactPoint sends the invite email to the contact point instead, with the data of
+// the email as labels and annotations, and records that the invite was sent.
+func (hs *HTTPServer) sendInviteToContactPoint(c *models.ReqContext, contactPoint string, emailCmd *models.SendEmailCommand, code string) response.Response {
+	labels := model.LabelSet{
+		"alertname": inviteAlertName,
+		"invitee":   model.LabelValue(emailCmd.To[0]),
+		"org":       model.LabelValue(fmt.Sprint(emailCmd.Data["OrgName"])),
+	}
+	if invitedBy, ok := emailCmd.Data["InvitedBy"].(string); ok && invitedBy != "" {
+		labels["invited_by"] = model.LabelValue(invitedBy)
+	}
+	annotations := model.LabelSet{
+		"summary": model.LabelValue(fmt.Sprintf("%s is invited to join %s", emailCmd.To[0], emailCmd.Data["OrgName"])),
+	}
+	if link, ok := emailCmd.Data["LinkUrl"].(string); ok {
+		annotations["invite_url"] = model.LabelValue(link)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Req.Context(), inviteContactPointTimeout)
+	defer cancel()
+	if err := notifyInviteContactPoint(ctx, hs, c.OrgID, contactPoint, labels, annotations); err != nil {
+		if errors.Is(err, notifier.ErrReceiverNotFound) {
+			return response.Error(http.StatusPreconditionFailed, fmt.Sprintf("Contact point %s not found", contactPoint), err)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to send invite to contact point", err)
+	}
+
+	emailSentCmd := models.UpdateTempUserWithEmailSentCommand{Code: code}
+	if err := hs.tempUserService.UpdateTempUserWithEmailSent(c.Req.Context(), &emailSentCmd); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to update invite with email sent info", err)
+	}
+	return nil
+}
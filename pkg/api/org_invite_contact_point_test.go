@@ -0,0 +1,92 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/ngalert/notifier"
+	pref "github.com/grafana/grafana/pkg/services/preference"
+	"github.com/grafana/grafana/pkg/services/preference/preftest"
+	"github.com/grafana/grafana/pkg/services/temp_user/invitetest"
+	"github.com/grafana/grafana/pkg/services/user/usertest"
+)
+
+func TestOrgInviteContactPoint(t *testing.T) {
+	type notification struct {
+		contactPoint string
+		labels       model.LabelSet
+		annotations  model.LabelSet
+	}
+	setup := func(t *testing.T, contactPoint string) (accessControlScenarioContext, *invitetest.Stack, *[]notification) {
+		sc, stack := setupInviteHTTPServer(t)
+		sc.hs.kvStore = kvstore.ProvideService(sc.db)
+		sc.hs.userService = &slackInviteUserService{&usertest.FakeUserService{}}
+		sc.hs.preferenceService = &preftest.FakePreferenceService{ExpectedPreference: &pref.Preference{
+			JSONData: &pref.PreferenceJSONData{Invites: pref.InvitesPreference{ContactPoint: contactPoint}},
+		}}
+		setInitCtxSignedInOrgAdmin(sc.initCtx)
+		setAccessControlPermissions(sc.acmock, []accesscontrol.Permission{
+			{Action: accesscontrol.ActionOrgUsersAdd, Scope: accesscontrol.ScopeUsersAll},
+			{Action: accesscontrol.ActionOrgsPreferencesWrite},
+		}, sc.initCtx.OrgID)
+
+		var sent []notification
+		origNotify, origExists := notifyInviteContactPoint, inviteContactPointExists
+		t.Cleanup(func() { notifyInviteContactPoint, inviteContactPointExists = origNotify, origExists })
+		notifyInviteContactPoint = func(_ context.Context, _ *HTTPServer, _ int64, contactPoint string, labels, annotations model.LabelSet) error {
+			if contactPoint != "onboarding" {
+				return notifier.ErrReceiverNotFound
+			}
+			sent = append(sent, notification{contactPoint: contactPoint, labels: labels, annotations: annotations})
+			return nil
+		}
+		inviteContactPointExists = func(_ *HTTPServer, _ int64, contactPoint string) (bool, error) {
+			return contactPoint == "onboarding", nil
+		}
+		return sc, stack, &sent
+	}
+
+	t.Run("invites are sent to the contact point of the organization", func(t *testing.T) {
+		sc, stack, sent := setup(t, "onboarding")
+
+		response := callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(`{"loginOrEmail": "new@example.com", "role": "Viewer", "sendEmail": true}`), t)
+		require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+
+		require.Len(t, *sent, 1)
+		n := (*sent)[0]
+		assert.Equal(t, model.LabelValue(inviteAlertName), n.labels["alertname"])
+		assert.Equal(t, model.LabelValue("new@example.com"), n.labels["invitee"])
+		assert.Contains(t, string(n.annotations["invite_url"]), "invite/")
+
+		pending := stack.Pending(t, 1)
+		require.Len(t, pending, 1)
+		assert.Equal(t, "new@example.com", pending[0].Email)
+		assert.True(t, pending[0].EmailSent)
+	})
+
+	t.Run("a contact point which no longer exists fails the invite", func(t *testing.T) {
+		sc, _, sent := setup(t, "deleted")
+
+		response := callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(`{"loginOrEmail": "new@example.com", "role": "Viewer", "sendEmail": true}`), t)
+		assert.Equal(t, http.StatusPreconditionFailed, response.Code)
+		assert.Empty(t, *sent)
+	})
+
+	t.Run("the contact point must exist", func(t *testing.T) {
+		sc, _, _ := setup(t, "")
+		patch := func(body string) int {
+			return callAPI(sc.server, http.MethodPatch, patchOrgPreferencesUrl, strings.NewReader(body), t).Code
+		}
+
+		assert.Equal(t, http.StatusOK, patch(`{"invites": {"contactPoint": "onboarding"}}`))
+		assert.Equal(t, http.StatusBadRequest, patch(`{"invites": {"contactPoint": "unknown"}}`))
+	})
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"regexp"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+const (
+	inviteCorrelationIDHeader = "X-Grafana-Correlation-Id"
+	inviteCorrelationIDField  = "correlationId"
+)
+
+// inviteCorrelationIDPattern restricts the correlation IDs sent by clients to values which are
+// safe to log and to echo back in a header
+var inviteCorrelationIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+type inviteCorrelationIDKey struct{}
+
+func init() {
+	log.RegisterContextualLogProvider(func(ctx context.Context) ([]interface{}, bool) {
+		if id := inviteCorrelationIDFromContext(ctx); id != "" {
+			return []interface{}{inviteCorrelationIDField, id}, true
+		}
+		return nil, false
+	})
+}
+
+func inviteCorrelationIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(inviteCorrelationIDKey{}).(string)
+	return id
+}
+
+// withInviteCorrelationID tags an invite request with a correlation ID, so that the errors the
+// frontend reports during onboarding can be matched with the backend logs and traces. The ID is
+// the one of the X-Grafana-Correlation-Id header when the client sent a valid one, otherwise the
+// trace ID of the request. It is added to the logs of the request, including the ones of the
+// database, email, access control and login calls made with its context, to its span, and to the
+// response header and JSON body.
+func (hs *HTTPServer) withInviteCorrelationID(handler func(c *models.ReqContext) response.Response) func(c *models.ReqContext) response.Response {
+	return func(c *models.ReqContext) response.Response {
+		id := c.Req.Header.Get(inviteCorrelationIDHeader)
+		if id != "" && !inviteCorrelationIDPattern.MatchString(id) {
+			c.Logger.Debug("Ignoring invalid correlation ID", "header", inviteCorrelationIDHeader)
+			id = ""
+		}
+		if id == "" {
+			id = tracing.TraceIDFromContext(c.Req.Context(), false)
+		}
+		if id == "" {
+			id = util.GenerateShortUID()
+		}
+
+		ctx := context.WithValue(c.Req.Context(), inviteCorrelationIDKey{}, id)
+		if hs.tracer != nil {
+			var span tracing.Span
+			ctx, span = hs.tracer.Start(ctx, "api.invite")
+			span.SetAttributes(inviteCorrelationIDField, id, attribute.String(inviteCorrelationIDField, id))
+			defer span.End()
+		}
+		c.Req = c.Req.WithContext(ctx)
+		c.Logger = c.Logger.New(inviteCorrelationIDField, id)
+
+		rsp := handler(c)
+		normal, ok := rsp.(*response.NormalResponse)
+		if !ok || normal.Header() == nil {
+			return rsp
+		}
+		return normal.SetHeader(inviteCorrelationIDHeader, id).SetJSONField(inviteCorrelationIDField, id)
+	}
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/user/usertest"
+)
+
+func TestInviteCorrelationID(t *testing.T) {
+	sc, _ := setupInviteHTTPServer(t)
+	sc.hs.userService = &slackInviteUserService{&usertest.FakeUserService{}}
+	setInitCtxSignedInOrgAdmin(sc.initCtx)
+	setAccessControlPermissions(sc.acmock, []accesscontrol.Permission{
+		{Action: accesscontrol.ActionOrgUsersAdd, Scope: accesscontrol.ScopeUsersAll},
+	}, sc.initCtx.OrgID)
+
+	call := func(t *testing.T, url, body, correlationID string) (*httptest.ResponseRecorder, string) {
+		req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if correlationID != "" {
+			req.Header.Set(inviteCorrelationIDHeader, correlationID)
+		}
+		recorder := httptest.NewRecorder()
+		sc.server.ServeHTTP(recorder, req)
+
+		var rsp struct {
+			CorrelationID string `json:"correlationId"`
+		}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+		assert.Equal(t, recorder.Header().Get(inviteCorrelationIDHeader), rsp.CorrelationID)
+		return recorder, rsp.CorrelationID
+	}
+
+	t.Run("the correlation ID sent by the client is returned", func(t *testing.T) {
+		recorder, id := call(t, "/api/org/invites", `{"loginOrEmail": "new@example.com", "role": "Viewer"}`, "onboarding-42")
+		require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+		assert.Equal(t, "onboarding-42", id)
+	})
+
+	t.Run("errors return the correlation ID", func(t *testing.T) {
+		recorder, id := call(t, "/api/user/invite/complete", `{"inviteCode": "unknown", "email": "new@example.com"}`, "onboarding-43")
+		require.Equal(t, http.StatusNotFound, recorder.Code)
+		assert.Equal(t, "onboarding-43", id)
+	})
+
+	t.Run("a correlation ID is generated without a valid one from the client", func(t *testing.T) {
+		for _, correlationID := range []string{"", "not a valid id", strings.Repeat("a", 65)} {
+			recorder, id := call(t, "/api/user/invite/complete", `{"inviteCode": "unknown", "email": "new@example.com"}`, correlationID)
+			require.Equal(t, http.StatusNotFound, recorder.Code)
+			assert.NotEmpty(t, id)
+			assert.NotEqual(t, correlationID, id)
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/models"
+	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
+	pref "github.com/grafana/grafana/pkg/services/preference"
+)
+
+// validateInvitesPreference checks the invite defaults set through the preferences API. They
+// can only be set for organizations, their role must be assignable in the organization and their
+// teams and contact point must belong to it.
+func (hs *HTTPServer) validateInvitesPreference(ctx context.Context, orgID, userID, teamID int64, invites *pref.InvitesPreference) response.Response {
+	if invites == nil {
+		return nil
+	}
+	if userID != 0 || teamID != 0 {
+		return response.Error(http.StatusBadRequest, "Invite defaults can only be set for the organization", nil)
+	}
+	if invites.DefaultRole != "" {
+		if !invites.DefaultRole.IsValid() {
+			return response.Error(http.StatusBadRequest, "Invalid default invite role", nil)
+		}
+		assignable, _, err := hs.isAssignableRole(ctx, orgID, invites.DefaultRole)
+		if err != nil {
+			return response.Error(http.StatusInternalServerError, "Failed to get the assignable roles", err)
+		}
+		if !assignable {
+			return response.Error(http.StatusBadRequest, fmt.Sprintf("The %s role can't be assigned in this organization", invites.DefaultRole), nil)
+		}
+	}
+	if rsp := hs.checkInviteContactPoint(orgID, invites.ContactPoint); rsp != nil {
+		return rsp
+	}
+	return hs.checkInviteTeams(ctx, orgID, invites.DefaultTeams)
+}
+
+// checkInviteTeams returns an error response when one of the teams is not a team of the organization.
+func (hs *HTTPServer) checkInviteTeams(ctx context.Context, orgID int64, teams []int64) response.Response {
+	for _, teamID := range teams {
+		query := models.GetTeamByIdQuery{OrgId: orgID, Id: teamID}
+		if err := hs.teamService.GetTeamById(ctx, &query); err != nil {
+			if errors.Is(err, models.ErrTeamNotFound) {
+				return response.Error(http.StatusBadRequest, fmt.Sprintf("Team %d not found", teamID), nil)
+			}
+			return response.Error(http.StatusInternalServerError, "Failed to get team", err)
+		}
+	}
+	return nil
+}
+
+// applyInviteDefaults fills in the role and the teams the invite does not specify with the
+// invite defaults of the organization. Teams given explicitly require the permission to add
+// members to them, the defaults were chosen by an administrator of the organization.
+func (hs *HTTPServer) applyInviteDefaults(c *models.ReqContext, inviteDto *dtos.AddInviteForm) response.Response {
+	defaults, err := hs.inviteDefaults(c.Req.Context(), c.OrgID)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get the invite defaults", err)
+	}
+
+	if inviteDto.Role == "" {
+		inviteDto.Role = defaults.DefaultRole
+	}
+	if inviteDto.Teams == nil {
+		inviteDto.Teams = defaults.DefaultTeams
+		return nil
+	}
+
+	for _, teamID := range inviteDto.Teams {
+		scope := ac.Scope("teams", "id", strconv.FormatInt(teamID, 10))
+		hasAccess, err := hs.AccessControl.Evaluate(c.Req.Context(), c.SignedInUser, ac.EvalPermission(ac.ActionTeamsPermissionsWrite, scope))
+		if err != nil {
+			return response.Error(http.StatusInternalServerError, "Failed to evaluate permissions", err)
+		}
+		if !hasAccess {
+			return response.Error(http.StatusForbidden, fmt.Sprintf("Permission denied: not permitted to add members to team %d", teamID), nil)
+		}
+	}
+	return hs.checkInviteTeams(c.Req.Context(), c.OrgID, inviteDto.Teams)
+}
+
+// inviteDefaults returns the invite defaults of the organization.
+func (hs *HTTPServer) inviteDefaults(ctx context.Context, orgID int64) (pref.InvitesPreference, error) {
+	preference, err := hs.preferenceService.Get(ctx, &pref.GetPreferenceQuery{OrgID: orgID})
+	if err != nil {
+		return pref.InvitesPreference{}, err
+	}
+	if preference == nil || preference.JSONData == nil {
+		return pref.InvitesPreference{}, nil
+	}
+	return preference.JSONData.Invites, nil
+}
+
+// addInviteTeams records the teams the invitee joins when the invite is accepted.
+func (hs *HTTPServer) addInviteTeams(ctx context.Context, orgID, inviteID int64, teams []int64) response.Response {
+	for _, teamID := range teams {
+		cmd := models.AddTempUserGrantCommand{
+			OrgID:        orgID,
+			TempUserID:   inviteID,
+			ResourceKind: models.TempUserGrantTeam,
+			ResourceUID:  strconv.FormatInt(teamID, 10),
+			Permission:   getPermissionName(0),
+		}
+		if err := hs.tempUserService.AddTempUserGrant(ctx, &cmd); err != nil {
+			return response.Error(http.StatusInternalServerError, "Failed to save invite team", err)
+		}
+	}
+	return nil
+}
+
+// joinInviteTeam adds the user who accepted an invite to one of its teams.
+func (hs *HTTPServer) joinInviteTeam(ctx context.Context, orgID, userID int64, grant *models.TempUserGrant) error {
+	teamID, err := strconv.ParseInt(grant.ResourceUid, 10, 64)
+	if err != nil {
+		return err
+	}
+	return addOrUpdateTeamMember(ctx, hs.teamPermissionsService, userID, orgID, teamID, grant.Permission)
+}
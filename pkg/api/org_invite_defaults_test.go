@@ -0,0 +1,151 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/org"
+	pref "github.com/grafana/grafana/pkg/services/preference"
+	"github.com/grafana/grafana/pkg/services/preference/preftest"
+	tempuser "github.com/grafana/grafana/pkg/services/temp_user"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/services/user/usertest"
+)
+
+// failingGrantsTempUserService fails to save the teams and organizations of invites while fail is set.
+type failingGrantsTempUserService struct {
+	tempuser.Service
+	fail bool
+}
+
+func (s *failingGrantsTempUserService) AddTempUserGrant(ctx context.Context, cmd *models.AddTempUserGrantCommand) error {
+	if s.fail {
+		return errors.New("database is gone")
+	}
+	return s.Service.AddTempUserGrant(ctx, cmd)
+}
+
+func TestOrgInviteDefaults(t *testing.T) {
+	setup := func(t *testing.T, perms ...accesscontrol.Permission) (accessControlScenarioContext, models.Team) {
+		sc, _ := setupInviteHTTPServer(t)
+		sc.hs.userService = &slackInviteUserService{&usertest.FakeUserService{}}
+		team, err := sc.hs.teamService.CreateTeam("onboarding", "", 1)
+		require.NoError(t, err)
+		sc.hs.preferenceService = &preftest.FakePreferenceService{ExpectedPreference: &pref.Preference{
+			JSONData: &pref.PreferenceJSONData{Invites: pref.InvitesPreference{DefaultRole: org.RoleEditor, DefaultTeams: []int64{team.Id}}},
+		}}
+
+		setInitCtxSignedInOrgAdmin(sc.initCtx)
+		perms = append(perms, accesscontrol.Permission{Action: accesscontrol.ActionOrgUsersAdd, Scope: accesscontrol.ScopeUsersAll})
+		setAccessControlPermissions(sc.acmock, perms, sc.initCtx.OrgID)
+		return sc, team
+	}
+
+	getInvite := func(t *testing.T, sc accessControlScenarioContext, email string) (*models.TempUserDTO, []*models.TempUserGrant) {
+		query := models.GetTempUsersQuery{OrgId: 1, Email: email, Status: models.TmpUserInvitePending}
+		require.NoError(t, sc.hs.tempUserService.GetTempUsersQuery(context.Background(), &query))
+		require.Len(t, query.Result, 1)
+		grants := models.GetTempUserGrantsQuery{TempUserID: query.Result[0].Id}
+		require.NoError(t, sc.hs.tempUserService.GetTempUserGrants(context.Background(), &grants))
+		return query.Result[0], grants.Result
+	}
+
+	t.Run("invites without a role or teams get the defaults of the organization", func(t *testing.T) {
+		sc, team := setup(t)
+
+		response := callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(`{"loginOrEmail": "new@example.com"}`), t)
+		require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+
+		invite, grants := getInvite(t, sc, "new@example.com")
+		assert.Equal(t, org.RoleEditor, invite.Role)
+		require.Len(t, grants, 1)
+		assert.Equal(t, models.TempUserGrantTeam, grants[0].ResourceKind)
+		assert.Equal(t, strconv.FormatInt(team.Id, 10), grants[0].ResourceUid)
+
+		t.Run("accepting it joins the teams", func(t *testing.T) {
+			origAddOrUpdateTeamMember := addOrUpdateTeamMember
+			t.Cleanup(func() { addOrUpdateTeamMember = origAddOrUpdateTeamMember })
+			var joined []int64
+			addOrUpdateTeamMember = func(ctx context.Context, _ accesscontrol.TeamPermissionsService, userID, orgID, teamID int64, permission string) error {
+				joined = append(joined, teamID)
+				assert.Equal(t, "Member", permission)
+				return nil
+			}
+
+			// the first user creates the organization
+			_, err := sc.db.CreateUser(context.Background(), user.CreateUserCommand{Login: "admin"})
+			require.NoError(t, err)
+			usr, err := sc.db.CreateUser(context.Background(), user.CreateUserCommand{Email: "new@example.com", Login: "new", SkipOrgSetup: true})
+			require.NoError(t, err)
+			ok, rsp := sc.hs.applyUserInvite(context.Background(), usr, invite, false, nil)
+			require.True(t, ok, "%v", rsp)
+			assert.Equal(t, []int64{team.Id}, joined)
+		})
+	})
+
+	t.Run("invites are saved with their teams or not at all", func(t *testing.T) {
+		sc, _ := setup(t)
+		sc.hs.Cfg.UniquePendingInvites = true
+		tempUsers := &failingGrantsTempUserService{Service: sc.hs.tempUserService, fail: true}
+		sc.hs.tempUserService = tempUsers
+
+		response := callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(`{"loginOrEmail": "new@example.com"}`), t)
+		require.Equal(t, http.StatusInternalServerError, response.Code, response.Body.String())
+		query := models.GetTempUsersQuery{OrgId: 1, Email: "new@example.com"}
+		require.NoError(t, sc.hs.tempUserService.GetTempUsersQuery(context.Background(), &query))
+		assert.Empty(t, query.Result)
+
+		// the failed invite doesn't block the next attempt
+		tempUsers.fail = false
+		response = callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(`{"loginOrEmail": "new@example.com"}`), t)
+		require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+		_, grants := getInvite(t, sc, "new@example.com")
+		assert.Len(t, grants, 1)
+	})
+
+	t.Run("an empty list of teams joins none", func(t *testing.T) {
+		sc, _ := setup(t)
+
+		response := callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(`{"loginOrEmail": "new@example.com", "role": "Viewer", "teams": []}`), t)
+		require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+
+		invite, grants := getInvite(t, sc, "new@example.com")
+		assert.Equal(t, org.RoleViewer, invite.Role)
+		assert.Empty(t, grants)
+	})
+
+	t.Run("given teams require the permission to add members to them", func(t *testing.T) {
+		sc, team := setup(t)
+		body := fmt.Sprintf(`{"loginOrEmail": "new@example.com", "teams": [%d]}`, team.Id)
+
+		response := callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(body), t)
+		assert.Equal(t, http.StatusForbidden, response.Code)
+
+		sc, team = setup(t, accesscontrol.Permission{Action: accesscontrol.ActionTeamsPermissionsWrite, Scope: accesscontrol.ScopeTeamsAll})
+		body = fmt.Sprintf(`{"loginOrEmail": "new@example.com", "teams": [%d, 42]}`, team.Id)
+		response = callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(body), t)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+
+	t.Run("invite defaults are validated", func(t *testing.T) {
+		sc, team := setup(t, accesscontrol.Permission{Action: accesscontrol.ActionOrgsPreferencesWrite})
+		patch := func(url, body string) int {
+			return callAPI(sc.server, http.MethodPatch, url, strings.NewReader(body), t).Code
+		}
+
+		assert.Equal(t, http.StatusOK, patch(patchOrgPreferencesUrl, fmt.Sprintf(`{"invites": {"defaultRole": "Viewer", "defaultTeams": [%d]}}`, team.Id)))
+		assert.Equal(t, http.StatusBadRequest, patch(patchOrgPreferencesUrl, `{"invites": {"defaultRole": "Owner"}}`))
+		assert.Equal(t, http.StatusBadRequest, patch(patchOrgPreferencesUrl, `{"invites": {"defaultTeams": [42]}}`))
+		assert.Equal(t, http.StatusBadRequest, patch(patchUserPreferencesUrl, `{"invites": {"defaultRole": "Viewer"}}`))
+	})
+}
@@ -0,0 +1,356 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/notifications"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/util"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+const (
+	inviteDomainsKey          = "verified-domains"
+	maxInviteDomains          = 50
+	inviteDomainRecordPrefix  = "_grafana-invite-challenge."
+	inviteDomainRecordValue   = "grafana-invite-verification="
+	inviteDomainTokenLength   = 32
+	inviteDomainLookupTimeout = 10 * time.Second
+)
+
+var inviteDomainPattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,63}$`)
+
+// inviteDomainResolver looks up the TXT records proving the ownership of domains, tests replace it.
+var inviteDomainResolver notifications.TXTResolver = net.DefaultResolver
+
+// swagger:route GET /org/invites/domains org_invites getOrgInviteDomains
+//
+// Get the email domains claimed by the organization, and whether their ownership is verified.
+//
+// Responses:
+// 200: getOrgInviteDomainsResponse
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (hs *HTTPServer) GetOrgInviteDomains(c *models.ReqContext) response.Response {
+	domains, err := hs.getInviteDomains(c.Req.Context(), c.OrgID)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get invite domains", err)
+	}
+	return response.JSON(http.StatusOK, dtos.InviteDomains{Domains: domains})
+}
+
+// swagger:route POST /org/invites/domains org_invites addOrgInviteDomain
+//
+// Claim an email domain for the invites of the organization.
+//
+// The response tells the TXT record to publish in the DNS zone of the domain to prove the
+// organization owns it, before verifying the domain. Invites to the domain get its options once
+// it is verified.
+//
+// Responses:
+// 200: orgInviteDomainResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 409: conflictError
+// 500: internalServerError
+func (hs *HTTPServer) AddOrgInviteDomain(c *models.ReqContext) response.Response {
+	form := dtos.AddInviteDomainForm{}
+	if err := web.Bind(c.Req, &form); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	name := normalizeInviteDomain(form.Domain)
+	if !inviteDomainPattern.MatchString(name) {
+		return response.Error(http.StatusBadRequest, fmt.Sprintf("Invalid domain %q", form.Domain), nil)
+	}
+
+	domains, err := hs.getInviteDomains(c.Req.Context(), c.OrgID)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get invite domains", err)
+	}
+	if findInviteDomain(domains, name) >= 0 {
+		return response.Error(http.StatusConflict, fmt.Sprintf("The domain %s is already claimed by the organization", name), nil)
+	}
+	if len(domains) >= maxInviteDomains {
+		return response.Error(http.StatusBadRequest, fmt.Sprintf("Organizations can claim at most %d invite domains", maxInviteDomains), nil)
+	}
+
+	token, err := util.GetRandomString(inviteDomainTokenLength)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Could not generate random string", err)
+	}
+	domain := dtos.InviteDomain{
+		Domain:              name,
+		RecordName:          inviteDomainRecordPrefix + name,
+		RecordValue:         inviteDomainRecordValue + token,
+		Created:             time.Now(),
+		InviteDomainOptions: form.InviteDomainOptions,
+	}
+	if err := hs.saveInviteDomains(c.Req.Context(), c.OrgID, append(domains, domain)); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to save invite domains", err)
+	}
+	return response.JSON(http.StatusOK, domain)
+}
+
+// swagger:route PUT /org/invites/domains/{domain} org_invites updateOrgInviteDomain
+//
+// Update the options of invites to an email domain claimed by the organization.
+//
+// Responses:
+// 200: orgInviteDomainResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (hs *HTTPServer) UpdateOrgInviteDomain(c *models.ReqContext) response.Response {
+	form := dtos.InviteDomainOptions{}
+	if err := web.Bind(c.Req, &form); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	return hs.updateInviteDomain(c, func(domain *dtos.InviteDomain) response.Response {
+		domain.InviteDomainOptions = form
+		return nil
+	})
+}
+
+// swagger:route POST /org/invites/domains/{domain}/verify org_invites verifyOrgInviteDomain
+//
+// Verify the organization owns an email domain it claimed, by looking up its TXT record.
+//
+// Verification can be run again at any time. A domain stays verified when the record is removed
+// afterwards.
+//
+// Responses:
+// 200: orgInviteDomainResponse
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 422: unprocessableEntityError
+// 500: internalServerError
+func (hs *HTTPServer) VerifyOrgInviteDomain(c *models.ReqContext) response.Response {
+	return hs.updateInviteDomain(c, func(domain *dtos.InviteDomain) response.Response {
+		ctx, cancel := context.WithTimeout(c.Req.Context(), inviteDomainLookupTimeout)
+		defer cancel()
+		records, err := inviteDomainResolver.LookupTXT(ctx, domain.RecordName)
+		var dnsErr *net.DNSError
+		if err != nil && !(errors.As(err, &dnsErr) && dnsErr.IsNotFound) {
+			return response.Error(http.StatusUnprocessableEntity, fmt.Sprintf("Failed to look up the TXT record %s", domain.RecordName), err)
+		}
+		for _, record := range records {
+			if strings.TrimSpace(record) == domain.RecordValue {
+				now := time.Now()
+				domain.Verified = true
+				domain.VerifiedAt = &now
+				return nil
+			}
+		}
+		return response.Error(http.StatusUnprocessableEntity, fmt.Sprintf("The TXT record %s does not have the value %s", domain.RecordName, domain.RecordValue), nil)
+	})
+}
+
+// swagger:route DELETE /org/invites/domains/{domain} org_invites deleteOrgInviteDomain
+//
+// Give up the claim of the organization on an email domain. Invites to the domain no longer get
+// its options.
+//
+// Responses:
+// 200: okResponse
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (hs *HTTPServer) DeleteOrgInviteDomain(c *models.ReqContext) response.Response {
+	name := normalizeInviteDomain(web.Params(c.Req)[":domain"])
+	domains, err := hs.getInviteDomains(c.Req.Context(), c.OrgID)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get invite domains", err)
+	}
+	i := findInviteDomain(domains, name)
+	if i < 0 {
+		return response.Error(http.StatusNotFound, "Invite domain not found", nil)
+	}
+	if err := hs.saveInviteDomains(c.Req.Context(), c.OrgID, append(domains[:i], domains[i+1:]...)); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to save invite domains", err)
+	}
+	return response.Success(fmt.Sprintf("Invite domain %s deleted", name))
+}
+
+// updateInviteDomain applies update to the domain of the request and saves it, unless update
+// returns an error response.
+func (hs *HTTPServer) updateInviteDomain(c *models.ReqContext, update func(domain *dtos.InviteDomain) response.Response) response.Response {
+	name := normalizeInviteDomain(web.Params(c.Req)[":domain"])
+	domains, err := hs.getInviteDomains(c.Req.Context(), c.OrgID)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get invite domains", err)
+	}
+	i := findInviteDomain(domains, name)
+	if i < 0 {
+		return response.Error(http.StatusNotFound, "Invite domain not found", nil)
+	}
+	if rsp := update(&domains[i]); rsp != nil {
+		return rsp
+	}
+	if err := hs.saveInviteDomains(c.Req.Context(), c.OrgID, domains); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to save invite domains", err)
+	}
+	return response.JSON(http.StatusOK, domains[i])
+}
+
+func (hs *HTTPServer) getInviteDomains(ctx context.Context, orgID int64) ([]dtos.InviteDomain, error) {
+	domains := []dtos.InviteDomain{}
+	value, ok, err := kvstore.WithNamespace(hs.kvStore, orgID, orgInvitesKVNamespace).Get(ctx, inviteDomainsKey)
+	if err != nil || !ok {
+		return domains, err
+	}
+	err = json.Unmarshal([]byte(value), &domains)
+	return domains, err
+}
+
+func (hs *HTTPServer) saveInviteDomains(ctx context.Context, orgID int64, domains []dtos.InviteDomain) error {
+	value, err := json.Marshal(domains)
+	if err != nil {
+		return err
+	}
+	return kvstore.WithNamespace(hs.kvStore, orgID, orgInvitesKVNamespace).Set(ctx, inviteDomainsKey, string(value))
+}
+
+// verifiedInviteDomain returns the verified domain of the organization the email belongs to, or
+// nil. Subdomains are other domains. Invites get no exemption when the domains can't be read.
+func (hs *HTTPServer) verifiedInviteDomain(ctx context.Context, orgID int64, email string) *dtos.InviteDomain {
+	name := inviteEmailDomain(email)
+	if name == "" {
+		return nil
+	}
+	domains, err := hs.getInviteDomains(ctx, orgID)
+	if err != nil {
+		hs.log.FromContext(ctx).Warn("Failed to get invite domains", "orgId", orgID, "error", err)
+		return nil
+	}
+	if i := findInviteDomain(domains, name); i >= 0 && domains[i].Verified {
+		return &domains[i]
+	}
+	return nil
+}
+
+// invitePolicy returns the invite policy for invites of the organization to the email, exempted
+// when the email belongs to a verified domain exempted from it.
+func (hs *HTTPServer) invitePolicy(ctx context.Context, orgID int64, email string) invitePolicy {
+	policy := newInvitePolicy(hs.Cfg)
+	if domain := hs.verifiedInviteDomain(ctx, orgID, email); domain != nil && domain.ExemptFromPolicy {
+		policy.exemptDomain = domain.Domain
+	}
+	return policy
+}
+
+// autoApproveInvite completes the invite of an existing user right away when their email belongs
+// to a verified domain with auto-approval, it returns whether it did.
+func (hs *HTTPServer) autoApproveInvite(c *models.ReqContext, usr *user.User, code string) (bool, response.Response) {
+	domain := hs.verifiedInviteDomain(c.Req.Context(), c.OrgID, usr.Email)
+	if domain == nil || !domain.AutoApprove {
+		return false, nil
+	}
+	query := models.GetTempUserByCodeQuery{Code: code}
+	if err := hs.tempUserService.GetTempUserByCode(c.Req.Context(), &query); err != nil {
+		return false, response.Error(http.StatusInternalServerError, "Failed to get invite", err)
+	}
+	return hs.applyUserInvite(c.Req.Context(), usr, query.Result, false, nil)
+}
+
+// completeSSOInvites completes the pending invites of a user signing in with SSO, in the
+// organizations which verified the domain of their email and enabled auto-completion. Viewer
+// tokens are left for the user to open. Sign in goes on when invites can't be completed.
+func (hs *HTTPServer) completeSSOInvites(c *models.ReqContext, usr *user.User) {
+	ctx := c.Req.Context()
+	if inviteEmailDomain(usr.Email) == "" {
+		return
+	}
+	query := models.GetTempUsersQuery{Email: usr.Email, Status: models.TmpUserInvitePending}
+	if err := hs.tempUserService.GetTempUsersQuery(ctx, &query); err != nil {
+		hs.log.FromContext(ctx).Warn("Failed to get the invites of the user signing in", "userId", usr.ID, "error", err)
+		return
+	}
+	for _, invite := range query.Result {
+		if invite.AccessExpires != nil || !invite.EmailMatch.Matches(invite.Email, usr.Email) {
+			continue
+		}
+		domain := hs.verifiedInviteDomain(ctx, invite.OrgId, usr.Email)
+		if domain == nil || !domain.AutoComplete {
+			continue
+		}
+		if ok, _ := hs.applyUserInvite(ctx, usr, invite, false, hs.inviteCompletion(c)); !ok {
+			hs.log.FromContext(ctx).Warn("Failed to complete the invite of the user signing in", "inviteId", invite.Id, "userId", usr.ID)
+		}
+	}
+}
+
+func findInviteDomain(domains []dtos.InviteDomain, name string) int {
+	for i := range domains {
+		if domains[i].Domain == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func normalizeInviteDomain(domain string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "@")), ".")
+}
+
+// inviteEmailDomain returns the lower-cased domain of the email, empty when it has none.
+func inviteEmailDomain(email string) string {
+	_, domain, ok := strings.Cut(strings.TrimSpace(email), "@")
+	if !ok {
+		return ""
+	}
+	return strings.ToLower(domain)
+}
+
+// swagger:parameters addOrgInviteDomain
+type AddOrgInviteDomainParams struct {
+	// in:body
+	// required:true
+	Body dtos.AddInviteDomainForm `json:"body"`
+}
+
+// swagger:parameters updateOrgInviteDomain
+type UpdateOrgInviteDomainParams struct {
+	// in:path
+	// required:true
+	Domain string `json:"domain"`
+	// in:body
+	// required:true
+	Body dtos.InviteDomainOptions `json:"body"`
+}
+
+// swagger:parameters verifyOrgInviteDomain deleteOrgInviteDomain
+type OrgInviteDomainParams struct {
+	// in:path
+	// required:true
+	Domain string `json:"domain"`
+}
+
+// swagger:response getOrgInviteDomainsResponse
+type GetOrgInviteDomainsResponse struct {
+	// in: body
+	Body dtos.InviteDomains `json:"body"`
+}
+
+// swagger:response orgInviteDomainResponse
+type OrgInviteDomainResponse struct {
+	// in: body
+	Body dtos.InviteDomain `json:"body"`
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/temp_user/invitetest"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/services/user/usertest"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+type fakeInviteDomainResolver map[string][]string
+
+func (r fakeInviteDomainResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	records, ok := r[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return records, nil
+}
+
+func TestOrgInviteDomains(t *testing.T) {
+	setup := func(t *testing.T, cfg *setting.Cfg) (accessControlScenarioContext, *invitetest.Stack, fakeInviteDomainResolver) {
+		sc, stack := setupInviteHTTPServerWithCfg(t, cfg)
+		sc.hs.userService = &slackInviteUserService{&usertest.FakeUserService{}}
+		sc.hs.kvStore = kvstore.ProvideService(sc.db)
+		setInitCtxSignedInOrgAdmin(sc.initCtx)
+		setAccessControlPermissions(sc.acmock, []accesscontrol.Permission{{Action: accesscontrol.ActionOrgUsersAdd, Scope: accesscontrol.ScopeUsersAll}, {Action: accesscontrol.ActionOrgsWrite}}, sc.initCtx.OrgID)
+
+		resolver := fakeInviteDomainResolver{}
+		origResolver := inviteDomainResolver
+		t.Cleanup(func() { inviteDomainResolver = origResolver })
+		inviteDomainResolver = resolver
+		return sc, stack, resolver
+	}
+	claim := func(t *testing.T, sc accessControlScenarioContext, body string) dtos.InviteDomain {
+		t.Helper()
+		response := callAPI(sc.server, http.MethodPost, "/api/org/invites/domains", strings.NewReader(body), t)
+		require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+		var domain dtos.InviteDomain
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &domain))
+		return domain
+	}
+	verify := func(t *testing.T, sc accessControlScenarioContext, resolver fakeInviteDomainResolver, body string) dtos.InviteDomain {
+		t.Helper()
+		domain := claim(t, sc, body)
+		resolver[domain.RecordName] = []string{domain.RecordValue}
+		response := callAPI(sc.server, http.MethodPost, "/api/org/invites/domains/"+domain.Domain+"/verify", nil, t)
+		require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+		return domain
+	}
+
+	t.Run("domains are verified with a TXT record", func(t *testing.T) {
+		sc, _, resolver := setup(t, setting.NewCfg())
+		assert.Equal(t, http.StatusBadRequest, callAPI(sc.server, http.MethodPost, "/api/org/invites/domains", strings.NewReader(`{"domain": "not a domain"}`), t).Code)
+
+		domain := claim(t, sc, `{"domain": " @Company.com", "autoApprove": true}`)
+		assert.Equal(t, "company.com", domain.Domain)
+		assert.Equal(t, "_grafana-invite-challenge.company.com", domain.RecordName)
+		assert.True(t, strings.HasPrefix(domain.RecordValue, "grafana-invite-verification="))
+		assert.False(t, domain.Verified)
+		assert.Equal(t, http.StatusConflict, callAPI(sc.server, http.MethodPost, "/api/org/invites/domains", strings.NewReader(`{"domain": "company.com"}`), t).Code)
+
+		verifyURL := "/api/org/invites/domains/company.com/verify"
+		assert.Equal(t, http.StatusUnprocessableEntity, callAPI(sc.server, http.MethodPost, verifyURL, nil, t).Code)
+		resolver[domain.RecordName] = []string{"grafana-invite-verification=other"}
+		assert.Equal(t, http.StatusUnprocessableEntity, callAPI(sc.server, http.MethodPost, verifyURL, nil, t).Code)
+		resolver[domain.RecordName] = []string{"v=spf1 -all", domain.RecordValue}
+		require.Equal(t, http.StatusOK, callAPI(sc.server, http.MethodPost, verifyURL, nil, t).Code)
+
+		response := callAPI(sc.server, http.MethodGet, "/api/org/invites/domains", nil, t)
+		require.Equal(t, http.StatusOK, response.Code)
+		var domains dtos.InviteDomains
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &domains))
+		require.Len(t, domains.Domains, 1)
+		assert.True(t, domains.Domains[0].Verified)
+		assert.True(t, domains.Domains[0].AutoApprove)
+
+		response = callAPI(sc.server, http.MethodPut, "/api/org/invites/domains/company.com", strings.NewReader(`{"autoComplete": true}`), t)
+		require.Equal(t, http.StatusOK, response.Code)
+		var updated dtos.InviteDomain
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &updated))
+		assert.True(t, updated.Verified)
+		assert.False(t, updated.AutoApprove)
+		assert.True(t, updated.AutoComplete)
+
+		assert.Equal(t, http.StatusOK, callAPI(sc.server, http.MethodDelete, "/api/org/invites/domains/company.com", nil, t).Code)
+		assert.Equal(t, http.StatusNotFound, callAPI(sc.server, http.MethodDelete, "/api/org/invites/domains/company.com", nil, t).Code)
+		assert.Nil(t, sc.hs.verifiedInviteDomain(context.Background(), sc.initCtx.OrgID, "ana@company.com"))
+	})
+
+	t.Run("existing users of auto-approved domains join right away", func(t *testing.T) {
+		sc, stack, resolver := setup(t, setting.NewCfg())
+		// the first user creates the organization
+		stack.User(t, "admin", 0, org.RoleAdmin)
+		usr, err := sc.db.CreateUser(context.Background(), user.CreateUserCommand{Email: "ana@company.com", Login: "ana", SkipOrgSetup: true})
+		require.NoError(t, err)
+		sc.hs.userService = &usertest.FakeUserService{ExpectedUser: usr}
+
+		domain := claim(t, sc, `{"domain": "company.com", "autoApprove": true}`)
+		response := callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(`{"loginOrEmail": "ana@company.com", "role": "Editor"}`), t)
+		require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+		require.Len(t, stack.Pending(t, sc.initCtx.OrgID), 1, "unverified domains are not approved")
+		stack.Revoke(t, stack.Pending(t, sc.initCtx.OrgID)[0].Code)
+
+		resolver[domain.RecordName] = []string{domain.RecordValue}
+		require.Equal(t, http.StatusOK, callAPI(sc.server, http.MethodPost, "/api/org/invites/domains/company.com/verify", nil, t).Code)
+
+		response = callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(`{"loginOrEmail": "ana@company.com", "role": "Editor"}`), t)
+		require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+		assert.Contains(t, response.Body.String(), "added to org")
+		assert.Empty(t, stack.Pending(t, sc.initCtx.OrgID))
+		member, err := sc.hs.isOrgMember(context.Background(), sc.initCtx.OrgID, usr.ID)
+		require.NoError(t, err)
+		assert.True(t, member)
+	})
+
+	t.Run("pending invites of verified domains are completed on SSO sign in", func(t *testing.T) {
+		sc, stack, resolver := setup(t, setting.NewCfg())
+		stack.User(t, "admin", 0, org.RoleAdmin)
+		verify(t, sc, resolver, `{"domain": "company.com", "autoComplete": true}`)
+
+		invite := stack.Invite("ana@company.com").InOrg(sc.initCtx.OrgID).WithRole(org.RoleEditor).Create(t)
+		other := stack.Invite("bob@other.com").InOrg(sc.initCtx.OrgID).Create(t)
+		usr, err := sc.db.CreateUser(context.Background(), user.CreateUserCommand{Email: "ana@company.com", Login: "ana", SkipOrgSetup: true})
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodGet, "/login/generic_oauth", nil)
+		sc.hs.completeSSOInvites(&models.ReqContext{Context: &web.Context{Req: req}}, usr)
+		assert.Equal(t, models.TmpUserCompleted, stack.Get(t, invite.Code).Status)
+		assert.Equal(t, models.TmpUserInvitePending, stack.Get(t, other.Code).Status)
+		member, err := sc.hs.isOrgMember(context.Background(), sc.initCtx.OrgID, usr.ID)
+		require.NoError(t, err)
+		assert.True(t, member)
+	})
+
+	t.Run("verified domains can be exempted from the invite policy", func(t *testing.T) {
+		cfg := setting.NewCfg()
+		cfg.InviteRequiresSignUp = true
+		sc, _, resolver := setup(t, cfg)
+		verify(t, sc, resolver, `{"domain": "company.com"}`)
+
+		invite := func(email string) int {
+			body := `{"loginOrEmail": "` + email + `", "role": "Viewer", "sendEmail": false}`
+			return callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(body), t).Code
+		}
+		assert.Equal(t, http.StatusBadRequest, invite("ana@company.com"))
+		require.Equal(t, http.StatusOK, callAPI(sc.server, http.MethodPut, "/api/org/invites/domains/company.com", strings.NewReader(`{"exemptFromPolicy": true}`), t).Code)
+		assert.Equal(t, http.StatusOK, invite("ana@company.com"))
+		assert.Equal(t, http.StatusBadRequest, invite("bob@other.com"))
+	})
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/temp_user/invitetest"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/services/user/usertest"
+)
+
+type externalIDUserService struct {
+	*slackInviteUserService
+	externalIDs map[int64]string
+}
+
+func (s *externalIDUserService) SetExternalID(ctx context.Context, cmd *user.SetExternalIDCommand) error {
+	if current := s.externalIDs[cmd.UserID]; current != "" && current != cmd.ExternalID {
+		return nil
+	}
+	s.externalIDs[cmd.UserID] = cmd.ExternalID
+	cmd.Updated = true
+	return nil
+}
+
+func TestOrgInviteExternalID(t *testing.T) {
+	setup := func(t *testing.T) (accessControlScenarioContext, *invitetest.Stack, *externalIDUserService) {
+		sc, stack := setupInviteHTTPServer(t)
+		userService := &externalIDUserService{&slackInviteUserService{&usertest.FakeUserService{}}, map[int64]string{}}
+		sc.hs.userService = userService
+		setInitCtxSignedInOrgAdmin(sc.initCtx)
+		setAccessControlPermissions(sc.acmock, []accesscontrol.Permission{
+			{Action: accesscontrol.ActionOrgUsersAdd, Scope: accesscontrol.ScopeUsersAll},
+		}, sc.initCtx.OrgID)
+		return sc, stack, userService
+	}
+
+	t.Run("invites store the external ID", func(t *testing.T) {
+		sc, stack, _ := setup(t)
+
+		response := callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(`{"loginOrEmail": "new.hire@example.com", "role": "Viewer", "externalId": " E-1001 "}`), t)
+		require.Equal(t, http.StatusOK, response.Code)
+
+		pending := stack.Pending(t, sc.initCtx.OrgID)
+		require.Len(t, pending, 1)
+		assert.Equal(t, "E-1001", pending[0].ExternalId)
+	})
+
+	t.Run("external IDs are limited in length", func(t *testing.T) {
+		sc, _, _ := setup(t)
+
+		body := `{"loginOrEmail": "new.hire@example.com", "role": "Viewer", "externalId": "` + strings.Repeat("x", maxInviteExternalIDLength+1) + `"}`
+		response := callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(body), t)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+
+	t.Run("accepting an invite sets the external ID on the user", func(t *testing.T) {
+		sc, stack, userService := setup(t)
+		userService.ExpectedUser = verifiedUser(testAdminOrg2)
+		setupOrgUsersDBForAccessControlTests(t, sc.db)
+		setInitCtxSignedInUser(sc.initCtx, testAdminOrg2)
+		invite := stack.Invite(testAdminOrg2.Email).InOrg(testServerAdminViewer.OrgID).WithRole(org.RoleEditor).WithExternalID("E-1001").Create(t)
+
+		response := callAPI(sc.server, http.MethodPost, fmt.Sprintf("/api/user/org-invites/%d/accept", invite.Id), nil, t)
+		require.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, "E-1001", userService.externalIDs[testAdminOrg2.UserID])
+	})
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/ngalert"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/temp_user/invitetest"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/services/user/usertest"
+)
+
+func TestOrgInviteFlow(t *testing.T) {
+	setup := func(t *testing.T) (accessControlScenarioContext, *invitetest.Stack) {
+		sc, stack := setupInviteHTTPServer(t)
+		sc.hs.AlertNG = &ngalert.AlertNG{NotificationService: stack.Mail}
+		sc.hs.Login = stack.Login
+		sc.hs.userService = &slackInviteUserService{&usertest.FakeUserService{}}
+		setInitCtxSignedInOrgAdmin(sc.initCtx)
+		setAccessControlPermissions(sc.acmock, []accesscontrol.Permission{{Action: accesscontrol.ActionOrgUsersAdd}, {Action: accesscontrol.ActionOrgInvitesRead, Scope: accesscontrol.ScopeInvitesAll}}, sc.initCtx.OrgID)
+		return sc, stack
+	}
+
+	inviteInfo := func(t *testing.T, sc accessControlScenarioContext, code string) int {
+		t.Helper()
+		// invitees are not signed in
+		signedIn := sc.initCtx.SignedInUser
+		sc.initCtx.SignedInUser = &user.SignedInUser{}
+		defer func() { sc.initCtx.SignedInUser = signedIn }()
+		return callAPI(sc.server, http.MethodGet, "/api/user/invite/"+code, nil, t).Code
+	}
+
+	t.Run("invitees get the link of the invite by email", func(t *testing.T) {
+		sc, stack := setup(t)
+
+		body := `{"loginOrEmail": "new@example.com", "name": "New", "role": "Editor", "sendEmail": true}`
+		response := callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(body), t)
+		require.Equal(t, http.StatusOK, response.Code)
+
+		emails := stack.Mail.SentTo("new@example.com")
+		require.Len(t, emails, 1)
+		assert.Equal(t, "new_user_invite", emails[0].Template)
+		code, err := stack.Mail.InviteCode("new@example.com")
+		require.NoError(t, err)
+
+		invite := stack.Get(t, code)
+		assert.Equal(t, org.RoleEditor, invite.Role)
+		assert.True(t, invite.EmailSent)
+
+		require.Equal(t, http.StatusOK, inviteInfo(t, sc, code))
+		response = callAPI(sc.server, http.MethodGet, "/api/org/invites", nil, t)
+		require.Equal(t, http.StatusOK, response.Code)
+		var pending []*models.TempUserDTO
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &pending))
+		require.Len(t, pending, 1)
+		assert.Equal(t, code, pending[0].Code)
+	})
+
+	t.Run("invites fail when emails can't be sent", func(t *testing.T) {
+		sc, stack := setup(t)
+		stack.Mail.FailWith(models.ErrSmtpNotEnabled)
+
+		body := `{"loginOrEmail": "new@example.com", "role": "Viewer", "sendEmail": true}`
+		response := callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(body), t)
+		assert.Equal(t, http.StatusPreconditionFailed, response.Code)
+		assert.Empty(t, stack.Mail.Emails())
+	})
+
+	t.Run("revoked invites no longer work", func(t *testing.T) {
+		sc, stack := setup(t)
+		invite := stack.Invite("revoked@example.com").InOrg(sc.initCtx.OrgID).EmailSent().Create(t)
+		require.Equal(t, http.StatusOK, inviteInfo(t, sc, invite.Code))
+
+		response := callAPI(sc.server, http.MethodPatch, "/api/org/invites/"+invite.Code+"/revoke", nil, t)
+		require.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, models.TmpUserRevoked, stack.Get(t, invite.Code).Status)
+		assert.Empty(t, stack.Pending(t, sc.initCtx.OrgID))
+
+		body, err := json.Marshal(dtos.CompleteInviteForm{InviteCode: invite.Code, Email: "revoked@example.com", Username: "revoked", Password: "password"})
+		require.NoError(t, err)
+		response = callAPI(sc.server, http.MethodPost, "/api/user/invite/complete", strings.NewReader(string(body)), t)
+		assert.Equal(t, http.StatusPreconditionFailed, response.Code)
+		assert.Empty(t, stack.Login.Created())
+	})
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+// swagger:route GET /org/invites/{invitation_code}/history org_invites getOrgInviteHistory
+//
+// Get the history of an invite.
+//
+// Lists the events of the invite, oldest first: when it was created, its email sent, its link
+// first opened, and when it was updated, revoked, completed, declined or expired. The user who
+// caused an event is given when known.
+// With the `invites:self` scope only the history of the invites created by the signed in user can
+// be read.
+//
+// Responses:
+// 200: getOrgInviteHistoryResponse
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (hs *HTTPServer) GetOrgInviteHistory(c *models.ReqContext) response.Response {
+	readable := models.GetTempUsersQuery{}
+	canRead, err := hs.filterReadableInvites(c, &readable)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to evaluate invite permissions", err)
+	}
+	if !canRead {
+		return response.Error(http.StatusForbidden, "Permission denied", nil)
+	}
+
+	query := models.GetTempUserHistoryQuery{
+		OrgID:           c.OrgID,
+		Code:            web.Params(c.Req)[":code"],
+		InvitedByUserID: readable.InvitedByUserID,
+	}
+	if err := hs.tempUserService.GetTempUserHistory(c.Req.Context(), &query); err != nil {
+		if errors.Is(err, models.ErrTempUserNotFound) {
+			return response.Error(http.StatusNotFound, "Invite not found", nil)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to get invite history", err)
+	}
+	return response.JSON(http.StatusOK, query.Result)
+}
+
+// swagger:parameters getOrgInviteHistory
+type GetOrgInviteHistoryParams struct {
+	// in:path
+	// required:true
+	Code string `json:"invitation_code"`
+}
+
+// swagger:response getOrgInviteHistoryResponse
+type GetOrgInviteHistoryResponse struct {
+	// in: body
+	Body []*models.TempUserEventDTO `json:"body"`
+}
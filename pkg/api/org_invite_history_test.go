@@ -0,0 +1,53 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+)
+
+func TestGetOrgInviteHistory(t *testing.T) {
+	setup := func(t *testing.T, permissions []accesscontrol.Permission) accessControlScenarioContext {
+		sc, stack := setupInviteHTTPServer(t)
+		setInitCtxSignedInViewer(sc.initCtx)
+		setAccessControlPermissions(sc.acmock, permissions, sc.initCtx.OrgID)
+
+		stack.Invite("invitee@example.com").WithCode("invite-code").InvitedBy(testUserID + 1).EmailSent().Create(t)
+		stack.Revoke(t, "invite-code")
+		return sc
+	}
+
+	t.Run("lists the events of the invite", func(t *testing.T) {
+		sc := setup(t, []accesscontrol.Permission{{Action: accesscontrol.ActionOrgInvitesRead, Scope: accesscontrol.ScopeInvitesAll}})
+		response := callAPI(sc.server, http.MethodGet, "/api/org/invites/invite-code/history", nil, t)
+		require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+		var history []models.TempUserEventDTO
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &history))
+		events := make([]models.TempUserEventType, 0, len(history))
+		for _, e := range history {
+			events = append(events, e.Event)
+		}
+		assert.Equal(t, []models.TempUserEventType{models.TempUserEventCreated, models.TempUserEventEmailSent, models.TempUserEventRevoked}, events)
+
+		response = callAPI(sc.server, http.MethodGet, "/api/org/invites/unknown/history", nil, t)
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+
+	t.Run("hides the invites of others with the self scope", func(t *testing.T) {
+		sc := setup(t, []accesscontrol.Permission{{Action: accesscontrol.ActionOrgInvitesRead, Scope: accesscontrol.ScopeInvitesSelf}})
+		response := callAPI(sc.server, http.MethodGet, "/api/org/invites/invite-code/history", nil, t)
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+
+	t.Run("forbids reading the history without permission", func(t *testing.T) {
+		sc := setup(t, []accesscontrol.Permission{{Action: accesscontrol.ActionOrgUsersAdd, Scope: accesscontrol.ScopeUsersAll}})
+		response := callAPI(sc.server, http.MethodGet, "/api/org/invites/invite-code/history", nil, t)
+		assert.Equal(t, http.StatusForbidden, response.Code)
+	})
+}
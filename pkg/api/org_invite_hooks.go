@@ -0,0 +1,155 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/pluginsettings"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+const (
+	// inviteHookAttempts is how many times a hook is called before giving up
+	inviteHookAttempts = 4
+	// inviteHookTimeout bounds each call of a hook
+	inviteHookTimeout = 30 * time.Second
+)
+
+// inviteHookRetryDelay is the wait before the first retry of a failed hook, it doubles with every retry.
+var inviteHookRetryDelay = 2 * time.Second
+
+// callInviteCompletedHook calls the invite completed hook of the app plugin as the user who joined,
+// responses other than 2xx are errors.
+var callInviteCompletedHook = func(ctx context.Context, hs *HTTPServer, plugin plugins.PluginDTO, usr *user.SignedInUser, body []byte) error {
+	pCtx, found, err := hs.PluginContextProvider.Get(ctx, plugin.ID, usr)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("plugin %s not found", plugin.ID)
+	}
+	sender := &inviteHookResponseSender{}
+	req := &backend.CallResourceRequest{
+		PluginContext: pCtx,
+		Path:          plugin.Hooks.InviteCompleted,
+		Method:        http.MethodPost,
+		URL:           plugin.Hooks.InviteCompleted,
+		Headers:       map[string][]string{"Content-Type": {"application/json"}},
+		Body:          body,
+	}
+	if err := hs.pluginClient.CallResource(ctx, req, sender); err != nil {
+		return err
+	}
+	if sender.status < 200 || sender.status > 299 {
+		return fmt.Errorf("hook responded with status %d", sender.status)
+	}
+	return nil
+}
+
+// inviteHookResponseSender keeps the status of the response of a hook, its body is ignored.
+type inviteHookResponseSender struct {
+	status int
+}
+
+func (s *inviteHookResponseSender) Send(res *backend.CallResourceResponse) error {
+	if s.status == 0 {
+		s.status = res.Status
+	}
+	return nil
+}
+
+// inviteCompletedHookPlugins returns the app plugins enabled in the organization which have an
+// invite completed hook.
+func (hs *HTTPServer) inviteCompletedHookPlugins(ctx context.Context, orgID int64) ([]plugins.PluginDTO, error) {
+	var result []plugins.PluginDTO
+	for _, plugin := range hs.pluginStore.Plugins(ctx, plugins.App) {
+		if plugin.Hooks.InviteCompleted == "" || !plugin.Backend {
+			continue
+		}
+		settings, err := hs.PluginSettings.GetPluginSettingByPluginID(ctx, &pluginsettings.GetByPluginIDArgs{PluginID: plugin.ID, OrgID: orgID})
+		switch {
+		case errors.Is(err, models.ErrPluginSettingNotFound):
+			if !plugin.AutoEnabled {
+				continue
+			}
+		case err != nil:
+			return nil, err
+		case !settings.Enabled:
+			continue
+		}
+		result = append(result, plugin)
+	}
+	return result, nil
+}
+
+// runInviteCompletedHooks calls the invite completed hooks of the app plugins enabled in the
+// organization of the invite in background, so that plugins can provision their resources for the
+// user who joined. Failed calls are retried with an exponential backoff.
+func (hs *HTTPServer) runInviteCompletedHooks(usr *user.User, invite *models.TempUserDTO, role org.RoleType) {
+	payload := dtos.InviteCompletedHook{
+		UserID: usr.ID,
+		OrgID:  invite.OrgId,
+		Role:   role,
+		Invite: dtos.InviteCompletedHookInvite{
+			ID:            invite.Id,
+			Email:         invite.Email,
+			Name:          invite.Name,
+			ExternalID:    invite.ExternalId,
+			InvitedBy:     invite.InvitedByLogin,
+			Created:       invite.Created,
+			AccessExpires: invite.AccessExpires,
+		},
+	}
+	signedInUser := &user.SignedInUser{UserID: usr.ID, OrgID: invite.OrgId, OrgRole: role, Login: usr.Login, Email: usr.Email, Name: usr.Name}
+	logger := hs.log.New("inviteId", invite.Id, "orgId", invite.OrgId, "userId", usr.ID)
+
+	go func() {
+		ctx := context.Background()
+		hookPlugins, err := hs.inviteCompletedHookPlugins(ctx, invite.OrgId)
+		if err != nil {
+			logger.Error("Failed to get the app plugins with an invite completed hook", "error", err)
+			return
+		}
+		if len(hookPlugins) == 0 {
+			return
+		}
+		body, err := json.Marshal(payload)
+		if err != nil {
+			logger.Error("Failed to encode the invite completed hook", "error", err)
+			return
+		}
+		for _, plugin := range hookPlugins {
+			go hs.retryInviteCompletedHook(ctx, logger.New("pluginId", plugin.ID), plugin, signedInUser, body)
+		}
+	}()
+}
+
+func (hs *HTTPServer) retryInviteCompletedHook(ctx context.Context, logger log.Logger, plugin plugins.PluginDTO, usr *user.SignedInUser, body []byte) {
+	delay := inviteHookRetryDelay
+	for attempt := 1; ; attempt++ {
+		callCtx, cancel := context.WithTimeout(ctx, inviteHookTimeout)
+		err := callInviteCompletedHook(callCtx, hs, plugin, usr, body)
+		cancel()
+		if err == nil {
+			return
+		}
+		if attempt == inviteHookAttempts {
+			logger.Error("Invite completed hook failed, giving up", "attempts", attempt, "error", err)
+			return
+		}
+		logger.Warn("Invite completed hook failed, retrying", "attempt", attempt, "retryIn", delay, "error", err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/pluginsettings"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/services/user/usertest"
+)
+
+func TestInviteCompletedHooks(t *testing.T) {
+	appPlugin := func(id string, hook string, autoEnabled bool) plugins.PluginDTO {
+		return plugins.PluginDTO{JSONData: plugins.JSONData{ID: id, Type: plugins.App, Backend: true, AutoEnabled: autoEnabled, Hooks: plugins.Hooks{InviteCompleted: hook}}}
+	}
+
+	var mu sync.Mutex
+	calls := map[string][]dtos.InviteCompletedHook{}
+	failures := map[string]int{"provisioner-app": 2}
+	origCall, origDelay := callInviteCompletedHook, inviteHookRetryDelay
+	t.Cleanup(func() {
+		callInviteCompletedHook, inviteHookRetryDelay = origCall, origDelay
+	})
+	inviteHookRetryDelay = time.Millisecond
+	callInviteCompletedHook = func(ctx context.Context, hs *HTTPServer, plugin plugins.PluginDTO, usr *user.SignedInUser, body []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		var payload dtos.InviteCompletedHook
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return err
+		}
+		calls[plugin.ID] = append(calls[plugin.ID], payload)
+		if failures[plugin.ID] > 0 {
+			failures[plugin.ID]--
+			return errors.New("plugin is restarting")
+		}
+		return nil
+	}
+
+	sc, stack := setupInviteHTTPServer(t)
+	sc.hs.userService = &externalIDUserService{&slackInviteUserService{&usertest.FakeUserService{ExpectedUser: verifiedUser(testAdminOrg2)}}, map[int64]string{}}
+	sc.hs.pluginStore = &fakePluginStore{plugins: map[string]plugins.PluginDTO{
+		"provisioner-app": appPlugin("provisioner-app", "invite-completed", false),
+		"disabled-app":    appPlugin("disabled-app", "invite-completed", false),
+		"auto-app":        appPlugin("auto-app", "hooks/invite", true),
+		"no-hook-app":     appPlugin("no-hook-app", "", true),
+	}}
+	sc.hs.PluginSettings = &fakePluginSettings{plugins: map[string]*pluginsettings.DTO{
+		"provisioner-app": {PluginID: "provisioner-app", Enabled: true},
+		"disabled-app":    {PluginID: "disabled-app", Enabled: false},
+	}}
+	setupOrgUsersDBForAccessControlTests(t, sc.db)
+	setInitCtxSignedInUser(sc.initCtx, testAdminOrg2)
+	setAccessControlPermissions(sc.acmock, []accesscontrol.Permission{}, sc.initCtx.OrgID)
+
+	invite := stack.Invite(testAdminOrg2.Email).InOrg(testServerAdminViewer.OrgID).WithRole(org.RoleEditor).WithCode("invite-code").WithExternalID("E-1001").Create(t)
+
+	response := callAPI(sc.server, http.MethodPost, fmt.Sprintf("/api/user/org-invites/%d/accept", invite.Id), nil, t)
+	require.Equal(t, http.StatusOK, response.Code)
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(calls["provisioner-app"]) == 3 && len(calls["auto-app"]) == 1
+	}, 5*time.Second, 10*time.Millisecond, "failed calls are retried")
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.NotContains(t, calls, "disabled-app")
+	assert.NotContains(t, calls, "no-hook-app")
+	payload := calls["auto-app"][0]
+	assert.Equal(t, testAdminOrg2.UserID, payload.UserID)
+	assert.Equal(t, testServerAdminViewer.OrgID, payload.OrgID)
+	assert.Equal(t, org.RoleEditor, payload.Role)
+	assert.Equal(t, invite.Id, payload.Invite.ID)
+	assert.Equal(t, "E-1001", payload.Invite.ExternalID)
+}
+
+func TestInviteCompletedHooksOfLinkedInvites(t *testing.T) {
+	var mu sync.Mutex
+	var called *user.SignedInUser
+	origCall := callInviteCompletedHook
+	t.Cleanup(func() { callInviteCompletedHook = origCall })
+	callInviteCompletedHook = func(ctx context.Context, hs *HTTPServer, plugin plugins.PluginDTO, usr *user.SignedInUser, body []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		called = usr
+		return nil
+	}
+
+	sc, stack := setupInviteHTTPServer(t)
+	sc.hs.userService = &usertest.FakeUserService{ExpectedUser: &user.User{ID: testEditorOrg1.UserID, Login: testEditorOrg1.Login, Email: testEditorOrg1.Email, Name: "Editor"}}
+	sc.hs.pluginStore = &fakePluginStore{plugins: map[string]plugins.PluginDTO{
+		"auto-app": {JSONData: plugins.JSONData{ID: "auto-app", Type: plugins.App, Backend: true, AutoEnabled: true, Hooks: plugins.Hooks{InviteCompleted: "hooks/invite"}}},
+	}}
+	sc.hs.PluginSettings = &fakePluginSettings{plugins: map[string]*pluginsettings.DTO{}}
+	setupOrgUsersDBForAccessControlTests(t, sc.db)
+	setInitCtxSignedInUser(sc.initCtx, testEditorOrg1)
+
+	stack.Invite(testEditorOrg1.Email).InOrg(testAdminOrg2.OrgID).WithCode("invite-code").Create(t)
+
+	token := sc.hs.createInviteLinkToken("invite-code", testEditorOrg1.UserID, time.Now().Add(time.Hour))
+	response := callAPI(sc.server, http.MethodPost, "/api/user/org-invites/link", strings.NewReader(`{"linkToken": "`+token+`"}`), t)
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return called != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, testEditorOrg1.Login, called.Login, "hooks are called as the full user who linked the invite")
+	assert.Equal(t, testEditorOrg1.Email, called.Email)
+	assert.Equal(t, "Editor", called.Name)
+}
@@ -0,0 +1,271 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+const (
+	inviteJoinRulesKey   = "join-rules"
+	maxInviteJoinRules   = 100
+	maxInviteJoinPattern = 256
+)
+
+// swagger:route GET /org/invites/join-rules org_invites getOrgInviteJoinRules
+//
+// Get the rules placing the users who join the organization with an invite in teams.
+//
+// Responses:
+// 200: getOrgInviteJoinRulesResponse
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (hs *HTTPServer) GetOrgInviteJoinRules(c *models.ReqContext) response.Response {
+	rules, err := hs.getInviteJoinRules(c.Req.Context(), c.OrgID)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get invite join rules", err)
+	}
+	return response.JSON(http.StatusOK, dtos.InviteJoinRules{Rules: rules})
+}
+
+// swagger:route PUT /org/invites/join-rules org_invites updateOrgInviteJoinRules
+//
+// Replace the rules placing the users who join the organization with an invite in teams.
+//
+// When the email an invite was sent to matches a rule, the user accepting it joins the teams of
+// the rule, and gets its role if it is higher than the role of the invite. Every matching rule
+// applies. Viewer tokens are left out.
+//
+// Responses:
+// 200: okResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (hs *HTTPServer) UpdateOrgInviteJoinRules(c *models.ReqContext) response.Response {
+	form := dtos.InviteJoinRules{}
+	if err := web.Bind(c.Req, &form); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	if rsp := hs.validateInviteJoinRules(c.Req.Context(), c.OrgID, form.Rules); rsp != nil {
+		return rsp
+	}
+
+	value, err := json.Marshal(form.Rules)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to save invite join rules", err)
+	}
+	store := kvstore.WithNamespace(hs.kvStore, c.OrgID, orgInvitesKVNamespace)
+	if err := store.Set(c.Req.Context(), inviteJoinRulesKey, string(value)); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to save invite join rules", err)
+	}
+	return response.Success("Invite join rules updated")
+}
+
+// swagger:route POST /org/invites/join-rules/evaluate org_invites evaluateOrgInviteJoinRules
+//
+// Tell which teams and role the invite join rules give to an email, without applying them.
+//
+// The rules of the organization are evaluated, unless rules are given to try them before
+// saving them.
+//
+// Responses:
+// 200: evaluateOrgInviteJoinRulesResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (hs *HTTPServer) EvaluateOrgInviteJoinRules(c *models.ReqContext) response.Response {
+	form := dtos.EvaluateInviteJoinRulesForm{}
+	if err := web.Bind(c.Req, &form); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	rules := form.Rules
+	if rules == nil {
+		var err error
+		if rules, err = hs.getInviteJoinRules(c.Req.Context(), c.OrgID); err != nil {
+			return response.Error(http.StatusInternalServerError, "Failed to get invite join rules", err)
+		}
+	} else if rsp := hs.validateInviteJoinRules(c.Req.Context(), c.OrgID, rules); rsp != nil {
+		return rsp
+	}
+	return response.JSON(http.StatusOK, evaluateInviteJoinRules(rules, form.Email))
+}
+
+// validateInviteJoinRules returns an error response when a rule has an unknown kind or an invalid
+// pattern, a role which can't be assigned in the organization or a team of another organization.
+// Domains are normalized to lower case without the leading @.
+func (hs *HTTPServer) validateInviteJoinRules(ctx context.Context, orgID int64, rules []dtos.InviteJoinRule) response.Response {
+	if len(rules) > maxInviteJoinRules {
+		return response.Error(http.StatusBadRequest, fmt.Sprintf("Organizations can have at most %d invite join rules", maxInviteJoinRules), nil)
+	}
+	for i := range rules {
+		rule := &rules[i]
+		rule.Pattern = strings.TrimSpace(rule.Pattern)
+		if rule.Pattern == "" || len(rule.Pattern) > maxInviteJoinPattern {
+			return response.Error(http.StatusBadRequest, fmt.Sprintf("Invite join rule %d needs a pattern of at most %d characters", i, maxInviteJoinPattern), nil)
+		}
+		switch rule.Match {
+		case dtos.InviteJoinRuleDomain:
+			rule.Pattern = strings.ToLower(strings.TrimPrefix(rule.Pattern, "@"))
+			if strings.ContainsAny(rule.Pattern, "@ ") {
+				return response.Error(http.StatusBadRequest, fmt.Sprintf("Invalid domain %q in invite join rule %d", rule.Pattern, i), nil)
+			}
+		case dtos.InviteJoinRuleRegex:
+			if _, err := regexp.Compile(rule.Pattern); err != nil {
+				return response.Error(http.StatusBadRequest, fmt.Sprintf("Invalid regular expression in invite join rule %d", i), err)
+			}
+		default:
+			return response.Error(http.StatusBadRequest, fmt.Sprintf("Invite join rule %d must match a domain or a regex", i), nil)
+		}
+		if rule.Role == "" && len(rule.Teams) == 0 {
+			return response.Error(http.StatusBadRequest, fmt.Sprintf("Invite join rule %d gives neither a role nor teams", i), nil)
+		}
+		if rule.Role != "" {
+			if !rule.Role.IsValid() {
+				return response.Error(http.StatusBadRequest, fmt.Sprintf("Invalid role in invite join rule %d", i), nil)
+			}
+			assignable, _, err := hs.isAssignableRole(ctx, orgID, rule.Role)
+			if err != nil {
+				return response.Error(http.StatusInternalServerError, "Failed to get the assignable roles", err)
+			}
+			if !assignable {
+				return response.Error(http.StatusBadRequest, fmt.Sprintf("The %s role can't be assigned in this organization", rule.Role), nil)
+			}
+		}
+		if rsp := hs.checkInviteTeams(ctx, orgID, rule.Teams); rsp != nil {
+			return rsp
+		}
+	}
+	return nil
+}
+
+func (hs *HTTPServer) getInviteJoinRules(ctx context.Context, orgID int64) ([]dtos.InviteJoinRule, error) {
+	rules := []dtos.InviteJoinRule{}
+	value, ok, err := kvstore.WithNamespace(hs.kvStore, orgID, orgInvitesKVNamespace).Get(ctx, inviteJoinRulesKey)
+	if err != nil || !ok {
+		return rules, err
+	}
+	err = json.Unmarshal([]byte(value), &rules)
+	return rules, err
+}
+
+// evaluateInviteJoinRules returns the teams of the rules matching the email and the highest of
+// their roles.
+func evaluateInviteJoinRules(rules []dtos.InviteJoinRule, email string) dtos.InviteJoinRulesEvaluation {
+	evaluation := dtos.InviteJoinRulesEvaluation{Matched: []int{}, Teams: []int64{}}
+	email = strings.ToLower(strings.TrimSpace(email))
+	_, domain, _ := strings.Cut(email, "@")
+	seen := map[int64]bool{}
+	for i, rule := range rules {
+		switch rule.Match {
+		case dtos.InviteJoinRuleDomain:
+			if domain == "" || domain != rule.Pattern {
+				continue
+			}
+		case dtos.InviteJoinRuleRegex:
+			re, err := regexp.Compile(rule.Pattern)
+			if err != nil || !re.MatchString(email) {
+				continue
+			}
+		default:
+			continue
+		}
+		evaluation.Matched = append(evaluation.Matched, i)
+		evaluation.Role = higherRole(evaluation.Role, rule.Role)
+		for _, teamID := range rule.Teams {
+			if !seen[teamID] {
+				seen[teamID] = true
+				evaluation.Teams = append(evaluation.Teams, teamID)
+			}
+		}
+	}
+	return evaluation
+}
+
+// inviteJoinRules evaluates the invite join rules of the organization for the user accepting an
+// invite. Viewer tokens only give a temporary read-only membership, rules don't apply to them.
+// The invite is still accepted when the rules can't be read.
+func (hs *HTTPServer) inviteJoinRules(ctx context.Context, usr *user.User, invite *models.TempUserDTO) dtos.InviteJoinRulesEvaluation {
+	if invite.AccessExpires != nil {
+		return dtos.InviteJoinRulesEvaluation{}
+	}
+	rules, err := hs.getInviteJoinRules(ctx, invite.OrgId)
+	if err != nil {
+		hs.log.FromContext(ctx).Warn("Failed to get invite join rules", "orgId", invite.OrgId, "error", err)
+		return dtos.InviteJoinRulesEvaluation{}
+	}
+	email := invite.Email
+	if email == "" {
+		email = usr.Email
+	}
+	return evaluateInviteJoinRules(rules, email)
+}
+
+// joinInviteRuleTeams adds the user who accepted an invite to the teams of the matching invite
+// join rules.
+func (hs *HTTPServer) joinInviteRuleTeams(ctx context.Context, usr *user.User, invite *models.TempUserDTO, teams []int64) {
+	for _, teamID := range teams {
+		if err := addOrUpdateTeamMember(ctx, hs.teamPermissionsService, usr.ID, invite.OrgId, teamID, getPermissionName(0)); err != nil {
+			hs.log.FromContext(ctx).Warn("Failed to add the invitee to the team of an invite join rule", "inviteId", invite.Id, "teamId", teamID, "error", err)
+		}
+	}
+}
+
+// raiseInviteRole raises the role of the invite to the role of its join rules, up to the maximum
+// role of invites of its delivery.
+func (hs *HTTPServer) raiseInviteRole(invite *models.TempUserDTO, role, ruleRole org.RoleType) org.RoleType {
+	raised := higherRole(role, ruleRole)
+	var roleErr inviteRoleError
+	if err := newInvitePolicy(hs.Cfg).canAssignRole(invite.Delivery, raised); errors.As(err, &roleErr) {
+		return higherRole(role, roleErr.MaxRole)
+	}
+	return raised
+}
+
+// higherRole returns the highest of the roles, empty roles are ignored.
+func higherRole(role, other org.RoleType) org.RoleType {
+	if role == "" || (other != "" && other.Includes(role)) {
+		return other
+	}
+	return role
+}
+
+// swagger:parameters updateOrgInviteJoinRules
+type UpdateOrgInviteJoinRulesParams struct {
+	// in:body
+	// required:true
+	Body dtos.InviteJoinRules `json:"body"`
+}
+
+// swagger:parameters evaluateOrgInviteJoinRules
+type EvaluateOrgInviteJoinRulesParams struct {
+	// in:body
+	// required:true
+	Body dtos.EvaluateInviteJoinRulesForm `json:"body"`
+}
+
+// swagger:response getOrgInviteJoinRulesResponse
+type GetOrgInviteJoinRulesResponse struct {
+	// in: body
+	Body dtos.InviteJoinRules `json:"body"`
+}
+
+// swagger:response evaluateOrgInviteJoinRulesResponse
+type EvaluateOrgInviteJoinRulesResponse struct {
+	// in: body
+	Body dtos.InviteJoinRulesEvaluation `json:"body"`
+}
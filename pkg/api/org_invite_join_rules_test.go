@@ -0,0 +1,155 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/temp_user/invitetest"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/services/user/usertest"
+)
+
+func TestEvaluateInviteJoinRules(t *testing.T) {
+	rules := []dtos.InviteJoinRule{
+		{Match: dtos.InviteJoinRuleDomain, Pattern: "sre.company.com", Teams: []int64{1}},
+		{Match: dtos.InviteJoinRuleRegex, Pattern: `^oncall-.*@`, Role: org.RoleEditor, Teams: []int64{1, 2}},
+		{Match: dtos.InviteJoinRuleDomain, Pattern: "company.com", Role: org.RoleViewer},
+	}
+
+	evaluation := evaluateInviteJoinRules(rules, "Oncall-Ana@SRE.company.com")
+	assert.Equal(t, []int{0, 1}, evaluation.Matched)
+	assert.Equal(t, org.RoleEditor, evaluation.Role)
+	assert.Equal(t, []int64{1, 2}, evaluation.Teams)
+
+	evaluation = evaluateInviteJoinRules(rules, "bob@company.com")
+	assert.Equal(t, []int{2}, evaluation.Matched, "subdomains are other domains")
+	assert.Equal(t, org.RoleViewer, evaluation.Role)
+	assert.Empty(t, evaluation.Teams)
+
+	assert.Empty(t, evaluateInviteJoinRules(rules, "someone@example.com").Matched)
+	assert.Equal(t, org.RoleAdmin, higherRole(org.RoleAdmin, org.RoleEditor))
+	assert.Equal(t, org.RoleEditor, higherRole(org.RoleViewer, org.RoleEditor))
+	assert.Equal(t, org.RoleViewer, higherRole(org.RoleViewer, ""))
+}
+
+func TestOrgInviteJoinRules(t *testing.T) {
+	setup := func(t *testing.T) (accessControlScenarioContext, *invitetest.Stack, models.Team) {
+		sc, stack := setupInviteHTTPServer(t)
+		sc.hs.userService = &slackInviteUserService{&usertest.FakeUserService{}}
+		sc.hs.kvStore = kvstore.ProvideService(sc.db)
+		team, err := sc.hs.teamService.CreateTeam("sre", "", 1)
+		require.NoError(t, err)
+		setInitCtxSignedInOrgAdmin(sc.initCtx)
+		setAccessControlPermissions(sc.acmock, []accesscontrol.Permission{{Action: accesscontrol.ActionOrgUsersAdd}, {Action: accesscontrol.ActionOrgsWrite}}, sc.initCtx.OrgID)
+		return sc, stack, team
+	}
+	put := func(t *testing.T, sc accessControlScenarioContext, body string) int {
+		return callAPI(sc.server, http.MethodPut, "/api/org/invites/join-rules", strings.NewReader(body), t).Code
+	}
+	evaluate := func(t *testing.T, sc accessControlScenarioContext, body string) dtos.InviteJoinRulesEvaluation {
+		t.Helper()
+		response := callAPI(sc.server, http.MethodPost, "/api/org/invites/join-rules/evaluate", strings.NewReader(body), t)
+		require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+		var evaluation dtos.InviteJoinRulesEvaluation
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &evaluation))
+		return evaluation
+	}
+
+	t.Run("rules are validated and normalized", func(t *testing.T) {
+		sc, _, team := setup(t)
+		assert.Equal(t, http.StatusBadRequest, put(t, sc, `{"rules": [{"match": "suffix", "pattern": "x", "role": "Viewer"}]}`))
+		assert.Equal(t, http.StatusBadRequest, put(t, sc, `{"rules": [{"match": "regex", "pattern": "(", "role": "Viewer"}]}`))
+		assert.Equal(t, http.StatusBadRequest, put(t, sc, `{"rules": [{"match": "domain", "pattern": "sre.company.com"}]}`))
+		assert.Equal(t, http.StatusBadRequest, put(t, sc, `{"rules": [{"match": "domain", "pattern": "sre.company.com", "teams": [42]}]}`))
+		assert.Equal(t, http.StatusBadRequest, put(t, sc, `{"rules": [{"match": "domain", "pattern": "sre.company.com", "role": "Owner"}]}`))
+
+		assert.Equal(t, http.StatusOK, put(t, sc, fmt.Sprintf(`{"rules": [{"match": "domain", "pattern": " @SRE.company.com", "teams": [%d]}]}`, team.Id)))
+		response := callAPI(sc.server, http.MethodGet, "/api/org/invites/join-rules", nil, t)
+		require.Equal(t, http.StatusOK, response.Code)
+		assert.JSONEq(t, fmt.Sprintf(`{"rules": [{"match": "domain", "pattern": "sre.company.com", "teams": [%d]}]}`, team.Id), response.Body.String())
+	})
+
+	t.Run("dry runs evaluate the saved or the given rules", func(t *testing.T) {
+		sc, _, team := setup(t)
+		require.Equal(t, http.StatusOK, put(t, sc, fmt.Sprintf(`{"rules": [{"match": "domain", "pattern": "sre.company.com", "teams": [%d]}]}`, team.Id)))
+
+		evaluation := evaluate(t, sc, `{"email": "ana@sre.company.com"}`)
+		assert.Equal(t, []int{0}, evaluation.Matched)
+		assert.Equal(t, []int64{team.Id}, evaluation.Teams)
+
+		evaluation = evaluate(t, sc, `{"email": "ana@sre.company.com", "rules": [{"match": "regex", "pattern": "@sre\\.", "role": "Editor"}]}`)
+		assert.Equal(t, org.RoleEditor, evaluation.Role)
+		assert.Empty(t, evaluation.Teams)
+	})
+
+	t.Run("accepting an invite applies the matching rules", func(t *testing.T) {
+		sc, stack, team := setup(t)
+		require.Equal(t, http.StatusOK, put(t, sc, fmt.Sprintf(`{"rules": [{"match": "domain", "pattern": "sre.company.com", "role": "Editor", "teams": [%d]}]}`, team.Id)))
+		origAddOrUpdateTeamMember := addOrUpdateTeamMember
+		t.Cleanup(func() { addOrUpdateTeamMember = origAddOrUpdateTeamMember })
+		var joined []int64
+		addOrUpdateTeamMember = func(ctx context.Context, _ accesscontrol.TeamPermissionsService, userID, orgID, teamID int64, permission string) error {
+			joined = append(joined, teamID)
+			return nil
+		}
+
+		response := callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(`{"loginOrEmail": "ana@sre.company.com", "role": "Viewer"}`), t)
+		require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+		pending := stack.Pending(t, 1)
+		require.Len(t, pending, 1)
+
+		// the first user creates the organization
+		_, err := sc.db.CreateUser(context.Background(), user.CreateUserCommand{Login: "admin"})
+		require.NoError(t, err)
+		usr, err := sc.db.CreateUser(context.Background(), user.CreateUserCommand{Email: "ana@sre.company.com", Login: "ana", SkipOrgSetup: true})
+		require.NoError(t, err)
+		ok, rsp := sc.hs.applyUserInvite(context.Background(), usr, pending[0], false, nil)
+		require.True(t, ok, "%v", rsp)
+		assert.Equal(t, []int64{team.Id}, joined)
+
+		var role string
+		require.NoError(t, sc.db.WithDbSession(context.Background(), func(sess *sqlstore.DBSession) error {
+			_, err := sess.SQL("SELECT role FROM org_user WHERE org_id = ? AND user_id = ?", 1, usr.ID).Get(&role)
+			return err
+		}))
+		assert.Equal(t, string(org.RoleEditor), role, "the rule raises the role of the invite")
+	})
+
+	t.Run("rules don't raise the role above the maximum role of invites", func(t *testing.T) {
+		sc, stack, _ := setup(t)
+		sc.hs.Cfg.InviteEmailMaxRole = string(org.RoleEditor)
+		require.Equal(t, http.StatusOK, put(t, sc, `{"rules": [{"match": "domain", "pattern": "sre.company.com", "role": "Admin"}]}`))
+
+		response := callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(`{"loginOrEmail": "ana@sre.company.com", "role": "Viewer"}`), t)
+		require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+		pending := stack.Pending(t, 1)
+		require.Len(t, pending, 1)
+
+		_, err := sc.db.CreateUser(context.Background(), user.CreateUserCommand{Login: "admin"})
+		require.NoError(t, err)
+		usr, err := sc.db.CreateUser(context.Background(), user.CreateUserCommand{Email: "ana@sre.company.com", Login: "ana", SkipOrgSetup: true})
+		require.NoError(t, err)
+		ok, rsp := sc.hs.applyUserInvite(context.Background(), usr, pending[0], false, nil)
+		require.True(t, ok, "%v", rsp)
+
+		var role string
+		require.NoError(t, sc.db.WithDbSession(context.Background(), func(sess *sqlstore.DBSession) error {
+			_, err := sess.SQL("SELECT role FROM org_user WHERE org_id = ? AND user_id = ?", 1, usr.ID).Get(&role)
+			return err
+		}))
+		assert.Equal(t, string(org.RoleEditor), role)
+	})
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+// inviteLinkTokenTTL is how long users who completed an invite with the email of an existing
+// account have to sign in as that account and link the invite to it.
+const inviteLinkTokenTTL = 30 * time.Minute
+
+var errInvalidInviteLinkToken = errors.New("invalid or expired invite link token")
+
+// createInviteLinkToken signs the invite code and the ID of the existing user the invite may be
+// applied to, the token format is code.userID.expires.signature.
+func (hs *HTTPServer) createInviteLinkToken(code string, userID int64, expires time.Time) string {
+	payload := fmt.Sprintf("%s.%d.%d", code, userID, expires.Unix())
+	return payload + "." + hs.signInviteLinkPayload(payload)
+}
+
+// parseInviteLinkToken returns the invite code and the user ID of a valid link token.
+func (hs *HTTPServer) parseInviteLinkToken(token string, now time.Time) (string, int64, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 4 {
+		return "", 0, errInvalidInviteLinkToken
+	}
+	payload := strings.Join(parts[:3], ".")
+	if !hmac.Equal([]byte(parts[3]), []byte(hs.signInviteLinkPayload(payload))) {
+		return "", 0, errInvalidInviteLinkToken
+	}
+	userID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "", 0, errInvalidInviteLinkToken
+	}
+	expires, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || now.Unix() > expires {
+		return "", 0, errInvalidInviteLinkToken
+	}
+	return parts[0], userID, nil
+}
+
+func (hs *HTTPServer) signInviteLinkPayload(payload string) string {
+	mac := hmac.New(sha256.New, []byte(hs.Cfg.SecretKey))
+	_, _ = mac.Write([]byte("invite-link:" + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// inviteConflictResponse is returned when an invite is completed with the email of an existing
+// user. Instead of creating a duplicate account, the invitee signs in as the existing user and
+// links the invite to it with the returned token.
+func (hs *HTTPServer) inviteConflictResponse(c *models.ReqContext, invite *models.TempUserDTO, email string) (response.Response, bool) {
+	existing, err := hs.userService.GetByLogin(c.Req.Context(), &user.GetUserByLoginQuery{LoginOrEmail: email})
+	if err != nil || !strings.EqualFold(existing.Email, email) {
+		// the username is taken, not the email
+		return nil, false
+	}
+
+	expires := time.Now().Add(inviteLinkTokenTTL)
+	return response.JSON(http.StatusConflict, dtos.InviteConflict{
+		Message:   fmt.Sprintf("A user with email '%s' already exists, sign in to join the organization with it", email),
+		LinkToken: hs.createInviteLinkToken(invite.Code, existing.ID, expires),
+		ExpiresAt: expires,
+		LoginURL:  hs.Cfg.AppSubURL + "/login",
+	}), true
+}
+
+// swagger:route POST /user/org-invites/link signed_in_user linkSignedInUserOrgInvite
+//
+// Link an invite to the actual User.
+//
+// Applies an invite completed with the email of the actual User, using the link token returned
+// by the completion of the invite.
+//
+// Responses:
+// 200: okResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (hs *HTTPServer) LinkSignedInUserOrgInvite(c *models.ReqContext) response.Response {
+	form := dtos.LinkInviteForm{}
+	if err := web.Bind(c.Req, &form); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	code, userID, err := hs.parseInviteLinkToken(form.LinkToken, time.Now())
+	if err != nil {
+		return response.Error(http.StatusBadRequest, err.Error(), nil)
+	}
+	if userID != c.UserID {
+		return response.Error(http.StatusForbidden, "The invite can only be linked to the user it was completed for", nil)
+	}
+
+	query := models.GetTempUserByCodeQuery{Code: code}
+	if err := hs.tempUserService.GetTempUserByCode(c.Req.Context(), &query); err != nil {
+		if errors.Is(err, models.ErrTempUserNotFound) {
+			return response.Error(http.StatusNotFound, "Invite not found", nil)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to get invite", err)
+	}
+	invite := query.Result
+	if invite.Status != models.TmpUserInvitePending {
+		return response.Error(http.StatusNotFound, "Invite not found", nil)
+	}
+
+	usr, err := hs.userService.GetByID(c.Req.Context(), &user.GetUserByIDQuery{ID: c.UserID})
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get user", err)
+	}
+
+	if ok, rsp := hs.applyUserInvite(c.Req.Context(), usr, invite, true, hs.inviteCompletion(c)); !ok {
+		return rsp
+	}
+
+	return response.Success(fmt.Sprintf("Joined organization %s", invite.OrgName))
+}
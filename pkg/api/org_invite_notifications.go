@@ -0,0 +1,128 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+// defaultInviteNotificationsLimit is the number of invite notifications returned when the
+// request doesn't set a limit.
+const defaultInviteNotificationsLimit = 50
+
+// swagger:route GET /user/invite-notifications signed_in_user getSignedInUserInviteNotifications
+//
+// Get the invite notifications of the actual user in the current organization.
+//
+// Admins of the organization and the users who created invites are notified when invites created
+// by API keys or service accounts are created, when invites are completed, and when pending
+// invites are about to expire. The latest notifications come first.
+//
+// Responses:
+// 200: getSignedInUserInviteNotificationsResponse
+// 401: unauthorisedError
+// 500: internalServerError
+func (hs *HTTPServer) GetSignedInUserInviteNotifications(c *models.ReqContext) response.Response {
+	limit := c.QueryInt("limit")
+	if limit <= 0 {
+		limit = defaultInviteNotificationsLimit
+	}
+	query := models.GetInviteNotificationsQuery{OrgID: c.OrgID, UserID: c.UserID, UnreadOnly: c.QueryBool("unread"), Limit: limit}
+	if err := hs.tempUserService.GetInviteNotifications(c.Req.Context(), &query); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get invite notifications", err)
+	}
+	return response.JSON(http.StatusOK, query.Result)
+}
+
+// swagger:route POST /user/invite-notifications/read signed_in_user markSignedInUserInviteNotificationsRead
+//
+// Mark invite notifications of the actual user as read, all of them in the current organization
+// when no IDs are given.
+//
+// Responses:
+// 200: okResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 500: internalServerError
+func (hs *HTTPServer) MarkSignedInUserInviteNotificationsRead(c *models.ReqContext) response.Response {
+	form := MarkInviteNotificationsReadForm{}
+	if err := web.Bind(c.Req, &form); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	cmd := models.MarkInviteNotificationsReadCommand{OrgID: c.OrgID, UserID: c.UserID, IDs: form.IDs}
+	if err := hs.tempUserService.MarkInviteNotificationsRead(c.Req.Context(), &cmd); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to mark invite notifications as read", err)
+	}
+	return response.Success("Invite notifications marked as read")
+}
+
+// swagger:route GET /user/invite-notifications/preferences signed_in_user getSignedInUserInviteNotificationPreferences
+//
+// Get the invite events the actual user is notified of.
+//
+// Responses:
+// 200: getSignedInUserInviteNotificationPreferencesResponse
+// 401: unauthorisedError
+// 500: internalServerError
+func (hs *HTTPServer) GetSignedInUserInviteNotificationPreferences(c *models.ReqContext) response.Response {
+	query := models.GetInviteNotificationPreferencesQuery{UserID: c.UserID}
+	if err := hs.tempUserService.GetInviteNotificationPreferences(c.Req.Context(), &query); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get invite notification preferences", err)
+	}
+	return response.JSON(http.StatusOK, query.Result)
+}
+
+// swagger:route PUT /user/invite-notifications/preferences signed_in_user updateSignedInUserInviteNotificationPreferences
+//
+// Set the invite events the actual user is notified of, in all organizations.
+//
+// Responses:
+// 200: okResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 500: internalServerError
+func (hs *HTTPServer) UpdateSignedInUserInviteNotificationPreferences(c *models.ReqContext) response.Response {
+	preferences := models.InviteNotificationPreferences{}
+	if err := web.Bind(c.Req, &preferences); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	cmd := models.SetInviteNotificationPreferencesCommand{UserID: c.UserID, Preferences: preferences}
+	if err := hs.tempUserService.SetInviteNotificationPreferences(c.Req.Context(), &cmd); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to update invite notification preferences", err)
+	}
+	return response.Success("Invite notification preferences updated")
+}
+
+type MarkInviteNotificationsReadForm struct {
+	IDs []int64 `json:"ids"`
+}
+
+// swagger:parameters markSignedInUserInviteNotificationsRead
+type MarkSignedInUserInviteNotificationsReadParams struct {
+	// in:body
+	// required:true
+	Body MarkInviteNotificationsReadForm `json:"body"`
+}
+
+// swagger:parameters updateSignedInUserInviteNotificationPreferences
+type UpdateSignedInUserInviteNotificationPreferencesParams struct {
+	// in:body
+	// required:true
+	Body models.InviteNotificationPreferences `json:"body"`
+}
+
+// swagger:response getSignedInUserInviteNotificationsResponse
+type GetSignedInUserInviteNotificationsResponse struct {
+	// The response message
+	// in: body
+	Body []*models.InviteNotificationDTO `json:"body"`
+}
+
+// swagger:response getSignedInUserInviteNotificationPreferencesResponse
+type GetSignedInUserInviteNotificationPreferencesResponse struct {
+	// The response message
+	// in: body
+	Body *models.InviteNotificationPreferences `json:"body"`
+}
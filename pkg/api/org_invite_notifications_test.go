@@ -0,0 +1,57 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+func TestSignedInUserInviteNotifications(t *testing.T) {
+	sc, stack := setupInviteHTTPServer(t)
+	setInitCtxSignedInOrgAdmin(sc.initCtx)
+	ctx := context.Background()
+	require.NoError(t, sc.db.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		_, err := sess.Insert(&org.OrgUser{OrgID: sc.initCtx.OrgID, UserID: testUserID, Role: org.RoleAdmin, Created: time.Now(), Updated: time.Now()})
+		return err
+	}))
+	for _, email := range []string{"a@example.com", "b@example.com"} {
+		stack.Invite(email).InOrg(sc.initCtx.OrgID).WithCode(email).InvitedByAPIKey(1).Create(t)
+	}
+	notifications := func(t *testing.T, url string) []*models.InviteNotificationDTO {
+		t.Helper()
+		response := callAPI(sc.server, http.MethodGet, url, nil, t)
+		require.Equal(t, http.StatusOK, response.Code)
+		var result []*models.InviteNotificationDTO
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &result))
+		return result
+	}
+
+	result := notifications(t, "/api/user/invite-notifications")
+	require.Len(t, result, 2)
+	assert.Equal(t, models.InviteNotificationCreated, result[0].Event)
+	assert.Equal(t, "b@example.com", result[0].Email)
+	assert.Len(t, notifications(t, "/api/user/invite-notifications?limit=1"), 1)
+
+	response := callAPI(sc.server, http.MethodPost, "/api/user/invite-notifications/read", strings.NewReader(`{"ids": [`+strconv.FormatInt(result[0].Id, 10)+`]}`), t)
+	require.Equal(t, http.StatusOK, response.Code)
+	unread := notifications(t, "/api/user/invite-notifications?unread=true")
+	require.Len(t, unread, 1)
+	assert.Equal(t, "a@example.com", unread[0].Email)
+
+	response = callAPI(sc.server, http.MethodPut, "/api/user/invite-notifications/preferences", strings.NewReader(`{"created": true, "completed": false, "expiring": true}`), t)
+	require.Equal(t, http.StatusOK, response.Code)
+	response = callAPI(sc.server, http.MethodGet, "/api/user/invite-notifications/preferences", nil, t)
+	require.Equal(t, http.StatusOK, response.Code)
+	assert.JSONEq(t, `{"created": true, "completed": false, "expiring": true}`, response.Body.String())
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+const (
+	orgInvitesKVNamespace          = "org-invites"
+	inviteOnboardingEventKey       = "onboarding-event"
+	inviteOnboardingEventFileName  = "onboarding.ics"
+	defaultOnboardingEventDuration = 60
+)
+
+// swagger:route GET /org/invites/onboarding-event org_invites getOrgInviteOnboardingEvent
+//
+// Get the onboarding event attached to invite emails.
+//
+// Responses:
+// 200: getOrgInviteOnboardingEventResponse
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (hs *HTTPServer) GetOrgInviteOnboardingEvent(c *models.ReqContext) response.Response {
+	event, err := hs.getInviteOnboardingEvent(c.Req.Context(), c.OrgID)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get onboarding event", err)
+	}
+	return response.JSON(http.StatusOK, event)
+}
+
+// swagger:route PUT /org/invites/onboarding-event org_invites updateOrgInviteOnboardingEvent
+//
+// Update the onboarding event attached to invite emails.
+//
+// When enabled, emails for new invites carry a calendar event (ICS) for the onboarding session,
+// as long as the session has not started yet.
+//
+// Responses:
+// 200: okResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (hs *HTTPServer) UpdateOrgInviteOnboardingEvent(c *models.ReqContext) response.Response {
+	event := dtos.InviteOnboardingEvent{}
+	if err := web.Bind(c.Req, &event); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	if event.Enabled {
+		if strings.TrimSpace(event.Title) == "" {
+			return response.Error(http.StatusBadRequest, "Onboarding event title is required", nil)
+		}
+		if event.Start.IsZero() {
+			return response.Error(http.StatusBadRequest, "Onboarding event start is required", nil)
+		}
+	}
+	if event.DurationMinutes < 0 {
+		return response.Error(http.StatusBadRequest, "Onboarding event duration can't be negative", nil)
+	}
+	if event.MeetingURL != "" {
+		if u, err := url.Parse(event.MeetingURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return response.Error(http.StatusBadRequest, "Onboarding event meeting link must be an http(s) URL", err)
+		}
+	}
+
+	value, err := json.Marshal(event)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to save onboarding event", err)
+	}
+	store := kvstore.WithNamespace(hs.kvStore, c.OrgID, orgInvitesKVNamespace)
+	if err := store.Set(c.Req.Context(), inviteOnboardingEventKey, string(value)); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to save onboarding event", err)
+	}
+	return response.Success("Onboarding event updated")
+}
+
+func (hs *HTTPServer) getInviteOnboardingEvent(ctx context.Context, orgID int64) (dtos.InviteOnboardingEvent, error) {
+	event := dtos.InviteOnboardingEvent{DurationMinutes: defaultOnboardingEventDuration}
+	value, ok, err := kvstore.WithNamespace(hs.kvStore, orgID, orgInvitesKVNamespace).Get(ctx, inviteOnboardingEventKey)
+	if err != nil || !ok {
+		return event, err
+	}
+	err = json.Unmarshal([]byte(value), &event)
+	return event, err
+}
+
+// inviteOnboardingAttachments returns the calendar event to attach to the email of an invite,
+// if the organization has an upcoming onboarding event.
+func (hs *HTTPServer) inviteOnboardingAttachments(ctx context.Context, orgID int64, inviteCode string) []*models.SendEmailAttachFile {
+	event, err := hs.getInviteOnboardingEvent(ctx, orgID)
+	if err != nil {
+		// the invite is still worth sending without the calendar event
+		hs.log.FromContext(ctx).Warn("Failed to get onboarding event for invite", "orgId", orgID, "error", err)
+		return nil
+	}
+	now := time.Now()
+	if !event.Enabled || event.Start.Before(now) {
+		return nil
+	}
+
+	uid := fmt.Sprintf("onboarding-%d-%s@%s", orgID, inviteCode, hs.Cfg.Domain)
+	return []*models.SendEmailAttachFile{{
+		Name:    inviteOnboardingEventFileName,
+		Content: buildOnboardingICS(event, uid, setting.ToAbsUrl("invite/"+inviteCode), now),
+	}}
+}
+
+// buildOnboardingICS renders the onboarding event as an iCalendar (RFC 5545) document.
+func buildOnboardingICS(event dtos.InviteOnboardingEvent, uid string, inviteURL string, now time.Time) []byte {
+	duration := event.DurationMinutes
+	if duration == 0 {
+		duration = defaultOnboardingEventDuration
+	}
+	description := event.Description
+	if event.MeetingURL != "" {
+		description = strings.TrimSpace(description + "\n\nJoin: " + event.MeetingURL)
+	}
+	description = strings.TrimSpace(description + "\n\nAccept your invite: " + inviteURL)
+
+	const timeFormat = "20060102T150405Z"
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Grafana Labs//Grafana//EN",
+		"METHOD:PUBLISH",
+		"BEGIN:VEVENT",
+		"UID:" + uid,
+		"DTSTAMP:" + now.UTC().Format(timeFormat),
+		"DTSTART:" + event.Start.UTC().Format(timeFormat),
+		"DTEND:" + event.Start.Add(time.Duration(duration)*time.Minute).UTC().Format(timeFormat),
+		"SUMMARY:" + escapeICSText(event.Title),
+		"DESCRIPTION:" + escapeICSText(description),
+	}
+	if event.MeetingURL != "" {
+		lines = append(lines, "LOCATION:"+escapeICSText(event.MeetingURL), "URL:"+event.MeetingURL)
+	}
+	lines = append(lines, "END:VEVENT", "END:VCALENDAR")
+
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(foldICSLine(line))
+		b.WriteString("\r\n")
+	}
+	return []byte(b.String())
+}
+
+func escapeICSText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// foldICSLine splits lines longer than 75 octets, continuation lines start with a space.
+func foldICSLine(line string) string {
+	const limit = 75
+	if len(line) <= limit {
+		return line
+	}
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > limit {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	return b.String()
+}
+
+// swagger:parameters updateOrgInviteOnboardingEvent
+type UpdateOrgInviteOnboardingEventParams struct {
+	// in:body
+	// required:true
+	Body dtos.InviteOnboardingEvent `json:"body"`
+}
+
+// swagger:response getOrgInviteOnboardingEventResponse
+type GetOrgInviteOnboardingEventResponse struct {
+	// in: body
+	Body dtos.InviteOnboardingEvent `json:"body"`
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+)
+
+func TestBuildOnboardingICS(t *testing.T) {
+	start := time.Date(2026, 3, 2, 15, 0, 0, 0, time.FixedZone("CET", 3600))
+	event := dtos.InviteOnboardingEvent{
+		Enabled:     true,
+		Title:       "Welcome, new users; intro",
+		Description: "A tour of dashboards and alerting, with enough words to make the description line longer than seventy five octets.",
+		Start:       start,
+		MeetingURL:  "https://meet.example.com/onboarding",
+	}
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	ics := string(buildOnboardingICS(event, "onboarding-1-code@example.com", "http://localhost:3000/invite/code", now))
+
+	assert.True(t, strings.HasPrefix(ics, "BEGIN:VCALENDAR\r\n"))
+	assert.True(t, strings.HasSuffix(ics, "END:VEVENT\r\nEND:VCALENDAR\r\n"))
+	assert.Contains(t, ics, "\r\nUID:onboarding-1-code@example.com\r\n")
+	assert.Contains(t, ics, "\r\nDTSTAMP:20260301T100000Z\r\n")
+	assert.Contains(t, ics, "\r\nDTSTART:20260302T140000Z\r\n")
+	assert.Contains(t, ics, "\r\nDTEND:20260302T150000Z\r\n")
+	assert.Contains(t, ics, "\r\nSUMMARY:Welcome\\, new users\\; intro\r\n")
+	assert.Contains(t, ics, "\r\nURL:https://meet.example.com/onboarding\r\n")
+
+	for _, line := range strings.Split(strings.TrimSuffix(ics, "\r\n"), "\r\n") {
+		assert.LessOrEqual(t, len(line), 75, line)
+	}
+	unfolded := strings.ReplaceAll(ics, "\r\n ", "")
+	assert.Contains(t, unfolded, "with enough words to make the description line longer than seventy five octets.\\n\\nJoin: https://meet.example.com/onboarding")
+	assert.Contains(t, unfolded, "Accept your invite: http://localhost:3000/invite/code")
+}
+
+func TestOrgInviteOnboardingEventAPIEndpoints(t *testing.T) {
+	setup := func(t *testing.T) accessControlScenarioContext {
+		sc := setupHTTPServer(t, true)
+		sc.hs.kvStore = kvstore.ProvideService(sc.db)
+		setInitCtxSignedInViewer(sc.initCtx)
+		setAccessControlPermissions(sc.acmock, []accesscontrol.Permission{
+			{Action: accesscontrol.ActionOrgUsersAdd, Scope: accesscontrol.ScopeUsersAll},
+			{Action: accesscontrol.ActionOrgsWrite},
+		}, sc.initCtx.OrgID)
+		return sc
+	}
+
+	t.Run("returns a disabled event by default", func(t *testing.T) {
+		sc := setup(t)
+
+		response := callAPI(sc.server, http.MethodGet, "/api/org/invites/onboarding-event", nil, t)
+		require.Equal(t, http.StatusOK, response.Code)
+
+		var event dtos.InviteOnboardingEvent
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &event))
+		assert.False(t, event.Enabled)
+		assert.Equal(t, defaultOnboardingEventDuration, event.DurationMinutes)
+		assert.Nil(t, sc.hs.inviteOnboardingAttachments(context.Background(), sc.initCtx.OrgID, "code"))
+	})
+
+	t.Run("saves the event and attaches it to invites", func(t *testing.T) {
+		sc := setup(t)
+		start := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+
+		body := `{"enabled": true, "title": "Onboarding", "start": "` + start.Format(time.RFC3339) + `", "durationMinutes": 30, "meetingUrl": "https://meet.example.com/x"}`
+		response := callAPI(sc.server, http.MethodPut, "/api/org/invites/onboarding-event", strings.NewReader(body), t)
+		require.Equal(t, http.StatusOK, response.Code)
+
+		response = callAPI(sc.server, http.MethodGet, "/api/org/invites/onboarding-event", nil, t)
+		require.Equal(t, http.StatusOK, response.Code)
+		var event dtos.InviteOnboardingEvent
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &event))
+		assert.True(t, event.Enabled)
+		assert.Equal(t, "Onboarding", event.Title)
+		assert.True(t, start.Equal(event.Start))
+		assert.Equal(t, 30, event.DurationMinutes)
+
+		files := sc.hs.inviteOnboardingAttachments(context.Background(), sc.initCtx.OrgID, "code")
+		require.Len(t, files, 1)
+		assert.Equal(t, inviteOnboardingEventFileName, files[0].Name)
+		assert.Contains(t, string(files[0].Content), "SUMMARY:Onboarding\r\n")
+	})
+
+	t.Run("does not attach past events", func(t *testing.T) {
+		sc := setup(t)
+		start := time.Now().Add(-time.Hour).UTC()
+
+		body := `{"enabled": true, "title": "Onboarding", "start": "` + start.Format(time.RFC3339) + `"}`
+		response := callAPI(sc.server, http.MethodPut, "/api/org/invites/onboarding-event", strings.NewReader(body), t)
+		require.Equal(t, http.StatusOK, response.Code)
+		assert.Nil(t, sc.hs.inviteOnboardingAttachments(context.Background(), sc.initCtx.OrgID, "code"))
+	})
+
+	t.Run("rejects invalid events", func(t *testing.T) {
+		sc := setup(t)
+
+		for _, body := range []string{
+			`{"enabled": true, "start": "2030-01-01T10:00:00Z"}`,
+			`{"enabled": true, "title": "Onboarding"}`,
+			`{"title": "Onboarding", "durationMinutes": -5}`,
+			`{"title": "Onboarding", "meetingUrl": "javascript:alert(1)"}`,
+		} {
+			response := callAPI(sc.server, http.MethodPut, "/api/org/invites/onboarding-event", strings.NewReader(body), t)
+			assert.Equal(t, http.StatusBadRequest, response.Code, body)
+		}
+	})
+
+	t.Run("requires org write permission to update", func(t *testing.T) {
+		sc := setup(t)
+		setAccessControlPermissions(sc.acmock, []accesscontrol.Permission{
+			{Action: accesscontrol.ActionOrgUsersAdd, Scope: accesscontrol.ScopeUsersAll},
+		}, sc.initCtx.OrgID)
+
+		response := callAPI(sc.server, http.MethodPut, "/api/org/invites/onboarding-event", strings.NewReader(`{}`), t)
+		assert.Equal(t, http.StatusForbidden, response.Code)
+	})
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+// checkInviteOrgs checks the other organizations of the form, which only Grafana admins can invite
+// to. It returns an error response when the invite can't be created.
+func (hs *HTTPServer) checkInviteOrgs(c *models.ReqContext, inviteDto *dtos.AddInviteForm) response.Response {
+	if len(inviteDto.Orgs) == 0 {
+		return nil
+	}
+	if !c.IsGrafanaAdmin {
+		return response.Error(http.StatusForbidden, "Permission denied: only Grafana admins can invite to several organizations", nil)
+	}
+	seen := make(map[int64]bool, len(inviteDto.Orgs))
+	for _, inviteOrg := range inviteDto.Orgs {
+		if inviteOrg.OrgID == c.OrgID {
+			return response.Error(http.StatusBadRequest, "The invite is to the current organization already, set its role with role", nil)
+		}
+		if seen[inviteOrg.OrgID] {
+			return response.Error(http.StatusBadRequest, fmt.Sprintf("Organization %d is listed more than once", inviteOrg.OrgID), nil)
+		}
+		seen[inviteOrg.OrgID] = true
+		if !inviteOrg.Role.IsValid() {
+			return response.Error(http.StatusBadRequest, fmt.Sprintf("Invalid role specified for organization %d", inviteOrg.OrgID), nil)
+		}
+		if _, err := hs.orgService.GetByID(c.Req.Context(), &org.GetOrgByIdQuery{ID: inviteOrg.OrgID}); err != nil {
+			if errors.Is(err, org.ErrOrgNotFound) || errors.Is(err, models.ErrOrgNotFound) {
+				return response.Error(http.StatusBadRequest, fmt.Sprintf("Organization %d not found", inviteOrg.OrgID), nil)
+			}
+			return response.Error(http.StatusInternalServerError, "Failed to get organization", err)
+		}
+		if rsp := hs.checkInviteRole(inviteDto.Delivery, inviteOrg.Role); rsp != nil {
+			return rsp
+		}
+	}
+	return nil
+}
+
+// addInviteOrgs records the other organizations the invitee joins when the invite is accepted.
+func (hs *HTTPServer) addInviteOrgs(ctx context.Context, orgID, inviteID int64, orgs []dtos.InviteOrg) response.Response {
+	for _, inviteOrg := range orgs {
+		cmd := models.AddTempUserGrantCommand{
+			OrgID:        orgID,
+			TempUserID:   inviteID,
+			ResourceKind: models.TempUserGrantOrg,
+			ResourceUID:  strconv.FormatInt(inviteOrg.OrgID, 10),
+			Permission:   string(inviteOrg.Role),
+		}
+		if err := hs.tempUserService.AddTempUserGrant(ctx, &cmd); err != nil {
+			return response.Error(http.StatusInternalServerError, "Failed to save invite organization", err)
+		}
+	}
+	return nil
+}
+
+// getInviteOrgs returns the grants of the other organizations of the invite.
+func (hs *HTTPServer) getInviteOrgs(ctx context.Context, invite *models.TempUserDTO) ([]*models.TempUserGrant, error) {
+	query := models.GetTempUserGrantsQuery{TempUserID: invite.Id}
+	if err := hs.tempUserService.GetTempUserGrants(ctx, &query); err != nil {
+		return nil, err
+	}
+	var grants []*models.TempUserGrant
+	for _, grant := range query.Result {
+		if grant.ResourceKind == models.TempUserGrantOrg {
+			grants = append(grants, grant)
+		}
+	}
+	return grants, nil
+}
+
+// joinInviteOrgs adds the user who accepted an invite to its other organizations. Organizations the
+// user is a member of already are left as they are, and organizations deleted since the invite was
+// created are skipped.
+func (hs *HTTPServer) joinInviteOrgs(ctx context.Context, usr *user.User, invite *models.TempUserDTO, grants []*models.TempUserGrant) error {
+	for _, grant := range grants {
+		orgID, err := strconv.ParseInt(grant.ResourceUid, 10, 64)
+		if err != nil {
+			return err
+		}
+		cmd := models.AddOrgUserCommand{OrgId: orgID, UserId: usr.ID, Role: org.RoleType(grant.Permission)}
+		if err := hs.SQLStore.AddOrgUser(ctx, &cmd); err != nil {
+			switch {
+			case errors.Is(err, models.ErrOrgUserAlreadyAdded):
+			case errors.Is(err, models.ErrOrgNotFound):
+				hs.log.FromContext(ctx).Warn("Skipping the deleted organization of an invite", "inviteId", invite.Id, "orgId", orgID)
+			default:
+				return err
+			}
+		}
+	}
+	return nil
+}
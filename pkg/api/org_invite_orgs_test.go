@@ -0,0 +1,127 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/org/orgimpl"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/services/user/usertest"
+)
+
+func TestAddOrgInviteToSeveralOrgs(t *testing.T) {
+	setup := func(t *testing.T) accessControlScenarioContext {
+		sc, _ := setupInviteHTTPServer(t)
+		sc.hs.userService = &slackInviteUserService{&usertest.FakeUserService{}}
+		sc.hs.orgService = orgimpl.ProvideService(sc.db, sc.cfg)
+		setupOrgUsersDBForAccessControlTests(t, sc.db)
+		setInitCtxSignedInOrgAdmin(sc.initCtx)
+		sc.initCtx.IsGrafanaAdmin = true
+		setAccessControlPermissions(sc.acmock, []accesscontrol.Permission{{Action: accesscontrol.ActionOrgUsersAdd}}, sc.initCtx.OrgID)
+		return sc
+	}
+	invite := func(t *testing.T, sc accessControlScenarioContext, orgs string) int {
+		body := `{"loginOrEmail": "new@example.com", "role": "Viewer", "orgs": ` + orgs + `}`
+		return callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(body), t).Code
+	}
+
+	t.Run("records the other organizations of the invite", func(t *testing.T) {
+		sc := setup(t)
+		require.Equal(t, http.StatusOK, invite(t, sc, `[{"orgId": 2, "role": "Editor"}]`))
+
+		query := models.GetTempUsersQuery{OrgId: sc.initCtx.OrgID, Email: "new@example.com", Status: models.TmpUserInvitePending}
+		require.NoError(t, sc.hs.tempUserService.GetTempUsersQuery(context.Background(), &query))
+		require.Len(t, query.Result, 1)
+		grants, err := sc.hs.getInviteOrgs(context.Background(), query.Result[0])
+		require.NoError(t, err)
+		require.Len(t, grants, 1)
+		assert.Equal(t, "2", grants[0].ResourceUid)
+		assert.Equal(t, string(org.RoleEditor), grants[0].Permission)
+	})
+
+	t.Run("rejects invalid organizations", func(t *testing.T) {
+		sc := setup(t)
+		assert.Equal(t, http.StatusBadRequest, invite(t, sc, `[{"orgId": 1, "role": "Editor"}]`), "current organization")
+		assert.Equal(t, http.StatusBadRequest, invite(t, sc, `[{"orgId": 2, "role": "Editor"}, {"orgId": 2, "role": "Viewer"}]`), "duplicate")
+		assert.Equal(t, http.StatusBadRequest, invite(t, sc, `[{"orgId": 2, "role": "Chief"}]`), "role")
+		assert.Equal(t, http.StatusBadRequest, invite(t, sc, `[{"orgId": 42, "role": "Editor"}]`), "missing organization")
+	})
+
+	t.Run("only Grafana admins can invite to several organizations", func(t *testing.T) {
+		sc := setup(t)
+		sc.initCtx.IsGrafanaAdmin = false
+		assert.Equal(t, http.StatusForbidden, invite(t, sc, `[{"orgId": 2, "role": "Editor"}]`))
+		assert.Equal(t, http.StatusOK, invite(t, sc, `[]`))
+	})
+}
+
+func TestApplyInviteToSeveralOrgs(t *testing.T) {
+	setup := func(t *testing.T, otherOrgs ...string) (accessControlScenarioContext, *models.TempUserDTO, int64) {
+		sc, stack := setupInviteHTTPServer(t)
+		setupOrgUsersDBForAccessControlTests(t, sc.db)
+		third, err := sc.db.CreateOrgWithMember("Third", testServerAdminViewer.UserID)
+		require.NoError(t, err)
+
+		invite := stack.Invite(testEditorOrg1.Email).InOrg(testAdminOrg2.OrgID).WithCode("invite-code").Create(t)
+		for _, other := range append([]string{strconv.FormatInt(third.Id, 10)}, otherOrgs...) {
+			require.NoError(t, stack.TempUsers.AddTempUserGrant(context.Background(), &models.AddTempUserGrantCommand{
+				OrgID:        testAdminOrg2.OrgID,
+				TempUserID:   invite.Id,
+				ResourceKind: models.TempUserGrantOrg,
+				ResourceUID:  other,
+				Permission:   string(org.RoleEditor),
+			}))
+		}
+		return sc, invite, third.Id
+	}
+	userOrgs := func(t *testing.T, sc accessControlScenarioContext) map[int64]org.RoleType {
+		query := models.GetUserOrgListQuery{UserId: testEditorOrg1.UserID}
+		require.NoError(t, sc.db.GetUserOrgList(context.Background(), &query))
+		orgs := map[int64]org.RoleType{}
+		for _, userOrg := range query.Result {
+			orgs[userOrg.OrgId] = userOrg.Role
+		}
+		return orgs
+	}
+
+	t.Run("joins all the organizations of the invite", func(t *testing.T) {
+		// the user is a member of the first organization already, and keeps their role
+		sc, invite, third := setup(t, strconv.FormatInt(testEditorOrg1.OrgID, 10), "42")
+		ok, rsp := sc.hs.applyUserInvite(context.Background(), &user.User{ID: testEditorOrg1.UserID}, invite, false, nil)
+		require.True(t, ok, rsp)
+
+		assert.Equal(t, map[int64]org.RoleType{
+			testEditorOrg1.OrgID: testEditorOrg1.OrgRole,
+			testAdminOrg2.OrgID:  org.RoleViewer,
+			third:                org.RoleEditor,
+		}, userOrgs(t, sc))
+	})
+
+	t.Run("joins none of the organizations when one fails", func(t *testing.T) {
+		sc, invite, _ := setup(t, "not-an-org")
+		ok, rsp := sc.hs.applyUserInvite(context.Background(), &user.User{ID: testEditorOrg1.UserID}, invite, false, nil)
+		require.False(t, ok)
+		assert.Equal(t, http.StatusInternalServerError, rsp.Status())
+
+		assert.Equal(t, map[int64]org.RoleType{testEditorOrg1.OrgID: testEditorOrg1.OrgRole}, userOrgs(t, sc))
+	})
+
+	t.Run("joins none of the organizations when the invite was revoked meanwhile", func(t *testing.T) {
+		sc, invite, _ := setup(t)
+		require.NoError(t, sc.hs.tempUserService.UpdateTempUserStatus(context.Background(), &models.UpdateTempUserStatusCommand{Code: invite.Code, Status: models.TmpUserRevoked}))
+		ok, rsp := sc.hs.applyUserInvite(context.Background(), &user.User{ID: testEditorOrg1.UserID}, invite, false, nil)
+		require.False(t, ok)
+		assert.Equal(t, http.StatusConflict, rsp.Status())
+
+		assert.Equal(t, map[int64]org.RoleType{testEditorOrg1.OrgID: testEditorOrg1.OrgRole}, userOrgs(t, sc))
+	})
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+const inviteRateLimitCacheKeyPrefix = "invite-rate-limit"
+
+// inviteRateCount is the number of invites created in a rate limit window.
+type inviteRateCount struct {
+	Count int
+}
+
+func init() {
+	remotecache.Register(inviteRateCount{})
+}
+
+// inviteRateLimit is the state of the invite rate limits of a request, see withInviteRateLimit.
+type inviteRateLimit struct {
+	// the count of each limit when the request was checked
+	counts map[string]int
+	end    time.Time
+}
+
+// withInviteRateLimit runs handler unless the organization or the inviter reached their invite rate
+// limit in the current window, which fails with 429 and a Retry-After header telling when the
+// window ends. Requests creating an invite are counted. The counts are kept in the remote cache so
+// that the limits hold across instances, they are best effort as the cache has no atomic increment,
+// and invites aren't limited when the cache fails.
+func (hs *HTTPServer) withInviteRateLimit(c *models.ReqContext, handler func(c *models.ReqContext) response.Response) response.Response {
+	limit, rsp := hs.checkInviteRateLimit(c.Req.Context(), c.OrgID, inviteRateLimitInviter(c), 1)
+	if rsp != nil {
+		return rsp
+	}
+	resp := handler(c)
+	if resp.Status() < http.StatusBadRequest {
+		hs.countInvites(c.Req.Context(), limit, 1)
+	}
+	return resp
+}
+
+// inviteRateLimitInviter identifies the inviter of a request for the per inviter limit.
+func inviteRateLimitInviter(c *models.ReqContext) string {
+	if c.ApiKeyID != 0 {
+		return fmt.Sprintf("apikey-%d", c.ApiKeyID)
+	}
+	return fmt.Sprintf("user-%d", c.UserID)
+}
+
+// checkInviteRateLimit returns a 429 response when creating n more invites would exceed the invite
+// rate limits of the organization or the inviter. Once the invites are created, they are counted
+// with countInvites and the returned limit, which is nil when invites aren't limited.
+func (hs *HTTPServer) checkInviteRateLimit(ctx context.Context, orgID int64, inviter string, n int) (*inviteRateLimit, response.Response) {
+	if hs.RemoteCacheService == nil || (hs.Cfg.InviteRateLimitPerOrg <= 0 && hs.Cfg.InviteRateLimitPerInviter <= 0) {
+		return nil, nil
+	}
+
+	window := hs.Cfg.InviteRateLimitWindow
+	start := time.Now().Truncate(window)
+	end := start.Add(window)
+	limits := map[string]int{}
+	if hs.Cfg.InviteRateLimitPerOrg > 0 {
+		limits[fmt.Sprintf("%s-org-%d-%d", inviteRateLimitCacheKeyPrefix, orgID, start.Unix())] = hs.Cfg.InviteRateLimitPerOrg
+	}
+	if hs.Cfg.InviteRateLimitPerInviter > 0 {
+		limits[fmt.Sprintf("%s-inviter-%d-%s-%d", inviteRateLimitCacheKeyPrefix, orgID, inviter, start.Unix())] = hs.Cfg.InviteRateLimitPerInviter
+	}
+
+	limit := &inviteRateLimit{counts: make(map[string]int, len(limits)), end: end}
+	for key, maxCount := range limits {
+		count, err := hs.inviteRateCount(ctx, key)
+		if err != nil {
+			hs.log.Warn("Failed to get invite rate count, not limiting invites", "key", key, "error", err)
+			return nil, nil
+		}
+		if count+n > maxCount {
+			return nil, response.Error(http.StatusTooManyRequests, "Too many invites, try again later", nil).
+				SetHeader("Retry-After", retryAfterSeconds(end))
+		}
+		limit.counts[key] = count
+	}
+	return limit, nil
+}
+
+// countInvites counts n invites created after checking limit.
+func (hs *HTTPServer) countInvites(ctx context.Context, limit *inviteRateLimit, n int) {
+	if limit == nil {
+		return
+	}
+	for key, count := range limit.counts {
+		// the counts expire with the window, the Set default of 24h applies to zero only
+		if err := hs.RemoteCacheService.Set(ctx, key, inviteRateCount{Count: count + n}, time.Until(limit.end)+time.Second); err != nil {
+			hs.log.Warn("Failed to update invite rate count", "key", key, "error", err)
+		}
+	}
+}
+
+func (hs *HTTPServer) inviteRateCount(ctx context.Context, key string) (int, error) {
+	cached, err := hs.RemoteCacheService.Get(ctx, key)
+	if errors.Is(err, remotecache.ErrCacheItemNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	count, _ := cached.(inviteRateCount)
+	return count.Count, nil
+}
+
+// TooManyRequestsError is returned when a rate limit is exceeded.
+//
+// swagger:response tooManyRequestsError
+type TooManyRequestsError struct {
+	// Seconds after which the request can be retried
+	//
+	// in:header
+	RetryAfter string `json:"Retry-After"`
+	// in:body
+	Body ErrorResponseBody `json:"body"`
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+)
+
+func TestAddOrgInviteRateLimit(t *testing.T) {
+	setup := func(t *testing.T, perOrg, perInviter int) accessControlScenarioContext {
+		sc, _ := setupInviteHTTPServer(t)
+		sc.hs.RemoteCacheService = remotecache.NewFakeStore(t)
+		sc.hs.Cfg.InviteRateLimitPerOrg = perOrg
+		sc.hs.Cfg.InviteRateLimitPerInviter = perInviter
+		sc.hs.Cfg.InviteRateLimitWindow = time.Hour
+		setInitCtxSignedInViewer(sc.initCtx)
+		setAccessControlPermissions(sc.acmock, []accesscontrol.Permission{{Action: accesscontrol.ActionOrgUsersAdd, Scope: accesscontrol.ScopeUsersAll}}, sc.initCtx.OrgID)
+		return sc
+	}
+	invite := func(t *testing.T, sc accessControlScenarioContext, n int) int {
+		body := fmt.Sprintf(`{"loginOrEmail": "invitee%d@example.com", "role": "Viewer"}`, n)
+		response := callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(body), t)
+		if response.Code == http.StatusTooManyRequests {
+			retryAfter, err := strconv.Atoi(response.Header().Get("Retry-After"))
+			require.NoError(t, err)
+			assert.True(t, retryAfter >= 1 && retryAfter <= 3600, retryAfter)
+		}
+		return response.Code
+	}
+
+	t.Run("limits the invites of each inviter", func(t *testing.T) {
+		sc := setup(t, 0, 2)
+		assert.Equal(t, http.StatusOK, invite(t, sc, 1))
+		assert.Equal(t, http.StatusOK, invite(t, sc, 2))
+		assert.Equal(t, http.StatusTooManyRequests, invite(t, sc, 3))
+
+		sc.initCtx.SignedInUser.UserID++
+		assert.Equal(t, http.StatusOK, invite(t, sc, 3), "other inviters have their own limit")
+	})
+
+	t.Run("limits the invites of the organization", func(t *testing.T) {
+		sc := setup(t, 2, 0)
+		assert.Equal(t, http.StatusOK, invite(t, sc, 1))
+		sc.initCtx.SignedInUser.UserID++
+		assert.Equal(t, http.StatusOK, invite(t, sc, 2))
+		sc.initCtx.SignedInUser.UserID++
+		assert.Equal(t, http.StatusTooManyRequests, invite(t, sc, 3))
+	})
+
+	t.Run("failed invites are not counted", func(t *testing.T) {
+		sc := setup(t, 1, 0)
+		response := callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(`{"loginOrEmail": "invitee@example.com", "role": "Chief"}`), t)
+		require.Equal(t, http.StatusBadRequest, response.Code)
+		assert.Equal(t, http.StatusOK, invite(t, sc, 1))
+	})
+
+	t.Run("each invite of a batch is counted", func(t *testing.T) {
+		sc := setup(t, 0, 3)
+		bulk := func(emails ...string) int {
+			invites := make([]string, 0, len(emails))
+			for _, email := range emails {
+				invites = append(invites, fmt.Sprintf(`{"loginOrEmail": "%s", "role": "Viewer"}`, email))
+			}
+			body := `{"invites": [` + strings.Join(invites, ",") + `]}`
+			return callAPI(sc.server, http.MethodPost, "/api/org/invites/bulk", strings.NewReader(body), t).Code
+		}
+
+		assert.Equal(t, http.StatusTooManyRequests, bulk("a@example.com", "b@example.com", "c@example.com", "d@example.com"))
+		assert.Equal(t, http.StatusOK, bulk("a@example.com", "b@example.com"))
+		assert.Equal(t, http.StatusOK, invite(t, sc, 1))
+		assert.Equal(t, http.StatusTooManyRequests, invite(t, sc, 2))
+	})
+
+	t.Run("invites are not limited by default", func(t *testing.T) {
+		sc := setup(t, 0, 0)
+		for n := 0; n < 5; n++ {
+			assert.Equal(t, http.StatusOK, invite(t, sc, n))
+		}
+	})
+}
@@ -0,0 +1,318 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/models"
+	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/guardian"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/util"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+// swagger:route POST /org/invites/share org_invites shareResourceByEmail
+//
+// Share a dashboard or folder by email.
+//
+// Members of the organization are given the permission right away. Other people are invited to the
+// organization as viewers, or get the permission added to their pending invite, and are given the
+// permission when they accept the invite. Inviting requires the `org.users:add` permission on top of
+// the admin permission on the dashboard or folder. New invites count towards the invite rate limits.
+//
+// Responses:
+// 200: shareResourceByEmailResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 409: conflictError
+// 412: SMTPNotEnabledError
+// 429: tooManyRequestsError
+// 500: internalServerError
+func (hs *HTTPServer) ShareResourceByEmail(c *models.ReqContext) response.Response {
+	form := dtos.ShareInviteForm{}
+	if err := web.Bind(c.Req, &form); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	form.Email = strings.TrimSpace(form.Email)
+	if !util.IsEmail(form.Email) {
+		return response.Error(http.StatusBadRequest, "Invalid email", nil)
+	}
+	if form.ResourceKind != models.TempUserGrantDashboard && form.ResourceKind != models.TempUserGrantFolder {
+		return response.Error(http.StatusBadRequest, "Invalid resource kind, must be dashboard or folder", nil)
+	}
+	if form.Permission == "" {
+		form.Permission = models.PERMISSION_VIEW.String()
+	}
+	if form.Permission != models.PERMISSION_VIEW.String() && form.Permission != models.PERMISSION_EDIT.String() {
+		return response.Error(http.StatusBadRequest, "Invalid permission, must be View or Edit", nil)
+	}
+
+	dash, rsp := hs.getSharedResource(c, form.ResourceKind, form.ResourceUID)
+	if rsp != nil {
+		return rsp
+	}
+
+	usr, err := hs.userService.GetByLogin(c.Req.Context(), &user.GetUserByLoginQuery{LoginOrEmail: form.Email})
+	if err != nil && !errors.Is(err, user.ErrUserNotFound) {
+		return response.Error(http.StatusInternalServerError, "Failed to query db for existing user check", err)
+	}
+	if usr != nil {
+		member, err := hs.isOrgMember(c.Req.Context(), c.OrgID, usr.ID)
+		if err != nil {
+			return response.Error(http.StatusInternalServerError, "Failed to get user organizations", err)
+		}
+		if member {
+			if err := hs.grantResourcePermission(c.Req.Context(), c.OrgID, usr.ID, form.ResourceKind, dash, form.Permission); err != nil {
+				return response.Error(http.StatusInternalServerError, "Failed to update permissions", err)
+			}
+			return response.JSON(http.StatusOK, util.DynMap{
+				"message": fmt.Sprintf("Shared %s with %s", dash.Title, usr.NameOrFallback()),
+				"userId":  usr.ID,
+			})
+		}
+	}
+
+	hasAccess, err := hs.AccessControl.Evaluate(c.Req.Context(), c.SignedInUser, ac.EvalPermission(ac.ActionOrgUsersAdd))
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to evaluate permissions", err)
+	}
+	if !hasAccess {
+		return response.Error(http.StatusForbidden, "Permission denied: not permitted to invite people to this organisation", nil)
+	}
+
+	invite, created, rsp := hs.getOrCreateShareInvite(c, &form, usr)
+	if rsp != nil {
+		return rsp
+	}
+
+	grantCmd := models.AddTempUserGrantCommand{
+		OrgID:        c.OrgID,
+		TempUserID:   invite.Id,
+		ResourceKind: form.ResourceKind,
+		ResourceUID:  dash.Uid,
+		Permission:   form.Permission,
+	}
+	if err := hs.tempUserService.AddTempUserGrant(c.Req.Context(), &grantCmd); err != nil {
+		if errors.Is(err, models.ErrTempUserInvalidTransition) {
+			return response.Error(http.StatusConflict, "The invite is not pending anymore", err)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to save invite permission", err)
+	}
+
+	if created && form.SendEmail {
+		if usr != nil {
+			_, rsp = hs.sendExistingUserInviteEmail(c, usr, invite.Code)
+		} else {
+			_, rsp = hs.sendNewUserInviteEmail(c, invite.Email, invite.Name, invite.Code)
+		}
+		if rsp != nil {
+			return rsp
+		}
+	}
+
+	return response.JSON(http.StatusOK, util.DynMap{
+		"message":  fmt.Sprintf("Invited %s to access %s", form.Email, dash.Title),
+		"inviteId": invite.Id,
+	})
+}
+
+// getOrCreateShareInvite returns the pending invite of the email, which is created with the viewer
+// role if there is none. It returns true if the invite was created.
+func (hs *HTTPServer) getOrCreateShareInvite(c *models.ReqContext, form *dtos.ShareInviteForm, usr *user.User) (*models.TempUserDTO, bool, response.Response) {
+	pendingQuery := models.GetTempUsersQuery{OrgId: c.OrgID, Email: form.Email, Status: models.TmpUserInvitePending}
+	if err := hs.tempUserService.GetTempUsersQuery(c.Req.Context(), &pendingQuery); err != nil {
+		return nil, false, response.Error(http.StatusInternalServerError, "Failed to get invites from db", err)
+	}
+	if len(pendingQuery.Result) > 0 {
+		return pendingQuery.Result[0], false, nil
+	}
+
+	if usr == nil {
+		if err := hs.invitePolicy(c.Req.Context(), c.OrgID, form.Email).canInviteNewUsers(); err != nil {
+			return nil, false, response.Error(http.StatusBadRequest, err.Error(), nil)
+		}
+	}
+	limitReached, err := hs.QuotaService.QuotaReached(c, "user")
+	if err != nil {
+		return nil, false, response.Error(http.StatusInternalServerError, "failed to get quota", err)
+	}
+	if limitReached {
+		return nil, false, response.Error(http.StatusForbidden, "Quota reached", nil)
+	}
+
+	name := form.Name
+	if usr != nil {
+		name = usr.Name
+	}
+	limit, rsp := hs.checkInviteRateLimit(c.Req.Context(), c.OrgID, inviteRateLimitInviter(c), 1)
+	if rsp != nil {
+		return nil, false, rsp
+	}
+	cmd, rsp := hs.createNewUserInvite(c, &dtos.AddInviteForm{
+		LoginOrEmail: form.Email,
+		Name:         name,
+		Role:         org.RoleViewer,
+		Delivery:     models.InviteDeliveryEmail,
+		EmailMatch:   models.InviteEmailMatchAny,
+	})
+	if rsp != nil {
+		return nil, false, rsp
+	}
+	hs.countInvites(c.Req.Context(), limit, 1)
+
+	invite := &models.TempUserDTO{
+		Id:       cmd.Result.Id,
+		OrgId:    cmd.Result.OrgId,
+		Name:     cmd.Result.Name,
+		Email:    cmd.Result.Email,
+		Role:     cmd.Result.Role,
+		Code:     cmd.Result.Code,
+		Status:   cmd.Result.Status,
+		Delivery: cmd.Result.Delivery,
+	}
+	return invite, true, nil
+}
+
+// getSharedResource returns the dashboard or folder to share, which the signed in user must be an
+// admin of.
+func (hs *HTTPServer) getSharedResource(c *models.ReqContext, kind string, uid string) (*models.Dashboard, response.Response) {
+	dash, rsp := hs.getDashboardHelper(c.Req.Context(), c.OrgID, 0, uid)
+	if rsp != nil {
+		return nil, rsp
+	}
+	if kind == models.TempUserGrantFolder && !dash.IsFolder {
+		return nil, response.Error(http.StatusNotFound, "Folder not found", nil)
+	}
+	if kind == models.TempUserGrantDashboard && dash.IsFolder {
+		return nil, response.Error(http.StatusNotFound, "Dashboard not found", nil)
+	}
+	g := guardian.New(c.Req.Context(), dash.Id, c.OrgID, c.SignedInUser)
+	if canAdmin, err := g.CanAdmin(); err != nil || !canAdmin {
+		return nil, dashboardGuardianResponse(err)
+	}
+	return dash, nil
+}
+
+func (hs *HTTPServer) isOrgMember(ctx context.Context, orgID, userID int64) (bool, error) {
+	orgsQuery := models.GetUserOrgListQuery{UserId: userID}
+	if err := hs.SQLStore.GetUserOrgList(ctx, &orgsQuery); err != nil {
+		return false, err
+	}
+	for _, userOrg := range orgsQuery.Result {
+		if userOrg.OrgId == orgID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// grantResourcePermission gives a user the View or Edit permission on a dashboard or folder,
+// keeping the other permissions of the resource.
+func (hs *HTTPServer) grantResourcePermission(ctx context.Context, orgID, userID int64, kind string, dash *models.Dashboard, permission string) error {
+	if !hs.AccessControl.IsDisabled() {
+		var err error
+		if kind == models.TempUserGrantFolder {
+			_, err = hs.folderPermissionsService.SetUserPermission(ctx, orgID, ac.User{ID: userID}, dash.Uid, permission)
+		} else {
+			_, err = hs.dashboardPermissionsService.SetUserPermission(ctx, orgID, ac.User{ID: userID}, dash.Uid, permission)
+		}
+		return err
+	}
+
+	query := models.GetDashboardACLInfoListQuery{DashboardID: dash.Id, OrgID: orgID}
+	if err := hs.DashboardService.GetDashboardACLInfoList(ctx, &query); err != nil {
+		return err
+	}
+	permissionType := models.PERMISSION_VIEW
+	if permission == models.PERMISSION_EDIT.String() {
+		permissionType = models.PERMISSION_EDIT
+	}
+
+	now := time.Now()
+	items := make([]*models.DashboardACL, 0, len(query.Result)+1)
+	for _, item := range query.Result {
+		if item.Inherited || item.UserId == userID {
+			continue
+		}
+		items = append(items, &models.DashboardACL{
+			OrgID:       orgID,
+			DashboardID: dash.Id,
+			UserID:      item.UserId,
+			TeamID:      item.TeamId,
+			Role:        item.Role,
+			Permission:  item.Permission,
+			Created:     item.Created,
+			Updated:     item.Updated,
+		})
+	}
+	items = append(items, &models.DashboardACL{
+		OrgID:       orgID,
+		DashboardID: dash.Id,
+		UserID:      userID,
+		Permission:  permissionType,
+		Created:     now,
+		Updated:     now,
+	})
+	return hs.DashboardService.UpdateDashboardACL(ctx, dash.Id, items)
+}
+
+// applyInviteGrants gives the user who accepted an invite the permissions the invite was sent with.
+// Dashboards and folders deleted since are skipped.
+func (hs *HTTPServer) applyInviteGrants(ctx context.Context, usr *user.User, invite *models.TempUserDTO) {
+	logger := hs.log.FromContext(ctx)
+	query := models.GetTempUserGrantsQuery{TempUserID: invite.Id}
+	if err := hs.tempUserService.GetTempUserGrants(ctx, &query); err != nil {
+		logger.Warn("Failed to get the permissions of the invite", "inviteId", invite.Id, "error", err)
+		return
+	}
+
+	for _, grant := range query.Result {
+		// the other organizations of the invite are joined with its organization
+		if grant.ResourceKind == models.TempUserGrantOrg {
+			continue
+		}
+		if grant.ResourceKind == models.TempUserGrantTeam {
+			if err := hs.joinInviteTeam(ctx, invite.OrgId, usr.ID, grant); err != nil {
+				logger.Warn("Failed to add the invitee to the invite team", "inviteId", invite.Id, "teamId", grant.ResourceUid, "error", err)
+			}
+			continue
+		}
+		dash, rsp := hs.getDashboardHelper(ctx, invite.OrgId, 0, grant.ResourceUid)
+		if rsp != nil {
+			logger.Warn("Skipping invite permission on a missing resource", "inviteId", invite.Id, "kind", grant.ResourceKind, "uid", grant.ResourceUid)
+			continue
+		}
+		if err := hs.grantResourcePermission(ctx, invite.OrgId, usr.ID, grant.ResourceKind, dash, grant.Permission); err != nil {
+			logger.Warn("Failed to apply invite permission", "inviteId", invite.Id, "kind", grant.ResourceKind, "uid", grant.ResourceUid, "error", err)
+		}
+	}
+}
+
+// swagger:parameters shareResourceByEmail
+type ShareResourceByEmailParams struct {
+	// in:body
+	// required:true
+	Body dtos.ShareInviteForm `json:"body"`
+}
+
+// swagger:response shareResourceByEmailResponse
+type ShareResourceByEmailResponse struct {
+	// in:body
+	Body struct {
+		Message string `json:"message"`
+		// UserID is set when the resource was shared with a member of the organization
+		UserID int64 `json:"userId,omitempty"`
+		// InviteID is set when the person was invited
+		InviteID int64 `json:"inviteId,omitempty"`
+	} `json:"body"`
+}
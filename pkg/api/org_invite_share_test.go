@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	accesscontrolmock "github.com/grafana/grafana/pkg/services/accesscontrol/mock"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/guardian"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/services/user/usertest"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestShareResourceByEmail(t *testing.T) {
+	type shareResponse struct {
+		UserID   int64 `json:"userId"`
+		InviteID int64 `json:"inviteId"`
+	}
+
+	setup := func(t *testing.T, perms ...accesscontrol.Permission) (accessControlScenarioContext, *accesscontrolmock.MockPermissionsService) {
+		cfg := setting.NewCfg()
+		cfg.RBACEnabled = true
+		sc, _ := setupInviteHTTPServerWithCfg(t, cfg)
+		sc.hs.userService = &slackInviteUserService{&usertest.FakeUserService{}}
+
+		dashSvc := dashboards.NewFakeDashboardService(t)
+		dashSvc.On("GetDashboard", mock.Anything, mock.AnythingOfType("*models.GetDashboardQuery")).Run(func(args mock.Arguments) {
+			q := args.Get(1).(*models.GetDashboardQuery)
+			q.Result = &models.Dashboard{Id: 1, Uid: "dash", OrgId: 1, Title: "Dash"}
+		}).Return(nil).Maybe()
+		sc.hs.DashboardService = dashSvc
+
+		dashboardPermissions := accesscontrolmock.NewMockedPermissionsService()
+		sc.hs.dashboardPermissionsService = dashboardPermissions
+
+		origNewGuardian := guardian.New
+		t.Cleanup(func() { guardian.New = origNewGuardian })
+		guardian.MockDashboardGuardian(&guardian.FakeDashboardGuardian{CanAdminValue: true})
+
+		setInitCtxSignedInOrgAdmin(sc.initCtx)
+		perms = append(perms, accesscontrol.Permission{Action: dashboards.ActionDashboardsPermissionsWrite, Scope: dashboards.ScopeDashboardsAll})
+		setAccessControlPermissions(sc.acmock, perms, sc.initCtx.OrgID)
+		return sc, dashboardPermissions
+	}
+
+	share := func(t *testing.T, sc accessControlScenarioContext, body string) (int, shareResponse) {
+		response := callAPI(sc.server, http.MethodPost, "/api/org/invites/share", strings.NewReader(body), t)
+		var rsp shareResponse
+		if response.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(response.Body.Bytes(), &rsp))
+		}
+		return response.Code, rsp
+	}
+
+	t.Run("grants the permission to members right away", func(t *testing.T) {
+		sc, dashboardPermissions := setup(t)
+		member, err := sc.db.CreateUser(context.Background(), user.CreateUserCommand{Email: "member@example.com", Login: "member"})
+		require.NoError(t, err)
+		sc.hs.userService = &usertest.FakeUserService{ExpectedUser: member}
+		dashboardPermissions.On("SetUserPermission", mock.Anything, int64(1), accesscontrol.User{ID: member.ID}, "dash", "Edit").
+			Return(&accesscontrol.ResourcePermission{}, nil).Once()
+
+		code, rsp := share(t, sc, `{"email": "member@example.com", "resourceKind": "dashboard", "resourceUid": "dash", "permission": "Edit"}`)
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, member.ID, rsp.UserID)
+		dashboardPermissions.AssertExpectations(t)
+	})
+
+	t.Run("invites other people and grants the permission on acceptance", func(t *testing.T) {
+		sc, dashboardPermissions := setup(t, accesscontrol.Permission{Action: accesscontrol.ActionOrgUsersAdd})
+
+		code, rsp := share(t, sc, `{"email": "external@example.com", "resourceKind": "dashboard", "resourceUid": "dash"}`)
+		require.Equal(t, http.StatusOK, code)
+		require.NotZero(t, rsp.InviteID)
+
+		// sharing again adds to the pending invite
+		code, again := share(t, sc, `{"email": "external@example.com", "resourceKind": "dashboard", "resourceUid": "dash", "permission": "Edit"}`)
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, rsp.InviteID, again.InviteID)
+
+		grants := models.GetTempUserGrantsQuery{TempUserID: rsp.InviteID}
+		require.NoError(t, sc.hs.tempUserService.GetTempUserGrants(context.Background(), &grants))
+		require.Len(t, grants.Result, 1)
+		assert.Equal(t, "Edit", grants.Result[0].Permission)
+
+		invite := models.GetTempUserByIDQuery{OrgID: 1, ID: rsp.InviteID}
+		require.NoError(t, sc.hs.tempUserService.GetTempUserByID(context.Background(), &invite))
+		assert.Equal(t, models.TmpUserInvitePending, invite.Result.Status)
+
+		dashboardPermissions.On("SetUserPermission", mock.Anything, int64(1), accesscontrol.User{ID: 42}, "dash", "Edit").
+			Return(&accesscontrol.ResourcePermission{}, nil).Once()
+		sc.hs.applyInviteGrants(context.Background(), &user.User{ID: 42}, invite.Result)
+		dashboardPermissions.AssertExpectations(t)
+	})
+
+	t.Run("requires the permission to invite for non-members", func(t *testing.T) {
+		sc, _ := setup(t)
+
+		code, _ := share(t, sc, `{"email": "external@example.com", "resourceKind": "dashboard", "resourceUid": "dash"}`)
+		assert.Equal(t, http.StatusForbidden, code)
+	})
+
+	t.Run("validates the request", func(t *testing.T) {
+		sc, _ := setup(t, accesscontrol.Permission{Action: accesscontrol.ActionOrgUsersAdd})
+
+		code, _ := share(t, sc, `{"email": "external@example.com", "resourceKind": "folder", "resourceUid": "dash"}`)
+		assert.Equal(t, http.StatusNotFound, code)
+		code, _ = share(t, sc, `{"email": "external@example.com", "resourceKind": "dashboard", "resourceUid": "dash", "permission": "Admin"}`)
+		assert.Equal(t, http.StatusBadRequest, code)
+		code, _ = share(t, sc, `{"email": "external", "resourceKind": "dashboard", "resourceUid": "dash"}`)
+		assert.Equal(t, http.StatusBadRequest, code)
+	})
+}
@@ -1,16 +1,39 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/accesscontrol/actest"
+	"github.com/grafana/grafana/pkg/services/login/loginservice"
+	"github.com/grafana/grafana/pkg/services/notifications"
+	"github.com/grafana/grafana/pkg/services/onboarding"
+	"github.com/grafana/grafana/pkg/services/onboarding/onboardingtest"
 	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/temp_user/invitetest"
 	"github.com/grafana/grafana/pkg/services/user"
 	"github.com/grafana/grafana/pkg/services/user/usertest"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/util/proxyutil"
+	"github.com/grafana/grafana/pkg/web"
 )
 
 func TestOrgInvitesAPIEndpointAccess(t *testing.T) {
@@ -67,7 +90,7 @@ func TestOrgInvitesAPIEndpointAccess(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.desc, func(t *testing.T) {
-			sc := setupHTTPServer(t, true)
+			sc, _ := setupInviteHTTPServer(t)
 			userService := usertest.NewUserServiceFake()
 			userService.ExpectedUser = &user.User{ID: 2}
 			sc.hs.userService = userService
@@ -81,3 +104,756 @@ func TestOrgInvitesAPIEndpointAccess(t *testing.T) {
 		})
 	}
 }
+
+func TestGetPendingOrgInvitesScopes(t *testing.T) {
+	setup := func(t *testing.T, permissions []accesscontrol.Permission) accessControlScenarioContext {
+		sc, stack := setupInviteHTTPServer(t)
+		setInitCtxSignedInViewer(sc.initCtx)
+		setAccessControlPermissions(sc.acmock, permissions, sc.initCtx.OrgID)
+
+		for i, invitedBy := range []int64{testUserID, testUserID + 1} {
+			stack.Invite(fmt.Sprintf("invitee%d@example.com", i)).WithCode(fmt.Sprintf("invite-code-%d", i)).InvitedBy(invitedBy).Create(t)
+		}
+		return sc
+	}
+
+	listInvites := func(t *testing.T, sc accessControlScenarioContext) []string {
+		response := callAPI(sc.server, http.MethodGet, "/api/org/invites", nil, t)
+		require.Equal(t, http.StatusOK, response.Code)
+		var invites []models.TempUserDTO
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &invites))
+		emails := make([]string, 0, len(invites))
+		for _, invite := range invites {
+			emails = append(emails, invite.Email)
+		}
+		return emails
+	}
+
+	t.Run("lists all invites with the org-wide scope", func(t *testing.T) {
+		sc := setup(t, []accesscontrol.Permission{{Action: accesscontrol.ActionOrgInvitesRead, Scope: accesscontrol.ScopeInvitesAll}})
+		assert.ElementsMatch(t, []string{"invitee0@example.com", "invitee1@example.com"}, listInvites(t, sc))
+	})
+
+	t.Run("lists only the invites created by the user with the self scope", func(t *testing.T) {
+		sc := setup(t, []accesscontrol.Permission{{Action: accesscontrol.ActionOrgInvitesRead, Scope: accesscontrol.ScopeInvitesSelf}})
+		assert.Equal(t, []string{"invitee0@example.com"}, listInvites(t, sc))
+	})
+
+	t.Run("forbids listing invites with another scope", func(t *testing.T) {
+		sc := setup(t, []accesscontrol.Permission{{Action: accesscontrol.ActionOrgInvitesRead, Scope: "invites:id:1"}})
+		response := callAPI(sc.server, http.MethodGet, "/api/org/invites", nil, t)
+		assert.Equal(t, http.StatusForbidden, response.Code)
+	})
+
+	t.Run("forbids listing invites without permission", func(t *testing.T) {
+		sc := setup(t, []accesscontrol.Permission{{Action: accesscontrol.ActionOrgUsersAdd, Scope: accesscontrol.ScopeUsersAll}})
+		response := callAPI(sc.server, http.MethodGet, "/api/org/invites", nil, t)
+		assert.Equal(t, http.StatusForbidden, response.Code)
+	})
+}
+
+// usersByIDService returns the users it holds by ID.
+type usersByIDService struct {
+	*usertest.FakeUserService
+	users map[int64]*user.User
+}
+
+func (s *usersByIDService) GetByID(ctx context.Context, query *user.GetUserByIDQuery) (*user.User, error) {
+	if usr, ok := s.users[query.ID]; ok {
+		return usr, nil
+	}
+	return nil, user.ErrUserNotFound
+}
+
+// verifiedUser returns the user of signedInUser, who verified owning their email.
+func verifiedUser(signedInUser user.SignedInUser) *user.User {
+	return &user.User{
+		ID:            signedInUser.UserID,
+		Login:         signedInUser.Login,
+		Email:         signedInUser.Email,
+		Name:          signedInUser.Name,
+		EmailVerified: true,
+	}
+}
+
+func TestSignedInUserOrgInvitesAPIEndpoints(t *testing.T) {
+	setup := func(t *testing.T) (accessControlScenarioContext, *usersByIDService, *models.TempUserDTO) {
+		sc, stack := setupInviteHTTPServer(t)
+		userService := &usersByIDService{&usertest.FakeUserService{}, map[int64]*user.User{
+			testAdminOrg2.UserID:  verifiedUser(testAdminOrg2),
+			testEditorOrg1.UserID: verifiedUser(testEditorOrg1),
+		}}
+		sc.hs.userService = userService
+		setupOrgUsersDBForAccessControlTests(t, sc.db)
+		setInitCtxSignedInUser(sc.initCtx, testAdminOrg2)
+
+		invite := stack.Invite(testAdminOrg2.Email).InOrg(testServerAdminViewer.OrgID).WithRole(org.RoleEditor).WithCode("invite-code").Create(t)
+		return sc, userService, invite
+	}
+
+	inviteURL := func(invite *models.TempUserDTO, action string) string {
+		return fmt.Sprintf("/api/user/org-invites/%d/%s", invite.Id, action)
+	}
+
+	listInvites := func(t *testing.T, sc accessControlScenarioContext) []map[string]interface{} {
+		response := callAPI(sc.server, http.MethodGet, "/api/user/org-invites", nil, t)
+		require.Equal(t, http.StatusOK, response.Code)
+		var invites []map[string]interface{}
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &invites))
+		return invites
+	}
+
+	t.Run("user can list invites targeting them, without their code", func(t *testing.T) {
+		sc, _, invite := setup(t)
+
+		invites := listInvites(t, sc)
+		require.Len(t, invites, 1)
+		assert.EqualValues(t, invite.Id, invites[0]["id"])
+		assert.Equal(t, testServerAdminViewer.OrgName, invites[0]["orgName"])
+		assert.NotContains(t, invites[0], "code")
+		assert.NotContains(t, invites[0], "url")
+	})
+
+	t.Run("user can accept an invite targeting them", func(t *testing.T) {
+		sc, _, invite := setup(t)
+
+		response := callAPI(sc.server, http.MethodPost, inviteURL(invite, "accept"), nil, t)
+		require.Equal(t, http.StatusOK, response.Code)
+
+		query := models.GetUserOrgListQuery{UserId: testAdminOrg2.UserID}
+		require.NoError(t, sc.db.GetUserOrgList(context.Background(), &query))
+		orgIDs := make([]int64, 0, len(query.Result))
+		for _, userOrg := range query.Result {
+			orgIDs = append(orgIDs, userOrg.OrgId)
+		}
+		assert.Contains(t, orgIDs, testServerAdminViewer.OrgID)
+
+		events := sc.hs.onboardingService.(*onboardingtest.FakeService).RecordedEvents
+		require.Len(t, events, 1)
+		assert.Equal(t, onboarding.EventInviteAccepted, events[0].Type)
+		assert.Equal(t, testServerAdminViewer.OrgID, events[0].OrgID)
+		assert.Equal(t, testAdminOrg2.UserID, events[0].UserID)
+
+		response = callAPI(sc.server, http.MethodPost, inviteURL(invite, "accept"), nil, t)
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+
+	t.Run("user can decline an invite targeting them", func(t *testing.T) {
+		sc, _, invite := setup(t)
+
+		response := callAPI(sc.server, http.MethodPost, inviteURL(invite, "decline"), nil, t)
+		require.Equal(t, http.StatusOK, response.Code)
+
+		query := models.GetTempUserByCodeQuery{Code: invite.Code}
+		require.NoError(t, sc.hs.tempUserService.GetTempUserByCode(context.Background(), &query))
+		assert.Equal(t, models.TmpUserDeclined, query.Result.Status)
+	})
+
+	t.Run("user cannot accept an invite targeting somebody else", func(t *testing.T) {
+		sc, _, invite := setup(t)
+		setInitCtxSignedInUser(sc.initCtx, testEditorOrg1)
+
+		response := callAPI(sc.server, http.MethodPost, inviteURL(invite, "accept"), nil, t)
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+
+	t.Run("user whose login is the email of somebody else cannot see or accept their invite", func(t *testing.T) {
+		sc, _, invite := setup(t)
+		impostor := testEditorOrg1
+		impostor.Login = testAdminOrg2.Email
+		setInitCtxSignedInUser(sc.initCtx, impostor)
+
+		assert.Empty(t, listInvites(t, sc))
+		response := callAPI(sc.server, http.MethodPost, inviteURL(invite, "accept"), nil, t)
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+
+	t.Run("user with an unverified email cannot see or accept invites", func(t *testing.T) {
+		sc, userService, invite := setup(t)
+		// e.g. after changing their email to the invited one
+		userService.users[testAdminOrg2.UserID].EmailVerified = false
+
+		assert.Empty(t, listInvites(t, sc))
+		response := callAPI(sc.server, http.MethodPost, inviteURL(invite, "accept"), nil, t)
+		assert.Equal(t, http.StatusNotFound, response.Code)
+		response = callAPI(sc.server, http.MethodPost, inviteURL(invite, "decline"), nil, t)
+		assert.Equal(t, http.StatusNotFound, response.Code)
+
+		userService.users[testAdminOrg2.UserID].EmailVerified = true
+		assert.Len(t, listInvites(t, sc), 1)
+	})
+}
+
+func TestAddOrgInviteIdempotencyKey(t *testing.T) {
+	setup := func(t *testing.T) accessControlScenarioContext {
+		sc, _ := setupInviteHTTPServer(t)
+		sc.hs.RemoteCacheService = remotecache.NewFakeStore(t)
+		setInitCtxSignedInViewer(sc.initCtx)
+		setAccessControlPermissions(sc.acmock, []accesscontrol.Permission{{Action: accesscontrol.ActionOrgUsersAdd, Scope: accesscontrol.ScopeUsersAll}}, sc.initCtx.OrgID)
+		return sc
+	}
+
+	invite := func(sc accessControlScenarioContext, key string, loginOrEmail string) *httptest.ResponseRecorder {
+		body := `{"loginOrEmail": "` + loginOrEmail + `", "role": "` + string(org.RoleViewer) + `"}`
+		req, err := http.NewRequest(http.MethodPost, "/api/org/invites", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+			// clients retry with the correlation ID of the original request
+			req.Header.Set(inviteCorrelationIDHeader, key)
+		}
+		recorder := httptest.NewRecorder()
+		sc.server.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	pendingInvites := func(sc accessControlScenarioContext) int {
+		query := models.GetTempUsersQuery{OrgId: sc.initCtx.OrgID, Status: models.TmpUserInvitePending}
+		require.NoError(t, sc.hs.tempUserService.GetTempUsersQuery(context.Background(), &query))
+		return len(query.Result)
+	}
+
+	t.Run("retried request returns the original response", func(t *testing.T) {
+		sc := setup(t)
+
+		first := invite(sc, "key-1", "new user")
+		require.Equal(t, http.StatusOK, first.Code)
+		assert.Empty(t, first.Header().Get("Idempotent-Replayed"))
+
+		replay := invite(sc, "key-1", "new user")
+		require.Equal(t, http.StatusOK, replay.Code)
+		assert.Equal(t, "true", replay.Header().Get("Idempotent-Replayed"))
+		assert.JSONEq(t, first.Body.String(), replay.Body.String())
+
+		assert.Equal(t, 1, pendingInvites(sc))
+	})
+
+	t.Run("key reused for a different request is rejected", func(t *testing.T) {
+		sc := setup(t)
+
+		require.Equal(t, http.StatusOK, invite(sc, "key-1", "new user").Code)
+		assert.Equal(t, http.StatusUnprocessableEntity, invite(sc, "key-1", "other user").Code)
+		assert.Equal(t, 1, pendingInvites(sc))
+	})
+
+	t.Run("API keys don't share keys", func(t *testing.T) {
+		sc := setup(t)
+		sc.initCtx.SignedInUser.UserID = 0
+		sc.initCtx.SignedInUser.ApiKeyID = 1
+		require.Equal(t, http.StatusOK, invite(sc, "key-1", "new user").Code)
+
+		sc.initCtx.SignedInUser.ApiKeyID = 2
+		other := invite(sc, "key-1", "other user")
+		require.Equal(t, http.StatusOK, other.Code)
+		assert.Empty(t, other.Header().Get("Idempotent-Replayed"))
+		assert.Equal(t, 2, pendingInvites(sc))
+	})
+
+	t.Run("key in use by a request in progress is rejected", func(t *testing.T) {
+		sc := setup(t)
+		// another instance is handling the request
+		cacheKey := idempotencyCacheKey(sc.initCtx, "org-invite", "key-1")
+		require.NoError(t, sc.hs.RemoteCacheService.Set(context.Background(), cacheKey, idempotentResponse{}, idempotencyInFlightTTL))
+
+		assert.Equal(t, http.StatusConflict, invite(sc, "key-1", "new user").Code)
+		assert.Equal(t, 0, pendingInvites(sc))
+
+		require.NoError(t, sc.hs.RemoteCacheService.Delete(context.Background(), cacheKey))
+		assert.Equal(t, http.StatusOK, invite(sc, "key-1", "new user").Code)
+	})
+
+	t.Run("large requests with a key are rejected", func(t *testing.T) {
+		sc := setup(t)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, invite(sc, "key-1", strings.Repeat("a", idempotencyMaxBodySize)).Code)
+		assert.Equal(t, 0, pendingInvites(sc))
+	})
+
+	t.Run("requests without a key are not replayed", func(t *testing.T) {
+		sc := setup(t)
+
+		require.Equal(t, http.StatusOK, invite(sc, "", "new user").Code)
+
+		retry := invite(sc, "", "new user")
+		assert.Equal(t, http.StatusPreconditionFailed, retry.Code)
+		assert.Empty(t, retry.Header().Get("Idempotent-Replayed"))
+		assert.Equal(t, 1, pendingInvites(sc))
+	})
+}
+
+func TestAddOrgInviteManualDelivery(t *testing.T) {
+	setup := func(t *testing.T) accessControlScenarioContext {
+		sc, _ := setupInviteHTTPServer(t)
+		sc.hs.userService = &slackInviteUserService{&usertest.FakeUserService{}}
+		setInitCtxSignedInOrgAdmin(sc.initCtx)
+		setAccessControlPermissions(sc.acmock, []accesscontrol.Permission{
+			{Action: accesscontrol.ActionOrgUsersAdd},
+			{Action: accesscontrol.ActionOrgInvitesRead, Scope: accesscontrol.ScopeInvitesAll},
+		}, sc.initCtx.OrgID)
+		return sc
+	}
+
+	t.Run("returns the link of the invite", func(t *testing.T) {
+		sc := setup(t)
+
+		response := callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(`{"loginOrEmail": "new@example.com", "role": "Viewer", "delivery": "manual"}`), t)
+		require.Equal(t, http.StatusOK, response.Code)
+
+		var link dtos.InviteLink
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &link))
+		assert.Equal(t, "new@example.com", link.Email)
+		assert.Equal(t, setting.ToAbsUrl("invite/"+link.Code), link.URL)
+
+		response = callAPI(sc.server, http.MethodGet, "/api/org/invites?delivery=manual", nil, t)
+		require.Equal(t, http.StatusOK, response.Code)
+		var invites []*models.TempUserDTO
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &invites))
+		require.Len(t, invites, 1)
+		assert.Equal(t, link.Code, invites[0].Code)
+		assert.Equal(t, models.InviteDeliveryManual, invites[0].Delivery)
+
+		response = callAPI(sc.server, http.MethodGet, "/api/org/invites?delivery=email", nil, t)
+		require.Equal(t, http.StatusOK, response.Code)
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &invites))
+		assert.Empty(t, invites)
+	})
+
+	t.Run("rejects emailing manually delivered invites", func(t *testing.T) {
+		sc := setup(t)
+
+		response := callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(`{"loginOrEmail": "new@example.com", "role": "Viewer", "delivery": "manual", "sendEmail": true}`), t)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+
+		response = callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(`{"loginOrEmail": "new@example.com", "role": "Viewer", "delivery": "fax"}`), t)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+}
+
+func TestAddOrgInviteRoleRestrictions(t *testing.T) {
+	sc, _ := setupInviteHTTPServer(t)
+	sc.hs.userService = &slackInviteUserService{&usertest.FakeUserService{}}
+	sc.hs.roleRestrictions = actest.FakeRoleRestrictionService{ExpectedRoles: []org.RoleType{org.RoleViewer}}
+	setInitCtxSignedInOrgAdmin(sc.initCtx)
+	setAccessControlPermissions(sc.acmock, []accesscontrol.Permission{{Action: accesscontrol.ActionOrgUsersAdd}}, sc.initCtx.OrgID)
+
+	response := callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(`{"loginOrEmail": "new@example.com", "role": "Editor"}`), t)
+	require.Equal(t, http.StatusBadRequest, response.Code)
+	var body struct {
+		AllowedRoles []org.RoleType `json:"allowedRoles"`
+	}
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &body))
+	assert.Equal(t, []org.RoleType{org.RoleViewer}, body.AllowedRoles)
+
+	response = callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(`{"loginOrEmail": "new@example.com", "role": "Viewer"}`), t)
+	assert.Equal(t, http.StatusOK, response.Code)
+}
+
+func TestAddOrgInviteMaxRoles(t *testing.T) {
+	sc, _ := setupInviteHTTPServer(t)
+	sc.hs.userService = &slackInviteUserService{&usertest.FakeUserService{}}
+	sc.hs.Cfg.InviteLinkMaxRole = "Viewer"
+	sc.hs.Cfg.InviteEmailMaxRole = "Editor"
+	setInitCtxSignedInOrgAdmin(sc.initCtx)
+	setAccessControlPermissions(sc.acmock, []accesscontrol.Permission{{Action: accesscontrol.ActionOrgUsersAdd}}, sc.initCtx.OrgID)
+
+	var body struct {
+		Code    string       `json:"code"`
+		MaxRole org.RoleType `json:"maxRole"`
+	}
+	response := callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(`{"loginOrEmail": "link@example.com", "role": "Editor", "delivery": "manual"}`), t)
+	require.Equal(t, http.StatusBadRequest, response.Code)
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &body))
+	assert.Equal(t, "invite.link-role-above-max", body.Code)
+	assert.Equal(t, org.RoleViewer, body.MaxRole)
+
+	response = callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(`{"loginOrEmail": "email@example.com", "role": "Admin"}`), t)
+	require.Equal(t, http.StatusBadRequest, response.Code)
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &body))
+	assert.Equal(t, "invite.email-role-above-max", body.Code)
+	assert.Equal(t, org.RoleEditor, body.MaxRole)
+
+	response = callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(`{"loginOrEmail": "email@example.com", "role": "Editor"}`), t)
+	assert.Equal(t, http.StatusOK, response.Code)
+	response = callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(`{"loginOrEmail": "link@example.com", "role": "Viewer", "delivery": "manual"}`), t)
+	assert.Equal(t, http.StatusOK, response.Code)
+}
+
+func TestAddOrgInviteExistingMember(t *testing.T) {
+	setup := func(t *testing.T, permissions []accesscontrol.Permission) accessControlScenarioContext {
+		sc, _ := setupInviteHTTPServer(t)
+		setInitCtxSignedInOrgAdmin(sc.initCtx)
+		setAccessControlPermissions(sc.acmock, permissions, sc.initCtx.OrgID)
+
+		// the first user creates the organization
+		_, err := sc.db.CreateUser(context.Background(), user.CreateUserCommand{Login: "admin"})
+		require.NoError(t, err)
+		editor, err := sc.db.CreateUser(context.Background(), user.CreateUserCommand{Login: "editor", SkipOrgSetup: true})
+		require.NoError(t, err)
+		require.NoError(t, sc.db.AddOrgUser(context.Background(), &models.AddOrgUserCommand{Role: org.RoleEditor, OrgId: 1, UserId: editor.ID}))
+		sc.hs.userService = &validateInvitesUserService{&usertest.FakeUserService{}, map[string]*user.User{"editor": editor}}
+		return sc
+	}
+	roleOf := func(t *testing.T, sc accessControlScenarioContext) org.RoleType {
+		query := models.GetOrgUsersQuery{OrgId: 1, Query: "editor", DontEnforceAccessControl: true}
+		require.NoError(t, sc.db.GetOrgUsers(context.Background(), &query))
+		require.Len(t, query.Result, 1)
+		return org.RoleType(query.Result[0].Role)
+	}
+	invite := func(t *testing.T, sc accessControlScenarioContext, body string) (int, map[string]interface{}) {
+		response := callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(body), t)
+		var result map[string]interface{}
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &result))
+		return response.Code, result
+	}
+	canAddAndWrite := []accesscontrol.Permission{
+		{Action: accesscontrol.ActionOrgUsersAdd, Scope: accesscontrol.ScopeUsersAll},
+		{Action: accesscontrol.ActionOrgUsersWrite, Scope: accesscontrol.ScopeUsersAll},
+	}
+
+	t.Run("members are not invited again", func(t *testing.T) {
+		sc := setup(t, canAddAndWrite)
+		for _, role := range []org.RoleType{org.RoleEditor, org.RoleAdmin} {
+			code, result := invite(t, sc, `{"loginOrEmail": "editor", "role": "`+string(role)+`", "allowDowngrade": true}`)
+			assert.Equal(t, http.StatusPreconditionFailed, code)
+			assert.Equal(t, "User editor is already added to organization", result["message"])
+			assert.Equal(t, string(org.RoleEditor), result["currentRole"])
+		}
+		assert.Equal(t, org.RoleEditor, roleOf(t, sc))
+	})
+
+	t.Run("downgrades must be allowed explicitly", func(t *testing.T) {
+		sc := setup(t, canAddAndWrite)
+		code, result := invite(t, sc, `{"loginOrEmail": "editor", "role": "Viewer"}`)
+		assert.Equal(t, http.StatusPreconditionFailed, code)
+		assert.Equal(t, string(org.RoleEditor), result["currentRole"])
+		assert.Equal(t, org.RoleEditor, roleOf(t, sc))
+	})
+
+	t.Run("downgrades need the permission to change roles", func(t *testing.T) {
+		sc := setup(t, canAddAndWrite[:1])
+		code, _ := invite(t, sc, `{"loginOrEmail": "editor", "role": "Viewer", "allowDowngrade": true}`)
+		assert.Equal(t, http.StatusForbidden, code)
+		assert.Equal(t, org.RoleEditor, roleOf(t, sc))
+	})
+
+	t.Run("allowed downgrades change the role", func(t *testing.T) {
+		sc := setup(t, canAddAndWrite)
+		code, result := invite(t, sc, `{"loginOrEmail": "editor", "role": "Viewer", "allowDowngrade": true}`)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, string(org.RoleEditor), result["previousRole"])
+		assert.Equal(t, org.RoleViewer, roleOf(t, sc))
+	})
+}
+
+func TestGetInviteInfoByCodeTracksOpening(t *testing.T) {
+	setup := func(t *testing.T) (accessControlScenarioContext, *invitetest.Stack) {
+		sc, stack := setupInviteHTTPServer(t)
+		// invitees are not signed in
+		sc.initCtx.SignedInUser = &user.SignedInUser{}
+
+		stack.Invite("invitee@example.com").WithCode("invite-code").Create(t)
+		return sc, stack
+	}
+
+	openedOn := func(t *testing.T, stack *invitetest.Stack) *time.Time {
+		return stack.Get(t, "invite-code").OpenedOn
+	}
+
+	t.Run("records when the link is opened", func(t *testing.T) {
+		sc, stack := setup(t)
+
+		response := callAPI(sc.server, http.MethodGet, "/api/user/invite/invite-code", nil, t)
+		require.Equal(t, http.StatusOK, response.Code)
+		assert.NotNil(t, openedOn(t, stack))
+	})
+
+	t.Run("doesn't record in organizations with tracking disabled", func(t *testing.T) {
+		sc, stack := setup(t)
+		sc.hs.Cfg.InviteTrackingDisabledOrgs = map[int64]struct{}{1: {}}
+
+		response := callAPI(sc.server, http.MethodGet, "/api/user/invite/invite-code", nil, t)
+		require.Equal(t, http.StatusOK, response.Code)
+		assert.Nil(t, openedOn(t, stack))
+	})
+}
+
+func TestCompleteInviteEmailMatch(t *testing.T) {
+	setup := func(t *testing.T, match models.InviteEmailMatch) (accessControlScenarioContext, *invitetest.Stack) {
+		sc, stack := setupInviteHTTPServer(t)
+		// invitees are not signed in
+		sc.initCtx.SignedInUser = &user.SignedInUser{}
+
+		stack.Invite("invitee@example.com").WithCode("invite-code").WithEmailMatch(match).Create(t)
+		return sc, stack
+	}
+
+	complete := func(t *testing.T, sc accessControlScenarioContext, email string) *httptest.ResponseRecorder {
+		body := `{"inviteCode": "invite-code", "email": "` + email + `", "username": "invitee", "password": "password"}`
+		return callAPI(sc.server, http.MethodPost, "/api/user/invite/complete", strings.NewReader(body), t)
+	}
+
+	t.Run("exact invites reject other emails", func(t *testing.T) {
+		sc, _ := setup(t, models.InviteEmailMatchExact)
+		assert.Equal(t, http.StatusForbidden, complete(t, sc, "colleague@example.com").Code)
+
+		response := callAPI(sc.server, http.MethodGet, "/api/user/invite/invite-code", nil, t)
+		require.Equal(t, http.StatusOK, response.Code)
+		var info dtos.InviteInfo
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &info))
+		assert.Equal(t, models.InviteEmailMatchExact, info.EmailMatch)
+	})
+
+	t.Run("domain invites reject emails of other domains", func(t *testing.T) {
+		sc, _ := setup(t, models.InviteEmailMatchDomain)
+		assert.Equal(t, http.StatusForbidden, complete(t, sc, "invitee@elsewhere.com").Code)
+	})
+
+	t.Run("invites accept any email by default", func(t *testing.T) {
+		_, stack := setup(t, "")
+		assert.Equal(t, models.InviteEmailMatchAny, stack.Get(t, "invite-code").EmailMatch)
+	})
+}
+
+func TestCompleteInviteExistingUser(t *testing.T) {
+	setup := func(t *testing.T) accessControlScenarioContext {
+		sc, stack := setupInviteHTTPServer(t)
+		sc.hs.Login = loginservice.LoginServiceMock{AlreadyExitingLogin: "invitee", NoExistingOrgId: -1}
+		existing := &user.User{ID: testEditorOrg1.UserID, Email: testEditorOrg1.Email, Login: testEditorOrg1.Login}
+		sc.hs.userService = &usertest.FakeUserService{ExpectedUser: existing}
+		setupOrgUsersDBForAccessControlTests(t, sc.db)
+		// invitees are not signed in
+		sc.initCtx.SignedInUser = &user.SignedInUser{}
+
+		stack.Invite(testEditorOrg1.Email).InOrg(testAdminOrg2.OrgID).WithCode("invite-code").Create(t)
+		return sc
+	}
+
+	complete := func(t *testing.T, sc accessControlScenarioContext) dtos.InviteConflict {
+		t.Helper()
+		body := `{"inviteCode": "invite-code", "email": "` + testEditorOrg1.Email + `", "username": "invitee", "password": "password"}`
+		response := callAPI(sc.server, http.MethodPost, "/api/user/invite/complete", strings.NewReader(body), t)
+		require.Equal(t, http.StatusConflict, response.Code)
+		var conflict dtos.InviteConflict
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &conflict))
+		require.NotEmpty(t, conflict.LinkToken)
+		return conflict
+	}
+
+	link := func(t *testing.T, sc accessControlScenarioContext, token string) *httptest.ResponseRecorder {
+		return callAPI(sc.server, http.MethodPost, "/api/user/org-invites/link", strings.NewReader(`{"linkToken": "`+token+`"}`), t)
+	}
+
+	t.Run("existing user can link the invite after signing in", func(t *testing.T) {
+		sc := setup(t)
+		conflict := complete(t, sc)
+
+		setInitCtxSignedInUser(sc.initCtx, testEditorOrg1)
+		require.Equal(t, http.StatusOK, link(t, sc, conflict.LinkToken).Code)
+
+		query := models.GetUserOrgListQuery{UserId: testEditorOrg1.UserID}
+		require.NoError(t, sc.db.GetUserOrgList(context.Background(), &query))
+		orgIDs := make([]int64, 0, len(query.Result))
+		for _, userOrg := range query.Result {
+			orgIDs = append(orgIDs, userOrg.OrgId)
+		}
+		assert.Contains(t, orgIDs, testAdminOrg2.OrgID)
+
+		assert.Equal(t, http.StatusNotFound, link(t, sc, conflict.LinkToken).Code, "the invite is completed")
+	})
+
+	t.Run("other users can't link the invite", func(t *testing.T) {
+		sc := setup(t)
+		conflict := complete(t, sc)
+
+		setInitCtxSignedInUser(sc.initCtx, testAdminOrg2)
+		assert.Equal(t, http.StatusForbidden, link(t, sc, conflict.LinkToken).Code)
+	})
+
+	t.Run("link tokens are signed and expire", func(t *testing.T) {
+		sc := setup(t)
+		setInitCtxSignedInUser(sc.initCtx, testEditorOrg1)
+
+		forged := fmt.Sprintf("invite-code.%d.%d.%s", testEditorOrg1.UserID, time.Now().Add(time.Hour).Unix(), strings.Repeat("0", 64))
+		assert.Equal(t, http.StatusBadRequest, link(t, sc, forged).Code)
+		expired := sc.hs.createInviteLinkToken("invite-code", testEditorOrg1.UserID, time.Now().Add(-time.Minute))
+		assert.Equal(t, http.StatusBadRequest, link(t, sc, expired).Code)
+	})
+}
+
+func TestAddOrgInviteEmailMatch(t *testing.T) {
+	sc, stack := setupInviteHTTPServer(t)
+	sc.hs.userService = &slackInviteUserService{&usertest.FakeUserService{}}
+	setInitCtxSignedInOrgAdmin(sc.initCtx)
+	setAccessControlPermissions(sc.acmock, []accesscontrol.Permission{{Action: accesscontrol.ActionOrgUsersAdd}}, sc.initCtx.OrgID)
+
+	response := callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(`{"loginOrEmail": "new@example.com", "role": "Viewer", "emailMatch": "fuzzy"}`), t)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+
+	response = callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(`{"loginOrEmail": "new@example.com", "role": "Viewer", "emailMatch": "domain"}`), t)
+	require.Equal(t, http.StatusOK, response.Code)
+
+	pending := stack.Pending(t, sc.initCtx.OrgID)
+	require.Len(t, pending, 1)
+	assert.Equal(t, models.InviteEmailMatchDomain, pending[0].EmailMatch)
+}
+
+func TestAddOrgInviteQueuedEmail(t *testing.T) {
+	sc, _ := setupInviteHTTPServer(t)
+	sc.hs.userService = &slackInviteUserService{&usertest.FakeUserService{}}
+	sc.hs.kvStore = kvstore.ProvideService(sc.db)
+	setInitCtxSignedInOrgAdmin(sc.initCtx)
+	setAccessControlPermissions(sc.acmock, []accesscontrol.Permission{
+		{Action: accesscontrol.ActionOrgUsersAdd},
+		{Action: accesscontrol.ActionOrgInvitesRead, Scope: accesscontrol.ScopeInvitesAll},
+	}, sc.initCtx.OrgID)
+
+	ns := saturatedNotificationService(t)
+	sc.hs.NotificationService = ns
+
+	response := callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(`{"loginOrEmail": "new@example.com", "role": "Viewer", "sendEmail": true}`), t)
+	require.Equal(t, http.StatusAccepted, response.Code)
+	assert.NotEmpty(t, response.Header().Get("Retry-After"))
+
+	var queued dtos.QueuedInvite
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &queued))
+	assert.Equal(t, ns.MailQueueDepth()+1, queued.QueuePosition)
+	assert.True(t, queued.EstimatedDelivery.After(time.Now()))
+
+	url := "/api/v2/org/invites/" + strconv.FormatInt(queued.InviteID, 10) + "/delivery"
+	response = callAPI(sc.server, http.MethodGet, url, nil, t)
+	require.Equal(t, http.StatusOK, response.Code)
+	var delivery dtos.InviteDelivery
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &delivery))
+	assert.Equal(t, "queued", delivery.State)
+	assert.Equal(t, queued.QueuePosition, delivery.QueuePosition)
+	assert.NotEmpty(t, response.Header().Get("Retry-After"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = ns.Run(ctx) }()
+
+	require.Eventually(t, func() bool {
+		response := callAPI(sc.server, http.MethodGet, url, nil, t)
+		var delivery dtos.InviteDelivery
+		return json.Unmarshal(response.Body.Bytes(), &delivery) == nil && delivery.State == "sent" && delivery.SentAt != nil
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+// saturatedNotificationService returns a notification service whose mail queue is full, so that
+// invite emails are queued. The queue isn't drained until the service runs.
+func saturatedNotificationService(t *testing.T) *notifications.NotificationService {
+	t.Helper()
+	cfg := setting.NewCfg()
+	cfg.StaticRootPath = "../../public/"
+	cfg.Smtp.Enabled = true
+	cfg.Smtp.TemplatesPatterns = []string{"emails/*.html", "emails/*.txt"}
+	cfg.Smtp.FromAddress = "from@example.com"
+	ns, err := notifications.ProvideService(bus.ProvideBus(tracing.InitializeTracerForTest()), cfg, notifications.NewFakeMailer(), nil)
+	require.NoError(t, err)
+
+	for !ns.MailQueueSaturated() {
+		require.NoError(t, ns.SendEmailCommandHandler(context.Background(), &models.SendEmailCommand{Subject: "subject", To: []string{"other@example.com"}, Template: "welcome_on_signup"}))
+	}
+	return ns
+}
+
+func TestAddOrgInviteFullMailBacklog(t *testing.T) {
+	sc, stack := setupInviteHTTPServer(t)
+	sc.hs.userService = &slackInviteUserService{&usertest.FakeUserService{}}
+	setInitCtxSignedInOrgAdmin(sc.initCtx)
+	setAccessControlPermissions(sc.acmock, []accesscontrol.Permission{{Action: accesscontrol.ActionOrgUsersAdd}}, sc.initCtx.OrgID)
+
+	ns := saturatedNotificationService(t)
+	sc.hs.NotificationService = ns
+	for i := 0; !ns.MailBacklogFull(); i++ {
+		cmd := &models.SendEmailCommand{Subject: "subject", To: []string{"other@example.com"}, Template: "welcome_on_signup"}
+		_, err := ns.QueueEmail(context.Background(), cmd, strconv.Itoa(i), nil)
+		require.NoError(t, err)
+	}
+
+	response := callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(`{"loginOrEmail": "new@example.com", "role": "Viewer", "sendEmail": true}`), t)
+	require.Equal(t, http.StatusServiceUnavailable, response.Code)
+
+	require.Empty(t, stack.Pending(t, sc.initCtx.OrgID), "the invite isn't saved")
+
+	response = callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(`{"loginOrEmail": "new@example.com", "role": "Viewer"}`), t)
+	require.Equal(t, http.StatusOK, response.Code, "invites without email are created")
+}
+
+func TestRequeueInviteEmails(t *testing.T) {
+	sc, stack := setupInviteHTTPServer(t)
+	sc.hs.userService = &slackInviteUserService{&usertest.FakeUserService{}}
+	sc.hs.kvStore = kvstore.ProvideService(sc.db)
+	setInitCtxSignedInOrgAdmin(sc.initCtx)
+	setAccessControlPermissions(sc.acmock, []accesscontrol.Permission{{Action: accesscontrol.ActionOrgUsersAdd}}, sc.initCtx.OrgID)
+
+	sc.hs.NotificationService = saturatedNotificationService(t)
+	response := callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(`{"loginOrEmail": "queued@example.com", "role": "Viewer", "sendEmail": true}`), t)
+	require.Equal(t, http.StatusAccepted, response.Code)
+	response = callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(`{"loginOrEmail": "unsent@example.com", "role": "Viewer"}`), t)
+	require.Equal(t, http.StatusOK, response.Code)
+
+	// Grafana restarts before the email is sent
+	ns := saturatedNotificationService(t)
+	sc.hs.NotificationService = ns
+	sc.hs.requeueInviteEmails(context.Background())
+
+	codes := map[string]string{}
+	for _, invite := range stack.Pending(t, sc.initCtx.OrgID) {
+		codes[invite.Email] = invite.Code
+	}
+	delivery, ok := ns.EmailDelivery(inviteEmailKey(codes["queued@example.com"]))
+	require.True(t, ok)
+	require.Equal(t, notifications.EmailDeliveryQueued, delivery.State)
+	_, ok = ns.EmailDelivery(inviteEmailKey(codes["unsent@example.com"]))
+	require.False(t, ok, "invites created without email aren't emailed")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = ns.Run(ctx) }()
+	require.Eventually(t, func() bool {
+		query := models.GetTempUsersQuery{Status: models.TmpUserInvitePending, EmailQueued: true}
+		return stack.TempUsers.GetTempUsersQuery(context.Background(), &query) == nil && len(query.Result) == 0
+	}, 5*time.Second, 10*time.Millisecond, "sent emails aren't queued again")
+}
+
+func TestWithInviteTimeout(t *testing.T) {
+	hs := &HTTPServer{Cfg: setting.NewCfg()}
+	hs.Cfg.InviteHandlerTimeout = 10 * time.Millisecond
+
+	// handler waiting on the SMTP server until the request context is done
+	waiting := hs.withInviteTimeout(func(c *models.ReqContext) response.Response {
+		<-c.Req.Context().Done()
+		return response.Error(http.StatusInternalServerError, "Failed to send invite email", c.Req.Context().Err())
+	})
+	call := func(ctx context.Context, handler func(c *models.ReqContext) response.Response) response.Response {
+		req := httptest.NewRequest(http.MethodPost, "/api/org/invites", nil).WithContext(ctx)
+		return handler(&models.ReqContext{Context: &web.Context{Req: req}, SignedInUser: &user.SignedInUser{}})
+	}
+
+	t.Run("requests exceeding the timeout fail with 504", func(t *testing.T) {
+		require.Equal(t, http.StatusGatewayTimeout, call(context.Background(), waiting).Status())
+	})
+
+	t.Run("requests closed by the client fail with 499", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		require.Equal(t, proxyutil.StatusClientClosedRequest, call(ctx, waiting).Status())
+	})
+
+	t.Run("other responses are left as they are", func(t *testing.T) {
+		notFound := hs.withInviteTimeout(func(c *models.ReqContext) response.Response {
+			<-c.Req.Context().Done()
+			return response.Error(http.StatusNotFound, "Invite not found", nil)
+		})
+		require.Equal(t, http.StatusNotFound, call(context.Background(), notFound).Status())
+
+		hs.Cfg.InviteHandlerTimeout = 0
+		ok := hs.withInviteTimeout(func(c *models.ReqContext) response.Response {
+			_, hasDeadline := c.Req.Context().Deadline()
+			require.False(t, hasDeadline)
+			return response.Success("ok")
+		})
+		require.Equal(t, http.StatusOK, call(context.Background(), ok).Status())
+	})
+}
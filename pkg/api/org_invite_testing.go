@@ -0,0 +1,157 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	tempUser "github.com/grafana/grafana/pkg/services/temp_user"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+// errInjectedSMTPFailure is returned instead of sending invite emails failed by the invite testing mode.
+var errInjectedSMTPFailure = errors.New("injected SMTP failure")
+
+// inviteFaults injects the faults of the invite testing mode, see setting.InviteTestingSettings.
+// A nil inviteFaults injects none and generates random invite codes.
+type inviteFaults struct {
+	settings setting.InviteTestingSettings
+	log      log.Logger
+
+	mu   sync.Mutex
+	rand *rand.Rand
+	// number of invite codes generated, for deterministic codes
+	codes int64
+}
+
+// newInviteFaults returns nil when the invite testing mode is disabled.
+func newInviteFaults(settings setting.InviteTestingSettings, logger log.Logger) *inviteFaults {
+	if !settings.Enabled {
+		return nil
+	}
+	seed := settings.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	logger.Warn("Invite testing mode is enabled, faults are injected in the invite paths",
+		"smtpFailureRate", settings.SMTPFailureRate, "dbLatency", settings.DBLatency,
+		"duplicateSubmissionRate", settings.DuplicateSubmissionRate, "deterministicCodes", settings.DeterministicCodes, "seed", seed)
+	return &inviteFaults{
+		settings: settings,
+		log:      logger,
+		rand:     rand.New(rand.NewSource(seed)),
+	}
+}
+
+// hit tells whether a fault injected at the given rate happens.
+func (f *inviteFaults) hit(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rand.Float64() < rate
+}
+
+// newCode returns the code of a new invite.
+func (f *inviteFaults) newCode() (string, error) {
+	if f == nil || !f.settings.DeterministicCodes {
+		return util.GetRandomString(30)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.codes++
+	return fmt.Sprintf("test%026d", f.codes), nil
+}
+
+// smtpFailure returns errInjectedSMTPFailure when the email of an invite should fail.
+func (f *inviteFaults) smtpFailure() error {
+	if f == nil || !f.hit(f.settings.SMTPFailureRate) {
+		return nil
+	}
+	f.log.Debug("Injecting SMTP failure in invite email")
+	return errInjectedSMTPFailure
+}
+
+// delay waits for the injected database latency, or until ctx is done.
+func (f *inviteFaults) delay(ctx context.Context) {
+	if f == nil || f.settings.DBLatency <= 0 {
+		return
+	}
+	t := time.NewTimer(f.settings.DBLatency)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
+
+// duplicateSubmission tells whether a request of the invite paths should be handled twice.
+func (f *inviteFaults) duplicateSubmission() bool {
+	return f != nil && f.hit(f.settings.DuplicateSubmissionRate)
+}
+
+// wrapTempUserService adds the injected database latency to the calls of the invite paths.
+func (f *inviteFaults) wrapTempUserService(svc tempUser.Service) tempUser.Service {
+	if f == nil || f.settings.DBLatency <= 0 {
+		return svc
+	}
+	return &slowTempUserService{Service: svc, faults: f}
+}
+
+type slowTempUserService struct {
+	tempUser.Service
+	faults *inviteFaults
+}
+
+func (s *slowTempUserService) CreateTempUser(ctx context.Context, cmd *models.CreateTempUserCommand) error {
+	s.faults.delay(ctx)
+	return s.Service.CreateTempUser(ctx, cmd)
+}
+
+func (s *slowTempUserService) GetTempUserByCode(ctx context.Context, query *models.GetTempUserByCodeQuery) error {
+	s.faults.delay(ctx)
+	return s.Service.GetTempUserByCode(ctx, query)
+}
+
+func (s *slowTempUserService) GetTempUsersQuery(ctx context.Context, query *models.GetTempUsersQuery) error {
+	s.faults.delay(ctx)
+	return s.Service.GetTempUsersQuery(ctx, query)
+}
+
+func (s *slowTempUserService) UpdateTempUserStatus(ctx context.Context, cmd *models.UpdateTempUserStatusCommand) error {
+	s.faults.delay(ctx)
+	return s.Service.UpdateTempUserStatus(ctx, cmd)
+}
+
+func (s *slowTempUserService) UpdateTempUserWithEmailSent(ctx context.Context, cmd *models.UpdateTempUserWithEmailSentCommand) error {
+	s.faults.delay(ctx)
+	return s.Service.UpdateTempUserWithEmailSent(ctx, cmd)
+}
+
+// withInviteDuplicateSubmission handles the request twice when the invite testing mode injects a
+// duplicate submission, as if the user double clicked. The response of the second submission is
+// returned, like browsers show.
+func (hs *HTTPServer) withInviteDuplicateSubmission(c *models.ReqContext, handler func(c *models.ReqContext) response.Response) response.Response {
+	if !hs.inviteFaults.duplicateSubmission() {
+		return handler(c)
+	}
+	body, err := io.ReadAll(c.Req.Body)
+	if err != nil {
+		return response.Error(400, "Failed to read request body", err)
+	}
+	c.Req.Body = io.NopCloser(bytes.NewReader(body))
+	first := handler(c)
+	hs.log.FromContext(c.Req.Context()).Debug("Injecting duplicate submission", "path", c.Req.URL.Path, "firstStatus", first.Status())
+	c.Req.Body = io.NopCloser(bytes.NewReader(body))
+	return handler(c)
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/temp_user/tempusertest"
+	"github.com/grafana/grafana/pkg/services/user/usertest"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestInviteTestingMode(t *testing.T) {
+	setup := func(t *testing.T, settings setting.InviteTestingSettings) accessControlScenarioContext {
+		sc, stack := setupInviteHTTPServer(t)
+		settings.Enabled = true
+		settings.Seed = 1
+		sc.hs.inviteFaults = newInviteFaults(settings, log.New("test"))
+		sc.hs.tempUserService = sc.hs.inviteFaults.wrapTempUserService(stack.TempUsers)
+		sc.hs.userService = &slackInviteUserService{&usertest.FakeUserService{}}
+		sc.hs.kvStore = kvstore.ProvideService(sc.db)
+		setInitCtxSignedInOrgAdmin(sc.initCtx)
+		setAccessControlPermissions(sc.acmock, []accesscontrol.Permission{{Action: accesscontrol.ActionOrgUsersAdd}}, sc.initCtx.OrgID)
+		return sc
+	}
+	pendingInvites := func(t *testing.T, sc accessControlScenarioContext) []*models.TempUserDTO {
+		query := models.GetTempUsersQuery{OrgId: sc.initCtx.OrgID, Status: models.TmpUserInvitePending}
+		require.NoError(t, sc.hs.tempUserService.GetTempUsersQuery(context.Background(), &query))
+		return query.Result
+	}
+
+	t.Run("invite codes are deterministic", func(t *testing.T) {
+		sc := setup(t, setting.InviteTestingSettings{DeterministicCodes: true})
+		for _, email := range []string{"first@example.com", "second@example.com"} {
+			response := callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(`{"loginOrEmail": "`+email+`", "role": "Viewer"}`), t)
+			require.Equal(t, http.StatusOK, response.Code)
+		}
+		var codes []string
+		for _, invite := range pendingInvites(t, sc) {
+			codes = append(codes, invite.Code)
+		}
+		assert.ElementsMatch(t, []string{fmt.Sprintf("test%026d", 1), fmt.Sprintf("test%026d", 2)}, codes)
+	})
+
+	t.Run("invite emails fail as if SMTP was down", func(t *testing.T) {
+		sc := setup(t, setting.InviteTestingSettings{SMTPFailureRate: 1})
+		response := callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(`{"loginOrEmail": "new@example.com", "role": "Viewer", "sendEmail": true}`), t)
+		assert.Equal(t, http.StatusInternalServerError, response.Code)
+		assert.Contains(t, response.Body.String(), "Failed to send email invite")
+	})
+
+	t.Run("invites are submitted twice", func(t *testing.T) {
+		sc := setup(t, setting.InviteTestingSettings{DuplicateSubmissionRate: 1})
+		response := callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(`{"loginOrEmail": "new@example.com", "role": "Viewer"}`), t)
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Len(t, pendingInvites(t, sc), 2)
+
+		sc = setup(t, setting.InviteTestingSettings{DuplicateSubmissionRate: 1})
+		sc.hs.Cfg.UniquePendingInvites = true
+		response = callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(`{"loginOrEmail": "new@example.com", "role": "Viewer"}`), t)
+		assert.Equal(t, http.StatusPreconditionFailed, response.Code, "the second submission finds the invite of the first")
+		assert.Len(t, pendingInvites(t, sc), 1)
+	})
+
+	t.Run("invite database calls are slowed down", func(t *testing.T) {
+		faults := newInviteFaults(setting.InviteTestingSettings{Enabled: true, DBLatency: 20 * time.Millisecond}, log.New("test"))
+		svc := faults.wrapTempUserService(&tempusertest.FakeTempUserService{})
+		start := time.Now()
+		require.NoError(t, svc.CreateTempUser(context.Background(), &models.CreateTempUserCommand{}))
+		assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+		fake := &tempusertest.FakeTempUserService{}
+		assert.Same(t, fake, newInviteFaults(setting.InviteTestingSettings{}, log.New("test")).wrapTempUserService(fake), "disabled mode doesn't wrap the service")
+	})
+}
@@ -0,0 +1,494 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/notifications"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/util"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+// The v2 invites API addresses invites by their ID rather than by their code, and uses the
+// invite version as ETag so that concurrent updates can be detected with If-Match.
+// The v1 endpoints are kept unchanged.
+
+// swagger:route GET /v2/org/invites org_invites searchOrgInvitesV2
+//
+// Search the invites of the current organization.
+//
+// Invites are sorted by creation date, newest first. They can be filtered by status, by how
+// they are delivered, by email or name with the `query` parameter, and with `invitedBy` by whether
+// they were created by automation, with an API key or by a service account, or by users.
+// Completed invites tell the IP address, user agent and country they were completed from, and
+// `anomaly=true` only returns the ones completed from a country the invites of the organization
+// are rarely completed from, for security reviews of the access they gave.
+// With the `invites:self` scope only the invites created by the signed in user are searched.
+//
+// Responses:
+// 200: searchOrgInvitesV2Response
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (hs *HTTPServer) SearchOrgInvitesV2(c *models.ReqContext) response.Response {
+	perPage := c.QueryInt("perpage")
+	if perPage <= 0 {
+		perPage = 100
+	}
+	page := c.QueryInt("page")
+	if page < 1 {
+		page = 1
+	}
+
+	status := models.TempUserStatus(c.Query("status"))
+	if status == models.TmpUserSignUpStarted || (status != "" && !isInviteStatus(status)) {
+		return response.Error(http.StatusBadRequest, "Invalid invite status", nil)
+	}
+	delivery := models.InviteDelivery(c.Query("delivery"))
+	if delivery != "" && !delivery.IsValid() {
+		return response.Error(http.StatusBadRequest, "Invalid delivery", nil)
+	}
+	invitedBy := models.InviteCreator(c.Query("invitedBy"))
+	if invitedBy != "" && !invitedBy.IsValid() {
+		return response.Error(http.StatusBadRequest, "Invalid invitedBy, must be automation or user", nil)
+	}
+
+	readable := models.GetTempUsersQuery{}
+	canRead, err := hs.filterReadableInvites(c, &readable)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to evaluate invite permissions", err)
+	}
+	if !canRead {
+		return response.Error(http.StatusForbidden, "Permission denied", nil)
+	}
+
+	query := models.SearchTempUsersQuery{
+		OrgID:           c.OrgID,
+		InvitedByUserID: readable.InvitedByUserID,
+		Query:           c.Query("query"),
+		Status:          status,
+		Delivery:        delivery,
+		InvitedBy:       invitedBy,
+		Page:            page,
+		Limit:           perPage,
+		Anomaly:         c.QueryBool("anomaly"),
+	}
+	if err := hs.tempUserService.SearchTempUsers(c.Req.Context(), &query); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to search invites", err)
+	}
+
+	for _, invite := range query.Result.Invites {
+		invite.Url = setting.ToAbsUrl("invite/" + invite.Code)
+	}
+
+	return response.JSON(http.StatusOK, query.Result)
+}
+
+// swagger:route GET /v2/org/invites/{invite_id} org_invites getOrgInviteV2
+//
+// Get an invite of the current organization.
+//
+// The `ETag` header of the response holds the version of the invite. With the `invites:self` scope
+// only the invites created by the signed in user can be read.
+//
+// Responses:
+// 200: getOrgInviteV2Response
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (hs *HTTPServer) GetOrgInviteV2(c *models.ReqContext) response.Response {
+	invite, rsp := hs.getReadableInviteV2(c)
+	if rsp != nil {
+		return rsp
+	}
+
+	return inviteV2Response(invite)
+}
+
+// getReadableInviteV2 returns the invite of the request. Invites the signed in user can't read with
+// the scope of their org.invites:read permission are reported as not found.
+func (hs *HTTPServer) getReadableInviteV2(c *models.ReqContext) (*models.TempUserDTO, response.Response) {
+	inviteID, err := strconv.ParseInt(web.Params(c.Req)[":inviteId"], 10, 64)
+	if err != nil {
+		return nil, response.Error(http.StatusBadRequest, "inviteId is invalid", err)
+	}
+
+	readable := models.GetTempUsersQuery{}
+	canRead, err := hs.filterReadableInvites(c, &readable)
+	if err != nil {
+		return nil, response.Error(http.StatusInternalServerError, "Failed to evaluate invite permissions", err)
+	}
+	if !canRead {
+		return nil, response.Error(http.StatusForbidden, "Permission denied", nil)
+	}
+
+	query := models.GetTempUserByIDQuery{OrgID: c.OrgID, ID: inviteID}
+	if err := hs.tempUserService.GetTempUserByID(c.Req.Context(), &query); err != nil {
+		if errors.Is(err, models.ErrTempUserNotFound) {
+			return nil, response.Error(http.StatusNotFound, "Invite not found", nil)
+		}
+		return nil, response.Error(http.StatusInternalServerError, "Failed to get invite", err)
+	}
+	if readable.InvitedByUserID > 0 && query.Result.InvitedByUserId != readable.InvitedByUserID {
+		return nil, response.Error(http.StatusNotFound, "Invite not found", nil)
+	}
+	return query.Result, nil
+}
+
+// swagger:route GET /v2/org/invites/{invite_id}/link org_invites getOrgInviteLinkV2
+//
+// Get the link of a pending invite of the current organization.
+//
+// The link can be copied and handed over to the invitee, for invites which are delivered manually
+// on instances without SMTP. The links of emailed invites are only sent to the invitee.
+//
+// Responses:
+// 200: getOrgInviteLinkV2Response
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 409: conflictError
+// 500: internalServerError
+func (hs *HTTPServer) GetOrgInviteLinkV2(c *models.ReqContext) response.Response {
+	invite, rsp := hs.getReadableInviteV2(c)
+	if rsp != nil {
+		return rsp
+	}
+	if invite.Status != models.TmpUserInvitePending {
+		return response.Error(http.StatusConflict, fmt.Sprintf("Invite is %s, only pending invites can be accepted", invite.Status), nil)
+	}
+	if invite.Delivery != models.InviteDeliveryManual {
+		return response.Error(http.StatusConflict, "Invite is delivered by email, only the links of manually delivered invites can be handed over", nil)
+	}
+
+	return response.JSON(http.StatusOK, newInviteLink(invite.Id, invite.Email, invite.Code))
+}
+
+// swagger:route GET /v2/org/invites/{invite_id}/delivery org_invites getOrgInviteDeliveryV2
+//
+// Get the delivery state of the email of an invite of the current organization.
+//
+// Emails of invites created while the email backend is saturated are queued. While they are, the
+// response has their position in the queue, when they are expected to be sent, and a
+// `Retry-After` header.
+//
+// Responses:
+// 200: getOrgInviteDeliveryV2Response
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (hs *HTTPServer) GetOrgInviteDeliveryV2(c *models.ReqContext) response.Response {
+	invite, rsp := hs.getReadableInviteV2(c)
+	if rsp != nil {
+		return rsp
+	}
+
+	var delivery notifications.EmailDelivery
+	queued := false
+	if hs.NotificationService != nil {
+		delivery, queued = hs.NotificationService.EmailDelivery(inviteEmailKey(invite.Code))
+	}
+
+	switch {
+	case queued && delivery.State == notifications.EmailDeliveryQueued:
+		return response.JSON(http.StatusOK, dtos.InviteDelivery{
+			State:             string(delivery.State),
+			QueuePosition:     delivery.Position,
+			EstimatedDelivery: &delivery.EstimatedDelivery,
+		}).SetHeader("Retry-After", retryAfterSeconds(delivery.EstimatedDelivery))
+	case queued && delivery.State == notifications.EmailDeliveryFailed:
+		return response.JSON(http.StatusOK, dtos.InviteDelivery{State: string(delivery.State), Error: delivery.Error})
+	case invite.EmailSent:
+		return response.JSON(http.StatusOK, dtos.InviteDelivery{State: string(notifications.EmailDeliverySent), SentAt: &invite.EmailSentOn})
+	}
+	return response.JSON(http.StatusOK, dtos.InviteDelivery{State: "not_sent"})
+}
+
+// swagger:route PATCH /v2/org/invites/{invite_id} org_invites updateOrgInviteV2
+//
+// Update an invite of the current organization.
+//
+// The name and role of the invitee can be changed, and the invite can be revoked by setting
+// its status to `Revoked`. When the `If-Match` header is set, the update is only applied if
+// the invite has not changed since that ETag was returned.
+//
+// The email of a pending invite can be corrected, e.g. after a typo. The invite keeps its ID
+// and history but gets a new link, the previous one stops working. Invites which had been
+// emailed are sent again to the new address. Correcting the email counts towards the invite rate
+// limits like a new invite.
+//
+// Responses:
+// 200: getOrgInviteV2Response
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 409: conflictError
+// 412: preconditionFailedError
+// 429: tooManyRequestsError
+// 500: internalServerError
+func (hs *HTTPServer) UpdateOrgInviteV2(c *models.ReqContext) response.Response {
+	inviteID, err := strconv.ParseInt(web.Params(c.Req)[":inviteId"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "inviteId is invalid", err)
+	}
+
+	form := dtos.PatchOrgInviteForm{}
+	if err := web.Bind(c.Req, &form); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	if form.Role != nil {
+		if !form.Role.IsValid() {
+			return response.Error(http.StatusBadRequest, "Invalid role specified", nil)
+		}
+		if !c.OrgRole.Includes(*form.Role) && !c.IsGrafanaAdmin {
+			return response.Error(http.StatusForbidden, "Cannot assign a role higher than user's role", nil)
+		}
+		if rsp := hs.checkAssignableRole(c, *form.Role); rsp != nil {
+			return rsp
+		}
+	}
+	// other states are reached by sending and accepting the invite
+	if form.Status != nil && *form.Status != models.TmpUserRevoked {
+		return response.Error(http.StatusBadRequest, "Invites can only be revoked", nil)
+	}
+	if form.Email != nil {
+		if form.Status != nil {
+			return response.Error(http.StatusBadRequest, "The email of a revoked invite can't be changed", nil)
+		}
+		email := strings.TrimSpace(*form.Email)
+		if !util.IsEmail(email) {
+			return response.Error(http.StatusBadRequest, "Invalid email address", nil)
+		}
+		form.Email = &email
+	}
+
+	cmd := models.UpdateTempUserCommand{
+		OrgID:  c.OrgID,
+		ID:     inviteID,
+		Name:   form.Name,
+		Role:   form.Role,
+		Status: form.Status,
+	}
+	if ifMatch := c.Req.Header.Get("If-Match"); ifMatch != "" && ifMatch != "*" {
+		version, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`))
+		if err != nil {
+			return response.Error(http.StatusPreconditionFailed, "Invite has been changed", err)
+		}
+		cmd.Version = &version
+	}
+
+	var invite *models.TempUserDTO
+	if form.Email != nil || form.Role != nil {
+		query := models.GetTempUserByIDQuery{OrgID: c.OrgID, ID: inviteID}
+		if err := hs.tempUserService.GetTempUserByID(c.Req.Context(), &query); err != nil {
+			if errors.Is(err, models.ErrTempUserNotFound) {
+				return response.Error(http.StatusNotFound, "Invite not found", nil)
+			}
+			return response.Error(http.StatusInternalServerError, "Failed to get invite", err)
+		}
+		invite = query.Result
+	}
+	if form.Role != nil {
+		if rsp := hs.checkInviteRole(invite.Delivery, *form.Role); rsp != nil {
+			return rsp
+		}
+	}
+
+	var previous *models.TempUserDTO
+	var limit *inviteRateLimit
+	if form.Email != nil && invite.Email != *form.Email {
+		if rsp := hs.checkInviteEmailAvailable(c, *form.Email); rsp != nil {
+			return rsp
+		}
+		// the invite is sent to someone else, it counts as a new invite
+		var rsp response.Response
+		if limit, rsp = hs.checkInviteRateLimit(c.Req.Context(), c.OrgID, inviteRateLimitInviter(c), 1); rsp != nil {
+			return rsp
+		}
+		code, err := hs.inviteFaults.newCode()
+		if err != nil {
+			return response.Error(http.StatusInternalServerError, "Could not generate random string", err)
+		}
+		cmd.Email = form.Email
+		cmd.Code = &code
+		previous = invite
+	}
+
+	if err := hs.tempUserService.UpdateTempUser(c.Req.Context(), &cmd); err != nil {
+		switch {
+		case errors.Is(err, models.ErrTempUserNotFound):
+			return response.Error(http.StatusNotFound, "Invite not found", nil)
+		case errors.Is(err, models.ErrTempUserVersionMismatch):
+			return response.Error(http.StatusPreconditionFailed, "Invite has been changed", err)
+		case errors.Is(err, models.ErrTempUserInvalidTransition):
+			return response.Error(http.StatusConflict, err.Error(), err)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to update invite", err)
+	}
+
+	if previous != nil {
+		hs.countInvites(c.Req.Context(), limit, 1)
+		hs.log.Info("Changed invite email", "inviteId", inviteID, "orgId", c.OrgID, "previousEmail", previous.Email, "email", *cmd.Email, "changedBy", c.UserID)
+		if previous.EmailSent && cmd.Result.Delivery == models.InviteDeliveryEmail {
+			if _, rsp := hs.sendNewUserInviteEmail(c, cmd.Result.Email, cmd.Result.Name, cmd.Result.Code); rsp != nil {
+				return rsp
+			}
+			query := models.GetTempUserByIDQuery{OrgID: c.OrgID, ID: inviteID}
+			if err := hs.tempUserService.GetTempUserByID(c.Req.Context(), &query); err != nil {
+				return response.Error(http.StatusInternalServerError, "Failed to get invite", err)
+			}
+			cmd.Result = query.Result
+		}
+	}
+
+	return inviteV2Response(cmd.Result)
+}
+
+// checkInviteEmailAvailable returns an error response when email belongs to a member of the
+// organization or to another pending invite of it. It returns nil otherwise.
+func (hs *HTTPServer) checkInviteEmailAvailable(c *models.ReqContext, email string) response.Response {
+	pendingQuery := models.GetTempUsersQuery{OrgId: c.OrgID, Email: email, Status: models.TmpUserInvitePending}
+	if err := hs.tempUserService.GetTempUsersQuery(c.Req.Context(), &pendingQuery); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get invites from db", err)
+	}
+	if len(pendingQuery.Result) > 0 {
+		return response.Error(http.StatusConflict, fmt.Sprintf("%s has already been invited to organization", email), nil)
+	}
+
+	usr, err := hs.userService.GetByLogin(c.Req.Context(), &user.GetUserByLoginQuery{LoginOrEmail: email})
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return nil
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to query db for existing user check", err)
+	}
+	orgsQuery := models.GetUserOrgListQuery{UserId: usr.ID}
+	if err := hs.SQLStore.GetUserOrgList(c.Req.Context(), &orgsQuery); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get user organizations", err)
+	}
+	for _, userOrg := range orgsQuery.Result {
+		if userOrg.OrgId == c.OrgID {
+			return response.Error(http.StatusConflict, fmt.Sprintf("User %s is already added to organization", email), nil)
+		}
+	}
+	return nil
+}
+
+func inviteV2Response(invite *models.TempUserDTO) response.Response {
+	invite.Url = setting.ToAbsUrl("invite/" + invite.Code)
+	return response.JSON(http.StatusOK, invite).SetHeader("ETag", inviteETag(invite))
+}
+
+func newInviteLink(id int64, email string, code string) dtos.InviteLink {
+	return dtos.InviteLink{
+		InviteID: id,
+		Email:    email,
+		Code:     code,
+		URL:      setting.ToAbsUrl("invite/" + code),
+	}
+}
+
+func inviteETag(invite *models.TempUserDTO) string {
+	return `"` + strconv.Itoa(invite.Version) + `"`
+}
+
+func isInviteStatus(status models.TempUserStatus) bool {
+	switch status {
+	case models.TmpUserInvitePending, models.TmpUserInviteEmailed, models.TmpUserCompleted,
+		models.TmpUserRevoked, models.TmpUserExpired, models.TmpUserDeclined:
+		return true
+	}
+	return false
+}
+
+// swagger:parameters searchOrgInvitesV2
+type SearchOrgInvitesV2Params struct {
+	// in:query
+	// required:false
+	Query string `json:"query"`
+	// in:query
+	// required:false
+	Status string `json:"status"`
+	// in:query
+	// required:false
+	// enum: email,manual
+	Delivery string `json:"delivery"`
+	// in:query
+	// required:false
+	// enum: automation,user
+	InvitedBy string `json:"invitedBy"`
+	// in:query
+	// required:false
+	Anomaly bool `json:"anomaly"`
+	// in:query
+	// required:false
+	// default:100
+	PerPage int `json:"perpage"`
+	// in:query
+	// required:false
+	// default:1
+	Page int `json:"page"`
+}
+
+// swagger:parameters getOrgInviteV2 getOrgInviteLinkV2 getOrgInviteDeliveryV2
+type GetOrgInviteV2Params struct {
+	// in:path
+	// required:true
+	InviteID int64 `json:"invite_id"`
+}
+
+// swagger:parameters updateOrgInviteV2
+type UpdateOrgInviteV2Params struct {
+	// in:path
+	// required:true
+	InviteID int64 `json:"invite_id"`
+	// in:header
+	// required:false
+	IfMatch string `json:"If-Match"`
+	// in:body
+	// required:true
+	Body dtos.PatchOrgInviteForm `json:"body"`
+}
+
+// swagger:response searchOrgInvitesV2Response
+type SearchOrgInvitesV2Response struct {
+	// in: body
+	Body models.SearchTempUsersQueryResult `json:"body"`
+}
+
+// swagger:response getOrgInviteV2Response
+type GetOrgInviteV2Response struct {
+	// in:header
+	ETag string `json:"ETag"`
+	// in: body
+	Body *models.TempUserDTO `json:"body"`
+}
+
+// swagger:response getOrgInviteLinkV2Response
+type GetOrgInviteLinkV2Response struct {
+	// in: body
+	Body dtos.InviteLink `json:"body"`
+}
+
+// swagger:response getOrgInviteDeliveryV2Response
+type GetOrgInviteDeliveryV2Response struct {
+	// in:header
+	RetryAfter string `json:"Retry-After"`
+	// in: body
+	Body dtos.InviteDelivery `json:"body"`
+}
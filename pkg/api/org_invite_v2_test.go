@@ -0,0 +1,259 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/ngalert"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/temp_user/invitetest"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/services/user/usertest"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestOrgInvitesV2APIEndpoints(t *testing.T) {
+	setup := func(t *testing.T) (accessControlScenarioContext, *invitetest.Stack, int64) {
+		sc, stack := setupInviteHTTPServer(t)
+		setInitCtxSignedInOrgAdmin(sc.initCtx)
+		setAccessControlPermissions(sc.acmock, []accesscontrol.Permission{
+			{Action: accesscontrol.ActionOrgUsersAdd},
+			{Action: accesscontrol.ActionOrgInvitesRead, Scope: accesscontrol.ScopeInvitesAll},
+		}, sc.initCtx.OrgID)
+
+		invite := stack.Invite("invitee@example.com").InOrg(sc.initCtx.OrgID).WithName("Invitee").WithCode("invite-code").Create(t)
+		stack.Invite("other@example.com").InOrg(sc.initCtx.OrgID).WithCode("other-code").WithStatus(models.TmpUserRevoked).WithDelivery(models.InviteDeliveryManual).Create(t)
+		return sc, stack, invite.Id
+	}
+
+	t.Run("searches invites", func(t *testing.T) {
+		sc, _, _ := setup(t)
+
+		response := callAPI(sc.server, http.MethodGet, "/api/v2/org/invites?status=InvitePending", nil, t)
+		require.Equal(t, http.StatusOK, response.Code)
+
+		var result models.SearchTempUsersQueryResult
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &result))
+		assert.Equal(t, int64(1), result.TotalCount)
+		require.Len(t, result.Invites, 1)
+		assert.Equal(t, "invite-code", result.Invites[0].Code)
+
+		response = callAPI(sc.server, http.MethodGet, "/api/v2/org/invites?delivery=manual", nil, t)
+		require.Equal(t, http.StatusOK, response.Code)
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &result))
+		require.Len(t, result.Invites, 1)
+		assert.Equal(t, "other-code", result.Invites[0].Code)
+		assert.Equal(t, models.InviteDeliveryManual, result.Invites[0].Delivery)
+
+		response = callAPI(sc.server, http.MethodGet, "/api/v2/org/invites?status=Unknown", nil, t)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+
+		response = callAPI(sc.server, http.MethodGet, "/api/v2/org/invites?delivery=carrier-pigeon", nil, t)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+
+	t.Run("searches invites created by automation", func(t *testing.T) {
+		sc, _, _ := setup(t)
+		sc.hs.userService = &slackInviteUserService{&usertest.FakeUserService{}}
+		sc.initCtx.SignedInUser = &user.SignedInUser{OrgID: 1, OrgRole: org.RoleAdmin, ApiKeyID: 7}
+		setAccessControlPermissions(sc.acmock, []accesscontrol.Permission{
+			{Action: accesscontrol.ActionOrgUsersAdd},
+			{Action: accesscontrol.ActionOrgInvitesRead, Scope: accesscontrol.ScopeInvitesAll},
+		}, sc.initCtx.OrgID)
+
+		response := callAPI(sc.server, http.MethodPost, "/api/org/invites", strings.NewReader(`{"loginOrEmail": "ci@example.com", "role": "Viewer"}`), t)
+		require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+
+		var result models.SearchTempUsersQueryResult
+		response = callAPI(sc.server, http.MethodGet, "/api/v2/org/invites?invitedBy=automation", nil, t)
+		require.Equal(t, http.StatusOK, response.Code)
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &result))
+		require.Len(t, result.Invites, 1)
+		assert.Equal(t, "ci@example.com", result.Invites[0].Email)
+		assert.Equal(t, int64(7), result.Invites[0].InvitedByApiKeyId)
+
+		response = callAPI(sc.server, http.MethodGet, "/api/v2/org/invites?invitedBy=user", nil, t)
+		require.Equal(t, http.StatusOK, response.Code)
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &result))
+		assert.Len(t, result.Invites, 2)
+
+		response = callAPI(sc.server, http.MethodGet, "/api/v2/org/invites?invitedBy=robots", nil, t)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+
+	t.Run("gets the link of a pending invite", func(t *testing.T) {
+		sc, stack, id := setup(t)
+		manual := stack.Invite("manual@example.com").InOrg(sc.initCtx.OrgID).WithCode("manual-code").WithDelivery(models.InviteDeliveryManual).Create(t)
+
+		response := callAPI(sc.server, http.MethodGet, "/api/v2/org/invites/"+strconv.FormatInt(manual.Id, 10)+"/link", nil, t)
+		require.Equal(t, http.StatusOK, response.Code)
+
+		var link dtos.InviteLink
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &link))
+		assert.Equal(t, manual.Id, link.InviteID)
+		assert.Equal(t, "manual-code", link.Code)
+		assert.Equal(t, setting.ToAbsUrl("invite/manual-code"), link.URL)
+
+		// the first invite is emailed, the other one revoked
+		response = callAPI(sc.server, http.MethodGet, "/api/v2/org/invites/"+strconv.FormatInt(id, 10)+"/link", nil, t)
+		assert.Equal(t, http.StatusConflict, response.Code)
+		response = callAPI(sc.server, http.MethodGet, "/api/v2/org/invites/"+strconv.FormatInt(id+1, 10)+"/link", nil, t)
+		assert.Equal(t, http.StatusConflict, response.Code)
+	})
+
+	t.Run("gets an invite with its ETag", func(t *testing.T) {
+		sc, _, id := setup(t)
+
+		response := callAPI(sc.server, http.MethodGet, "/api/v2/org/invites/"+strconv.FormatInt(id, 10), nil, t)
+		require.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, `"0"`, response.Header().Get("ETag"))
+
+		var invite models.TempUserDTO
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &invite))
+		assert.Equal(t, id, invite.Id)
+
+		response = callAPI(sc.server, http.MethodGet, "/api/v2/org/invites/1000", nil, t)
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+
+	t.Run("updates an invite", func(t *testing.T) {
+		sc, _, id := setup(t)
+		url := "/api/v2/org/invites/" + strconv.FormatInt(id, 10)
+
+		response := callAPI(sc.server, http.MethodPatch, url, strings.NewReader(`{"role": "Editor"}`), t)
+		require.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, `"1"`, response.Header().Get("ETag"))
+
+		var invite models.TempUserDTO
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &invite))
+		assert.Equal(t, org.RoleEditor, invite.Role)
+		assert.Equal(t, "Invitee", invite.Name)
+
+		response = callAPI(sc.server, http.MethodPatch, url, strings.NewReader(`{"status": "Completed"}`), t)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+
+		response = callAPI(sc.server, http.MethodPatch, url, strings.NewReader(`{"role": "Admin"}`), t)
+		assert.Equal(t, http.StatusOK, response.Code)
+	})
+
+	t.Run("changes the email of a pending invite and sends it again", func(t *testing.T) {
+		sc, stack, id := setup(t)
+		url := "/api/v2/org/invites/" + strconv.FormatInt(id, 10)
+		require.NoError(t, stack.TempUsers.UpdateTempUserWithEmailSent(context.Background(), &models.UpdateTempUserWithEmailSentCommand{Code: "invite-code"}))
+		sc.hs.AlertNG = &ngalert.AlertNG{NotificationService: stack.Mail}
+		sc.hs.kvStore = kvstore.ProvideService(sc.db)
+
+		response := callAPI(sc.server, http.MethodPatch, url, strings.NewReader(`{"email": " invitee@example.org "}`), t)
+		require.Equal(t, http.StatusOK, response.Code)
+
+		var invite models.TempUserDTO
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &invite))
+		assert.Equal(t, id, invite.Id)
+		assert.Equal(t, "invitee@example.org", invite.Email)
+		assert.Equal(t, "Invitee", invite.Name)
+		assert.NotEqual(t, "invite-code", invite.Code)
+		assert.True(t, invite.EmailSent)
+		assert.Equal(t, `"3"`, response.Header().Get("ETag"))
+
+		emails := stack.Mail.Emails()
+		require.Len(t, emails, 1)
+		assert.Equal(t, []string{"invitee@example.org"}, emails[0].To)
+		assert.Equal(t, setting.ToAbsUrl("invite/"+invite.Code), emails[0].Data["LinkUrl"])
+
+		// the previous link no longer works
+		query := models.GetTempUserByCodeQuery{Code: "invite-code"}
+		assert.ErrorIs(t, stack.TempUsers.GetTempUserByCode(context.Background(), &query), models.ErrTempUserNotFound)
+
+		// unchanged emails are not sent again
+		stack.Mail.Reset()
+		response = callAPI(sc.server, http.MethodPatch, url, strings.NewReader(`{"email": "invitee@example.org"}`), t)
+		require.Equal(t, http.StatusOK, response.Code)
+		assert.Empty(t, stack.Mail.Emails())
+	})
+
+	t.Run("rejects email changes", func(t *testing.T) {
+		sc, stack, id := setup(t)
+		url := "/api/v2/org/invites/" + strconv.FormatInt(id, 10)
+		stack.Invite("taken@example.com").InOrg(sc.initCtx.OrgID).WithCode("taken-code").Create(t)
+
+		response := callAPI(sc.server, http.MethodPatch, url, strings.NewReader(`{"email": "not-an-email"}`), t)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+
+		response = callAPI(sc.server, http.MethodPatch, url, strings.NewReader(`{"email": "taken@example.com"}`), t)
+		assert.Equal(t, http.StatusConflict, response.Code)
+
+		response = callAPI(sc.server, http.MethodPatch, url, strings.NewReader(`{"email": "new@example.com", "status": "Revoked"}`), t)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+
+		// the other invite is revoked
+		response = callAPI(sc.server, http.MethodPatch, "/api/v2/org/invites/"+strconv.FormatInt(id+1, 10), strings.NewReader(`{"email": "new@example.com"}`), t)
+		assert.Equal(t, http.StatusConflict, response.Code)
+	})
+
+	t.Run("reads only the invites created by the user with the self scope", func(t *testing.T) {
+		sc, stack, id := setup(t)
+		setAccessControlPermissions(sc.acmock, []accesscontrol.Permission{
+			{Action: accesscontrol.ActionOrgUsersAdd},
+			{Action: accesscontrol.ActionOrgInvitesRead, Scope: accesscontrol.ScopeInvitesSelf},
+		}, sc.initCtx.OrgID)
+		own := stack.Invite("own@example.com").InOrg(sc.initCtx.OrgID).WithCode("own-code").InvitedBy(testUserID).WithDelivery(models.InviteDeliveryManual).Create(t)
+
+		response := callAPI(sc.server, http.MethodGet, "/api/v2/org/invites", nil, t)
+		require.Equal(t, http.StatusOK, response.Code)
+		var result models.SearchTempUsersQueryResult
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &result))
+		assert.Equal(t, int64(1), result.TotalCount)
+		require.Len(t, result.Invites, 1)
+		assert.Equal(t, own.Id, result.Invites[0].Id)
+
+		for _, path := range []string{"", "/link", "/delivery"} {
+			response = callAPI(sc.server, http.MethodGet, "/api/v2/org/invites/"+strconv.FormatInt(id, 10)+path, nil, t)
+			assert.Equal(t, http.StatusNotFound, response.Code, path)
+			response = callAPI(sc.server, http.MethodGet, "/api/v2/org/invites/"+strconv.FormatInt(own.Id, 10)+path, nil, t)
+			assert.Equal(t, http.StatusOK, response.Code, path)
+		}
+	})
+
+	t.Run("forbids reading invites without the read permission", func(t *testing.T) {
+		sc, _, id := setup(t)
+		setAccessControlPermissions(sc.acmock, []accesscontrol.Permission{{Action: accesscontrol.ActionOrgUsersAdd}}, sc.initCtx.OrgID)
+
+		for _, path := range []string{"", "/" + strconv.FormatInt(id, 10), "/" + strconv.FormatInt(id, 10) + "/link"} {
+			response := callAPI(sc.server, http.MethodGet, "/api/v2/org/invites"+path, nil, t)
+			assert.Equal(t, http.StatusForbidden, response.Code, path)
+		}
+	})
+
+	t.Run("rejects updates of a changed invite", func(t *testing.T) {
+		sc, _, id := setup(t)
+		url := "/api/v2/org/invites/" + strconv.FormatInt(id, 10)
+
+		patchIfMatch := func(etag string) *httptest.ResponseRecorder {
+			req, err := http.NewRequest(http.MethodPatch, url, strings.NewReader(`{"status": "Revoked"}`))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("If-Match", etag)
+			recorder := httptest.NewRecorder()
+			sc.server.ServeHTTP(recorder, req)
+			return recorder
+		}
+
+		assert.Equal(t, http.StatusPreconditionFailed, patchIfMatch(`"5"`).Code)
+		assert.Equal(t, http.StatusOK, patchIfMatch(`"0"`).Code)
+
+		response := callAPI(sc.server, http.MethodPatch, url, strings.NewReader(`{"status": "Revoked"}`), t)
+		assert.Equal(t, http.StatusConflict, response.Code)
+	})
+}
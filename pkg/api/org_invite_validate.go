@@ -0,0 +1,192 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/models"
+	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/util"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+// swagger:route POST /org/invites/validate org_invites validateOrgInvites
+//
+// Validate a batch of invites.
+//
+// Checks each invite the way adding it would, without creating anything, so that the rows of a
+// CSV file can be fixed before inviting them one by one: the role and the teams the signed in
+// user can give, the email address of new users and whether they can be invited, invitees
+// listed twice, members of the organization and pending invites.
+//
+// Responses:
+// 200: validateOrgInvitesResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (hs *HTTPServer) ValidateOrgInvites(c *models.ReqContext) response.Response {
+	form := dtos.ValidateInvitesForm{}
+	if err := web.Bind(c.Req, &form); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+
+	validation := dtos.InvitesValidation{Valid: true, Rows: make([]dtos.InviteRowValidation, 0, len(form.Invites))}
+	seen := map[string]int{}
+	for i, raw := range form.Invites {
+		var inviteDto dtos.AddInviteForm
+		if err := json.Unmarshal(raw, &inviteDto); err != nil {
+			validation.Valid = false
+			validation.Rows = append(validation.Rows, dtos.InviteRowValidation{Row: i + 1, Errors: []string{fmt.Sprintf("Invalid invite: %s", err)}})
+			continue
+		}
+		row, rsp := hs.validateInviteRow(c, i+1, &inviteDto, seen)
+		if rsp != nil {
+			return rsp
+		}
+		if len(row.Errors) > 0 {
+			validation.Valid = false
+		}
+		validation.Rows = append(validation.Rows, row)
+	}
+	return response.JSON(http.StatusOK, validation)
+}
+
+// validateInviteRow checks an invite of a batch, seen holds the rows of the invitees of the
+// previous invites. It only returns a response when the invite couldn't be checked.
+func (hs *HTTPServer) validateInviteRow(c *models.ReqContext, number int, inviteDto *dtos.AddInviteForm, seen map[string]int) (dtos.InviteRowValidation, response.Response) {
+	inviteDto.LoginOrEmail = strings.TrimSpace(inviteDto.LoginOrEmail)
+	row := dtos.InviteRowValidation{Row: number, LoginOrEmail: inviteDto.LoginOrEmail}
+	if inviteDto.LoginOrEmail == "" {
+		row.Errors = append(row.Errors, "Login or email is required")
+		return row, nil
+	}
+
+	if rsp := hs.checkInviteForm(c, inviteDto); rsp != nil {
+		if rsp.Status() >= http.StatusInternalServerError {
+			return row, rsp
+		}
+		row.Errors = append(row.Errors, responseMessage(rsp))
+	}
+	row.Role = inviteDto.Role
+
+	key := strings.ToLower(inviteDto.LoginOrEmail)
+	if first, ok := seen[key]; ok {
+		row.Errors = append(row.Errors, fmt.Sprintf("%s is already invited by row %d", inviteDto.LoginOrEmail, first))
+	} else {
+		seen[key] = number
+	}
+
+	usr, err := hs.userService.GetByLogin(c.Req.Context(), &user.GetUserByLoginQuery{LoginOrEmail: inviteDto.LoginOrEmail})
+	if err != nil && !errors.Is(err, user.ErrUserNotFound) {
+		return row, response.Error(http.StatusInternalServerError, "Failed to query db for existing user check", err)
+	}
+
+	inviteEmail := inviteDto.LoginOrEmail
+	if err == nil {
+		row.ExistingUser = true
+		inviteEmail = util.StringsFallback3(usr.Email, usr.Login, inviteDto.LoginOrEmail)
+		rowErrors, rsp := hs.checkExistingUserInvite(c, usr, inviteDto)
+		if rsp != nil {
+			return row, rsp
+		}
+		row.Errors = append(row.Errors, rowErrors...)
+	} else {
+		if !util.IsEmail(inviteDto.LoginOrEmail) {
+			row.Errors = append(row.Errors, fmt.Sprintf("%s is neither a user nor a valid email address", inviteDto.LoginOrEmail))
+		}
+		if err := hs.invitePolicy(c.Req.Context(), c.OrgID, inviteDto.LoginOrEmail).canInviteNewUsers(); err != nil {
+			row.Errors = append(row.Errors, err.Error())
+		}
+	}
+
+	if inviteDto.SendEmail && util.IsEmail(inviteEmail) {
+		msg, err := hs.emailSuppressionError(c.Req.Context(), inviteEmail)
+		if err != nil {
+			return row, response.Error(http.StatusInternalServerError, "Failed to check the email suppression list", err)
+		}
+		if msg != "" {
+			row.Errors = append(row.Errors, msg)
+		}
+	}
+
+	pendingQuery := models.GetTempUsersQuery{OrgId: c.OrgID, Email: inviteEmail, Status: models.TmpUserInvitePending}
+	if err := hs.tempUserService.GetTempUsersQuery(c.Req.Context(), &pendingQuery); err != nil {
+		return row, response.Error(http.StatusInternalServerError, "Failed to get invites from db", err)
+	}
+	if len(pendingQuery.Result) > 0 {
+		// existing users can only have one pending invite per organization
+		if row.ExistingUser || hs.Cfg.UniquePendingInvites {
+			row.Errors = append(row.Errors, fmt.Sprintf("%s has already been invited to organization", inviteDto.LoginOrEmail))
+		} else {
+			row.Warnings = append(row.Warnings, fmt.Sprintf("%s has a pending invite already, another one would be created", inviteDto.LoginOrEmail))
+		}
+	}
+	return row, nil
+}
+
+// checkExistingUserInvite returns why an existing user can't be invited to the organization.
+func (hs *HTTPServer) checkExistingUserInvite(c *models.ReqContext, usr *user.User, inviteDto *dtos.AddInviteForm) ([]string, response.Response) {
+	var rowErrors []string
+	userIDScope := ac.Scope("users", "id", strconv.FormatInt(usr.ID, 10))
+	hasAccess, err := hs.AccessControl.Evaluate(c.Req.Context(), c.SignedInUser, ac.EvalPermission(ac.ActionOrgUsersAdd, userIDScope))
+	if err != nil {
+		return nil, response.Error(http.StatusInternalServerError, "Failed to evaluate permissions", err)
+	}
+	if !hasAccess {
+		rowErrors = append(rowErrors, "Permission denied: not permitted to add an existing user to this organisation")
+	}
+
+	orgsQuery := models.GetUserOrgListQuery{UserId: usr.ID}
+	if err := hs.SQLStore.GetUserOrgList(c.Req.Context(), &orgsQuery); err != nil {
+		return nil, response.Error(http.StatusInternalServerError, "Failed to get user organizations", err)
+	}
+	for _, userOrg := range orgsQuery.Result {
+		if userOrg.OrgId != c.OrgID {
+			continue
+		}
+		if msg := memberInviteError(inviteDto, userOrg.Role); msg != "" {
+			rowErrors = append(rowErrors, msg)
+			continue
+		}
+		canWrite, err := hs.canWriteOrgUser(c, usr.ID)
+		if err != nil {
+			return nil, response.Error(http.StatusInternalServerError, "Failed to evaluate permissions", err)
+		}
+		if !canWrite {
+			rowErrors = append(rowErrors, "Permission denied: not permitted to change the role of members of this organisation")
+		}
+	}
+	return rowErrors, nil
+}
+
+// responseMessage returns the message of an error response.
+func responseMessage(rsp response.Response) string {
+	var body struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(rsp.Body(), &body); err != nil || body.Message == "" {
+		return http.StatusText(rsp.Status())
+	}
+	return body.Message
+}
+
+// swagger:parameters validateOrgInvites
+type ValidateOrgInvitesParams struct {
+	// in:body
+	// required:true
+	Body dtos.ValidateInvitesForm `json:"body"`
+}
+
+// swagger:response validateOrgInvitesResponse
+type ValidateOrgInvitesResponse struct {
+	// in: body
+	Body dtos.InvitesValidation `json:"body"`
+}
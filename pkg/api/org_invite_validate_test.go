@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/services/user/usertest"
+)
+
+type validateInvitesUserService struct {
+	*usertest.FakeUserService
+	users map[string]*user.User
+}
+
+func (s *validateInvitesUserService) GetByLogin(ctx context.Context, query *user.GetUserByLoginQuery) (*user.User, error) {
+	if usr, ok := s.users[query.LoginOrEmail]; ok {
+		return usr, nil
+	}
+	return nil, user.ErrUserNotFound
+}
+
+func TestValidateOrgInvites(t *testing.T) {
+	sc, stack := setupInviteHTTPServer(t)
+	setInitCtxSignedInOrgAdmin(sc.initCtx)
+	setAccessControlPermissions(sc.acmock, []accesscontrol.Permission{
+		{Action: accesscontrol.ActionOrgUsersAdd, Scope: accesscontrol.ScopeUsersAll},
+	}, sc.initCtx.OrgID)
+
+	// the first user creates the organization
+	member, err := sc.db.CreateUser(context.Background(), user.CreateUserCommand{Login: "admin"})
+	require.NoError(t, err)
+	outsider, err := sc.db.CreateUser(context.Background(), user.CreateUserCommand{Email: "outsider@example.com", Login: "outsider", SkipOrgSetup: true})
+	require.NoError(t, err)
+	sc.hs.userService = &validateInvitesUserService{&usertest.FakeUserService{}, map[string]*user.User{
+		"admin":                member,
+		"outsider@example.com": outsider,
+	}}
+
+	stack.Invite("pending@example.com").WithCode("pending").Create(t)
+	pendingInvites := func() int {
+		return len(stack.Pending(t, 1))
+	}
+
+	validate := func(t *testing.T, body string) (int, dtos.InvitesValidation) {
+		response := callAPI(sc.server, http.MethodPost, "/api/org/invites/validate", strings.NewReader(body), t)
+		var validation dtos.InvitesValidation
+		if response.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(response.Body.Bytes(), &validation))
+		}
+		return response.Code, validation
+	}
+
+	t.Run("reports every row without creating invites", func(t *testing.T) {
+		code, validation := validate(t, `{"invites": [
+			{"loginOrEmail": "new@example.com", "role": "Viewer"},
+			{"loginOrEmail": " NEW@example.com", "role": "Viewer"},
+			{"loginOrEmail": "not an email", "role": "Viewer"},
+			{"loginOrEmail": "admin", "role": "Viewer"},
+			{"loginOrEmail": "outsider@example.com", "role": "Editor"},
+			{"loginOrEmail": "pending@example.com", "role": "Viewer"},
+			{"loginOrEmail": "superuser@example.com", "role": "Superuser"},
+			{"role": "Viewer"}
+		]}`)
+		require.Equal(t, http.StatusOK, code)
+		assert.False(t, validation.Valid)
+		require.Len(t, validation.Rows, 8)
+
+		errorsByRow := map[int][]string{}
+		for i, row := range validation.Rows {
+			assert.Equal(t, i+1, row.Row)
+			errorsByRow[row.Row] = row.Errors
+		}
+		assert.Equal(t, map[int][]string{
+			1: nil,
+			2: {"NEW@example.com is already invited by row 1"},
+			3: {"not an email is neither a user nor a valid email address"},
+			4: {"User admin is already a member of the organization with the Admin role, set allowDowngrade to change it to Viewer"},
+			5: nil,
+			6: nil,
+			7: {"Invalid invite: invalid role value: Superuser"},
+			8: {"Login or email is required"},
+		}, errorsByRow)
+
+		assert.True(t, validation.Rows[4].ExistingUser)
+		assert.Equal(t, org.RoleEditor, validation.Rows[4].Role)
+		assert.Equal(t, []string{"pending@example.com has a pending invite already, another one would be created"}, validation.Rows[5].Warnings)
+		assert.Equal(t, 1, pendingInvites())
+	})
+
+	t.Run("a batch without errors is valid", func(t *testing.T) {
+		code, validation := validate(t, `{"invites": [{"loginOrEmail": "new@example.com", "role": "Viewer"}]}`)
+		require.Equal(t, http.StatusOK, code)
+		assert.True(t, validation.Valid)
+	})
+
+	t.Run("batches must not be empty or too large", func(t *testing.T) {
+		code, _ := validate(t, `{"invites": []}`)
+		assert.Equal(t, http.StatusBadRequest, code)
+
+		rows := strings.Repeat(`{"loginOrEmail": "new@example.com"},`, dtos.MaxValidatedInvites)
+		code, _ = validate(t, `{"invites": [`+rows+`{"loginOrEmail": "new@example.com"}]}`)
+		assert.Equal(t, http.StatusBadRequest, code)
+	})
+}
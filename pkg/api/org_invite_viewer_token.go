@@ -0,0 +1,153 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/models"
+	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/util"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+// swagger:route POST /org/invites/share/viewer-token org_invites shareViewerToken
+//
+// Share a dashboard or folder by email for a few days.
+//
+// Creates a viewer token, an invite which can only be accepted with the invited email. The invitee
+// joins the organization as a viewer and is given the View permission on the dashboard or folder.
+// The membership is removed once the days are over, the token can't be used anymore then either.
+// Members of the organization can't be sent viewer tokens. Creating them requires the
+// `org.users:add` permission on top of the admin permission on the dashboard or folder. Viewer
+// tokens count towards the invite rate limits.
+//
+// Responses:
+// 200: shareViewerTokenResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 409: conflictError
+// 412: SMTPNotEnabledError
+// 429: tooManyRequestsError
+// 500: internalServerError
+func (hs *HTTPServer) ShareViewerToken(c *models.ReqContext) response.Response {
+	form := dtos.ShareViewerTokenForm{}
+	if err := web.Bind(c.Req, &form); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	form.Email = strings.TrimSpace(form.Email)
+	if !util.IsEmail(form.Email) {
+		return response.Error(http.StatusBadRequest, "Invalid email", nil)
+	}
+	if form.ResourceKind != models.TempUserGrantDashboard && form.ResourceKind != models.TempUserGrantFolder {
+		return response.Error(http.StatusBadRequest, "Invalid resource kind, must be dashboard or folder", nil)
+	}
+	if form.Days < 1 || form.Days > hs.Cfg.ViewerTokenMaxDays {
+		return response.Error(http.StatusBadRequest, fmt.Sprintf("Invalid days, must be between 1 and %d", hs.Cfg.ViewerTokenMaxDays), nil)
+	}
+
+	dash, rsp := hs.getSharedResource(c, form.ResourceKind, form.ResourceUID)
+	if rsp != nil {
+		return rsp
+	}
+
+	hasAccess, err := hs.AccessControl.Evaluate(c.Req.Context(), c.SignedInUser, ac.EvalPermission(ac.ActionOrgUsersAdd))
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to evaluate permissions", err)
+	}
+	if !hasAccess {
+		return response.Error(http.StatusForbidden, "Permission denied: not permitted to invite people to this organisation", nil)
+	}
+
+	usr, err := hs.userService.GetByLogin(c.Req.Context(), &user.GetUserByLoginQuery{LoginOrEmail: form.Email})
+	if err != nil && !errors.Is(err, user.ErrUserNotFound) {
+		return response.Error(http.StatusInternalServerError, "Failed to query db for existing user check", err)
+	}
+	if usr != nil {
+		member, err := hs.isOrgMember(c.Req.Context(), c.OrgID, usr.ID)
+		if err != nil {
+			return response.Error(http.StatusInternalServerError, "Failed to get user organizations", err)
+		}
+		if member {
+			return response.Error(http.StatusConflict, fmt.Sprintf("%s is already a member of the organization, share %s with them instead", form.Email, dash.Title), nil)
+		}
+	} else if err := hs.invitePolicy(c.Req.Context(), c.OrgID, form.Email).canInviteNewUsers(); err != nil {
+		return response.Error(http.StatusBadRequest, err.Error(), nil)
+	}
+
+	name := form.Name
+	if usr != nil {
+		name = usr.Name
+	}
+	accessExpires := time.Now().AddDate(0, 0, form.Days)
+	limit, rsp := hs.checkInviteRateLimit(c.Req.Context(), c.OrgID, inviteRateLimitInviter(c), 1)
+	if rsp != nil {
+		return rsp
+	}
+	cmd, rsp := hs.createNewUserInvite(c, &dtos.AddInviteForm{
+		LoginOrEmail:  form.Email,
+		Name:          name,
+		Role:          org.RoleViewer,
+		Delivery:      models.InviteDeliveryEmail,
+		EmailMatch:    models.InviteEmailMatchExact,
+		AccessExpires: &accessExpires,
+	})
+	if rsp != nil {
+		return rsp
+	}
+	hs.countInvites(c.Req.Context(), limit, 1)
+
+	grantCmd := models.AddTempUserGrantCommand{
+		OrgID:        c.OrgID,
+		TempUserID:   cmd.Result.Id,
+		ResourceKind: form.ResourceKind,
+		ResourceUID:  dash.Uid,
+		Permission:   models.PERMISSION_VIEW.String(),
+	}
+	if err := hs.tempUserService.AddTempUserGrant(c.Req.Context(), &grantCmd); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to save invite permission", err)
+	}
+
+	if form.SendEmail {
+		if usr != nil {
+			_, rsp = hs.sendExistingUserInviteEmail(c, usr, cmd.Result.Code)
+		} else {
+			_, rsp = hs.sendNewUserInviteEmail(c, cmd.Result.Email, cmd.Result.Name, cmd.Result.Code)
+		}
+		if rsp != nil {
+			return rsp
+		}
+	}
+
+	return response.JSON(http.StatusOK, util.DynMap{
+		"message":       fmt.Sprintf("Invited %s to view %s for %d days", form.Email, dash.Title, form.Days),
+		"inviteId":      cmd.Result.Id,
+		"accessExpires": accessExpires,
+	})
+}
+
+// swagger:parameters shareViewerToken
+type ShareViewerTokenParams struct {
+	// in:body
+	// required:true
+	Body dtos.ShareViewerTokenForm `json:"body"`
+}
+
+// swagger:response shareViewerTokenResponse
+type ShareViewerTokenResponse struct {
+	// in:body
+	Body struct {
+		Message  string `json:"message"`
+		InviteID int64  `json:"inviteId"`
+		// AccessExpires is when the membership given by the token ends
+		AccessExpires time.Time `json:"accessExpires"`
+	} `json:"body"`
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	accesscontrolmock "github.com/grafana/grafana/pkg/services/accesscontrol/mock"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/guardian"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/services/user/usertest"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestShareViewerToken(t *testing.T) {
+	type tokenResponse struct {
+		InviteID      int64     `json:"inviteId"`
+		AccessExpires time.Time `json:"accessExpires"`
+	}
+
+	setup := func(t *testing.T, perms ...accesscontrol.Permission) (accessControlScenarioContext, *accesscontrolmock.MockPermissionsService) {
+		cfg := setting.NewCfg()
+		cfg.RBACEnabled = true
+		cfg.ViewerTokenMaxDays = 30
+		sc, _ := setupInviteHTTPServerWithCfg(t, cfg)
+		sc.hs.userService = &slackInviteUserService{&usertest.FakeUserService{}}
+
+		dashSvc := dashboards.NewFakeDashboardService(t)
+		dashSvc.On("GetDashboard", mock.Anything, mock.AnythingOfType("*models.GetDashboardQuery")).Run(func(args mock.Arguments) {
+			q := args.Get(1).(*models.GetDashboardQuery)
+			q.Result = &models.Dashboard{Id: 1, Uid: "dash", OrgId: 1, Title: "Dash"}
+		}).Return(nil).Maybe()
+		sc.hs.DashboardService = dashSvc
+		dashboardPermissions := accesscontrolmock.NewMockedPermissionsService()
+		sc.hs.dashboardPermissionsService = dashboardPermissions
+
+		origNewGuardian := guardian.New
+		t.Cleanup(func() { guardian.New = origNewGuardian })
+		guardian.MockDashboardGuardian(&guardian.FakeDashboardGuardian{CanAdminValue: true})
+
+		setInitCtxSignedInOrgAdmin(sc.initCtx)
+		perms = append(perms, accesscontrol.Permission{Action: dashboards.ActionDashboardsPermissionsWrite, Scope: dashboards.ScopeDashboardsAll})
+		setAccessControlPermissions(sc.acmock, perms, sc.initCtx.OrgID)
+		return sc, dashboardPermissions
+	}
+
+	createToken := func(t *testing.T, sc accessControlScenarioContext, body string) (int, tokenResponse) {
+		response := callAPI(sc.server, http.MethodPost, "/api/org/invites/share/viewer-token", strings.NewReader(body), t)
+		var rsp tokenResponse
+		if response.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(response.Body.Bytes(), &rsp))
+		}
+		return response.Code, rsp
+	}
+
+	t.Run("creates a viewer invite for the email which ends after the days", func(t *testing.T) {
+		sc, dashboardPermissions := setup(t, accesscontrol.Permission{Action: accesscontrol.ActionOrgUsersAdd})
+
+		code, rsp := createToken(t, sc, `{"email": "external@example.com", "resourceKind": "dashboard", "resourceUid": "dash", "days": 7}`)
+		require.Equal(t, http.StatusOK, code)
+		assert.WithinDuration(t, time.Now().AddDate(0, 0, 7), rsp.AccessExpires, time.Minute)
+
+		invite := models.GetTempUserByIDQuery{OrgID: 1, ID: rsp.InviteID}
+		require.NoError(t, sc.hs.tempUserService.GetTempUserByID(context.Background(), &invite))
+		assert.Equal(t, org.RoleViewer, invite.Result.Role)
+		assert.Equal(t, models.InviteEmailMatchExact, invite.Result.EmailMatch)
+		require.NotNil(t, invite.Result.AccessExpires)
+
+		grants := models.GetTempUserGrantsQuery{TempUserID: rsp.InviteID}
+		require.NoError(t, sc.hs.tempUserService.GetTempUserGrants(context.Background(), &grants))
+		require.Len(t, grants.Result, 1)
+		assert.Equal(t, "View", grants.Result[0].Permission)
+
+		t.Run("accepting it records the membership to end", func(t *testing.T) {
+			// the first user creates the organization
+			_, err := sc.db.CreateUser(context.Background(), user.CreateUserCommand{Login: "admin"})
+			require.NoError(t, err)
+			usr, err := sc.db.CreateUser(context.Background(), user.CreateUserCommand{Email: "external@example.com", Login: "external", SkipOrgSetup: true})
+			require.NoError(t, err)
+			dashboardPermissions.On("SetUserPermission", mock.Anything, int64(1), accesscontrol.User{ID: usr.ID}, "dash", "View").
+				Return(&accesscontrol.ResourcePermission{}, nil).Once()
+			ok, applyRsp := sc.hs.applyUserInvite(context.Background(), usr, invite.Result, false, nil)
+			require.True(t, ok, "%v", applyRsp)
+
+			expired := models.GetExpiredTempUserAccessQuery{Now: rsp.AccessExpires.Add(time.Minute)}
+			require.NoError(t, sc.hs.tempUserService.GetExpiredTempUserAccess(context.Background(), &expired))
+			require.Len(t, expired.Result, 1)
+			assert.Equal(t, usr.ID, expired.Result[0].AccessUserId)
+			dashboardPermissions.AssertExpectations(t)
+		})
+	})
+
+	t.Run("ended tokens can't be accepted", func(t *testing.T) {
+		sc, _ := setup(t)
+		ended := time.Now().Add(-time.Minute)
+		ok, rsp := sc.hs.applyUserInvite(context.Background(), &user.User{ID: 42}, &models.TempUserDTO{OrgId: 1, Code: "ended", AccessExpires: &ended}, false, nil)
+		require.False(t, ok)
+		assert.Equal(t, http.StatusNotFound, rsp.Status())
+	})
+
+	t.Run("members can't be sent viewer tokens", func(t *testing.T) {
+		sc, _ := setup(t, accesscontrol.Permission{Action: accesscontrol.ActionOrgUsersAdd})
+		member, err := sc.db.CreateUser(context.Background(), user.CreateUserCommand{Email: "member@example.com", Login: "member"})
+		require.NoError(t, err)
+		sc.hs.userService = &usertest.FakeUserService{ExpectedUser: member}
+
+		code, _ := createToken(t, sc, `{"email": "member@example.com", "resourceKind": "dashboard", "resourceUid": "dash", "days": 7}`)
+		assert.Equal(t, http.StatusConflict, code)
+	})
+
+	t.Run("requires the permission to invite", func(t *testing.T) {
+		sc, _ := setup(t)
+
+		code, _ := createToken(t, sc, `{"email": "external@example.com", "resourceKind": "dashboard", "resourceUid": "dash", "days": 7}`)
+		assert.Equal(t, http.StatusForbidden, code)
+	})
+
+	t.Run("validates the days", func(t *testing.T) {
+		sc, _ := setup(t, accesscontrol.Permission{Action: accesscontrol.ActionOrgUsersAdd})
+
+		code, _ := createToken(t, sc, `{"email": "external@example.com", "resourceKind": "dashboard", "resourceUid": "dash", "days": 31}`)
+		assert.Equal(t, http.StatusBadRequest, code)
+		code, _ = createToken(t, sc, `{"email": "external@example.com", "resourceKind": "dashboard", "resourceUid": "dash", "days": -1}`)
+		assert.Equal(t, http.StatusBadRequest, code)
+	})
+}
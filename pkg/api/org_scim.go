@@ -0,0 +1,704 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/util"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+// The SCIM endpoints map the Users resource of an identity provider to the
+// members of the current organization. Users that already exist in Grafana are
+// added to the organization directly, other users are invited. Deactivating or
+// deleting a SCIM user removes it from the organization or revokes its invite.
+// Org members are identified by their user ID, pending invites by
+// scimInviteIDPrefix followed by the invite ID. Invite codes are never exposed,
+// listing SCIM users only requires reading the members of the organization.
+
+const (
+	scimContentType     = "application/scim+json"
+	scimInviteIDPrefix  = "invite-"
+	scimDefaultPageSize = 100
+	scimMaxPageSize     = 1000
+)
+
+var scimUserNameFilter = regexp.MustCompile(`(?i)^\s*(userName|emails(?:\.value)?)\s+eq\s+"([^"]*)"\s*$`)
+
+// swagger:route GET /org/scim/v2/Users org_scim getSCIMUsers
+//
+// List SCIM users.
+//
+// Lists the members and pending invites of the current organization as SCIM 2.0 User resources.
+// Supports the `userName eq "value"` and `emails eq "value"` filters and paging with `startIndex` and `count`.
+//
+// Responses:
+// 200: getSCIMUsersResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (hs *HTTPServer) GetSCIMUsers(c *models.ReqContext) response.Response {
+	var filterValue string
+	if filter := c.Query("filter"); filter != "" {
+		matches := scimUserNameFilter.FindStringSubmatch(filter)
+		if matches == nil {
+			return hs.scimError(http.StatusBadRequest, "invalidFilter", "Only userName and emails equality filters are supported", nil)
+		}
+		filterValue = matches[2]
+	}
+
+	startIndex := c.QueryInt("startIndex")
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	count := scimDefaultPageSize
+	if c.Query("count") != "" {
+		count = c.QueryInt("count")
+	}
+	if count < 0 {
+		count = 0
+	}
+	if count > scimMaxPageSize {
+		count = scimMaxPageSize
+	}
+
+	membersQuery := models.GetOrgUsersQuery{OrgId: c.OrgID, User: c.SignedInUser}
+	if err := hs.SQLStore.GetOrgUsers(c.Req.Context(), &membersQuery); err != nil {
+		return hs.scimError(http.StatusInternalServerError, "", "Failed to get organization users", err)
+	}
+	invitesQuery := models.GetTempUsersQuery{OrgId: c.OrgID, Status: models.TmpUserInvitePending}
+	if err := hs.tempUserService.GetTempUsersQuery(c.Req.Context(), &invitesQuery); err != nil {
+		return hs.scimError(http.StatusInternalServerError, "", "Failed to get invites", err)
+	}
+
+	resources := make([]dtos.SCIMUser, 0, len(membersQuery.Result)+len(invitesQuery.Result))
+	for _, member := range membersQuery.Result {
+		if filterValue == "" || strings.EqualFold(member.Login, filterValue) || strings.EqualFold(member.Email, filterValue) {
+			resources = append(resources, orgMemberToSCIMUser(member, true))
+		}
+	}
+	for _, invite := range invitesQuery.Result {
+		if filterValue == "" || strings.EqualFold(invite.Email, filterValue) {
+			resources = append(resources, inviteToSCIMUser(invite))
+		}
+	}
+
+	total := len(resources)
+	from := startIndex - 1
+	if from > total {
+		from = total
+	}
+	to := from + count
+	if to > total {
+		to = total
+	}
+
+	return scimResponse(http.StatusOK, dtos.SCIMListResponse{
+		Schemas:      []string{dtos.SCIMListResponseSchema},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: to - from,
+		Resources:    resources[from:to],
+	})
+}
+
+// swagger:route GET /org/scim/v2/Users/{scim_user_id} org_scim getSCIMUser
+//
+// Get a SCIM user.
+//
+// Responses:
+// 200: getSCIMUserResponse
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (hs *HTTPServer) GetSCIMUser(c *models.ReqContext) response.Response {
+	member, invite, rsp := hs.getSCIMResource(c, web.Params(c.Req)[":id"])
+	if rsp != nil {
+		return rsp
+	}
+	if member != nil {
+		return scimResponse(http.StatusOK, orgMemberToSCIMUser(member, true))
+	}
+	return scimResponse(http.StatusOK, inviteToSCIMUser(invite))
+}
+
+// swagger:route POST /org/scim/v2/Users org_scim createSCIMUser
+//
+// Provision a SCIM user.
+//
+// SCIM users are invited like with the invite API: the invite defaults, role limits and rate limits
+// of invites apply. Users that already exist in Grafana join the current organization with their
+// invite right away. Other users are invited to it; the invite email is sent when SMTP is enabled,
+// and is left pending when it can't be sent. The organization role is taken from the primary
+// entry of `roles` and defaults to the default invite role of the organization, or Viewer.
+//
+// Responses:
+// 201: getSCIMUserResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 409: conflictError
+// 429: tooManyRequestsError
+// 500: internalServerError
+func (hs *HTTPServer) CreateSCIMUser(c *models.ReqContext) response.Response {
+	form := dtos.SCIMUser{}
+	if err := bindSCIM(c.Req, &form); err != nil {
+		return hs.scimError(http.StatusBadRequest, "invalidSyntax", "Bad request data", err)
+	}
+	if form.UserName == "" {
+		return hs.scimError(http.StatusBadRequest, "invalidValue", "userName is required", nil)
+	}
+	if form.Active != nil && !*form.Active {
+		return hs.scimError(http.StatusBadRequest, "invalidValue", "Inactive users cannot be provisioned", nil)
+	}
+
+	role, rsp := hs.scimRole(c, form.Roles)
+	if rsp != nil {
+		return rsp
+	}
+	if role == "" {
+		defaults, err := hs.inviteDefaults(c.Req.Context(), c.OrgID)
+		if err != nil {
+			return hs.scimError(http.StatusInternalServerError, "", "Failed to get the invite defaults", err)
+		}
+		role = defaults.DefaultRole
+		if role == "" {
+			role = org.RoleViewer
+		}
+	}
+	email := scimPrimaryValue(form.Emails)
+	if email == "" {
+		email = form.UserName
+	}
+
+	usr, err := hs.findSCIMUser(c.Req.Context(), form.UserName, email)
+	if err != nil {
+		return hs.scimError(http.StatusInternalServerError, "", "Failed to query db for existing user check", err)
+	}
+	inviteForm := dtos.AddInviteForm{LoginOrEmail: email, Name: scimDisplayName(form), Role: role}
+	if usr != nil {
+		inviteForm.LoginOrEmail = util.StringsFallback2(usr.Login, usr.Email)
+	} else {
+		if !util.IsEmail(email) {
+			return hs.scimError(http.StatusBadRequest, "invalidValue", "An email address is required to invite a new user", nil)
+		}
+		// SCIM users are unique, whether or not pending invites are
+		invite, err := hs.getSCIMPendingInvite(c.Req.Context(), c.OrgID, email)
+		if err != nil {
+			return hs.scimError(http.StatusInternalServerError, "", "Failed to get invites", err)
+		}
+		if invite != nil {
+			return hs.scimError(http.StatusConflict, "uniqueness", fmt.Sprintf("User %s has already been invited to organization", email), nil)
+		}
+	}
+
+	// SCIM users are invited like any other, the email is sent once the invite is saved
+	limit, rsp := hs.checkInviteRateLimit(c.Req.Context(), c.OrgID, inviteRateLimitInviter(c), 1)
+	if rsp != nil {
+		return hs.scimErrorFromResponse(rsp)
+	}
+	if rsp := hs.createOrgInvite(c, inviteForm); rsp.Status() != http.StatusOK {
+		return hs.scimErrorFromResponse(rsp)
+	}
+	hs.countInvites(c.Req.Context(), limit, 1)
+
+	if usr != nil {
+		return hs.addSCIMUserToOrg(c, usr)
+	}
+
+	invite, err := hs.getSCIMPendingInvite(c.Req.Context(), c.OrgID, email)
+	if err != nil || invite == nil {
+		return hs.scimError(http.StatusInternalServerError, "", "Failed to get invite", err)
+	}
+	if hs.Cfg.Smtp.Enabled {
+		// the invite exists, failing would make the identity provider retry into a conflict
+		if _, rsp := hs.sendNewUserInviteEmail(c, invite.Email, invite.Name, invite.Code); rsp != nil {
+			hs.log.FromContext(c.Req.Context()).Warn("Failed to send the invite email of a SCIM user, the email is pending", "inviteId", invite.Id, "message", responseMessage(rsp))
+		}
+	}
+
+	return scimResponse(http.StatusCreated, inviteToSCIMUser(invite))
+}
+
+// swagger:route PUT /org/scim/v2/Users/{scim_user_id} org_scim replaceSCIMUser
+//
+// Replace a SCIM user.
+//
+// Setting `active` to false removes the user from the current organization or revokes its invite.
+// The organization role of members is updated from `roles`.
+//
+// Responses:
+// 200: getSCIMUserResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (hs *HTTPServer) ReplaceSCIMUser(c *models.ReqContext) response.Response {
+	form := dtos.SCIMUser{}
+	if err := bindSCIM(c.Req, &form); err != nil {
+		return hs.scimError(http.StatusBadRequest, "invalidSyntax", "Bad request data", err)
+	}
+
+	return hs.updateSCIMUser(c, web.Params(c.Req)[":id"], form.Active, form.Roles)
+}
+
+// swagger:route PATCH /org/scim/v2/Users/{scim_user_id} org_scim patchSCIMUser
+//
+// Patch a SCIM user.
+//
+// Supports `add` and `replace` operations on the `active` and `roles` attributes.
+// Operations on other attributes are ignored.
+//
+// Responses:
+// 200: getSCIMUserResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (hs *HTTPServer) PatchSCIMUser(c *models.ReqContext) response.Response {
+	form := dtos.SCIMPatchRequest{}
+	if err := bindSCIM(c.Req, &form); err != nil {
+		return hs.scimError(http.StatusBadRequest, "invalidSyntax", "Bad request data", err)
+	}
+
+	var active *bool
+	var roles []dtos.SCIMValue
+	for _, op := range form.Operations {
+		switch strings.ToLower(op.Op) {
+		case "add", "replace":
+		default:
+			return hs.scimError(http.StatusBadRequest, "invalidSyntax", fmt.Sprintf("Unsupported patch operation %q", op.Op), nil)
+		}
+
+		var err error
+		switch strings.ToLower(op.Path) {
+		case "active":
+			err = json.Unmarshal(op.Value, &active)
+		case "roles":
+			err = json.Unmarshal(op.Value, &roles)
+		case "":
+			patch := dtos.SCIMUser{}
+			if err = json.Unmarshal(op.Value, &patch); err == nil {
+				if patch.Active != nil {
+					active = patch.Active
+				}
+				if patch.Roles != nil {
+					roles = patch.Roles
+				}
+			}
+		}
+		if err != nil {
+			return hs.scimError(http.StatusBadRequest, "invalidValue", fmt.Sprintf("Invalid value for %q", op.Path), err)
+		}
+	}
+
+	return hs.updateSCIMUser(c, web.Params(c.Req)[":id"], active, roles)
+}
+
+// swagger:route DELETE /org/scim/v2/Users/{scim_user_id} org_scim deleteSCIMUser
+//
+// Delete a SCIM user.
+//
+// Removes the user from the current organization or revokes its invite. The Grafana user itself is kept.
+//
+// Responses:
+// 204: noContentResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (hs *HTTPServer) DeleteSCIMUser(c *models.ReqContext) response.Response {
+	member, invite, rsp := hs.getSCIMResource(c, web.Params(c.Req)[":id"])
+	if rsp != nil {
+		return rsp
+	}
+	if rsp := hs.deactivateSCIMUser(c, member, invite); rsp != nil {
+		return rsp
+	}
+	return response.Empty(http.StatusNoContent)
+}
+
+func (hs *HTTPServer) updateSCIMUser(c *models.ReqContext, id string, active *bool, roles []dtos.SCIMValue) response.Response {
+	member, invite, rsp := hs.getSCIMResource(c, id)
+	if rsp != nil {
+		return rsp
+	}
+
+	if active != nil && !*active {
+		if rsp := hs.deactivateSCIMUser(c, member, invite); rsp != nil {
+			return rsp
+		}
+		if member != nil {
+			return scimResponse(http.StatusOK, orgMemberToSCIMUser(member, false))
+		}
+		res := inviteToSCIMUser(invite)
+		inactive := false
+		res.Active = &inactive
+		return scimResponse(http.StatusOK, res)
+	}
+
+	role, rsp := hs.scimRole(c, roles)
+	if rsp != nil {
+		return rsp
+	}
+	if member == nil {
+		// the role of a pending invite can't be changed, it is applied once the invite is accepted
+		return scimResponse(http.StatusOK, inviteToSCIMUser(invite))
+	}
+
+	if role != "" && string(role) != member.Role {
+		cmd := models.UpdateOrgUserCommand{OrgId: c.OrgID, UserId: member.UserId, Role: role}
+		if err := hs.SQLStore.UpdateOrgUser(c.Req.Context(), &cmd); err != nil {
+			if errors.Is(err, models.ErrLastOrgAdmin) {
+				return hs.scimError(http.StatusBadRequest, "mutability", "Cannot change role so that there is no organization admin left", nil)
+			}
+			return hs.scimError(http.StatusInternalServerError, "", "Failed to update org user", err)
+		}
+		member.Role = string(role)
+	}
+
+	return scimResponse(http.StatusOK, orgMemberToSCIMUser(member, true))
+}
+
+func (hs *HTTPServer) deactivateSCIMUser(c *models.ReqContext, member *models.OrgUserDTO, invite *models.TempUserDTO) response.Response {
+	if invite != nil {
+		if ok, rsp := hs.updateTempUserStatus(c.Req.Context(), invite.Code, models.TmpUserRevoked); !ok {
+			return hs.scimError(rsp.Status(), "", "Failed to revoke invite", nil)
+		}
+		return nil
+	}
+
+	cmd := models.RemoveOrgUserCommand{OrgId: c.OrgID, UserId: member.UserId}
+	if err := hs.SQLStore.RemoveOrgUser(c.Req.Context(), &cmd); err != nil {
+		if errors.Is(err, models.ErrLastOrgAdmin) {
+			return hs.scimError(http.StatusBadRequest, "mutability", "Cannot remove last organization admin", nil)
+		}
+		return hs.scimError(http.StatusInternalServerError, "", "Failed to remove user from organization", err)
+	}
+	if err := hs.accesscontrolService.DeleteUserPermissions(c.Req.Context(), c.OrgID, member.UserId); err != nil {
+		hs.log.Warn("failed to delete permissions for user", "userID", member.UserId, "orgID", c.OrgID, "err", err)
+	}
+	return nil
+}
+
+// addSCIMUserToOrg adds an existing user, whose invite was just created, to the organization. The
+// identity provider provisioning the user stands for the user accepting the invite.
+func (hs *HTTPServer) addSCIMUserToOrg(c *models.ReqContext, usr *user.User) response.Response {
+	member, err := hs.getSCIMOrgMember(c.Req.Context(), c.OrgID, usr.ID)
+	if err != nil {
+		return hs.scimError(http.StatusInternalServerError, "", "Failed to get organization user", err)
+	}
+	// users of verified domains with auto-approval joined with the invite already
+	if member == nil {
+		invite, err := hs.getSCIMPendingInvite(c.Req.Context(), c.OrgID, util.StringsFallback2(usr.Email, usr.Login))
+		if err != nil || invite == nil {
+			return hs.scimError(http.StatusInternalServerError, "", "Failed to get invite", err)
+		}
+		if ok, rsp := hs.applyUserInvite(c.Req.Context(), usr, invite, false, hs.inviteCompletion(c)); !ok {
+			return hs.scimErrorFromResponse(rsp)
+		}
+		if member, err = hs.getSCIMOrgMember(c.Req.Context(), c.OrgID, usr.ID); err != nil || member == nil {
+			return hs.scimError(http.StatusInternalServerError, "", "Failed to get organization user", err)
+		}
+	}
+	return scimResponse(http.StatusCreated, orgMemberToSCIMUser(member, true))
+}
+
+// getSCIMPendingInvite returns the latest pending invite of the email in the organization, or nil.
+func (hs *HTTPServer) getSCIMPendingInvite(ctx context.Context, orgID int64, email string) (*models.TempUserDTO, error) {
+	query := models.GetTempUsersQuery{OrgId: orgID, Email: email, Status: models.TmpUserInvitePending}
+	if err := hs.tempUserService.GetTempUsersQuery(ctx, &query); err != nil {
+		return nil, err
+	}
+	var invite *models.TempUserDTO
+	for _, pending := range query.Result {
+		if invite == nil || pending.Id > invite.Id {
+			invite = pending
+		}
+	}
+	return invite, nil
+}
+
+// getSCIMResource resolves a SCIM user ID to either an org member or a pending invite.
+// Invites that have been accepted since they were provisioned resolve to the resulting member.
+func (hs *HTTPServer) getSCIMResource(c *models.ReqContext, id string) (*models.OrgUserDTO, *models.TempUserDTO, response.Response) {
+	notFound := hs.scimError(http.StatusNotFound, "", fmt.Sprintf("User %s not found", id), nil)
+
+	if inviteID := strings.TrimPrefix(id, scimInviteIDPrefix); inviteID != id {
+		parsedID, err := strconv.ParseInt(inviteID, 10, 64)
+		if err != nil {
+			return nil, nil, notFound
+		}
+		query := models.GetTempUserByIDQuery{OrgID: c.OrgID, ID: parsedID}
+		if err := hs.tempUserService.GetTempUserByID(c.Req.Context(), &query); err != nil {
+			if errors.Is(err, models.ErrTempUserNotFound) {
+				return nil, nil, notFound
+			}
+			return nil, nil, hs.scimError(http.StatusInternalServerError, "", "Failed to get invite", err)
+		}
+
+		invite := query.Result
+		switch invite.Status {
+		case models.TmpUserInvitePending:
+			return nil, invite, nil
+		case models.TmpUserCompleted:
+			usr, err := hs.findSCIMUser(c.Req.Context(), invite.Email, invite.Email)
+			if err != nil {
+				return nil, nil, hs.scimError(http.StatusInternalServerError, "", "Failed to get user", err)
+			}
+			if usr == nil {
+				return nil, nil, notFound
+			}
+			id = strconv.FormatInt(usr.ID, 10)
+		default:
+			return nil, nil, notFound
+		}
+	}
+
+	userID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, nil, notFound
+	}
+	member, err := hs.getSCIMOrgMember(c.Req.Context(), c.OrgID, userID)
+	if err != nil {
+		return nil, nil, hs.scimError(http.StatusInternalServerError, "", "Failed to get organization user", err)
+	}
+	if member == nil {
+		return nil, nil, notFound
+	}
+	return member, nil, nil
+}
+
+func (hs *HTTPServer) getSCIMOrgMember(ctx context.Context, orgID int64, userID int64) (*models.OrgUserDTO, error) {
+	query := models.GetOrgUsersQuery{OrgId: orgID, UserID: userID, DontEnforceAccessControl: true}
+	if err := hs.SQLStore.GetOrgUsers(ctx, &query); err != nil {
+		return nil, err
+	}
+	if len(query.Result) == 0 {
+		return nil, nil
+	}
+	return query.Result[0], nil
+}
+
+// findSCIMUser looks up an existing Grafana user by user name, then by email.
+// It returns nil if there is no such user.
+func (hs *HTTPServer) findSCIMUser(ctx context.Context, userName string, email string) (*user.User, error) {
+	for _, loginOrEmail := range []string{userName, email} {
+		usr, err := hs.userService.GetByLogin(ctx, &user.GetUserByLoginQuery{LoginOrEmail: loginOrEmail})
+		if err == nil {
+			return usr, nil
+		}
+		if !errors.Is(err, user.ErrUserNotFound) {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// scimRole returns the organization role from the primary SCIM role, or an
+// empty role if none is given.
+func (hs *HTTPServer) scimRole(c *models.ReqContext, roles []dtos.SCIMValue) (org.RoleType, response.Response) {
+	value := scimPrimaryValue(roles)
+	if value == "" {
+		return "", nil
+	}
+
+	role := org.RoleType(value)
+	if !role.IsValid() {
+		return "", hs.scimError(http.StatusBadRequest, "invalidValue", fmt.Sprintf("Invalid role %s", value), nil)
+	}
+	if !c.OrgRole.Includes(role) && !c.IsGrafanaAdmin {
+		return "", hs.scimError(http.StatusForbidden, "", "Cannot assign a role higher than user's role", nil)
+	}
+	return role, nil
+}
+
+func (hs *HTTPServer) scimError(status int, scimType string, detail string, err error) response.Response {
+	if err != nil && status >= http.StatusInternalServerError {
+		hs.log.Error(detail, "error", err)
+	}
+	return scimResponse(status, dtos.SCIMError{
+		Schemas:  []string{dtos.SCIMErrorSchema},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	})
+}
+
+// scimErrorFromResponse turns an error response of the invite API into a SCIM error. Invites
+// conflicting with a pending invite or a membership are uniqueness errors.
+func (hs *HTTPServer) scimErrorFromResponse(rsp response.Response) response.Response {
+	status, scimType := rsp.Status(), ""
+	if status == http.StatusPreconditionFailed {
+		status, scimType = http.StatusConflict, "uniqueness"
+	}
+	res := hs.scimError(status, scimType, responseMessage(rsp), nil)
+	if normal, ok := rsp.(*response.NormalResponse); ok {
+		if retryAfter := normal.Header().Get("Retry-After"); retryAfter != "" {
+			res.(*response.NormalResponse).SetHeader("Retry-After", retryAfter)
+		}
+	}
+	return res
+}
+
+// bindSCIM binds a JSON request body, which SCIM clients send as application/scim+json.
+func bindSCIM(req *http.Request, v interface{}) error {
+	if m, _, err := mime.ParseMediaType(req.Header.Get("Content-Type")); err == nil && m == scimContentType {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return web.Bind(req, v)
+}
+
+func scimResponse(status int, body interface{}) response.Response {
+	return response.JSON(status, body).SetHeader("Content-Type", scimContentType)
+}
+
+func scimPrimaryValue(values []dtos.SCIMValue) string {
+	for _, v := range values {
+		if v.Primary {
+			return v.Value
+		}
+	}
+	if len(values) > 0 {
+		return values[0].Value
+	}
+	return ""
+}
+
+func scimDisplayName(u dtos.SCIMUser) string {
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+	if u.Name != nil {
+		if u.Name.Formatted != "" {
+			return u.Name.Formatted
+		}
+		return strings.TrimSpace(u.Name.GivenName + " " + u.Name.FamilyName)
+	}
+	return ""
+}
+
+func scimUserLocation(id string) string {
+	return setting.ToAbsUrl("api/org/scim/v2/Users/" + id)
+}
+
+func orgMemberToSCIMUser(member *models.OrgUserDTO, active bool) dtos.SCIMUser {
+	id := strconv.FormatInt(member.UserId, 10)
+	created, updated := member.Created, member.Updated
+	res := dtos.SCIMUser{
+		Schemas:     []string{dtos.SCIMUserSchema},
+		ID:          id,
+		UserName:    member.Login,
+		DisplayName: member.Name,
+		Roles:       []dtos.SCIMValue{{Value: member.Role, Primary: true}},
+		Active:      &active,
+		Meta: &dtos.SCIMMeta{
+			ResourceType: "User",
+			Created:      &created,
+			LastModified: &updated,
+			Location:     scimUserLocation(id),
+		},
+	}
+	if member.Name != "" {
+		res.Name = &dtos.SCIMName{Formatted: member.Name}
+	}
+	if member.Email != "" {
+		res.Emails = []dtos.SCIMValue{{Value: member.Email, Primary: true}}
+	}
+	return res
+}
+
+func inviteToSCIMUser(invite *models.TempUserDTO) dtos.SCIMUser {
+	id := scimInviteIDPrefix + strconv.FormatInt(invite.Id, 10)
+	active := invite.Status == models.TmpUserInvitePending
+	created := invite.Created
+	res := dtos.SCIMUser{
+		Schemas:     []string{dtos.SCIMUserSchema},
+		ID:          id,
+		UserName:    invite.Email,
+		DisplayName: invite.Name,
+		Emails:      []dtos.SCIMValue{{Value: invite.Email, Primary: true}},
+		Roles:       []dtos.SCIMValue{{Value: string(invite.Role), Primary: true}},
+		Active:      &active,
+		Meta: &dtos.SCIMMeta{
+			ResourceType: "User",
+			Created:      &created,
+			Location:     scimUserLocation(id),
+		},
+	}
+	if invite.Name != "" {
+		res.Name = &dtos.SCIMName{Formatted: invite.Name}
+	}
+	return res
+}
+
+// swagger:parameters getSCIMUser replaceSCIMUser patchSCIMUser deleteSCIMUser
+type SCIMUserParams struct {
+	// in:path
+	// required:true
+	ID string `json:"scim_user_id"`
+}
+
+// swagger:parameters getSCIMUsers
+type GetSCIMUsersParams struct {
+	// in:query
+	// required:false
+	Filter string `json:"filter"`
+	// in:query
+	// required:false
+	StartIndex int `json:"startIndex"`
+	// in:query
+	// required:false
+	Count int `json:"count"`
+}
+
+// swagger:parameters createSCIMUser
+type CreateSCIMUserParams struct {
+	// in:body
+	// required:true
+	Body dtos.SCIMUser `json:"body"`
+}
+
+// swagger:parameters replaceSCIMUser
+type ReplaceSCIMUserParams struct {
+	// in:body
+	// required:true
+	Body dtos.SCIMUser `json:"body"`
+}
+
+// swagger:parameters patchSCIMUser
+type PatchSCIMUserParams struct {
+	// in:body
+	// required:true
+	Body dtos.SCIMPatchRequest `json:"body"`
+}
+
+// swagger:response getSCIMUsersResponse
+type GetSCIMUsersResponse struct {
+	// in: body
+	Body dtos.SCIMListResponse `json:"body"`
+}
+
+// swagger:response getSCIMUserResponse
+type GetSCIMUserResponse struct {
+	// in: body
+	Body dtos.SCIMUser `json:"body"`
+}
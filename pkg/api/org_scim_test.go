@@ -0,0 +1,175 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/ngalert"
+	"github.com/grafana/grafana/pkg/services/notifications"
+	"github.com/grafana/grafana/pkg/services/org"
+	pref "github.com/grafana/grafana/pkg/services/preference"
+	"github.com/grafana/grafana/pkg/services/preference/preftest"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/services/user/usertest"
+)
+
+func TestSCIMUsersAPIEndpoints(t *testing.T) {
+	setup := func(t *testing.T, existingUser *user.User) accessControlScenarioContext {
+		sc, _ := setupInviteHTTPServer(t)
+		userService := usertest.NewUserServiceFake()
+		if existingUser != nil {
+			userService.ExpectedUser = existingUser
+		} else {
+			userService.ExpectedError = user.ErrUserNotFound
+		}
+		sc.hs.userService = userService
+		setupOrgUsersDBForAccessControlTests(t, sc.db)
+		setInitCtxSignedInUser(sc.initCtx, testServerAdminViewer)
+		setAccessControlPermissions(sc.acmock, []accesscontrol.Permission{
+			{Action: accesscontrol.ActionOrgUsersRead, Scope: accesscontrol.ScopeUsersAll},
+			{Action: accesscontrol.ActionOrgUsersAdd, Scope: accesscontrol.ScopeUsersAll},
+			{Action: accesscontrol.ActionOrgUsersWrite, Scope: accesscontrol.ScopeUsersAll},
+			{Action: accesscontrol.ActionOrgUsersRemove, Scope: accesscontrol.ScopeUsersAll},
+		}, sc.initCtx.OrgID)
+		return sc
+	}
+
+	call := func(t *testing.T, sc accessControlScenarioContext, method string, url string, body string) (int, dtos.SCIMUser) {
+		t.Helper()
+		var input *strings.Reader
+		if body != "" {
+			input = strings.NewReader(body)
+		} else {
+			input = strings.NewReader("{}")
+		}
+		response := callAPI(sc.server, method, url, input, t)
+		var res dtos.SCIMUser
+		if response.Body.Len() > 0 {
+			require.NoError(t, json.Unmarshal(response.Body.Bytes(), &res))
+		}
+		return response.Code, res
+	}
+
+	t.Run("new user is invited and deactivation revokes the invite", func(t *testing.T) {
+		sc := setup(t, nil)
+
+		code, created := call(t, sc, http.MethodPost, "/api/org/scim/v2/Users",
+			`{"schemas":["`+dtos.SCIMUserSchema+`"],"userName":"new@example.org","displayName":"New User","roles":[{"value":"Editor","primary":true}]}`)
+		require.Equal(t, http.StatusCreated, code)
+		require.True(t, strings.HasPrefix(created.ID, scimInviteIDPrefix))
+		invite, err := sc.hs.getSCIMPendingInvite(context.Background(), sc.initCtx.OrgID, "new@example.org")
+		require.NoError(t, err)
+		// the IDs don't give away the code of the invite
+		assert.Equal(t, scimInviteIDPrefix+strconv.FormatInt(invite.Id, 10), created.ID)
+		assert.NotContains(t, created.ID, invite.Code)
+		assert.Equal(t, "New User", created.DisplayName)
+		assert.Equal(t, "Editor", created.Roles[0].Value)
+		assert.True(t, *created.Active)
+
+		code, _ = call(t, sc, http.MethodPost, "/api/org/scim/v2/Users", `{"userName":"new@example.org"}`)
+		assert.Equal(t, http.StatusConflict, code)
+
+		code, fetched := call(t, sc, http.MethodGet, "/api/org/scim/v2/Users/"+created.ID, "")
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "new@example.org", fetched.UserName)
+
+		code, patched := call(t, sc, http.MethodPatch, "/api/org/scim/v2/Users/"+created.ID,
+			`{"schemas":["`+dtos.SCIMPatchOpSchema+`"],"Operations":[{"op":"replace","value":{"active":false}}]}`)
+		require.Equal(t, http.StatusOK, code)
+		assert.False(t, *patched.Active)
+
+		code, _ = call(t, sc, http.MethodGet, "/api/org/scim/v2/Users/"+created.ID, "")
+		assert.Equal(t, http.StatusNotFound, code)
+	})
+
+	t.Run("existing user is added to the organization and removed on delete", func(t *testing.T) {
+		sc := setup(t, &user.User{ID: testAdminOrg2.UserID, Login: testAdminOrg2.Login, Email: testAdminOrg2.Email})
+
+		code, created := call(t, sc, http.MethodPost, "/api/org/scim/v2/Users", `{"userName":"`+testAdminOrg2.Login+`"}`)
+		require.Equal(t, http.StatusCreated, code)
+		assert.Equal(t, "2", created.ID)
+		assert.Equal(t, "Viewer", created.Roles[0].Value)
+
+		code, updated := call(t, sc, http.MethodPut, "/api/org/scim/v2/Users/2", `{"userName":"`+testAdminOrg2.Login+`","roles":[{"value":"Editor"}]}`)
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "Editor", updated.Roles[0].Value)
+
+		response := callAPI(sc.server, http.MethodDelete, "/api/org/scim/v2/Users/2", nil, t)
+		require.Equal(t, http.StatusNoContent, response.Code)
+
+		query := models.GetOrgUsersQuery{OrgId: testServerAdminViewer.OrgID, UserID: testAdminOrg2.UserID, DontEnforceAccessControl: true}
+		require.NoError(t, sc.db.GetOrgUsers(context.Background(), &query))
+		assert.Empty(t, query.Result)
+	})
+
+	t.Run("new users are invited with the invite defaults and limits", func(t *testing.T) {
+		sc := setup(t, nil)
+		sc.hs.preferenceService = &preftest.FakePreferenceService{ExpectedPreference: &pref.Preference{
+			JSONData: &pref.PreferenceJSONData{Invites: pref.InvitesPreference{DefaultRole: org.RoleEditor}},
+		}}
+		sc.hs.Cfg.InviteEmailMaxRole = string(org.RoleEditor)
+		sc.hs.RemoteCacheService = remotecache.NewFakeStore(t)
+		sc.hs.Cfg.InviteRateLimitPerOrg = 2
+		sc.hs.Cfg.InviteRateLimitWindow = time.Hour
+
+		code, created := call(t, sc, http.MethodPost, "/api/org/scim/v2/Users", `{"userName":"first@example.org"}`)
+		require.Equal(t, http.StatusCreated, code)
+		assert.Equal(t, "Editor", created.Roles[0].Value)
+
+		code, _ = call(t, sc, http.MethodPost, "/api/org/scim/v2/Users", `{"userName":"admin@example.org","roles":[{"value":"Admin"}]}`)
+		assert.Equal(t, http.StatusBadRequest, code)
+
+		code, _ = call(t, sc, http.MethodPost, "/api/org/scim/v2/Users", `{"userName":"second@example.org"}`)
+		require.Equal(t, http.StatusCreated, code)
+		response := callAPI(sc.server, http.MethodPost, "/api/org/scim/v2/Users", strings.NewReader(`{"userName":"third@example.org"}`), t)
+		assert.Equal(t, http.StatusTooManyRequests, response.Code)
+		assert.NotEmpty(t, response.Header().Get("Retry-After"))
+	})
+
+	t.Run("new user is invited when the invite email fails to send", func(t *testing.T) {
+		sc := setup(t, nil)
+		sc.hs.Cfg.Smtp.Enabled = true
+		mailer := notifications.MockNotificationService()
+		mailer.ShouldError = errors.New("smtp is down")
+		sc.hs.AlertNG = &ngalert.AlertNG{NotificationService: mailer}
+
+		code, created := call(t, sc, http.MethodPost, "/api/org/scim/v2/Users", `{"userName":"new@example.org"}`)
+		require.Equal(t, http.StatusCreated, code)
+		assert.True(t, *created.Active)
+		assert.Equal(t, []string{"new@example.org"}, mailer.Email.To)
+
+		invite, err := sc.hs.getSCIMPendingInvite(context.Background(), sc.initCtx.OrgID, "new@example.org")
+		require.NoError(t, err)
+		require.NotNil(t, invite)
+		assert.False(t, invite.EmailSent)
+	})
+
+	t.Run("users can be filtered by user name", func(t *testing.T) {
+		sc := setup(t, nil)
+
+		response := callAPI(sc.server, http.MethodGet, `/api/org/scim/v2/Users?filter=userName+eq+"`+testEditorOrg1.Login+`"`, nil, t)
+		require.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, scimContentType, response.Header().Get("Content-Type"))
+
+		var list dtos.SCIMListResponse
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &list))
+		require.Equal(t, 1, list.TotalResults)
+		assert.Equal(t, "3", list.Resources[0].ID)
+
+		response = callAPI(sc.server, http.MethodGet, `/api/org/scim/v2/Users?filter=name+co+"test"`, nil, t)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+}
@@ -337,10 +337,11 @@ func TestGetOrgUsersAPIEndpoint_AccessControlMetadata(t *testing.T) {
 			enableAccessControl: true,
 			expectedCode:        http.StatusOK,
 			expectedMetadata: map[string]bool{
-				"org.users:write":  true,
-				"org.users:add":    true,
-				"org.users:read":   true,
-				"org.users:remove": true},
+				"org.users:write":       true,
+				"org.users:add":         true,
+				"org.users:read":        true,
+				"org.users:remove":      true,
+				"org.users:impersonate": true},
 			user:      testServerAdminViewer,
 			targetOrg: testServerAdminViewer.OrgID,
 		},
@@ -353,7 +354,7 @@ func TestGetOrgUsersAPIEndpoint_AccessControlMetadata(t *testing.T) {
 			sc := setupHTTPServerWithCfg(t, false, cfg, func(hs *HTTPServer) {
 				hs.userService = userimpl.ProvideService(
 					hs.SQLStore, nil, nil, nil, nil,
-					nil, nil, nil, nil, nil, hs.SQLStore.(*sqlstore.SQLStore),
+					nil, nil, nil, nil, nil, nil, hs.SQLStore.(*sqlstore.SQLStore),
 				)
 			})
 			setupOrgUsersDBForAccessControlTests(t, sc.db)
@@ -457,7 +458,7 @@ func TestGetOrgUsersAPIEndpoint_AccessControl(t *testing.T) {
 			sc := setupHTTPServerWithCfg(t, false, cfg, func(hs *HTTPServer) {
 				hs.userService = userimpl.ProvideService(
 					hs.SQLStore, nil, nil, nil, nil,
-					nil, nil, nil, nil, nil, hs.SQLStore.(*sqlstore.SQLStore),
+					nil, nil, nil, nil, nil, nil, hs.SQLStore.(*sqlstore.SQLStore),
 				)
 			})
 			setInitCtxSignedInUser(sc.initCtx, tc.user)
@@ -562,7 +563,7 @@ func TestPostOrgUsersAPIEndpoint_AccessControl(t *testing.T) {
 			sc := setupHTTPServerWithCfg(t, false, cfg, func(hs *HTTPServer) {
 				hs.userService = userimpl.ProvideService(
 					hs.SQLStore, nil, nil, nil, nil,
-					nil, nil, nil, nil, nil, hs.SQLStore.(*sqlstore.SQLStore),
+					nil, nil, nil, nil, nil, nil, hs.SQLStore.(*sqlstore.SQLStore),
 				)
 			})
 			setupOrgUsersDBForAccessControlTests(t, sc.db)
@@ -688,7 +689,7 @@ func TestOrgUsersAPIEndpointWithSetPerms_AccessControl(t *testing.T) {
 				hs.tempUserService = tempuserimpl.ProvideService(hs.SQLStore)
 				hs.userService = userimpl.ProvideService(
 					hs.SQLStore, nil, nil, nil, nil,
-					nil, nil, nil, nil, nil, hs.SQLStore.(*sqlstore.SQLStore),
+					nil, nil, nil, nil, nil, nil, hs.SQLStore.(*sqlstore.SQLStore),
 				)
 			})
 			setInitCtxSignedInViewer(sc.initCtx)
@@ -807,7 +808,7 @@ func TestPatchOrgUsersAPIEndpoint_AccessControl(t *testing.T) {
 			sc := setupHTTPServerWithCfg(t, false, cfg, func(hs *HTTPServer) {
 				hs.userService = userimpl.ProvideService(
 					hs.SQLStore, nil, nil, nil, nil,
-					nil, nil, nil, nil, nil, hs.SQLStore.(*sqlstore.SQLStore),
+					nil, nil, nil, nil, nil, nil, hs.SQLStore.(*sqlstore.SQLStore),
 				)
 			})
 			setupOrgUsersDBForAccessControlTests(t, sc.db)
@@ -934,7 +935,7 @@ func TestDeleteOrgUsersAPIEndpoint_AccessControl(t *testing.T) {
 			sc := setupHTTPServerWithCfg(t, false, cfg, func(hs *HTTPServer) {
 				hs.userService = userimpl.ProvideService(
 					hs.SQLStore, nil, nil, nil, nil,
-					nil, nil, nil, nil, nil, hs.SQLStore.(*sqlstore.SQLStore),
+					nil, nil, nil, nil, nil, nil, hs.SQLStore.(*sqlstore.SQLStore),
 				)
 			})
 			setupOrgUsersDBForAccessControlTests(t, sc.db)
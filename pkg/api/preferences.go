@@ -94,6 +94,9 @@ func (hs *HTTPServer) getPreferencesFor(ctx context.Context, orgID, userID, team
 		dto.Locale = preference.JSONData.Locale
 		dto.Navbar = preference.JSONData.Navbar
 		dto.QueryHistory = preference.JSONData.QueryHistory
+		if userID == 0 && teamID == 0 {
+			dto.Invites = &preference.JSONData.Invites
+		}
 	}
 
 	return response.JSON(http.StatusOK, &dto)
@@ -122,6 +125,9 @@ func (hs *HTTPServer) updatePreferencesFor(ctx context.Context, orgID, userID, t
 	if dtoCmd.Theme != lightTheme && dtoCmd.Theme != darkTheme && dtoCmd.Theme != defaultTheme {
 		return response.Error(400, "Invalid theme", nil)
 	}
+	if rsp := hs.validateInvitesPreference(ctx, orgID, userID, teamId, dtoCmd.Invites); rsp != nil {
+		return rsp
+	}
 
 	dashboardID := dtoCmd.HomeDashboardID
 	if dtoCmd.HomeDashboardUID != nil {
@@ -150,6 +156,7 @@ func (hs *HTTPServer) updatePreferencesFor(ctx context.Context, orgID, userID, t
 		HomeDashboardID: dtoCmd.HomeDashboardID,
 		QueryHistory:    dtoCmd.QueryHistory,
 		Navbar:          dtoCmd.Navbar,
+		Invites:         dtoCmd.Invites,
 	}
 
 	if err := hs.preferenceService.Save(ctx, &saveCmd); err != nil {
@@ -180,6 +187,9 @@ func (hs *HTTPServer) patchPreferencesFor(ctx context.Context, orgID, userID, te
 	if dtoCmd.Theme != nil && *dtoCmd.Theme != lightTheme && *dtoCmd.Theme != darkTheme && *dtoCmd.Theme != defaultTheme {
 		return response.Error(400, "Invalid theme", nil)
 	}
+	if rsp := hs.validateInvitesPreference(ctx, orgID, userID, teamId, dtoCmd.Invites); rsp != nil {
+		return rsp
+	}
 
 	// convert dashboard UID to ID in order to store internally if it exists in the query, otherwise take the id from query
 	dashboardID := dtoCmd.HomeDashboardID
@@ -210,6 +220,7 @@ func (hs *HTTPServer) patchPreferencesFor(ctx context.Context, orgID, userID, te
 		Locale:          dtoCmd.Locale,
 		Navbar:          dtoCmd.Navbar,
 		QueryHistory:    dtoCmd.QueryHistory,
+		Invites:         dtoCmd.Invites,
 	}
 
 	if err := hs.preferenceService.Patch(ctx, &patchCmd); err != nil {
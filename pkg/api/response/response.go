@@ -111,6 +111,19 @@ func (r *NormalResponse) SetHeader(key, value string) *NormalResponse {
 	return r
 }
 
+// SetJSONField sets a field of the body when it is a JSON object, other bodies are left unchanged.
+func (r *NormalResponse) SetJSONField(key string, value interface{}) *NormalResponse {
+	v := map[string]interface{}{}
+	if err := json.Unmarshal(r.body.Bytes(), &v); err != nil || v == nil {
+		return r
+	}
+	v[key] = value
+	if b, err := json.Marshal(v); err == nil {
+		r.body = bytes.NewBuffer(b)
+	}
+	return r
+}
+
 // StreamingResponse is a response that streams itself back to the client.
 type StreamingResponse struct {
 	body   interface{}
@@ -125,3 +125,13 @@ func TestErrors(t *testing.T) {
 		)
 	}
 }
+
+func TestSetJSONField(t *testing.T) {
+	rsp := Success("done").SetJSONField("id", "abc")
+	assert.JSONEq(t, `{"message": "done", "id": "abc"}`, string(rsp.Body()))
+
+	for _, body := range []string{"", "null", "[1]", "plain text"} {
+		rsp := Respond(http.StatusOK, body).SetJSONField("id", "abc")
+		assert.Equal(t, body, string(rsp.Body()))
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/grafana/grafana/pkg/events"
 	"github.com/grafana/grafana/pkg/infra/metrics"
 	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/captcha"
 	"github.com/grafana/grafana/pkg/services/user"
 	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/util"
@@ -33,6 +34,9 @@ func (hs *HTTPServer) SignUp(c *models.ReqContext) response.Response {
 	if !setting.AllowUserSignUp {
 		return response.Error(401, "User signup is disabled", nil)
 	}
+	if rsp := hs.verifyCaptcha(c, form.CaptchaToken); rsp != nil {
+		return rsp
+	}
 
 	existing := user.GetUserByLoginQuery{LoginOrEmail: form.Email}
 	_, err := hs.userService.GetByLogin(c.Req.Context(), &existing)
@@ -75,6 +79,9 @@ func (hs *HTTPServer) SignUpStep2(c *models.ReqContext) response.Response {
 	if !setting.AllowUserSignUp {
 		return response.Error(401, "User signup is disabled", nil)
 	}
+	if rsp := hs.verifyCaptcha(c, form.CaptchaToken); rsp != nil {
+		return rsp
+	}
 
 	createUserCmd := user.CreateUserCommand{
 		Email:    form.Email,
@@ -122,7 +129,7 @@ func (hs *HTTPServer) SignUpStep2(c *models.ReqContext) response.Response {
 
 	apiResponse := util.DynMap{"message": "User sign up completed successfully", "code": "redirect-to-landing-page"}
 	for _, invite := range invitesQuery.Result {
-		if ok, rsp := hs.applyUserInvite(c.Req.Context(), usr, invite, false); !ok {
+		if ok, rsp := hs.applyUserInvite(c.Req.Context(), usr, invite, false, hs.inviteCompletion(c)); !ok {
 			return rsp
 		}
 		apiResponse["code"] = "redirect-to-select-org"
@@ -155,3 +162,19 @@ func (hs *HTTPServer) verifyUserSignUpEmail(ctx context.Context, email string, c
 
 	return true, nil
 }
+
+// verifyCaptcha validates the CAPTCHA token submitted with unauthenticated forms
+// when CAPTCHA verification is enabled. It returns nil if the token is valid.
+func (hs *HTTPServer) verifyCaptcha(c *models.ReqContext, token string) response.Response {
+	if !hs.Cfg.Captcha.Enabled {
+		return nil
+	}
+
+	if err := hs.captchaService.Verify(c.Req.Context(), token, c.RemoteAddr()); err != nil {
+		if errors.Is(err, captcha.ErrMissingToken) || errors.Is(err, captcha.ErrVerificationFailed) {
+			return response.Error(http.StatusBadRequest, "CAPTCHA verification failed", err)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to verify CAPTCHA", err)
+	}
+	return nil
+}
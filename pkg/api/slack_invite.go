@@ -0,0 +1,184 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/models"
+	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+const slackInviteUsage = "Usage: /grafana-invite email [Viewer|Editor|Admin]"
+
+// SlackInviteCommand handles the /grafana-invite Slack slash command.
+//
+// Requests are authenticated with the signing secret of the Slack app, and the invite is
+// created as the configured service account. Slack displays the text of the reply to the
+// user who ran the command, so user errors are replied with status 200.
+func (hs *HTTPServer) SlackInviteCommand(c *models.ReqContext) response.Response {
+	settings := hs.Cfg.SlackInvites
+	if !settings.Enabled {
+		return response.Error(http.StatusNotFound, "Not found", nil)
+	}
+
+	body, err := io.ReadAll(c.Req.Body)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "Failed to read request body", err)
+	}
+	if err := verifySlackSignature(settings, c.Req.Header, body, time.Now()); err != nil {
+		return response.Error(http.StatusUnauthorized, "Invalid Slack signature", err)
+	}
+
+	form, err := url.ParseQuery(string(bytes.TrimSpace(body)))
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "Invalid slash command", err)
+	}
+
+	inviteDto, ok := parseSlackInviteText(form.Get("text"))
+	if !ok {
+		return slackReply(slackInviteUsage)
+	}
+
+	saCtx, rsp := hs.slackInviteContext(c, settings.ServiceAccountID)
+	if rsp != nil {
+		return rsp
+	}
+
+	userQuery := user.GetUserByLoginQuery{LoginOrEmail: inviteDto.LoginOrEmail}
+	if _, err := hs.userService.GetByLogin(c.Req.Context(), &userQuery); err == nil {
+		return slackReply(fmt.Sprintf("%s already has an account, add them to the organization from Grafana.", inviteDto.LoginOrEmail))
+	} else if !errors.Is(err, user.ErrUserNotFound) {
+		return response.Error(http.StatusInternalServerError, "Failed to query db for existing user check", err)
+	}
+
+	// invites are all created as the service account, each Slack user has their own limit
+	limit, rsp := hs.checkInviteRateLimit(c.Req.Context(), saCtx.OrgID, "slack-"+form.Get("user_id"), 1)
+	if rsp != nil {
+		return slackReply("Too many invites, try again later.")
+	}
+	rsp = hs.createOrgInvite(saCtx, *inviteDto)
+	switch {
+	case rsp.Status() >= http.StatusInternalServerError:
+		return rsp
+	case rsp.Status() != http.StatusOK:
+		return slackReply(fmt.Sprintf("Cannot invite %s: %s.", inviteDto.LoginOrEmail, strings.TrimSuffix(responseMessage(rsp), ".")))
+	}
+	var link dtos.InviteLink
+	if err := json.Unmarshal(rsp.Body(), &link); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to read the invite link", err)
+	}
+	hs.countInvites(c.Req.Context(), limit, 1)
+
+	return slackReply(fmt.Sprintf("Invited %s as %s: %s", inviteDto.LoginOrEmail, inviteDto.Role, link.URL))
+}
+
+// slackInviteContext returns a request context signed in as the service account invites are
+// created as, after checking that it is allowed to invite users.
+func (hs *HTTPServer) slackInviteContext(c *models.ReqContext, serviceAccountID int64) (*models.ReqContext, response.Response) {
+	ctx := c.Req.Context()
+
+	sa, err := hs.userService.GetByID(ctx, &user.GetUserByIDQuery{ID: serviceAccountID})
+	if err != nil || !sa.IsServiceAccount {
+		return nil, response.Error(http.StatusInternalServerError, "Slack invites service account not found", err)
+	}
+
+	signedInUser, err := hs.userService.GetSignedInUserWithCacheCtx(ctx, &user.GetSignedInUserQuery{UserID: sa.ID, OrgID: sa.OrgID})
+	if err != nil {
+		return nil, response.Error(http.StatusInternalServerError, "Failed to get Slack invites service account", err)
+	}
+
+	hasAccess, err := hs.AccessControl.Evaluate(ctx, signedInUser, ac.EvalPermission(ac.ActionOrgUsersAdd))
+	if err != nil {
+		return nil, response.Error(http.StatusInternalServerError, "Failed to evaluate permissions", err)
+	}
+	if !hasAccess {
+		return nil, slackReply("The Grafana service account is not allowed to invite users.")
+	}
+
+	return &models.ReqContext{
+		Context:      c.Context,
+		SignedInUser: signedInUser,
+		IsSignedIn:   true,
+		Logger:       c.Logger,
+	}, nil
+}
+
+// verifySlackSignature checks the signature Slack computes over the timestamp and the raw body
+// of requests, see https://api.slack.com/authentication/verifying-requests-from-slack.
+func verifySlackSignature(settings setting.SlackInvitesSettings, header http.Header, body []byte, now time.Time) error {
+	if settings.SigningSecret == "" {
+		return errors.New("signing secret is not configured")
+	}
+
+	timestamp := header.Get("X-Slack-Request-Timestamp")
+	sent, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid request timestamp: %w", err)
+	}
+	if age := now.Sub(time.Unix(sent, 0)); age > settings.MaxRequestAge || age < -settings.MaxRequestAge {
+		return errors.New("request timestamp is too old")
+	}
+
+	mac := hmac.New(sha256.New, []byte(settings.SigningSecret))
+	_, _ = mac.Write([]byte("v0:" + timestamp + ":"))
+	_, _ = mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+
+	if !hmac.Equal([]byte(expected), []byte(header.Get("X-Slack-Signature"))) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+// parseSlackInviteText parses the "email [role]" text of the slash command, the role defaults to Viewer.
+func parseSlackInviteText(text string) (*dtos.AddInviteForm, bool) {
+	args := strings.Fields(text)
+	if len(args) == 0 || len(args) > 2 {
+		return nil, false
+	}
+
+	// Slack formats emails as <mailto:user@example.com|user@example.com>
+	email := strings.TrimSuffix(strings.TrimPrefix(args[0], "<"), ">")
+	if i := strings.Index(email, "|"); i >= 0 {
+		email = email[i+1:]
+	}
+	if !util.IsEmail(email) {
+		return nil, false
+	}
+
+	role := org.RoleViewer
+	if len(args) == 2 {
+		role = org.RoleType(strings.ToUpper(args[1][:1]) + strings.ToLower(args[1][1:]))
+		if !role.IsValid() {
+			return nil, false
+		}
+	}
+
+	// the link is replied in Slack, the invite is never emailed
+	return &dtos.AddInviteForm{LoginOrEmail: email, Role: role, Delivery: models.InviteDeliveryManual}, true
+}
+
+// slackReply replies to the user who ran the command only.
+func slackReply(text string) response.Response {
+	return response.JSON(http.StatusOK, map[string]string{
+		"response_type": "ephemeral",
+		"text":          text,
+	})
+}
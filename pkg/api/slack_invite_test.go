@@ -0,0 +1,167 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/org"
+	pref "github.com/grafana/grafana/pkg/services/preference"
+	"github.com/grafana/grafana/pkg/services/preference/preftest"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/services/user/usertest"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const testSlackSigningSecret = "slack-secret"
+
+// slackInviteUserService knows the service account only.
+type slackInviteUserService struct {
+	*usertest.FakeUserService
+}
+
+func (s *slackInviteUserService) GetByLogin(ctx context.Context, query *user.GetUserByLoginQuery) (*user.User, error) {
+	return nil, user.ErrUserNotFound
+}
+
+func signSlackRequest(t *testing.T, body string, sent time.Time) *http.Request {
+	t.Helper()
+	timestamp := strconv.FormatInt(sent.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(testSlackSigningSecret))
+	_, _ = mac.Write([]byte("v0:" + timestamp + ":" + body))
+
+	req, err := http.NewRequest(http.MethodPost, "/api/integrations/slack/invite", strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Slack-Request-Timestamp", timestamp)
+	req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+func TestSlackInviteCommand(t *testing.T) {
+	setup := func(t *testing.T, perms []accesscontrol.Permission) accessControlScenarioContext {
+		sc, _ := setupInviteHTTPServer(t)
+		sc.hs.Cfg.SlackInvites = setting.SlackInvitesSettings{
+			Enabled:          true,
+			SigningSecret:    testSlackSigningSecret,
+			ServiceAccountID: 10,
+			MaxRequestAge:    5 * time.Minute,
+		}
+		sc.hs.userService = &slackInviteUserService{&usertest.FakeUserService{
+			ExpectedUser:         &user.User{ID: 10, OrgID: 1, IsServiceAccount: true},
+			ExpectedSignedInUser: &user.SignedInUser{UserID: 10, OrgID: 1, OrgRole: org.RoleEditor},
+		}}
+		// Slack requests are not signed in
+		sc.initCtx.SignedInUser = &user.SignedInUser{}
+		setAccessControlPermissions(sc.acmock, perms, 1)
+		return sc
+	}
+
+	run := func(sc accessControlScenarioContext, req *http.Request) (*httptest.ResponseRecorder, string) {
+		recorder := httptest.NewRecorder()
+		sc.server.ServeHTTP(recorder, req)
+		var reply map[string]string
+		_ = json.Unmarshal(recorder.Body.Bytes(), &reply)
+		return recorder, reply["text"]
+	}
+
+	command := func(text string) string {
+		return url.Values{"command": {"/grafana-invite"}, "text": {text}}.Encode()
+	}
+
+	t.Run("creates an invite and replies with its link", func(t *testing.T) {
+		sc := setup(t, []accesscontrol.Permission{{Action: accesscontrol.ActionOrgUsersAdd}})
+
+		recorder, text := run(sc, signSlackRequest(t, command("<mailto:new@example.com|new@example.com> editor"), time.Now()))
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, text, setting.ToAbsUrl("invite/"))
+
+		query := models.GetTempUsersQuery{OrgId: 1, Email: "new@example.com", Status: models.TmpUserInvitePending}
+		require.NoError(t, sc.hs.tempUserService.GetTempUsersQuery(context.Background(), &query))
+		require.Len(t, query.Result, 1)
+		assert.Equal(t, org.RoleEditor, query.Result[0].Role)
+		assert.Contains(t, text, query.Result[0].Code)
+	})
+
+	t.Run("replies with usage and role errors", func(t *testing.T) {
+		sc := setup(t, []accesscontrol.Permission{{Action: accesscontrol.ActionOrgUsersAdd}})
+
+		_, text := run(sc, signSlackRequest(t, command("not-an-email"), time.Now()))
+		assert.Equal(t, slackInviteUsage, text)
+
+		_, text = run(sc, signSlackRequest(t, command("new@example.com admin"), time.Now()))
+		assert.Equal(t, "Cannot invite new@example.com: Cannot assign a role higher than user's role.", text)
+	})
+
+	t.Run("invites get the defaults of the organization", func(t *testing.T) {
+		sc := setup(t, []accesscontrol.Permission{{Action: accesscontrol.ActionOrgUsersAdd}})
+		team, err := sc.hs.teamService.CreateTeam("onboarding", "", 1)
+		require.NoError(t, err)
+		sc.hs.preferenceService = &preftest.FakePreferenceService{ExpectedPreference: &pref.Preference{
+			JSONData: &pref.PreferenceJSONData{Invites: pref.InvitesPreference{DefaultTeams: []int64{team.Id}}},
+		}}
+
+		_, text := run(sc, signSlackRequest(t, command("new@example.com"), time.Now()))
+		assert.Contains(t, text, "Invited new@example.com as Viewer")
+
+		query := models.GetTempUsersQuery{OrgId: 1, Email: "new@example.com", Status: models.TmpUserInvitePending}
+		require.NoError(t, sc.hs.tempUserService.GetTempUsersQuery(context.Background(), &query))
+		require.Len(t, query.Result, 1)
+		assert.Equal(t, models.InviteDeliveryManual, query.Result[0].Delivery)
+		grants := models.GetTempUserGrantsQuery{TempUserID: query.Result[0].Id}
+		require.NoError(t, sc.hs.tempUserService.GetTempUserGrants(context.Background(), &grants))
+		require.Len(t, grants.Result, 1)
+		assert.Equal(t, strconv.FormatInt(team.Id, 10), grants.Result[0].ResourceUid)
+	})
+
+	t.Run("limits the invites of each Slack user", func(t *testing.T) {
+		sc := setup(t, []accesscontrol.Permission{{Action: accesscontrol.ActionOrgUsersAdd}})
+		sc.hs.RemoteCacheService = remotecache.NewFakeStore(t)
+		sc.hs.Cfg.InviteRateLimitPerInviter = 1
+		sc.hs.Cfg.InviteRateLimitWindow = time.Hour
+		commandOf := func(userID, email string) string {
+			return url.Values{"command": {"/grafana-invite"}, "text": {email}, "user_id": {userID}}.Encode()
+		}
+
+		_, text := run(sc, signSlackRequest(t, commandOf("U1", "a@example.com"), time.Now()))
+		assert.Contains(t, text, "Invited a@example.com")
+		_, text = run(sc, signSlackRequest(t, commandOf("U1", "b@example.com"), time.Now()))
+		assert.Equal(t, "Too many invites, try again later.", text)
+		_, text = run(sc, signSlackRequest(t, commandOf("U2", "b@example.com"), time.Now()))
+		assert.Contains(t, text, "Invited b@example.com")
+	})
+
+	t.Run("requires the service account to be allowed to invite", func(t *testing.T) {
+		sc := setup(t, nil)
+
+		_, text := run(sc, signSlackRequest(t, command("new@example.com"), time.Now()))
+		assert.Equal(t, "The Grafana service account is not allowed to invite users.", text)
+	})
+
+	t.Run("rejects unsigned and replayed requests", func(t *testing.T) {
+		sc := setup(t, []accesscontrol.Permission{{Action: accesscontrol.ActionOrgUsersAdd}})
+
+		req := signSlackRequest(t, command("new@example.com"), time.Now())
+		req.Header.Set("X-Slack-Signature", "v0=invalid")
+		recorder, _ := run(sc, req)
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+		recorder, _ = run(sc, signSlackRequest(t, command("new@example.com"), time.Now().Add(-time.Hour)))
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	})
+}
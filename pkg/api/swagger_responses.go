@@ -67,6 +67,11 @@ type PreconditionFailedError GenericError
 // swagger:response unprocessableEntityError
 type UnprocessableEntityError GenericError
 
+// ServiceUnavailableError is returned when the server can't handle the request for now.
+//
+// swagger:response serviceUnavailableError
+type ServiceUnavailableError GenericError
+
 // InternalServerError is a general error indicating something went wrong internally.
 //
 // swagger:response internalServerError
@@ -81,3 +86,8 @@ type UnauthorizedError GenericError
 //
 // swagger:response acceptedResponse
 type AcceptedResponse GenericError
+
+// NoContentResponse is returned when the request was successful and there is no content to return.
+//
+// swagger:response noContentResponse
+type NoContentResponse struct{}
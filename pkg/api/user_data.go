@@ -0,0 +1,132 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+// swagger:route GET /user/data-export signed_in_user getSignedInUserDataExport
+//
+// Export the personal data of the signed in user.
+//
+// Includes the invites and sign ups the user sent, and the ones sent to their email, in all
+// organizations. Invite codes are left out.
+//
+// Responses:
+// 200: getUserDataExportResponse
+// 401: unauthorisedError
+// 403: forbiddenError
+// 500: internalServerError
+func (hs *HTTPServer) GetSignedInUserDataExport(c *models.ReqContext) response.Response {
+	return hs.getUserDataExport(c, c.UserID)
+}
+
+// swagger:route GET /admin/users/{user_id}/data-export admin_users adminGetUserDataExport
+//
+// Export the personal data of a user, for data subject access requests.
+//
+// If you are running Grafana Enterprise and have Fine-grained access control enabled, you need to have a permission with action `users:read` and scope `global.users:*`.
+//
+// Security:
+// - basic:
+//
+// Responses:
+// 200: getUserDataExportResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (hs *HTTPServer) AdminGetUserDataExport(c *models.ReqContext) response.Response {
+	userID, err := strconv.ParseInt(web.Params(c.Req)[":id"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "id is invalid", err)
+	}
+	return hs.getUserDataExport(c, userID)
+}
+
+func (hs *HTTPServer) getUserDataExport(c *models.ReqContext, userID int64) response.Response {
+	usr, err := hs.userService.GetByID(c.Req.Context(), &user.GetUserByIDQuery{ID: userID})
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return response.Error(http.StatusNotFound, user.ErrUserNotFound.Error(), nil)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to get user", err)
+	}
+
+	query := models.GetTempUsersForUserQuery{UserID: usr.ID, Email: usr.Email}
+	if err := hs.tempUserService.GetTempUsersForUser(c.Req.Context(), &query); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get the invites of the user", err)
+	}
+	return response.JSON(http.StatusOK, dtos.UserDataExport{
+		UserID:  usr.ID,
+		Login:   usr.Login,
+		Email:   usr.Email,
+		Name:    usr.Name,
+		Invites: query.Result,
+	})
+}
+
+// swagger:route POST /admin/users/{user_id}/erase-data admin_users adminEraseUserData
+//
+// Erase the personal data of a user, for erasure requests of users who keep their account.
+//
+// Invites and sign ups sent to the email of the user are anonymized and the open ones closed, and
+// the user is unlinked from the invites they sent. Deleting a user erases the same data.
+// If you are running Grafana Enterprise and have Fine-grained access control enabled, you need to have a permission with action `users:write` and scope `global.users:*`.
+//
+// Security:
+// - basic:
+//
+// Responses:
+// 200: adminEraseUserDataResponse
+// 400: badRequestError
+// 401: unauthorisedError
+// 403: forbiddenError
+// 404: notFoundError
+// 500: internalServerError
+func (hs *HTTPServer) AdminEraseUserData(c *models.ReqContext) response.Response {
+	userID, err := strconv.ParseInt(web.Params(c.Req)[":id"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "id is invalid", err)
+	}
+	usr, err := hs.userService.GetByID(c.Req.Context(), &user.GetUserByIDQuery{ID: userID})
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return response.Error(http.StatusNotFound, user.ErrUserNotFound.Error(), nil)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to get user", err)
+	}
+
+	cmd := models.EraseTempUserDataCommand{UserID: usr.ID, Email: usr.Email}
+	if err := hs.tempUserService.EraseTempUserData(c.Req.Context(), &cmd); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to erase the invites of the user", err)
+	}
+	return response.JSON(http.StatusOK, dtos.UserDataErasure{Erased: cmd.NumErased})
+}
+
+// swagger:parameters adminGetUserDataExport adminEraseUserData
+type AdminUserDataParams struct {
+	// in:path
+	// required:true
+	UserID int64 `json:"user_id"`
+}
+
+// swagger:response getUserDataExportResponse
+type GetUserDataExportResponse struct {
+	// in: body
+	Body dtos.UserDataExport `json:"body"`
+}
+
+// swagger:response adminEraseUserDataResponse
+type AdminEraseUserDataResponse struct {
+	// in: body
+	Body dtos.UserDataErasure `json:"body"`
+}
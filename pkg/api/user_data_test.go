@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/services/user/usertest"
+)
+
+func TestUserDataExportAndErasure(t *testing.T) {
+	setup := func(t *testing.T) accessControlScenarioContext {
+		sc, stack := setupInviteHTTPServer(t)
+		sc.hs.userService = &usertest.FakeUserService{ExpectedUser: &user.User{ID: testEditorOrg1.UserID, Login: testEditorOrg1.Login, Email: "Editor@Example.com"}}
+		stack.Invite("editor@example.com").WithCode("to-user").Create(t)
+		stack.Invite("other@example.com").WithCode("by-user").InvitedBy(testEditorOrg1.UserID).Create(t)
+		stack.Invite("unrelated@example.com").WithCode("unrelated").Create(t)
+		return sc
+	}
+	exportedEmails := func(t *testing.T, sc accessControlScenarioContext, url string) []string {
+		t.Helper()
+		response := callAPI(sc.server, http.MethodGet, url, nil, t)
+		require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+		var export dtos.UserDataExport
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &export))
+		assert.Equal(t, testEditorOrg1.UserID, export.UserID)
+		emails := make([]string, 0, len(export.Invites))
+		for _, invite := range export.Invites {
+			assert.Empty(t, invite.Code)
+			emails = append(emails, invite.Email)
+		}
+		return emails
+	}
+
+	t.Run("users export the invites they sent and the ones sent to them", func(t *testing.T) {
+		sc := setup(t)
+		setInitCtxSignedInUser(sc.initCtx, testEditorOrg1)
+		assert.ElementsMatch(t, []string{"editor@example.com", "other@example.com"}, exportedEmails(t, sc, "/api/user/data-export"))
+	})
+
+	t.Run("admins export and erase the personal data of users", func(t *testing.T) {
+		sc := setup(t)
+		setInitCtxSignedInOrgAdmin(sc.initCtx)
+		sc.initCtx.IsGrafanaAdmin = true
+		setAccessControlPermissions(sc.acmock, []accesscontrol.Permission{
+			{Action: accesscontrol.ActionUsersRead, Scope: accesscontrol.ScopeGlobalUsersAll},
+			{Action: accesscontrol.ActionUsersWrite, Scope: accesscontrol.ScopeGlobalUsersAll},
+		}, sc.initCtx.OrgID)
+		url := "/api/admin/users/" + strconv.FormatInt(testEditorOrg1.UserID, 10) + "/"
+		assert.ElementsMatch(t, []string{"editor@example.com", "other@example.com"}, exportedEmails(t, sc, url+"data-export"))
+
+		response := callAPI(sc.server, http.MethodPost, url+"erase-data", nil, t)
+		require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+		var erasure dtos.UserDataErasure
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &erasure))
+		assert.Equal(t, int64(2), erasure.Erased)
+
+		query := models.GetTempUserByCodeQuery{Code: "to-user"}
+		require.NoError(t, sc.hs.tempUserService.GetTempUserByCode(context.Background(), &query))
+		assert.Empty(t, query.Result.Email)
+		assert.Equal(t, models.TmpUserRevoked, query.Result.Status)
+		assert.Empty(t, exportedEmails(t, sc, url+"data-export"))
+	})
+
+	t.Run("only admins with the permissions export and erase the data of users", func(t *testing.T) {
+		sc := setup(t)
+		setInitCtxSignedInOrgAdmin(sc.initCtx)
+		setAccessControlPermissions(sc.acmock, []accesscontrol.Permission{}, sc.initCtx.OrgID)
+		url := "/api/admin/users/" + strconv.FormatInt(testEditorOrg1.UserID, 10) + "/"
+		assert.Equal(t, http.StatusForbidden, callAPI(sc.server, http.MethodGet, url+"data-export", nil, t).Code)
+		assert.Equal(t, http.StatusForbidden, callAPI(sc.server, http.MethodPost, url+"erase-data", nil, t).Code)
+	})
+}
@@ -217,6 +217,15 @@ var adminCommands = []*cli.Command{
 	},
 }
 
+var searchCommands = []*cli.Command{
+	{
+		Name:   "benchmark",
+		Usage:  "benchmarks building and querying the search index of synthetic organizations",
+		Action: runSearchBenchmarkCommand,
+		Flags:  searchBenchmarkFlags(),
+	},
+}
+
 var Commands = []*cli.Command{
 	{
 		Name:        "plugins",
@@ -228,4 +237,9 @@ var Commands = []*cli.Command{
 		Usage:       "Grafana admin commands",
 		Subcommands: adminCommands,
 	},
+	{
+		Name:        "search",
+		Usage:       "Grafana search commands",
+		Subcommands: searchCommands,
+	},
 }
@@ -0,0 +1,84 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/logger"
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/utils"
+	"github.com/grafana/grafana/pkg/services/searchV2"
+)
+
+func searchBenchmarkFlags() []cli.Flag {
+	defaults := searchV2.DefaultBenchmarkOptions()
+	return []cli.Flag{
+		&cli.IntFlag{Name: "orgs", Usage: "Number of organizations", Value: defaults.Orgs},
+		&cli.IntFlag{Name: "folders", Usage: "Number of folders per organization", Value: defaults.FoldersPerOrg},
+		&cli.IntFlag{Name: "dashboards", Usage: "Number of dashboards per organization", Value: defaults.DashboardsPerOrg},
+		&cli.IntFlag{Name: "panels", Usage: "Number of panels per dashboard", Value: defaults.PanelsPerDashboard},
+		&cli.IntFlag{Name: "queries", Usage: "Number of queries per organization", Value: defaults.QueriesPerOrg},
+		&cli.IntFlag{Name: "concurrency", Usage: "Number of queries run at once", Value: defaults.Concurrency},
+		&cli.IntFlag{Name: "seed", Usage: "Seed of the generated dashboards and queries", Value: int(defaults.Seed)},
+		&cli.BoolFlag{Name: "sparse", Usage: "Build sparse indexes, which only hold the titles, tags, folders and UIDs of dashboards"},
+		&cli.BoolFlag{Name: "json", Usage: "Print the results as JSON, e.g. to compare them between releases"},
+	}
+}
+
+func runSearchBenchmarkCommand(c *cli.Context) error {
+	return searchBenchmarkCommand(c.Context, &utils.ContextCommandLine{Context: c})
+}
+
+// searchBenchmarkCommand indexes synthetic organizations in memory and reports the index build
+// time and memory and the query latency percentiles. It doesn't need a Grafana database.
+func searchBenchmarkCommand(ctx context.Context, c utils.CommandLine) error {
+	opts := searchV2.BenchmarkOptions{
+		Orgs:               c.Int("orgs"),
+		FoldersPerOrg:      c.Int("folders"),
+		DashboardsPerOrg:   c.Int("dashboards"),
+		PanelsPerDashboard: c.Int("panels"),
+		QueriesPerOrg:      c.Int("queries"),
+		Concurrency:        c.Int("concurrency"),
+		Seed:               int64(c.Int("seed")),
+		Sparse:             c.Bool("sparse"),
+	}
+
+	result, err := searchV2.RunBenchmark(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("%v: %w", "search benchmark failed", err)
+	}
+
+	if c.Bool("json") {
+		out, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		logger.Infof("%s\n", out)
+		return nil
+	}
+
+	logger.Info(formatSearchBenchmarkResult(result))
+	return nil
+}
+
+func formatSearchBenchmarkResult(result *searchV2.BenchmarkResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Indexed %d dashboards and folders in %d organizations in %s, using %.1f MiB of heap\n\n",
+		result.Documents, result.Options.Orgs, result.BuildTime.Round(time.Millisecond), float64(result.HeapBytes)/(1<<20))
+
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "QUERY\tCOUNT\tP50\tP90\tP99\tMAX")
+	for _, q := range result.Queries {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\n", q.Name, q.Count,
+			q.Latency.P50.Round(time.Microsecond), q.Latency.P90.Round(time.Microsecond),
+			q.Latency.P99.Round(time.Microsecond), q.Latency.Max.Round(time.Microsecond))
+	}
+	_ = w.Flush()
+	b.WriteString("\n")
+	return b.String()
+}
@@ -26,6 +26,9 @@ type SendEmailCommand struct {
 	ReplyTo       []string
 	EmbeddedFiles []string
 	AttachedFiles []*SendEmailAttachFile
+	// Multipart sends both the HTML and the plain text template as alternatives, regardless of
+	// the configured content types
+	Multipart bool
 }
 
 // SendEmailCommandSync is the command for sending emails synchronously
@@ -1,7 +1,10 @@
 package models
 
 import (
+	"crypto/sha256"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/grafana/grafana/pkg/services/org"
@@ -9,7 +12,12 @@ import (
 
 // Typed errors
 var (
-	ErrTempUserNotFound = errors.New("user not found")
+	ErrTempUserNotFound          = errors.New("user not found")
+	ErrTempUserInvalidTransition = errors.New("invalid temp user status transition")
+	ErrTempUserVersionMismatch   = errors.New("the invite has been changed by someone else")
+	ErrTempUserPendingExists     = errors.New("a pending invite for the email already exists in the organization")
+	ErrEmailSuppressionNotFound  = errors.New("email is not on the suppression list")
+	ErrInvalidEmailSuppression   = errors.New("suppressed emails need a valid email and a reason of unsubscribed, bounced or manual")
 )
 
 type TempUserStatus string
@@ -20,8 +28,118 @@ const (
 	TmpUserCompleted     TempUserStatus = "Completed"
 	TmpUserRevoked       TempUserStatus = "Revoked"
 	TmpUserExpired       TempUserStatus = "Expired"
+	TmpUserDeclined      TempUserStatus = "Declined"
+	// TmpUserAccessExpired is the state of a completed viewer token whose membership has ended.
+	TmpUserAccessExpired TempUserStatus = "AccessExpired"
+
+	// TmpUserInviteEmailed is the state of a pending invite whose email has been sent.
+	// It is not stored as a status: emailed invites keep the InvitePending status and
+	// have the EmailSent flag set, so that they are still listed as pending.
+	TmpUserInviteEmailed TempUserStatus = "InviteEmailed"
+)
+
+// tempUserTransitions lists the states a temp user can move to from each state.
+// Revoked, expired, declined and access expired temp users are final, completed ones too unless
+// they are viewer tokens.
+var tempUserTransitions = map[TempUserStatus][]TempUserStatus{
+	TmpUserSignUpStarted: {TmpUserCompleted, TmpUserExpired},
+	TmpUserInvitePending: {TmpUserInviteEmailed, TmpUserCompleted, TmpUserRevoked, TmpUserExpired, TmpUserDeclined},
+	TmpUserInviteEmailed: {TmpUserInviteEmailed, TmpUserCompleted, TmpUserRevoked, TmpUserExpired, TmpUserDeclined},
+	TmpUserCompleted:     {TmpUserAccessExpired},
+}
+
+// CanTransitionTo returns true if a temp user in state s can move to state to.
+func (s TempUserStatus) CanTransitionTo(to TempUserStatus) bool {
+	for _, allowed := range tempUserTransitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TempUserTransitionError is returned when a temp user can't move from its current state to the requested one.
+type TempUserTransitionError struct {
+	From TempUserStatus
+	To   TempUserStatus
+}
+
+func (e TempUserTransitionError) Error() string {
+	return fmt.Sprintf("cannot change invite from %s to %s", e.From, e.To)
+}
+
+func (e TempUserTransitionError) Unwrap() error {
+	return ErrTempUserInvalidTransition
+}
+
+// InviteDelivery is how the invite link reaches the invitee.
+type InviteDelivery string
+
+const (
+	// InviteDeliveryEmail invites are emailed if requested when they are created.
+	InviteDeliveryEmail InviteDelivery = "email"
+	// InviteDeliveryManual invites are never emailed, the inviter hands the link over, for
+	// example on instances without SMTP.
+	InviteDeliveryManual InviteDelivery = "manual"
+)
+
+func (d InviteDelivery) IsValid() bool {
+	return d == InviteDeliveryEmail || d == InviteDeliveryManual
+}
+
+// InviteCreator filters invites by who created them.
+type InviteCreator string
+
+const (
+	// InviteCreatorAutomation invites were created with an API key or by a service account.
+	InviteCreatorAutomation InviteCreator = "automation"
+	// InviteCreatorUser invites were created by a user.
+	InviteCreatorUser InviteCreator = "user"
 )
 
+func (c InviteCreator) IsValid() bool {
+	return c == InviteCreatorAutomation || c == InviteCreatorUser
+}
+
+// InviteEmailMatch restricts the email a new user can sign up with when completing an invite,
+// so that a forwarded invite link can't be redeemed with another identity.
+type InviteEmailMatch string
+
+const (
+	// InviteEmailMatchAny invites can be completed with any email.
+	InviteEmailMatchAny InviteEmailMatch = "any"
+	// InviteEmailMatchExact invites can only be completed with the invited email.
+	InviteEmailMatchExact InviteEmailMatch = "exact"
+	// InviteEmailMatchDomain invites can only be completed with an email of the domain of the invited email.
+	InviteEmailMatchDomain InviteEmailMatch = "domain"
+)
+
+func (m InviteEmailMatch) IsValid() bool {
+	return m == InviteEmailMatchAny || m == InviteEmailMatchExact || m == InviteEmailMatchDomain
+}
+
+// Matches returns true if an invite sent to invited can be completed with email. Emails are
+// compared case-insensitively.
+func (m InviteEmailMatch) Matches(invited, email string) bool {
+	switch m {
+	case InviteEmailMatchExact:
+		return strings.EqualFold(strings.TrimSpace(invited), strings.TrimSpace(email))
+	case InviteEmailMatchDomain:
+		domain := emailDomain(invited)
+		return domain != "" && strings.EqualFold(domain, emailDomain(email))
+	default:
+		return true
+	}
+}
+
+func emailDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+	return strings.TrimSpace(email[at+1:])
+}
+
 // TempUser holds data for org invites and unconfirmed sign ups
 type TempUser struct {
 	Id              int64
@@ -37,11 +155,194 @@ type TempUser struct {
 	EmailSentOn time.Time
 	Code        string
 	RemoteAddr  string
+	// OpenedOn is when the invite link was first opened, nil if it wasn't or tracking is disabled
+	OpenedOn   *time.Time
+	Delivery   InviteDelivery
+	EmailMatch InviteEmailMatch
+	// PendingKey identifies the pending invites of an email in an organization, which are unique
+	// when it is set. It is nil for other invites, see CreateTempUserCommand.UniquePending
+	PendingKey *string
+	// Archived invites are closed and left out of invite listings
+	Archived bool
+	// AccessExpires is set for viewer tokens, invites giving a read-only membership of the
+	// organization which ends at that time. AccessUserId is the user who joined the organization
+	// with the token, it is 0 if the user was already a member.
+	AccessExpires *time.Time
+	AccessUserId  int64
+	// InvitedByApiKeyId is the API key the invite was created with, InvitedByUserId is 0 then.
+	// Invites created with the token of a service account are attributed to the service account.
+	InvitedByApiKeyId int64
+	// Completed* describe the device and location the invite was completed from, for the
+	// security review of the access it gave, see InviteCompletion
+	CompletedRemoteAddr string
+	CompletedUserAgent  string
+	CompletedCountry    string
+	CompletedAnomaly    bool
+	// ExternalId correlates the invite with a record of another system, e.g. the employee number
+	// in an HR system. It is set on the user who completes the invite, see user.User.ExternalID
+	ExternalId string
+	// CompletedOn is when the invite was completed, CompletedUserId the user who completed it. They
+	// are 0 for invites completed before they were recorded.
+	CompletedOn     int64
+	CompletedUserId int64
+
+	Created int64
+	Updated int64
+}
+
+// TempUserPendingKey returns the key of the pending invites of the email in the organization. The
+// email is hashed to fit in the indexed column.
+func TempUserPendingKey(orgID int64, email string) string {
+	return fmt.Sprintf("%d:%x", orgID, sha256.Sum256([]byte(strings.ToLower(email))))
+}
+
+// Kinds of resources invites can grant a permission on.
+const (
+	TempUserGrantDashboard = "dashboard"
+	TempUserGrantFolder    = "folder"
+	// TempUserGrantTeam grants the membership of the team, its resource UID is the team ID
+	TempUserGrantTeam = "team"
+	// TempUserGrantOrg grants the membership of another organization, its resource UID is the
+	// organization ID and its permission the role of the invitee in it
+	TempUserGrantOrg = "org"
+)
+
+// TempUserGrant is a permission on a dashboard or folder given to the invitee when the invite is accepted,
+// for invites sent by sharing the resource, or the membership of a team the invitee joins.
+type TempUserGrant struct {
+	Id           int64
+	OrgId        int64
+	TempUserId   int64
+	ResourceKind string
+	ResourceUid  string
+	// Permission is View or Edit
+	Permission string
 
 	Created int64
 	Updated int64
 }
 
+// Reasons emails are on the suppression list.
+const (
+	EmailSuppressionUnsubscribed = "unsubscribed"
+	EmailSuppressionBounced      = "bounced"
+	EmailSuppressionManual       = "manual"
+)
+
+// EmailSuppression is an email address Grafana doesn't send invites to, because its owner opted out
+// of Grafana emails or emails to it hard-bounced. Sending to these hurts the reputation of the sender.
+// Emails are stored in lower case.
+type EmailSuppression struct {
+	Id      int64  `json:"id"`
+	Email   string `json:"email"`
+	Reason  string `json:"reason"`
+	Created int64  `json:"created"`
+}
+
+// InviteNotificationEvent is a change of an invite the admins of its organization are notified of
+// in Grafana, see InviteNotification.
+type InviteNotificationEvent string
+
+const (
+	// InviteNotificationCreated is sent when an invite is created on behalf of the admins by an API
+	// key or a service account, e.g. through SCIM or Slack
+	InviteNotificationCreated InviteNotificationEvent = "created"
+	// InviteNotificationCompleted is sent when the invited user joins the organization
+	InviteNotificationCompleted InviteNotificationEvent = "completed"
+	// InviteNotificationExpiring is sent once when a pending invite is about to expire
+	InviteNotificationExpiring InviteNotificationEvent = "expiring"
+)
+
+func (e InviteNotificationEvent) IsValid() bool {
+	switch e {
+	case InviteNotificationCreated, InviteNotificationCompleted, InviteNotificationExpiring:
+		return true
+	}
+	return false
+}
+
+// InviteNotification tells an admin of the organization of an invite, or the user who created it,
+// about a change of the invite. Admins mute the events they don't want to be notified of.
+type InviteNotification struct {
+	Id         int64
+	OrgId      int64
+	UserId     int64
+	TempUserId int64
+	Event      InviteNotificationEvent
+	IsRead     bool
+	Created    int64
+}
+
+// InviteNotificationMute stops the notifications of an event for a user.
+type InviteNotificationMute struct {
+	Id     int64
+	UserId int64
+	Event  InviteNotificationEvent
+}
+
+// InviteNotificationPreferences tell which invite events a user is notified of.
+type InviteNotificationPreferences struct {
+	Created   bool `json:"created"`
+	Completed bool `json:"completed"`
+	Expiring  bool `json:"expiring"`
+}
+
+// TempUserEventType is a step of the lifecycle of a temp user, see TempUserEvent.
+type TempUserEventType string
+
+const (
+	TempUserEventCreated   TempUserEventType = "created"
+	TempUserEventEmailSent TempUserEventType = "email_sent"
+	// TempUserEventEmailQueued is recorded when the email of the invite waits in the mail backlog,
+	// until TempUserEventEmailSent
+	TempUserEventEmailQueued TempUserEventType = "email_queued"
+	// TempUserEventViewed is recorded the first time the invite link is opened
+	TempUserEventViewed TempUserEventType = "viewed"
+	// TempUserEventUpdated is recorded when the name, role or email of a pending invite is changed
+	TempUserEventUpdated       TempUserEventType = "updated"
+	TempUserEventRevoked       TempUserEventType = "revoked"
+	TempUserEventCompleted     TempUserEventType = "completed"
+	TempUserEventExpired       TempUserEventType = "expired"
+	TempUserEventDeclined      TempUserEventType = "declined"
+	TempUserEventAccessExpired TempUserEventType = "access_expired"
+)
+
+// TempUserStatusEvent returns the event recorded when a temp user moves to the status.
+func TempUserStatusEvent(status TempUserStatus) (TempUserEventType, bool) {
+	switch status {
+	case TmpUserRevoked:
+		return TempUserEventRevoked, true
+	case TmpUserCompleted:
+		return TempUserEventCompleted, true
+	case TmpUserExpired:
+		return TempUserEventExpired, true
+	case TmpUserDeclined:
+		return TempUserEventDeclined, true
+	case TmpUserAccessExpired:
+		return TempUserEventAccessExpired, true
+	}
+	return "", false
+}
+
+// TempUserEvent records a step of the lifecycle of a temp user, so that admins can tell what
+// happened to an invite, e.g. whether its email was sent and its link opened.
+type TempUserEvent struct {
+	Id         int64
+	OrgId      int64
+	TempUserId int64
+	Event      TempUserEventType
+	// ActorUserId is the user who caused the event, 0 when unknown or done by Grafana
+	ActorUserId int64
+	Created     int64
+}
+
+type TempUserEventDTO struct {
+	Event       TempUserEventType `json:"event"`
+	ActorUserId int64             `json:"actorUserId,omitempty"`
+	ActorLogin  string            `json:"actorLogin,omitempty"`
+	Created     time.Time         `json:"created"`
+}
+
 // ---------------------
 // COMMANDS
 
@@ -54,6 +355,19 @@ type CreateTempUserCommand struct {
 	Code            string
 	Role            org.RoleType
 	RemoteAddr      string
+	// Delivery defaults to InviteDeliveryEmail
+	Delivery InviteDelivery
+	// EmailMatch defaults to InviteEmailMatchAny
+	EmailMatch InviteEmailMatch
+	// UniquePending makes the creation fail with ErrTempUserPendingExists when the email already
+	// has a pending invite to the organization created with UniquePending
+	UniquePending bool
+	// AccessExpires makes the invite a viewer token, see TempUser.AccessExpires
+	AccessExpires *time.Time
+	// InvitedByApiKeyId is set when the invite is created with an API key
+	InvitedByApiKeyId int64
+	// ExternalId is the identifier of the invitee in another system, see TempUser.ExternalId
+	ExternalId string
 
 	Result *TempUser
 }
@@ -61,22 +375,208 @@ type CreateTempUserCommand struct {
 type UpdateTempUserStatusCommand struct {
 	Code   string
 	Status TempUserStatus
+	// AccessUserID records the user who joined the organization with a viewer token
+	AccessUserID int64
+	// Completion records where the invite was completed from, when completing it
+	Completion *InviteCompletion
+}
+
+// InviteCompletion is the device and coarse location an invite was completed from.
+type InviteCompletion struct {
+	// UserID is the user who completed the invite
+	UserID     int64
+	RemoteAddr string
+	UserAgent  string
+	// Country is the ISO 3166-1 alpha-2 code of the country of the invitee, empty when unknown
+	Country string
+	// Anomaly is set when invites of the organization are rarely completed from the country
+	Anomaly bool
 }
 
+// ExpireTempUsersCommand expires the pending invites and sign ups created before OlderThan, and
+// the pending viewer tokens whose access has ended.
 type ExpireTempUsersCommand struct {
 	OlderThan time.Time
 
 	NumExpired int64
 }
 
+// GetExpiredTempUserAccessQuery returns the completed viewer tokens whose access ended before
+// Now, and which gave a membership of the organization.
+type GetExpiredTempUserAccessQuery struct {
+	Now time.Time
+
+	Result []*TempUser
+}
+
+// GetInviteCompletionCountriesQuery counts the completed invites of an organization by the
+// country they were completed from, invites completed from an unknown country are left out.
+type GetInviteCompletionCountriesQuery struct {
+	OrgID int64
+
+	Result map[string]int64
+}
+
+// GetInviteAuditQuery returns the invites of an organization completed from From (included) to To
+// (excluded), ordered by completion time and ID. Sign ups are not included.
+type GetInviteAuditQuery struct {
+	OrgID int64
+	From  time.Time
+	To    time.Time
+	// InvitedByUserID restricts the report to the invites created by the user when set
+	InvitedByUserID int64
+
+	Result []*InviteAuditEntry
+}
+
+// InviteAuditEntry is a membership of an organization given by an invite, as reported in compliance
+// exports. The approver is the user or API key who created the invite.
+type InviteAuditEntry struct {
+	InviteID   int64        `json:"inviteId"`
+	Email      string       `json:"email"`
+	Name       string       `json:"name"`
+	ExternalID string       `json:"externalId"`
+	Role       org.RoleType `json:"role"`
+	// AccessExpires is set for viewer tokens, which grant the Viewer role until that time
+	AccessExpires *time.Time `json:"accessExpires"`
+	Created       time.Time  `json:"created"`
+
+	ApproverUserID     int64  `json:"approverUserId"`
+	ApproverLogin      string `json:"approverLogin"`
+	ApproverAPIKeyID   int64  `json:"approverApiKeyId"`
+	ApproverAPIKeyName string `json:"approverApiKeyName"`
+
+	// the completing user and device are empty for invites completed before they were recorded
+	Completed           time.Time `json:"completed"`
+	CompletedUserID     int64     `json:"completedUserId"`
+	CompletedLogin      string    `json:"completedLogin"`
+	CompletedRemoteAddr string    `json:"completedRemoteAddr"`
+	CompletedUserAgent  string    `json:"completedUserAgent"`
+	CompletedCountry    string    `json:"completedCountry"`
+	CompletedAnomaly    bool      `json:"completedAnomaly"`
+}
+
+// ArchiveTempUsersCommand archives the closed invites and sign ups last updated before OlderThan.
+type ArchiveTempUsersCommand struct {
+	OlderThan time.Time
+
+	NumArchived int64
+}
+
 type UpdateTempUserWithEmailSentCommand struct {
 	Code string
 }
 
-type GetTempUsersQuery struct {
-	OrgId  int64
+// MarkTempUserEmailQueuedCommand records that the email of the invite waits to be sent.
+type MarkTempUserEmailQueuedCommand struct {
+	Code string
+}
+
+// MarkTempUserOpenedCommand records when the invite link was first opened.
+type MarkTempUserOpenedCommand struct {
+	Code string
+
+	// Opened is false if the link had been opened before
+	Opened bool
+}
+
+// AddTempUserGrantCommand adds a grant to an invite, or changes the permission of the grant
+// of the invite on the same resource.
+type AddTempUserGrantCommand struct {
+	OrgID        int64
+	TempUserID   int64
+	ResourceKind string
+	ResourceUID  string
+	Permission   string
+}
+
+// AddEmailSuppressionCommand adds the email to the suppression list, or changes the reason it is on it.
+type AddEmailSuppressionCommand struct {
 	Email  string
-	Status TempUserStatus
+	Reason string
+
+	Result *EmailSuppression
+}
+
+type RemoveEmailSuppressionCommand struct {
+	Email string
+}
+
+type GetEmailSuppressionsQuery struct {
+	Result []*EmailSuppression
+}
+
+// GetEmailSuppressionQuery fails with ErrEmailSuppressionNotFound when the email isn't suppressed.
+type GetEmailSuppressionQuery struct {
+	Email string
+
+	Result *EmailSuppression
+}
+
+// AddInviteNotificationsCommand notifies the admins of the organization of the invite and the
+// user who created it about the event, except the user who caused it and the users who muted it.
+type AddInviteNotificationsCommand struct {
+	TempUserID  int64
+	Event       InviteNotificationEvent
+	ActorUserID int64
+}
+
+// AddExpiringInviteNotificationsCommand notifies about the pending invites created between
+// CreatedAfter and CreatedBefore which weren't notified as expiring yet.
+type AddExpiringInviteNotificationsCommand struct {
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+
+	NumNotified int64
+}
+
+// GetInviteNotificationsQuery returns the latest invite notifications of the user in the organization.
+type GetInviteNotificationsQuery struct {
+	OrgID      int64
+	UserID     int64
+	UnreadOnly bool
+	Limit      int
+
+	Result []*InviteNotificationDTO
+}
+
+// MarkInviteNotificationsReadCommand marks the notifications with the given IDs as read, or all the
+// notifications of the user in the organization when there are none.
+type MarkInviteNotificationsReadCommand struct {
+	OrgID  int64
+	UserID int64
+	IDs    []int64
+}
+
+type GetInviteNotificationPreferencesQuery struct {
+	UserID int64
+
+	Result *InviteNotificationPreferences
+}
+
+type SetInviteNotificationPreferencesCommand struct {
+	UserID      int64
+	Preferences InviteNotificationPreferences
+}
+
+type GetTempUserGrantsQuery struct {
+	TempUserID int64
+
+	Result []*TempUserGrant
+}
+
+type GetTempUsersQuery struct {
+	OrgId    int64
+	Email    string
+	Status   TempUserStatus
+	Delivery InviteDelivery
+	// InvitedByUserID restricts the invites to the ones created by the user when set
+	InvitedByUserID int64
+	// InvitedBy restricts the invites to the ones created by automation or by users when set
+	InvitedBy InviteCreator
+	// EmailQueued restricts the invites to the ones whose queued email wasn't sent yet, see
+	// TempUserEventEmailQueued
+	EmailQueued bool
 
 	Result []*TempUserDTO
 }
@@ -87,19 +587,154 @@ type GetTempUserByCodeQuery struct {
 	Result *TempUserDTO
 }
 
+type GetTempUserByIDQuery struct {
+	OrgID int64
+	ID    int64
+
+	Result *TempUserDTO
+}
+
+// SearchTempUsersQuery pages through the invites of an organization, newest first.
+// Sign ups are not included.
+type SearchTempUsersQuery struct {
+	OrgID int64
+	// matched against the email and name of the invitee
+	Query    string
+	Status   TempUserStatus
+	Delivery InviteDelivery
+	// InvitedBy restricts the invites to the ones created by automation or by users when set
+	InvitedBy InviteCreator
+	Page      int
+	Limit     int
+	// Anomaly restricts the invites to the ones completed from an unusual country, see InviteCompletion
+	Anomaly bool
+	// InvitedByUserID restricts the invites to the ones created by the user when set
+	InvitedByUserID int64
+	// CountOnly only counts the invites, the invites of the result are left empty
+	CountOnly bool
+
+	Result SearchTempUsersQueryResult
+}
+
+type SearchTempUsersQueryResult struct {
+	TotalCount int64          `json:"totalCount"`
+	Invites    []*TempUserDTO `json:"invites"`
+	Page       int            `json:"page"`
+	PerPage    int            `json:"perPage"`
+}
+
+// UpdateTempUserCommand updates the fields of an invite which are set.
+// When Version is set, the update fails with ErrTempUserVersionMismatch if the
+// invite has been changed since that version was read.
+// The email of pending invites can be changed together with their code, which
+// invalidates the links sent to the previous address.
+type UpdateTempUserCommand struct {
+	OrgID   int64
+	ID      int64
+	Version *int
+	Name    *string
+	Role    *org.RoleType
+	Status  *TempUserStatus
+	Email   *string
+	Code    *string
+
+	Result *TempUserDTO
+}
+
+// GetTempUserHistoryQuery returns the events of the temp user with the code in the organization,
+// oldest first. InvitedByUserID restricts it to the temp users created by the user when set.
+type GetTempUserHistoryQuery struct {
+	OrgID           int64
+	Code            string
+	InvitedByUserID int64
+
+	Result []*TempUserEventDTO
+}
+
+// GetTempUsersForUserQuery returns the invites and sign ups sent by the user,
+// or sent to the user's email address in any case, in all organizations.
+type GetTempUsersForUserQuery struct {
+	UserID int64
+	Email  string
+
+	Result []*TempUserDTO
+}
+
+// EraseTempUserDataCommand removes the personal data of a user from temp users.
+// Temp users sent to the email address in any case are anonymized and no longer usable, and
+// the user is unlinked from the invites they sent.
+type EraseTempUserDataCommand struct {
+	UserID int64
+	Email  string
+
+	NumErased int64
+}
+
 type TempUserDTO struct {
-	Id             int64          `json:"id"`
-	OrgId          int64          `json:"orgId"`
-	Name           string         `json:"name"`
-	Email          string         `json:"email"`
-	Role           org.RoleType   `json:"role"`
-	InvitedByLogin string         `json:"invitedByLogin"`
-	InvitedByEmail string         `json:"invitedByEmail"`
-	InvitedByName  string         `json:"invitedByName"`
-	Code           string         `json:"code"`
-	Status         TempUserStatus `json:"status"`
-	Url            string         `json:"url"`
-	EmailSent      bool           `json:"emailSent"`
-	EmailSentOn    time.Time      `json:"emailSentOn"`
-	Created        time.Time      `json:"createdOn"`
+	Id             int64            `json:"id"`
+	OrgId          int64            `json:"orgId"`
+	OrgName        string           `json:"orgName"`
+	Name           string           `json:"name"`
+	Email          string           `json:"email"`
+	Role           org.RoleType     `json:"role"`
+	InvitedByLogin string           `json:"invitedByLogin"`
+	InvitedByEmail string           `json:"invitedByEmail"`
+	InvitedByName  string           `json:"invitedByName"`
+	Code           string           `json:"code"`
+	Status         TempUserStatus   `json:"status"`
+	Url            string           `json:"url"`
+	EmailSent      bool             `json:"emailSent"`
+	EmailSentOn    time.Time        `json:"emailSentOn"`
+	OpenedOn       *time.Time       `json:"openedOn"`
+	Delivery       InviteDelivery   `json:"delivery"`
+	EmailMatch     InviteEmailMatch `json:"emailMatch"`
+	AccessExpires  *time.Time       `json:"accessExpires,omitempty"`
+	Created        time.Time        `json:"createdOn"`
+	Version        int              `json:"-"`
+
+	// InvitedByServiceAccount is set when the inviter is a service account, InvitedByLogin is then
+	// the login of the service account
+	InvitedByServiceAccount bool `json:"invitedByServiceAccount,omitempty"`
+	// InvitedByUserId is the user who created the invite, 0 when it was created with an API key
+	InvitedByUserId int64 `json:"-"`
+	// InvitedByApiKeyId and InvitedByApiKeyName are set when the invite was created with an API key
+	InvitedByApiKeyId   int64  `json:"invitedByApiKeyId,omitempty"`
+	InvitedByApiKeyName string `json:"invitedByApiKeyName,omitempty"`
+
+	// Completed* are set for completed invites, see InviteCompletion
+	CompletedRemoteAddr string `json:"completedRemoteAddr,omitempty"`
+	CompletedUserAgent  string `json:"completedUserAgent,omitempty"`
+	CompletedCountry    string `json:"completedCountry,omitempty"`
+	CompletedAnomaly    bool   `json:"completedAnomaly,omitempty"`
+
+	ExternalId string `json:"externalId,omitempty"`
+}
+
+// InviteNotificationDTO is an invite notification with the invite it is about.
+type InviteNotificationDTO struct {
+	Id       int64                   `json:"id"`
+	Event    InviteNotificationEvent `json:"event"`
+	Read     bool                    `json:"read"`
+	Created  time.Time               `json:"created"`
+	InviteID int64                   `json:"inviteId"`
+	Email    string                  `json:"email"`
+	Name     string                  `json:"name"`
+	Role     org.RoleType            `json:"role"`
+	Status   TempUserStatus          `json:"status"`
+}
+
+// Inviter is the user who created an invite, as shown to the invitee.
+type Inviter struct {
+	Login            string
+	Name             string
+	Email            string
+	IsServiceAccount bool
+}
+
+// State returns the lifecycle state of the temp user, which distinguishes emailed invites from pending ones.
+func (t *TempUserDTO) State() TempUserStatus {
+	if t.Status == TmpUserInvitePending && t.EmailSent {
+		return TmpUserInviteEmailed
+	}
+	return t.Status
 }
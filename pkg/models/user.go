@@ -137,6 +137,7 @@ type UserSearchHitDTO struct {
 	LastSeenAtAge string               `json:"lastSeenAtAge"`
 	AuthLabels    []string             `json:"authLabels"`
 	AuthModule    AuthModuleConversion `json:"-"`
+	ExternalId    string               `json:"externalId,omitempty"`
 }
 
 type UserIdDTO struct {
@@ -122,7 +122,8 @@ type JSONData struct {
 	SkipDataQuery bool `json:"skipDataQuery"`
 
 	// App settings
-	AutoEnabled bool `json:"autoEnabled"`
+	AutoEnabled bool  `json:"autoEnabled"`
+	Hooks       Hooks `json:"hooks"`
 
 	// Datasource settings
 	Annotations  bool            `json:"annotations"`
@@ -153,6 +154,14 @@ func (d JSONData) DashboardIncludes() []*Includes {
 	return result
 }
 
+// Hooks are the resources of the backend of an app plugin which Grafana calls when events happen in
+// the organizations the app is enabled in.
+type Hooks struct {
+	// InviteCompleted is called with a POST request after a user joined an organization with an
+	// invite, see dtos.InviteCompletedHook
+	InviteCompleted string `json:"inviteCompleted,omitempty"`
+}
+
 // Route describes a plugin route that is defined in
 // the plugin.json file for a plugin.
 type Route struct {
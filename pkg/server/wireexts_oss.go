@@ -83,6 +83,8 @@ var wireExtsBasicSet = wire.NewSet(
 	wire.Bind(new(registry.UsageStatsProvidersRegistry), new(*usagestatssvcs.UsageStatsProvidersRegistry)),
 	ossaccesscontrol.ProvideDatasourcePermissionsService,
 	wire.Bind(new(accesscontrol.DatasourcePermissionsService), new(*ossaccesscontrol.DatasourcePermissionsService)),
+	ossaccesscontrol.ProvideRoleRestrictionService,
+	wire.Bind(new(accesscontrol.RoleRestrictionService), new(*ossaccesscontrol.OSSRoleRestrictionService)),
 )
 
 var wireExtsSet = wire.NewSet(
@@ -41,6 +41,14 @@ type RoleRegistry interface {
 	RegisterFixedRoles(ctx context.Context) error
 }
 
+// RoleRestrictionService tells which basic roles can be assigned in an organization, when they are
+// restricted or remapped to custom basic roles.
+type RoleRestrictionService interface {
+	// GetAssignableBasicRoles returns the basic roles which can be assigned in the organization,
+	// or nil when all valid roles can be assigned.
+	GetAssignableBasicRoles(ctx context.Context, orgID int64) ([]org.RoleType, error)
+}
+
 type Options struct {
 	ReloadCache bool
 }
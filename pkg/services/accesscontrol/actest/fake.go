@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/org"
 	"github.com/grafana/grafana/pkg/services/user"
 )
 
@@ -58,3 +59,14 @@ func (f FakeAccessControl) RegisterScopeAttributeResolver(prefix string, resolve
 func (f FakeAccessControl) IsDisabled() bool {
 	return f.ExpectedDisabled
 }
+
+var _ accesscontrol.RoleRestrictionService = new(FakeRoleRestrictionService)
+
+type FakeRoleRestrictionService struct {
+	ExpectedErr   error
+	ExpectedRoles []org.RoleType
+}
+
+func (f FakeRoleRestrictionService) GetAssignableBasicRoles(ctx context.Context, orgID int64) ([]org.RoleType, error) {
+	return f.ExpectedRoles, f.ExpectedErr
+}
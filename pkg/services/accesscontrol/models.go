@@ -319,6 +319,10 @@ const (
 	ActionOrgUsersAdd    = "org.users:add"
 	ActionOrgUsersRemove = "org.users:remove"
 	ActionOrgUsersWrite  = "org.users:write"
+	// Evaluate what a user of the organization can access, e.g. search results, without signing in as them
+	ActionOrgUsersImpersonate = "org.users:impersonate"
+
+	ActionOrgInvitesRead = "org.invites:read"
 
 	// LDAP actions
 	ActionLDAPUsersRead    = "ldap.user:read"
@@ -344,6 +348,11 @@ const (
 	// Users scope
 	ScopeUsersAll = "users:*"
 
+	// Invites scopes
+	ScopeInvitesAll = "invites:*"
+	// ScopeInvitesSelf matches the invites created by the signed in user
+	ScopeInvitesSelf = "invites:self"
+
 	// Settings scope
 	ScopeSettingsAll = "settings:*"
 
@@ -0,0 +1,18 @@
+package ossaccesscontrol
+
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/services/org"
+)
+
+func ProvideRoleRestrictionService() *OSSRoleRestrictionService {
+	return &OSSRoleRestrictionService{}
+}
+
+// OSSRoleRestrictionService doesn't restrict basic roles, all valid roles can be assigned.
+type OSSRoleRestrictionService struct{}
+
+func (s *OSSRoleRestrictionService) GetAssignableBasicRoles(ctx context.Context, orgID int64) ([]org.RoleType, error) {
+	return nil, nil
+}
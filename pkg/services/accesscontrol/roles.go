@@ -58,6 +58,14 @@ var (
 				Action: ActionOrgUsersRemove,
 				Scope:  ScopeUsersAll,
 			},
+			{
+				Action: ActionOrgInvitesRead,
+				Scope:  ScopeInvitesAll,
+			},
+			{
+				Action: ActionOrgUsersImpersonate,
+				Scope:  ScopeUsersAll,
+			},
 		}),
 	}
 
@@ -0,0 +1,18 @@
+package captcha
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrVerificationFailed = errors.New("captcha verification failed")
+	ErrMissingToken       = errors.New("captcha token is missing")
+)
+
+type Service interface {
+	// IsEnabled returns true when CAPTCHA verification is configured.
+	IsEnabled() bool
+	// Verify validates a CAPTCHA response token server-side with the configured provider.
+	Verify(ctx context.Context, token string, remoteIP string) error
+}
@@ -0,0 +1,101 @@
+package captchaimpl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/captcha"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+var verifyURLs = map[string]string{
+	setting.CaptchaProviderReCAPTCHA: "https://www.google.com/recaptcha/api/siteverify",
+	setting.CaptchaProviderHCaptcha:  "https://hcaptcha.com/siteverify",
+}
+
+type Service struct {
+	cfg    setting.CaptchaSettings
+	client *http.Client
+	log    log.Logger
+}
+
+func ProvideService(cfg *setting.Cfg) captcha.Service {
+	return &Service{
+		cfg:    cfg.Captcha,
+		client: &http.Client{Timeout: 10 * time.Second},
+		log:    log.New("captcha"),
+	}
+}
+
+func (s *Service) IsEnabled() bool {
+	return s.cfg.Enabled
+}
+
+type verifyResponse struct {
+	Success    bool     `json:"success"`
+	Score      *float64 `json:"score,omitempty"`
+	ErrorCodes []string `json:"error-codes,omitempty"`
+}
+
+func (s *Service) Verify(ctx context.Context, token string, remoteIP string) error {
+	if !s.cfg.Enabled {
+		return nil
+	}
+	if token == "" {
+		return captcha.ErrMissingToken
+	}
+
+	verifyURL := s.cfg.VerifyURL
+	if verifyURL == "" {
+		verifyURL = verifyURLs[s.cfg.Provider]
+	}
+
+	form := url.Values{}
+	form.Set("secret", s.cfg.SecretKey)
+	form.Set("response", token)
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, verifyURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to verify captcha: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			s.log.Warn("Failed to close response body", "err", err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to verify captcha: unexpected status code %d", resp.StatusCode)
+	}
+
+	var result verifyResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode captcha verification response: %w", err)
+	}
+
+	if !result.Success {
+		s.log.Debug("Captcha verification failed", "errorCodes", result.ErrorCodes)
+		return captcha.ErrVerificationFailed
+	}
+	if result.Score != nil && *result.Score < s.cfg.MinScore {
+		s.log.Debug("Captcha score below threshold", "score", *result.Score, "minScore", s.cfg.MinScore)
+		return captcha.ErrVerificationFailed
+	}
+
+	return nil
+}
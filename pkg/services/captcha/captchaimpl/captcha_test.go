@@ -0,0 +1,63 @@
+package captchaimpl
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/captcha"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestVerify(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "secret", r.PostForm.Get("secret"))
+		require.Equal(t, "127.0.0.1", r.PostForm.Get("remoteip"))
+
+		switch r.PostForm.Get("response") {
+		case "valid":
+			_, _ = w.Write([]byte(`{"success": true}`))
+		case "low-score":
+			_, _ = w.Write([]byte(`{"success": true, "score": 0.1}`))
+		default:
+			_, _ = w.Write([]byte(`{"success": false, "error-codes": ["invalid-input-response"]}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	newService := func(enabled bool) captcha.Service {
+		cfg := setting.NewCfg()
+		cfg.Captcha = setting.CaptchaSettings{
+			Enabled:   enabled,
+			Provider:  setting.CaptchaProviderHCaptcha,
+			SecretKey: "secret",
+			VerifyURL: server.URL,
+			MinScore:  0.5,
+		}
+		return ProvideService(cfg)
+	}
+
+	t.Run("disabled verification accepts anything", func(t *testing.T) {
+		require.NoError(t, newService(false).Verify(context.Background(), "", "127.0.0.1"))
+	})
+
+	t.Run("missing token", func(t *testing.T) {
+		require.ErrorIs(t, newService(true).Verify(context.Background(), "", "127.0.0.1"), captcha.ErrMissingToken)
+	})
+
+	t.Run("valid token", func(t *testing.T) {
+		require.NoError(t, newService(true).Verify(context.Background(), "valid", "127.0.0.1"))
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		require.ErrorIs(t, newService(true).Verify(context.Background(), "invalid", "127.0.0.1"), captcha.ErrVerificationFailed)
+	})
+
+	t.Run("score below threshold", func(t *testing.T) {
+		require.ErrorIs(t, newService(true).Verify(context.Background(), "low-score", "127.0.0.1"), captcha.ErrVerificationFailed)
+	})
+}
@@ -0,0 +1,22 @@
+package captchatest
+
+import (
+	"context"
+)
+
+type FakeService struct {
+	ExpectedEnabled bool
+	ExpectedError   error
+}
+
+func NewFakeService() *FakeService {
+	return &FakeService{}
+}
+
+func (f *FakeService) IsEnabled() bool {
+	return f.ExpectedEnabled
+}
+
+func (f *FakeService) Verify(ctx context.Context, token string, remoteIP string) error {
+	return f.ExpectedError
+}
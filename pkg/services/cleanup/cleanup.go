@@ -16,6 +16,7 @@ import (
 	"github.com/grafana/grafana/pkg/infra/serverlock"
 	"github.com/grafana/grafana/pkg/infra/tracing"
 	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
 	"github.com/grafana/grafana/pkg/services/annotations"
 	"github.com/grafana/grafana/pkg/services/dashboardsnapshots"
 	dashver "github.com/grafana/grafana/pkg/services/dashboardversion"
@@ -25,13 +26,15 @@ import (
 	"github.com/grafana/grafana/pkg/services/shorturls"
 	"github.com/grafana/grafana/pkg/services/sqlstore"
 	tempuser "github.com/grafana/grafana/pkg/services/temp_user"
+	"github.com/grafana/grafana/pkg/services/user"
 	"github.com/grafana/grafana/pkg/setting"
 )
 
 func ProvideService(cfg *setting.Cfg, serverLockService *serverlock.ServerLockService,
 	shortURLService shorturls.Service, sqlstore *sqlstore.SQLStore, queryHistoryService queryhistory.Service,
 	dashboardVersionService dashver.Service, dashSnapSvc dashboardsnapshots.Service, deleteExpiredImageService *image.DeleteExpiredService,
-	loginAttemptService loginattempt.Service, tempUserService tempuser.Service, tracer tracing.Tracer, annotationCleaner annotations.Cleaner) *CleanUpService {
+	loginAttemptService loginattempt.Service, tempUserService tempuser.Service, tracer tracing.Tracer, annotationCleaner annotations.Cleaner,
+	accesscontrolService accesscontrol.Service) *CleanUpService {
 	s := &CleanUpService{
 		Cfg:                       cfg,
 		ServerLockService:         serverLockService,
@@ -46,6 +49,7 @@ func ProvideService(cfg *setting.Cfg, serverLockService *serverlock.ServerLockSe
 		tempUserService:           tempUserService,
 		tracer:                    tracer,
 		annotationCleaner:         annotationCleaner,
+		accesscontrolService:      accesscontrolService,
 	}
 	return s
 }
@@ -64,6 +68,7 @@ type CleanUpService struct {
 	loginAttemptService       loginattempt.Service
 	tempUserService           tempuser.Service
 	annotationCleaner         annotations.Cleaner
+	accesscontrolService      accesscontrol.Service
 }
 
 type cleanUpJob struct {
@@ -104,6 +109,9 @@ func (srv *CleanUpService) clean(ctx context.Context) {
 		{"delete expired images", srv.deleteExpiredImages},
 		{"cleanup old annotations", srv.cleanUpOldAnnotations},
 		{"expire old user invites", srv.expireOldUserInvites},
+		{"notify expiring user invites", srv.notifyExpiringUserInvites},
+		{"archive closed user invites", srv.archiveClosedUserInvites},
+		{"revoke expired viewer tokens", srv.revokeExpiredViewerTokens},
 		{"delete stale short URLs", srv.deleteStaleShortURLs},
 		{"delete stale query history", srv.deleteStaleQueryHistory},
 		{"delete old login attempts", srv.deleteOldLoginAttempts},
@@ -269,6 +277,103 @@ func (srv *CleanUpService) expireOldUserInvites(ctx context.Context) {
 	}
 }
 
+// notifyExpiringUserInvites notifies about the pending invites expiring within the notice.
+func (srv *CleanUpService) notifyExpiringUserInvites(ctx context.Context) {
+	logger := srv.log.FromContext(ctx)
+	notice, maxInviteLifetime := srv.Cfg.InviteExpiryNotice, srv.Cfg.UserInviteMaxLifetime
+	if notice <= 0 || notice >= maxInviteLifetime {
+		return
+	}
+
+	now := time.Now()
+	cmd := models.AddExpiringInviteNotificationsCommand{
+		CreatedAfter:  now.Add(-maxInviteLifetime),
+		CreatedBefore: now.Add(notice - maxInviteLifetime),
+	}
+
+	if err := srv.tempUserService.AddExpiringInviteNotifications(ctx, &cmd); err != nil {
+		logger.Error("Problem notifying expiring user invites", "error", err.Error())
+	} else {
+		logger.Debug("Notified expiring user invites", "notifications", cmd.NumNotified)
+	}
+}
+
+func (srv *CleanUpService) archiveClosedUserInvites(ctx context.Context) {
+	logger := srv.log.FromContext(ctx)
+	if srv.Cfg.InviteArchiveAfter <= 0 {
+		return
+	}
+
+	cmd := models.ArchiveTempUsersCommand{
+		OlderThan: time.Now().Add(-srv.Cfg.InviteArchiveAfter),
+	}
+
+	if err := srv.tempUserService.ArchiveTempUsers(ctx, &cmd); err != nil {
+		logger.Error("Problem archiving user invites", "error", err.Error())
+	} else {
+		logger.Debug("Archived user invites", "rows affected", cmd.NumArchived)
+	}
+}
+
+// revokeExpiredViewerTokens removes the users who joined an organization with a viewer token from
+// it once the token has ended, with their permissions in the organization. Users keep their account,
+// and members whose role was changed since they joined keep their membership.
+func (srv *CleanUpService) revokeExpiredViewerTokens(ctx context.Context) {
+	logger := srv.log.FromContext(ctx)
+
+	query := models.GetExpiredTempUserAccessQuery{Now: time.Now()}
+	if err := srv.tempUserService.GetExpiredTempUserAccess(ctx, &query); err != nil {
+		logger.Error("Problem getting expired viewer tokens", "error", err.Error())
+		return
+	}
+
+	var revoked int
+	for _, token := range query.Result {
+		if err := srv.removeViewerTokenMember(ctx, token); err != nil {
+			logger.Error("Problem removing the user of an expired viewer token", "inviteId", token.Id, "userId", token.AccessUserId, "error", err.Error())
+			continue
+		}
+		statusCmd := models.UpdateTempUserStatusCommand{Code: token.Code, Status: models.TmpUserAccessExpired}
+		if err := srv.tempUserService.UpdateTempUserStatus(ctx, &statusCmd); err != nil {
+			logger.Error("Problem updating expired viewer token", "inviteId", token.Id, "error", err.Error())
+			continue
+		}
+		revoked++
+	}
+	logger.Debug("Revoked expired viewer tokens", "rows affected", revoked)
+}
+
+// removeViewerTokenMember removes the user of a viewer token from the organization, unless they
+// left it or were given another role than the one of the token meanwhile.
+func (srv *CleanUpService) removeViewerTokenMember(ctx context.Context, token *models.TempUser) error {
+	orgsQuery := models.GetUserOrgListQuery{UserId: token.AccessUserId}
+	if err := srv.store.GetUserOrgList(ctx, &orgsQuery); err != nil {
+		return err
+	}
+	var member *models.UserOrgDTO
+	for _, o := range orgsQuery.Result {
+		if o.OrgId == token.OrgId {
+			member = o
+		}
+	}
+	if member == nil || member.Role != token.Role {
+		return nil
+	}
+
+	removeCmd := models.RemoveOrgUserCommand{OrgId: token.OrgId, UserId: token.AccessUserId}
+	if err := srv.store.RemoveOrgUser(ctx, &removeCmd); err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+	// This should be called from appropriate service when moved
+	if err := srv.accesscontrolService.DeleteUserPermissions(ctx, token.OrgId, token.AccessUserId); err != nil {
+		srv.log.FromContext(ctx).Warn("Failed to delete permissions for user", "userID", token.AccessUserId, "orgID", token.OrgId, "error", err)
+	}
+	return nil
+}
+
 func (srv *CleanUpService) deleteStaleShortURLs(ctx context.Context) {
 	logger := srv.log.FromContext(ctx)
 	cmd := models.DeleteShortUrlCommand{
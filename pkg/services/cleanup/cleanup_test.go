@@ -1,9 +1,18 @@
 package cleanup
 
 import (
+	"context"
 	"testing"
 	"time"
 
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	accesscontrolmock "github.com/grafana/grafana/pkg/services/accesscontrol/mock"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/temp_user/tempuserimpl"
+	"github.com/grafana/grafana/pkg/services/user"
 	"github.com/grafana/grafana/pkg/setting"
 	"github.com/stretchr/testify/require"
 )
@@ -35,3 +44,55 @@ func TestCleanUpTmpFiles(t *testing.T) {
 		require.False(t, service.shouldCleanupTempFile(weekAgo, now))
 	})
 }
+
+func TestIntegrationRevokeExpiredViewerTokens(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+	sqlStore := sqlstore.InitTestDB(t)
+	// the first user creates the organization and is made its admin
+	_, err := sqlStore.CreateUser(ctx, user.CreateUserCommand{Login: "admin"})
+	require.NoError(t, err)
+	sqlStore.Cfg.AutoAssignOrg = true
+	t.Cleanup(func() { sqlStore.Cfg.AutoAssignOrg = false })
+	viewer, err := sqlStore.CreateUser(ctx, user.CreateUserCommand{Login: "viewer", OrgID: 1, DefaultOrgRole: string(org.RoleViewer)})
+	require.NoError(t, err)
+	promoted, err := sqlStore.CreateUser(ctx, user.CreateUserCommand{Login: "promoted", OrgID: 1, DefaultOrgRole: string(org.RoleViewer)})
+	require.NoError(t, err)
+	require.NoError(t, sqlStore.UpdateOrgUser(ctx, &models.UpdateOrgUserCommand{OrgId: 1, UserId: promoted.ID, Role: org.RoleEditor}))
+
+	tempUserService := tempuserimpl.ProvideService(sqlStore)
+	ended := time.Now().Add(-time.Minute)
+	for code, userID := range map[string]int64{"viewer": viewer.ID, "promoted": promoted.ID} {
+		token := models.CreateTempUserCommand{OrgId: 1, Email: code + "@example.com", Code: code, Role: org.RoleViewer, Status: models.TmpUserInvitePending, AccessExpires: &ended}
+		require.NoError(t, tempUserService.CreateTempUser(ctx, &token))
+		require.NoError(t, tempUserService.UpdateTempUserStatus(ctx, &models.UpdateTempUserStatusCommand{Code: code, Status: models.TmpUserCompleted, AccessUserID: userID}))
+	}
+
+	ac := accesscontrolmock.New()
+	service := CleanUpService{
+		log:                  log.New("cleanup"),
+		store:                sqlStore,
+		tempUserService:      tempUserService,
+		accesscontrolService: ac,
+	}
+	service.revokeExpiredViewerTokens(ctx)
+
+	orgUsers := models.GetOrgUsersQuery{OrgId: 1, User: &user.SignedInUser{OrgID: 1, Permissions: map[int64]map[string][]string{1: {accesscontrol.ActionOrgUsersRead: {accesscontrol.ScopeUsersAll}}}}}
+	require.NoError(t, sqlStore.GetOrgUsers(ctx, &orgUsers))
+	logins := make([]string, 0, len(orgUsers.Result))
+	for _, u := range orgUsers.Result {
+		logins = append(logins, u.Login)
+	}
+	require.ElementsMatch(t, []string{"admin", "promoted"}, logins, "members promoted since they joined are kept")
+	require.Equal(t, []interface{}{ctx, int64(1), viewer.ID}, ac.Calls.DeleteUserPermissions[0])
+	require.Len(t, ac.Calls.DeleteUserPermissions, 1)
+
+	for _, code := range []string{"viewer", "promoted"} {
+		query := models.GetTempUserByCodeQuery{Code: code}
+		require.NoError(t, tempUserService.GetTempUserByCode(ctx, &query))
+		require.Equal(t, models.TmpUserAccessExpired, query.Result.Status, code)
+	}
+}
@@ -288,5 +288,15 @@ var (
 			Name:        "increaseInMemDatabaseQueryCache",
 			Description: "Enable more in memory caching for database queries",
 		},
+		{
+			Name:        "searchSemantic",
+			Description: "Blend the similarity of dashboard embeddings with keyword scores in search",
+			State:       FeatureStateAlpha,
+		},
+		{
+			Name:        "searchPersonalization",
+			Description: "Rank the search results of users with the dashboards they recently opened from search",
+			State:       FeatureStateAlpha,
+		},
 	}
 )
@@ -214,4 +214,12 @@ const (
 	// FlagIncreaseInMemDatabaseQueryCache
 	// Enable more in memory caching for database queries
 	FlagIncreaseInMemDatabaseQueryCache = "increaseInMemDatabaseQueryCache"
+
+	// FlagSearchSemantic
+	// Blend the similarity of dashboard embeddings with keyword scores in search
+	FlagSearchSemantic = "searchSemantic"
+
+	// FlagSearchPersonalization
+	// Rank the search results of users with the dashboards they recently opened from search
+	FlagSearchPersonalization = "searchPersonalization"
 )
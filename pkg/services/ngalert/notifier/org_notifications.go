@@ -0,0 +1,82 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+
+	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+)
+
+var ErrReceiverNotFound = errors.New("contact point not found")
+
+// HasReceiver tells whether the configuration of the Alertmanager has a contact point with the name.
+func (am *Alertmanager) HasReceiver(name string) bool {
+	return am.getReceiver(name) != nil
+}
+
+func (am *Alertmanager) getReceiver(name string) *apimodels.PostableApiReceiver {
+	am.reloadConfigMtx.RLock()
+	defer am.reloadConfigMtx.RUnlock()
+	if am.config == nil {
+		return nil
+	}
+	for _, receiver := range am.config.AlertmanagerConfig.Receivers {
+		if receiver.Name == name {
+			return receiver
+		}
+	}
+	return nil
+}
+
+// NotifyReceiver sends an organizational message which isn't about an alert, e.g. an invite, to
+// each integration of a contact point. The message is an alert with the labels and annotations,
+// templated by the contact point like any other alert, and is not routed, grouped or silenced.
+// Notifications failing with a recoverable error are retried until the context is done.
+func (am *Alertmanager) NotifyReceiver(ctx context.Context, name string, labels, annotations model.LabelSet) error {
+	receiver := am.getReceiver(name)
+	if receiver == nil {
+		return ErrReceiverNotFound
+	}
+	tmpl, err := am.getTemplate()
+	if err != nil {
+		return fmt.Errorf("failed to get template: %w", err)
+	}
+	integrations, err := am.buildReceiverIntegrations(receiver, tmpl)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	alert := &types.Alert{
+		Alert: model.Alert{
+			Labels:      labels,
+			Annotations: annotations,
+			StartsAt:    now,
+		},
+		UpdatedAt: now,
+	}
+	// like for test notifications, the group key is unique so that receivers don't deduplicate
+	// the message
+	ctx = notify.WithGroupKey(ctx, labels.String()+now.String())
+	ctx = notify.WithGroupLabels(ctx, labels)
+	ctx = notify.WithReceiverName(ctx, name)
+	ctx = notify.WithFiringAlerts(ctx, []uint64{uint64(labels.Fingerprint())})
+	ctx = notify.WithResolvedAlerts(ctx, nil)
+
+	var errs []error
+	for _, integration := range integrations {
+		if _, _, err := notify.NewRetryStage(integration, name, am.stageMetrics).Exec(ctx, am.logger, alert); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to notify %d of %d integrations of contact point %s: %w", len(errs), len(integrations), name, errs[0])
+	}
+	return nil
+}
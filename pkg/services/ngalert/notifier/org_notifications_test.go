@@ -0,0 +1,64 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/models"
+	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/notifications"
+)
+
+func TestNotifyReceiver(t *testing.T) {
+	am := setupAMTest(t)
+	ns := notifications.MockNotificationService()
+	am.NotificationService = ns
+
+	cfg := apimodels.PostableUserConfig{}
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"alertmanager_config": {
+			"route": {"receiver": "onboarding"},
+			"receivers": [{
+				"name": "onboarding",
+				"grafana_managed_receiver_configs": [{"uid": "hook", "name": "onboarding", "type": "webhook", "settings": {"url": "http://localhost/invites"}}]
+			}]
+		}
+	}`), &cfg))
+	require.NoError(t, am.SaveAndApplyConfig(context.Background(), &cfg))
+
+	require.True(t, am.HasReceiver("onboarding"))
+	require.False(t, am.HasReceiver("unknown"))
+
+	labels := model.LabelSet{"alertname": "OrgInvite", "invitee": "new@example.com"}
+	annotations := model.LabelSet{"summary": "Invite to Main Org."}
+	require.NoError(t, am.NotifyReceiver(context.Background(), "onboarding", labels, annotations))
+	require.Equal(t, "http://localhost/invites", ns.Webhook.Url)
+
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(ns.Webhook.Body), &body))
+	require.Equal(t, "onboarding", body["receiver"])
+	alerts := body["alerts"].([]interface{})
+	require.Len(t, alerts, 1)
+	alert := alerts[0].(map[string]interface{})
+	require.Equal(t, "new@example.com", alert["labels"].(map[string]interface{})["invitee"])
+	require.Equal(t, "Invite to Main Org.", alert["annotations"].(map[string]interface{})["summary"])
+
+	t.Run("unknown contact point", func(t *testing.T) {
+		require.ErrorIs(t, am.NotifyReceiver(context.Background(), "unknown", labels, annotations), ErrReceiverNotFound)
+	})
+
+	t.Run("failed notifications", func(t *testing.T) {
+		ns.WebhookHandler = func(context.Context, *models.SendWebhookSync) error {
+			return errors.New("unavailable")
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		require.ErrorContains(t, am.NotifyReceiver(ctx, "onboarding", labels, annotations), "unavailable")
+	})
+}
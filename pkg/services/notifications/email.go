@@ -22,6 +22,14 @@ type Message struct {
 	ReplyTo       []string
 	EmbeddedFiles []string
 	AttachedFiles []*AttachedFile
+	// MessageID is set as the Message-ID header when not empty
+	MessageID string
+	// ContentTypes of the body alternatives in descending preference, the configured content
+	// types are used when empty
+	ContentTypes []string
+
+	// sent is called once the message was sent or failed, for messages queued with QueueEmail
+	sent func(error)
 }
 
 func setDefaultTemplateData(cfg *setting.Cfg, data map[string]interface{}, u *user.User) {
@@ -30,6 +38,7 @@ func setDefaultTemplateData(cfg *setting.Cfg, data map[string]interface{}, u *us
 	data["BuildStamp"] = setting.BuildStamp
 	data["EmailCodeValidHours"] = cfg.EmailCodeValidMinutes / 60
 	data["Subject"] = map[string]interface{}{}
+	data["Preheader"] = map[string]interface{}{}
 	if u != nil {
 		data["Name"] = u.NameOrFallback()
 	}
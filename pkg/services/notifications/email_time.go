@@ -0,0 +1,70 @@
+package notifications
+
+import (
+	"strings"
+	"time"
+)
+
+// EmailTime is a point in time shown in emails, formatted in the timezone and with the locale of
+// the organization the email is about. Templates print it formatted, its UTC method returns the
+// absolute UTC timestamp, e.g. for a data attribute:
+//
+//	<time datetime="{{.Expires.UTC}}" data-utc="{{.Expires.UTC}}">{{.Expires}}</time>
+type EmailTime struct {
+	Time     time.Time
+	Location *time.Location
+	Locale   string
+}
+
+// emailTimeLayouts are the layouts of times by locale and language, month names are only spelled
+// out in English as emails aren't translated.
+var emailTimeLayouts = map[string]string{
+	"en-us": "January 2, 2006 at 3:04 PM MST",
+	"en":    "2 January 2006 at 15:04 MST",
+	"de":    "02.01.2006, 15:04 MST",
+	"fr":    "02/01/2006 15:04 MST",
+	"es":    "02/01/2006 15:04 MST",
+	"zh":    "2006-01-02 15:04 MST",
+}
+
+const defaultEmailTimeLayout = "2006-01-02 15:04 MST"
+
+// NewEmailTime returns the time for emails in the timezone, an IANA name, and the locale, e.g.
+// en-US. Empty, browser and unknown timezones fall back to UTC, and unknown locales to ISO 8601.
+func NewEmailTime(t time.Time, timezone, locale string) EmailTime {
+	location := time.UTC
+	switch strings.ToLower(timezone) {
+	case "", "browser", "utc":
+	default:
+		if l, err := time.LoadLocation(timezone); err == nil {
+			location = l
+		}
+	}
+	return EmailTime{Time: t, Location: location, Locale: locale}
+}
+
+func (t EmailTime) layout() string {
+	locale := strings.ToLower(t.Locale)
+	if layout, ok := emailTimeLayouts[locale]; ok {
+		return layout
+	}
+	language, _, _ := strings.Cut(locale, "-")
+	if layout, ok := emailTimeLayouts[language]; ok {
+		return layout
+	}
+	return defaultEmailTimeLayout
+}
+
+// String returns the time formatted for the timezone and locale.
+func (t EmailTime) String() string {
+	location := t.Location
+	if location == nil {
+		location = time.UTC
+	}
+	return t.Time.In(location).Format(t.layout())
+}
+
+// UTC returns the time as an RFC 3339 UTC timestamp.
+func (t EmailTime) UTC() string {
+	return t.Time.UTC().Format(time.RFC3339)
+}
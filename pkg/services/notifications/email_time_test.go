@@ -0,0 +1,31 @@
+package notifications
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmailTime(t *testing.T) {
+	instant := time.Date(2022, time.March, 4, 17, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		timezone string
+		locale   string
+		expected string
+	}{
+		{timezone: "America/New_York", locale: "en-US", expected: "March 4, 2022 at 12:30 PM EST"},
+		{timezone: "Europe/London", locale: "en-GB", expected: "4 March 2022 at 17:30 GMT"},
+		{timezone: "Europe/Berlin", locale: "de-DE", expected: "04.03.2022, 18:30 CET"},
+		{timezone: "browser", locale: "fr-FR", expected: "04/03/2022 17:30 UTC"},
+		{timezone: "Mars/Olympus", locale: "", expected: "2022-03-04 17:30 UTC"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.timezone+" "+tt.locale, func(t *testing.T) {
+			emailTime := NewEmailTime(instant, tt.timezone, tt.locale)
+			require.Equal(t, tt.expected, emailTime.String())
+			require.Equal(t, "2022-03-04T17:30:00Z", emailTime.UTC())
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package notifications
+
+import (
+	"time"
+)
+
+// smtpProbeKey is the key of the SMTP connectivity probe in the probe group, concurrent callers
+// share the probe in flight.
+const smtpProbeKey = "smtp"
+
+// smtpProbeCacheTTL defines for how long the result of an SMTP connectivity
+// probe is reused before the SMTP server is dialed again.
+const smtpProbeCacheTTL = time.Minute
+
+// Pinger is implemented by mailers which are able to verify connectivity
+// to the underlying mail server without sending a message.
+type Pinger interface {
+	Ping() error
+}
+
+type SMTPStatus struct {
+	Enabled   bool      `json:"enabled"`
+	Connected bool      `json:"connected"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// SMTPStatus returns the result of the latest SMTP connectivity probe. The
+// probe is only executed again once the cached result is older than smtpProbeCacheTTL.
+// The SMTP server is dialed without holding any lock, so that a slow server doesn't hold up
+// the emails being sent.
+func (ns *NotificationService) SMTPStatus() SMTPStatus {
+	if !ns.Cfg.Smtp.Enabled {
+		return SMTPStatus{Enabled: false, CheckedAt: time.Now()}
+	}
+
+	if cached, ok := ns.cachedSMTPStatus(); ok {
+		return cached
+	}
+
+	status, _, _ := ns.smtpProbe.Do(smtpProbeKey, func() (interface{}, error) {
+		// the probe of another caller may have completed meanwhile
+		if cached, ok := ns.cachedSMTPStatus(); ok {
+			return cached, nil
+		}
+		status := SMTPStatus{Enabled: true, Connected: true, CheckedAt: time.Now()}
+		if pinger, ok := ns.mailer.(Pinger); ok {
+			if err := pinger.Ping(); err != nil {
+				ns.log.Warn("SMTP connectivity probe failed", "error", err)
+				status.Connected = false
+				status.Error = err.Error()
+			}
+		}
+		ns.smtpStatusMu.Lock()
+		ns.smtpStatus = &status
+		ns.smtpStatusMu.Unlock()
+		return status, nil
+	})
+	return status.(SMTPStatus)
+}
+
+func (ns *NotificationService) cachedSMTPStatus() (SMTPStatus, bool) {
+	ns.smtpStatusMu.Lock()
+	defer ns.smtpStatusMu.Unlock()
+	if ns.smtpStatus == nil || time.Since(ns.smtpStatus.CheckedAt) >= smtpProbeCacheTTL {
+		return SMTPStatus{}, false
+	}
+	return *ns.smtpStatus, true
+}
+
+// MailQueueDepth returns the number of emails waiting to be sent asynchronously.
+func (ns *NotificationService) MailQueueDepth() int {
+	return len(ns.mailQueue)
+}
+
+// LastEmailSentAt returns the time of the latest successfully sent email,
+// or the zero time if no email has been sent since startup.
+func (ns *NotificationService) LastEmailSentAt() time.Time {
+	ns.healthMu.Lock()
+	defer ns.healthMu.Unlock()
+	return ns.lastEmailSentAt
+}
+
+func (ns *NotificationService) recordEmailSent(sentAt time.Time) {
+	ns.healthMu.Lock()
+	defer ns.healthMu.Unlock()
+	ns.lastEmailSentAt = sentAt
+}
@@ -0,0 +1,112 @@
+package notifications
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSMTPHealth(t *testing.T) {
+	bus := newBus(t)
+
+	t.Run("When SMTP is disabled", func(t *testing.T) {
+		cfg := createSmtpConfig()
+		cfg.Smtp.Enabled = false
+		ns, _, err := createSutWithConfig(t, bus, cfg)
+		require.NoError(t, err)
+
+		status := ns.SMTPStatus()
+		require.False(t, status.Enabled)
+		require.False(t, status.Connected)
+	})
+
+	t.Run("When SMTP server is reachable", func(t *testing.T) {
+		ns, _ := createSut(t, bus)
+
+		status := ns.SMTPStatus()
+		require.True(t, status.Enabled)
+		require.True(t, status.Connected)
+		require.Empty(t, status.Error)
+	})
+
+	t.Run("When SMTP server is unreachable", func(t *testing.T) {
+		ns := createDisconnectedSut(t, bus)
+
+		status := ns.SMTPStatus()
+		require.True(t, status.Enabled)
+		require.False(t, status.Connected)
+		require.Equal(t, "connect: connection refused", status.Error)
+
+		cached := ns.SMTPStatus()
+		require.Equal(t, status.CheckedAt, cached.CheckedAt)
+	})
+
+	t.Run("When the SMTP server is slow emails are still recorded", func(t *testing.T) {
+		cfg := createSmtpConfig()
+		mailer := &slowPingMailer{FakeMailer: NewFakeMailer(), pinged: make(chan struct{}), release: make(chan struct{})}
+		ns, err := ProvideService(bus, cfg, mailer, nil)
+		require.NoError(t, err)
+
+		probed := make(chan SMTPStatus, 2)
+		for i := 0; i < 2; i++ {
+			go func() { probed <- ns.SMTPStatus() }()
+		}
+		<-mailer.pinged
+		ns.recordEmailSent(time.Now())
+		require.False(t, ns.LastEmailSentAt().IsZero())
+
+		close(mailer.release)
+		first, second := <-probed, <-probed
+		require.True(t, first.Connected)
+		require.Equal(t, first.CheckedAt, second.CheckedAt)
+		require.Equal(t, int32(1), atomic.LoadInt32(&mailer.pings), "concurrent callers share the probe")
+	})
+
+	t.Run("When an email is sent the last sent timestamp is recorded", func(t *testing.T) {
+		ns, _ := createSut(t, bus)
+		require.True(t, ns.LastEmailSentAt().IsZero())
+
+		err := ns.SendEmailCommandHandlerSync(context.Background(), &models.SendEmailCommandSync{
+			SendEmailCommand: models.SendEmailCommand{
+				Subject:  "subject",
+				To:       []string{"asdf@grafana.com"},
+				Template: "welcome_on_signup",
+			},
+		})
+		require.NoError(t, err)
+		require.False(t, ns.LastEmailSentAt().IsZero())
+	})
+
+	t.Run("When emails are queued the queue depth is reported", func(t *testing.T) {
+		ns, _ := createSut(t, bus)
+		require.Equal(t, 0, ns.MailQueueDepth())
+
+		err := ns.SendEmailCommandHandler(context.Background(), &models.SendEmailCommand{
+			Subject:  "subject",
+			To:       []string{"asdf@grafana.com"},
+			Template: "welcome_on_signup",
+		})
+		require.NoError(t, err)
+		require.Equal(t, 1, ns.MailQueueDepth())
+	})
+}
+
+// slowPingMailer blocks probes until released.
+type slowPingMailer struct {
+	*FakeMailer
+	pings   int32
+	pinged  chan struct{}
+	release chan struct{}
+}
+
+func (m *slowPingMailer) Ping() error {
+	if atomic.AddInt32(&m.pings, 1) == 1 {
+		close(m.pinged)
+	}
+	<-m.release
+	return nil
+}
@@ -0,0 +1,182 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/grafana/grafana/pkg/models"
+)
+
+const (
+	// defaultEmailSendTime is the time an email is assumed to take to be sent until one was sent
+	defaultEmailSendTime = 2 * time.Second
+	// emailDeliveryRetention is how long the delivery state of queued emails is kept once sent or failed
+	emailDeliveryRetention = 24 * time.Hour
+	// defaultMaxEmailBacklog is how many emails can wait in the backlog for room in the mail queue
+	defaultMaxEmailBacklog = 1000
+)
+
+// ErrMailBacklogFull is returned by QueueEmail when too many emails are already waiting to be sent.
+var ErrMailBacklogFull = errors.New("too many emails are waiting to be sent")
+
+type EmailDeliveryState string
+
+const (
+	EmailDeliveryQueued EmailDeliveryState = "queued"
+	EmailDeliverySent   EmailDeliveryState = "sent"
+	EmailDeliveryFailed EmailDeliveryState = "failed"
+)
+
+// EmailDelivery is the delivery state of an email queued with QueueEmail.
+type EmailDelivery struct {
+	State EmailDeliveryState
+	// Position is the number of emails to send up to this one, for queued emails
+	Position int
+	// EstimatedDelivery is when a queued email is expected to be sent
+	EstimatedDelivery time.Time
+	// Finished is when the email was sent or failed
+	Finished time.Time
+	Error    string
+}
+
+// queuedEmail is an email waiting in the backlog for room in the mail queue.
+type queuedEmail struct {
+	key string
+	msg *Message
+}
+
+// MailQueueSaturated returns true when emails can't be queued without waiting for the mail queue
+// to be drained, i.e. sending them with SendEmailCommandHandler would block.
+func (ns *NotificationService) MailQueueSaturated() bool {
+	ns.backlogMu.Lock()
+	defer ns.backlogMu.Unlock()
+	return len(ns.backlog) > 0 || len(ns.mailQueue) == cap(ns.mailQueue)
+}
+
+// MailBacklogFull returns true when QueueEmail would fail with ErrMailBacklogFull.
+func (ns *NotificationService) MailBacklogFull() bool {
+	ns.backlogMu.Lock()
+	defer ns.backlogMu.Unlock()
+	ns.drainBacklog()
+	return len(ns.backlog) >= ns.maxBacklog
+}
+
+// QueueEmail queues an email without blocking, in a backlog when the mail queue is full. Its
+// delivery state can be followed with EmailDelivery using the given key, and onSent is called
+// once it was sent or failed. The backlog is kept in memory only, so that callers need to queue
+// the emails left unsent again after a restart, and is bounded: ErrMailBacklogFull is returned
+// when it is full.
+func (ns *NotificationService) QueueEmail(ctx context.Context, cmd *models.SendEmailCommand, key string, onSent func(error)) (EmailDelivery, error) {
+	msg, err := ns.buildEmailMessage(cmd)
+	if err != nil {
+		return EmailDelivery{}, err
+	}
+	msg.sent = func(err error) {
+		ns.recordDelivery(key, err)
+		if onSent != nil {
+			onSent(err)
+		}
+	}
+
+	ns.backlogMu.Lock()
+	ns.drainBacklog()
+	if len(ns.backlog) >= ns.maxBacklog {
+		ns.backlogMu.Unlock()
+		return EmailDelivery{}, ErrMailBacklogFull
+	}
+	ns.pruneDeliveries()
+	ns.backlog = append(ns.backlog, &queuedEmail{key: key, msg: msg})
+	ns.deliveries[key] = &EmailDelivery{State: EmailDeliveryQueued}
+	ns.drainBacklog()
+	delivery := ns.emailDelivery(key)
+	ns.backlogMu.Unlock()
+
+	return delivery, nil
+}
+
+// EmailDelivery returns the delivery state of an email queued with QueueEmail, and false if no
+// email was queued with the key or its state isn't retained anymore.
+func (ns *NotificationService) EmailDelivery(key string) (EmailDelivery, bool) {
+	ns.backlogMu.Lock()
+	defer ns.backlogMu.Unlock()
+	if _, ok := ns.deliveries[key]; !ok {
+		return EmailDelivery{}, false
+	}
+	return ns.emailDelivery(key), true
+}
+
+// emailDelivery must be called with backlogMu held.
+func (ns *NotificationService) emailDelivery(key string) EmailDelivery {
+	delivery := *ns.deliveries[key]
+	if delivery.State != EmailDeliveryQueued {
+		return delivery
+	}
+
+	// emails moved to the mail queue are somewhere in it
+	delivery.Position = len(ns.mailQueue)
+	for i, email := range ns.backlog {
+		if email.key == key {
+			delivery.Position += i + 1
+			break
+		}
+	}
+	if delivery.Position == 0 {
+		delivery.Position = 1
+	}
+	delivery.EstimatedDelivery = time.Now().Add(time.Duration(delivery.Position) * ns.emailSendTime())
+	return delivery
+}
+
+// drainBacklog moves emails from the backlog to the mail queue while there is room. It must be
+// called with backlogMu held.
+func (ns *NotificationService) drainBacklog() {
+	for len(ns.backlog) > 0 {
+		select {
+		case ns.mailQueue <- ns.backlog[0].msg:
+			ns.backlog[0] = nil
+			ns.backlog = ns.backlog[1:]
+		default:
+			return
+		}
+	}
+}
+
+func (ns *NotificationService) recordDelivery(key string, err error) {
+	ns.backlogMu.Lock()
+	defer ns.backlogMu.Unlock()
+	delivery := &EmailDelivery{State: EmailDeliverySent, Finished: time.Now()}
+	if err != nil {
+		delivery.State = EmailDeliveryFailed
+		delivery.Error = err.Error()
+	}
+	ns.deliveries[key] = delivery
+}
+
+// pruneDeliveries must be called with backlogMu held.
+func (ns *NotificationService) pruneDeliveries() {
+	for key, delivery := range ns.deliveries {
+		if delivery.State != EmailDeliveryQueued && time.Since(delivery.Finished) > emailDeliveryRetention {
+			delete(ns.deliveries, key)
+		}
+	}
+}
+
+// recordSendTime updates the moving average of the time taken to send an email.
+func (ns *NotificationService) recordSendTime(elapsed time.Duration) {
+	ns.backlogMu.Lock()
+	defer ns.backlogMu.Unlock()
+	if ns.avgSendTime == 0 {
+		ns.avgSendTime = elapsed
+		return
+	}
+	ns.avgSendTime = (4*ns.avgSendTime + elapsed) / 5
+}
+
+// emailSendTime must be called with backlogMu held.
+func (ns *NotificationService) emailSendTime() time.Duration {
+	if ns.avgSendTime == 0 {
+		return defaultEmailSendTime
+	}
+	return ns.avgSendTime
+}
@@ -0,0 +1,124 @@
+package notifications
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/models"
+)
+
+func TestQueueEmail(t *testing.T) {
+	bus := newBus(t)
+	cmd := func(to string) *models.SendEmailCommand {
+		return &models.SendEmailCommand{Subject: "subject", To: []string{to}, Template: "welcome_on_signup"}
+	}
+
+	t.Run("When the mail queue is full emails wait in the backlog", func(t *testing.T) {
+		ns, _ := createSut(t, bus)
+		ns.mailQueue = make(chan *Message, 1)
+		require.False(t, ns.MailQueueSaturated())
+
+		require.NoError(t, ns.SendEmailCommandHandler(context.Background(), cmd("first@grafana.com")))
+		require.True(t, ns.MailQueueSaturated())
+
+		var mu sync.Mutex
+		var sent []error
+		onSent := func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			sent = append(sent, err)
+		}
+
+		second, err := ns.QueueEmail(context.Background(), cmd("second@grafana.com"), "second", onSent)
+		require.NoError(t, err)
+		require.Equal(t, EmailDeliveryQueued, second.State)
+		require.Equal(t, 2, second.Position)
+		require.WithinDuration(t, time.Now().Add(2*defaultEmailSendTime), second.EstimatedDelivery, time.Second)
+
+		third, err := ns.QueueEmail(context.Background(), cmd("third@grafana.com"), "third", onSent)
+		require.NoError(t, err)
+		require.Equal(t, 3, third.Position)
+
+		delivery, ok := ns.EmailDelivery("third")
+		require.True(t, ok)
+		require.Equal(t, 3, delivery.Position)
+		_, ok = ns.EmailDelivery("unknown")
+		require.False(t, ok)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() { _ = ns.Run(ctx) }()
+
+		require.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(sent) == 2
+		}, 5*time.Second, 10*time.Millisecond)
+		require.Equal(t, []error{nil, nil}, sent)
+
+		delivery, ok = ns.EmailDelivery("third")
+		require.True(t, ok)
+		require.Equal(t, EmailDeliverySent, delivery.State)
+		require.False(t, delivery.Finished.IsZero())
+		require.False(t, ns.MailQueueSaturated())
+	})
+
+	t.Run("When the backlog is full emails are rejected", func(t *testing.T) {
+		ns, _ := createSut(t, bus)
+		ns.mailQueue = make(chan *Message, 1)
+		ns.maxBacklog = 1
+
+		require.NoError(t, ns.SendEmailCommandHandler(context.Background(), cmd("first@grafana.com")))
+		_, err := ns.QueueEmail(context.Background(), cmd("second@grafana.com"), "second", nil)
+		require.NoError(t, err)
+		require.True(t, ns.MailBacklogFull())
+
+		_, err = ns.QueueEmail(context.Background(), cmd("third@grafana.com"), "third", nil)
+		require.ErrorIs(t, err, ErrMailBacklogFull)
+		_, ok := ns.EmailDelivery("third")
+		require.False(t, ok)
+
+		// room is made as soon as the mail queue is drained
+		<-ns.mailQueue
+		require.False(t, ns.MailBacklogFull())
+	})
+
+	t.Run("When sending fails the error is recorded", func(t *testing.T) {
+		ns := createDisconnectedSut(t, bus)
+
+		failed := make(chan error, 1)
+		_, err := ns.QueueEmail(context.Background(), cmd("asdf@grafana.com"), "failing", func(err error) { failed <- err })
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() { _ = ns.Run(ctx) }()
+
+		select {
+		case err := <-failed:
+			require.Error(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("queued email was not sent")
+		}
+		delivery, ok := ns.EmailDelivery("failing")
+		require.True(t, ok)
+		require.Equal(t, EmailDeliveryFailed, delivery.State)
+		require.Equal(t, "connect: connection refused", delivery.Error)
+	})
+
+	t.Run("When SMTP is disabled emails are not queued", func(t *testing.T) {
+		cfg := createSmtpConfig()
+		cfg.Smtp.Enabled = false
+		ns, _, err := createSutWithConfig(t, bus, cfg)
+		require.NoError(t, err)
+
+		_, err = ns.QueueEmail(context.Background(), cmd("asdf@grafana.com"), "disabled", nil)
+		require.ErrorIs(t, err, models.ErrSmtpNotEnabled)
+		_, ok := ns.EmailDelivery("disabled")
+		require.False(t, ok)
+	})
+}
@@ -6,9 +6,11 @@ package notifications
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"html/template"
 	"net/mail"
+	"time"
 
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/prometheus/client_golang/prometheus"
@@ -38,7 +40,18 @@ type Mailer interface {
 	Send(messages ...*Message) (int, error)
 }
 
+// ContextMailer is implemented by mailers which stop sending when the context is done, so that
+// requests sending emails synchronously don't outlive their context.
+type ContextMailer interface {
+	SendContext(ctx context.Context, messages ...*Message) (int, error)
+}
+
 func (ns *NotificationService) Send(msg *Message) (int, error) {
+	return ns.SendContext(context.Background(), msg)
+}
+
+// SendContext sends the message, giving up when the context is done.
+func (ns *NotificationService) SendContext(ctx context.Context, msg *Message) (int, error) {
 	messages := []*Message{}
 
 	if msg.SingleEmail {
@@ -51,7 +64,17 @@ func (ns *NotificationService) Send(msg *Message) (int, error) {
 		}
 	}
 
-	return ns.mailer.Send(messages...)
+	var num int
+	var err error
+	if mailer, ok := ns.mailer.(ContextMailer); ok {
+		num, err = mailer.SendContext(ctx, messages...)
+	} else if err = ctx.Err(); err == nil {
+		num, err = ns.mailer.Send(messages...)
+	}
+	if num > 0 {
+		ns.recordEmailSent(time.Now())
+	}
+	return num, err
 }
 
 func (ns *NotificationService) buildEmailMessage(cmd *models.SendEmailCommand) (*Message, error) {
@@ -66,24 +89,33 @@ func (ns *NotificationService) buildEmailMessage(cmd *models.SendEmailCommand) (
 
 	setDefaultTemplateData(ns.Cfg, data, nil)
 
-	body := make(map[string]string)
-	for _, contentType := range ns.Cfg.Smtp.ContentTypes {
-		fileExtension, err := getFileExtensionByContentType(contentType)
+	contentTypes := ns.Cfg.Smtp.ContentTypes
+	if cmd.Multipart {
+		contentTypes = multipartContentTypes(contentTypes)
+	}
+
+	body, err := renderEmailBody(cmd.Template, contentTypes, data)
+	if err != nil {
+		return nil, err
+	}
+
+	// the preheader is set by the template while the body is rendered, after the layout would
+	// have displayed it, so the body is rendered again with the preheader text
+	if preheaderText, ok := data["Preheader"].(map[string]interface{})["value"]; ok {
+		text, err := renderTemplateText("preheader", preheaderText.(string), data)
 		if err != nil {
 			return nil, err
 		}
-		var buffer bytes.Buffer
-		err = mailTemplates.ExecuteTemplate(&buffer, cmd.Template+fileExtension, data)
+		// already escaped when rendered
+		data["PreheaderText"] = template.HTML(text)
+		body, err = renderEmailBody(cmd.Template, contentTypes, data)
 		if err != nil {
 			return nil, err
 		}
-
-		body[contentType] = buffer.String()
 	}
 
 	subject := cmd.Subject
 	if cmd.Subject == "" {
-		var subjectText interface{}
 		subjectData := data["Subject"].(map[string]interface{})
 		subjectText, hasSubject := subjectData["value"]
 
@@ -91,18 +123,10 @@ func (ns *NotificationService) buildEmailMessage(cmd *models.SendEmailCommand) (
 			return nil, fmt.Errorf("missing subject in template %s", cmd.Template)
 		}
 
-		subjectTmpl, err := template.New("subject").Parse(subjectText.(string))
+		subject, err = renderTemplateText("subject", subjectText.(string), data)
 		if err != nil {
 			return nil, err
 		}
-
-		var subjectBuffer bytes.Buffer
-		err = subjectTmpl.ExecuteTemplate(&subjectBuffer, "subject", data)
-		if err != nil {
-			return nil, err
-		}
-
-		subject = subjectBuffer.String()
 	}
 
 	addr := mail.Address{Name: ns.Cfg.Smtp.FromName, Address: ns.Cfg.Smtp.FromAddress}
@@ -115,9 +139,61 @@ func (ns *NotificationService) buildEmailMessage(cmd *models.SendEmailCommand) (
 		EmbeddedFiles: cmd.EmbeddedFiles,
 		AttachedFiles: buildAttachedFiles(cmd.AttachedFiles),
 		ReplyTo:       cmd.ReplyTo,
+		ContentTypes:  contentTypes,
 	}, nil
 }
 
+func renderEmailBody(templateName string, contentTypes []string, data map[string]interface{}) (map[string]string, error) {
+	body := make(map[string]string)
+	for _, contentType := range contentTypes {
+		fileExtension, err := getFileExtensionByContentType(contentType)
+		if err != nil {
+			return nil, err
+		}
+		var buffer bytes.Buffer
+		err = mailTemplates.ExecuteTemplate(&buffer, templateName+fileExtension, data)
+		if err != nil {
+			return nil, err
+		}
+
+		body[contentType] = buffer.String()
+	}
+	return body, nil
+}
+
+// renderTemplateText renders the text set by the Subject and Preheader template functions.
+func renderTemplateText(name string, text string, data map[string]interface{}) (string, error) {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var buffer bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buffer, name, data); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+// multipartContentTypes adds the HTML and plain text content types missing from the configured
+// ones, keeping the configured preference first.
+func multipartContentTypes(configured []string) []string {
+	contentTypes := append([]string{}, configured...)
+	for _, contentType := range []string{"text/html", "text/plain"} {
+		found := false
+		for _, c := range contentTypes {
+			if c == contentType {
+				found = true
+				break
+			}
+		}
+		if !found {
+			contentTypes = append(contentTypes, contentType)
+		}
+	}
+	return contentTypes
+}
+
 // buildAttachedFiles build attached files
 func buildAttachedFiles(
 	attached []*models.SendEmailAttachFile,
@@ -5,9 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"html/template"
+	"net"
 	"net/url"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
 
 	"github.com/grafana/grafana/pkg/bus"
 	"github.com/grafana/grafana/pkg/events"
@@ -44,6 +49,9 @@ func ProvideService(bus bus.Bus, cfg *setting.Cfg, mailer Mailer, store TempUser
 		webhookQueue: make(chan *Webhook, 10),
 		mailer:       mailer,
 		store:        store,
+		resolver:     net.DefaultResolver,
+		deliveries:   map[string]*EmailDelivery{},
+		maxBacklog:   defaultMaxEmailBacklog,
 	}
 
 	ns.Bus.AddEventListener(ns.signUpStartedHandler)
@@ -51,7 +59,8 @@ func ProvideService(bus bus.Bus, cfg *setting.Cfg, mailer Mailer, store TempUser
 
 	mailTemplates = template.New("name")
 	mailTemplates.Funcs(template.FuncMap{
-		"Subject": subjectTemplateFunc,
+		"Subject":   subjectTemplateFunc,
+		"Preheader": subjectTemplateFunc,
 	})
 
 	for _, pattern := range ns.Cfg.Smtp.TemplatesPatterns {
@@ -85,6 +94,22 @@ type NotificationService struct {
 	mailer       Mailer
 	log          log.Logger
 	store        TempUserStore
+	resolver     TXTResolver
+
+	healthMu        sync.Mutex
+	lastEmailSentAt time.Time
+
+	// the latest SMTP connectivity probe, see SMTPStatus
+	smtpProbe    singleflight.Group
+	smtpStatusMu sync.Mutex
+	smtpStatus   *SMTPStatus
+
+	// emails waiting for room in the mail queue, see QueueEmail
+	backlogMu   sync.Mutex
+	backlog     []*queuedEmail
+	maxBacklog  int
+	deliveries  map[string]*EmailDelivery
+	avgSendTime time.Duration
 }
 
 func (ns *NotificationService) Run(ctx context.Context) error {
@@ -97,7 +122,15 @@ func (ns *NotificationService) Run(ctx context.Context) error {
 				ns.log.Error("Failed to send webrequest ", "error", err)
 			}
 		case msg := <-ns.mailQueue:
+			started := time.Now()
 			num, err := ns.Send(msg)
+			ns.recordSendTime(time.Since(started))
+			if msg.sent != nil {
+				msg.sent(err)
+			}
+			ns.backlogMu.Lock()
+			ns.drainBacklog()
+			ns.backlogMu.Unlock()
 			tos := strings.Join(msg.To, "; ")
 			info := ""
 			if err != nil {
@@ -147,13 +180,14 @@ func (ns *NotificationService) SendEmailCommandHandlerSync(ctx context.Context,
 		AttachedFiles: cmd.AttachedFiles,
 		Subject:       cmd.Subject,
 		ReplyTo:       cmd.ReplyTo,
+		Multipart:     cmd.Multipart,
 	})
 
 	if err != nil {
 		return err
 	}
 
-	_, err = ns.Send(message)
+	_, err = ns.SendContext(ctx, message)
 	return err
 }
 
@@ -164,8 +198,12 @@ func (ns *NotificationService) SendEmailCommandHandler(ctx context.Context, cmd
 		return err
 	}
 
-	ns.mailQueue <- message
-	return nil
+	select {
+	case ns.mailQueue <- message:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func (ns *NotificationService) SendResetPasswordEmail(ctx context.Context, cmd *models.SendResetPasswordEmailCommand) error {
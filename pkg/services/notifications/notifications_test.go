@@ -4,6 +4,7 @@ import (
 	"context"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/grafana/grafana/pkg/bus"
 	"github.com/grafana/grafana/pkg/infra/tracing"
@@ -124,6 +125,62 @@ func TestSendEmailSync(t *testing.T) {
 		require.Equal(t, []byte("text file content"), file.Content)
 	})
 
+	t.Run("When sending multipart emails", func(t *testing.T) {
+		cfg := createSmtpConfig()
+		cfg.Smtp.ContentTypes = []string{"text/html"}
+		ns, mailer, err := createSutWithConfig(t, bus, cfg)
+		require.NoError(t, err)
+		cmd := &models.SendEmailCommandSync{
+			SendEmailCommand: models.SendEmailCommand{
+				To:        []string{"asdf@grafana.com"},
+				Template:  "new_user_invite",
+				Multipart: true,
+				Data: map[string]interface{}{
+					"OrgName":   "Main & Co",
+					"InvitedBy": "Admin",
+					"LinkUrl":   "http://localhost:3000/invite/code",
+				},
+			},
+		}
+
+		err = ns.SendEmailCommandHandlerSync(context.Background(), cmd)
+		require.NoError(t, err)
+
+		require.Len(t, mailer.Sent, 1)
+		sent := mailer.Sent[0]
+		require.Equal(t, []string{"text/html", "text/plain"}, sent.ContentTypes)
+		require.Equal(t, "Admin has invited you to join Grafana", sent.Subject)
+		require.Contains(t, sent.Body["text/html"], `<meta name="color-scheme" content="light dark" />`)
+		require.Contains(t, sent.Body["text/html"], "You've been invited to join the Main &amp; Co organization by Admin</div>")
+		require.NotContains(t, sent.Body["text/plain"], "<")
+		require.Contains(t, sent.Body["text/plain"], "http://localhost:3000/invite/code")
+	})
+
+	t.Run("When sending invite emails with an expiry", func(t *testing.T) {
+		ns, mailer := createSut(t, bus)
+		cmd := &models.SendEmailCommandSync{
+			SendEmailCommand: models.SendEmailCommand{
+				To:        []string{"asdf@grafana.com"},
+				Template:  "new_user_invite",
+				Multipart: true,
+				Data: map[string]interface{}{
+					"OrgName":       "Main",
+					"InvitedBy":     "Admin",
+					"LinkUrl":       "http://localhost:3000/invite/code",
+					"InviteExpires": NewEmailTime(time.Date(2022, time.March, 4, 17, 30, 0, 0, time.UTC), "Europe/Berlin", "de-DE"),
+				},
+			},
+		}
+
+		err := ns.SendEmailCommandHandlerSync(context.Background(), cmd)
+		require.NoError(t, err)
+
+		require.Len(t, mailer.Sent, 1)
+		sent := mailer.Sent[0]
+		require.Contains(t, sent.Body["text/html"], `<time datetime="2022-03-04T17:30:00Z" data-utc="2022-03-04T17:30:00Z">04.03.2022, 18:30 CET</time>`)
+		require.Contains(t, sent.Body["text/plain"], "This invitation expires on 04.03.2022, 18:30 CET (2022-03-04T17:30:00Z).")
+	})
+
 	t.Run("When SMTP disabled in configuration", func(t *testing.T) {
 		cfg := createSmtpConfig()
 		cfg.Smtp.Enabled = false
@@ -264,6 +321,23 @@ func TestSendEmailAsync(t *testing.T) {
 		// The async version should not surface connection errors via Bus. It should only log them.
 		require.NoError(t, err)
 	})
+
+	t.Run("When the mail queue is full until the context is done", func(t *testing.T) {
+		ns, mailer := createSut(t, bus)
+		ns.mailQueue = make(chan *Message)
+		cmd := &models.SendEmailCommand{
+			Subject:  "subject",
+			To:       []string{"1@grafana.com"},
+			Template: "welcome_on_signup",
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		err := ns.SendEmailCommandHandler(ctx, cmd)
+
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Empty(t, mailer.Sent)
+	})
 }
 
 func createSut(t *testing.T, bus bus.Bus) (*NotificationService, *FakeMailer) {
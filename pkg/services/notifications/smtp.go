@@ -1,17 +1,38 @@
 package notifications
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"io"
 	"net"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/grafana/grafana/pkg/setting"
 	gomail "gopkg.in/mail.v2"
 )
 
+func init() {
+	gomail.NetDialTimeout = dialWithDeadline
+}
+
+// dialWithDeadline bounds the whole SMTP conversation by the dial timeout from the start, as the
+// mail client only sets the connection deadline after the server greeting and would otherwise
+// wait forever on a server which accepts connections but never greets.
+func dialWithDeadline(network, address string, timeout time.Duration) (net.Conn, error) {
+	conn, err := net.DialTimeout(network, address, timeout)
+	if err != nil || timeout <= 0 {
+		return conn, err
+	}
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
 type SmtpClient struct {
 	cfg setting.SmtpSettings
 }
@@ -29,16 +50,33 @@ func NewSmtpClient(cfg setting.SmtpSettings) (*SmtpClient, error) {
 }
 
 func (sc *SmtpClient) Send(messages ...*Message) (int, error) {
+	return sc.SendContext(context.Background(), messages...)
+}
+
+// SendContext sends the messages until the context is done. The SMTP client can't be interrupted,
+// so the dial and each read and write to the SMTP server time out at the context deadline instead.
+func (sc *SmtpClient) SendContext(ctx context.Context, messages ...*Message) (int, error) {
 	sentEmailsCount := 0
 	dialer, err := sc.createDialer()
 	if err != nil {
 		return sentEmailsCount, err
 	}
+	defaultTimeout := dialer.Timeout
 
 	for _, msg := range messages {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return sentEmailsCount, ctxErr
+		}
+		dialer.Timeout = timeoutUntilDeadline(ctx, defaultTimeout)
 		m := sc.buildEmail(msg)
 
 		innerError := dialer.DialAndSend(m)
+		if ctxErr := ctx.Err(); innerError != nil && ctxErr != nil {
+			// the send timed out because of the context deadline
+			emailsSentTotal.Inc()
+			emailsSentFailed.Inc()
+			return sentEmailsCount, fmt.Errorf("failed to send notification to email addresses: %s: %w", strings.Join(msg.To, ";"), ctxErr)
+		}
 		emailsSentTotal.Inc()
 		if innerError != nil {
 			// As gomail does not returned typed errors we have to parse the error
@@ -58,23 +96,45 @@ func (sc *SmtpClient) Send(messages ...*Message) (int, error) {
 	return sentEmailsCount, err
 }
 
+// Ping dials the configured SMTP server and closes the connection right away
+// to verify that it is reachable and accepts the configured credentials.
+func (sc *SmtpClient) Ping() error {
+	dialer, err := sc.createDialer()
+	if err != nil {
+		return err
+	}
+
+	closer, err := dialer.Dial()
+	if err != nil {
+		return err
+	}
+	return closer.Close()
+}
+
 // buildEmail converts the Message DTO to a gomail message.
 func (sc *SmtpClient) buildEmail(msg *Message) *gomail.Message {
 	m := gomail.NewMessage()
 	m.SetHeader("From", msg.From)
 	m.SetHeader("To", msg.To...)
 	m.SetHeader("Subject", msg.Subject)
+	if msg.MessageID != "" {
+		m.SetHeader("Message-ID", msg.MessageID)
+	}
 	sc.setFiles(m, msg)
 	for _, replyTo := range msg.ReplyTo {
 		m.SetAddressHeader("Reply-To", replyTo, "")
 	}
-	// loop over content types from settings in reverse order as they are ordered in according to descending
+	contentTypes := msg.ContentTypes
+	if len(contentTypes) == 0 {
+		contentTypes = sc.cfg.ContentTypes
+	}
+	// loop over content types in reverse order as they are ordered in according to descending
 	// preference while the alternatives should be ordered according to ascending preference
-	for i := len(sc.cfg.ContentTypes) - 1; i >= 0; i-- {
-		if i == len(sc.cfg.ContentTypes)-1 {
-			m.SetBody(sc.cfg.ContentTypes[i], msg.Body[sc.cfg.ContentTypes[i]])
+	for i := len(contentTypes) - 1; i >= 0; i-- {
+		if i == len(contentTypes)-1 {
+			m.SetBody(contentTypes[i], msg.Body[contentTypes[i]])
 		} else {
-			m.AddAlternative(sc.cfg.ContentTypes[i], msg.Body[sc.cfg.ContentTypes[i]])
+			m.AddAlternative(contentTypes[i], msg.Body[contentTypes[i]])
 		}
 	}
 
@@ -134,6 +194,22 @@ func (sc *SmtpClient) createDialer() (*gomail.Dialer, error) {
 	return d, nil
 }
 
+// timeoutUntilDeadline returns the time left until the context deadline when it is shorter than timeout.
+func timeoutUntilDeadline(ctx context.Context, timeout time.Duration) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return timeout
+	}
+	if left := time.Until(deadline); left < timeout {
+		// a zero timeout disables it
+		if left <= 0 {
+			return time.Nanosecond
+		}
+		return left
+	}
+	return timeout
+}
+
 func getStartTLSPolicy(policy string) gomail.StartTLSPolicy {
 	switch policy {
 	case "NoStartTLS":
@@ -2,8 +2,11 @@ package notifications
 
 import (
 	"bytes"
+	"context"
+	"net"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/grafana/grafana/pkg/setting"
 	"github.com/stretchr/testify/assert"
@@ -39,6 +42,19 @@ func TestBuildMail(t *testing.T) {
 		assert.Contains(t, buf.String(), "Some plain text body")
 		assert.Less(t, strings.Index(buf.String(), "Some plain text body"), strings.Index(buf.String(), "Some HTML body"))
 	})
+
+	t.Run("When building email with the content types of the message", func(t *testing.T) {
+		msg := *message
+		msg.ContentTypes = []string{"text/plain"}
+		email := sc.buildEmail(&msg)
+
+		buf := new(bytes.Buffer)
+		_, err := email.WriteTo(buf)
+		require.NoError(t, err)
+
+		assert.NotContains(t, buf.String(), "Some HTML body")
+		assert.Contains(t, buf.String(), "Some plain text body")
+	})
 }
 
 func TestSmtpDialer(t *testing.T) {
@@ -106,3 +122,45 @@ func TestSmtpDialer(t *testing.T) {
 		require.EqualError(t, err, "could not load cert or key file: open /var/certs/does-not-exist.pem: no such file or directory")
 	})
 }
+
+func TestSmtpSendContext(t *testing.T) {
+	// an SMTP server which accepts connections but never answers
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { _ = conn.Close() })
+		}
+	}()
+
+	cfg := createSmtpConfig()
+	cfg.Smtp.Host = listener.Addr().String()
+	client, err := NewSmtpClient(cfg.Smtp)
+	require.NoError(t, err)
+	message := &Message{To: []string{"asdf@grafana.com"}, Subject: "subject", Body: map[string]string{"text/html": "body"}}
+
+	t.Run("When the context deadline is exceeded the SMTP server is no longer waited for", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		started := time.Now()
+		count, err := client.SendContext(ctx, message, message)
+		require.Equal(t, 0, count)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Less(t, time.Since(started), 5*time.Second)
+	})
+
+	t.Run("When the context is canceled nothing is sent", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		count, err := client.SendContext(ctx, message)
+		require.Equal(t, 0, count)
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,150 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+// TXTResolver looks up DNS TXT records, it is implemented by net.Resolver.
+type TXTResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// TestEmailResult describes how a test email was handed over to the SMTP server.
+type TestEmailResult struct {
+	MessageID string   `json:"messageId"`
+	From      string   `json:"from"`
+	Accepted  []string `json:"accepted"`
+	Rejected  []string `json:"rejected"`
+	Error     string   `json:"error,omitempty"`
+	// Warnings about the sender domain which likely make receivers reject or quarantine the email
+	Warnings []string `json:"warnings"`
+}
+
+// SendTestEmail sends the email synchronously to each recipient separately, so that accepted and
+// rejected recipients can be told apart, and checks the DNS records of the sender domain.
+// Only errors building the email and the context errors are returned, delivery errors are part of the result.
+func (ns *NotificationService) SendTestEmail(ctx context.Context, cmd *models.SendEmailCommand) (*TestEmailResult, error) {
+	message, err := ns.buildEmailMessage(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	domain := emailDomain(ns.Cfg.Smtp.FromAddress)
+	id, err := util.GetRandomString(24)
+	if err != nil {
+		return nil, err
+	}
+	message.MessageID = fmt.Sprintf("<%s@%s>", id, domain)
+	message.SingleEmail = true
+
+	result := &TestEmailResult{
+		MessageID: message.MessageID,
+		From:      message.From,
+		Accepted:  []string{},
+		Rejected:  []string{},
+		Warnings:  ns.checkSenderDomain(ctx),
+	}
+
+	var sendErrors []string
+	for _, to := range cmd.To {
+		msg := *message
+		msg.To = []string{to}
+		if _, err := ns.SendContext(ctx, &msg); err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			result.Rejected = append(result.Rejected, to)
+			sendErrors = append(sendErrors, err.Error())
+			continue
+		}
+		result.Accepted = append(result.Accepted, to)
+	}
+	result.Error = strings.Join(sendErrors, "; ")
+
+	return result, nil
+}
+
+// checkSenderDomain returns warnings when the From domain likely fails SPF, DKIM or DMARC alignment.
+// Grafana doesn't sign emails itself, so DKIM alignment depends on the SMTP provider signing with
+// the From domain.
+func (ns *NotificationService) checkSenderDomain(ctx context.Context) []string {
+	warnings := []string{}
+	smtp := ns.Cfg.Smtp
+
+	fromDomain := emailDomain(smtp.FromAddress)
+	if fromDomain == "" {
+		return append(warnings, fmt.Sprintf("The from_address %q has no domain", smtp.FromAddress))
+	}
+
+	spf, err := ns.lookupTXTRecord(ctx, fromDomain, "v=spf1")
+	switch {
+	case err != nil:
+		warnings = append(warnings, fmt.Sprintf("Failed to look up the SPF record of %s: %s", fromDomain, err))
+	case spf == "":
+		warnings = append(warnings, fmt.Sprintf("%s has no SPF record, receivers can't verify that the SMTP server may send its emails", fromDomain))
+	}
+
+	dmarc, err := ns.lookupTXTRecord(ctx, "_dmarc."+organizationalDomain(fromDomain), "v=DMARC1")
+	switch {
+	case err != nil:
+		warnings = append(warnings, fmt.Sprintf("Failed to look up the DMARC record of %s: %s", fromDomain, err))
+	case dmarc == "":
+		warnings = append(warnings, fmt.Sprintf("%s has no DMARC record, some receivers reject or quarantine emails from such domains", fromDomain))
+	}
+
+	// SMTP providers usually sign with the domain of the account, which doesn't align with a different From domain
+	if userDomain := emailDomain(smtp.User); userDomain != "" && organizationalDomain(userDomain) != organizationalDomain(fromDomain) {
+		warnings = append(warnings, fmt.Sprintf("The SMTP user domain %s differs from the from_address domain %s, DKIM signatures of the SMTP provider likely don't align", userDomain, fromDomain))
+	}
+
+	return warnings
+}
+
+// lookupTXTRecord returns the TXT record of name starting with prefix, or an empty string when
+// there is none.
+func (ns *NotificationService) lookupTXTRecord(ctx context.Context, name string, prefix string) (string, error) {
+	records, err := ns.resolver.LookupTXT(ctx, name)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return "", nil
+		}
+		return "", err
+	}
+	for _, record := range records {
+		if strings.HasPrefix(strings.ToLower(record), strings.ToLower(prefix)) {
+			return record, nil
+		}
+	}
+	return "", nil
+}
+
+func emailDomain(address string) string {
+	addr, err := mail.ParseAddress(address)
+	if err != nil {
+		return ""
+	}
+	at := strings.LastIndex(addr.Address, "@")
+	if at < 0 {
+		return ""
+	}
+	return strings.ToLower(addr.Address[at+1:])
+}
+
+// organizationalDomain returns the registered domain DMARC relaxed alignment compares.
+func organizationalDomain(domain string) string {
+	if org, err := publicsuffix.EffectiveTLDPlusOne(domain); err == nil {
+		return org
+	}
+	return domain
+}
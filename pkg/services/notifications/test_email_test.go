@@ -0,0 +1,86 @@
+package notifications
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/models"
+)
+
+type fakeTXTResolver map[string][]string
+
+func (r fakeTXTResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	records, ok := r[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return records, nil
+}
+
+func TestSendTestEmail(t *testing.T) {
+	bus := newBus(t)
+	cmd := func() *models.SendEmailCommand {
+		return &models.SendEmailCommand{
+			To:       []string{"admin@example.com"},
+			Template: "new_user_invite",
+			Data:     map[string]interface{}{"LinkUrl": "http://localhost/invite/test"},
+		}
+	}
+
+	t.Run("sends the email and reports missing DNS records", func(t *testing.T) {
+		ns, mailer := createSut(t, bus)
+		ns.resolver = fakeTXTResolver{"address.com": {"google-site-verification=abc"}}
+
+		result, err := ns.SendTestEmail(context.Background(), cmd())
+		require.NoError(t, err)
+		require.Equal(t, []string{"admin@example.com"}, result.Accepted)
+		require.Empty(t, result.Rejected)
+		require.Regexp(t, `^<\w+@address\.com>$`, result.MessageID)
+		require.Len(t, mailer.Sent, 1)
+		require.Equal(t, result.MessageID, mailer.Sent[0].MessageID)
+		require.Len(t, result.Warnings, 2)
+		require.Contains(t, result.Warnings[0], "no SPF record")
+		require.Contains(t, result.Warnings[1], "no DMARC record")
+	})
+
+	t.Run("warns when the SMTP user domain does not align", func(t *testing.T) {
+		cfg := createSmtpConfig()
+		cfg.Smtp.FromAddress = "grafana@mail.address.com"
+		cfg.Smtp.User = "apikey@provider.net"
+		ns, _, err := createSutWithConfig(t, bus, cfg)
+		require.NoError(t, err)
+		ns.resolver = fakeTXTResolver{
+			"mail.address.com":   {"v=spf1 include:provider.net -all"},
+			"_dmarc.address.com": {"v=DMARC1; p=reject"},
+		}
+
+		result, err := ns.SendTestEmail(context.Background(), cmd())
+		require.NoError(t, err)
+		require.Len(t, result.Warnings, 1)
+		require.Contains(t, result.Warnings[0], "provider.net differs")
+	})
+
+	t.Run("reports rejected recipients", func(t *testing.T) {
+		ns := createDisconnectedSut(t, bus)
+		ns.resolver = fakeTXTResolver{}
+
+		result, err := ns.SendTestEmail(context.Background(), cmd())
+		require.NoError(t, err)
+		require.Empty(t, result.Accepted)
+		require.Equal(t, []string{"admin@example.com"}, result.Rejected)
+		require.Equal(t, "connect: connection refused", result.Error)
+	})
+
+	t.Run("fails when SMTP is disabled", func(t *testing.T) {
+		cfg := createSmtpConfig()
+		cfg.Smtp.Enabled = false
+		ns, _, err := createSutWithConfig(t, bus, cfg)
+		require.NoError(t, err)
+
+		_, err = ns.SendTestEmail(context.Background(), cmd())
+		require.ErrorIs(t, err, models.ErrSmtpNotEnabled)
+	})
+}
@@ -21,6 +21,10 @@ func (fm *FakeMailer) Send(messages ...*Message) (int, error) {
 	return sentEmailsCount, nil
 }
 
+func (fm *FakeMailer) Ping() error {
+	return nil
+}
+
 type FakeDisconnectedMailer struct{}
 
 func NewFakeDisconnectedMailer() *FakeDisconnectedMailer {
@@ -31,6 +35,10 @@ func (fdm *FakeDisconnectedMailer) Send(messages ...*Message) (int, error) {
 	return 0, fmt.Errorf("connect: connection refused")
 }
 
+func (fdm *FakeDisconnectedMailer) Ping() error {
+	return fmt.Errorf("connect: connection refused")
+}
+
 // NetClient is used to export original in test.
 var NetClient = &netClient
 
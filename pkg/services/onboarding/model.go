@@ -0,0 +1,74 @@
+package onboarding
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrInvalidEventType = errors.New("invalid onboarding event type")
+
+// EventType is a step of the onboarding checklist of invited users.
+type EventType string
+
+const (
+	EventInviteAccepted  EventType = "invite_accepted"
+	EventDashboardViewed EventType = "dashboard_viewed"
+	EventQueryRun        EventType = "query_run"
+)
+
+// ChecklistEvents are the steps of the onboarding checklist, in the order they are displayed.
+var ChecklistEvents = []EventType{EventInviteAccepted, EventDashboardViewed, EventQueryRun}
+
+func (t EventType) IsValid() bool {
+	for _, e := range ChecklistEvents {
+		if t == e {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is the first occurrence of an onboarding step of a user in an organization.
+type Event struct {
+	ID     int64     `xorm:"pk autoincr 'id'"`
+	OrgID  int64     `xorm:"org_id"`
+	UserID int64     `xorm:"user_id"`
+	Type   EventType `xorm:"type"`
+	// InviteID is the invite the user accepted, set on invite_accepted events
+	InviteID int64     `xorm:"invite_id"`
+	Created  time.Time `xorm:"created"`
+}
+
+func (e Event) TableName() string { return "onboarding_event" }
+
+// ----------------------
+// COMMANDS
+
+type RecordEventCommand struct {
+	OrgID    int64
+	UserID   int64
+	Type     EventType
+	InviteID int64
+}
+
+// ---------------------
+// QUERIES
+
+type GetChecklistQuery struct {
+	OrgID  int64
+	UserID int64
+}
+
+// Checklist is the onboarding progress of a user in an organization. It is only active for users
+// who joined the organization by accepting an invite.
+type Checklist struct {
+	Active   bool            `json:"active"`
+	Complete bool            `json:"complete"`
+	Steps    []ChecklistStep `json:"steps"`
+}
+
+type ChecklistStep struct {
+	Event       EventType  `json:"event"`
+	Done        bool       `json:"done"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
@@ -0,0 +1,13 @@
+package onboarding
+
+import (
+	"context"
+)
+
+type Service interface {
+	// RecordEvent records the first occurrence of an onboarding step. Accepting an invite starts
+	// the checklist of the user, the other events are ignored for users who weren't invited.
+	RecordEvent(ctx context.Context, cmd *RecordEventCommand) error
+	// GetChecklist returns the onboarding checklist of a user in an organization.
+	GetChecklist(ctx context.Context, query *GetChecklistQuery) (*Checklist, error)
+}
@@ -0,0 +1,107 @@
+package onboardingimpl
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/localcache"
+	"github.com/grafana/grafana/pkg/services/onboarding"
+	"github.com/grafana/grafana/pkg/services/sqlstore/db"
+)
+
+// Service records onboarding events. Dashboards views and queries happen all the time, so the
+// events of users are cached to record them without querying the database once they are done.
+// Users who accept an invite on another instance start their checklist here once the cache expires.
+type Service struct {
+	store store
+	cache *localcache.CacheService
+}
+
+func ProvideService(db db.DB) onboarding.Service {
+	return &Service{
+		store: &sqlStore{db: db},
+		cache: localcache.New(5*time.Minute, 10*time.Minute),
+	}
+}
+
+func (s *Service) RecordEvent(ctx context.Context, cmd *onboarding.RecordEventCommand) error {
+	if !cmd.Type.IsValid() {
+		return onboarding.ErrInvalidEventType
+	}
+
+	if cmd.Type != onboarding.EventInviteAccepted {
+		done, err := s.doneEvents(ctx, cmd.OrgID, cmd.UserID)
+		if err != nil {
+			return err
+		}
+		if !done[onboarding.EventInviteAccepted] || done[cmd.Type] {
+			return nil
+		}
+	}
+
+	event := &onboarding.Event{
+		OrgID:    cmd.OrgID,
+		UserID:   cmd.UserID,
+		Type:     cmd.Type,
+		InviteID: cmd.InviteID,
+		Created:  time.Now(),
+	}
+	if err := s.store.Insert(ctx, event); err != nil {
+		return err
+	}
+	s.cache.Delete(cacheKey(cmd.OrgID, cmd.UserID))
+	return nil
+}
+
+func (s *Service) GetChecklist(ctx context.Context, query *onboarding.GetChecklistQuery) (*onboarding.Checklist, error) {
+	events, err := s.store.List(ctx, query.OrgID, query.UserID)
+	if err != nil {
+		return nil, err
+	}
+	completed := make(map[onboarding.EventType]time.Time, len(events))
+	for _, e := range events {
+		completed[e.Type] = e.Created
+	}
+
+	_, invited := completed[onboarding.EventInviteAccepted]
+	checklist := &onboarding.Checklist{
+		Active:   invited,
+		Complete: invited,
+		Steps:    make([]onboarding.ChecklistStep, 0, len(onboarding.ChecklistEvents)),
+	}
+	for _, eventType := range onboarding.ChecklistEvents {
+		step := onboarding.ChecklistStep{Event: eventType}
+		if created, ok := completed[eventType]; ok {
+			step.Done = true
+			step.CompletedAt = &created
+		} else {
+			checklist.Complete = false
+		}
+		checklist.Steps = append(checklist.Steps, step)
+	}
+	return checklist, nil
+}
+
+// doneEvents returns the types of the events the user already has in the organization.
+func (s *Service) doneEvents(ctx context.Context, orgID, userID int64) (map[onboarding.EventType]bool, error) {
+	key := cacheKey(orgID, userID)
+	if cached, ok := s.cache.Get(key); ok {
+		return cached.(map[onboarding.EventType]bool), nil
+	}
+
+	events, err := s.store.List(ctx, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	done := make(map[onboarding.EventType]bool, len(events))
+	for _, e := range events {
+		done[e.Type] = true
+	}
+	s.cache.SetDefault(key, done)
+	return done, nil
+}
+
+func cacheKey(orgID, userID int64) string {
+	return fmt.Sprintf("onboarding-%d-%d", orgID, userID)
+}
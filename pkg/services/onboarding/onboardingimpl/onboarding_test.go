@@ -0,0 +1,73 @@
+package onboardingimpl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/onboarding"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+func TestIntegrationOnboardingService(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+	svc := ProvideService(sqlstore.InitTestDB(t)).(*Service)
+
+	record := func(t *testing.T, userID int64, eventType onboarding.EventType) {
+		t.Helper()
+		require.NoError(t, svc.RecordEvent(ctx, &onboarding.RecordEventCommand{OrgID: 1, UserID: userID, Type: eventType, InviteID: 10}))
+	}
+
+	t.Run("ignores events of users who weren't invited", func(t *testing.T) {
+		record(t, 1, onboarding.EventDashboardViewed)
+		record(t, 1, onboarding.EventQueryRun)
+
+		checklist, err := svc.GetChecklist(ctx, &onboarding.GetChecklistQuery{OrgID: 1, UserID: 1})
+		require.NoError(t, err)
+		require.False(t, checklist.Active)
+		require.False(t, checklist.Complete)
+		require.Len(t, checklist.Steps, len(onboarding.ChecklistEvents))
+	})
+
+	t.Run("completes the checklist of invited users", func(t *testing.T) {
+		record(t, 2, onboarding.EventDashboardViewed)
+		record(t, 2, onboarding.EventInviteAccepted)
+		record(t, 2, onboarding.EventDashboardViewed)
+		record(t, 2, onboarding.EventDashboardViewed)
+
+		checklist, err := svc.GetChecklist(ctx, &onboarding.GetChecklistQuery{OrgID: 1, UserID: 2})
+		require.NoError(t, err)
+		require.True(t, checklist.Active)
+		require.False(t, checklist.Complete)
+		for _, step := range checklist.Steps {
+			require.Equal(t, step.Event != onboarding.EventQueryRun, step.Done, step.Event)
+		}
+
+		record(t, 2, onboarding.EventQueryRun)
+		checklist, err = svc.GetChecklist(ctx, &onboarding.GetChecklistQuery{OrgID: 1, UserID: 2})
+		require.NoError(t, err)
+		require.True(t, checklist.Complete)
+		require.NotNil(t, checklist.Steps[0].CompletedAt)
+
+		events, err := svc.store.List(ctx, 1, 2)
+		require.NoError(t, err)
+		require.Len(t, events, 3)
+		require.Equal(t, int64(10), events[0].InviteID)
+	})
+
+	t.Run("checklists are per organization", func(t *testing.T) {
+		checklist, err := svc.GetChecklist(ctx, &onboarding.GetChecklistQuery{OrgID: 2, UserID: 2})
+		require.NoError(t, err)
+		require.False(t, checklist.Active)
+	})
+
+	t.Run("rejects unknown events", func(t *testing.T) {
+		err := svc.RecordEvent(ctx, &onboarding.RecordEventCommand{OrgID: 1, UserID: 2, Type: "unknown"})
+		require.ErrorIs(t, err, onboarding.ErrInvalidEventType)
+	})
+}
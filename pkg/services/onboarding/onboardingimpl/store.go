@@ -0,0 +1,13 @@
+package onboardingimpl
+
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/services/onboarding"
+)
+
+type store interface {
+	// Insert inserts the event unless the user already has an event of the type in the organization.
+	Insert(context.Context, *onboarding.Event) error
+	List(ctx context.Context, orgID, userID int64) ([]*onboarding.Event, error)
+}
@@ -0,0 +1,38 @@
+package onboardingimpl
+
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/services/onboarding"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/sqlstore/db"
+)
+
+type sqlStore struct {
+	db db.DB
+}
+
+func (s *sqlStore) Insert(ctx context.Context, event *onboarding.Event) error {
+	return s.db.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		exists, err := sess.Where("org_id=? AND user_id=? AND type=?", event.OrgID, event.UserID, event.Type).Exist(&onboarding.Event{})
+		if err != nil || exists {
+			return err
+		}
+		if _, err := sess.Insert(event); err != nil {
+			// recorded concurrently
+			if s.db.GetDialect().IsUniqueConstraintViolation(err) {
+				return nil
+			}
+			return err
+		}
+		return nil
+	})
+}
+
+func (s *sqlStore) List(ctx context.Context, orgID, userID int64) ([]*onboarding.Event, error) {
+	events := make([]*onboarding.Event, 0)
+	err := s.db.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		return sess.Where("org_id=? AND user_id=?", orgID, userID).Asc("id").Find(&events)
+	})
+	return events, err
+}
@@ -0,0 +1,26 @@
+package onboardingtest
+
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/services/onboarding"
+)
+
+type FakeService struct {
+	ExpectedChecklist *onboarding.Checklist
+	ExpectedError     error
+	RecordedEvents    []onboarding.RecordEventCommand
+}
+
+func NewFakeService() *FakeService {
+	return &FakeService{}
+}
+
+func (f *FakeService) RecordEvent(ctx context.Context, cmd *onboarding.RecordEventCommand) error {
+	f.RecordedEvents = append(f.RecordedEvents, *cmd)
+	return f.ExpectedError
+}
+
+func (f *FakeService) GetChecklist(ctx context.Context, query *onboarding.GetChecklistQuery) (*onboarding.Checklist, error) {
+	return f.ExpectedChecklist, f.ExpectedError
+}
@@ -7,6 +7,8 @@ import (
 	"errors"
 	"fmt"
 	"time"
+
+	"github.com/grafana/grafana/pkg/services/org"
 )
 
 var ErrPrefNotFound = errors.New("preference not found")
@@ -52,6 +54,7 @@ type SavePreferenceCommand struct {
 	Locale           string                  `json:"locale,omitempty"`
 	Navbar           *NavbarPreference       `json:"navbar,omitempty"`
 	QueryHistory     *QueryHistoryPreference `json:"queryHistory,omitempty"`
+	Invites          *InvitesPreference      `json:"invites,omitempty"`
 }
 
 type PatchPreferenceCommand struct {
@@ -67,6 +70,7 @@ type PatchPreferenceCommand struct {
 	Locale           *string                 `json:"locale,omitempty"`
 	Navbar           *NavbarPreference       `json:"navbar,omitempty"`
 	QueryHistory     *QueryHistoryPreference `json:"queryHistory,omitempty"`
+	Invites          *InvitesPreference      `json:"invites,omitempty"`
 }
 
 type NavLink struct {
@@ -84,12 +88,24 @@ type PreferenceJSONData struct {
 	Locale       string                 `json:"locale"`
 	Navbar       NavbarPreference       `json:"navbar"`
 	QueryHistory QueryHistoryPreference `json:"queryHistory"`
+	Invites      InvitesPreference      `json:"invites"`
 }
 
 type QueryHistoryPreference struct {
 	HomeTab string `json:"homeTab"`
 }
 
+// InvitesPreference holds the defaults of the invites of an organization, used when an invite
+// does not specify its role or teams, and how invites are delivered. It is only set on the
+// preferences of organizations.
+type InvitesPreference struct {
+	DefaultRole  org.RoleType `json:"defaultRole,omitempty"`
+	DefaultTeams []int64      `json:"defaultTeams,omitempty"`
+	// ContactPoint is the alerting contact point invite notifications are sent to instead of
+	// emailing the invitee, when set
+	ContactPoint string `json:"contactPoint,omitempty"`
+}
+
 func (j *PreferenceJSONData) FromDB(data []byte) error {
 	dec := json.NewDecoder(bytes.NewBuffer(data))
 	dec.UseNumber()
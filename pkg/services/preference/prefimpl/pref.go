@@ -116,6 +116,9 @@ func (s *Service) Save(ctx context.Context, cmd *pref.SavePreferenceCommand) err
 					Locale: cmd.Locale,
 				},
 			}
+			if cmd.Invites != nil {
+				preference.JSONData.Invites = *cmd.Invites
+			}
 			_, err = s.store.Insert(ctx, preference)
 			if err != nil {
 				return err
@@ -140,6 +143,9 @@ func (s *Service) Save(ctx context.Context, cmd *pref.SavePreferenceCommand) err
 	if cmd.QueryHistory != nil {
 		preference.JSONData.QueryHistory = *cmd.QueryHistory
 	}
+	if cmd.Invites != nil {
+		preference.JSONData.Invites = *cmd.Invites
+	}
 	return s.store.Update(ctx, preference)
 }
 
@@ -191,6 +197,13 @@ func (s *Service) Patch(ctx context.Context, cmd *pref.PatchPreferenceCommand) e
 		}
 	}
 
+	if cmd.Invites != nil {
+		if preference.JSONData == nil {
+			preference.JSONData = &pref.PreferenceJSONData{}
+		}
+		preference.JSONData.Invites = *cmd.Invites
+	}
+
 	if cmd.HomeDashboardID != nil {
 		preference.HomeDashboardID = *cmd.HomeDashboardID
 	}
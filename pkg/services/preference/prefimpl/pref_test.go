@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/org"
 	pref "github.com/grafana/grafana/pkg/services/preference"
 	"github.com/grafana/grafana/pkg/setting"
 )
@@ -443,6 +444,19 @@ func TestSave(t *testing.T) {
 		assert.Equal(t, "1", stored.WeekStart)
 		assert.EqualValues(t, 2, stored.Version)
 	})
+
+	t.Run("patch invites", func(t *testing.T) {
+		invites := pref.InvitesPreference{DefaultRole: org.RoleEditor, DefaultTeams: []int64{1, 2}}
+		err := prefService.Patch(context.Background(), &pref.PatchPreferenceCommand{
+			OrgID:   1,
+			Invites: &invites,
+		})
+		require.NoError(t, err)
+
+		stored := prefService.store.(*inmemStore).preference[preferenceKey{OrgID: 1}]
+		assert.Equal(t, invites, stored.JSONData.Invites)
+		assert.Equal(t, "light", stored.Theme)
+	})
 }
 
 func insertPrefs(t testing.TB, store store, preferences ...pref.Preference) {
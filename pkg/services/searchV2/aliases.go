@@ -0,0 +1,138 @@
+package searchV2
+
+import (
+	"encoding/json"
+	"html"
+	"strings"
+	"unicode"
+
+	"github.com/blugelabs/bluge"
+)
+
+const (
+	// documentFieldAlias holds the search aliases of dashboards, keywords set in the searchAliases
+	// of the dashboard JSON which the dashboard is also found by
+	documentFieldAlias       = "alias"
+	documentFieldAlias_ngram = "alias_ngram"
+	// aliasBoost is the boost of alias matches, below the one of title matches so that dashboards
+	// named after the query rank first
+	aliasBoost = 2
+
+	highlightStart = "<mark>"
+	highlightEnd   = "</mark>"
+)
+
+func addAliasFields(doc *bluge.Document, aliases []string) {
+	for _, alias := range aliases {
+		doc.AddField(bluge.NewTextField(documentFieldAlias, alias).StoreValue())
+		doc.AddField(bluge.NewTextField(documentFieldAlias_ngram, alias).WithAnalyzer(ngramIndexAnalyzer))
+	}
+}
+
+// newAliasQuery matches the dashboards with aliases containing all the words of the query, or
+// words starting with them when the query is short enough for prefix matching.
+func newAliasQuery(q DashboardQuery) bluge.Query {
+	bq := bluge.NewBooleanQuery()
+	bq.AddShould(bluge.NewMatchQuery(q.Query).
+		SetField(documentFieldAlias).
+		SetOperator(bluge.MatchQueryOperatorAnd).
+		SetBoost(aliasBoost))
+	if shouldUseNgram(q) {
+		bq.AddShould(bluge.NewMatchQuery(q.Query).
+			SetField(documentFieldAlias_ngram).
+			SetOperator(bluge.MatchQueryOperatorAnd).
+			SetAnalyzer(ngramQueryAnalyzer).
+			SetBoost(aliasBoost))
+	}
+	return bq
+}
+
+// resultHighlight tells which parts of the name and of the aliases of a result match the query.
+type resultHighlight struct {
+	Name    string   `json:"name,omitempty"`
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// queryTerms returns the lowercase words of a query, as matched against the index.
+func queryTerms(query string) []string {
+	var terms []string
+	for _, token := range ngramQueryAnalyzer.Analyze([]byte(query)) {
+		terms = append(terms, string(token.Term))
+	}
+	return terms
+}
+
+// highlightResult returns the highlight of a result, nil when neither its name nor its aliases
+// match the terms.
+func highlightResult(terms []string, name string, aliases []string) *json.RawMessage {
+	var h resultHighlight
+	if marked, ok := highlightTerms(name, terms); ok {
+		h.Name = marked
+	}
+	for _, alias := range aliases {
+		if marked, ok := highlightTerms(alias, terms); ok {
+			h.Aliases = append(h.Aliases, marked)
+		}
+	}
+	if h.Name == "" && len(h.Aliases) == 0 {
+		return nil
+	}
+	js, _ := json.Marshal(h)
+	jsb := json.RawMessage(js)
+	return &jsb
+}
+
+// highlightTerms wraps the beginning of the words of the text matching one of the terms in
+// <mark> tags, the rest of the text is HTML escaped. It tells whether any word matched.
+func highlightTerms(text string, terms []string) (string, bool) {
+	var sb strings.Builder
+	matched := false
+	runes := []rune(text)
+	for start := 0; start < len(runes); {
+		if !isWordRune(runes[start]) {
+			end := start
+			for end < len(runes) && !isWordRune(runes[end]) {
+				end++
+			}
+			sb.WriteString(html.EscapeString(string(runes[start:end])))
+			start = end
+			continue
+		}
+
+		end := start
+		for end < len(runes) && isWordRune(runes[end]) {
+			end++
+		}
+		word := runes[start:end]
+		prefix := longestMatchingPrefix(word, terms)
+		if prefix > 0 {
+			matched = true
+			sb.WriteString(highlightStart)
+			sb.WriteString(html.EscapeString(string(word[:prefix])))
+			sb.WriteString(highlightEnd)
+		}
+		sb.WriteString(html.EscapeString(string(word[prefix:])))
+		start = end
+	}
+	return sb.String(), matched
+}
+
+// longestMatchingPrefix returns the length in runes of the longest term the word starts with.
+func longestMatchingPrefix(word []rune, terms []string) int {
+	lower := []rune(strings.ToLower(string(word)))
+	if len(lower) != len(word) {
+		return 0
+	}
+	longest := 0
+	for _, term := range terms {
+		t := []rune(term)
+		if len(t) > longest && len(t) <= len(lower) && string(lower[:len(t)]) == term {
+			longest = len(t)
+		}
+	}
+	return longest
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
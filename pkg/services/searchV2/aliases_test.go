@@ -0,0 +1,67 @@
+package searchV2
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/searchV2/extract"
+)
+
+func TestDashboardIndex_SearchAliases(t *testing.T) {
+	index := initTestOrgIndexFromDashes(t, []dashboard{
+		{id: 1, uid: "revenue", info: &extract.DashboardInfo{Title: "Revenue", SearchAliases: []string{"billing", "Payments & invoices"}}},
+		{id: 2, uid: "payments", info: &extract.DashboardInfo{Title: "Payments overview"}},
+		{id: 3, uid: "nodes", info: &extract.DashboardInfo{Title: "Nodes"}},
+	})
+	search := func(query string) []string {
+		return searchUIDs(t, index, testAllowAllFilter, DashboardQuery{Query: query, Kind: []string{string(entityKindDashboard)}})
+	}
+
+	require.Equal(t, []string{"revenue"}, search("billing"))
+	require.Equal(t, []string{"revenue"}, search("bill"))
+	require.Equal(t, []string{"revenue"}, search("invoices"))
+	// dashboards named after the query rank first
+	require.Equal(t, []string{"payments", "revenue"}, search("payments"))
+	require.Empty(t, search("billing nodes"))
+
+	t.Run("alias matches are highlighted", func(t *testing.T) {
+		q := DashboardQuery{Query: "pay", Kind: []string{string(entityKindDashboard)}, Fields: []string{resultFieldUID, resultFieldHighlight}}
+		resp := doSearchQuery(context.Background(), testLogger, index, testAllowAllFilter, q, &NoopQueryExtender{}, "")
+		require.NoError(t, resp.Error)
+
+		highlights := map[string]resultHighlight{}
+		uids, _ := resp.Frames[0].FieldByName(resultFieldUID)
+		field, _ := resp.Frames[0].FieldByName(resultFieldHighlight)
+		for i := 0; i < field.Len(); i++ {
+			var h resultHighlight
+			require.NoError(t, json.Unmarshal(*field.At(i).(*json.RawMessage), &h))
+			highlights[uids.At(i).(string)] = h
+		}
+		require.Equal(t, map[string]resultHighlight{
+			"payments": {Name: "<mark>Pay</mark>ments overview"},
+			"revenue":  {Aliases: []string{"<mark>Pay</mark>ments &amp; invoices"}},
+		}, highlights)
+	})
+}
+
+func TestHighlightTerms(t *testing.T) {
+	for _, tt := range []struct {
+		text     string
+		terms    []string
+		expected string
+		matched  bool
+	}{
+		{"CPU usage", []string{"cpu"}, "<mark>CPU</mark> usage", true},
+		{"node-exporter", []string{"exp", "export"}, "node-<mark>export</mark>er", true},
+		{"Reexport", []string{"export"}, "Reexport", false},
+		{"<script>", []string{"script"}, "&lt;<mark>script</mark>&gt;", true},
+		{"Überblick", []string{"über"}, "<mark>Über</mark>blick", true},
+	} {
+		marked, matched := highlightTerms(tt.text, tt.terms)
+		require.Equal(t, tt.expected, marked, tt.text)
+		require.Equal(t, tt.matched, matched, tt.text)
+	}
+}
@@ -0,0 +1,123 @@
+package searchV2
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// Versions of the search query API. Clients set DashboardQuery.APIVersion to the version they
+// were written for so that the query contract can change without breaking them, queries without
+// it are of version 1.
+const (
+	// QueryAPIVersion1 responds with the results frame alone, facets are left out
+	QueryAPIVersion1 = 1
+	// QueryAPIVersion2 responds with a list of frames: the results followed by a frame per facet
+	QueryAPIVersion2 = 2
+
+	CurrentQueryAPIVersion = QueryAPIVersion2
+)
+
+// QueryDeprecationHeader is set on the responses to queries using a deprecated version or shape
+// of the query API. The warnings are in the meta of the results frame.
+const QueryDeprecationHeader = "Deprecation"
+
+var ErrUnsupportedQueryAPIVersion = errors.New("unsupported search query API version")
+
+// queryMigration maps a query shape deprecated in an API version to the current one. It returns
+// the warning for the client, or an empty string when the query doesn't use the shape.
+type queryMigration struct {
+	deprecatedIn int
+	migrate      func(q *DashboardQuery) string
+}
+
+// queryMigrations apply to the queries of the versions before the one they were deprecated in.
+var queryMigrations = []queryMigration{
+	{deprecatedIn: QueryAPIVersion2, migrate: migrateSortDirection},
+	{deprecatedIn: QueryAPIVersion2, migrate: migrateHasPreview},
+}
+
+// migrateSortDirection maps sorts written "field ASC" or "field DESC" to "field" and "-field".
+func migrateSortDirection(q *DashboardQuery) string {
+	field, direction, ok := strings.Cut(strings.TrimSpace(q.Sort), " ")
+	if !ok {
+		return ""
+	}
+	switch strings.ToUpper(strings.TrimSpace(direction)) {
+	case "ASC":
+		q.Sort = field
+	case "DESC":
+		q.Sort = "-" + field
+	default:
+		return ""
+	}
+	return fmt.Sprintf("sort %q is deprecated, use %q", field+" "+strings.TrimSpace(direction), q.Sort)
+}
+
+// migrateHasPreview drops the preview filter, previews are no longer indexed.
+func migrateHasPreview(q *DashboardQuery) string {
+	if q.HasPreview == "" {
+		return ""
+	}
+	q.HasPreview = ""
+	return "hasPreview is deprecated and ignored"
+}
+
+// parseVersionedQuery parses the body of a search request and maps the query shapes of older API
+// versions to the current ones. It returns the deprecation warnings for the client.
+func parseVersionedQuery(body []byte) (DashboardQuery, []string, error) {
+	q := DashboardQuery{}
+	if err := json.Unmarshal(body, &q); err != nil {
+		return q, nil, err
+	}
+	if q.APIVersion == 0 {
+		q.APIVersion = QueryAPIVersion1
+	}
+	if q.APIVersion < QueryAPIVersion1 || q.APIVersion > CurrentQueryAPIVersion {
+		return q, nil, fmt.Errorf("%w: %d, the current version is %d", ErrUnsupportedQueryAPIVersion, q.APIVersion, CurrentQueryAPIVersion)
+	}
+
+	var warnings []string
+	if q.APIVersion < CurrentQueryAPIVersion {
+		warnings = append(warnings, fmt.Sprintf("apiVersion %d is deprecated, the current version is %d", q.APIVersion, CurrentQueryAPIVersion))
+	}
+	for _, m := range queryMigrations {
+		if q.APIVersion >= m.deprecatedIn {
+			continue
+		}
+		if warning := m.migrate(&q); warning != "" {
+			warnings = append(warnings, warning)
+		}
+	}
+	return q, warnings, nil
+}
+
+// marshalVersionedFrames marshals the frames of a response in the shape of the API version of
+// the query, and adds the API version and deprecation warnings to the meta of the results frame.
+// It returns all the warnings of the response.
+func marshalVersionedFrames(frames data.Frames, version int, warnings []string) ([]byte, []string, error) {
+	if version < QueryAPIVersion2 && len(frames) > 1 {
+		frames = frames[:1]
+		warnings = append(warnings, "facets are only returned from apiVersion "+strconv.Itoa(QueryAPIVersion2))
+	}
+	if len(frames) > 0 && frames[0].Meta != nil {
+		if meta, ok := frames[0].Meta.Custom.(*customMeta); ok {
+			meta.APIVersion = version
+			meta.Warnings = append(meta.Warnings, warnings...)
+		}
+	}
+
+	if version < QueryAPIVersion2 {
+		if len(frames) != 1 {
+			return nil, warnings, errors.New("invalid search response")
+		}
+		body, err := frames[0].MarshalJSON()
+		return body, warnings, err
+	}
+	body, err := json.Marshal(frames)
+	return body, warnings, err
+}
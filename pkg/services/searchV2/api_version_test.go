@@ -0,0 +1,83 @@
+package searchV2
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVersionedQuery(t *testing.T) {
+	t.Run("queries without a version are of version 1", func(t *testing.T) {
+		q, warnings, err := parseVersionedQuery([]byte(`{"query": "cpu", "sort": "name_sort DESC", "hasPreview": "dark"}`))
+		require.NoError(t, err)
+		require.Equal(t, QueryAPIVersion1, q.APIVersion)
+		require.Equal(t, "-name_sort", q.Sort)
+		require.Empty(t, q.HasPreview)
+		require.Equal(t, []string{
+			"apiVersion 1 is deprecated, the current version is 2",
+			`sort "name_sort DESC" is deprecated, use "-name_sort"`,
+			"hasPreview is deprecated and ignored",
+		}, warnings)
+
+		q, _, err = parseVersionedQuery([]byte(`{"sort": "name_sort asc"}`))
+		require.NoError(t, err)
+		require.Equal(t, "name_sort", q.Sort)
+	})
+
+	t.Run("current queries aren't migrated", func(t *testing.T) {
+		q, warnings, err := parseVersionedQuery([]byte(`{"apiVersion": 2, "sort": "-name_sort"}`))
+		require.NoError(t, err)
+		require.Equal(t, "-name_sort", q.Sort)
+		require.Empty(t, warnings)
+	})
+
+	t.Run("unknown versions are rejected", func(t *testing.T) {
+		for _, body := range []string{`{"apiVersion": 3}`, `{"apiVersion": -1}`} {
+			_, _, err := parseVersionedQuery([]byte(body))
+			require.ErrorIs(t, err, ErrUnsupportedQueryAPIVersion, body)
+		}
+	})
+}
+
+func TestMarshalVersionedFrames(t *testing.T) {
+	frames := func() data.Frames {
+		results := data.NewFrame("Query results", data.NewField("uid", nil, []string{"a"}))
+		results.SetMeta(&data.FrameMeta{Custom: &customMeta{Count: 1}})
+		return data.Frames{results, data.NewFrame("Facet: tag", data.NewField("tag", nil, []string{"prod"}))}
+	}
+	meta := func(t *testing.T, frame json.RawMessage) customMeta {
+		t.Helper()
+		var decoded struct {
+			Schema struct {
+				Meta struct {
+					Custom customMeta `json:"custom"`
+				} `json:"meta"`
+			} `json:"schema"`
+		}
+		require.NoError(t, json.Unmarshal(frame, &decoded))
+		return decoded.Schema.Meta.Custom
+	}
+
+	t.Run("version 1 responds with the results frame", func(t *testing.T) {
+		body, warnings, err := marshalVersionedFrames(frames(), QueryAPIVersion1, []string{"apiVersion 1 is deprecated"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"apiVersion 1 is deprecated", "facets are only returned from apiVersion 2"}, warnings)
+		m := meta(t, body)
+		require.Equal(t, QueryAPIVersion1, m.APIVersion)
+		require.Equal(t, warnings, m.Warnings)
+	})
+
+	t.Run("version 2 responds with every frame", func(t *testing.T) {
+		body, warnings, err := marshalVersionedFrames(frames(), QueryAPIVersion2, nil)
+		require.NoError(t, err)
+		require.Empty(t, warnings)
+		var list []json.RawMessage
+		require.NoError(t, json.Unmarshal(body, &list))
+		require.Len(t, list, 2)
+		m := meta(t, list[0])
+		require.Equal(t, QueryAPIVersion2, m.APIVersion)
+		require.Empty(t, m.Warnings)
+	})
+}
@@ -19,7 +19,23 @@ type FutureAuthService interface {
 	GetDashboardReadFilter(user *user.SignedInUser) (ResourceFilter, error)
 }
 
-var _ FutureAuthService = (*simpleSQLAuthService)(nil)
+// readableUIDsAuthService is implemented by auth services which can list all the dashboards
+// and folders a user can read, allowing permissions to be checked within the search query.
+type readableUIDsAuthService interface {
+	GetDashboardReadUIDs(user *user.SignedInUser) (map[string]bool, error)
+}
+
+// capabilitiesAuthService is implemented by auth services which can tell what a user can do with
+// the dashboards and folders besides reading them.
+type capabilitiesAuthService interface {
+	GetDashboardCapabilities(user *user.SignedInUser) (capabilityChecker, error)
+}
+
+var (
+	_ FutureAuthService       = (*simpleSQLAuthService)(nil)
+	_ readableUIDsAuthService = (*simpleSQLAuthService)(nil)
+	_ capabilitiesAuthService = (*simpleSQLAuthService)(nil)
+)
 
 type simpleSQLAuthService struct {
 	sql *sqlstore.SQLStore
@@ -32,20 +48,64 @@ type dashIdQueryResult struct {
 
 func (a *simpleSQLAuthService) getDashboardTableAuthFilter(user *user.SignedInUser) searchstore.FilterWhere {
 	if a.ac.IsDisabled() {
-		return permissions.DashboardPermissionFilter{
-			OrgRole:         user.OrgRole,
-			OrgId:           user.OrgID,
-			Dialect:         a.sql.Dialect,
-			UserId:          user.UserID,
-			PermissionLevel: models.PERMISSION_VIEW,
-		}
+		return a.getLegacyPermissionFilter(user, models.PERMISSION_VIEW)
 	}
 
 	return permissions.NewAccessControlDashboardPermissionFilter(user, models.PERMISSION_VIEW, searchstore.TypeDashboard)
 }
 
 func (a *simpleSQLAuthService) GetDashboardReadFilter(user *user.SignedInUser) (ResourceFilter, error) {
-	filter := a.getDashboardTableAuthFilter(user)
+	uids, err := a.GetDashboardReadUIDs(user)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(uid string) bool {
+		return uids[uid]
+	}, nil
+}
+
+func (a *simpleSQLAuthService) GetDashboardReadUIDs(user *user.SignedInUser) (map[string]bool, error) {
+	return a.getDashboardUIDs(user, a.getDashboardTableAuthFilter(user))
+}
+
+// GetDashboardCapabilities evaluates the permissions of the user in memory, or lists the dashboards
+// and folders the user can edit and administer when access control is disabled.
+func (a *simpleSQLAuthService) GetDashboardCapabilities(signedInUser *user.SignedInUser) (capabilityChecker, error) {
+	if !a.ac.IsDisabled() {
+		return newPermissionsCapabilityChecker(signedInUser), nil
+	}
+
+	editable, err := a.getDashboardUIDs(signedInUser, a.getLegacyPermissionFilter(signedInUser, models.PERMISSION_EDIT))
+	if err != nil {
+		return nil, err
+	}
+	administrable, err := a.getDashboardUIDs(signedInUser, a.getLegacyPermissionFilter(signedInUser, models.PERMISSION_ADMIN))
+	if err != nil {
+		return nil, err
+	}
+
+	canStar := canStarDashboards(signedInUser)
+	return func(kind entityKind, uid string, folderUID string) resultCapabilities {
+		return resultCapabilities{
+			canEdit:  editable[uid],
+			canAdmin: administrable[uid],
+			canStar:  canStar && kind == entityKindDashboard,
+		}
+	}, nil
+}
+
+func (a *simpleSQLAuthService) getLegacyPermissionFilter(user *user.SignedInUser, level models.PermissionType) searchstore.FilterWhere {
+	return permissions.DashboardPermissionFilter{
+		OrgRole:         user.OrgRole,
+		OrgId:           user.OrgID,
+		Dialect:         a.sql.Dialect,
+		UserId:          user.UserID,
+		PermissionLevel: level,
+	}
+}
+
+func (a *simpleSQLAuthService) getDashboardUIDs(user *user.SignedInUser, filter searchstore.FilterWhere) (map[string]bool, error) {
 	rows := make([]*dashIdQueryResult, 0)
 
 	err := a.sql.WithDbSession(context.Background(), func(sess *sqlstore.DBSession) error {
@@ -71,8 +131,5 @@ func (a *simpleSQLAuthService) GetDashboardReadFilter(user *user.SignedInUser) (
 	for i := 0; i < len(rows); i++ {
 		uids[rows[i].UID] = true
 	}
-
-	return func(uid string) bool {
-		return uids[uid]
-	}, err
+	return uids, nil
 }
@@ -0,0 +1,308 @@
+package searchV2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/searchV2/extract"
+)
+
+var benchmarkWords = []string{
+	"cpu", "memory", "disk", "network", "latency", "errors", "requests", "saturation",
+	"kubernetes", "postgres", "redis", "nginx", "kafka", "billing", "checkout", "payments",
+	"frontend", "backend", "gateway", "queue", "cache", "storage", "ingest", "overview",
+}
+
+var benchmarkPanelTypes = []string{"timeseries", "stat", "table", "gauge", "barchart", "logs", "text"}
+
+// BenchmarkOptions describes the synthetic instance indexed and queried by RunBenchmark.
+type BenchmarkOptions struct {
+	Orgs               int
+	FoldersPerOrg      int
+	DashboardsPerOrg   int
+	PanelsPerDashboard int
+	// QueriesPerOrg is the number of queries run against the index of each organization
+	QueriesPerOrg int
+	// Concurrency is the number of queries run at once
+	Concurrency int
+	// Seed makes the generated dashboards and queries reproducible
+	Seed int64
+	// Sparse builds sparse indexes, see setting.SearchSettings.IndexMode
+	Sparse bool
+}
+
+// DefaultBenchmarkOptions returns options for an instance with a few large organizations.
+func DefaultBenchmarkOptions() BenchmarkOptions {
+	return BenchmarkOptions{
+		Orgs:               3,
+		FoldersPerOrg:      50,
+		DashboardsPerOrg:   5000,
+		PanelsPerDashboard: 10,
+		QueriesPerOrg:      1000,
+		Concurrency:        4,
+		Seed:               1,
+	}
+}
+
+// LatencyPercentiles summarizes the latency of a kind of query.
+type LatencyPercentiles struct {
+	P50 time.Duration `json:"p50"`
+	P90 time.Duration `json:"p90"`
+	P99 time.Duration `json:"p99"`
+	Max time.Duration `json:"max"`
+}
+
+// BenchmarkQueryResult is the latency of the queries of a kind.
+type BenchmarkQueryResult struct {
+	Name    string             `json:"name"`
+	Count   int                `json:"count"`
+	Latency LatencyPercentiles `json:"latency"`
+}
+
+// BenchmarkResult is what RunBenchmark measured.
+type BenchmarkResult struct {
+	Options   BenchmarkOptions `json:"options"`
+	Documents int              `json:"documents"` // dashboards and folders, panels excluded
+	BuildTime time.Duration    `json:"buildTime"`
+	// HeapBytes is the heap held by the indexes of all organizations once built
+	HeapBytes uint64                 `json:"heapBytes"`
+	Queries   []BenchmarkQueryResult `json:"queries"`
+}
+
+type benchmarkQuery struct {
+	name  string
+	query func(r *rand.Rand) DashboardQuery
+}
+
+// benchmarkQueries is the mix of queries run by RunBenchmark, they are run in turn.
+var benchmarkQueries = []benchmarkQuery{
+	{name: "list", query: func(r *rand.Rand) DashboardQuery {
+		return DashboardQuery{Kind: []string{string(entityKindDashboard), string(entityKindFolder)}, Limit: 50}
+	}},
+	{name: "title", query: func(r *rand.Rand) DashboardQuery {
+		return DashboardQuery{Query: benchmarkWords[r.Intn(len(benchmarkWords))], Limit: 50}
+	}},
+	{name: "prefix", query: func(r *rand.Rand) DashboardQuery {
+		return DashboardQuery{Query: benchmarkWords[r.Intn(len(benchmarkWords))][:3], Limit: 50}
+	}},
+	{name: "tags", query: func(r *rand.Rand) DashboardQuery {
+		return DashboardQuery{Tags: []string{benchmarkWords[r.Intn(len(benchmarkWords))]}, Limit: 50}
+	}},
+	{name: "panel_type", query: func(r *rand.Rand) DashboardQuery {
+		return DashboardQuery{Kind: []string{string(entityKindPanel)}, PanelType: benchmarkPanelTypes[r.Intn(len(benchmarkPanelTypes))], Limit: 50}
+	}},
+	{name: "sorted_facets", query: func(r *rand.Rand) DashboardQuery {
+		return DashboardQuery{
+			Kind:  []string{string(entityKindDashboard)},
+			Sort:  documentFieldName_sort,
+			Facet: []FacetField{{Field: documentFieldTag}},
+			Limit: 50,
+		}
+	}},
+}
+
+// RunBenchmark indexes synthetic organizations and measures the index build time and memory and
+// the latency of a mix of queries, so that performance regressions of the indexer and of the
+// query path can be caught before release.
+func RunBenchmark(ctx context.Context, opts BenchmarkOptions) (*BenchmarkResult, error) {
+	if opts.Orgs < 1 || opts.DashboardsPerOrg < 1 {
+		return nil, errors.New("at least one organization with one dashboard is required")
+	}
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+
+	logger := log.New("searchV2.benchmark")
+	orgDashboards := make([][]dashboard, opts.Orgs)
+	result := &BenchmarkResult{Options: opts}
+	for org := range orgDashboards {
+		orgDashboards[org] = generateBenchmarkDashboards(rand.New(rand.NewSource(opts.Seed+int64(org))), opts)
+		result.Documents += len(orgDashboards[org])
+	}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	mode := indexModeFull
+	if opts.Sparse {
+		mode = indexModeSparse
+	}
+
+	started := time.Now()
+	indexes := make([]*orgIndex, opts.Orgs)
+	defer func() {
+		for _, index := range indexes {
+			if index == nil {
+				continue
+			}
+			for _, w := range index.writers {
+				_ = w.Close()
+			}
+		}
+	}()
+	for org, dashboards := range orgDashboards {
+		index, err := initOrgIndex(dashboards, logger, NoopDocumentExtender{}.GetDashboardExtender(int64(org+1)), mode, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error building the index of organization %d: %w", org+1, err)
+		}
+		indexes[org] = index
+	}
+	result.BuildTime = time.Since(started)
+
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	// held during both measurements, so that only the indexes are accounted for
+	runtime.KeepAlive(orgDashboards)
+	if after.HeapAlloc > before.HeapAlloc {
+		result.HeapBytes = after.HeapAlloc - before.HeapAlloc
+	}
+
+	latencies, err := runBenchmarkQueries(ctx, logger, indexes, opts)
+	if err != nil {
+		return nil, err
+	}
+	for i, q := range benchmarkQueries {
+		result.Queries = append(result.Queries, BenchmarkQueryResult{
+			Name:    q.name,
+			Count:   len(latencies[i]),
+			Latency: latencyPercentiles(latencies[i]),
+		})
+	}
+
+	return result, nil
+}
+
+// runBenchmarkQueries returns the latencies of each query of benchmarkQueries.
+func runBenchmarkQueries(ctx context.Context, logger log.Logger, indexes []*orgIndex, opts BenchmarkOptions) ([][]time.Duration, error) {
+	type job struct {
+		index *orgIndex
+		kind  int
+		q     DashboardQuery
+	}
+
+	r := rand.New(rand.NewSource(opts.Seed))
+	jobs := make(chan job)
+	go func() {
+		defer close(jobs)
+		for i := 0; i < opts.QueriesPerOrg; i++ {
+			for _, index := range indexes {
+				kind := i % len(benchmarkQueries)
+				select {
+				case jobs <- job{index: index, kind: kind, q: benchmarkQueries[kind].query(r)}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		latencies = make([][]time.Duration, len(benchmarkQueries))
+		queryErr  error
+	)
+	allowAll := func(uid string) bool { return true }
+	for w := 0; w < opts.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				started := time.Now()
+				resp := doSearchQuery(ctx, logger, j.index, allowAll, j.q, &NoopQueryExtender{}, "")
+				elapsed := time.Since(started)
+
+				mu.Lock()
+				if resp.Error != nil && queryErr == nil {
+					queryErr = fmt.Errorf("%s query failed: %w", benchmarkQueries[j.kind].name, resp.Error)
+				}
+				latencies[j.kind] = append(latencies[j.kind], elapsed)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if queryErr != nil {
+		return nil, queryErr
+	}
+	return latencies, ctx.Err()
+}
+
+func latencyPercentiles(latencies []time.Duration) LatencyPercentiles {
+	if len(latencies) == 0 {
+		return LatencyPercentiles{}
+	}
+	sorted := make([]time.Duration, len(latencies))
+	copy(sorted, latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	// nearest rank
+	percentile := func(p int) time.Duration {
+		rank := (p*len(sorted) + 99) / 100
+		if rank < 1 {
+			rank = 1
+		}
+		return sorted[rank-1]
+	}
+	return LatencyPercentiles{
+		P50: percentile(50),
+		P90: percentile(90),
+		P99: percentile(99),
+		Max: sorted[len(sorted)-1],
+	}
+}
+
+// generateBenchmarkDashboards returns the folders and dashboards of an organization, with titles,
+// tags and panel types drawn from small vocabularies so that queries match realistic shares of them.
+func generateBenchmarkDashboards(r *rand.Rand, opts BenchmarkOptions) []dashboard {
+	word := func() string { return benchmarkWords[r.Intn(len(benchmarkWords))] }
+	created := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	dashboards := make([]dashboard, 0, opts.FoldersPerOrg+opts.DashboardsPerOrg)
+	for i := 0; i < opts.FoldersPerOrg; i++ {
+		dashboards = append(dashboards, dashboard{
+			id:       int64(i + 1),
+			uid:      fmt.Sprintf("folder-%d", i),
+			isFolder: true,
+			created:  created,
+			updated:  created,
+			info:     &extract.DashboardInfo{Title: fmt.Sprintf("%s %s", word(), word())},
+		})
+	}
+
+	for i := 0; i < opts.DashboardsPerOrg; i++ {
+		info := &extract.DashboardInfo{
+			Title: fmt.Sprintf("%s %s %s %d", word(), word(), word(), i),
+			Tags:  []string{word(), word()},
+		}
+		for p := 0; p < opts.PanelsPerDashboard; p++ {
+			info.Panels = append(info.Panels, extract.PanelInfo{
+				ID:    int64(p + 1),
+				Title: fmt.Sprintf("%s %s", word(), word()),
+				Type:  benchmarkPanelTypes[r.Intn(len(benchmarkPanelTypes))],
+			})
+		}
+
+		dash := dashboard{
+			id:      int64(opts.FoldersPerOrg + i + 1),
+			uid:     fmt.Sprintf("dash-%d", i),
+			created: created,
+			updated: created.Add(time.Duration(i) * time.Minute),
+			info:    info,
+		}
+		if opts.FoldersPerOrg > 0 {
+			dash.folderID = int64(r.Intn(opts.FoldersPerOrg) + 1)
+		}
+		dashboards = append(dashboards, dash)
+	}
+	return dashboards
+}
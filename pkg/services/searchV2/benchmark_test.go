@@ -0,0 +1,111 @@
+package searchV2
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunBenchmark(t *testing.T) {
+	result, err := RunBenchmark(context.Background(), BenchmarkOptions{
+		Orgs:               2,
+		FoldersPerOrg:      3,
+		DashboardsPerOrg:   20,
+		PanelsPerDashboard: 2,
+		QueriesPerOrg:      len(benchmarkQueries) * 2,
+		Concurrency:        2,
+		Seed:               1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 46, result.Documents)
+	require.Len(t, result.Queries, len(benchmarkQueries))
+	for _, q := range result.Queries {
+		require.Equal(t, 4, q.Count, q.Name)
+		require.LessOrEqual(t, q.Latency.P50, q.Latency.P99)
+		require.LessOrEqual(t, q.Latency.P99, q.Latency.Max)
+	}
+
+	_, err = RunBenchmark(context.Background(), BenchmarkOptions{})
+	require.Error(t, err)
+}
+
+func TestLatencyPercentiles(t *testing.T) {
+	latencies := make([]time.Duration, 0, 100)
+	for i := 100; i > 0; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	require.Equal(t, LatencyPercentiles{
+		P50: 50 * time.Millisecond,
+		P90: 90 * time.Millisecond,
+		P99: 99 * time.Millisecond,
+		Max: 100 * time.Millisecond,
+	}, latencyPercentiles(latencies))
+	require.Equal(t, LatencyPercentiles{}, latencyPercentiles(nil))
+}
+
+func benchmarkOptions(dashboards int) BenchmarkOptions {
+	opts := DefaultBenchmarkOptions()
+	opts.DashboardsPerOrg = dashboards
+	return opts
+}
+
+func BenchmarkBuildOrgIndex(b *testing.B) {
+	for _, count := range []int{1000, 10000} {
+		dashboards := generateBenchmarkDashboards(rand.New(rand.NewSource(1)), benchmarkOptions(count))
+		b.Run(fmt.Sprintf("dashboards=%d", count), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				index, err := initOrgIndex(dashboards, testLogger, NoopDocumentExtender{}.GetDashboardExtender(testOrgID), indexModeFull, nil)
+				require.NoError(b, err)
+				for _, w := range index.writers {
+					_ = w.Close()
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkSearchQueries(b *testing.B) {
+	index := initTestOrgIndexFromDashes(b, generateBenchmarkDashboards(rand.New(rand.NewSource(1)), benchmarkOptions(5000)))
+	for _, q := range benchmarkQueries {
+		q := q
+		b.Run(q.name, func(b *testing.B) {
+			r := rand.New(rand.NewSource(1))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				resp := doSearchQuery(context.Background(), testLogger, index, testAllowAllFilter, q.query(r), &NoopQueryExtender{}, "")
+				require.NoError(b, resp.Error)
+			}
+		})
+	}
+}
+
+// BenchmarkTopKEarlyTermination compares the filter queries of large organizations with and
+// without ExperimentTopK.
+func BenchmarkTopKEarlyTermination(b *testing.B) {
+	index := initTestOrgIndexFromDashes(b, generateBenchmarkDashboards(rand.New(rand.NewSource(1)), benchmarkOptions(20000)))
+	for _, q := range benchmarkQueries {
+		q := q
+		if q.name != "list" && q.name != "tags" && q.name != "panel_type" {
+			continue
+		}
+		for _, experiments := range [][]string{nil, {ExperimentTopK}} {
+			experiments := experiments
+			b.Run(fmt.Sprintf("%s/topK=%t", q.name, experiments != nil), func(b *testing.B) {
+				r := rand.New(rand.NewSource(1))
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					query := q.query(r)
+					query.Experiments = experiments
+					applyExperiments(&query)
+					resp := doSearchQuery(context.Background(), testLogger, index, testAllowAllFilter, query, &NoopQueryExtender{}, "")
+					require.NoError(b, resp.Error)
+				}
+			})
+		}
+	}
+}
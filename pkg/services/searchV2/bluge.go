@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/blugelabs/bluge"
+	"github.com/blugelabs/bluge/index"
 	"github.com/blugelabs/bluge/search"
 	"github.com/blugelabs/bluge/search/aggregations"
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
@@ -30,17 +31,61 @@ const (
 	documentFieldTransformer = "transformer"
 	documentFieldDSUID       = "ds_uid"
 	documentFieldDSType      = "ds_type"
+	documentFieldTeam        = "team" // teams allowed to edit the folder
+	documentFieldLanguage    = "language"
+	documentFieldName_lang   = "name_lang" // name analyzed for the document language
+	documentFieldAuthzUID    = "authz_uid" // UID of the dashboard or folder permissions are checked on
+	documentFieldIntegrity   = "integrity" // referential integrity problems, see the Report* constants
+	documentFieldQuality     = "quality_score"
 	DocumentFieldCreatedAt   = "created_at"
 	DocumentFieldUpdatedAt   = "updated_at"
 )
 
-func initOrgIndex(dashboards []dashboard, logger log.Logger, extendDoc ExtendDashboardFunc) (*orgIndex, error) {
-	dashboardWriter, err := bluge.OpenWriter(bluge.InMemoryOnlyConfig())
+// openOrgIndex opens an org index on top of the given directory, which may already
+// contain a previously built index.
+func openOrgIndex(dir *memoryDirectory) (*orgIndex, error) {
+	dashboardWriter, err := bluge.OpenWriter(bluge.DefaultConfigWithDirectory(func() index.Directory {
+		return dir
+	}))
 	if err != nil {
 		return nil, fmt.Errorf("error opening writer: %v", err)
 	}
+	return &orgIndex{
+		mode:      indexModeFull,
+		placement: indexPlacementMemory,
+		writers: map[indexType]*bluge.Writer{
+			indexTypeDashboard: dashboardWriter,
+		},
+		directories: map[indexType]*memoryDirectory{
+			indexTypeDashboard: dir,
+		},
+	}, nil
+}
+
+// initOrgIndex builds the index of the dashboards of an organization. With checkpoints, the build
+// saves its progress periodically and starts from the checkpoint it resumes, if any.
+func initOrgIndex(dashboards []dashboard, logger log.Logger, extendDoc ExtendDashboardFunc, mode indexMode, checkpoints *buildCheckpoints) (*orgIndex, error) {
+	orgIdx, err := openOrgIndex(checkpoints.directory())
+	if err != nil {
+		return nil, err
+	}
+	orgIdx.mode = mode
+	if err := fillOrgIndex(orgIdx, dashboards, logger, extendDoc, checkpoints); err != nil {
+		return nil, err
+	}
+	return orgIdx, nil
+}
+
+// fillOrgIndex indexes the dashboards in an opened org index, in the mode of the index.
+func fillOrgIndex(orgIdx *orgIndex, dashboards []dashboard, logger log.Logger, extendDoc ExtendDashboardFunc, checkpoints *buildCheckpoints) error {
+	mode := orgIdx.mode
+	dashboardWriter := orgIdx.writerForIndex(indexTypeDashboard)
 	// Not closing Writer here since we use it later while processing dashboard change events.
 
+	if err := checkpoints.removeStale(orgIdx, dashboards); err != nil {
+		return fmt.Errorf("error removing changed dashboards from checkpoint: %w", err)
+	}
+
 	start := time.Now()
 	label := start
 
@@ -66,75 +111,97 @@ func initOrgIndex(dashboards []dashboard, logger log.Logger, extendDoc ExtendDas
 		return nil
 	}
 
+	// Checkpoints are only saved between dashboards, so that they never hold part of the panels
+	// of a dashboard.
+	checkpointIfDue := func(uid string, dash dashboard) error {
+		checkpoints.indexedDoc(uid, dash)
+		if !checkpoints.due() {
+			return nil
+		}
+		if err := flushIfRequired(true); err != nil {
+			return err
+		}
+		checkpoints.checkpoint(orgIdx)
+		return nil
+	}
+
 	// First index the folders to construct folderIdLookup.
 	folderIdLookup := make(map[int64]string, 50)
 	for _, dash := range dashboards {
 		if !dash.isFolder {
 			continue
 		}
+		uid := checkpointUID(dash)
+		folderIdLookup[dash.id] = uid
+		if checkpoints.upToDate(uid, dash) {
+			continue
+		}
 		doc := getFolderDashboardDoc(dash)
 		if err := extendDoc(dash.uid, doc); err != nil {
-			return nil, err
+			return err
 		}
 		batch.Insert(doc)
 		if err := flushIfRequired(false); err != nil {
-			return nil, err
+			return err
 		}
-		uid := dash.uid
-		if uid == "" {
-			uid = "general"
+		if err := checkpointIfDue(uid, dash); err != nil {
+			return err
 		}
-		folderIdLookup[dash.id] = uid
 	}
 
 	// Then each dashboard.
 	for _, dash := range dashboards {
-		if dash.isFolder {
+		if dash.isFolder || checkpoints.upToDate(dash.uid, dash) {
 			continue
 		}
-		folderUID := folderIdLookup[dash.folderID]
-		location := folderUID
-		doc := getNonFolderDashboardDoc(dash, location)
+		location := dash.folderUID
+		if location == "" {
+			location = folderIdLookup[dash.folderID]
+		}
+		doc := getDashboardDoc(dash, location, mode)
 		if err := extendDoc(dash.uid, doc); err != nil {
-			return nil, err
+			return err
 		}
 		batch.Insert(doc)
 		if err := flushIfRequired(false); err != nil {
-			return nil, err
-		}
-
-		// Index each panel in dashboard.
-		if location != "" {
-			location += "/"
+			return err
 		}
-		location += dash.uid
-		docs := getDashboardPanelDocs(dash, location)
-
-		for _, panelDoc := range docs {
-			batch.Insert(panelDoc)
-			if err := flushIfRequired(false); err != nil {
-				return nil, err
+		if mode != indexModeSparse {
+			// Index each panel in dashboard.
+			if location != "" {
+				location += "/"
+			}
+			location += dash.uid
+			docs := getDashboardPanelDocs(dash, location)
+
+			for _, panelDoc := range docs {
+				batch.Insert(panelDoc)
+				if err := flushIfRequired(false); err != nil {
+					return err
+				}
 			}
 		}
+		if err := checkpointIfDue(dash.uid, dash); err != nil {
+			return err
+		}
 	}
 
 	// Flush docs in batch with force as we are in the end.
 	if err := flushIfRequired(true); err != nil {
-		return nil, err
+		return err
 	}
 
 	logger.Info("Finish inserting docs into index", "elapsed", time.Since(label))
 	logger.Info("Finish building index", "totalElapsed", time.Since(start))
-	return &orgIndex{
-		writers: map[indexType]*bluge.Writer{
-			indexTypeDashboard: dashboardWriter,
-		},
-	}, err
+	return nil
 }
 
 func getFolderDashboardDoc(dash dashboard) *bluge.Document {
 	uid := dash.uid
 	url := fmt.Sprintf("/dashboards/f/%s/%s", dash.uid, dash.slug)
+	if dash.url != "" {
+		url = dash.url
+	}
 	if uid == "" {
 		uid = "general"
 		url = "/dashboards"
@@ -142,21 +209,44 @@ func getFolderDashboardDoc(dash dashboard) *bluge.Document {
 		dash.info.Description = ""
 	}
 
-	return newSearchDocument(uid, dash.info.Title, dash.info.Description, url).
+	doc := newSearchDocument(uid, dash.info.Title, dash.info.Description, url).
 		AddField(bluge.NewKeywordField(documentFieldKind, string(entityKindFolder)).Aggregatable().StoreValue()).
+		AddField(bluge.NewKeywordField(documentFieldAuthzUID, uid)).
 		AddField(bluge.NewDateTimeField(DocumentFieldCreatedAt, dash.created).Sortable().StoreValue()).
 		AddField(bluge.NewDateTimeField(DocumentFieldUpdatedAt, dash.updated).Sortable().StoreValue())
+	addFreshnessFields(doc, time.Now())
+
+	for _, teamID := range dash.teams {
+		doc.AddField(bluge.NewKeywordField(documentFieldTeam, teamTerm(teamID)))
+	}
+
+	return doc
 }
 
 func getNonFolderDashboardDoc(dash dashboard, location string) *bluge.Document {
-	url := fmt.Sprintf("/d/%s/%s", dash.uid, dash.slug)
+	url := dashboardURL(dash)
 
 	// Dashboard document
 	doc := newSearchDocument(dash.uid, dash.info.Title, dash.info.Description, url).
 		AddField(bluge.NewKeywordField(documentFieldKind, string(entityKindDashboard)).Aggregatable().StoreValue()).
+		AddField(bluge.NewKeywordField(documentFieldAuthzUID, dash.uid)).
 		AddField(bluge.NewKeywordField(documentFieldLocation, location).Aggregatable().StoreValue()).
 		AddField(bluge.NewDateTimeField(DocumentFieldCreatedAt, dash.created).Sortable().StoreValue()).
 		AddField(bluge.NewDateTimeField(DocumentFieldUpdatedAt, dash.updated).Sortable().StoreValue())
+	addLanguageFields(doc, dashboardLanguage(dash), dash.info.Title)
+	addIntegrityFields(doc, dashboardIntegrity(dash, location))
+	addQualityField(doc, dashboardQuality(dash, time.Now()))
+	addFreshnessFields(doc, time.Now())
+	addCustomFields(doc, dash.custom)
+	addGovernanceField(doc, dash.governance)
+	addProvisioningFields(doc, dash.provisioning)
+	addEmbeddingField(doc, dash.embedding)
+	addAliasFields(doc, dash.info.SearchAliases)
+	addVariableFields(doc, dash.info.TemplateVars, dash.info.TemplateVarValues)
+
+	for _, teamID := range dash.teams {
+		doc.AddField(bluge.NewKeywordField(documentFieldTeam, teamTerm(teamID)))
+	}
 
 	for _, tag := range dash.info.Tags {
 		doc.AddField(bluge.NewKeywordField(documentFieldTag, tag).
@@ -185,7 +275,9 @@ func getNonFolderDashboardDoc(dash dashboard, location string) *bluge.Document {
 
 func getDashboardPanelDocs(dash dashboard, location string) []*bluge.Document {
 	var docs []*bluge.Document
-	url := fmt.Sprintf("/d/%s/%s", dash.uid, dash.slug)
+	url := dashboardURL(dash)
+	// panel texts are too short to tell their language, they get the one of the dashboard
+	language := dashboardLanguage(dash)
 	for _, panel := range dash.info.Panels {
 		if panel.Type == "row" {
 			continue // for now, we are excluding rows from the search index
@@ -197,7 +289,15 @@ func getDashboardPanelDocs(dash dashboard, location string) []*bluge.Document {
 		doc := newSearchDocument(uid, panel.Title, panel.Description, purl).
 			AddField(bluge.NewKeywordField(documentFieldPanelType, panel.Type).Aggregatable().StoreValue()).
 			AddField(bluge.NewKeywordField(documentFieldLocation, location).Aggregatable().StoreValue()).
-			AddField(bluge.NewKeywordField(documentFieldKind, string(entityKindPanel)).Aggregatable().StoreValue()) // likely want independent index for this
+			AddField(bluge.NewKeywordField(documentFieldKind, string(entityKindPanel)).Aggregatable().StoreValue()). // likely want independent index for this
+			AddField(bluge.NewKeywordField(documentFieldAuthzUID, dash.uid)).
+			// panels change with their dashboard, they aren't sorted by update time though
+			AddField(bluge.NewDateTimeField(DocumentFieldUpdatedAt, dash.updated).StoreValue())
+		addLanguageFields(doc, language, panel.Title)
+		addFreshnessFields(doc, time.Now())
+		if len(panel.MissingDatasource) > 0 {
+			addIntegrityFields(doc, []string{ReportMissingDatasource})
+		}
 
 		for _, xform := range panel.Transformer {
 			doc.AddField(bluge.NewKeywordField(documentFieldTransformer, xform).Aggregatable())
@@ -223,6 +323,13 @@ func getDashboardPanelDocs(dash dashboard, location string) []*bluge.Document {
 	return docs
 }
 
+func dashboardURL(dash dashboard) string {
+	if dash.url != "" {
+		return dash.url
+	}
+	return fmt.Sprintf("/d/%s/%s", dash.uid, dash.slug)
+}
+
 // Names need to be indexed a few ways to support key features
 func newSearchDocument(uid string, name string, descr string, url string) *bluge.Document {
 	doc := bluge.NewDocument(uid)
@@ -350,6 +457,28 @@ func getDashboardLocation(index *orgIndex, dashboardUID string) (string, bool, e
 	return dashboardLocation, found, err
 }
 
+// isFolderIndexed returns true when the index has a folder document with the UID.
+func isFolderIndexed(index *orgIndex, folderUID string) (bool, error) {
+	reader, cancel, err := index.readerForIndex(indexTypeDashboard)
+	if err != nil {
+		return false, err
+	}
+	defer cancel()
+
+	fullQuery := bluge.NewBooleanQuery()
+	fullQuery.AddMust(bluge.NewTermQuery(folderUID).SetField(documentFieldUID))
+	fullQuery.AddMust(bluge.NewTermQuery(string(entityKindFolder)).SetField(documentFieldKind))
+	documentMatchIterator, err := reader.Search(context.Background(), bluge.NewTopNSearch(1, fullQuery))
+	if err != nil {
+		return false, err
+	}
+	match, err := documentMatchIterator.Next()
+	return match != nil, err
+}
+
+// defaultQueryLimit is the number of results returned when the query has no limit.
+const defaultQueryLimit = 50 // default view
+
 //nolint:gocyclo
 func doSearchQuery(
 	ctx context.Context,
@@ -361,7 +490,11 @@ func doSearchQuery(
 	appSubUrl string,
 ) *backend.DataResponse {
 	response := &backend.DataResponse{}
-	header := &customMeta{}
+	header := &customMeta{IndexGeneration: index.generation, Degraded: index.isCorrupted(), StaleReason: index.staleReason()}
+	header.Stale = header.StaleReason != ""
+	if updated := index.lastUpdated(); !updated.IsZero() {
+		header.IndexUpdated = &updated
+	}
 
 	reader, cancel, err := index.readerForIndex(indexTypeDashboard)
 	if err != nil {
@@ -373,7 +506,13 @@ func doSearchQuery(
 
 	hasConstraints := false
 	fullQuery := bluge.NewBooleanQuery()
-	fullQuery.AddMust(newPermissionFilter(filter, logger))
+	var access bluge.Query
+	if q.readableUIDs != nil {
+		access = newReadableUIDsFilter(q.readableUIDs)
+	} else {
+		access = newPermissionFilter(filter, logger)
+	}
+	fullQuery.AddMust(access)
 
 	// Only show dashboard / folders / panels.
 	if len(q.Kind) > 0 {
@@ -398,6 +537,17 @@ func doSearchQuery(
 		hasConstraints = true
 	}
 
+	// Promoted dashboards merged at the top of the results
+	for _, uid := range q.excludedUIDs {
+		fullQuery.AddMustNot(bluge.NewTermQuery(uid).SetField(documentFieldUID))
+	}
+
+	// Constrained set of dashboards
+	if q.DashboardUIDs != nil {
+		fullQuery.AddMust(newDashboardUIDsFilter(q.DashboardUIDs))
+		hasConstraints = true
+	}
+
 	// Tags
 	if len(q.Tags) > 0 {
 		bq := bluge.NewBooleanQuery()
@@ -408,6 +558,12 @@ func doSearchQuery(
 		hasConstraints = true
 	}
 
+	// Template variables
+	if len(q.Variables) > 0 {
+		fullQuery.AddMust(newVariablesFilter(q.Variables))
+		hasConstraints = true
+	}
+
 	// Panel type
 	if q.PanelType != "" {
 		fullQuery.AddMust(bluge.NewTermQuery(q.PanelType).SetField(documentFieldPanelType))
@@ -420,6 +576,58 @@ func doSearchQuery(
 		hasConstraints = true
 	}
 
+	// Datasources the user can't query
+	if q.DatasourceAccess == DatasourceAccessFilter {
+		for uid := range q.deniedDatasources {
+			fullQuery.AddMustNot(bluge.NewTermQuery(uid).SetField(documentFieldDSUID))
+		}
+	}
+
+	// Dashboards and folders owned by a team
+	if q.TeamID > 0 {
+		fullQuery.AddMust(bluge.NewTermQuery(teamTerm(q.TeamID)).SetField(documentFieldTeam))
+		hasConstraints = true
+	}
+
+	// Detected language
+	if q.Language != "" {
+		fullQuery.AddMust(bluge.NewTermQuery(q.Language).SetField(documentFieldLanguage))
+		hasConstraints = true
+	}
+
+	// Fields added by document enrichers
+	for name, values := range q.Custom {
+		if len(values) == 0 {
+			continue
+		}
+		customQuery := bluge.NewBooleanQuery()
+		for _, value := range values {
+			customQuery.AddShould(bluge.NewTermQuery(value).SetField(customFieldPrefix + name))
+		}
+		fullQuery.AddMust(customQuery)
+		hasConstraints = true
+	}
+
+	// Governance labels
+	if len(q.Governance) > 0 {
+		fullQuery.AddMust(newGovernanceFilter(q.Governance))
+		hasConstraints = true
+	}
+
+	// Provisioning
+	if q.Provisioned != nil {
+		fullQuery.AddMust(newProvisionedFilter(*q.Provisioned))
+		hasConstraints = true
+	}
+
+	// Maintenance report
+	if q.Report != "" {
+		fullQuery.AddMust(bluge.NewTermQuery(q.Report).SetField(documentFieldIntegrity))
+	}
+	if q.excludeIntegrityProblems {
+		fullQuery.AddMustNot(newIntegrityProblemsQuery())
+	}
+
 	// Folder
 	if q.Location != "" {
 		fullQuery.AddMust(bluge.NewTermQuery(q.Location).SetField(documentFieldLocation))
@@ -445,10 +653,37 @@ func doSearchQuery(
 				SetAnalyzer(ngramQueryAnalyzer).SetBoost(1))
 		}
 
+		if analyzer, ok := languageAnalyzers[q.Language]; ok {
+			bq.AddShould(bluge.NewMatchQuery(q.Query).
+				SetField(documentFieldName_lang).
+				SetOperator(bluge.MatchQueryOperatorAnd).
+				SetAnalyzer(analyzer).SetBoost(1))
+		}
+
+		bq.AddShould(newAliasQuery(q))
+
+		// dashboards similar in meaning match without keywords in common
+		if len(q.semanticBoosts) > 0 {
+			bq.AddShould(newSemanticQuery(q.semanticBoosts))
+		}
+
 		fullQuery.AddMust(bq)
 	}
 
-	limit := 50 // default view
+	if q.QualityBoost > 0 {
+		fullQuery.AddShould(newQualityBoostQuery(q.QualityBoost))
+	}
+	if len(q.GovernanceBoost) > 0 {
+		fullQuery.AddShould(newGovernanceBoostQuery(q.GovernanceBoost))
+	}
+	if q.scoring != nil {
+		fullQuery.AddShould(q.scoring.query(time.Now()))
+	}
+	if q.personalization != nil {
+		fullQuery.AddShould(q.personalization.query())
+	}
+
+	limit := defaultQueryLimit
 	if q.Limit > 0 {
 		limit = q.Limit
 	}
@@ -475,8 +710,15 @@ func doSearchQuery(
 		req.AddAggregation(t.Field, aggregations.NewTermsAggregation(search.Field(t.Field), lim))
 	}
 
+	var searchReq bluge.SearchRequest = req
+	var earlyTermination *earlyTerminationSearch
+	if canTerminateEarly(q, isMatchAllQuery) {
+		earlyTermination = newEarlyTerminationSearch(req)
+		searchReq = earlyTermination
+	}
+
 	// execute this search on the reader
-	documentMatchIterator, err := reader.Search(ctx, req)
+	documentMatchIterator, err := reader.Search(ctx, searchReq)
 	if err != nil {
 		logger.Error("error executing search", "err", err)
 		response.Error = err
@@ -493,6 +735,15 @@ func doSearchQuery(
 	fTags := data.NewFieldFromFieldType(data.FieldTypeNullableJSON, 0)
 	fDSUIDs := data.NewFieldFromFieldType(data.FieldTypeJSON, 0)
 	fExplain := data.NewFieldFromFieldType(data.FieldTypeNullableJSON, 0)
+	fDSDenied := data.NewFieldFromFieldType(data.FieldTypeJSON, 0)
+	fCanEdit := data.NewFieldFromFieldType(data.FieldTypeBool, 0)
+	fCanAdmin := data.NewFieldFromFieldType(data.FieldTypeBool, 0)
+	fCanStar := data.NewFieldFromFieldType(data.FieldTypeBool, 0)
+	fQuality := data.NewFieldFromFieldType(data.FieldTypeNullableInt64, 0)
+	fHighlight := data.NewFieldFromFieldType(data.FieldTypeNullableJSON, 0)
+	fGovernance := data.NewFieldFromFieldType(data.FieldTypeNullableString, 0)
+	fIndexedAt := data.NewFieldFromFieldType(data.FieldTypeNullableTime, 0)
+	fSourceUpdatedAt := data.NewFieldFromFieldType(data.FieldTypeNullableTime, 0)
 
 	fScore.Name = "score"
 	fUID.Name = "uid"
@@ -509,11 +760,32 @@ func doSearchQuery(
 	fDSUIDs.Name = "ds_uid"
 	fTags.Name = "tags"
 	fExplain.Name = "explain"
-
-	frame := data.NewFrame("Query results", fKind, fUID, fName, fPType, fURL, fTags, fDSUIDs, fLocation)
+	fDSDenied.Name = "ds_access_denied"
+	fCanEdit.Name = "can_edit"
+	fCanAdmin.Name = "can_admin"
+	fCanStar.Name = "can_star"
+	fQuality.Name = "quality_score"
+	fHighlight.Name = resultFieldHighlight
+	fGovernance.Name = resultFieldGovernance
+	fIndexedAt.Name = resultFieldIndexedAt
+	fSourceUpdatedAt.Name = resultFieldSourceUpdatedAt
+
+	selection := newResultFieldSelection(q)
+	frame := data.NewFrame("Query results")
+	for _, f := range []*data.Field{fKind, fUID, fName, fPType, fURL, fTags, fDSUIDs, fLocation, fQuality, fHighlight, fGovernance, fIndexedAt, fSourceUpdatedAt} {
+		if selection.includes(f.Name) {
+			frame.Fields = append(frame.Fields, f)
+		}
+	}
 	if q.Explain {
 		frame.Fields = append(frame.Fields, fScore, fExplain)
 	}
+	if q.DatasourceAccess == DatasourceAccessAnnotate {
+		frame.Fields = append(frame.Fields, fDSDenied)
+	}
+	if q.capabilities != nil {
+		frame.Fields = append(frame.Fields, fCanEdit, fCanAdmin, fCanStar)
+	}
 	frame.SetMeta(&data.FrameMeta{
 		Type:   "search-results",
 		Custom: header,
@@ -523,6 +795,10 @@ func doSearchQuery(
 	ext := extender.GetFramer(frame)
 
 	locationItems := make(map[string]bool, 50)
+	var highlightedTerms []string
+	if selection.includes(resultFieldHighlight) && !isMatchAllQuery {
+		highlightedTerms = queryTerms(q.Query)
+	}
 
 	// iterate through the document matches
 	match, err := documentMatchIterator.Next()
@@ -535,6 +811,10 @@ func doSearchQuery(
 		loc := ""
 		var dsUIDs []string
 		var tags []string
+		var aliases []string
+		var quality *int64
+		var governance *string
+		var indexedAt, sourceUpdatedAt *time.Time
 
 		err = match.VisitStoredFields(func(field string, value []byte) bool {
 			switch field {
@@ -547,13 +827,34 @@ func doSearchQuery(
 			case documentFieldName:
 				name = string(value)
 			case documentFieldURL:
-				url = appSubUrl + string(value)
+				if selection.includes(resultFieldURL) {
+					url = appSubUrl + string(value)
+				}
 			case documentFieldLocation:
 				loc = string(value)
 			case documentFieldDSUID:
 				dsUIDs = append(dsUIDs, string(value))
 			case documentFieldTag:
 				tags = append(tags, string(value))
+			case documentFieldAlias:
+				aliases = append(aliases, string(value))
+			case documentFieldQuality:
+				if score, err := bluge.DecodeNumericFloat64(value); err == nil {
+					v := int64(score)
+					quality = &v
+				}
+			case documentFieldGovernance:
+				label := string(value)
+				governance = &label
+			case documentFieldIndexedAt:
+				if t, err := bluge.DecodeDateTime(value); err == nil {
+					indexedAt = &t
+				}
+			case DocumentFieldUpdatedAt:
+				if t, err := bluge.DecodeDateTime(value); err == nil {
+					sourceUpdatedAt = &t
+				}
+				ext(field, value)
 			default:
 				ext(field, value)
 			}
@@ -565,35 +866,88 @@ func doSearchQuery(
 			return response
 		}
 
-		fKind.Append(kind)
-		fUID.Append(uid)
-		fPType.Append(ptype)
-		fName.Append(name)
-		fURL.Append(url)
-		fLocation.Append(loc)
+		if selection.includes(resultFieldKind) {
+			fKind.Append(kind)
+		}
+		if selection.includes(resultFieldUID) {
+			fUID.Append(uid)
+		}
+		if selection.includes(resultFieldPanelType) {
+			fPType.Append(ptype)
+		}
+		if selection.includes(resultFieldName) {
+			fName.Append(name)
+		}
+		if selection.includes(resultFieldURL) {
+			fURL.Append(url)
+		}
+		if selection.includes(resultFieldLocation) {
+			fLocation.Append(loc)
+
+			// set a key for all path parts we return
+			if !q.SkipLocation {
+				for _, v := range strings.Split(loc, "/") {
+					locationItems[v] = true
+				}
+			}
+		}
 
-		// set a key for all path parts we return
-		if !q.SkipLocation {
-			for _, v := range strings.Split(loc, "/") {
-				locationItems[v] = true
+		if selection.includes(resultFieldTags) {
+			if len(tags) > 0 {
+				js, _ := json.Marshal(tags)
+				jsb := json.RawMessage(js)
+				fTags.Append(&jsb)
+			} else {
+				fTags.Append(nil)
 			}
 		}
 
-		if len(tags) > 0 {
-			js, _ := json.Marshal(tags)
+		if selection.includes(resultFieldDSUID) {
+			if len(dsUIDs) == 0 {
+				dsUIDs = []string{}
+			}
+
+			js, _ := json.Marshal(dsUIDs)
 			jsb := json.RawMessage(js)
-			fTags.Append(&jsb)
-		} else {
-			fTags.Append(nil)
+			fDSUIDs.Append(jsb)
+		}
+
+		if selection.includes(resultFieldQuality) {
+			fQuality.Append(quality)
+		}
+
+		if selection.includes(resultFieldGovernance) {
+			fGovernance.Append(governance)
 		}
 
-		if len(dsUIDs) == 0 {
-			dsUIDs = []string{}
+		if selection.includes(resultFieldHighlight) {
+			fHighlight.Append(highlightResult(highlightedTerms, name, aliases))
 		}
 
-		js, _ := json.Marshal(dsUIDs)
-		jsb := json.RawMessage(js)
-		fDSUIDs.Append(jsb)
+		if selection.includes(resultFieldIndexedAt) {
+			fIndexedAt.Append(indexedAt)
+		}
+		if selection.includes(resultFieldSourceUpdatedAt) {
+			fSourceUpdatedAt.Append(sourceUpdatedAt)
+		}
+
+		if q.DatasourceAccess == DatasourceAccessAnnotate {
+			denied := make([]string, 0)
+			for _, uid := range dsUIDs {
+				if q.deniedDatasources[uid] {
+					denied = append(denied, uid)
+				}
+			}
+			js, _ := json.Marshal(denied)
+			fDSDenied.Append(json.RawMessage(js))
+		}
+
+		if q.capabilities != nil {
+			capabilities := resultCapabilitiesOf(q.capabilities, kind, uid, loc)
+			fCanEdit.Append(capabilities.canEdit)
+			fCanAdmin.Append(capabilities.canAdmin)
+			fCanStar.Append(capabilities.canStar)
+		}
 
 		if q.Explain {
 			if isMatchAllQuery {
@@ -626,6 +980,7 @@ func doSearchQuery(
 	aggs := documentMatchIterator.Aggregations()
 
 	header.Count = aggs.Count() // Total count
+	header.CountIsLowerBound = earlyTermination != nil && earlyTermination.terminated()
 	if q.Explain {
 		header.MaxScore = aggs.Metric("max_score")
 	}
@@ -656,6 +1011,16 @@ func doSearchQuery(
 		}
 	}
 
+	// few results may come from typos, offer corrections of the query
+	if !isMatchAllQuery && q.From == 0 && header.Count < suggestBelowCount {
+		suggestions, err := suggestQuery(ctx, reader, access, q.Query)
+		if err != nil {
+			logger.Warn("error computing search suggestions", "err", err)
+		} else if len(suggestions) > 0 {
+			response.Frames = append(response.Frames, suggestionsFrame(suggestions))
+		}
+	}
+
 	return response
 }
 
@@ -737,8 +1102,30 @@ type locationItem struct {
 }
 
 type customMeta struct {
-	Count     uint64                  `json:"count"`
-	MaxScore  float64                 `json:"max_score,omitempty"`
-	Locations map[string]locationItem `json:"locationInfo,omitempty"`
-	SortBy    string                  `json:"sortBy,omitempty"`
+	Count uint64 `json:"count"`
+	// more documents match than counted, when the search stopped once it had the results, see
+	// ExperimentTopK
+	CountIsLowerBound bool                    `json:"countIsLowerBound,omitempty"`
+	MaxScore          float64                 `json:"max_score,omitempty"`
+	Locations         map[string]locationItem `json:"locationInfo,omitempty"`
+	SortBy            string                  `json:"sortBy,omitempty"`
+	Debug             *searchDebugInfo        `json:"debug,omitempty"`
+	// federated instances which failed to answer, by name
+	FederationErrors map[string]string `json:"federationErrors,omitempty"`
+	// corrections of the query when it has few results, only set by the HTTP API
+	Suggestions []string `json:"suggestions,omitempty"`
+	// generation and last change of the index which answered the query, to correlate odd results
+	// with a build of the index
+	IndexGeneration int64      `json:"indexGeneration,omitempty"`
+	IndexUpdated    *time.Time `json:"indexUpdated,omitempty"`
+	// the index is corrupted and being rebuilt, results may be wrong or incomplete
+	Degraded bool `json:"degraded,omitempty"`
+	// the results may be out of date, StaleReason tells why. The indexed_at and source_updated_at
+	// result fields tell which results are older than their dashboard
+	Stale       bool   `json:"stale,omitempty"`
+	StaleReason string `json:"staleReason,omitempty"`
+	// the version of the query API the response follows, and the deprecated parts of the API the
+	// query used. Only set by the HTTP API
+	APIVersion int      `json:"apiVersion,omitempty"`
+	Warnings   []string `json:"warnings,omitempty"`
 }
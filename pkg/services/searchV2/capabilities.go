@@ -0,0 +1,98 @@
+package searchV2
+
+import (
+	"strings"
+
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+// resultCapabilities tells list UIs which actions to offer for a result. They are hints only,
+// the actions are still authorized when performed.
+type resultCapabilities struct {
+	canEdit  bool
+	canAdmin bool
+	canStar  bool
+}
+
+// capabilityChecker returns the capabilities of the user for a dashboard or folder, folderUID
+// being the folder of a dashboard.
+type capabilityChecker func(kind entityKind, uid string, folderUID string) resultCapabilities
+
+// resultCapabilitiesOf returns the capabilities for a result, panels have the capabilities of
+// their dashboard except starring.
+func resultCapabilitiesOf(check capabilityChecker, kind string, uid string, location string) resultCapabilities {
+	switch entityKind(kind) {
+	case entityKindFolder:
+		return check(entityKindFolder, uid, "")
+	case entityKindDashboard:
+		return check(entityKindDashboard, uid, location)
+	case entityKindPanel:
+		// the location of a panel is "folder/dashboard", or "dashboard" in the general folder
+		folderUID, dashboardUID := "", location
+		if i := strings.LastIndex(location, "/"); i >= 0 {
+			folderUID, dashboardUID = location[:i], location[i+1:]
+		}
+		capabilities := check(entityKindDashboard, dashboardUID, folderUID)
+		capabilities.canStar = false
+		return capabilities
+	}
+	return resultCapabilities{}
+}
+
+// newPermissionsCapabilityChecker evaluates the access control permissions of the user, dashboards
+// inheriting the permissions granted on their folder.
+func newPermissionsCapabilityChecker(signedInUser *user.SignedInUser) capabilityChecker {
+	var permissions map[string][]string
+	if signedInUser.Permissions != nil {
+		permissions = signedInUser.Permissions[signedInUser.OrgID]
+	}
+	canStar := canStarDashboards(signedInUser)
+
+	return func(kind entityKind, uid string, folderUID string) resultCapabilities {
+		if kind == entityKindFolder {
+			scope := dashboards.ScopeFoldersProvider.GetResourceScopeUID(uid)
+			return resultCapabilities{
+				canEdit:  accesscontrol.EvalPermission(dashboards.ActionFoldersWrite, scope).Evaluate(permissions),
+				canAdmin: accesscontrol.EvalPermission(dashboards.ActionFoldersPermissionsWrite, scope).Evaluate(permissions),
+			}
+		}
+
+		scopes := []string{dashboards.ScopeDashboardsProvider.GetResourceScopeUID(uid)}
+		if folderUID != "" && folderUID != "general" {
+			scopes = append(scopes, dashboards.ScopeFoldersProvider.GetResourceScopeUID(folderUID))
+		}
+		hasAction := func(action string) bool {
+			for _, scope := range scopes {
+				if accesscontrol.EvalPermission(action, scope).Evaluate(permissions) {
+					return true
+				}
+			}
+			return false
+		}
+		return resultCapabilities{
+			canEdit:  hasAction(dashboards.ActionDashboardsWrite),
+			canAdmin: hasAction(dashboards.ActionDashboardsPermissionsWrite),
+			canStar:  canStar,
+		}
+	}
+}
+
+// newRoleCapabilityChecker approximates the capabilities from the organization role, for auth
+// services which can't tell them.
+func newRoleCapabilityChecker(signedInUser *user.SignedInUser) capabilityChecker {
+	canStar := canStarDashboards(signedInUser)
+	return func(kind entityKind, uid string, folderUID string) resultCapabilities {
+		return resultCapabilities{
+			canEdit:  signedInUser.HasRole(org.RoleEditor),
+			canAdmin: signedInUser.HasRole(org.RoleAdmin),
+			canStar:  canStar && kind == entityKindDashboard,
+		}
+	}
+}
+
+func canStarDashboards(signedInUser *user.SignedInUser) bool {
+	return signedInUser.UserID > 0 && !signedInUser.IsAnonymous
+}
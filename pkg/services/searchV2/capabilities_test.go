@@ -0,0 +1,94 @@
+package searchV2
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/searchV2/extract"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+var testCapabilitiesDashboards = []dashboard{
+	{
+		id:       1,
+		uid:      "folder",
+		isFolder: true,
+		info:     &extract.DashboardInfo{Title: "folder"},
+	},
+	{
+		id:        2,
+		uid:       "in-folder",
+		folderID:  1,
+		folderUID: "folder",
+		info: &extract.DashboardInfo{
+			Title:  "in folder",
+			Panels: []extract.PanelInfo{{ID: 1, Title: "panel"}},
+		},
+	},
+	{
+		id:   3,
+		uid:  "general",
+		info: &extract.DashboardInfo{Title: "in general folder"},
+	},
+}
+
+func TestResultCapabilities(t *testing.T) {
+	index := initTestOrgIndexFromDashes(t, testCapabilitiesDashboards)
+
+	search := func(t *testing.T, checker capabilityChecker) map[string][3]bool {
+		t.Helper()
+		q := DashboardQuery{capabilities: checker}
+		resp := doSearchQuery(context.Background(), testLogger, index, testAllowAllFilter, q, &NoopQueryExtender{}, "")
+		require.NoError(t, resp.Error)
+
+		frame := resp.Frames[0]
+		uidField, _ := frame.FieldByName("uid")
+		canEdit, _ := frame.FieldByName("can_edit")
+		canAdmin, _ := frame.FieldByName("can_admin")
+		canStar, _ := frame.FieldByName("can_star")
+		results := make(map[string][3]bool, uidField.Len())
+		for i := 0; i < uidField.Len(); i++ {
+			results[uidField.At(i).(string)] = [3]bool{canEdit.At(i).(bool), canAdmin.At(i).(bool), canStar.At(i).(bool)}
+		}
+		return results
+	}
+
+	t.Run("no capability fields by default", func(t *testing.T) {
+		resp := doSearchQuery(context.Background(), testLogger, index, testAllowAllFilter, DashboardQuery{}, &NoopQueryExtender{}, "")
+		require.NoError(t, resp.Error)
+		field, _ := resp.Frames[0].FieldByName("can_edit")
+		require.Nil(t, field)
+	})
+
+	t.Run("dashboards and panels inherit folder permissions", func(t *testing.T) {
+		signedInUser := &user.SignedInUser{UserID: 1, OrgID: 1, Permissions: map[int64]map[string][]string{1: {
+			dashboards.ActionDashboardsWrite:            {"folders:uid:folder"},
+			dashboards.ActionDashboardsPermissionsWrite: {"dashboards:uid:general"},
+			dashboards.ActionFoldersPermissionsWrite:    {"folders:*"},
+		}}}
+
+		results := search(t, newPermissionsCapabilityChecker(signedInUser))
+		require.Equal(t, map[string][3]bool{
+			"folder":      {false, true, false},
+			"in-folder":   {true, false, true},
+			"in-folder#1": {true, false, false},
+			"general":     {false, true, true},
+		}, results)
+	})
+
+	t.Run("anonymous users can't star", func(t *testing.T) {
+		signedInUser := &user.SignedInUser{OrgID: 1, OrgRole: org.RoleEditor, IsAnonymous: true}
+
+		results := search(t, newRoleCapabilityChecker(signedInUser))
+		require.Equal(t, map[string][3]bool{
+			"folder":      {true, false, false},
+			"in-folder":   {true, false, false},
+			"in-folder#1": {true, false, false},
+			"general":     {true, false, false},
+		}, results)
+	})
+}
@@ -0,0 +1,152 @@
+package searchV2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const (
+	coalesceOutcomeShared  = "shared"   // answered with the response of the identical query in flight
+	coalesceOutcomeOverCap = "over_cap" // executed on its own as the query in flight had too many waiters
+	coalesceOutcomeRetried = "retried"  // executed on its own as the query in flight was canceled
+)
+
+var dashboardSearchCoalescedRequestsCounter = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "dashboard_search_coalesced_requests_total",
+		Help:      "A counter for dashboard search queries identical to a query in flight, by outcome",
+	},
+	[]string{"outcome"},
+)
+
+// queryCoalescer executes identical queries received at once only once, the queries received while
+// the first one is executed wait for it and share its response. Up to maxWaiters queries wait for
+// each query in flight, the next ones are executed on their own.
+type queryCoalescer struct {
+	maxWaiters int
+
+	mu      sync.Mutex
+	flights map[string]*queryFlight
+}
+
+type queryFlight struct {
+	done    chan struct{}
+	waiters int
+	rsp     *backend.DataResponse
+}
+
+// newQueryCoalescer returns nil when coalescing is disabled.
+func newQueryCoalescer(settings setting.SearchSettings) *queryCoalescer {
+	if settings.MaxCoalescedQueries <= 0 {
+		return nil
+	}
+	return &queryCoalescer{
+		maxWaiters: settings.MaxCoalescedQueries,
+		flights:    map[string]*queryFlight{},
+	}
+}
+
+// coalescingKey identifies the queries which return the same response. The results depend on the
+// permissions of the user: the queries of users who can read the same dashboards and folders are
+// identical when the auth service lists them, e.g. the home page queries of the users of an
+// organization. Queries whose results also depend on the user, e.g. on their starred dashboards or
+// datasource permissions, are only identical to the queries of the same user. Debug queries report
+// their own execution and federated queries are merged with the results of remote instances, they
+// are never coalesced.
+func (s *StandardSearchService) coalescingKey(orgID int64, usr *user.SignedInUser, q DashboardQuery) (string, bool) {
+	if s.coalescer == nil || q.Debug || q.Federated {
+		return "", false
+	}
+	data, err := json.Marshal(q)
+	if err != nil {
+		return "", false
+	}
+
+	userKey := fmt.Sprintf("user/%d/%d/%t/%s", usr.UserID, usr.ApiKeyID, usr.IsAnonymous, usr.OrgRole)
+	auth, ok := s.auth.(readableUIDsAuthService)
+	if !ok || s.deniedCache == nil || s.dependsOnUser(orgID, usr, q) {
+		return fmt.Sprintf("%d/%s/%s", orgID, userKey, data), true
+	}
+	// the list is cached, the query loads it again from the cache
+	readable, err := s.deniedCache.readable(usr, func() (map[string]bool, error) {
+		return auth.GetDashboardReadUIDs(usr)
+	})
+	if err != nil {
+		return fmt.Sprintf("%d/%s/%s", orgID, userKey, data), true
+	}
+	return fmt.Sprintf("%d/readable/%x/%s", orgID, readable.fingerprint, data), true
+}
+
+// dependsOnUser tells whether the results of the query depend on the user beyond the dashboards and
+// folders they can read.
+func (s *StandardSearchService) dependsOnUser(orgID int64, usr *user.SignedInUser, q DashboardQuery) bool {
+	if q.PreviewAs != nil || q.DatasourceAccess != "" || q.WithCapabilities || q.WithAllowedActions {
+		return true
+	}
+	for _, kind := range q.Kind {
+		if isVirtualKind(kind) {
+			return true
+		}
+	}
+	return q.Sort == "" && usr.UserID > 0 && s.personalization.boosts(personalizationKey{orgID: orgID, userID: usr.UserID}) != nil
+}
+
+// do returns the response of the query with the given key in flight, or executes it. Waiting
+// queries return when their context is done, and execute the query themselves when the query in
+// flight failed because its own context was done.
+func (c *queryCoalescer) do(ctx context.Context, key string, query func() *backend.DataResponse) *backend.DataResponse {
+	if c == nil {
+		return query()
+	}
+
+	c.mu.Lock()
+	if flight, ok := c.flights[key]; ok {
+		if flight.waiters >= c.maxWaiters {
+			c.mu.Unlock()
+			dashboardSearchCoalescedRequestsCounter.WithLabelValues(coalesceOutcomeOverCap).Inc()
+			return query()
+		}
+		flight.waiters++
+		c.mu.Unlock()
+
+		select {
+		case <-flight.done:
+		case <-ctx.Done():
+			return &backend.DataResponse{Error: ctx.Err()}
+		}
+		if errors.Is(flight.rsp.Error, context.Canceled) || errors.Is(flight.rsp.Error, context.DeadlineExceeded) {
+			dashboardSearchCoalescedRequestsCounter.WithLabelValues(coalesceOutcomeRetried).Inc()
+			return query()
+		}
+		dashboardSearchCoalescedRequestsCounter.WithLabelValues(coalesceOutcomeShared).Inc()
+		return flight.rsp
+	}
+	flight := &queryFlight{done: make(chan struct{})}
+	c.flights[key] = flight
+	c.mu.Unlock()
+
+	defer func() {
+		if flight.rsp == nil {
+			// the query panicked
+			flight.rsp = &backend.DataResponse{Error: errors.New("search query failed")}
+		}
+		c.mu.Lock()
+		delete(c.flights, key)
+		c.mu.Unlock()
+		close(flight.done)
+	}()
+	flight.rsp = query()
+	return flight.rsp
+}
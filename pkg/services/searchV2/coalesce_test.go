@@ -0,0 +1,201 @@
+package searchV2
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// blockingQuery returns a query counting its executions which blocks until release is closed,
+// started is signaled when it starts.
+func blockingQuery(executions *int32, started chan struct{}, release chan struct{}, rsp *backend.DataResponse) func() *backend.DataResponse {
+	return func() *backend.DataResponse {
+		atomic.AddInt32(executions, 1)
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		return rsp
+	}
+}
+
+// waitForWaiters waits until the query in flight with the key has n waiters.
+func waitForWaiters(t *testing.T, c *queryCoalescer, key string, n int) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		flight, ok := c.flights[key]
+		return ok && flight.waiters == n
+	}, time.Second, time.Millisecond)
+}
+
+func TestQueryCoalescer(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("disabled", func(t *testing.T) {
+		require.Nil(t, newQueryCoalescer(setting.SearchSettings{}))
+		var c *queryCoalescer
+		rsp := &backend.DataResponse{}
+		require.Same(t, rsp, c.do(ctx, "key", func() *backend.DataResponse { return rsp }))
+	})
+
+	t.Run("identical queries in flight are executed once", func(t *testing.T) {
+		c := newQueryCoalescer(setting.SearchSettings{MaxCoalescedQueries: 10})
+		var executions int32
+		started, release := make(chan struct{}, 1), make(chan struct{})
+		rsp := &backend.DataResponse{}
+		query := blockingQuery(&executions, started, release, rsp)
+
+		var wg sync.WaitGroup
+		responses := make([]*backend.DataResponse, 4)
+		wg.Add(1)
+		go func() { defer wg.Done(); responses[0] = c.do(ctx, "key", query) }()
+		<-started
+		for i := 1; i < len(responses); i++ {
+			wg.Add(1)
+			go func(i int) { defer wg.Done(); responses[i] = c.do(ctx, "key", query) }(i)
+		}
+		waitForWaiters(t, c, "key", len(responses)-1)
+		close(release)
+		wg.Wait()
+
+		require.Equal(t, int32(1), executions)
+		for _, response := range responses {
+			require.Same(t, rsp, response)
+		}
+		require.Empty(t, c.flights)
+	})
+
+	t.Run("queries over the cap are executed on their own", func(t *testing.T) {
+		c := newQueryCoalescer(setting.SearchSettings{MaxCoalescedQueries: 1})
+		var executions int32
+		started, release := make(chan struct{}, 1), make(chan struct{})
+		query := blockingQuery(&executions, started, release, &backend.DataResponse{})
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() { defer wg.Done(); c.do(ctx, "key", query) }()
+		<-started
+		go func() { defer wg.Done(); c.do(ctx, "key", query) }()
+		waitForWaiters(t, c, "key", 1)
+
+		done := make(chan struct{})
+		go func() { c.do(ctx, "key", func() *backend.DataResponse { return &backend.DataResponse{} }); close(done) }()
+		<-done
+		close(release)
+		wg.Wait()
+		require.Equal(t, int32(1), executions)
+	})
+
+	t.Run("waiting queries retry when the query in flight is canceled", func(t *testing.T) {
+		c := newQueryCoalescer(setting.SearchSettings{MaxCoalescedQueries: 10})
+		var executions int32
+		started, release := make(chan struct{}, 1), make(chan struct{})
+		canceled := blockingQuery(&executions, started, release, &backend.DataResponse{Error: context.Canceled})
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() { defer wg.Done(); c.do(ctx, "key", canceled) }()
+		<-started
+		var rsp *backend.DataResponse
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rsp = c.do(ctx, "key", func() *backend.DataResponse { return &backend.DataResponse{} })
+		}()
+		waitForWaiters(t, c, "key", 1)
+		close(release)
+		wg.Wait()
+		require.NoError(t, rsp.Error)
+	})
+
+	t.Run("waiting queries return when their context is done", func(t *testing.T) {
+		c := newQueryCoalescer(setting.SearchSettings{MaxCoalescedQueries: 10})
+		var executions int32
+		started, release := make(chan struct{}, 1), make(chan struct{})
+		defer close(release)
+		go c.do(ctx, "key", blockingQuery(&executions, started, release, &backend.DataResponse{}))
+		<-started
+
+		waitCtx, cancel := context.WithCancel(ctx)
+		cancel()
+		rsp := c.do(waitCtx, "key", func() *backend.DataResponse { return &backend.DataResponse{} })
+		require.ErrorIs(t, rsp.Error, context.Canceled)
+	})
+}
+
+// readableUIDsTestAuth lists the dashboards each user can read.
+type readableUIDsTestAuth map[int64]map[string]bool
+
+func (a readableUIDsTestAuth) GetDashboardReadFilter(usr *user.SignedInUser) (ResourceFilter, error) {
+	return func(uid string) bool { return a[usr.UserID][uid] }, nil
+}
+
+func (a readableUIDsTestAuth) GetDashboardReadUIDs(usr *user.SignedInUser) (map[string]bool, error) {
+	return a[usr.UserID], nil
+}
+
+func TestCoalescingKey(t *testing.T) {
+	s := &StandardSearchService{
+		auth: readableUIDsTestAuth{
+			1: {"a": true, "b": true},
+			2: {"b": true, "a": true},
+			3: {"a": true},
+		},
+		coalescer:   newQueryCoalescer(setting.SearchSettings{MaxCoalescedQueries: 10}),
+		deniedCache: newDeniedCache(10 * time.Second),
+	}
+	viewer := &user.SignedInUser{OrgID: 1, UserID: 1, OrgRole: org.RoleViewer}
+	sameDashboards := &user.SignedInUser{OrgID: 1, UserID: 2, OrgRole: org.RoleViewer}
+	otherDashboards := &user.SignedInUser{OrgID: 1, UserID: 3, OrgRole: org.RoleViewer}
+	q := DashboardQuery{Query: "latency", Tags: []string{"prod"}}
+
+	key, ok := s.coalescingKey(1, viewer, q)
+	require.True(t, ok)
+	other, _ := s.coalescingKey(1, viewer, DashboardQuery{Query: "latency", Tags: []string{"prod"}})
+	require.Equal(t, key, other)
+
+	other, _ = s.coalescingKey(1, sameDashboards, q)
+	require.Equal(t, key, other, "users who can read the same dashboards share results")
+	other, _ = s.coalescingKey(1, otherDashboards, q)
+	require.NotEqual(t, key, other, "results depend on the permissions of the user")
+	other, _ = s.coalescingKey(2, viewer, q)
+	require.NotEqual(t, key, other)
+	other, _ = s.coalescingKey(1, viewer, DashboardQuery{Query: "latency"})
+	require.NotEqual(t, key, other)
+
+	for name, q := range map[string]DashboardQuery{
+		"capabilities":        {Query: "latency", WithCapabilities: true},
+		"allowed actions":     {Query: "latency", WithAllowedActions: true},
+		"datasources":         {Query: "latency", DatasourceAccess: DatasourceAccessFilter},
+		"starred":             {Query: "latency", Kind: []string{virtualKindStarred}},
+		"permissions preview": {Query: "latency", PreviewAs: &PermissionsPreview{UserID: 3}},
+	} {
+		key, _ := s.coalescingKey(1, viewer, q)
+		other, _ := s.coalescingKey(1, sameDashboards, q)
+		require.NotEqual(t, key, other, name)
+	}
+
+	_, ok = s.coalescingKey(1, viewer, DashboardQuery{Query: "latency", Debug: true})
+	require.False(t, ok)
+	_, ok = s.coalescingKey(1, viewer, DashboardQuery{Query: "latency", Federated: true})
+	require.False(t, ok)
+
+	perUser := &StandardSearchService{auth: readableUIDsTestAuth{}, coalescer: s.coalescer}
+	key, _ = perUser.coalescingKey(1, viewer, q)
+	other, _ = perUser.coalescingKey(1, sameDashboards, q)
+	require.NotEqual(t, key, other, "the list of readable dashboards isn't loaded again when it isn't cached")
+	_, ok = (&StandardSearchService{}).coalescingKey(1, viewer, q)
+	require.False(t, ok, "coalescing is disabled")
+}
@@ -0,0 +1,80 @@
+package searchV2
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+// Values of DashboardQuery.DatasourceAccess
+const (
+	// DatasourceAccessFilter removes dashboards and panels referencing datasources the user can't query.
+	DatasourceAccessFilter = "filter"
+	// DatasourceAccessAnnotate lists the datasources the user can't query for every result.
+	DatasourceAccessAnnotate = "annotate"
+)
+
+func validateDatasourceAccess(mode string) error {
+	switch mode {
+	case "", DatasourceAccessFilter, DatasourceAccessAnnotate:
+		return nil
+	}
+	return fmt.Errorf("invalid datasource access mode: %s", mode)
+}
+
+// getIndexedDatasourceUIDs returns the UIDs of all datasources referenced by dashboards and panels in the index.
+func getIndexedDatasourceUIDs(index *orgIndex) ([]string, error) {
+	reader, cancel, err := index.readerForIndex(indexTypeDashboard)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	dict, err := reader.DictionaryIterator(documentFieldDSUID, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = dict.Close() }()
+
+	var uids []string
+	entry, err := dict.Next()
+	for err == nil && entry != nil {
+		uids = append(uids, entry.Term())
+		entry, err = dict.Next()
+	}
+	return uids, err
+}
+
+// getDeniedDatasources returns the datasources referenced in the index that the user is not allowed to query.
+// Nothing is denied when access control is disabled, as datasource permissions are not enforced in that case.
+func (s *StandardSearchService) getDeniedDatasources(ctx context.Context, signedInUser *user.SignedInUser, orgID int64, index *orgIndex) (map[string]bool, error) {
+	denied := make(map[string]bool)
+	if s.ac.IsDisabled() {
+		return denied, nil
+	}
+
+	uids, err := getIndexedDatasourceUIDs(index)
+	if err != nil {
+		return nil, err
+	}
+
+	var permissions map[string][]string
+	if signedInUser.Permissions != nil {
+		permissions = signedInUser.Permissions[orgID]
+	}
+
+	resources := make(map[string]bool, len(uids))
+	for _, uid := range uids {
+		resources[uid] = true
+	}
+	metadata := accesscontrol.GetResourcesMetadata(ctx, permissions, datasources.ScopePrefix, resources)
+	for _, uid := range uids {
+		if !metadata[uid][datasources.ActionQuery] {
+			denied[uid] = true
+		}
+	}
+	return denied, nil
+}
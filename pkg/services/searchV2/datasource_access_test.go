@@ -0,0 +1,107 @@
+package searchV2
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	accesscontrolmock "github.com/grafana/grafana/pkg/services/accesscontrol/mock"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/searchV2/dslookup"
+	"github.com/grafana/grafana/pkg/services/searchV2/extract"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+var testDatasourceDashboards = []dashboard{
+	{
+		id:  1,
+		uid: "1",
+		info: &extract.DashboardInfo{
+			Title:      "allowed",
+			Datasource: []dslookup.DataSourceRef{{UID: "ds-allowed"}},
+			Panels: []extract.PanelInfo{
+				{ID: 1, Title: "allowed panel", Datasource: []dslookup.DataSourceRef{{UID: "ds-allowed"}}},
+			},
+		},
+	},
+	{
+		id:  2,
+		uid: "2",
+		info: &extract.DashboardInfo{
+			Title:      "mixed",
+			Datasource: []dslookup.DataSourceRef{{UID: "ds-allowed"}, {UID: "ds-denied"}},
+			Panels: []extract.PanelInfo{
+				{ID: 1, Title: "allowed panel", Datasource: []dslookup.DataSourceRef{{UID: "ds-allowed"}}},
+				{ID: 2, Title: "denied panel", Datasource: []dslookup.DataSourceRef{{UID: "ds-denied"}}},
+			},
+		},
+	},
+}
+
+func TestDatasourceAccess(t *testing.T) {
+	index := initTestOrgIndexFromDashes(t, testDatasourceDashboards)
+	denied := map[string]bool{"ds-denied": true}
+
+	search := func(t *testing.T, q DashboardQuery) map[string]string {
+		t.Helper()
+		q.deniedDatasources = denied
+		resp := doSearchQuery(context.Background(), testLogger, index, testAllowAllFilter, q, &NoopQueryExtender{}, "/pfix")
+		require.NoError(t, resp.Error)
+
+		uidField, _ := resp.Frames[0].FieldByName("uid")
+		deniedField, _ := resp.Frames[0].FieldByName("ds_access_denied")
+		results := make(map[string]string, uidField.Len())
+		for i := 0; i < uidField.Len(); i++ {
+			value := ""
+			if deniedField != nil {
+				value = string(deniedField.At(i).(json.RawMessage))
+			}
+			results[uidField.At(i).(string)] = value
+		}
+		return results
+	}
+
+	t.Run("indexed datasources", func(t *testing.T) {
+		uids, err := getIndexedDatasourceUIDs(index)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"ds-allowed", "ds-denied"}, uids)
+	})
+
+	t.Run("no enforcement by default", func(t *testing.T) {
+		results := search(t, DashboardQuery{})
+		require.Len(t, results, 5)
+	})
+
+	t.Run("filter removes dashboards and panels using denied datasources", func(t *testing.T) {
+		results := search(t, DashboardQuery{DatasourceAccess: DatasourceAccessFilter})
+		require.Equal(t, map[string]string{"1": "", "1#1": "", "2#1": ""}, results)
+	})
+
+	t.Run("annotate lists denied datasources", func(t *testing.T) {
+		results := search(t, DashboardQuery{DatasourceAccess: DatasourceAccessAnnotate, Kind: []string{string(entityKindDashboard)}})
+		require.Equal(t, map[string]string{"1": "[]", "2": `["ds-denied"]`}, results)
+	})
+
+	t.Run("denied datasources are resolved from query permissions", func(t *testing.T) {
+		s := &StandardSearchService{ac: accesscontrolmock.New()}
+		usr := &user.SignedInUser{
+			OrgID: testOrgID,
+			Permissions: map[int64]map[string][]string{
+				testOrgID: {
+					datasources.ActionQuery: {datasources.ScopeProvider.GetResourceScopeUID("ds-allowed")},
+					datasources.ActionRead:  {datasources.ScopeAll},
+				},
+			},
+		}
+		res, err := s.getDeniedDatasources(context.Background(), usr, testOrgID, index)
+		require.NoError(t, err)
+		require.Equal(t, denied, res)
+
+		s.ac = accesscontrolmock.New().WithDisabled()
+		res, err = s.getDeniedDatasources(context.Background(), usr, testOrgID, index)
+		require.NoError(t, err)
+		require.Empty(t, res)
+	})
+}
@@ -0,0 +1,230 @@
+package searchV2
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+var dashboardSearchDeniedCacheLookups = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "dashboard_search_denied_cache_lookups_total",
+		Help:      "A counter for permission checks of search results answered by the cache of denied dashboards and folders (hit) or evaluated (miss)",
+	},
+	[]string{"result"},
+)
+
+var dashboardSearchReadableCacheLookups = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "dashboard_search_readable_cache_lookups_total",
+		Help:      "A counter for lists of the dashboards and folders a user can read answered by the cache (hit) or loaded (miss)",
+	},
+	[]string{"result"},
+)
+
+// deniedCache remembers for a short while the dashboards and folders users were denied read access
+// to, or all those they can read when the auth service lists them, so that the queries users type
+// keystroke by keystroke don't evaluate the same permissions again.
+//
+// Entries are keyed by the organization and a fingerprint of the role, teams and permissions of the
+// user, so that changes to them are never served stale permissions. The entries of an organization
+// are dropped when its dashboards or folders change, as moving them changes inherited permissions.
+// No event tells of other changes, e.g. of legacy dashboard or folder permissions, or of the
+// permissions of a team or role which don't show in the fingerprint until the permissions of the
+// user are reloaded: the TTL is the only bound on how long they are served stale.
+type deniedCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[deniedCacheKey]*deniedEntry
+	swept   time.Time
+}
+
+type deniedCacheKey struct {
+	orgID       int64
+	fingerprint uint64
+}
+
+type deniedEntry struct {
+	expires time.Time
+
+	mu   sync.RWMutex
+	uids map[string]struct{}
+	// nil until loaded
+	readable *readableSet
+}
+
+// readableSet is the list of the dashboards and folders a user can read. It is shared by queries
+// and must not be modified.
+type readableSet struct {
+	uids map[string]bool
+	// fingerprint identifies the list, users with the same list share it
+	fingerprint uint64
+}
+
+func newReadableSet(uids map[string]bool) *readableSet {
+	sorted := make([]string, 0, len(uids))
+	for uid, readable := range uids {
+		if readable {
+			sorted = append(sorted, uid)
+		}
+	}
+	sort.Strings(sorted)
+	h := fnv.New64a()
+	for _, uid := range sorted {
+		_, _ = h.Write([]byte(uid))
+		_, _ = h.Write([]byte{0})
+	}
+	if uids == nil {
+		uids = map[string]bool{}
+	}
+	return &readableSet{uids: uids, fingerprint: h.Sum64()}
+}
+
+// newDeniedCache returns nil, which caches nothing, when ttl isn't positive.
+func newDeniedCache(ttl time.Duration) *deniedCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &deniedCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[deniedCacheKey]*deniedEntry),
+	}
+}
+
+// wrap returns a filter answering from the denials cached for the user and caching new ones.
+func (c *deniedCache) wrap(signedInUser *user.SignedInUser, filter ResourceFilter) ResourceFilter {
+	if c == nil {
+		return filter
+	}
+
+	entry := c.entry(deniedCacheKey{orgID: signedInUser.OrgID, fingerprint: permissionsFingerprint(signedInUser)})
+	return func(uid string) bool {
+		entry.mu.RLock()
+		_, denied := entry.uids[uid]
+		entry.mu.RUnlock()
+		if denied {
+			dashboardSearchDeniedCacheLookups.WithLabelValues("hit").Inc()
+			return false
+		}
+
+		dashboardSearchDeniedCacheLookups.WithLabelValues("miss").Inc()
+		if filter(uid) {
+			return true
+		}
+		entry.mu.Lock()
+		entry.uids[uid] = struct{}{}
+		entry.mu.Unlock()
+		return false
+	}
+}
+
+// readable returns the dashboards and folders the user can read, loading them with load once per
+// entry. Errors aren't cached.
+func (c *deniedCache) readable(signedInUser *user.SignedInUser, load func() (map[string]bool, error)) (*readableSet, error) {
+	if c == nil {
+		uids, err := load()
+		if err != nil {
+			return nil, err
+		}
+		return newReadableSet(uids), nil
+	}
+
+	entry := c.entry(deniedCacheKey{orgID: signedInUser.OrgID, fingerprint: permissionsFingerprint(signedInUser)})
+	entry.mu.RLock()
+	readable := entry.readable
+	entry.mu.RUnlock()
+	if readable != nil {
+		dashboardSearchReadableCacheLookups.WithLabelValues("hit").Inc()
+		return readable, nil
+	}
+
+	// concurrent queries of the user wait for the first to load the list
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if entry.readable != nil {
+		dashboardSearchReadableCacheLookups.WithLabelValues("hit").Inc()
+		return entry.readable, nil
+	}
+	dashboardSearchReadableCacheLookups.WithLabelValues("miss").Inc()
+	uids, err := load()
+	if err != nil {
+		return nil, err
+	}
+	entry.readable = newReadableSet(uids)
+	return entry.readable, nil
+}
+
+func (c *deniedCache) entry(key deniedCacheKey) *deniedEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if now.Sub(c.swept) > c.ttl {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		c.swept = now
+	}
+
+	entry, ok := c.entries[key]
+	if !ok || !now.Before(entry.expires) {
+		entry = &deniedEntry{expires: now.Add(c.ttl), uids: make(map[string]struct{})}
+		c.entries[key] = entry
+	}
+	return entry
+}
+
+// invalidateOrg drops the permissions cached for the users of an organization. It is called when the
+// dashboards or folders of the organization change, not when permissions do, see deniedCache.
+func (c *deniedCache) invalidateOrg(orgID int64) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k := range c.entries {
+		if k.orgID == orgID {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// permissionsFingerprint hashes what the dashboards and folders a user can read depend on.
+func permissionsFingerprint(signedInUser *user.SignedInUser) uint64 {
+	h := fnv.New64a()
+	_, _ = fmt.Fprintf(h, "%d/%d/%s/%t/%t/", signedInUser.UserID, signedInUser.ApiKeyID, signedInUser.OrgRole, signedInUser.IsGrafanaAdmin, signedInUser.IsAnonymous)
+
+	teams := append([]int64(nil), signedInUser.Teams...)
+	sort.Slice(teams, func(i, j int) bool { return teams[i] < teams[j] })
+	_, _ = fmt.Fprintf(h, "%v/", teams)
+
+	permissions := signedInUser.Permissions[signedInUser.OrgID]
+	actions := make([]string, 0, len(permissions))
+	for action := range permissions {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	for _, action := range actions {
+		scopes := append([]string(nil), permissions[action]...)
+		sort.Strings(scopes)
+		_, _ = fmt.Fprintf(h, "%s=%v/", action, scopes)
+	}
+	return h.Sum64()
+}
@@ -0,0 +1,134 @@
+package searchV2
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestDeniedCache(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	newCache := func() *deniedCache {
+		c := newDeniedCache(10 * time.Second)
+		c.now = func() time.Time { return now }
+		return c
+	}
+
+	evaluated := map[string]int{}
+	filter := func(uid string) bool {
+		evaluated[uid]++
+		return uid == "allowed"
+	}
+	viewer := &user.SignedInUser{OrgID: 1, UserID: 1, OrgRole: org.RoleViewer}
+
+	t.Run("denials are evaluated once", func(t *testing.T) {
+		evaluated = map[string]int{}
+		c := newCache()
+		hits := testutil.ToFloat64(dashboardSearchDeniedCacheLookups.WithLabelValues("hit"))
+
+		for i := 0; i < 3; i++ {
+			cached := c.wrap(viewer, filter)
+			require.True(t, cached("allowed"))
+			require.False(t, cached("denied"))
+		}
+		require.Equal(t, map[string]int{"allowed": 3, "denied": 1}, evaluated)
+		require.Equal(t, hits+2, testutil.ToFloat64(dashboardSearchDeniedCacheLookups.WithLabelValues("hit")))
+	})
+
+	t.Run("denials expire", func(t *testing.T) {
+		evaluated = map[string]int{}
+		c := newCache()
+
+		require.False(t, c.wrap(viewer, filter)("denied"))
+		now = now.Add(10 * time.Second)
+		require.False(t, c.wrap(viewer, filter)("denied"))
+		require.Equal(t, 2, evaluated["denied"])
+		require.Len(t, c.entries, 1)
+	})
+
+	t.Run("permission changes aren't served cached denials", func(t *testing.T) {
+		evaluated = map[string]int{}
+		c := newCache()
+
+		require.False(t, c.wrap(viewer, filter)("denied"))
+		editor := *viewer
+		editor.OrgRole = org.RoleEditor
+		require.False(t, c.wrap(&editor, filter)("denied"))
+		inTeam := *viewer
+		inTeam.Teams = []int64{1}
+		require.False(t, c.wrap(&inTeam, filter)("denied"))
+		withPermission := *viewer
+		withPermission.Permissions = map[int64]map[string][]string{1: {"dashboards:read": {"dashboards:uid:denied"}}}
+		require.False(t, c.wrap(&withPermission, filter)("denied"))
+		require.Equal(t, 4, evaluated["denied"])
+	})
+
+	t.Run("invalidating an organization drops its denials", func(t *testing.T) {
+		evaluated = map[string]int{}
+		c := newCache()
+		otherOrg := &user.SignedInUser{OrgID: 2, UserID: 1, OrgRole: org.RoleViewer}
+
+		require.False(t, c.wrap(viewer, filter)("denied"))
+		require.False(t, c.wrap(otherOrg, filter)("denied"))
+		c.invalidateOrg(1)
+		require.False(t, c.wrap(viewer, filter)("denied"))
+		require.False(t, c.wrap(otherOrg, filter)("denied"))
+		require.Equal(t, 3, evaluated["denied"])
+	})
+
+	t.Run("a zero TTL disables the cache", func(t *testing.T) {
+		c := newDeniedCache(0)
+		require.Nil(t, c)
+		evaluated = map[string]int{}
+		require.False(t, c.wrap(viewer, filter)("denied"))
+		require.False(t, c.wrap(viewer, filter)("denied"))
+		require.Equal(t, 2, evaluated["denied"])
+		c.invalidateOrg(1)
+	})
+	t.Run("readable dashboards are loaded once per user and permissions", func(t *testing.T) {
+		c := newCache()
+		loads := 0
+		load := func() (map[string]bool, error) {
+			loads++
+			return map[string]bool{"allowed": true}, nil
+		}
+
+		for i := 0; i < 3; i++ {
+			readable, err := c.readable(viewer, load)
+			require.NoError(t, err)
+			require.Equal(t, map[string]bool{"allowed": true}, readable.uids)
+		}
+		require.Equal(t, 1, loads)
+
+		editor := *viewer
+		editor.OrgRole = org.RoleEditor
+		_, err := c.readable(&editor, load)
+		require.NoError(t, err)
+		require.Equal(t, 2, loads)
+
+		c.invalidateOrg(1)
+		_, err = c.readable(viewer, load)
+		require.NoError(t, err)
+		require.Equal(t, 3, loads)
+
+		_, err = c.readable(viewer, func() (map[string]bool, error) { return nil, errors.New("failed") })
+		require.NoError(t, err, "loaded already")
+		now = now.Add(10 * time.Second)
+		_, err = c.readable(viewer, func() (map[string]bool, error) { return nil, errors.New("failed") })
+		require.Error(t, err)
+		_, err = c.readable(viewer, load)
+		require.NoError(t, err)
+		require.Equal(t, 4, loads, "errors aren't cached")
+	})
+	t.Run("readable lists are identified by their dashboards", func(t *testing.T) {
+		require.Equal(t, newReadableSet(map[string]bool{"a": true, "b": true}).fingerprint, newReadableSet(map[string]bool{"b": true, "a": true, "c": false}).fingerprint)
+		require.NotEqual(t, newReadableSet(map[string]bool{"a": true}).fingerprint, newReadableSet(map[string]bool{"ab": true}).fingerprint)
+		require.NotEqual(t, newReadableSet(map[string]bool{"a": true, "b": true}).fingerprint, newReadableSet(map[string]bool{"ab": true}).fingerprint)
+	})
+}
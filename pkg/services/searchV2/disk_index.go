@@ -0,0 +1,89 @@
+package searchV2
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/blugelabs/bluge"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// indexPlacement tells where the segments of an organization index are kept.
+type indexPlacement string
+
+const (
+	indexPlacementMemory indexPlacement = "memory"
+	// indexPlacementDisk keeps the segments in memory-mapped files, which the OS pages in when they
+	// are read. It trades some search latency for a much lower memory use of large indexes.
+	indexPlacementDisk indexPlacement = "disk"
+)
+
+// indexPlacementFor returns where the index of an organization with the given number of dashboards
+// is kept.
+func (i *searchIndex) indexPlacementFor(dashboardCount int) indexPlacement {
+	// indexes on disk can't be published to query instances
+	if i.indexTarget() == setting.SearchIndexTargetBuilder {
+		return indexPlacementMemory
+	}
+	threshold := i.settings.DiskIndexDashboardThreshold
+	if threshold > 0 && dashboardCount >= threshold {
+		return indexPlacementDisk
+	}
+	return indexPlacementMemory
+}
+
+// openDiskOrgIndex opens an empty org index in a new directory below the given path. Each build
+// gets its own directory, as the index it replaces is searched until the build is complete.
+func openDiskOrgIndex(path string, orgID int64) (*orgIndex, error) {
+	dir := filepath.Join(path, fmt.Sprintf("org-%d-%d", orgID, time.Now().UnixNano()))
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("error creating index directory: %w", err)
+	}
+	dashboardWriter, err := bluge.OpenWriter(bluge.DefaultConfig(dir))
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, fmt.Errorf("error opening writer: %v", err)
+	}
+	return &orgIndex{
+		mode:      indexModeFull,
+		placement: indexPlacementDisk,
+		diskPath:  dir,
+		writers: map[indexType]*bluge.Writer{
+			indexTypeDashboard: dashboardWriter,
+		},
+	}, nil
+}
+
+// close closes the writers of the index, and removes its files when it is on disk. Searches still
+// reading the index are not affected, the files stay mapped until they are done.
+func (i *orgIndex) close(logger log.Logger) {
+	for _, w := range i.writers {
+		_ = w.Close()
+	}
+	if i.diskPath == "" {
+		return
+	}
+	if err := os.RemoveAll(i.diskPath); err != nil {
+		logger.Warn("Failed to remove org index directory", "path", i.diskPath, "error", err)
+	}
+}
+
+// removeDiskIndexes removes the indexes left on disk by a previous run, which are rebuilt.
+func removeDiskIndexes(path string, logger log.Logger) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warn("Failed to list org index directories", "path", path, "error", err)
+		}
+		return
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(path, entry.Name())); err != nil {
+			logger.Warn("Failed to remove org index directory", "path", entry.Name(), "error", err)
+		}
+	}
+}
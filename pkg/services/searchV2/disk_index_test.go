@@ -0,0 +1,63 @@
+package searchV2
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/store"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestDiskIndex(t *testing.T) {
+	path := t.TempDir()
+	loader := &testDashboardLoader{dashboards: testPermissionDashboards(10)}
+	settings := setting.SearchSettings{DiskIndexDashboardThreshold: 5, DiskIndexPath: path}
+	index := newSearchIndex(loader, &store.MockEntityEventsService{}, &NoopDocumentExtender{}, nil, tracing.InitializeTracerForTest(), featuremgmt.WithFeatures(), settings, nil)
+
+	_, err := index.buildOrgIndex(context.Background(), testOrgID)
+	require.NoError(t, err)
+	require.Equal(t, "disk", index.getOrgStatus(testOrgID).Placement)
+	orgIdx, _ := index.getOrgIndex(testOrgID)
+	require.Empty(t, orgIdx.directories, "not persisted nor checkpointed")
+	require.Equal(t, []string{"dash-7"}, searchUIDs(t, orgIdx, testAllowAllFilter, DashboardQuery{Query: "dashboard 7"}))
+
+	dirs, err := os.ReadDir(path)
+	require.NoError(t, err)
+	require.Len(t, dirs, 1)
+
+	t.Run("rebuilds replace the directory of the index", func(t *testing.T) {
+		_, err := index.buildOrgIndex(context.Background(), testOrgID)
+		require.NoError(t, err)
+
+		rebuilt, err := os.ReadDir(path)
+		require.NoError(t, err)
+		require.Len(t, rebuilt, 1)
+		require.NotEqual(t, dirs[0].Name(), rebuilt[0].Name())
+	})
+
+	t.Run("small organizations are indexed in memory", func(t *testing.T) {
+		loader.dashboards = loader.dashboards[:4]
+		_, err := index.buildOrgIndex(context.Background(), testOrgID)
+		require.NoError(t, err)
+		require.Equal(t, "memory", index.getOrgStatus(testOrgID).Placement)
+
+		left, err := os.ReadDir(path)
+		require.NoError(t, err)
+		require.Empty(t, left)
+	})
+
+	t.Run("indexes left by a previous run are removed", func(t *testing.T) {
+		require.NoError(t, os.MkdirAll(filepath.Join(path, "org-1-123"), 0750))
+		removeDiskIndexes(path, testLogger)
+
+		left, err := os.ReadDir(path)
+		require.NoError(t, err)
+		require.Empty(t, left)
+	})
+}
@@ -0,0 +1,75 @@
+package searchV2
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/blugelabs/bluge"
+
+	"github.com/grafana/grafana/pkg/services/searchV2/extract"
+)
+
+// customFieldPrefix is prepended to the names of the fields added by document enrichers, so that
+// they can't collide with the built-in fields.
+const customFieldPrefix = "custom."
+
+var customFieldNameRegex = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// EnrichedDashboard is what document enrichers know about a dashboard.
+type EnrichedDashboard struct {
+	UID  string
+	Info *extract.DashboardInfo
+}
+
+// DocumentEnricher adds custom fields to dashboard documents while they are indexed, for example
+// a service name following from the dashboard naming conventions or metadata from a CMDB.
+// The fields can be filtered on with DashboardQuery.Custom.
+type DocumentEnricher interface {
+	// EnrichDashboard returns the values of the custom fields of a dashboard, keyed by field name.
+	// Field names may only contain lowercase letters, digits and underscores.
+	EnrichDashboard(ctx context.Context, orgID int64, dash EnrichedDashboard) (map[string][]string, error)
+}
+
+type namedEnricher struct {
+	name     string
+	enricher DocumentEnricher
+}
+
+// enrichDashboards sets the custom fields of the dashboards. A failing enricher doesn't fail
+// indexing, the dashboard is indexed without its fields.
+func (i *searchIndex) enrichDashboards(ctx context.Context, orgID int64, dashboards []dashboard) {
+	if len(i.enrichers) == 0 {
+		return
+	}
+	for idx := range dashboards {
+		dash := &dashboards[idx]
+		if dash.isFolder || dash.info == nil {
+			continue
+		}
+		for _, e := range i.enrichers {
+			fields, err := e.enricher.EnrichDashboard(ctx, orgID, EnrichedDashboard{UID: dash.uid, Info: dash.info})
+			if err != nil {
+				i.logger.Warn("Failed to enrich dashboard", "enricher", e.name, "orgId", orgID, "uid", dash.uid, "error", err)
+				continue
+			}
+			for name, values := range fields {
+				if !customFieldNameRegex.MatchString(name) {
+					i.logger.Warn("Ignoring invalid custom field", "enricher", e.name, "field", name)
+					continue
+				}
+				if dash.custom == nil {
+					dash.custom = make(map[string][]string)
+				}
+				dash.custom[name] = append(dash.custom[name], values...)
+			}
+		}
+	}
+}
+
+func addCustomFields(doc *bluge.Document, custom map[string][]string) {
+	for name, values := range custom {
+		for _, value := range values {
+			doc.AddField(bluge.NewKeywordField(customFieldPrefix+name, value).Aggregatable().StoreValue())
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package searchV2
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/searchV2/extract"
+)
+
+type enricherFunc func(ctx context.Context, orgID int64, dash EnrichedDashboard) (map[string][]string, error)
+
+func (f enricherFunc) EnrichDashboard(ctx context.Context, orgID int64, dash EnrichedDashboard) (map[string][]string, error) {
+	return f(ctx, orgID, dash)
+}
+
+// serviceEnricher takes the service from dashboard titles following the "<service> / <title>" convention.
+var serviceEnricher = enricherFunc(func(_ context.Context, _ int64, dash EnrichedDashboard) (map[string][]string, error) {
+	service, _, found := strings.Cut(dash.Info.Title, " / ")
+	if !found {
+		return nil, nil
+	}
+	return map[string][]string{"service": {strings.ToLower(service)}, "Invalid-Name": {"x"}}, nil
+})
+
+func TestDashboardIndex_DocumentEnrichers(t *testing.T) {
+	i := &searchIndex{
+		logger: testLogger,
+		enrichers: []namedEnricher{
+			{name: "service", enricher: serviceEnricher},
+			{name: "failing", enricher: enricherFunc(func(context.Context, int64, EnrichedDashboard) (map[string][]string, error) {
+				return nil, errors.New("cmdb unavailable")
+			})},
+		},
+	}
+
+	dashboards := []dashboard{
+		{id: 1, uid: "folder", isFolder: true, info: &extract.DashboardInfo{Title: "Checkout / Folder"}},
+		{id: 2, uid: "checkout", folderID: 1, info: &extract.DashboardInfo{Title: "Checkout / Latency"}},
+		{id: 3, uid: "payments", folderID: 1, info: &extract.DashboardInfo{Title: "Payments / Errors"}},
+		{id: 4, uid: "other", folderID: 1, info: &extract.DashboardInfo{Title: "Overview"}},
+	}
+	i.enrichDashboards(context.Background(), 1, dashboards)
+	require.Nil(t, dashboards[0].custom)
+	require.Equal(t, map[string][]string{"service": {"checkout"}}, dashboards[1].custom)
+	require.Nil(t, dashboards[3].custom)
+
+	index := initTestOrgIndexFromDashes(t, dashboards)
+	search := func(custom map[string][]string) []string {
+		return searchUIDs(t, index, testAllowAllFilter, DashboardQuery{Custom: custom})
+	}
+
+	require.Equal(t, []string{"checkout"}, search(map[string][]string{"service": {"checkout"}}))
+	require.ElementsMatch(t, []string{"checkout", "payments"}, search(map[string][]string{"service": {"checkout", "payments"}}))
+	require.Empty(t, search(map[string][]string{"service": {"unknown"}}))
+}
@@ -0,0 +1,73 @@
+package searchV2
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/blugelabs/bluge"
+)
+
+// Values of DashboardQuery.Experiments, each enables a search behavior which is not yet the default.
+// Queries may only enable the experiments allowed by setting.SearchSettings.AllowedQueryExperiments,
+// so that the frontend can roll them out to a part of the users without a restart.
+const (
+	// ExperimentQualityRanking ranks well maintained dashboards first, as with a QualityBoost of
+	// experimentQualityBoost, unless the query sets its own boost.
+	ExperimentQualityRanking = "qualityRanking"
+	// ExperimentExcludeBrokenDashboards leaves out the dashboards and panels referencing missing
+	// folders or datasources, unless the query asks for a report of them.
+	ExperimentExcludeBrokenDashboards = "excludeBrokenDashboards"
+	// ExperimentTopK stops the queries which only filter documents once they have their results,
+	// instead of visiting every match. Their results come in index order and their count is only
+	// a lower bound when more documents match, see customMeta.CountIsLowerBound.
+	ExperimentTopK = "topK"
+)
+
+const experimentQualityBoost = 2
+
+var ErrQueryExperimentNotAllowed = errors.New("search query experiment not allowed")
+
+// queryExperiments are applied to the queries enabling them, before the search query is built.
+var queryExperiments = map[string]func(q *DashboardQuery){
+	ExperimentQualityRanking: func(q *DashboardQuery) {
+		if q.QualityBoost == 0 {
+			q.QualityBoost = experimentQualityBoost
+		}
+	},
+	ExperimentExcludeBrokenDashboards: func(q *DashboardQuery) {
+		if q.Report == "" {
+			q.excludeIntegrityProblems = true
+		}
+	},
+	ExperimentTopK: func(q *DashboardQuery) {
+		q.topK = true
+	},
+}
+
+func validateExperiments(experiments []string, allowed []string) error {
+	for _, name := range experiments {
+		if _, ok := queryExperiments[name]; !ok {
+			return fmt.Errorf("%w: unknown experiment %q", ErrQueryExperimentNotAllowed, name)
+		}
+		if !containsString(allowed, name) {
+			return fmt.Errorf("%w: %q", ErrQueryExperimentNotAllowed, name)
+		}
+	}
+	return nil
+}
+
+func applyExperiments(q *DashboardQuery) {
+	for _, name := range q.Experiments {
+		queryExperiments[name](q)
+	}
+}
+
+// newIntegrityProblemsQuery matches the documents referencing folders or datasources which do not
+// exist. Dashboards in the general folder are not broken.
+func newIntegrityProblemsQuery() bluge.Query {
+	bq := bluge.NewBooleanQuery()
+	for _, report := range []string{ReportMissingFolder, ReportMissingDatasource} {
+		bq.AddShould(bluge.NewTermQuery(report).SetField(documentFieldIntegrity))
+	}
+	return bq
+}
@@ -0,0 +1,59 @@
+package searchV2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/searchV2/extract"
+)
+
+func TestQueryExperiments(t *testing.T) {
+	t.Run("only allowed experiments are accepted", func(t *testing.T) {
+		allowed := []string{ExperimentQualityRanking}
+
+		require.NoError(t, validateExperiments(nil, nil))
+		require.NoError(t, validateExperiments([]string{ExperimentQualityRanking}, allowed))
+		require.ErrorIs(t, validateExperiments([]string{ExperimentExcludeBrokenDashboards}, allowed), ErrQueryExperimentNotAllowed)
+		require.ErrorIs(t, validateExperiments([]string{"unknown"}, []string{"unknown"}), ErrQueryExperimentNotAllowed)
+	})
+
+	t.Run("quality ranking keeps the boost of the query", func(t *testing.T) {
+		q := DashboardQuery{Experiments: []string{ExperimentQualityRanking}}
+		applyExperiments(&q)
+		require.Equal(t, float64(experimentQualityBoost), q.QualityBoost)
+
+		q = DashboardQuery{Experiments: []string{ExperimentQualityRanking}, QualityBoost: 5}
+		applyExperiments(&q)
+		require.Equal(t, float64(5), q.QualityBoost)
+	})
+
+	t.Run("broken dashboards are excluded unless reported", func(t *testing.T) {
+		index := initTestOrgIndexFromDashes(t, []dashboard{
+			{id: 1, uid: "folder", isFolder: true, info: &extract.DashboardInfo{Title: "Folder"}},
+			{id: 2, uid: "in-general", info: &extract.DashboardInfo{Title: "In general"}},
+			{id: 3, uid: "orphaned", folderID: 100, info: &extract.DashboardInfo{Title: "Orphaned"}},
+			{
+				id:       4,
+				uid:      "missing-ds",
+				folderID: 1,
+				info: &extract.DashboardInfo{
+					Title:             "Missing datasource",
+					MissingDatasource: []string{"deleted"},
+					Panels: []extract.PanelInfo{
+						{ID: 1, Title: "Broken", Type: "timeseries", MissingDatasource: []string{"deleted"}},
+					},
+				},
+			},
+		})
+		search := func(q DashboardQuery) []string {
+			q.Experiments = []string{ExperimentExcludeBrokenDashboards}
+			applyExperiments(&q)
+			return searchUIDs(t, index, testAllowAllFilter, q)
+		}
+
+		require.ElementsMatch(t, []string{"folder", "in-general"}, search(DashboardQuery{}))
+		require.Equal(t, []string{"in-general"}, search(DashboardQuery{Query: "general"}))
+		require.Equal(t, []string{"orphaned"}, search(DashboardQuery{Report: ReportMissingFolder}))
+	})
+}
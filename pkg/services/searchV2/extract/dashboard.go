@@ -21,6 +21,66 @@ type templateVariable struct {
 	name         string
 	query        interface{}
 	variableType string
+	options      []string
+}
+
+// maxTemplateVariableValues bounds the values indexed per variable, custom variables can list
+// thousands of them
+const maxTemplateVariableValues = 100
+
+// staticValues returns the values of custom, constant, interval and textbox variables, which are
+// set in the dashboard rather than queried. The options are used when saved, the query otherwise.
+func (v templateVariable) staticValues() []string {
+	if v.name == "" {
+		return nil
+	}
+	var values []string
+	switch v.variableType {
+	case "custom", "interval":
+		values = v.options
+		if query, ok := v.query.(string); ok && len(values) == 0 {
+			values = strings.Split(query, ",")
+		}
+	case "constant", "textbox":
+		values = v.options
+		if query, ok := v.query.(string); ok && len(values) == 0 {
+			values = []string{query}
+		}
+	default:
+		return nil
+	}
+
+	unique := make([]string, 0, len(values))
+	seen := map[string]bool{}
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" || value == "$__all" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		unique = append(unique, value)
+		if len(unique) == maxTemplateVariableValues {
+			break
+		}
+	}
+	return unique
+}
+
+// optionValues returns the values of a variable option, a string or a list of strings.
+func optionValues(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
 }
 
 type datasourceVariableLookup struct {
@@ -174,6 +234,17 @@ func ReadDashboard(stream io.Reader, lookup dslookup.DatasourceLookup) (*Dashboa
 				dash.Tags = append(dash.Tags, iter.ReadString())
 			}
 
+		case "searchAliases":
+			for iter.ReadArray() {
+				if iter.WhatIsNext() != jsoniter.StringValue {
+					iter.Skip()
+					continue
+				}
+				if alias := strings.TrimSpace(iter.ReadString()); alias != "" {
+					dash.SearchAliases = append(dash.SearchAliases, alias)
+				}
+			}
+
 		case "links":
 			for iter.ReadArray() {
 				iter.Skip()
@@ -237,6 +308,20 @@ func ReadDashboard(stream io.Reader, lookup dslookup.DatasourceLookup) (*Dashboa
 										iter.Skip()
 									}
 								}
+							case "options":
+								for iter.ReadArray() {
+									if iter.WhatIsNext() != jsoniter.ObjectValue {
+										iter.Skip()
+										continue
+									}
+									for o := iter.ReadObject(); o != ""; o = iter.ReadObject() {
+										if o == "value" {
+											templateVariable.options = append(templateVariable.options, optionValues(iter.Read())...)
+										} else {
+											iter.Skip()
+										}
+									}
+								}
 							default:
 								iter.Skip()
 							}
@@ -245,6 +330,12 @@ func ReadDashboard(stream io.Reader, lookup dslookup.DatasourceLookup) (*Dashboa
 						if templateVariable.variableType == "datasource" {
 							datasourceVariablesLookup.add(templateVariable)
 						}
+						if values := templateVariable.staticValues(); len(values) > 0 {
+							if dash.TemplateVarValues == nil {
+								dash.TemplateVarValues = map[string][]string{}
+							}
+							dash.TemplateVarValues[templateVariable.name] = values
+						}
 					}
 				} else {
 					iter.Skip()
@@ -274,6 +365,7 @@ func ReadDashboard(stream io.Reader, lookup dslookup.DatasourceLookup) (*Dashboa
 		targets.addPanel(panel)
 	}
 	dash.Datasource = targets.GetDatasourceInfo()
+	dash.MissingDatasource = targets.GetMissingDatasources()
 
 	return dash, iter.Error
 }
@@ -284,7 +376,7 @@ func panelRequiresDatasource(panel PanelInfo) bool {
 
 func fillDefaultDatasources(dash *DashboardInfo, lookup dslookup.DatasourceLookup) {
 	for i, panel := range dash.Panels {
-		if len(panel.Datasource) != 0 || !panelRequiresDatasource(panel) {
+		if len(panel.Datasource) != 0 || len(panel.MissingDatasource) != 0 || !panelRequiresDatasource(panel) {
 			continue
 		}
 
@@ -447,6 +539,7 @@ func readPanelInfo(iter *jsoniter.Iterator, lookup dslookup.DatasourceLookup) Pa
 	}
 
 	panel.Datasource = targets.GetDatasourceInfo()
+	panel.MissingDatasource = targets.GetMissingDatasources()
 
 	return panel
 }
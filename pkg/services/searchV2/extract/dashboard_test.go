@@ -72,6 +72,9 @@ func TestReadDashboard(t *testing.T) {
 		"mixed-datasource-with-variable",
 		"special-datasource-types",
 		"panels-without-datasources",
+		"missing-datasources",
+		"search-aliases",
+		"template-variable-values",
 	}
 
 	devdash := "../../../../devenv/dev-dashboards/"
@@ -1,20 +1,24 @@
 package extract
 
 import (
+	"sort"
+
 	jsoniter "github.com/json-iterator/go"
 
 	"github.com/grafana/grafana/pkg/services/searchV2/dslookup"
 )
 
 type targetInfo struct {
-	lookup dslookup.DatasourceLookup
-	uids   map[string]*dslookup.DataSourceRef
+	lookup  dslookup.DatasourceLookup
+	uids    map[string]*dslookup.DataSourceRef
+	missing map[string]bool // references that could not be resolved
 }
 
 func newTargetInfo(lookup dslookup.DatasourceLookup) targetInfo {
 	return targetInfo{
-		lookup: lookup,
-		uids:   make(map[string]*dslookup.DataSourceRef),
+		lookup:  lookup,
+		uids:    make(map[string]*dslookup.DataSourceRef),
+		missing: make(map[string]bool),
 	}
 }
 
@@ -28,6 +32,19 @@ func (s *targetInfo) GetDatasourceInfo() []dslookup.DataSourceRef {
 	return keys
 }
 
+// GetMissingDatasources returns the sorted references to datasources that do not exist
+func (s *targetInfo) GetMissingDatasources() []string {
+	if len(s.missing) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(s.missing))
+	for k := range s.missing {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // the node will either be string (name|uid) OR ref
 func (s *targetInfo) addDatasource(iter *jsoniter.Iterator) {
 	switch iter.WhatIsNext() {
@@ -36,8 +53,7 @@ func (s *targetInfo) addDatasource(iter *jsoniter.Iterator) {
 
 		dsRef := &dslookup.DataSourceRef{UID: key}
 		if !isVariableRef(dsRef.UID) && !isSpecialDatasource(dsRef.UID) {
-			ds := s.lookup.ByRef(dsRef)
-			s.addRef(ds)
+			s.addResolvedRef(key, s.lookup.ByRef(dsRef))
 		} else {
 			s.addRef(dsRef)
 		}
@@ -51,7 +67,7 @@ func (s *targetInfo) addDatasource(iter *jsoniter.Iterator) {
 		iter.ReadVal(ref)
 
 		if !isVariableRef(ref.UID) && !isSpecialDatasource(ref.UID) {
-			s.addRef(s.lookup.ByRef(ref))
+			s.addResolvedRef(ref.UID, s.lookup.ByRef(ref))
 		} else {
 			s.addRef(ref)
 		}
@@ -68,6 +84,15 @@ func (s *targetInfo) addRef(ref *dslookup.DataSourceRef) {
 	}
 }
 
+// addResolvedRef adds the datasource found for key, or records key as missing
+func (s *targetInfo) addResolvedRef(key string, ref *dslookup.DataSourceRef) {
+	if ref == nil && key != "" {
+		s.missing[key] = true
+		return
+	}
+	s.addRef(ref)
+}
+
 func (s *targetInfo) addTarget(iter *jsoniter.Iterator) {
 	for l1Field := iter.ReadObject(); l1Field != ""; l1Field = iter.ReadObject() {
 		switch l1Field {
@@ -90,4 +115,7 @@ func (s *targetInfo) addPanel(panel PanelInfo) {
 			s.uids[v.UID] = &panel.Datasource[idx]
 		}
 	}
+	for _, uid := range panel.MissingDatasource {
+		s.missing[uid] = true
+	}
 }
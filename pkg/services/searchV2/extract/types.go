@@ -13,6 +13,9 @@ type PanelInfo struct {
 	Datasource    []dslookup.DataSourceRef `json:"datasource,omitempty"`  // UIDs
 	Transformer   []string                 `json:"transformer,omitempty"` // ids of the transformation steps
 
+	// MissingDatasource holds the references to datasources that do not exist
+	MissingDatasource []string `json:"missingDatasource,omitempty"`
+
 	// Rows define panels as sub objects
 	Collapsed []PanelInfo `json:"collapsed,omitempty"`
 }
@@ -33,4 +36,12 @@ type DashboardInfo struct {
 	TimeZone      string                   `json:"timezone"`
 	Refresh       string                   `json:"refresh,omitempty"`
 	ReadOnly      bool                     `json:"readOnly,omitempty"` // editable = false
+
+	// MissingDatasource holds the references to datasources that do not exist in any of the panels
+	MissingDatasource []string `json:"missingDatasource,omitempty"`
+	// SearchAliases are keywords the dashboard is also found by, e.g. synonyms of its title
+	SearchAliases []string `json:"searchAliases,omitempty"`
+	// TemplateVarValues are the static option values of the custom, constant, interval and textbox
+	// template variables, keyed by variable name
+	TemplateVarValues map[string][]string `json:"templateVarValues,omitempty"`
 }
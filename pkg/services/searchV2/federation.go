@@ -0,0 +1,381 @@
+package searchV2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// ErrFederatedSearchDenied is returned for federated queries of users who can't read all dashboards
+// and folders of the organization.
+var ErrFederatedSearchDenied = errors.New("not allowed to search the federated instances")
+
+var dashboardSearchFederatedRequestsCounter = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "dashboard_search_federated_requests_total",
+		Help:      "A counter for search queries proxied to federated Grafana instances",
+	},
+	[]string{"instance", "status"},
+)
+
+// resultFieldInstance labels the results of federated queries with the instance they come from.
+const resultFieldInstance = "instance"
+
+// maxFederatedResponseSize caps the response of a remote instance.
+const maxFederatedResponseSize = 32 << 20
+
+// federation proxies queries to remote Grafana instances and merges their results with the
+// results of this instance.
+type federation struct {
+	localName string
+	instances []setting.SearchFederatedInstance
+	timeout   time.Duration
+	client    *http.Client
+	logger    log.Logger
+}
+
+// newFederation returns nil when federation is disabled.
+func newFederation(settings setting.SearchSettings) *federation {
+	if !settings.FederationEnabled || len(settings.FederatedInstances) == 0 {
+		return nil
+	}
+	return &federation{
+		localName: settings.FederationLocalName,
+		instances: settings.FederatedInstances,
+		timeout:   settings.FederationTimeout,
+		client:    &http.Client{},
+		logger:    log.New("searchV2.federation"),
+	}
+}
+
+// canSearchFederated tells whether the user may search the remote instances. Their results are the
+// dashboards and folders a service account of the remote instance can read, whoever searches, so
+// federated queries are limited to the users who can read all dashboards and folders of the
+// organization. Without role-based access control only organization admins can run them.
+func canSearchFederated(ac accesscontrol.Service, signedInUser *user.SignedInUser, orgID int64) bool {
+	if ac.IsDisabled() {
+		return signedInUser.OrgRole == org.RoleAdmin || signedInUser.IsGrafanaAdmin
+	}
+	return accesscontrol.EvalAll(
+		accesscontrol.EvalPermission(dashboards.ActionDashboardsRead, dashboards.ScopeDashboardsAll),
+		accesscontrol.EvalPermission(dashboards.ActionFoldersRead, dashboards.ScopeFoldersAll),
+	).Evaluate(signedInUser.Permissions[orgID])
+}
+
+type federatedResult struct {
+	instance string
+	// baseURL makes the relative URLs of remote results absolute, it is empty for local results
+	baseURL string
+	frame   *data.Frame
+}
+
+// query runs the query on this instance with local and on the remote instances of the organization,
+// and merges the results. Queries scoped to folders, teams or dashboards of this instance aren't
+// proxied, as their UIDs and IDs mean nothing to remote instances.
+func (f *federation) query(ctx context.Context, orgID int64, q DashboardQuery, local func(DashboardQuery) *backend.DataResponse) *backend.DataResponse {
+	q.Federated = false
+	instances := f.instancesFor(orgID)
+	if len(instances) == 0 || q.Location != "" || q.TeamID != 0 || len(q.UIDs) > 0 || len(q.DashboardUIDs) > 0 {
+		return local(q)
+	}
+
+	limit := 50
+	if q.Limit > 0 {
+		limit = q.Limit
+	}
+	from := q.From
+	// each instance returns the first results of the page, the page is cut from the merged results
+	q.From = 0
+	q.Limit = from + limit
+
+	remote := make([]*federatedResult, len(instances))
+	errs := make([]error, len(instances))
+	var wg sync.WaitGroup
+	for i, instance := range instances {
+		wg.Add(1)
+		go func(i int, instance setting.SearchFederatedInstance) {
+			defer wg.Done()
+			remote[i], errs[i] = f.queryInstance(ctx, instance, q)
+			status := "success"
+			if errs[i] != nil {
+				status = "error"
+				f.logger.Warn("Federated search query failed", "instance", instance.Name, "error", errs[i])
+			}
+			dashboardSearchFederatedRequestsCounter.WithLabelValues(instance.Name, status).Inc()
+		}(i, instance)
+	}
+	rsp := local(q)
+	wg.Wait()
+	if rsp.Error != nil || len(rsp.Frames) == 0 {
+		return rsp
+	}
+
+	results := []*federatedResult{{instance: f.localName, frame: rsp.Frames[0]}}
+	failed := map[string]string{}
+	for i, result := range remote {
+		if errs[i] != nil {
+			failed[instances[i].Name] = errs[i].Error()
+			continue
+		}
+		results = append(results, result)
+	}
+
+	frame := mergeFederatedResults(results, q.Sort, from, limit)
+	if meta, ok := frame.Meta.Custom.(*customMeta); ok && len(failed) > 0 {
+		meta.FederationErrors = failed
+	}
+	rsp.Frames[0] = frame
+	return rsp
+}
+
+func (f *federation) instancesFor(orgID int64) []setting.SearchFederatedInstance {
+	instances := make([]setting.SearchFederatedInstance, 0, len(f.instances))
+	for _, instance := range f.instances {
+		if instance.OrgID == orgID {
+			instances = append(instances, instance)
+		}
+	}
+	return instances
+}
+
+func (f *federation) queryInstance(ctx context.Context, instance setting.SearchFederatedInstance, q DashboardQuery) (*federatedResult, error) {
+	if f.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.timeout)
+		defer cancel()
+	}
+
+	base, err := url.Parse(instance.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	// the results are read as a single frame, whatever the version of the instance
+	q.APIVersion = QueryAPIVersion1
+	body, err := json.Marshal(q)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, instance.URL+"/api/search-v2", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+instance.Token)
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			f.logger.Warn("Failed to close response body", "instance", instance.Name, "error", err)
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	frame := &data.Frame{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxFederatedResponseSize)).Decode(frame); err != nil {
+		return nil, fmt.Errorf("invalid search response: %w", err)
+	}
+	// returned while the search index of the instance is being built
+	if len(frame.Fields) == 0 {
+		return nil, fmt.Errorf("search is not ready")
+	}
+
+	return &federatedResult{
+		instance: instance.Name,
+		baseURL:  base.Scheme + "://" + base.Host,
+		frame:    frame,
+	}, nil
+}
+
+// mergeFederatedResults merges the results of the instances into a frame with the fields of the
+// local results and the instance field, and returns the requested page of it. The scores of
+// different indexes can't be compared, so results are taken in turn from each instance unless
+// they are sorted by name.
+func mergeFederatedResults(results []*federatedResult, sortBy string, from, limit int) *data.Frame {
+	local := results[0].frame
+	frame := data.NewFrame(local.Name)
+	for _, field := range local.Fields {
+		merged := data.NewFieldFromFieldType(field.Type(), 0)
+		merged.Name = field.Name
+		merged.Config = field.Config
+		frame.Fields = append(frame.Fields, merged)
+	}
+	fInstance := data.NewFieldFromFieldType(data.FieldTypeString, 0)
+	fInstance.Name = resultFieldInstance
+	frame.Fields = append(frame.Fields, fInstance)
+
+	meta := &customMeta{}
+	frameMeta := &data.FrameMeta{Custom: meta}
+	if local.Meta != nil {
+		if localMeta, ok := local.Meta.Custom.(*customMeta); ok {
+			*meta = *localMeta
+		}
+		frameMeta.Type = local.Meta.Type
+	}
+	frame.SetMeta(frameMeta)
+
+	sources := make([]*federatedSource, 0, len(results))
+	for _, result := range results {
+		source := newFederatedSource(result, frame)
+		sources = append(sources, source)
+		if result.baseURL == "" {
+			continue
+		}
+		remoteMeta := source.meta()
+		meta.Count += remoteMeta.Count
+		for uid, location := range remoteMeta.Locations {
+			if _, ok := meta.Locations[uid]; ok {
+				continue
+			}
+			if meta.Locations == nil {
+				meta.Locations = map[string]locationItem{}
+			}
+			location.URL = result.baseURL + location.URL
+			meta.Locations[uid] = location
+		}
+	}
+
+	next := nextInTurn
+	if strings.TrimPrefix(sortBy, "-") == documentFieldName_sort {
+		next = nextByName(strings.HasPrefix(sortBy, "-"))
+	}
+	for n := 0; n < from+limit; n++ {
+		source := next(sources, n)
+		if source == nil {
+			break
+		}
+		if n >= from {
+			source.appendRow(frame)
+		}
+		source.row++
+	}
+	return frame
+}
+
+// federatedSource iterates over the results of an instance.
+type federatedSource struct {
+	result *federatedResult
+	// fields holds the field of the results matching each field of the merged frame
+	fields []*data.Field
+	name   *data.Field
+	row    int
+}
+
+func newFederatedSource(result *federatedResult, merged *data.Frame) *federatedSource {
+	byName := make(map[string]*data.Field, len(result.frame.Fields))
+	for _, field := range result.frame.Fields {
+		byName[field.Name] = field
+	}
+
+	source := &federatedSource{result: result, name: byName[resultFieldName]}
+	for _, field := range merged.Fields {
+		if f, ok := byName[field.Name]; ok && f.Type() == field.Type() {
+			source.fields = append(source.fields, f)
+		} else {
+			source.fields = append(source.fields, nil)
+		}
+	}
+	return source
+}
+
+func (s *federatedSource) done() bool {
+	return s.row >= s.result.frame.Rows()
+}
+
+func (s *federatedSource) sortName() string {
+	if s.name == nil {
+		return ""
+	}
+	name, _ := s.name.At(s.row).(string)
+	return formatForNameSortField(name)
+}
+
+func (s *federatedSource) appendRow(frame *data.Frame) {
+	for i, merged := range frame.Fields {
+		switch {
+		case merged.Name == resultFieldInstance:
+			merged.Append(s.result.instance)
+		case s.fields[i] == nil:
+			merged.Extend(1)
+		case merged.Name == resultFieldURL && s.result.baseURL != "":
+			link, _ := s.fields[i].At(s.row).(string)
+			if strings.HasPrefix(link, "/") {
+				link = s.result.baseURL + link
+			}
+			merged.Append(link)
+		default:
+			merged.Append(s.fields[i].At(s.row))
+		}
+	}
+}
+
+// meta returns the count and locations of the results of a remote instance.
+func (s *federatedSource) meta() customMeta {
+	meta := customMeta{}
+	if s.result.frame.Meta == nil || s.result.frame.Meta.Custom == nil {
+		return meta
+	}
+	// remote meta is decoded as a map
+	raw, err := json.Marshal(s.result.frame.Meta.Custom)
+	if err == nil {
+		_ = json.Unmarshal(raw, &meta)
+	}
+	return meta
+}
+
+// nextInTurn returns the sources in turn, skipping those without more results.
+func nextInTurn(sources []*federatedSource, n int) *federatedSource {
+	for i := 0; i < len(sources); i++ {
+		if source := sources[(n+i)%len(sources)]; !source.done() {
+			return source
+		}
+	}
+	return nil
+}
+
+// nextByName returns the source whose next result comes first by name.
+func nextByName(desc bool) func([]*federatedSource, int) *federatedSource {
+	return func(sources []*federatedSource, _ int) *federatedSource {
+		var next *federatedSource
+		for _, source := range sources {
+			if source.done() {
+				continue
+			}
+			if next == nil {
+				next = source
+				continue
+			}
+			name, nextName := source.sortName(), next.sortName()
+			if (!desc && name < nextName) || (desc && name > nextName) {
+				next = source
+			}
+		}
+		return next
+	}
+}
@@ -0,0 +1,184 @@
+package searchV2
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+
+	accesscontrolmock "github.com/grafana/grafana/pkg/services/accesscontrol/mock"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/searchV2/extract"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func federationTestDashboards(titles ...string) []dashboard {
+	dashboards := make([]dashboard, 0, len(titles))
+	for i, title := range titles {
+		dashboards = append(dashboards, dashboard{
+			id:   int64(i + 1),
+			uid:  strings.ToLower(strings.ReplaceAll(title, " ", "-")),
+			info: &extract.DashboardInfo{Title: title},
+		})
+	}
+	return dashboards
+}
+
+// federationTestServer serves the search API of an instance with the given dashboards.
+func federationTestServer(t *testing.T, token string, dashboards []dashboard) (*httptest.Server, *[]DashboardQuery) {
+	t.Helper()
+	index := initTestOrgIndexFromDashes(t, dashboards)
+	var queries []DashboardQuery
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/search-v2" || r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		q := DashboardQuery{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&q))
+		queries = append(queries, q)
+		resp := doSearchQuery(r.Context(), testLogger, index, testAllowAllFilter, q, &NoopQueryExtender{}, "")
+		require.NoError(t, resp.Error)
+		body, err := resp.Frames[0].MarshalJSON()
+		require.NoError(t, err)
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &queries
+}
+
+func federatedQuery(t *testing.T, instances []setting.SearchFederatedInstance, q DashboardQuery) (*data.Frame, []DashboardQuery) {
+	t.Helper()
+	f := newFederation(setting.SearchSettings{
+		FederationEnabled:   true,
+		FederationLocalName: "local",
+		FederatedInstances:  instances,
+	})
+	require.NotNil(t, f)
+
+	index := initTestOrgIndexFromDashes(t, federationTestDashboards("Alpha local", "Gamma local"))
+	var local []DashboardQuery
+	resp := f.query(context.Background(), testOrgID, q, func(q DashboardQuery) *backend.DataResponse {
+		local = append(local, q)
+		return doSearchQuery(context.Background(), testLogger, index, testAllowAllFilter, q, &NoopQueryExtender{}, "")
+	})
+	require.NoError(t, resp.Error)
+	require.Len(t, resp.Frames, 1)
+	return resp.Frames[0], local
+}
+
+func frameStrings(t *testing.T, frame *data.Frame, name string) []string {
+	t.Helper()
+	field, _ := frame.FieldByName(name)
+	require.NotNil(t, field, name)
+	values := make([]string, 0, field.Len())
+	for i := 0; i < field.Len(); i++ {
+		values = append(values, field.At(i).(string))
+	}
+	return values
+}
+
+func TestFederatedSearch(t *testing.T) {
+	remote, remoteQueries := federationTestServer(t, "secret", federationTestDashboards("Beta remote", "Delta remote"))
+	instances := []setting.SearchFederatedInstance{{Name: "eu", URL: remote.URL, Token: "secret", OrgID: testOrgID}}
+
+	t.Run("merges and labels the results of the instances", func(t *testing.T) {
+		frame, _ := federatedQuery(t, instances, DashboardQuery{Sort: documentFieldName_sort, Limit: 10})
+		require.Equal(t, []string{"Alpha local", "Beta remote", "Delta remote", "Gamma local"}, frameStrings(t, frame, resultFieldName))
+		require.Equal(t, []string{"local", "eu", "eu", "local"}, frameStrings(t, frame, resultFieldInstance))
+
+		urls := frameStrings(t, frame, resultFieldURL)
+		require.True(t, strings.HasPrefix(urls[0], "/d/"), urls[0])
+		require.True(t, strings.HasPrefix(urls[1], remote.URL+"/d/"), urls[1])
+
+		meta := frame.Meta.Custom.(*customMeta)
+		require.Equal(t, uint64(4), meta.Count)
+		require.Empty(t, meta.FederationErrors)
+	})
+
+	t.Run("pages the merged results", func(t *testing.T) {
+		frame, local := federatedQuery(t, instances, DashboardQuery{Sort: "-" + documentFieldName_sort, From: 1, Limit: 2})
+		require.Equal(t, []string{"Delta remote", "Beta remote"}, frameStrings(t, frame, resultFieldName))
+		require.Equal(t, 0, local[0].From)
+		require.Equal(t, 3, local[0].Limit)
+		require.False(t, local[0].Federated)
+	})
+
+	t.Run("reports instances which failed to answer", func(t *testing.T) {
+		failing := append([]setting.SearchFederatedInstance{{Name: "us", URL: remote.URL, Token: "wrong", OrgID: testOrgID}}, instances...)
+		frame, _ := federatedQuery(t, failing, DashboardQuery{Limit: 10})
+		require.ElementsMatch(t, []string{"local", "local", "eu", "eu"}, frameStrings(t, frame, resultFieldInstance))
+
+		meta := frame.Meta.Custom.(*customMeta)
+		require.Contains(t, meta.FederationErrors, "us")
+		require.NotContains(t, meta.FederationErrors, "eu")
+	})
+
+	t.Run("queries scoped to this instance stay local", func(t *testing.T) {
+		sent := len(*remoteQueries)
+		frame, _ := federatedQuery(t, instances, DashboardQuery{UIDs: []string{"alpha-local"}})
+		require.Equal(t, []string{"Alpha local"}, frameStrings(t, frame, resultFieldName))
+		require.Len(t, *remoteQueries, sent)
+	})
+
+	t.Run("instances of other organizations aren't queried", func(t *testing.T) {
+		sent := len(*remoteQueries)
+		other := []setting.SearchFederatedInstance{{Name: "eu", URL: remote.URL, Token: "secret", OrgID: testOrgID + 1}}
+		frame, _ := federatedQuery(t, other, DashboardQuery{Limit: 10})
+		require.Equal(t, 2, frame.Rows())
+		require.Len(t, *remoteQueries, sent)
+	})
+}
+
+func TestFederatedSearchPermissions(t *testing.T) {
+	remote, remoteQueries := federationTestServer(t, "secret", federationTestDashboards("Beta remote"))
+	s := &StandardSearchService{
+		ac: accesscontrolmock.New(),
+		federation: newFederation(setting.SearchSettings{
+			FederationEnabled:  true,
+			FederatedInstances: []setting.SearchFederatedInstance{{Name: "eu", URL: remote.URL, Token: "secret", OrgID: testOrgID}},
+		}),
+	}
+	readAll := map[int64]map[string][]string{testOrgID: {
+		dashboards.ActionDashboardsRead: {dashboards.ScopeDashboardsAll},
+		dashboards.ActionFoldersRead:    {dashboards.ScopeFoldersAll},
+	}}
+	readSome := map[int64]map[string][]string{testOrgID: {
+		dashboards.ActionDashboardsRead: {dashboards.ScopeDashboardsProvider.GetResourceScopeUID("alpha-local")},
+		dashboards.ActionFoldersRead:    {dashboards.ScopeFoldersAll},
+	}}
+
+	t.Run("users who can't read all dashboards and folders can't search the remote instances", func(t *testing.T) {
+		usr := &user.SignedInUser{OrgID: testOrgID, OrgRole: org.RoleEditor, Permissions: readSome}
+		resp := s.executeDashboardQuery(context.Background(), usr, testOrgID, DashboardQuery{Federated: true})
+		require.ErrorIs(t, resp.Error, ErrFederatedSearchDenied)
+		require.Empty(t, *remoteQueries)
+	})
+
+	t.Run("users who can read all dashboards and folders can", func(t *testing.T) {
+		require.True(t, canSearchFederated(s.ac, &user.SignedInUser{Permissions: readAll}, testOrgID))
+		require.False(t, canSearchFederated(s.ac, &user.SignedInUser{Permissions: readAll}, testOrgID+1))
+	})
+
+	t.Run("only organization admins can without role-based access control", func(t *testing.T) {
+		legacy := accesscontrolmock.New().WithDisabled()
+		require.True(t, canSearchFederated(legacy, &user.SignedInUser{OrgRole: org.RoleAdmin}, testOrgID))
+		require.True(t, canSearchFederated(legacy, &user.SignedInUser{OrgRole: org.RoleViewer, IsGrafanaAdmin: true}, testOrgID))
+		require.False(t, canSearchFederated(legacy, &user.SignedInUser{OrgRole: org.RoleEditor, Permissions: readAll}, testOrgID))
+	})
+}
+
+func TestNewFederation(t *testing.T) {
+	instances := []setting.SearchFederatedInstance{{Name: "eu", URL: "https://eu.grafana.example", OrgID: 1}}
+	require.Nil(t, newFederation(setting.SearchSettings{FederatedInstances: instances}))
+	require.Nil(t, newFederation(setting.SearchSettings{FederationEnabled: true}))
+	require.NotNil(t, newFederation(setting.SearchSettings{FederationEnabled: true, FederatedInstances: instances}))
+}
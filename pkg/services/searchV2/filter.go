@@ -131,3 +131,37 @@ func (q *PermissionFilter) Searcher(i search.Reader, options search.SearcherOpti
 		return q.canAccess(e, id)
 	}), err
 }
+
+// DashboardUIDsFilter only matches documents of the dashboards and folders in a known set, and
+// the panels of those dashboards. It restricts searches to the dashboards a user can read, or to
+// an explicit list such as the dashboards of a playlist.
+// Unlike PermissionFilter, it doesn't check documents one by one: the set is compiled into
+// a disjunction of terms on the authz UID field, so the search only visits matching documents.
+type DashboardUIDsFilter struct {
+	uids []string
+}
+
+var _ bluge.Query = (*DashboardUIDsFilter)(nil)
+
+func newReadableUIDsFilter(readable map[string]bool) *DashboardUIDsFilter {
+	uids := make([]string, 0, len(readable))
+	for uid, ok := range readable {
+		if ok {
+			uids = append(uids, uid)
+		}
+	}
+	return &DashboardUIDsFilter{uids: uids}
+}
+
+func newDashboardUIDsFilter(uids []string) *DashboardUIDsFilter {
+	return &DashboardUIDsFilter{uids: uids}
+}
+
+func (q *DashboardUIDsFilter) Searcher(i search.Reader, options search.SearcherOptions) (search.Searcher, error) {
+	if len(q.uids) == 0 {
+		return searcher.NewMatchNoneSearcher(i, options)
+	}
+	// every document has a single authz UID, so all matches get the same score as with PermissionFilter
+	return searcher.NewMultiTermSearcher(i, q.uids, documentFieldAuthzUID, 1, similarity.ConstantScorer(1),
+		similarity.NewCompositeSumScorer(), options, false)
+}
@@ -0,0 +1,128 @@
+package searchV2
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/searchV2/extract"
+)
+
+func testPermissionDashboards(count int) []dashboard {
+	dashboards := []dashboard{
+		{id: 1, uid: "folder-a", isFolder: true, info: &extract.DashboardInfo{Title: "Folder A"}},
+		{id: 2, uid: "folder-b", isFolder: true, info: &extract.DashboardInfo{Title: "Folder B"}},
+	}
+	for i := 0; i < count; i++ {
+		dash := dashboard{
+			id:       int64(i + 3),
+			uid:      fmt.Sprintf("dash-%d", i),
+			folderID: int64(i%2 + 1),
+			info:     &extract.DashboardInfo{Title: fmt.Sprintf("Dashboard %d", i)},
+		}
+		for p := int64(1); p <= 5; p++ {
+			dash.info.Panels = append(dash.info.Panels, extract.PanelInfo{ID: p, Title: fmt.Sprintf("Panel %d", p), Type: "timeseries"})
+		}
+		dashboards = append(dashboards, dash)
+	}
+	return dashboards
+}
+
+// testReadableUIDs allows one dashboard out of ten and the first folder.
+func testReadableUIDs(count int) map[string]bool {
+	readable := map[string]bool{"folder-a": true}
+	for i := 0; i < count; i += 10 {
+		readable[fmt.Sprintf("dash-%d", i)] = true
+	}
+	return readable
+}
+
+func searchUIDs(t testing.TB, index *orgIndex, filter ResourceFilter, q DashboardQuery) []string {
+	resp := doSearchQuery(context.Background(), testLogger, index, filter, q, &NoopQueryExtender{}, "")
+	require.NoError(t, resp.Error)
+	uidField, _ := resp.Frames[0].FieldByName("uid")
+	uids := make([]string, 0, uidField.Len())
+	for i := 0; i < uidField.Len(); i++ {
+		uids = append(uids, uidField.At(i).(string))
+	}
+	return uids
+}
+
+func TestReadableUIDsFilter(t *testing.T) {
+	index := initTestOrgIndexFromDashes(t, testPermissionDashboards(50))
+	readable := testReadableUIDs(50)
+	filter := func(uid string) bool { return readable[uid] }
+
+	for _, q := range []DashboardQuery{
+		{Limit: 1000},
+		{Query: "dashboard", Limit: 1000},
+		{Kind: []string{string(entityKindPanel)}, Limit: 1000},
+		{Kind: []string{string(entityKindFolder)}, Limit: 1000},
+	} {
+		expected := searchUIDs(t, index, filter, q)
+		require.NotEmpty(t, expected)
+
+		q.readableUIDs = readable
+		require.Equal(t, expected, searchUIDs(t, index, testDisallowAllFilter, q))
+	}
+
+	require.Empty(t, searchUIDs(t, index, testAllowAllFilter, DashboardQuery{readableUIDs: map[string]bool{}}))
+}
+
+func TestDashboardUIDsQuery(t *testing.T) {
+	index := initTestOrgIndexFromDashes(t, testPermissionDashboards(20))
+	readable := testReadableUIDs(20)
+	filter := func(uid string) bool { return readable[uid] }
+
+	t.Run("searches within the dashboards", func(t *testing.T) {
+		q := DashboardQuery{Query: "dashboard", Kind: []string{string(entityKindDashboard)}, DashboardUIDs: []string{"dash-1", "dash-12", "dash-5"}}
+		require.ElementsMatch(t, []string{"dash-1", "dash-12", "dash-5"}, searchUIDs(t, index, testAllowAllFilter, q))
+
+		q.Query = "dashboard 1"
+		require.ElementsMatch(t, []string{"dash-1", "dash-12"}, searchUIDs(t, index, testAllowAllFilter, q))
+	})
+
+	t.Run("includes the panels of the dashboards", func(t *testing.T) {
+		q := DashboardQuery{Query: "panel 2", DashboardUIDs: []string{"dash-3"}}
+		require.Equal(t, []string{"dash-3#2"}, searchUIDs(t, index, testAllowAllFilter, q))
+	})
+
+	t.Run("permissions still apply", func(t *testing.T) {
+		q := DashboardQuery{DashboardUIDs: []string{"dash-0", "dash-1", "dash-10"}, Kind: []string{string(entityKindDashboard)}}
+		require.ElementsMatch(t, []string{"dash-0", "dash-10"}, searchUIDs(t, index, filter, q))
+
+		q.readableUIDs = readable
+		require.ElementsMatch(t, []string{"dash-0", "dash-10"}, searchUIDs(t, index, testDisallowAllFilter, q))
+	})
+
+	t.Run("empty list matches nothing", func(t *testing.T) {
+		require.Empty(t, searchUIDs(t, index, testAllowAllFilter, DashboardQuery{DashboardUIDs: []string{}}))
+	})
+}
+
+func benchmarkPermissionFilter(b *testing.B, compiled bool) {
+	const count = 2000
+	index := initTestOrgIndexFromDashes(b, testPermissionDashboards(count))
+	readable := testReadableUIDs(count)
+	filter := func(uid string) bool { return readable[uid] }
+
+	q := DashboardQuery{Query: "panel", Kind: []string{string(entityKindPanel)}}
+	if compiled {
+		q.readableUIDs = readable
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		searchUIDs(b, index, filter, q)
+	}
+}
+
+func BenchmarkPermissionFilter(b *testing.B) {
+	benchmarkPermissionFilter(b, false)
+}
+
+func BenchmarkReadableUIDsFilter(b *testing.B) {
+	benchmarkPermissionFilter(b, true)
+}
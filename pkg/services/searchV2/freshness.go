@@ -0,0 +1,51 @@
+package searchV2
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/blugelabs/bluge"
+)
+
+// documentFieldIndexedAt is when the document was last written to the index.
+const documentFieldIndexedAt = "indexed_at"
+
+// Reasons the results of an index may be out of date, see customMeta.StaleReason.
+const (
+	// the index is corrupted and being rebuilt, see customMeta.Degraded
+	staleReasonCorrupted = "corrupted"
+	// the index is known to miss changes and is being rebuilt, e.g. after provisioning
+	staleReasonRebuilding = "rebuilding"
+	// the index was restored from disk and misses the changes made while Grafana wasn't running
+	// until it is rebuilt
+	staleReasonRestored = "restored"
+)
+
+// addFreshnessFields records when the document is written to the index, results compare it with
+// when their dashboard was updated to tell how fresh they are.
+func addFreshnessFields(doc *bluge.Document, indexed time.Time) {
+	doc.AddField(bluge.NewDateTimeField(documentFieldIndexedAt, indexed).StoreValue())
+}
+
+// setRebuilding flags the index as missing changes until the rebuild replacing it finishes.
+func (i *orgIndex) setRebuilding(rebuilding bool) {
+	var v int32
+	if rebuilding {
+		v = 1
+	}
+	atomic.StoreInt32(&i.rebuilding, v)
+}
+
+// staleReason returns why the results of the index may be out of date, empty when they aren't.
+func (i *orgIndex) staleReason() string {
+	switch {
+	case i.isCorrupted():
+		return staleReasonCorrupted
+	case atomic.LoadInt32(&i.rebuilding) == 1:
+		return staleReasonRebuilding
+	case i.restored:
+		return staleReasonRestored
+	default:
+		return ""
+	}
+}
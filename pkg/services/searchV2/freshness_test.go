@@ -0,0 +1,89 @@
+package searchV2
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/searchV2/extract"
+	"github.com/grafana/grafana/pkg/services/store"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestResultFreshness(t *testing.T) {
+	ctx := context.Background()
+	updated := time.Date(2026, 5, 4, 12, 0, 0, 0, time.UTC)
+	dashboards := []dashboard{{
+		id: 1, uid: "latency", updated: updated,
+		info: &extract.DashboardInfo{Title: "Latency", Panels: []extract.PanelInfo{{ID: 2, Title: "Latency p99", Type: "timeseries"}}},
+	}}
+
+	t.Run("results tell when they were indexed and updated when requested", func(t *testing.T) {
+		before := time.Now()
+		index := initTestOrgIndexFromDashes(t, dashboards)
+		q := DashboardQuery{Query: "latency", Fields: []string{resultFieldUID, resultFieldIndexedAt, resultFieldSourceUpdatedAt}}
+		resp := doSearchQuery(ctx, testLogger, index, testAllowAllFilter, q, &NoopQueryExtender{}, "")
+		require.NoError(t, resp.Error)
+		frame := resp.Frames[0]
+		require.Equal(t, 2, frame.Rows(), "the dashboard and its panel")
+
+		indexedAt, _ := frame.FieldByName(resultFieldIndexedAt)
+		sourceUpdatedAt, _ := frame.FieldByName(resultFieldSourceUpdatedAt)
+		require.NotNil(t, indexedAt)
+		require.NotNil(t, sourceUpdatedAt)
+		for row := 0; row < frame.Rows(); row++ {
+			indexed := indexedAt.At(row).(*time.Time)
+			require.NotNil(t, indexed)
+			require.False(t, indexed.Before(before.Truncate(time.Second)))
+			require.True(t, updated.Equal(*sourceUpdatedAt.At(row).(*time.Time)))
+		}
+
+		resp = doSearchQuery(ctx, testLogger, index, testAllowAllFilter, DashboardQuery{Query: "latency"}, &NoopQueryExtender{}, "")
+		field, _ := resp.Frames[0].FieldByName(resultFieldIndexedAt)
+		require.Nil(t, field, "freshness fields are opt-in")
+	})
+
+	t.Run("results of stale indexes are flagged", func(t *testing.T) {
+		index := initTestOrgIndexFromDashes(t, dashboards)
+		staleness := func() (bool, string) {
+			resp := doSearchQuery(ctx, testLogger, index, testAllowAllFilter, DashboardQuery{Query: "latency"}, &NoopQueryExtender{}, "")
+			require.NoError(t, resp.Error)
+			meta := resp.Frames[0].Meta.Custom.(*customMeta)
+			return meta.Stale, meta.StaleReason
+		}
+
+		stale, reason := staleness()
+		require.False(t, stale)
+		require.Empty(t, reason)
+
+		index.restored = true
+		stale, reason = staleness()
+		require.True(t, stale)
+		require.Equal(t, staleReasonRestored, reason)
+
+		index.setRebuilding(true)
+		_, reason = staleness()
+		require.Equal(t, staleReasonRebuilding, reason)
+
+		index.markCorrupted()
+		_, reason = staleness()
+		require.Equal(t, staleReasonCorrupted, reason)
+	})
+
+	t.Run("forced re-indexes flag the indexes they replace", func(t *testing.T) {
+		i := newSearchIndex(&testDashboardLoader{dashboards: dashboards}, &store.MockEntityEventsService{}, &NoopDocumentExtender{}, nil, tracing.InitializeTracerForTest(), featuremgmt.WithFeatures(), setting.SearchSettings{}, nil)
+		_, err := i.buildOrgIndex(ctx, testOrgID)
+		require.NoError(t, err)
+		previous, _ := i.getOrgIndex(testOrgID)
+
+		i.reIndexFromScratch(ctx, true)
+		current, _ := i.getOrgIndex(testOrgID)
+		require.NotSame(t, previous, current)
+		require.Equal(t, staleReasonRebuilding, previous.staleReason())
+		require.Empty(t, current.staleReason())
+	})
+}
@@ -0,0 +1,214 @@
+package searchV2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/blugelabs/bluge"
+
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/sqlstore/db"
+	"github.com/grafana/grafana/pkg/services/store"
+)
+
+const (
+	GovernanceLabelCertified  = "certified"
+	GovernanceLabelDraft      = "draft"
+	GovernanceLabelDeprecated = "deprecated"
+
+	documentFieldGovernance = "governance"
+)
+
+var governanceLabels = map[string]bool{
+	GovernanceLabelCertified:  true,
+	GovernanceLabelDraft:      true,
+	GovernanceLabelDeprecated: true,
+}
+
+var (
+	ErrGovernanceLabelNotFound = errors.New("dashboard has no governance label")
+	ErrInvalidGovernanceLabel  = errors.New("governance labels are certified, draft or deprecated and need a dashboard UID")
+)
+
+// GovernanceLabel marks a dashboard as certified, draft or deprecated, so that governance programs
+// don't depend on tag conventions which anyone editing the dashboard can break. Dashboards have at
+// most one label, which is indexed and can be filtered on and boosted with DashboardQuery.Governance
+// and DashboardQuery.GovernanceBoost.
+type GovernanceLabel struct {
+	ID           int64     `json:"id" xorm:"pk autoincr 'id'"`
+	OrgID        int64     `json:"orgId" xorm:"org_id"`
+	DashboardUID string    `json:"dashboardUid" xorm:"dashboard_uid"`
+	Label        string    `json:"label" xorm:"label"`
+	Updated      time.Time `json:"updated" xorm:"updated"`
+}
+
+func (GovernanceLabel) TableName() string { return "search_governance_label" }
+
+type SetGovernanceLabelCommand struct {
+	OrgID        int64  `json:"-"`
+	DashboardUID string `json:"-"`
+	Label        string `json:"label"`
+}
+
+// governanceLabelStore stores the governance labels. Changes are saved along with an entity event
+// of the dashboard, so that every Grafana instance re-indexes it.
+type governanceLabelStore struct {
+	db  db.DB
+	now func() time.Time
+}
+
+func newGovernanceLabelStore(db db.DB) *governanceLabelStore {
+	return &governanceLabelStore{
+		db:  db,
+		now: time.Now,
+	}
+}
+
+func (g *governanceLabelStore) list(ctx context.Context, orgID int64) ([]*GovernanceLabel, error) {
+	labels := make([]*GovernanceLabel, 0)
+	err := g.db.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		return sess.Where("org_id=?", orgID).Asc("dashboard_uid").Find(&labels)
+	})
+	return labels, err
+}
+
+func (g *governanceLabelStore) set(ctx context.Context, cmd *SetGovernanceLabelCommand) (*GovernanceLabel, error) {
+	if !governanceLabels[cmd.Label] || cmd.DashboardUID == "" {
+		return nil, ErrInvalidGovernanceLabel
+	}
+
+	label := &GovernanceLabel{
+		OrgID:        cmd.OrgID,
+		DashboardUID: cmd.DashboardUID,
+		Label:        cmd.Label,
+		Updated:      g.now(),
+	}
+	err := g.db.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		existing := GovernanceLabel{}
+		exists, err := sess.Where("org_id=? AND dashboard_uid=?", label.OrgID, label.DashboardUID).Get(&existing)
+		if err != nil {
+			return err
+		}
+		if exists {
+			label.ID = existing.ID
+			_, err = sess.ID(existing.ID).Cols("label", "updated").Update(label)
+		} else {
+			_, err = sess.Insert(label)
+		}
+		if err != nil {
+			return err
+		}
+		return g.saveDashboardEvent(sess, label.OrgID, label.DashboardUID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return label, nil
+}
+
+func (g *governanceLabelStore) delete(ctx context.Context, orgID int64, dashboardUID string) error {
+	return g.db.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		deleted, err := sess.Where("org_id=? AND dashboard_uid=?", orgID, dashboardUID).Delete(&GovernanceLabel{})
+		if err != nil {
+			return err
+		}
+		if deleted == 0 {
+			return ErrGovernanceLabelNotFound
+		}
+		return g.saveDashboardEvent(sess, orgID, dashboardUID)
+	})
+}
+
+func (g *governanceLabelStore) saveDashboardEvent(sess *sqlstore.DBSession, orgID int64, dashboardUID string) error {
+	_, err := sess.Insert(&store.EntityEvent{
+		EventType: store.EntityEventTypeUpdate,
+		EntityId:  store.CreateDatabaseEntityId(dashboardUID, orgID, store.EntityTypeDashboard),
+		Created:   g.now().Unix(),
+	})
+	return err
+}
+
+// byDashboard returns the governance labels of the organization keyed by dashboard UID.
+func (g *governanceLabelStore) byDashboard(ctx context.Context, orgID int64) (map[string]string, error) {
+	labels, err := g.list(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	byDashboard := make(map[string]string, len(labels))
+	for _, label := range labels {
+		byDashboard[label.DashboardUID] = label.Label
+	}
+	return byDashboard, nil
+}
+
+// labelDashboards sets the governance label of the dashboards. Dashboards are indexed without
+// their label when the labels can't be loaded.
+func (i *searchIndex) labelDashboards(ctx context.Context, orgID int64, dashboards []dashboard) {
+	if i.governance == nil {
+		return
+	}
+	labels, err := i.governance.byDashboard(ctx, orgID)
+	if err != nil {
+		i.logger.Warn("Failed to load governance labels", "orgId", orgID, "error", err)
+		return
+	}
+	for idx := range dashboards {
+		if !dashboards[idx].isFolder {
+			dashboards[idx].governance = labels[dashboards[idx].uid]
+		}
+	}
+}
+
+func addGovernanceField(doc *bluge.Document, label string) {
+	if label != "" {
+		doc.AddField(bluge.NewKeywordField(documentFieldGovernance, label).Aggregatable().StoreValue())
+	}
+}
+
+// newGovernanceFilter matches the dashboards with one of the labels.
+func newGovernanceFilter(labels []string) bluge.Query {
+	bq := bluge.NewBooleanQuery()
+	for _, label := range labels {
+		bq.AddShould(bluge.NewTermQuery(label).SetField(documentFieldGovernance))
+	}
+	return bq
+}
+
+// newGovernanceBoostQuery returns a query adding the boost of their label to the score of the
+// dashboards.
+func newGovernanceBoostQuery(boosts map[string]float64) bluge.Query {
+	labels := make([]string, 0, len(boosts))
+	for label := range boosts {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	bq := bluge.NewBooleanQuery()
+	for _, label := range labels {
+		if boosts[label] > 0 {
+			bq.AddShould(bluge.NewTermQuery(label).SetField(documentFieldGovernance).SetBoost(boosts[label]))
+		}
+	}
+	return bq
+}
+
+func validateGovernance(labels []string, boosts map[string]float64) error {
+	for _, label := range labels {
+		if !governanceLabels[label] {
+			return fmt.Errorf("invalid governance label: %s", label)
+		}
+	}
+	for label, boost := range boosts {
+		if !governanceLabels[label] {
+			return fmt.Errorf("invalid governance label: %s", label)
+		}
+		if boost < 0 || math.IsNaN(boost) || math.IsInf(boost, 0) {
+			return fmt.Errorf("invalid governance boost: %v", boost)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package searchV2
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/searchV2/extract"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/store"
+)
+
+func TestIntegrationGovernanceLabels(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+	sqlStore := sqlstore.InitTestDB(t)
+	governance := newGovernanceLabelStore(sqlStore)
+	events := store.ProvideEntityEventsService(sqlStore.Cfg, sqlStore, featuremgmt.WithFeatures(featuremgmt.FlagPanelTitleSearch))
+
+	set := func(t *testing.T, orgID int64, uid, label string) *GovernanceLabel {
+		t.Helper()
+		result, err := governance.set(ctx, &SetGovernanceLabelCommand{OrgID: orgID, DashboardUID: uid, Label: label})
+		require.NoError(t, err)
+		return result
+	}
+
+	first := set(t, 1, "slo", GovernanceLabelDraft)
+	set(t, 2, "slo", GovernanceLabelDeprecated)
+
+	t.Run("dashboards have a single label", func(t *testing.T) {
+		updated := set(t, 1, "slo", GovernanceLabelCertified)
+		require.Equal(t, first.ID, updated.ID)
+
+		labels, err := governance.byDashboard(ctx, 1)
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"slo": GovernanceLabelCertified}, labels)
+	})
+
+	t.Run("changes re-index the dashboard", func(t *testing.T) {
+		event, err := events.GetLastEvent(ctx)
+		require.NoError(t, err)
+		require.Equal(t, store.EntityEventTypeUpdate, event.EventType)
+		require.Equal(t, store.CreateDatabaseEntityId("slo", 1, store.EntityTypeDashboard), event.EntityId)
+	})
+
+	t.Run("rejects unknown labels", func(t *testing.T) {
+		_, err := governance.set(ctx, &SetGovernanceLabelCommand{OrgID: 1, DashboardUID: "slo", Label: "gold"})
+		require.ErrorIs(t, err, ErrInvalidGovernanceLabel)
+		_, err = governance.set(ctx, &SetGovernanceLabelCommand{OrgID: 1, Label: GovernanceLabelDraft})
+		require.ErrorIs(t, err, ErrInvalidGovernanceLabel)
+	})
+
+	t.Run("deletes labels", func(t *testing.T) {
+		require.NoError(t, governance.delete(ctx, 1, "slo"))
+		require.ErrorIs(t, governance.delete(ctx, 1, "slo"), ErrGovernanceLabelNotFound)
+
+		labels, err := governance.list(ctx, 2)
+		require.NoError(t, err)
+		require.Len(t, labels, 1)
+	})
+}
+
+func TestDashboardIndex_Governance(t *testing.T) {
+	index := initTestOrgIndexFromDashes(t, []dashboard{
+		{id: 1, uid: "latency-old", governance: GovernanceLabelDeprecated, info: &extract.DashboardInfo{Title: "Latency"}},
+		{id: 2, uid: "latency", governance: GovernanceLabelCertified, info: &extract.DashboardInfo{Title: "Latency overview"}},
+		{id: 3, uid: "latency-draft", info: &extract.DashboardInfo{Title: "Latency draft"}},
+	})
+	search := func(q DashboardQuery) []string {
+		q.Kind = []string{string(entityKindDashboard)}
+		return searchUIDs(t, index, testAllowAllFilter, q)
+	}
+
+	t.Run("filters by label", func(t *testing.T) {
+		require.Equal(t, []string{"latency"}, search(DashboardQuery{Governance: []string{GovernanceLabelCertified}}))
+		require.ElementsMatch(t, []string{"latency", "latency-old"}, search(DashboardQuery{Governance: []string{GovernanceLabelCertified, GovernanceLabelDeprecated}}))
+	})
+
+	t.Run("boosts by label", func(t *testing.T) {
+		require.Equal(t, "latency-old", search(DashboardQuery{Query: "latency"})[0])
+		require.Equal(t, "latency", search(DashboardQuery{Query: "latency", GovernanceBoost: map[string]float64{GovernanceLabelCertified: 10}})[0])
+	})
+
+	t.Run("returns the label column when requested", func(t *testing.T) {
+		q := DashboardQuery{Kind: []string{string(entityKindDashboard)}, Fields: []string{resultFieldUID, resultFieldGovernance}}
+		resp := doSearchQuery(context.Background(), testLogger, index, testAllowAllFilter, q, &NoopQueryExtender{}, "")
+		require.NoError(t, resp.Error)
+
+		labels := map[string]string{}
+		uids, _ := resp.Frames[0].FieldByName(resultFieldUID)
+		field, _ := resp.Frames[0].FieldByName(resultFieldGovernance)
+		for i := 0; i < field.Len(); i++ {
+			if label := field.At(i).(*string); label != nil {
+				labels[uids.At(i).(string)] = *label
+			}
+		}
+		require.Equal(t, map[string]string{"latency-old": GovernanceLabelDeprecated, "latency": GovernanceLabelCertified}, labels)
+	})
+}
+
+func TestValidateGovernance(t *testing.T) {
+	require.NoError(t, validateGovernance([]string{GovernanceLabelDraft}, map[string]float64{GovernanceLabelCertified: 2}))
+	require.Error(t, validateGovernance([]string{"gold"}, nil))
+	require.Error(t, validateGovernance(nil, map[string]float64{"gold": 2}))
+	require.Error(t, validateGovernance(nil, map[string]float64{GovernanceLabelCertified: -1}))
+}
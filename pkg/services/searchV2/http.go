@@ -1,15 +1,19 @@
 package searchV2
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
+	"strconv"
+	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/grafana/grafana/pkg/api/response"
 	"github.com/grafana/grafana/pkg/api/routing"
 	"github.com/grafana/grafana/pkg/middleware"
 	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/web"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -27,50 +31,346 @@ func ProvideSearchHTTPService(search SearchService) SearchHTTPService {
 
 func (s *searchHTTPService) RegisterHTTPRoutes(storageRoute routing.RouteRegister) {
 	storageRoute.Post("/", middleware.ReqSignedIn, routing.Wrap(s.doQuery))
+	storageRoute.Get("/status", middleware.ReqOrgAdmin, routing.Wrap(s.getStatus))
+	storageRoute.Get("/estimate", middleware.ReqOrgAdmin, routing.Wrap(s.estimateIndex))
+	storageRoute.Get("/promoted", middleware.ReqOrgAdmin, routing.Wrap(s.listPromotedResults))
+	storageRoute.Post("/promoted", middleware.ReqOrgAdmin, routing.Wrap(s.addPromotedResult))
+	storageRoute.Delete("/promoted/:id", middleware.ReqOrgAdmin, routing.Wrap(s.deletePromotedResult))
+	storageRoute.Get("/warm-queries", middleware.ReqOrgAdmin, routing.Wrap(s.listWarmQueries))
+	storageRoute.Post("/warm-queries", middleware.ReqOrgAdmin, routing.Wrap(s.addWarmQuery))
+	storageRoute.Post("/warm-queries/run", middleware.ReqOrgAdmin, routing.Wrap(s.runWarmQueries))
+	storageRoute.Delete("/warm-queries/:id", middleware.ReqOrgAdmin, routing.Wrap(s.deleteWarmQuery))
+	storageRoute.Post("/diff", middleware.ReqOrgAdmin, routing.Wrap(s.diffQuery))
+	storageRoute.Get("/governance", middleware.ReqOrgAdmin, routing.Wrap(s.listGovernanceLabels))
+	storageRoute.Put("/governance/:uid", middleware.ReqOrgAdmin, routing.Wrap(s.setGovernanceLabel))
+	storageRoute.Delete("/governance/:uid", middleware.ReqOrgAdmin, routing.Wrap(s.deleteGovernanceLabel))
+	storageRoute.Get("/scoring-script", middleware.ReqOrgAdmin, routing.Wrap(s.getScoringScript))
+	storageRoute.Put("/scoring-script", middleware.ReqOrgAdmin, routing.Wrap(s.setScoringScript))
+	storageRoute.Delete("/scoring-script", middleware.ReqOrgAdmin, routing.Wrap(s.deleteScoringScript))
+	storageRoute.Get("/personalization", middleware.ReqSignedIn, routing.Wrap(s.getPersonalization))
+	storageRoute.Delete("/personalization", middleware.ReqSignedIn, routing.Wrap(s.clearPersonalization))
+	storageRoute.Post("/personalization/clicks", middleware.ReqSignedIn, routing.Wrap(s.recordSearchClick))
 }
 
+func (s *searchHTTPService) listPromotedResults(c *models.ReqContext) response.Response {
+	results, err := s.search.ListPromotedResults(c.Req.Context(), c.OrgID)
+	if err != nil {
+		return response.Error(500, "error getting promoted results", err)
+	}
+	return response.JSON(200, results)
+}
+
+func (s *searchHTTPService) addPromotedResult(c *models.ReqContext) response.Response {
+	cmd := &AddPromotedResultCommand{}
+	if err := web.Bind(c.Req, cmd); err != nil {
+		return response.Error(400, "error parsing body", err)
+	}
+	cmd.OrgID = c.OrgID
+
+	result, err := s.search.AddPromotedResult(c.Req.Context(), cmd)
+	switch {
+	case errors.Is(err, ErrInvalidPromotedResult):
+		return response.Error(400, err.Error(), err)
+	case errors.Is(err, ErrPromotedResultExists):
+		return response.Error(409, err.Error(), err)
+	case err != nil:
+		return response.Error(500, "error adding promoted result", err)
+	}
+	return response.JSON(200, result)
+}
+
+func (s *searchHTTPService) deletePromotedResult(c *models.ReqContext) response.Response {
+	id, err := strconv.ParseInt(web.Params(c.Req)[":id"], 10, 64)
+	if err != nil {
+		return response.Error(400, "id is invalid", err)
+	}
+
+	err = s.search.DeletePromotedResult(c.Req.Context(), c.OrgID, id)
+	switch {
+	case errors.Is(err, ErrPromotedResultNotFound):
+		return response.Error(404, err.Error(), err)
+	case err != nil:
+		return response.Error(500, "error deleting promoted result", err)
+	}
+	return response.Success("Promoted result deleted")
+}
+
+func (s *searchHTTPService) listWarmQueries(c *models.ReqContext) response.Response {
+	queries, err := s.search.ListWarmQueries(c.Req.Context(), c.OrgID)
+	if err != nil {
+		return response.Error(500, "error getting warm queries", err)
+	}
+	return response.JSON(200, queries)
+}
+
+func (s *searchHTTPService) addWarmQuery(c *models.ReqContext) response.Response {
+	cmd := &AddWarmQueryCommand{}
+	if err := web.Bind(c.Req, cmd); err != nil {
+		return response.Error(400, "error parsing body", err)
+	}
+	cmd.OrgID = c.OrgID
+
+	query, err := s.search.AddWarmQuery(c.Req.Context(), cmd)
+	switch {
+	case errors.Is(err, ErrInvalidWarmQuery):
+		return response.Error(400, err.Error(), err)
+	case errors.Is(err, ErrWarmQueryExists):
+		return response.Error(409, err.Error(), err)
+	case errors.Is(err, ErrTooManyWarmQueries):
+		return response.Error(422, err.Error(), err)
+	case err != nil:
+		return response.Error(500, "error adding warm query", err)
+	}
+	return response.JSON(200, query)
+}
+
+func (s *searchHTTPService) runWarmQueries(c *models.ReqContext) response.Response {
+	runs, err := s.search.RunWarmQueries(c.Req.Context(), c.OrgID)
+	if err != nil {
+		return response.Error(500, "error running warm queries", err)
+	}
+	return response.JSON(200, runs)
+}
+
+func (s *searchHTTPService) deleteWarmQuery(c *models.ReqContext) response.Response {
+	id, err := strconv.ParseInt(web.Params(c.Req)[":id"], 10, 64)
+	if err != nil {
+		return response.Error(400, "id is invalid", err)
+	}
+
+	err = s.search.DeleteWarmQuery(c.Req.Context(), c.OrgID, id)
+	switch {
+	case errors.Is(err, ErrWarmQueryNotFound):
+		return response.Error(404, err.Error(), err)
+	case err != nil:
+		return response.Error(500, "error deleting warm query", err)
+	}
+	return response.Success("Warm query deleted")
+}
+
+func (s *searchHTTPService) diffQuery(c *models.ReqContext) response.Response {
+	cmd := &DiffQueryCommand{}
+	if err := web.Bind(c.Req, cmd); err != nil {
+		return response.Error(400, "error parsing body", err)
+	}
+	cmd.OrgID = c.OrgID
+
+	diff, err := s.search.DiffQuery(c.Req.Context(), cmd)
+	switch {
+	case errors.Is(err, ErrInvalidQueryDiff):
+		return response.Error(400, err.Error(), err)
+	case errors.Is(err, ErrNoPersistedIndex):
+		return response.Error(404, err.Error(), err)
+	case err != nil:
+		return response.Error(500, "error comparing query results", err)
+	}
+	return response.JSON(200, diff)
+}
+
+func (s *searchHTTPService) listGovernanceLabels(c *models.ReqContext) response.Response {
+	labels, err := s.search.ListGovernanceLabels(c.Req.Context(), c.OrgID)
+	if err != nil {
+		return response.Error(500, "error getting governance labels", err)
+	}
+	return response.JSON(200, labels)
+}
+
+func (s *searchHTTPService) setGovernanceLabel(c *models.ReqContext) response.Response {
+	cmd := &SetGovernanceLabelCommand{}
+	if err := web.Bind(c.Req, cmd); err != nil {
+		return response.Error(400, "error parsing body", err)
+	}
+	cmd.OrgID = c.OrgID
+	cmd.DashboardUID = web.Params(c.Req)[":uid"]
+
+	label, err := s.search.SetGovernanceLabel(c.Req.Context(), cmd)
+	switch {
+	case errors.Is(err, ErrInvalidGovernanceLabel):
+		return response.Error(400, err.Error(), err)
+	case err != nil:
+		return response.Error(500, "error setting governance label", err)
+	}
+	return response.JSON(200, label)
+}
+
+func (s *searchHTTPService) deleteGovernanceLabel(c *models.ReqContext) response.Response {
+	err := s.search.DeleteGovernanceLabel(c.Req.Context(), c.OrgID, web.Params(c.Req)[":uid"])
+	switch {
+	case errors.Is(err, ErrGovernanceLabelNotFound):
+		return response.Error(404, err.Error(), err)
+	case err != nil:
+		return response.Error(500, "error deleting governance label", err)
+	}
+	return response.Success("Governance label deleted")
+}
+
+func (s *searchHTTPService) getScoringScript(c *models.ReqContext) response.Response {
+	script, err := s.search.GetScoringScript(c.Req.Context(), c.OrgID)
+	switch {
+	case errors.Is(err, ErrScoringScriptNotFound):
+		return response.Error(404, err.Error(), err)
+	case err != nil:
+		return response.Error(500, "error getting scoring script", err)
+	}
+	return response.JSON(200, script)
+}
+
+func (s *searchHTTPService) setScoringScript(c *models.ReqContext) response.Response {
+	cmd := &SetScoringScriptCommand{}
+	if err := web.Bind(c.Req, cmd); err != nil {
+		return response.Error(400, "error parsing body", err)
+	}
+	cmd.OrgID = c.OrgID
+
+	script, err := s.search.SetScoringScript(c.Req.Context(), cmd)
+	switch {
+	case errors.Is(err, ErrInvalidScoringScript):
+		return response.Error(400, err.Error(), err)
+	case err != nil:
+		return response.Error(500, "error setting scoring script", err)
+	}
+	return response.JSON(200, script)
+}
+
+func (s *searchHTTPService) deleteScoringScript(c *models.ReqContext) response.Response {
+	err := s.search.DeleteScoringScript(c.Req.Context(), c.OrgID)
+	switch {
+	case errors.Is(err, ErrScoringScriptNotFound):
+		return response.Error(404, err.Error(), err)
+	case err != nil:
+		return response.Error(500, "error deleting scoring script", err)
+	}
+	return response.Success("Scoring script deleted")
+}
+
+func (s *searchHTTPService) getPersonalization(c *models.ReqContext) response.Response {
+	signals, err := s.search.GetPersonalization(c.Req.Context(), c.OrgID, c.UserID)
+	if err != nil {
+		return response.Error(500, "error getting personalization", err)
+	}
+	return response.JSON(200, signals)
+}
+
+func (s *searchHTTPService) clearPersonalization(c *models.ReqContext) response.Response {
+	if err := s.search.ClearPersonalization(c.Req.Context(), c.OrgID, c.UserID); err != nil {
+		return response.Error(500, "error clearing personalization", err)
+	}
+	return response.Success("Personalization cleared")
+}
+
+func (s *searchHTTPService) recordSearchClick(c *models.ReqContext) response.Response {
+	cmd := &RecordSearchClickCommand{}
+	if err := web.Bind(c.Req, cmd); err != nil {
+		return response.Error(400, "error parsing body", err)
+	}
+	if cmd.DashboardUID == "" {
+		return response.Error(400, "dashboardUid is required", nil)
+	}
+	cmd.OrgID = c.OrgID
+	cmd.UserID = c.UserID
+
+	err := s.search.RecordSearchClick(c.Req.Context(), cmd)
+	switch {
+	case errors.Is(err, ErrPersonalizationDisabled), errors.Is(err, ErrPersonalizationDashboardNotFound):
+		return response.Error(404, err.Error(), err)
+	case err != nil:
+		return response.Error(500, "error recording search click", err)
+	}
+	return response.Success("Search click recorded")
+}
+
+func (s *searchHTTPService) getStatus(c *models.ReqContext) response.Response {
+	return response.JSON(200, s.search.GetIndexStatus(c.Req.Context(), c.OrgID))
+}
+
+func (s *searchHTTPService) estimateIndex(c *models.ReqContext) response.Response {
+	estimate, err := s.search.EstimateIndex(c.Req.Context(), c.OrgID)
+	if err != nil {
+		return response.Error(500, "error estimating index", err)
+	}
+	return response.JSON(200, estimate)
+}
+
+// maxWaitForReady caps how long search requests wait for the index to be ready.
+const maxWaitForReady = 30 * time.Second
+
 func (s *searchHTTPService) doQuery(c *models.ReqContext) response.Response {
+	body, err := io.ReadAll(c.Req.Body)
+	if err != nil {
+		return response.Error(500, "error reading bytes", err)
+	}
+
+	query, warnings, err := parseVersionedQuery(body)
+	if errors.Is(err, ErrUnsupportedQueryAPIVersion) {
+		return response.Error(400, err.Error(), err)
+	}
+	if err != nil {
+		return response.Error(400, "error parsing body", err)
+	}
+	dashboardSearchQueryAPIVersionCounter.With(prometheus.Labels{"version": strconv.Itoa(query.APIVersion)}).Inc()
+
 	searchReadinessCheckResp := s.search.IsReady(c.Req.Context(), c.OrgID)
+	if !searchReadinessCheckResp.IsReady && c.Query("waitForReady") != "" {
+		// clients which would otherwise retry until the index is ready wait for it instead
+		wait, err := time.ParseDuration(c.Query("waitForReady"))
+		if err != nil || wait < 0 {
+			return response.Error(400, "waitForReady must be a duration such as 10s", err)
+		}
+		if wait > maxWaitForReady {
+			wait = maxWaitForReady
+		}
+		ctx, cancel := context.WithTimeout(c.Req.Context(), wait)
+		searchReadinessCheckResp = s.search.WaitUntilReady(ctx, c.OrgID)
+		cancel()
+	}
 	if !searchReadinessCheckResp.IsReady {
 		dashboardSearchNotServedRequestsCounter.With(prometheus.Labels{
 			"reason": searchReadinessCheckResp.Reason,
 		}).Inc()
 
-		bytes, err := (&data.Frame{
-			Name: "Loading",
-		}).MarshalJSON()
-
+		bytes, _, err := marshalVersionedFrames(data.Frames{{Name: "Loading"}}, query.APIVersion, nil)
 		if err != nil {
 			return response.Error(500, "error marshalling response", err)
 		}
 		return response.JSON(200, bytes)
 	}
 
-	body, err := io.ReadAll(c.Req.Body)
-	if err != nil {
-		return response.Error(500, "error reading bytes", err)
-	}
+	resp := s.search.doDashboardQuery(c.Req.Context(), c.SignedInUser, c.OrgID, query)
 
-	query := &DashboardQuery{}
-	err = json.Unmarshal(body, query)
-	if err != nil {
-		return response.Error(400, "error parsing body", err)
+	if errors.Is(resp.Error, ErrTooManyQueries) {
+		return tooManyQueriesResponse(resp.Error)
+	}
+	switch {
+	case errors.Is(resp.Error, ErrInvalidPermissionsPreview), errors.Is(resp.Error, ErrTeamPermissionsPreviewUnsupported),
+		errors.Is(resp.Error, ErrQueryExperimentNotAllowed):
+		return response.Error(400, resp.Error.Error(), resp.Error)
+	case errors.Is(resp.Error, ErrPermissionsPreviewDenied), errors.Is(resp.Error, ErrFederatedSearchDenied):
+		return response.Error(403, resp.Error.Error(), resp.Error)
+	case errors.Is(resp.Error, ErrPermissionsPreviewNotFound):
+		return response.Error(404, resp.Error.Error(), resp.Error)
 	}
-
-	resp := s.search.doDashboardQuery(c.Req.Context(), c.SignedInUser, c.OrgID, *query)
-
 	if resp.Error != nil {
 		return response.Error(500, "error handling search request", resp.Error)
 	}
 
-	if len(resp.Frames) != 1 {
-		return response.Error(500, "invalid search response", errors.New("invalid search response"))
-	}
-
-	bytes, err := resp.Frames[0].MarshalJSON()
+	// suggestions are moved to the meta of the results frame
+	resp.Frames = moveSuggestionsToMeta(resp.Frames)
+	bytes, warnings, err := marshalVersionedFrames(resp.Frames, query.APIVersion, warnings)
 	if err != nil {
 		return response.Error(500, "error marshalling response", err)
 	}
 
-	return response.JSON(200, bytes)
+	rsp := response.JSON(200, bytes)
+	if len(warnings) > 0 {
+		rsp.SetHeader(QueryDeprecationHeader, "true")
+	}
+	if len(resp.Frames) == 0 || resp.Frames[0].Meta == nil {
+		return rsp
+	}
+	if meta, ok := resp.Frames[0].Meta.Custom.(*customMeta); ok && meta.Debug != nil {
+		if debug, err := json.Marshal(meta.Debug); err == nil {
+			rsp.SetHeader(SearchDebugHeader, string(debug))
+		}
+	}
+	return rsp
 }
@@ -11,6 +11,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/grafana/grafana/pkg/infra/log"
@@ -24,6 +25,7 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/blugelabs/bluge"
+	blugeindex "github.com/blugelabs/bluge/index"
 )
 
 type dashboardLoader interface {
@@ -35,6 +37,13 @@ type dashboardLoader interface {
 	LoadDashboards(ctx context.Context, orgID int64, dashboardUID string) ([]dashboard, error)
 }
 
+// folderDashboardLoader is implemented by loaders which can load the dashboards of a folder.
+// It lets the index move the dashboards of a folder whose UID changed without re-indexing
+// the whole organization.
+type folderDashboardLoader interface {
+	LoadFolderDashboards(ctx context.Context, orgID int64, folderID int64) ([]dashboard, error)
+}
+
 type eventStore interface {
 	GetLastEvent(ctx context.Context) (*store.EntityEvent, error)
 	GetAllEventsAfter(ctx context.Context, id int64) ([]*store.EntityEvent, error)
@@ -53,6 +62,24 @@ type dashboard struct {
 	created  time.Time
 	updated  time.Time
 	info     *extract.DashboardInfo
+	teams    []int64             // teams allowed to edit the folder (or the parent folder of a dashboard)
+	custom   map[string][]string // fields added by document enrichers
+
+	// governance label set by admins, see GovernanceLabel
+	governance string
+
+	// set when the dashboard is managed by a provisioner
+	provisioning *provisioningSource
+
+	// normalized embedding of the title and description, set when semantic search is enabled
+	embedding []float32
+
+	// set by loaders which don't identify dashboards by database ID
+	folderUID string
+	url       string
+
+	// set when the dashboard couldn't be fully indexed, see IndexErrorReport
+	indexErr *dashboardIndexError
 }
 
 // buildSignal is sent when search index is accessed in organization for which
@@ -63,7 +90,23 @@ type buildSignal struct {
 }
 
 type orgIndex struct {
-	writers map[indexType]*bluge.Writer
+	// updated is when the index was last built or changed in unix nanoseconds, accessed
+	// atomically as searches read it while events are applied
+	updated int64
+	// generation identifies the build of the index, see searchIndex.generations
+	generation  int64
+	mode        indexMode
+	placement   indexPlacement
+	diskPath    string // directory of indexes on disk
+	writers     map[indexType]*bluge.Writer
+	directories map[indexType]*memoryDirectory
+	vectors     *dashboardVectors // embeddings of the dashboards, for semantic search
+	// corrupted is set once a corruption of the index is detected, accessed atomically
+	corrupted int32
+	// rebuilding is set while a rebuild of an index known to miss changes is pending, accessed
+	// atomically. restored is set for indexes restored from disk, see staleReason
+	rebuilding int32
+	restored   bool
 }
 
 type indexType string
@@ -72,6 +115,20 @@ const (
 	indexTypeDashboard indexType = "dashboard"
 )
 
+// markUpdated records when the index was last built or changed.
+func (i *orgIndex) markUpdated(t time.Time) {
+	atomic.StoreInt64(&i.updated, t.UnixNano())
+}
+
+// lastUpdated returns when the index was last built or changed, the zero time when unknown.
+func (i *orgIndex) lastUpdated() time.Time {
+	updated := atomic.LoadInt64(&i.updated)
+	if updated == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, updated)
+}
+
 func (i *orgIndex) writerForIndex(idxType indexType) *bluge.Writer {
 	return i.writers[idxType]
 }
@@ -91,24 +148,61 @@ type searchIndex struct {
 	initializedOrgs         map[int64]bool
 	initialIndexingComplete bool
 	initializationMutex     sync.RWMutex
+	readinessCh             chan struct{} // closed and replaced when readiness changes, to wake up waiting requests
 	eventStore              eventStore
 	logger                  log.Logger
 	buildSignals            chan buildSignal
 	extender                DocumentExtender
+	enrichers               []namedEnricher
+	embeddings              EmbeddingProvider
 	folderIdLookup          folderUIDLookup
 	syncCh                  chan chan struct{}
 	tracer                  tracing.Tracer
 	features                featuremgmt.FeatureToggles
 	settings                setting.SearchSettings
+	persister               *indexPersister
+	restoredFromDisk        bool
+	orgStatus               map[int64]*orgIndexStatus
+	deniedCache             *deniedCache
+	warmQueries             *warmQueries
+	governance              *governanceLabelStore
+	// indexErrors are the dashboards of each organization which couldn't be fully indexed, by UID
+	indexErrors map[int64]map[string]*dashboardIndexError
+	// generations counts the org indexes built or restored since startup, it gives each its
+	// generation to correlate search results with the build of the index which answered them
+	generations int64
+	// recoverSignals receives the organizations whose index is corrupted and must be rebuilt
+	recoverSignals chan int64
+	// published is when the index of each organization was last published by the index builder,
+	// see setting.SearchSettings.IndexTarget
+	published map[int64]time.Time
+	// orgLister lists the organizations, whose indexes index builders build without waiting for
+	// searches of their users
+	orgLister func(ctx context.Context) ([]int64, error)
 }
 
-func newSearchIndex(dashLoader dashboardLoader, evStore eventStore, extender DocumentExtender, folderIDs folderUIDLookup, tracer tracing.Tracer, features featuremgmt.FeatureToggles, settings setting.SearchSettings) *searchIndex {
+// orgIndexStatus tracks full re-indexing schedule of an organization index.
+type orgIndexStatus struct {
+	mode            indexMode
+	placement       indexPlacement
+	dashboardCount  int
+	lastFullReindex time.Time
+	nextFullReindex time.Time
+}
+
+func newSearchIndex(dashLoader dashboardLoader, evStore eventStore, extender DocumentExtender, folderIDs folderUIDLookup, tracer tracing.Tracer, features featuremgmt.FeatureToggles, settings setting.SearchSettings, persister *indexPersister) *searchIndex {
+	logger := log.New("searchIndex")
+	if settings.IndexTarget != setting.SearchIndexTargetAll && settings.IndexTarget != "" && persister == nil {
+		logger.Error("Search index target needs an index path to publish indexes to, running all the indexing instead", "indexTarget", settings.IndexTarget)
+		settings.IndexTarget = setting.SearchIndexTargetAll
+	}
 	return &searchIndex{
 		loader:          dashLoader,
 		eventStore:      evStore,
 		perOrgIndex:     map[int64]*orgIndex{},
 		initializedOrgs: map[int64]bool{},
-		logger:          log.New("searchIndex"),
+		readinessCh:     make(chan struct{}),
+		logger:          logger,
 		buildSignals:    make(chan buildSignal),
 		extender:        extender,
 		folderIdLookup:  folderIDs,
@@ -116,10 +210,66 @@ func newSearchIndex(dashLoader dashboardLoader, evStore eventStore, extender Doc
 		tracer:          tracer,
 		features:        features,
 		settings:        settings,
+		persister:       persister,
+		orgStatus:       map[int64]*orgIndexStatus{},
+		indexErrors:     map[int64]map[string]*dashboardIndexError{},
+		recoverSignals:  make(chan int64, 16),
+		published:       map[int64]time.Time{},
 	}
 }
 
+// fullReindexInterval returns how often an organization with the given number
+// of dashboards is fully re-indexed.
+func (i *searchIndex) fullReindexInterval(dashboardCount int) time.Duration {
+	if i.settings.LargeOrgDashboardThreshold > 0 && dashboardCount >= i.settings.LargeOrgDashboardThreshold && i.settings.LargeOrgFullReindexInterval > 0 {
+		return i.settings.LargeOrgFullReindexInterval
+	}
+	return i.settings.FullReindexInterval
+}
+
+// fullReindexCheckInterval returns how often orgs are checked for a due full re-index.
+func (i *searchIndex) fullReindexCheckInterval() time.Duration {
+	interval := i.settings.FullReindexInterval
+	if i.settings.LargeOrgFullReindexInterval > 0 && i.settings.LargeOrgFullReindexInterval < interval {
+		interval = i.settings.LargeOrgFullReindexInterval
+	}
+	return interval
+}
+
+func (i *searchIndex) getOrgStatus(orgID int64) IndexStatus {
+	status := IndexStatus{
+		OrgID:  orgID,
+		Ready:  i.isInitialized(context.Background(), orgID).IsReady,
+		Target: i.indexTarget(),
+	}
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	if orgStatus, ok := i.orgStatus[orgID]; ok {
+		status.Mode = string(orgStatus.mode)
+		status.Placement = string(orgStatus.placement)
+		status.DashboardCount = orgStatus.dashboardCount
+		status.FullReindexInterval = i.fullReindexInterval(orgStatus.dashboardCount).String()
+		status.LastFullReindex = orgStatus.lastFullReindex
+		status.NextFullReindex = orgStatus.nextFullReindex
+	}
+	if index, ok := i.perOrgIndex[orgID]; ok {
+		status.Generation = index.generation
+		status.LastUpdated = index.lastUpdated()
+	}
+	status.IndexErrors = i.indexErrorReport(orgID)
+	return status
+}
+
 func (i *searchIndex) isInitialized(_ context.Context, orgId int64) IsSearchReadyResponse {
+	resp := i.readiness(orgId)
+	if resp.Reason == "org-indexing-ongoing" {
+		i.triggerBuildingOrgIndex(orgId)
+	}
+	return resp
+}
+
+func (i *searchIndex) readiness(orgId int64) IsSearchReadyResponse {
 	i.initializationMutex.RLock()
 	orgInitialized := i.initializedOrgs[orgId]
 	initialInitComplete := i.initialIndexingComplete
@@ -133,10 +283,42 @@ func (i *searchIndex) isInitialized(_ context.Context, orgId int64) IsSearchRead
 		return IsSearchReadyResponse{IsReady: false, Reason: "initial-indexing-ongoing"}
 	}
 
-	i.triggerBuildingOrgIndex(orgId)
 	return IsSearchReadyResponse{IsReady: false, Reason: "org-indexing-ongoing"}
 }
 
+// waitUntilReady blocks until the index of the organization is ready or the context is done,
+// and returns the readiness of the index at that point. The index of the organization is built
+// when it isn't yet.
+func (i *searchIndex) waitUntilReady(ctx context.Context, orgId int64) IsSearchReadyResponse {
+	triggered := false
+	for {
+		i.initializationMutex.RLock()
+		changed := i.readinessCh
+		i.initializationMutex.RUnlock()
+
+		resp := i.readiness(orgId)
+		if resp.IsReady {
+			return resp
+		}
+		if resp.Reason == "org-indexing-ongoing" && !triggered {
+			i.triggerBuildingOrgIndex(orgId)
+			triggered = true
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return i.readiness(orgId)
+		}
+	}
+}
+
+// notifyReadinessChanged wakes up the requests waiting for the index to be ready, it must be
+// called with the initialization mutex locked.
+func (i *searchIndex) notifyReadinessChanged() {
+	close(i.readinessCh)
+	i.readinessCh = make(chan struct{})
+}
+
 func (i *searchIndex) triggerBuildingOrgIndex(orgId int64) {
 	go func() {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
@@ -179,10 +361,13 @@ func (i *searchIndex) sync(ctx context.Context) error {
 }
 
 func (i *searchIndex) run(ctx context.Context, orgIDs []int64, reIndexSignalCh chan struct{}) error {
+	if i.indexTarget() == setting.SearchIndexTargetQuery {
+		return i.runQueryTarget(ctx, orgIDs, reIndexSignalCh)
+	}
 	i.logger.Info("Initializing SearchV2", "dashboardLoadingBatchSize", i.settings.DashboardLoadingBatchSize, "fullReindexInterval", i.settings.FullReindexInterval, "indexUpdateInterval", i.settings.IndexUpdateInterval)
 	initialSetupCtx, initialSetupSpan := i.tracer.Start(ctx, "searchV2 initialSetup")
 
-	reIndexInterval := i.settings.FullReindexInterval
+	reIndexInterval := i.fullReindexCheckInterval()
 	fullReIndexTimer := time.NewTimer(reIndexInterval)
 	defer fullReIndexTimer.Stop()
 
@@ -190,6 +375,20 @@ func (i *searchIndex) run(ctx context.Context, orgIDs []int64, reIndexSignalCh c
 	partialUpdateTimer := time.NewTimer(partialUpdateInterval)
 	defer partialUpdateTimer.Stop()
 
+	var warmQueryCh <-chan time.Time
+	if i.settings.WarmQueryInterval > 0 {
+		warmQueryTicker := time.NewTicker(i.settings.WarmQueryInterval)
+		defer warmQueryTicker.Stop()
+		warmQueryCh = warmQueryTicker.C
+	}
+
+	var verifyCh <-chan time.Time
+	if i.settings.IndexVerifyInterval > 0 {
+		verifyTicker := time.NewTicker(i.settings.IndexVerifyInterval)
+		defer verifyTicker.Stop()
+		verifyCh = verifyTicker.C
+	}
+
 	var lastEventID int64
 	lastEvent, err := i.eventStore.GetLastEvent(initialSetupCtx)
 	if err != nil {
@@ -200,20 +399,34 @@ func (i *searchIndex) run(ctx context.Context, orgIDs []int64, reIndexSignalCh c
 		lastEventID = lastEvent.Id
 	}
 
+	if i.settings.DiskIndexDashboardThreshold > 0 {
+		removeDiskIndexes(i.settings.DiskIndexPath, i.logger)
+	}
+
 	err = i.buildInitialIndexes(initialSetupCtx, orgIDs)
 	if err != nil {
 		initialSetupSpan.End()
 		return err
 	}
 
+	if i.restoredFromDisk {
+		// Indexes restored from disk may miss changes made while Grafana was not
+		// running, so rebuild them in background right away.
+		fullReIndexTimer.Reset(0)
+	}
+
 	// This semaphore channel allows limiting concurrent async re-indexing routines to 1.
 	asyncReIndexSemaphore := make(chan struct{}, 1)
 
 	// Channel to handle signals about asynchronous full re-indexing completion.
 	reIndexDoneCh := make(chan int64, 1)
 
+	// Re-index all organizations regardless of their schedule on the next full re-index.
+	forceFullReIndex := i.restoredFromDisk
+
 	i.initializationMutex.Lock()
 	i.initialIndexingComplete = true
+	i.notifyReadinessChanged()
 	i.initializationMutex.Unlock()
 
 	initialSetupSpan.End()
@@ -228,11 +441,22 @@ func (i *searchIndex) run(ctx context.Context, orgIDs []int64, reIndexSignalCh c
 			// Periodically apply updates collected in entity events table.
 			partialIndexUpdateCtx, span := i.tracer.Start(ctx, "searchV2 partial update timer")
 			lastEventID = i.applyIndexUpdates(partialIndexUpdateCtx, lastEventID)
+			if i.indexTarget() == setting.SearchIndexTargetBuilder {
+				i.publishUpdatedOrgIndexes(partialIndexUpdateCtx)
+			}
 			span.End()
 			partialUpdateTimer.Reset(partialUpdateInterval)
+		case <-warmQueryCh:
+			// Periodically run the warm queries, so that caches stay warm between index builds.
+			i.warmOrgIndexes(WarmQueryTriggerSchedule)
+		case <-verifyCh:
+			// Periodically verify the checksums of the indexes, as corrupted indexes return wrong
+			// results or fail queries until they are rebuilt.
+			go i.verifyOrgIndexes()
 		case <-reIndexSignalCh:
 			// External systems may trigger re-indexing, at this moment provisioning does this.
 			i.logger.Info("Full re-indexing due to external signal")
+			forceFullReIndex = true
 			fullReIndexTimer.Reset(0)
 		case signal := <-i.buildSignals:
 			buildSignalCtx, span := i.tracer.Start(ctx, "searchV2 build signal")
@@ -262,6 +486,26 @@ func (i *searchIndex) run(ctx context.Context, orgIDs []int64, reIndexSignalCh c
 				signal.done <- err
 				reIndexDoneCh <- lastIndexedEventID
 			}()
+		case orgID := <-i.recoverSignals:
+			recoverCtx, span := i.tracer.Start(ctx, "searchV2 recover corrupted index")
+			lastIndexedEventID := lastEventID
+			// Prevent full re-indexing while the corrupted index is rebuilt, as for build signals.
+			fullReIndexTimer.Stop()
+			go func() {
+				defer span.End()
+				asyncReIndexSemaphore <- struct{}{}
+				defer func() { <-asyncReIndexSemaphore }()
+				if _, err := i.buildOrgIndex(recoverCtx, orgID); err != nil {
+					i.logger.Error("Failed to rebuild corrupted org index", "orgId", orgID, "error", err)
+					// the next verification reports the index again, retrying the rebuild
+					if index, ok := i.getOrgIndex(orgID); ok {
+						index.clearCorrupted()
+					}
+				} else {
+					i.logger.Info("Rebuilt corrupted org index", "orgId", orgID)
+				}
+				reIndexDoneCh <- lastIndexedEventID
+			}()
 		case <-fullReIndexTimer.C:
 			fullReindexCtx, span := i.tracer.Start(ctx, "searchV2 full reindex timer")
 
@@ -271,6 +515,8 @@ func (i *searchIndex) run(ctx context.Context, orgIDs []int64, reIndexSignalCh c
 			// come to an approach which does not require periodic re-indexing at all. One possible way
 			// is to use DB triggers, see https://github.com/grafana/grafana/pull/47712.
 			lastIndexedEventID := lastEventID
+			force := forceFullReIndex
+			forceFullReIndex = false
 			go func() {
 				defer span.End()
 				// Do full re-index asynchronously to avoid blocking index synchronization
@@ -282,7 +528,7 @@ func (i *searchIndex) run(ctx context.Context, orgIDs []int64, reIndexSignalCh c
 
 				started := time.Now()
 				i.logger.Info("Start re-indexing", i.withCtxData(fullReindexCtx)...)
-				i.reIndexFromScratch(fullReindexCtx)
+				i.reIndexFromScratch(fullReindexCtx, force)
 				i.logger.Info("Full re-indexing finished", i.withCtxData(fullReindexCtx, "fullReIndexElapsed", time.Since(started))...)
 				reIndexDoneCh <- lastIndexedEventID
 			}()
@@ -323,6 +569,12 @@ func (i *searchIndex) buildInitialIndex(ctx context.Context, orgID int64) error
 	}
 
 	started := time.Now()
+	if i.restoreOrgIndex(ctx, orgID) {
+		debugCtxCancel()
+		i.logger.Info("Restored org index from disk", "orgIndexElapsed", time.Since(started), "orgId", orgID)
+		return nil
+	}
+
 	numDashboards, err := i.buildOrgIndex(ctx, orgID)
 	if err != nil {
 		debugCtxCancel()
@@ -483,6 +735,9 @@ func (i *searchIndex) buildOrgIndex(ctx context.Context, orgID int64) (int, erro
 		return 0, fmt.Errorf("error loading dashboards: %w, elapsed: %s", err, orgSearchIndexLoadTime.String())
 	}
 	i.logger.Info("Finish loading org dashboards", "elapsed", orgSearchIndexLoadTime, "orgId", orgID)
+	i.labelDashboards(ctx, orgID, dashboards)
+	i.enrichDashboards(ctx, orgID, dashboards)
+	i.embedDashboards(ctx, orgID, dashboards)
 
 	dashboardExtender := i.extender.GetDashboardExtender(orgID)
 
@@ -490,36 +745,70 @@ func (i *searchIndex) buildOrgIndex(ctx context.Context, orgID int64) (int, erro
 	initOrgIndexSpan.SetAttributes("org_id", orgID, attribute.Key("org_id").Int64(orgID))
 	initOrgIndexSpan.SetAttributes("dashboardCount", len(dashboards), attribute.Key("dashboardCount").Int(len(dashboards)))
 
-	index, err := initOrgIndex(dashboards, i.logger, dashboardExtender)
+	mode := i.indexModeFor(len(dashboards))
+	placement := i.indexPlacementFor(len(dashboards))
+	var index *orgIndex
+	var checkpoints *buildCheckpoints
+	if placement == indexPlacementDisk {
+		// checkpoints are saved from memory indexes only
+		index, err = i.initDiskOrgIndex(orgID, dashboards, dashboardExtender, mode)
+	} else {
+		checkpoints = i.buildCheckpointsFor(ctx, orgID, mode, len(dashboards))
+		index, err = initOrgIndex(dashboards, i.logger, dashboardExtender, mode, checkpoints)
+		checkpoints.finish()
+	}
 
 	initOrgIndexSpan.End()
 
 	if err != nil {
 		return 0, fmt.Errorf("error initializing index: %w", err)
 	}
+	index.vectors = newDashboardVectors(dashboards)
 	orgSearchIndexTotalTime := time.Since(started)
 	orgSearchIndexBuildTime := orgSearchIndexTotalTime - orgSearchIndexLoadTime
 
-	i.logger.Info("Re-indexed dashboards for organization",
-		i.withCtxData(ctx, "orgId", orgID,
-			"orgSearchIndexLoadTime", orgSearchIndexLoadTime,
-			"orgSearchIndexBuildTime", orgSearchIndexBuildTime,
-			"orgSearchIndexTotalTime", orgSearchIndexTotalTime,
-			"orgSearchDashboardCount", len(dashboards))...)
-
 	i.mu.Lock()
 	if oldIndex, ok := i.perOrgIndex[orgID]; ok {
-		for _, w := range oldIndex.writers {
-			_ = w.Close()
-		}
+		oldIndex.close(i.logger)
 	}
+	finished := time.Now()
+	i.generations++
+	index.generation = i.generations
+	index.markUpdated(finished)
 	i.perOrgIndex[orgID] = index
+	i.orgStatus[orgID] = &orgIndexStatus{
+		mode:            mode,
+		placement:       placement,
+		dashboardCount:  len(dashboards),
+		lastFullReindex: finished,
+		nextFullReindex: finished.Add(i.fullReindexInterval(len(dashboards))),
+	}
+	i.indexErrors[orgID] = orgIndexErrors(dashboards)
 	i.mu.Unlock()
 
+	i.logger.Info("Re-indexed dashboards for organization",
+		i.withCtxData(ctx, "orgId", orgID,
+			"orgSearchIndexLoadTime", orgSearchIndexLoadTime,
+			"orgSearchIndexBuildTime", orgSearchIndexBuildTime,
+			"orgSearchIndexTotalTime", orgSearchIndexTotalTime,
+			"orgSearchDashboardCount", len(dashboards),
+			"orgSearchIndexMode", mode,
+			"orgSearchIndexPlacement", placement,
+			"orgSearchIndexGeneration", index.generation)...)
+
 	i.initializationMutex.Lock()
 	i.initializedOrgs[orgID] = true
+	i.notifyReadinessChanged()
 	i.initializationMutex.Unlock()
 
+	i.persistOrgIndex(ctx, orgID, index)
+	i.warmQueries.warmInBackground(orgID, index, WarmQueryTriggerIndexBuild)
+	if checkpoints != nil {
+		if err := i.persister.removeCheckpoint(orgID, indexTypeDashboard); err != nil {
+			i.logger.Warn("Failed to remove org index checkpoint", "orgId", orgID, "error", err)
+		}
+	}
+
 	if orgID == 1 {
 		go func() {
 			if reader, cancel, err := index.readerForIndex(indexTypeDashboard); err == nil {
@@ -531,6 +820,142 @@ func (i *searchIndex) buildOrgIndex(ctx context.Context, orgID int64) (int, erro
 	return len(dashboards), nil
 }
 
+// initDiskOrgIndex builds the index of the dashboards of an organization on disk.
+func (i *searchIndex) initDiskOrgIndex(orgID int64, dashboards []dashboard, extendDoc ExtendDashboardFunc, mode indexMode) (*orgIndex, error) {
+	index, err := openDiskOrgIndex(i.settings.DiskIndexPath, orgID)
+	if err != nil {
+		return nil, err
+	}
+	index.mode = mode
+	if err := fillOrgIndex(index, dashboards, i.logger, extendDoc, nil); err != nil {
+		index.close(i.logger)
+		return nil, err
+	}
+	return index, nil
+}
+
+// restoreOrgIndex tries to load a previously persisted index for an organization.
+// It returns false if persistence is disabled or the index could not be restored.
+func (i *searchIndex) restoreOrgIndex(ctx context.Context, orgID int64) bool {
+	if i.persister == nil {
+		return false
+	}
+
+	dir, needsRewrite, err := i.persister.load(ctx, orgID, indexTypeDashboard)
+	if err != nil {
+		switch {
+		case errors.Is(err, errIndexCorrupted):
+			i.logger.Error("Persisted org index is corrupted, building it instead", "orgId", orgID, "error", err)
+			countCorruption(corruptionSourceLoad)
+			if err := i.persister.quarantine(orgID, indexTypeDashboard); err != nil {
+				i.logger.Warn("Failed to quarantine corrupted org index", "orgId", orgID, "error", err)
+			}
+		case !errors.Is(err, errPersistedIndexNotFound):
+			i.logger.Warn("Failed to restore org index from disk", "orgId", orgID, "error", err)
+		}
+		return false
+	}
+
+	index, err := i.openPersistedOrgIndex(orgID, dir)
+	if err != nil {
+		i.logger.Warn("Failed to open restored org index", "orgId", orgID, "error", err)
+		return false
+	}
+	i.initializationMutex.Lock()
+	i.restoredFromDisk = true
+	i.initializationMutex.Unlock()
+	index.restored = true
+	i.setPersistedOrgIndex(orgID, index)
+	i.logger.Info("Restored org index from disk", "orgId", orgID, "orgSearchIndexGeneration", index.generation)
+
+	if needsRewrite {
+		// Migrate indexes persisted before encryption was enabled.
+		i.persistOrgIndex(ctx, orgID, index)
+	}
+	return true
+}
+
+func (i *searchIndex) persistOrgIndex(ctx context.Context, orgID int64, index *orgIndex) {
+	if i.persister == nil {
+		return
+	}
+	dir, ok := index.directories[indexTypeDashboard]
+	if !ok {
+		return
+	}
+	saved := time.Now()
+	if err := i.persister.save(ctx, orgID, indexTypeDashboard, dir); err != nil {
+		i.logger.Error("Failed to persist org index", "orgId", orgID, "error", err)
+		return
+	}
+	i.mu.Lock()
+	i.published[orgID] = saved
+	i.mu.Unlock()
+}
+
+// openPersistedOrgIndex opens an index loaded from disk.
+func (i *searchIndex) openPersistedOrgIndex(orgID int64, dir *memoryDirectory) (*orgIndex, error) {
+	index, err := openOrgIndex(dir)
+	if err != nil {
+		return nil, err
+	}
+	// the number of dashboards isn't known until the next full re-index, which picks the mode
+	// following from the size threshold
+	index.mode = i.indexModeFor(0)
+	if i.semanticSearchEnabled() {
+		if index.vectors, err = loadDashboardVectors(index); err != nil {
+			// the dashboards are found by keywords only until the next full re-index
+			i.logger.Warn("Failed to load dashboard embeddings of restored org index", "orgId", orgID, "error", err)
+		}
+	}
+	return index, nil
+}
+
+// setPersistedOrgIndex makes an index loaded from disk the index of the organization, replacing
+// its current index.
+func (i *searchIndex) setPersistedOrgIndex(orgID int64, index *orgIndex) {
+	i.mu.Lock()
+	if oldIndex, ok := i.perOrgIndex[orgID]; ok {
+		oldIndex.close(i.logger)
+	}
+	i.generations++
+	index.generation = i.generations
+	index.markUpdated(time.Now())
+	i.perOrgIndex[orgID] = index
+	i.mu.Unlock()
+
+	i.initializationMutex.Lock()
+	i.initializedOrgs[orgID] = true
+	i.notifyReadinessChanged()
+	i.initializationMutex.Unlock()
+}
+
+// buildCheckpointsFor returns the checkpoints of a build of the index of an organization, resuming
+// from the checkpoint of a previous build which didn't finish if it was saved within the full
+// re-index interval. It returns nil if persistence or checkpoints are disabled.
+func (i *searchIndex) buildCheckpointsFor(ctx context.Context, orgID int64, mode indexMode, dashboardCount int) *buildCheckpoints {
+	if i.persister == nil || i.settings.IndexCheckpointInterval <= 0 {
+		return nil
+	}
+
+	resume, err := i.persister.loadCheckpoint(ctx, orgID, indexTypeDashboard)
+	switch {
+	case err != nil:
+		if !errors.Is(err, errPersistedIndexNotFound) {
+			i.logger.Warn("Failed to load org index checkpoint", "orgId", orgID, "error", err)
+		}
+		resume = nil
+	case resume.Mode != mode || time.Since(resume.Saved) > i.fullReindexInterval(dashboardCount):
+		resume = nil
+	default:
+		i.logger.Info("Resuming org index build from checkpoint", "orgId", orgID, "checkpointAge", time.Since(resume.Saved), "indexed", len(resume.Indexed))
+	}
+
+	return newBuildCheckpoints(orgID, mode, i.settings.IndexCheckpointInterval, resume, func(checkpoint *indexCheckpoint) error {
+		return i.persister.saveCheckpoint(ctx, orgID, indexTypeDashboard, checkpoint)
+	}, i.logger)
+}
+
 func (i *searchIndex) getOrgIndex(orgID int64) (*orgIndex, bool) {
 	i.mu.RLock()
 	defer i.mu.RUnlock()
@@ -565,14 +990,30 @@ func (i *searchIndex) getOrCreateOrgIndex(ctx context.Context, orgID int64) (*or
 	return index, nil
 }
 
-func (i *searchIndex) reIndexFromScratch(ctx context.Context) {
+// reIndexFromScratch rebuilds indexes of organizations which are due for a full
+// re-index according to their schedule, or all indexes when force is true.
+func (i *searchIndex) reIndexFromScratch(ctx context.Context, force bool) {
+	now := time.Now()
 	i.mu.RLock()
 	orgIDs := make([]int64, 0, len(i.perOrgIndex))
 	for orgID := range i.perOrgIndex {
+		if status, ok := i.orgStatus[orgID]; ok && !force && now.Before(status.nextFullReindex) {
+			continue
+		}
 		orgIDs = append(orgIDs, orgID)
 	}
 	i.mu.RUnlock()
-
+	orgIDs = append(orgIDs, i.unindexedOrgIDs(ctx)...)
+
+	if force {
+		// forced re-indexes follow changes the indexes miss, their results are flagged as stale
+		// until they are replaced, or a later rebuild succeeds
+		for _, orgID := range orgIDs {
+			if index, ok := i.getOrgIndex(orgID); ok {
+				index.setRebuilding(true)
+			}
+		}
+	}
 	for _, orgID := range orgIDs {
 		_, err := i.buildOrgIndex(ctx, orgID)
 		if err != nil {
@@ -582,6 +1023,28 @@ func (i *searchIndex) reIndexFromScratch(ctx context.Context) {
 	}
 }
 
+// warmOrgIndexes runs the warm queries of the organizations with an index in background, one
+// organization after the other.
+func (i *searchIndex) warmOrgIndexes(trigger string) {
+	if i.warmQueries == nil {
+		return
+	}
+	i.mu.RLock()
+	indexes := make(map[int64]*orgIndex, len(i.perOrgIndex))
+	for orgID, index := range i.perOrgIndex {
+		indexes[orgID] = index
+	}
+	i.mu.RUnlock()
+
+	go func() {
+		for orgID, index := range indexes {
+			if _, err := i.warmQueries.warm(context.Background(), orgID, index, trigger); err != nil {
+				i.logger.Warn("Failed to run warm queries", "orgId", orgID, "trigger", trigger, "error", err)
+			}
+		}
+	}()
+}
+
 func (i *searchIndex) withCtxData(ctx context.Context, params ...interface{}) []interface{} {
 	traceID := tracing.TraceIDFromContext(ctx, false)
 	if traceID != "" {
@@ -621,6 +1084,9 @@ func (i *searchIndex) applyIndexUpdates(ctx context.Context, lastEventID int64)
 func (i *searchIndex) applyEventOnIndex(ctx context.Context, e *store.EntityEvent) error {
 	i.logger.Debug("processing event", "event", e)
 
+	if strings.HasPrefix(e.EntityId, "storage/") {
+		return i.applyStorageEventOnIndex(ctx, e)
+	}
 	if !strings.HasPrefix(e.EntityId, "database/") {
 		i.logger.Warn("unknown storage", "entityId", e.EntityId)
 		return nil
@@ -642,6 +1108,27 @@ func (i *searchIndex) applyEventOnIndex(ctx context.Context, e *store.EntityEven
 	return i.applyEvent(ctx, orgID, kind, uid, e.EventType)
 }
 
+// applyStorageEventOnIndex applies events of dashboards stored in the storage service,
+// which are only indexed when dashboards are loaded from storage.
+func (i *searchIndex) applyStorageEventOnIndex(ctx context.Context, e *store.EntityEvent) error {
+	// storage/org/path*
+	parts := strings.SplitN(strings.TrimPrefix(e.EntityId, "storage/"), "/", 2)
+	if len(parts) != 2 {
+		i.logger.Error("can't parse entityId", "entityId", e.EntityId)
+		return nil
+	}
+	orgID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		i.logger.Error("can't extract org ID", "entityId", e.EntityId)
+		return nil
+	}
+	kind := store.EntityTypeFolder
+	if strings.HasSuffix(parts[1], ".json") {
+		kind = store.EntityTypeDashboard
+	}
+	return i.applyEvent(ctx, orgID, kind, parts[1], e.EventType)
+}
+
 func (i *searchIndex) applyEvent(ctx context.Context, orgID int64, kind store.EntityType, uid string, _ store.EntityEventType) error {
 	i.mu.Lock()
 	_, ok := i.perOrgIndex[orgID]
@@ -657,6 +1144,17 @@ func (i *searchIndex) applyEvent(ctx context.Context, orgID int64, kind store.En
 	if err != nil {
 		return err
 	}
+	i.labelDashboards(ctx, orgID, dbDashboards)
+	i.enrichDashboards(ctx, orgID, dbDashboards)
+	i.embedDashboards(ctx, orgID, dbDashboards)
+
+	var move *folderMove
+	if kind == store.EntityTypeFolder && len(dbDashboards) > 0 && dbDashboards[0].isFolder {
+		move, err = i.loadFolderMove(ctx, orgID, dbDashboards[0])
+		if err != nil {
+			return err
+		}
+	}
 
 	i.mu.Lock()
 	defer i.mu.Unlock()
@@ -679,13 +1177,101 @@ func (i *searchIndex) applyEvent(ctx context.Context, orgID int64, kind store.En
 		}
 	} else {
 		err = i.updateDashboard(ctx, orgID, index, dbDashboards[0])
+		if err == nil && !dbDashboards[0].isFolder {
+			index.vectors.set(uid, dbDashboards[0].embedding)
+		}
+		if err == nil && move != nil {
+			err = i.moveFolderDashboards(ctx, orgID, index, move)
+		}
 	}
+	index.markUpdated(time.Now())
+	i.deniedCache.invalidateOrg(orgID)
+	i.updateIndexErrors(orgID, uid, dbDashboards)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// folderMove holds the dashboards of a folder whose UID changed, which are still indexed
+// under the previous UID of the folder.
+type folderMove struct {
+	folderUID  string
+	dashboards []dashboard
+	// previous UIDs of the folder, whose documents are removed
+	previousUIDs []string
+}
+
+// loadFolderMove returns the dashboards to move when a folder changed its UID, which is the
+// case when the folder is not indexed under its UID yet but has dashboards. It returns nil
+// for folders which kept their UID, so that renaming a folder only updates its document.
+func (i *searchIndex) loadFolderMove(ctx context.Context, orgID int64, folder dashboard) (*folderMove, error) {
+	loader, ok := i.loader.(folderDashboardLoader)
+	if !ok || folder.id == 0 {
+		return nil, nil
+	}
+
+	i.mu.Lock()
+	index, ok := i.perOrgIndex[orgID]
+	i.mu.Unlock()
+	if !ok {
+		return nil, nil
+	}
+	if indexed, err := isFolderIndexed(index, folder.uid); err != nil || indexed {
+		return nil, err
+	}
+
+	dashboards, err := loader.LoadFolderDashboards(ctx, orgID, folder.id)
+	if err != nil || len(dashboards) == 0 {
+		return nil, err
+	}
+	i.labelDashboards(ctx, orgID, dashboards)
+	i.enrichDashboards(ctx, orgID, dashboards)
+	i.embedDashboards(ctx, orgID, dashboards)
+
+	move := &folderMove{folderUID: folder.uid, dashboards: dashboards}
+	previous := map[string]struct{}{}
+	for _, dash := range dashboards {
+		location, ok, err := getDashboardLocation(index, dash.uid)
+		if err != nil {
+			return nil, err
+		}
+		if !ok || location == folder.uid {
+			continue
+		}
+		if _, seen := previous[location]; seen {
+			continue
+		}
+		previous[location] = struct{}{}
+		// the dashboards may also come from a folder which still exists
+		existing, err := i.loader.LoadDashboards(ctx, orgID, location)
+		if err != nil {
+			return nil, err
+		}
+		if len(existing) == 0 {
+			move.previousUIDs = append(move.previousUIDs, location)
+		}
+	}
+	return move, nil
+}
+
+// moveFolderDashboards re-indexes the dashboards of a folder whose UID changed under its new
+// UID in a single batch, and removes the documents of the folder under its previous UIDs.
+func (i *searchIndex) moveFolderDashboards(ctx context.Context, orgID int64, index *orgIndex, move *folderMove) error {
+	batch := bluge.NewBatch()
+	for _, dash := range move.dashboards {
+		dash.folderUID = move.folderUID
+		if err := i.batchDashboardUpdate(ctx, orgID, index, batch, dash); err != nil {
+			return err
+		}
+	}
+	for _, uid := range move.previousUIDs {
+		batch.Delete(bluge.NewDocument(uid).ID())
+	}
+	i.logger.Info("Moved folder dashboards", "orgId", orgID, "folderUID", move.folderUID, "previousUIDs", move.previousUIDs, "numDashboards", len(move.dashboards))
+	return index.writerForIndex(indexTypeDashboard).Batch(batch)
+}
+
 func (i *searchIndex) removeDashboard(_ context.Context, index *orgIndex, dashboardUID string) error {
 	dashboardLocation, ok, err := getDashboardLocation(index, dashboardUID)
 	if err != nil {
@@ -714,7 +1300,11 @@ func (i *searchIndex) removeDashboard(_ context.Context, index *orgIndex, dashbo
 		batch.Delete(bluge.NewDocument(panelID).ID())
 	}
 
-	return writer.Batch(batch)
+	if err := writer.Batch(batch); err != nil {
+		return err
+	}
+	index.vectors.remove(dashboardUID)
+	return nil
 }
 
 func (i *searchIndex) removeFolder(_ context.Context, index *orgIndex, folderUID string) error {
@@ -729,7 +1319,11 @@ func (i *searchIndex) removeFolder(_ context.Context, index *orgIndex, folderUID
 		batch.Delete(bluge.NewDocument(id).ID())
 	}
 	writer := index.writerForIndex(indexTypeDashboard)
-	return writer.Batch(batch)
+	if err := writer.Batch(batch); err != nil {
+		return err
+	}
+	index.vectors.remove(ids...)
+	return nil
 }
 
 func stringInSlice(str string, slice []string) bool {
@@ -742,13 +1336,11 @@ func stringInSlice(str string, slice []string) bool {
 }
 
 func (i *searchIndex) updateDashboard(ctx context.Context, orgID int64, index *orgIndex, dash dashboard) error {
-	extendDoc := i.extender.GetDashboardExtender(orgID, dash.uid)
-
 	writer := index.writerForIndex(indexTypeDashboard)
 
-	var doc *bluge.Document
 	if dash.isFolder {
-		doc = getFolderDashboardDoc(dash)
+		extendDoc := i.extender.GetDashboardExtender(orgID, dash.uid)
+		doc := getFolderDashboardDoc(dash)
 		if err := extendDoc(dash.uid, doc); err != nil {
 			return err
 		}
@@ -756,9 +1348,21 @@ func (i *searchIndex) updateDashboard(ctx context.Context, orgID int64, index *o
 	}
 
 	batch := bluge.NewBatch()
+	if err := i.batchDashboardUpdate(ctx, orgID, index, batch, dash); err != nil {
+		return err
+	}
+	return writer.Batch(batch)
+}
+
+// batchDashboardUpdate adds the documents of a dashboard and its panels to the batch, and
+// removes the panels which are gone, including the ones indexed under a previous folder.
+func (i *searchIndex) batchDashboardUpdate(ctx context.Context, orgID int64, index *orgIndex, batch *blugeindex.Batch, dash dashboard) error {
+	extendDoc := i.extender.GetDashboardExtender(orgID, dash.uid)
 
 	var folderUID string
-	if dash.folderID == 0 {
+	if dash.folderUID != "" {
+		folderUID = dash.folderUID
+	} else if dash.folderID == 0 {
 		folderUID = "general"
 	} else {
 		var err error
@@ -769,37 +1373,54 @@ func (i *searchIndex) updateDashboard(ctx context.Context, orgID int64, index *o
 	}
 
 	location := folderUID
-	doc = getNonFolderDashboardDoc(dash, location)
+	doc := getDashboardDoc(dash, location, index.mode)
 	if err := extendDoc(dash.uid, doc); err != nil {
 		return err
 	}
+	if index.mode == indexModeSparse {
+		batch.Update(doc.ID(), doc)
+		return nil
+	}
 
 	var actualPanelIDs []string
 
-	if location != "" {
-		location += "/"
-	}
-	location += dash.uid
-	panelDocs := getDashboardPanelDocs(dash, location)
+	panelLocation := dashboardPanelLocation(location, dash.uid)
+	panelDocs := getDashboardPanelDocs(dash, panelLocation)
 	for _, panelDoc := range panelDocs {
 		actualPanelIDs = append(actualPanelIDs, string(panelDoc.ID().Term()))
 		batch.Update(panelDoc.ID(), panelDoc)
 	}
 
-	indexedPanelIDs, err := getDashboardPanelIDs(index, location)
+	panelLocations := []string{panelLocation}
+	previousLocation, ok, err := getDashboardLocation(index, dash.uid)
 	if err != nil {
 		return err
 	}
-
-	for _, panelID := range indexedPanelIDs {
-		if !stringInSlice(panelID, actualPanelIDs) {
-			batch.Delete(bluge.NewDocument(panelID).ID())
+	if ok && previousLocation != location {
+		panelLocations = append(panelLocations, dashboardPanelLocation(previousLocation, dash.uid))
+	}
+	for _, l := range panelLocations {
+		indexedPanelIDs, err := getDashboardPanelIDs(index, l)
+		if err != nil {
+			return err
+		}
+		for _, panelID := range indexedPanelIDs {
+			if !stringInSlice(panelID, actualPanelIDs) {
+				batch.Delete(bluge.NewDocument(panelID).ID())
+			}
 		}
 	}
 
 	batch.Update(doc.ID(), doc)
+	return nil
+}
 
-	return writer.Batch(batch)
+// dashboardPanelLocation returns the location of the panels of a dashboard in a folder.
+func dashboardPanelLocation(folderLocation string, dashboardUID string) string {
+	if folderLocation == "" {
+		return dashboardUID
+	}
+	return folderLocation + "/" + dashboardUID
 }
 
 type sqlDashboardLoader struct {
@@ -895,21 +1516,7 @@ func (l sqlDashboardLoader) LoadDashboards(ctx context.Context, orgID int64, das
 		readDashboardSpan.SetAttributes("dashboardCount", len(rows), attribute.Key("dashboardCount").Int(len(rows)))
 
 		for _, row := range rows {
-			info, err := extract.ReadDashboard(bytes.NewReader(row.Data), lookup)
-			if err != nil {
-				l.logger.Warn("Error indexing dashboard data", "error", err, "dashboardId", row.Id, "dashboardSlug", row.Slug)
-				// But append info anyway for now, since we possibly extracted useful information.
-			}
-			dashboards = append(dashboards, dashboard{
-				id:       row.Id,
-				uid:      row.Uid,
-				isFolder: row.IsFolder,
-				folderID: row.FolderID,
-				slug:     row.Slug,
-				created:  row.Created,
-				updated:  row.Updated,
-				info:     info,
-			})
+			dashboards = append(dashboards, l.readDashboard(row, lookup))
 			lastID = row.Id
 		}
 		readDashboardSpan.End()
@@ -919,9 +1526,101 @@ func (l sqlDashboardLoader) LoadDashboards(ctx context.Context, orgID int64, das
 		}
 	}
 
+	folderTeams, err := loadFolderTeams(ctx, l.sql, orgID)
+	if err != nil {
+		return nil, err
+	}
+	provisioning, err := loadProvisioningSources(ctx, l.sql, orgID)
+	if err != nil {
+		return nil, err
+	}
+	for i, dash := range dashboards {
+		if dash.isFolder {
+			dashboards[i].teams = folderTeams[dash.id]
+		} else {
+			dashboards[i].teams = folderTeams[dash.folderID]
+			dashboards[i].provisioning = provisioning[dash.id]
+		}
+	}
+
 	return dashboards, err
 }
 
+// LoadFolderDashboards returns the dashboards of a folder, loaded in batches.
+func (l sqlDashboardLoader) LoadFolderDashboards(ctx context.Context, orgID int64, folderID int64) ([]dashboard, error) {
+	ctx, span := l.tracer.Start(ctx, "sqlDashboardLoader LoadFolderDashboards")
+	span.SetAttributes("orgID", orgID, attribute.Key("orgID").Int64(orgID))
+	span.SetAttributes("folderID", folderID, attribute.Key("folderID").Int64(folderID))
+	defer span.End()
+
+	lookup, err := dslookup.LoadDatasourceLookup(ctx, orgID, l.sql)
+	if err != nil {
+		return nil, err
+	}
+	folderTeams, err := loadFolderTeams(ctx, l.sql, orgID)
+	if err != nil {
+		return nil, err
+	}
+	provisioning, err := loadProvisioningSources(ctx, l.sql, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := l.settings.DashboardLoadingBatchSize
+	if limit <= 0 {
+		limit = 200
+	}
+	var dashboards []dashboard
+	var lastID int64
+	for {
+		rows := make([]dashboardQueryResult, 0, limit)
+		err := l.sql.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+			sess.Table("dashboard").
+				Where("org_id = ? AND folder_id = ? AND is_folder = ?", orgID, folderID, l.sql.Dialect.BooleanStr(false))
+			if lastID > 0 {
+				sess.Where("id > ?", lastID)
+			}
+			sess.Cols("id", "uid", "is_folder", "folder_id", "data", "slug", "created", "updated")
+			sess.OrderBy("id ASC")
+			sess.Limit(limit)
+			return sess.Find(&rows)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, row := range rows {
+			dash := l.readDashboard(row, lookup)
+			dash.teams = folderTeams[folderID]
+			dash.provisioning = provisioning[dash.id]
+			dashboards = append(dashboards, dash)
+			lastID = row.Id
+		}
+		if len(rows) < limit {
+			break
+		}
+	}
+	return dashboards, nil
+}
+
+func (l sqlDashboardLoader) readDashboard(row dashboardQueryResult, lookup dslookup.DatasourceLookup) dashboard {
+	info, indexErr := readDashboardInfo(row.Data, row.Slug, lookup, l.settings.MaxDashboardSize)
+	if indexErr != nil {
+		l.logger.Warn("Error indexing dashboard data", "error", indexErr.message, "errorType", indexErr.kind, "dashboardId", row.Id, "dashboardSlug", row.Slug)
+	}
+	return dashboard{
+		id:       row.Id,
+		uid:      row.Uid,
+		isFolder: row.IsFolder,
+		folderID: row.FolderID,
+		slug:     row.Slug,
+		created:  row.Created,
+		updated:  row.Updated,
+		info:     info,
+		indexErr: indexErr,
+	}
+}
+
 func newFolderIDLookup(sql *sqlstore.SQLStore) folderUIDLookup {
 	return func(ctx context.Context, folderID int64) (string, error) {
 		uid := ""
@@ -0,0 +1,242 @@
+package searchV2
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/blugelabs/bluge"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+)
+
+var buildsInProgress = &checkpointedBuilds{lastCheckpoint: map[int64]time.Time{}}
+
+var dashboardSearchIndexCheckpointAge = promauto.NewGaugeFunc(
+	prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "dashboard_search_index_checkpoint_age_seconds",
+		Help:      "Seconds since the last checkpoint of the oldest org index build in progress, i.e. the work lost if Grafana stopped, 0 when no index is being built",
+	},
+	buildsInProgress.oldestCheckpointAge,
+)
+
+// indexCheckpoint is the state of an org index being built. Checkpoints are saved periodically
+// while building, so that a build interrupted by a crash resumes from the last one instead of
+// starting over.
+type indexCheckpoint struct {
+	Saved time.Time
+	Mode  indexMode
+	Items map[string]map[uint64][]byte
+	// Indexed holds the update time, in milliseconds, of the dashboards and folders in the
+	// index by UID. Dashboards are only listed once all their panels are indexed.
+	Indexed map[string]int64
+}
+
+func (p *indexPersister) checkpointFileName(orgID int64, idxType indexType) string {
+	return filepath.Join(p.path, fmt.Sprintf("org-%d.%s.checkpoint", orgID, idxType))
+}
+
+func (p *indexPersister) saveCheckpoint(ctx context.Context, orgID int64, idxType indexType, checkpoint *indexCheckpoint) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(checkpoint); err != nil {
+		return fmt.Errorf("error encoding checkpoint: %w", err)
+	}
+	return p.writeFile(ctx, p.checkpointFileName(orgID, idxType), buf.Bytes())
+}
+
+func (p *indexPersister) loadCheckpoint(ctx context.Context, orgID int64, idxType indexType) (*indexCheckpoint, error) {
+	data, _, err := p.readFile(ctx, p.checkpointFileName(orgID, idxType))
+	if err != nil {
+		return nil, err
+	}
+	checkpoint := &indexCheckpoint{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(checkpoint); err != nil {
+		return nil, fmt.Errorf("error decoding checkpoint: %w", err)
+	}
+	return checkpoint, nil
+}
+
+func (p *indexPersister) removeCheckpoint(orgID int64, idxType indexType) error {
+	err := os.Remove(p.checkpointFileName(orgID, idxType))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// buildCheckpoints saves the progress of an org index build every interval, and holds the
+// checkpoint the build resumes from, if any. A nil *buildCheckpoints disables checkpoints.
+type buildCheckpoints struct {
+	orgID    int64
+	mode     indexMode
+	interval time.Duration
+	save     func(*indexCheckpoint) error
+	logger   log.Logger
+
+	resume  *indexCheckpoint
+	indexed map[string]int64
+	last    time.Time
+}
+
+func newBuildCheckpoints(orgID int64, mode indexMode, interval time.Duration, resume *indexCheckpoint, save func(*indexCheckpoint) error, logger log.Logger) *buildCheckpoints {
+	c := &buildCheckpoints{
+		orgID:    orgID,
+		mode:     mode,
+		interval: interval,
+		save:     save,
+		logger:   logger,
+		resume:   resume,
+		indexed:  map[string]int64{},
+		last:     time.Now(),
+	}
+	if resume != nil {
+		for uid, updated := range resume.Indexed {
+			c.indexed[uid] = updated
+		}
+	}
+	buildsInProgress.checkpointed(orgID, c.last)
+	return c
+}
+
+// directory returns the directory the build starts from, with the documents of the checkpoint.
+func (c *buildCheckpoints) directory() *memoryDirectory {
+	if c == nil || c.resume == nil {
+		return newMemoryDirectory()
+	}
+	return newMemoryDirectoryFromItems(c.resume.Items)
+}
+
+// upToDate returns true if the dashboard or folder is indexed in the checkpoint the build resumed from.
+func (c *buildCheckpoints) upToDate(uid string, dash dashboard) bool {
+	if c == nil || c.resume == nil {
+		return false
+	}
+	updated, ok := c.indexed[uid]
+	return ok && updated == dash.updated.UnixMilli()
+}
+
+// removeStale deletes the dashboards and folders of the checkpoint which were changed or deleted
+// since it was saved, so that they are indexed again.
+func (c *buildCheckpoints) removeStale(index *orgIndex, dashboards []dashboard) error {
+	if c == nil || c.resume == nil {
+		return nil
+	}
+	current := make(map[string]int64, len(dashboards))
+	for _, dash := range dashboards {
+		current[checkpointUID(dash)] = dash.updated.UnixMilli()
+	}
+
+	batch := bluge.NewBatch()
+	for uid, updated := range c.indexed {
+		if currentUpdated, ok := current[uid]; ok && currentUpdated == updated {
+			continue
+		}
+		location, isDashboard, err := getDashboardLocation(index, uid)
+		if err != nil {
+			return err
+		}
+		if isDashboard {
+			panelLocation := uid
+			if location != "" {
+				panelLocation = location + "/" + uid
+			}
+			panelIDs, err := getDashboardPanelIDs(index, panelLocation)
+			if err != nil {
+				return err
+			}
+			for _, panelID := range panelIDs {
+				batch.Delete(bluge.NewDocument(panelID).ID())
+			}
+		}
+		batch.Delete(bluge.NewDocument(uid).ID())
+		delete(c.indexed, uid)
+	}
+	return index.writerForIndex(indexTypeDashboard).Batch(batch)
+}
+
+// indexedDoc records that a dashboard and its panels, or a folder, were added to the batch.
+func (c *buildCheckpoints) indexedDoc(uid string, dash dashboard) {
+	if c == nil {
+		return
+	}
+	c.indexed[uid] = dash.updated.UnixMilli()
+}
+
+// due returns true when a checkpoint should be saved. The batch must be flushed before saving.
+func (c *buildCheckpoints) due() bool {
+	return c != nil && c.interval > 0 && time.Since(c.last) >= c.interval
+}
+
+// checkpoint saves the documents flushed to the index. Failing to save a checkpoint doesn't fail
+// the build, which can still complete.
+func (c *buildCheckpoints) checkpoint(index *orgIndex) {
+	now := time.Now()
+	err := c.save(&indexCheckpoint{
+		Saved:   now,
+		Mode:    c.mode,
+		Items:   index.directories[indexTypeDashboard].copyItems(),
+		Indexed: c.indexed,
+	})
+	c.last = now
+	if err != nil {
+		c.logger.Warn("Failed to save org index checkpoint", "orgId", c.orgID, "error", err)
+		return
+	}
+	buildsInProgress.checkpointed(c.orgID, now)
+}
+
+// finish stops tracking the checkpoint age of the build.
+func (c *buildCheckpoints) finish() {
+	if c == nil {
+		return
+	}
+	buildsInProgress.finished(c.orgID)
+}
+
+func checkpointUID(dash dashboard) string {
+	if dash.isFolder && dash.uid == "" {
+		return "general"
+	}
+	return dash.uid
+}
+
+// checkpointedBuilds tracks the last checkpoint of the org index builds in progress, the start
+// of the build until one is saved.
+type checkpointedBuilds struct {
+	mu             sync.Mutex
+	lastCheckpoint map[int64]time.Time
+}
+
+func (b *checkpointedBuilds) checkpointed(orgID int64, at time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastCheckpoint[orgID] = at
+}
+
+func (b *checkpointedBuilds) finished(orgID int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.lastCheckpoint, orgID)
+}
+
+func (b *checkpointedBuilds) oldestCheckpointAge() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var age time.Duration
+	for _, at := range b.lastCheckpoint {
+		if since := time.Since(at); since > age {
+			age = since
+		}
+	}
+	return age.Seconds()
+}
@@ -0,0 +1,120 @@
+package searchV2
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/searchV2/extract"
+	"github.com/grafana/grafana/pkg/services/store"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func checkpointTestDashboards(updated time.Time) []dashboard {
+	return []dashboard{
+		{id: 1, uid: "ops", isFolder: true, updated: updated, info: &extract.DashboardInfo{Title: "Ops"}},
+		{id: 2, uid: "nodes", folderID: 1, updated: updated, info: &extract.DashboardInfo{Title: "Nodes", Panels: []extract.PanelInfo{{ID: 1, Title: "CPU"}, {ID: 2, Title: "Memory"}}}},
+		{id: 3, uid: "pods", folderID: 1, updated: updated, info: &extract.DashboardInfo{Title: "Pods", Panels: []extract.PanelInfo{{ID: 1, Title: "Restarts"}}}},
+		{id: 4, uid: "billing", updated: updated, info: &extract.DashboardInfo{Title: "Billing", Panels: []extract.PanelInfo{{ID: 1, Title: "Costs"}}}},
+		{id: 5, uid: "latency", updated: updated, info: &extract.DashboardInfo{Title: "Latency"}},
+	}
+}
+
+func indexedNames(t *testing.T, index *orgIndex) []string {
+	t.Helper()
+	resp := doSearchQuery(context.Background(), testLogger, index, testAllowAllFilter, DashboardQuery{Limit: 100}, &NoopQueryExtender{}, "")
+	require.NoError(t, resp.Error)
+	field, _ := resp.Frames[0].FieldByName(documentFieldName)
+	names := make([]string, 0, field.Len())
+	for i := 0; i < field.Len(); i++ {
+		names = append(names, field.At(i).(string))
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestIndexCheckpoints(t *testing.T) {
+	ctx := context.Background()
+	updated := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	// interruptedBuild builds an index saving a checkpoint after every dashboard, and keeps the
+	// checkpoint saved after the given number of dashboards and folders, as if Grafana crashed then.
+	interruptedBuild := func(t *testing.T, persister *indexPersister, dashboards []dashboard, after int) {
+		t.Helper()
+		saved := 0
+		checkpoints := newBuildCheckpoints(testOrgID, indexModeFull, time.Nanosecond, nil, func(checkpoint *indexCheckpoint) error {
+			saved++
+			if saved > after {
+				return errors.New("crashed")
+			}
+			return persister.saveCheckpoint(ctx, testOrgID, indexTypeDashboard, checkpoint)
+		}, testLogger)
+		_, err := initOrgIndex(dashboards, testLogger, NoopDocumentExtender{}.GetDashboardExtender(testOrgID), indexModeFull, checkpoints)
+		checkpoints.finish()
+		require.NoError(t, err)
+	}
+
+	resumedBuild := func(t *testing.T, persister *indexPersister, dashboards []dashboard) (*orgIndex, *indexCheckpoint) {
+		t.Helper()
+		resume, err := persister.loadCheckpoint(ctx, testOrgID, indexTypeDashboard)
+		require.NoError(t, err)
+		checkpoints := newBuildCheckpoints(testOrgID, indexModeFull, 0, resume, nil, testLogger)
+		index, err := initOrgIndex(dashboards, testLogger, NoopDocumentExtender{}.GetDashboardExtender(testOrgID), indexModeFull, checkpoints)
+		checkpoints.finish()
+		require.NoError(t, err)
+		return index, resume
+	}
+
+	t.Run("resumed build indexes the remaining dashboards", func(t *testing.T) {
+		persister := newIndexPersister(t.TempDir(), true, xorSecretsService{})
+		dashboards := checkpointTestDashboards(updated)
+		interruptedBuild(t, persister, dashboards, 3)
+
+		index, resume := resumedBuild(t, persister, dashboards)
+		require.Equal(t, map[string]int64{"ops": updated.UnixMilli(), "nodes": updated.UnixMilli(), "pods": updated.UnixMilli()}, resume.Indexed)
+
+		full, err := initOrgIndex(dashboards, testLogger, NoopDocumentExtender{}.GetDashboardExtender(testOrgID), indexModeFull, nil)
+		require.NoError(t, err)
+		require.Equal(t, countIndexDocs(t, full), countIndexDocs(t, index))
+		require.Equal(t, indexedNames(t, full), indexedNames(t, index))
+	})
+
+	t.Run("dashboards changed since the checkpoint are indexed again", func(t *testing.T) {
+		persister := newIndexPersister(t.TempDir(), false, nil)
+		interruptedBuild(t, persister, checkpointTestDashboards(updated), 4)
+
+		dashboards := checkpointTestDashboards(updated)
+		// nodes lost a panel and was renamed, pods was deleted
+		dashboards[1].updated = updated.Add(time.Minute)
+		dashboards[1].info = &extract.DashboardInfo{Title: "Kubernetes nodes", Panels: []extract.PanelInfo{{ID: 1, Title: "CPU"}}}
+		dashboards = append(dashboards[:2], dashboards[3:]...)
+
+		index, _ := resumedBuild(t, persister, dashboards)
+		full, err := initOrgIndex(dashboards, testLogger, NoopDocumentExtender{}.GetDashboardExtender(testOrgID), indexModeFull, nil)
+		require.NoError(t, err)
+		require.Equal(t, countIndexDocs(t, full), countIndexDocs(t, index))
+		require.Equal(t, indexedNames(t, full), indexedNames(t, index))
+		require.Contains(t, indexedNames(t, index), "CPU")
+		require.NotContains(t, indexedNames(t, index), "Memory")
+	})
+
+	t.Run("checkpoint is removed once the index is built", func(t *testing.T) {
+		persister := newIndexPersister(t.TempDir(), false, nil)
+		interruptedBuild(t, persister, checkpointTestDashboards(updated), 2)
+
+		settings := setting.SearchSettings{FullReindexInterval: time.Hour, IndexCheckpointInterval: time.Minute}
+		searchIdx := newSearchIndex(&testDashboardLoader{dashboards: checkpointTestDashboards(updated)}, &store.MockEntityEventsService{}, &NoopDocumentExtender{}, func(ctx context.Context, folderId int64) (string, error) { return "ops", nil }, tracing.InitializeTracerForTest(), featuremgmt.WithFeatures(), settings, persister)
+		_, err := searchIdx.buildOrgIndex(ctx, testOrgID)
+		require.NoError(t, err)
+
+		_, err = persister.loadCheckpoint(ctx, testOrgID, indexTypeDashboard)
+		require.ErrorIs(t, err, errPersistedIndexNotFound)
+		require.Equal(t, float64(0), buildsInProgress.oldestCheckpointAge())
+	})
+}
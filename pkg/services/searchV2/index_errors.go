@@ -0,0 +1,116 @@
+package searchV2
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/grafana/grafana/pkg/services/searchV2/dslookup"
+	"github.com/grafana/grafana/pkg/services/searchV2/extract"
+)
+
+// Types of the errors of dashboards which couldn't be fully indexed, see IndexErrorReport.
+const (
+	// IndexErrorMalformedJSON dashboards are indexed with what could be read before the error
+	IndexErrorMalformedJSON = "malformedJson"
+	// IndexErrorOversized dashboards are over setting.SearchSettings.MaxDashboardSize, they are
+	// only indexed with their slug as title
+	IndexErrorOversized = "oversized"
+)
+
+// maxReportedIndexErrors caps the dashboards listed by the index error report of an organization,
+// its counts include all of them.
+const maxReportedIndexErrors = 100
+
+// IndexErrorReport lists the dashboards of an organization which couldn't be fully indexed at the
+// last build of its index or since, so that admins can fix the dashboards missing from results.
+type IndexErrorReport struct {
+	Total  int            `json:"total"`
+	Counts map[string]int `json:"counts"` // by error type
+	// Dashboards are the first dashboards by UID, Truncated is set when there are more
+	Dashboards []IndexError `json:"dashboards"`
+	Truncated  bool         `json:"truncated,omitempty"`
+}
+
+type IndexError struct {
+	UID   string `json:"uid"`
+	Type  string `json:"type"`
+	Error string `json:"error"`
+}
+
+// dashboardIndexError is why a dashboard couldn't be fully indexed.
+type dashboardIndexError struct {
+	kind    string
+	message string
+}
+
+// readDashboardInfo reads the info of a dashboard to index. Dashboards over maxSize bytes are not
+// read, maxSize 0 means unlimited.
+func readDashboardInfo(data []byte, slug string, lookup dslookup.DatasourceLookup, maxSize int) (*extract.DashboardInfo, *dashboardIndexError) {
+	if maxSize > 0 && len(data) > maxSize {
+		return &extract.DashboardInfo{Title: slug}, &dashboardIndexError{
+			kind:    IndexErrorOversized,
+			message: fmt.Sprintf("dashboard is %d bytes, over the limit of %d bytes", len(data), maxSize),
+		}
+	}
+	info, err := extract.ReadDashboard(bytes.NewReader(data), lookup)
+	if err != nil {
+		// info is indexed anyway, since it possibly holds useful information
+		return info, &dashboardIndexError{kind: IndexErrorMalformedJSON, message: err.Error()}
+	}
+	return info, nil
+}
+
+// orgIndexErrors returns the errors of the dashboards of a full build, by UID.
+func orgIndexErrors(dashboards []dashboard) map[string]*dashboardIndexError {
+	errs := map[string]*dashboardIndexError{}
+	for _, dash := range dashboards {
+		if dash.indexErr != nil {
+			errs[dash.uid] = dash.indexErr
+		}
+	}
+	return errs
+}
+
+// updateIndexErrors records the errors of a dashboard indexed again, or removed when dashboards is
+// empty. It must be called with i.mu held.
+func (i *searchIndex) updateIndexErrors(orgID int64, uid string, dashboards []dashboard) {
+	errs, ok := i.indexErrors[orgID]
+	if !ok {
+		return
+	}
+	delete(errs, uid)
+	for _, dash := range dashboards {
+		if dash.indexErr != nil {
+			errs[dash.uid] = dash.indexErr
+		}
+	}
+}
+
+// indexErrorReport returns the index error report of an organization, nil until its index is built.
+// It must be called with i.mu held.
+func (i *searchIndex) indexErrorReport(orgID int64) *IndexErrorReport {
+	errs, ok := i.indexErrors[orgID]
+	if !ok {
+		return nil
+	}
+	report := &IndexErrorReport{
+		Total:      len(errs),
+		Counts:     map[string]int{},
+		Dashboards: []IndexError{},
+	}
+	uids := make([]string, 0, len(errs))
+	for uid, err := range errs {
+		report.Counts[err.kind]++
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids)
+	if len(uids) > maxReportedIndexErrors {
+		uids = uids[:maxReportedIndexErrors]
+		report.Truncated = true
+	}
+	for _, uid := range uids {
+		report.Dashboards = append(report.Dashboards, IndexError{UID: uid, Type: errs[uid].kind, Error: errs[uid].message})
+	}
+	return report
+}
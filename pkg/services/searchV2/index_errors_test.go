@@ -0,0 +1,94 @@
+package searchV2
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/searchV2/dslookup"
+	"github.com/grafana/grafana/pkg/services/searchV2/extract"
+	"github.com/grafana/grafana/pkg/services/store"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestReadDashboardInfo(t *testing.T) {
+	lookup := dslookup.CreateDatasourceLookup(nil)
+
+	info, indexErr := readDashboardInfo([]byte(`{"title": "Latency"}`), "latency", lookup, 100)
+	require.Nil(t, indexErr)
+	require.Equal(t, "Latency", info.Title)
+
+	info, indexErr = readDashboardInfo([]byte(`{"title": "Latency", "panels": [{`), "latency", lookup, 0)
+	require.NotNil(t, indexErr)
+	require.Equal(t, IndexErrorMalformedJSON, indexErr.kind)
+	require.Equal(t, "Latency", info.Title, "what could be read is indexed")
+
+	info, indexErr = readDashboardInfo([]byte(`{"title": "Latency"}`), "latency", lookup, 10)
+	require.NotNil(t, indexErr)
+	require.Equal(t, IndexErrorOversized, indexErr.kind)
+	require.Equal(t, "latency", info.Title)
+}
+
+func TestIndexErrorReport(t *testing.T) {
+	ctx := context.Background()
+	malformed := &dashboardIndexError{kind: IndexErrorMalformedJSON, message: "unexpected end of JSON"}
+	loader := &testDashboardLoader{dashboards: []dashboard{
+		{id: 1, uid: "latency", info: &extract.DashboardInfo{Title: "Latency"}},
+		{id: 2, uid: "broken", info: &extract.DashboardInfo{Title: "Broken"}, indexErr: malformed},
+		{id: 3, uid: "huge", info: &extract.DashboardInfo{Title: "huge"}, indexErr: &dashboardIndexError{kind: IndexErrorOversized, message: "too large"}},
+	}}
+	index := newSearchIndex(loader, &store.MockEntityEventsService{}, &NoopDocumentExtender{}, nil, tracing.InitializeTracerForTest(), featuremgmt.WithFeatures(), setting.SearchSettings{}, nil)
+	require.Nil(t, index.getOrgStatus(testOrgID).IndexErrors)
+
+	_, err := index.buildOrgIndex(ctx, testOrgID)
+	require.NoError(t, err)
+	report := index.getOrgStatus(testOrgID).IndexErrors
+	require.Equal(t, 2, report.Total)
+	require.Equal(t, map[string]int{IndexErrorMalformedJSON: 1, IndexErrorOversized: 1}, report.Counts)
+	require.Equal(t, []IndexError{
+		{UID: "broken", Type: IndexErrorMalformedJSON, Error: "unexpected end of JSON"},
+		{UID: "huge", Type: IndexErrorOversized, Error: "too large"},
+	}, report.Dashboards)
+
+	t.Run("fixed dashboards leave the report", func(t *testing.T) {
+		loader.dashboards = []dashboard{{id: 2, uid: "broken", info: &extract.DashboardInfo{Title: "Broken"}}}
+		require.NoError(t, index.applyEvent(ctx, testOrgID, store.EntityTypeDashboard, "broken", store.EntityEventTypeUpdate))
+		report := index.getOrgStatus(testOrgID).IndexErrors
+		require.Equal(t, 1, report.Total)
+		require.Equal(t, map[string]int{IndexErrorOversized: 1}, report.Counts)
+	})
+
+	t.Run("broken dashboards enter the report", func(t *testing.T) {
+		loader.dashboards = []dashboard{{id: 1, uid: "latency", info: &extract.DashboardInfo{Title: "Latency"}, indexErr: malformed}}
+		require.NoError(t, index.applyEvent(ctx, testOrgID, store.EntityTypeDashboard, "latency", store.EntityEventTypeUpdate))
+		require.Equal(t, 2, index.getOrgStatus(testOrgID).IndexErrors.Total)
+	})
+
+	t.Run("deleted dashboards leave the report", func(t *testing.T) {
+		loader.dashboards = nil
+		require.NoError(t, index.applyEvent(ctx, testOrgID, store.EntityTypeDashboard, "huge", store.EntityEventTypeDelete))
+		report := index.getOrgStatus(testOrgID).IndexErrors
+		require.Equal(t, 1, report.Total)
+		require.Equal(t, "latency", report.Dashboards[0].UID)
+	})
+
+	t.Run("the listed dashboards are limited", func(t *testing.T) {
+		loader.dashboards = nil
+		for id := 0; id < maxReportedIndexErrors+5; id++ {
+			uid := fmt.Sprintf("broken-%03d", id)
+			loader.dashboards = append(loader.dashboards, dashboard{id: int64(id + 1), uid: uid, info: &extract.DashboardInfo{Title: uid}, indexErr: malformed})
+		}
+		_, err := index.buildOrgIndex(ctx, testOrgID)
+		require.NoError(t, err)
+		report := index.getOrgStatus(testOrgID).IndexErrors
+		require.Equal(t, maxReportedIndexErrors+5, report.Total)
+		require.Equal(t, maxReportedIndexErrors+5, report.Counts[IndexErrorMalformedJSON])
+		require.Len(t, report.Dashboards, maxReportedIndexErrors)
+		require.True(t, report.Truncated)
+		require.Equal(t, "broken-000", report.Dashboards[0].UID)
+	})
+}
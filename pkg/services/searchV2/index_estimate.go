@@ -0,0 +1,163 @@
+package searchV2
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// indexEstimateSampleSize is the number of dashboards indexed to estimate the index of an organization.
+const indexEstimateSampleSize = 200
+
+// IndexEstimate is the estimated index of an organization in each mode and placement, see
+// SearchService.EstimateIndex.
+type IndexEstimate struct {
+	OrgID          int64 `json:"orgId"`
+	DashboardCount int   `json:"dashboardCount"`
+	FolderCount    int   `json:"folderCount"`
+	PanelCount     int   `json:"panelCount"`
+	// SampleSize is the number of dashboards indexed, which the estimates are extrapolated from
+	SampleSize int                 `json:"sampleSize"`
+	LoadTime   time.Duration       `json:"loadTime"` // time it took to load the dashboards
+	Estimates  []IndexModeEstimate `json:"estimates"`
+}
+
+// IndexModeEstimate is the estimated index of an organization in one mode and placement.
+type IndexModeEstimate struct {
+	Mode      string `json:"mode"`      // full or sparse
+	Placement string `json:"placement"` // memory or disk
+	// Current is set on the mode and placement the organization gets with the current settings
+	Current   bool          `json:"current"`
+	BuildTime time.Duration `json:"buildTime"` // including the time to load the dashboards
+	// HeapBytes is the memory held by the index. Indexes on disk are paged in by the OS when read,
+	// so their memory depends on the searches and is left out.
+	HeapBytes uint64 `json:"heapBytes"`
+	DiskBytes uint64 `json:"diskBytes"`
+	HeapSize  string `json:"heapSize"`
+	DiskSize  string `json:"diskSize"`
+}
+
+// estimateOrgIndex estimates the index of an organization without building it. A sample of the
+// dashboards is indexed in each mode and placement, and the measures scaled to all the dashboards.
+// Dashboards are neither enriched nor embedded, which would call the enrichers and embedding providers.
+func (i *searchIndex) estimateOrgIndex(ctx context.Context, orgID int64) (*IndexEstimate, error) {
+	started := time.Now()
+	dashboards, err := i.loader.LoadDashboards(ctx, orgID, "")
+	if err != nil {
+		return nil, fmt.Errorf("error loading dashboards: %w", err)
+	}
+	estimate := &IndexEstimate{
+		OrgID:          orgID,
+		DashboardCount: len(dashboards),
+		LoadTime:       time.Since(started),
+	}
+	for _, dash := range dashboards {
+		if dash.isFolder {
+			estimate.FolderCount++
+		} else if dash.info != nil {
+			estimate.PanelCount += len(dash.info.Panels)
+		}
+	}
+
+	sample := sampleDashboards(dashboards, indexEstimateSampleSize)
+	estimate.SampleSize = len(sample)
+	scale := 0.0
+	if len(sample) > 0 {
+		scale = float64(len(dashboards)) / float64(len(sample))
+	}
+
+	extendDoc := i.extender.GetDashboardExtender(orgID)
+	currentMode := i.indexModeFor(len(dashboards))
+	currentPlacement := i.indexPlacementFor(len(dashboards))
+	for _, placement := range []indexPlacement{indexPlacementMemory, indexPlacementDisk} {
+		for _, mode := range []indexMode{indexModeFull, indexModeSparse} {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			buildTime, size, err := i.measureSampleIndex(orgID, sample, extendDoc, mode, placement)
+			if err != nil {
+				return nil, fmt.Errorf("error indexing %s %s sample: %w", mode, placement, err)
+			}
+			e := IndexModeEstimate{
+				Mode:      string(mode),
+				Placement: string(placement),
+				Current:   mode == currentMode && placement == currentPlacement,
+				BuildTime: estimate.LoadTime + time.Duration(float64(buildTime)*scale),
+			}
+			bytes := uint64(float64(size) * scale)
+			if placement == indexPlacementDisk {
+				e.DiskBytes = bytes
+			} else {
+				e.HeapBytes = bytes
+			}
+			e.HeapSize = formatBytes(e.HeapBytes)
+			e.DiskSize = formatBytes(e.DiskBytes)
+			estimate.Estimates = append(estimate.Estimates, e)
+		}
+	}
+	return estimate, nil
+}
+
+// measureSampleIndex indexes the dashboards in a throwaway index, and returns the time it took and
+// the size of the index.
+func (i *searchIndex) measureSampleIndex(orgID int64, dashboards []dashboard, extendDoc ExtendDashboardFunc, mode indexMode, placement indexPlacement) (time.Duration, uint64, error) {
+	started := time.Now()
+	if placement == indexPlacementDisk {
+		path := i.settings.DiskIndexPath
+		if path == "" {
+			path = os.TempDir()
+		}
+		index, err := openDiskOrgIndex(path, orgID)
+		if err != nil {
+			return 0, 0, err
+		}
+		defer func() {
+			if err := os.RemoveAll(index.diskPath); err != nil {
+				i.logger.Warn("Failed to remove sample index directory", "path", index.diskPath, "error", err)
+			}
+		}()
+		index.mode = mode
+		err = fillOrgIndex(index, dashboards, i.logger, extendDoc, nil)
+		// closing the writers persists the segments
+		for _, w := range index.writers {
+			_ = w.Close()
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		elapsed := time.Since(started)
+		size, err := dirSize(index.diskPath)
+		if err != nil {
+			return 0, 0, err
+		}
+		return elapsed, uint64(size), nil
+	}
+
+	dir := newMemoryDirectory()
+	index, err := openOrgIndex(dir)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer index.close(i.logger)
+	index.mode = mode
+	if err := fillOrgIndex(index, dashboards, i.logger, extendDoc, nil); err != nil {
+		return 0, 0, err
+	}
+	_, size := dir.Stats()
+	return time.Since(started), size, nil
+}
+
+// sampleDashboards returns up to n dashboards evenly spread over the given dashboards, keeping
+// their order so that folders and dashboards are sampled alike.
+func sampleDashboards(dashboards []dashboard, n int) []dashboard {
+	if len(dashboards) <= n {
+		return dashboards
+	}
+	sample := make([]dashboard, 0, n)
+	step := float64(len(dashboards)) / float64(n)
+	for k := 0; k < n; k++ {
+		sample = append(sample, dashboards[int(float64(k)*step)])
+	}
+	return sample
+}
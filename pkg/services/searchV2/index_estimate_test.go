@@ -0,0 +1,61 @@
+package searchV2
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/store"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestEstimateOrgIndex(t *testing.T) {
+	path := t.TempDir()
+	loader := &testDashboardLoader{dashboards: testPermissionDashboards(500)}
+	settings := setting.SearchSettings{DiskIndexDashboardThreshold: 100, DiskIndexPath: path}
+	index := newSearchIndex(loader, &store.MockEntityEventsService{}, &NoopDocumentExtender{}, nil, tracing.InitializeTracerForTest(), featuremgmt.WithFeatures(), settings, nil)
+
+	estimate, err := index.estimateOrgIndex(context.Background(), testOrgID)
+	require.NoError(t, err)
+	require.Equal(t, 502, estimate.DashboardCount)
+	require.Equal(t, 2, estimate.FolderCount)
+	require.Equal(t, 2500, estimate.PanelCount)
+	require.Equal(t, indexEstimateSampleSize, estimate.SampleSize)
+
+	require.Len(t, estimate.Estimates, 4)
+	byMode := map[string]IndexModeEstimate{}
+	for _, e := range estimate.Estimates {
+		byMode[e.Mode+"/"+e.Placement] = e
+		require.Positive(t, e.BuildTime)
+		require.Equal(t, e.Mode == "full" && e.Placement == "disk", e.Current, "%s %s", e.Mode, e.Placement)
+	}
+	require.Positive(t, byMode["full/memory"].HeapBytes)
+	require.Less(t, byMode["sparse/memory"].HeapBytes, byMode["full/memory"].HeapBytes, "sparse indexes leave panels out")
+	require.Positive(t, byMode["full/disk"].DiskBytes)
+	require.Zero(t, byMode["full/disk"].HeapBytes)
+	require.Less(t, byMode["sparse/disk"].DiskBytes, byMode["full/disk"].DiskBytes)
+
+	t.Run("nothing is left behind", func(t *testing.T) {
+		dirs, err := os.ReadDir(path)
+		require.NoError(t, err)
+		require.Empty(t, dirs)
+		_, ok := index.getOrgIndex(testOrgID)
+		require.False(t, ok)
+	})
+}
+
+func TestSampleDashboards(t *testing.T) {
+	dashboards := testPermissionDashboards(8)
+	require.Len(t, sampleDashboards(dashboards, 20), 10)
+
+	sample := sampleDashboards(dashboards, 5)
+	uids := make([]string, 0, len(sample))
+	for _, dash := range sample {
+		uids = append(uids, dash.uid)
+	}
+	require.Equal(t, []string{"folder-a", "dash-0", "dash-2", "dash-4", "dash-6"}, uids)
+}
@@ -0,0 +1,141 @@
+package searchV2
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	corruptionSourceLoad   = "load"   // persisted index failed to decode or verify
+	corruptionSourceVerify = "verify" // periodic verification of the indexes in memory
+	corruptionSourceQuery  = "query"  // verification after a failed search query
+)
+
+var dashboardSearchIndexCorruptions = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "dashboard_search_index_corruptions_total",
+		Help:      "A counter for corrupted org indexes, by where the corruption was detected",
+	},
+	[]string{"source"},
+)
+
+// errIndexCorrupted is returned when the items of an index directory don't match their checksum.
+var errIndexCorrupted = errors.New("index is corrupted")
+
+func countCorruption(source string) {
+	dashboardSearchIndexCorruptions.With(prometheus.Labels{"source": source}).Inc()
+}
+
+var itemChecksumTable = crc32.MakeTable(crc32.Castagnoli)
+
+func itemChecksum(data []byte) uint32 {
+	return crc32.Checksum(data, itemChecksumTable)
+}
+
+func (d *memoryDirectory) verifyItem(kind string, id uint64, data []byte) error {
+	checksum, ok := d.checksums[kind][id]
+	if !ok {
+		return fmt.Errorf("%w: item %d%s has no checksum", errIndexCorrupted, id, kind)
+	}
+	if itemChecksum(data) != checksum {
+		return fmt.Errorf("%w: item %d%s doesn't match its checksum", errIndexCorrupted, id, kind)
+	}
+	return nil
+}
+
+// verify checks every item of the directory against its checksum.
+func (d *memoryDirectory) verify() error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for kind, items := range d.items {
+		for id, data := range items {
+			if err := d.verifyItem(kind, id, data); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// quarantine moves a corrupted persisted index aside, so that it is kept for investigation but
+// never loaded again.
+func (p *indexPersister) quarantine(orgID int64, idxType indexType) error {
+	name := p.fileName(orgID, idxType)
+	if err := os.Rename(name, name+".corrupt"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// markCorrupted flags the index as corrupted, it returns false when it already was.
+func (i *orgIndex) markCorrupted() bool {
+	return atomic.CompareAndSwapInt32(&i.corrupted, 0, 1)
+}
+
+func (i *orgIndex) clearCorrupted() {
+	atomic.StoreInt32(&i.corrupted, 0)
+}
+
+func (i *orgIndex) isCorrupted() bool {
+	return atomic.LoadInt32(&i.corrupted) == 1
+}
+
+// verify checks the memory directories of the index, disk indexes are verified by bluge itself.
+func (i *orgIndex) verify() error {
+	for idxType, dir := range i.directories {
+		if err := dir.verify(); err != nil {
+			return fmt.Errorf("%s index: %w", idxType, err)
+		}
+	}
+	return nil
+}
+
+// reportCorruption logs and counts the corruption of the index of an organization, and rebuilds the
+// index in background. The corrupted index keeps answering searches until the rebuild finishes,
+// which are flagged as degraded.
+func (i *searchIndex) reportCorruption(orgID int64, index *orgIndex, source string, err error) {
+	if !index.markCorrupted() {
+		return
+	}
+	i.logger.Error("Org index is corrupted, rebuilding it", "orgId", orgID, "orgSearchIndexGeneration", index.generation, "source", source, "error", err)
+	countCorruption(source)
+	select {
+	case i.recoverSignals <- orgID:
+	default:
+		// the next verification reports the index again
+		i.logger.Warn("Org index rebuild queue is full", "orgId", orgID)
+		index.clearCorrupted()
+	}
+}
+
+// checkOrgIndex verifies the index of an organization, reporting it when corrupted.
+func (i *searchIndex) checkOrgIndex(orgID int64, index *orgIndex, source string) {
+	if index.isCorrupted() {
+		return
+	}
+	if err := index.verify(); err != nil {
+		i.reportCorruption(orgID, index, source, err)
+	}
+}
+
+// verifyOrgIndexes verifies the indexes of all organizations.
+func (i *searchIndex) verifyOrgIndexes() {
+	i.mu.RLock()
+	indexes := make(map[int64]*orgIndex, len(i.perOrgIndex))
+	for orgID, index := range i.perOrgIndex {
+		indexes[orgID] = index
+	}
+	i.mu.RUnlock()
+
+	for orgID, index := range indexes {
+		i.checkOrgIndex(orgID, index, corruptionSourceVerify)
+	}
+}
@@ -0,0 +1,98 @@
+package searchV2
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	blugeindex "github.com/blugelabs/bluge/index"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/store"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// corruptDirectory flips a byte of a segment of the directory.
+func corruptDirectory(t *testing.T, dir *memoryDirectory) {
+	t.Helper()
+	for _, data := range dir.items[blugeindex.ItemKindSegment] {
+		data[len(data)/2] ^= 0xff
+		return
+	}
+	t.Fatal("directory has no segment")
+}
+
+func TestIndexIntegrity(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("intact directories verify", func(t *testing.T) {
+		index := initTestOrgIndexFromDashes(t, testDashboards)
+		require.NoError(t, index.verify())
+
+		data, err := index.directories[indexTypeDashboard].encode()
+		require.NoError(t, err)
+		_, err = decodeMemoryDirectory(data)
+		require.NoError(t, err)
+	})
+
+	t.Run("corrupted items fail verification and decoding", func(t *testing.T) {
+		index := initTestOrgIndexFromDashes(t, testDashboards)
+		dir := index.directories[indexTypeDashboard]
+		data, err := dir.encode()
+		require.NoError(t, err)
+
+		corruptDirectory(t, dir)
+		require.ErrorIs(t, index.verify(), errIndexCorrupted)
+
+		corrupted, err := dir.encode()
+		require.NoError(t, err)
+		_, err = decodeMemoryDirectory(corrupted)
+		require.ErrorIs(t, err, errIndexCorrupted)
+
+		_, err = decodeMemoryDirectory(data[:len(data)/2])
+		require.ErrorIs(t, err, errIndexCorrupted)
+	})
+
+	t.Run("corrupted persisted indexes are quarantined", func(t *testing.T) {
+		index := initTestOrgIndexFromDashes(t, testDashboards)
+		dir := index.directories[indexTypeDashboard]
+		corruptDirectory(t, dir)
+
+		persister := newIndexPersister(t.TempDir(), false, nil)
+		require.NoError(t, persister.save(ctx, testOrgID, indexTypeDashboard, dir))
+		_, _, err := persister.load(ctx, testOrgID, indexTypeDashboard)
+		require.ErrorIs(t, err, errIndexCorrupted)
+
+		i := newSearchIndex(nil, &store.MockEntityEventsService{}, &NoopDocumentExtender{}, nil, tracing.InitializeTracerForTest(), featuremgmt.WithFeatures(), setting.SearchSettings{}, persister)
+		require.False(t, i.restoreOrgIndex(ctx, testOrgID))
+
+		_, err = os.Stat(persister.fileName(testOrgID, indexTypeDashboard))
+		require.ErrorIs(t, err, os.ErrNotExist)
+		_, err = os.Stat(persister.fileName(testOrgID, indexTypeDashboard) + ".corrupt")
+		require.NoError(t, err)
+	})
+
+	t.Run("corrupted indexes are rebuilt and serve degraded results meanwhile", func(t *testing.T) {
+		index := initTestOrgIndexFromDashes(t, testDashboards)
+		i := newSearchIndex(nil, &store.MockEntityEventsService{}, &NoopDocumentExtender{}, nil, tracing.InitializeTracerForTest(), featuremgmt.WithFeatures(), setting.SearchSettings{}, nil)
+		i.perOrgIndex[testOrgID] = index
+
+		i.verifyOrgIndexes()
+		require.Empty(t, i.recoverSignals)
+
+		// the checksums are corrupted rather than the segments, which must still be searchable
+		for id := range index.directories[indexTypeDashboard].checksums[blugeindex.ItemKindSegment] {
+			index.directories[indexTypeDashboard].checksums[blugeindex.ItemKindSegment][id]++
+		}
+		i.verifyOrgIndexes()
+		i.checkOrgIndex(testOrgID, index, corruptionSourceQuery)
+		require.Len(t, i.recoverSignals, 1, "corruptions are reported once")
+		require.Equal(t, testOrgID, <-i.recoverSignals)
+
+		resp := doSearchQuery(ctx, testLogger, index, testAllowAllFilter, DashboardQuery{Query: "boom"}, &NoopQueryExtender{}, "")
+		require.NoError(t, resp.Error)
+		require.True(t, resp.Frames[0].Meta.Custom.(*customMeta).Degraded)
+	})
+}
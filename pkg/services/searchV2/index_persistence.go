@@ -0,0 +1,314 @@
+package searchV2
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/blugelabs/bluge/index"
+	segment "github.com/blugelabs/bluge_segment_api"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/secrets"
+)
+
+// memoryDirectory is an in-memory bluge index directory. Unlike the in-memory
+// directory shipped with bluge it keeps index snapshots alongside segments, so
+// its content can be serialized and an index can be re-opened from it later.
+// The checksum of each item is kept to detect corrupted items, see verify.
+type memoryDirectory struct {
+	mu        sync.RWMutex
+	items     map[string]map[uint64][]byte
+	checksums map[string]map[uint64]uint32
+}
+
+var _ index.Directory = (*memoryDirectory)(nil)
+
+func newMemoryDirectory() *memoryDirectory {
+	return &memoryDirectory{
+		items: map[string]map[uint64][]byte{
+			index.ItemKindSegment:  {},
+			index.ItemKindSnapshot: {},
+		},
+		checksums: map[string]map[uint64]uint32{
+			index.ItemKindSegment:  {},
+			index.ItemKindSnapshot: {},
+		},
+	}
+}
+
+// newMemoryDirectoryFromItems returns a directory holding the items, which are trusted to be intact.
+func newMemoryDirectoryFromItems(items map[string]map[uint64][]byte) *memoryDirectory {
+	d := &memoryDirectory{items: items, checksums: make(map[string]map[uint64]uint32, len(items))}
+	for kind, kindItems := range items {
+		d.checksums[kind] = make(map[uint64]uint32, len(kindItems))
+		for id, data := range kindItems {
+			d.checksums[kind][id] = itemChecksum(data)
+		}
+	}
+	return d
+}
+
+func (d *memoryDirectory) Setup(_ bool) error {
+	return nil
+}
+
+func (d *memoryDirectory) List(kind string) ([]uint64, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	ids := make([]uint64, 0, len(d.items[kind]))
+	for id := range d.items[kind] {
+		ids = append(ids, id)
+	}
+	// Items must be returned in descending order.
+	sort.Slice(ids, func(i, j int) bool { return ids[i] > ids[j] })
+	return ids, nil
+}
+
+func (d *memoryDirectory) Load(kind string, id uint64) (*segment.Data, io.Closer, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	data, ok := d.items[kind][id]
+	if !ok {
+		return nil, nil, fmt.Errorf("item %d%s not found", id, kind)
+	}
+	if err := d.verifyItem(kind, id, data); err != nil {
+		return nil, nil, err
+	}
+	return segment.NewDataBytes(data), nil, nil
+}
+
+func (d *memoryDirectory) Persist(kind string, id uint64, w index.WriterTo, closeCh chan struct{}) error {
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf, closeCh); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.items[kind]; !ok {
+		d.items[kind] = map[uint64][]byte{}
+		d.checksums[kind] = map[uint64]uint32{}
+	}
+	d.items[kind][id] = buf.Bytes()
+	d.checksums[kind][id] = itemChecksum(buf.Bytes())
+	return nil
+}
+
+func (d *memoryDirectory) Remove(kind string, id uint64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.items[kind], id)
+	delete(d.checksums[kind], id)
+	return nil
+}
+
+func (d *memoryDirectory) Stats() (uint64, uint64) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	var numItems, numBytes uint64
+	for _, items := range d.items {
+		for _, data := range items {
+			numItems++
+			numBytes += uint64(len(data))
+		}
+	}
+	return numItems, numBytes
+}
+
+func (d *memoryDirectory) Sync() error {
+	return nil
+}
+
+func (d *memoryDirectory) Lock() error {
+	return nil
+}
+
+func (d *memoryDirectory) Unlock() error {
+	return nil
+}
+
+// persistedDirectory is how directories are serialized, items are saved with their checksum so
+// that corrupted items are detected when the directory is loaded.
+type persistedDirectory struct {
+	Items     map[string]map[uint64][]byte
+	Checksums map[string]map[uint64]uint32
+}
+
+func (d *memoryDirectory) encode() ([]byte, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(persistedDirectory{Items: d.items, Checksums: d.checksums}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// copyItems returns the segments and snapshots of the directory, which are never modified once persisted.
+func (d *memoryDirectory) copyItems() map[string]map[uint64][]byte {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	items := make(map[string]map[uint64][]byte, len(d.items))
+	for kind, kindItems := range d.items {
+		items[kind] = make(map[uint64][]byte, len(kindItems))
+		for id, data := range kindItems {
+			items[kind][id] = data
+		}
+	}
+	return items
+}
+
+// decodeMemoryDirectory decodes a serialized directory, it fails with errIndexCorrupted when the
+// data or one of its items is corrupted.
+func decodeMemoryDirectory(data []byte) (*memoryDirectory, error) {
+	var persisted persistedDirectory
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&persisted); err != nil {
+		return nil, fmt.Errorf("%w: %s", errIndexCorrupted, err)
+	}
+	d := &memoryDirectory{items: persisted.Items, checksums: persisted.Checksums}
+	if d.items == nil || d.checksums == nil {
+		return nil, fmt.Errorf("%w: missing items", errIndexCorrupted)
+	}
+	if err := d.verify(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+const (
+	persistedIndexMagic         = "GFSIDX"
+	persistedIndexVersion       = byte(7) // bumped when indexed fields or the format change, forcing a rebuild
+	persistedIndexFlagEncrypted = byte(1)
+)
+
+var errPersistedIndexNotFound = errors.New("persisted index not found")
+
+// indexPersister stores org index snapshots on disk. When encryption is enabled
+// snapshots are encrypted with the envelope encryption of the secrets service,
+// so that dashboard titles and contents do not sit in plain text on disk.
+type indexPersister struct {
+	path    string
+	encrypt bool
+	secrets secrets.Service
+	logger  log.Logger
+}
+
+func newIndexPersister(path string, encrypt bool, secretsService secrets.Service) *indexPersister {
+	return &indexPersister{
+		path:    path,
+		encrypt: encrypt,
+		secrets: secretsService,
+		logger:  log.New("searchIndexPersister"),
+	}
+}
+
+func (p *indexPersister) fileName(orgID int64, idxType indexType) string {
+	return filepath.Join(p.path, fmt.Sprintf("org-%d.%s.idx", orgID, idxType))
+}
+
+func (p *indexPersister) save(ctx context.Context, orgID int64, idxType indexType, dir *memoryDirectory) error {
+	data, err := dir.encode()
+	if err != nil {
+		return fmt.Errorf("error encoding index: %w", err)
+	}
+	return p.writeFile(ctx, p.fileName(orgID, idxType), data)
+}
+
+func (p *indexPersister) writeFile(ctx context.Context, name string, data []byte) error {
+	var flags byte
+	var err error
+	if p.encrypt {
+		data, err = p.secrets.Encrypt(ctx, data, secrets.WithoutScope())
+		if err != nil {
+			return fmt.Errorf("error encrypting index: %w", err)
+		}
+		flags |= persistedIndexFlagEncrypted
+	}
+
+	if err := os.MkdirAll(p.path, 0750); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first to never leave a partially written index behind.
+	tmp, err := os.CreateTemp(p.path, ".index-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	header := append([]byte(persistedIndexMagic), persistedIndexVersion, flags)
+	if _, err := tmp.Write(header); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), name)
+}
+
+// load reads a persisted org index. The returned boolean is true when the
+// index must be saved again to reach the configured state, i.e. when an index
+// persisted without encryption is loaded while encryption is enabled.
+func (p *indexPersister) load(ctx context.Context, orgID int64, idxType indexType) (*memoryDirectory, bool, error) {
+	data, needsRewrite, err := p.readFile(ctx, p.fileName(orgID, idxType))
+	if err != nil {
+		return nil, false, err
+	}
+
+	dir, err := decodeMemoryDirectory(data)
+	if err != nil {
+		return nil, false, fmt.Errorf("error decoding index %s: %w", p.fileName(orgID, idxType), err)
+	}
+
+	return dir, needsRewrite, nil
+}
+
+func (p *indexPersister) readFile(ctx context.Context, name string) ([]byte, bool, error) {
+	// nolint:gosec
+	content, err := os.ReadFile(name)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, false, errPersistedIndexNotFound
+		}
+		return nil, false, err
+	}
+
+	headerLen := len(persistedIndexMagic) + 2
+	if len(content) < headerLen || string(content[:len(persistedIndexMagic)]) != persistedIndexMagic {
+		return nil, false, errors.New("invalid persisted index header")
+	}
+	if version := content[len(persistedIndexMagic)]; version != persistedIndexVersion {
+		return nil, false, fmt.Errorf("unsupported persisted index version %d", version)
+	}
+
+	flags := content[len(persistedIndexMagic)+1]
+	data := content[headerLen:]
+	encrypted := flags&persistedIndexFlagEncrypted != 0
+
+	if encrypted {
+		if p.secrets == nil {
+			return nil, false, errors.New("persisted index is encrypted but no secrets service is available")
+		}
+		// Envelope encryption stores the data key ID with the payload, so indexes
+		// encrypted with data keys that have been rotated can still be decrypted.
+		data, err = p.secrets.Decrypt(ctx, data)
+		if err != nil {
+			return nil, false, fmt.Errorf("error decrypting index: %w", err)
+		}
+	}
+
+	return data, encrypted != p.encrypt, nil
+}
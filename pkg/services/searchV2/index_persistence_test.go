@@ -0,0 +1,104 @@
+package searchV2
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/secrets"
+	"github.com/grafana/grafana/pkg/services/secrets/fakes"
+)
+
+// xorSecretsService mimics encryption so that tests can assert that the
+// persisted index is not stored in plain text.
+type xorSecretsService struct {
+	fakes.FakeSecretsService
+}
+
+func (s xorSecretsService) Encrypt(_ context.Context, payload []byte, _ secrets.EncryptionOptions) ([]byte, error) {
+	return xor(payload), nil
+}
+
+func (s xorSecretsService) Decrypt(_ context.Context, payload []byte) ([]byte, error) {
+	return xor(payload), nil
+}
+
+func xor(payload []byte) []byte {
+	res := make([]byte, len(payload))
+	for i, b := range payload {
+		res[i] = b ^ 0x5a
+	}
+	return res
+}
+
+func countIndexDocs(t *testing.T, index *orgIndex) uint64 {
+	t.Helper()
+	reader, cancel, err := index.readerForIndex(indexTypeDashboard)
+	require.NoError(t, err)
+	defer cancel()
+	count, err := reader.Count()
+	require.NoError(t, err)
+	return count
+}
+
+func TestIndexPersistence(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("encrypted index round trip", func(t *testing.T) {
+		index := initTestOrgIndexFromDashes(t, testDashboards)
+		persister := newIndexPersister(t.TempDir(), true, xorSecretsService{})
+
+		require.NoError(t, persister.save(ctx, testOrgID, indexTypeDashboard, index.directories[indexTypeDashboard]))
+
+		content, err := os.ReadFile(persister.fileName(testOrgID, indexTypeDashboard))
+		require.NoError(t, err)
+		require.False(t, bytes.Contains(content, []byte("boom")), "dashboard title must not be stored in plain text")
+
+		dir, needsRewrite, err := persister.load(ctx, testOrgID, indexTypeDashboard)
+		require.NoError(t, err)
+		require.False(t, needsRewrite)
+
+		restored, err := openOrgIndex(dir)
+		require.NoError(t, err)
+		require.Equal(t, countIndexDocs(t, index), countIndexDocs(t, restored))
+
+		checkSearchResponse(t, "basic-search", restored, testAllowAllFilter, DashboardQuery{Query: "boom"})
+	})
+
+	t.Run("unencrypted index is migrated when encryption is enabled", func(t *testing.T) {
+		index := initTestOrgIndexFromDashes(t, testDashboards)
+		path := t.TempDir()
+
+		plain := newIndexPersister(path, false, nil)
+		require.NoError(t, plain.save(ctx, testOrgID, indexTypeDashboard, index.directories[indexTypeDashboard]))
+
+		encrypted := newIndexPersister(path, true, xorSecretsService{})
+		_, needsRewrite, err := encrypted.load(ctx, testOrgID, indexTypeDashboard)
+		require.NoError(t, err)
+		require.True(t, needsRewrite)
+	})
+
+	t.Run("restored index accepts updates", func(t *testing.T) {
+		index := initTestOrgIndexFromDashes(t, testDashboards)
+		persister := newIndexPersister(t.TempDir(), false, nil)
+		require.NoError(t, persister.save(ctx, testOrgID, indexTypeDashboard, index.directories[indexTypeDashboard]))
+
+		dir, _, err := persister.load(ctx, testOrgID, indexTypeDashboard)
+		require.NoError(t, err)
+		restored, err := openOrgIndex(dir)
+		require.NoError(t, err)
+
+		doc := newSearchDocument("3", "new", "", "/d/3/new")
+		require.NoError(t, restored.writerForIndex(indexTypeDashboard).Update(doc.ID(), doc))
+		require.Equal(t, countIndexDocs(t, index)+1, countIndexDocs(t, restored))
+	})
+
+	t.Run("missing index", func(t *testing.T) {
+		persister := newIndexPersister(t.TempDir(), true, xorSecretsService{})
+		_, _, err := persister.load(ctx, testOrgID, indexTypeDashboard)
+		require.ErrorIs(t, err, errPersistedIndexNotFound)
+	})
+}
@@ -0,0 +1,214 @@
+package searchV2
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// ErrIndexNotPublished is returned by query instances for the organizations whose index the index
+// builder hasn't published yet, see setting.SearchSettings.IndexTarget.
+var ErrIndexNotPublished = errors.New("the index of the organization isn't published by the index builder yet")
+
+func (i *searchIndex) indexTarget() string {
+	if i.settings.IndexTarget == "" {
+		return setting.SearchIndexTargetAll
+	}
+	return i.settings.IndexTarget
+}
+
+// modTime returns when the index of the organization was last persisted.
+func (p *indexPersister) modTime(orgID int64, idxType indexType) (time.Time, error) {
+	info, err := os.Stat(p.fileName(orgID, idxType))
+	if errors.Is(err, os.ErrNotExist) {
+		return time.Time{}, errPersistedIndexNotFound
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// publishUpdatedOrgIndexes persists the indexes changed by entity events since they were last
+// persisted, so that query instances pick the changes up. Builds persist the indexes already.
+func (i *searchIndex) publishUpdatedOrgIndexes(ctx context.Context) {
+	i.mu.RLock()
+	updated := map[int64]*orgIndex{}
+	for orgID, index := range i.perOrgIndex {
+		if index.lastUpdated().After(i.published[orgID]) {
+			updated[orgID] = index
+		}
+	}
+	i.mu.RUnlock()
+
+	for orgID, index := range updated {
+		i.persistOrgIndex(ctx, orgID, index)
+	}
+}
+
+// unindexedOrgIDs returns the organizations without index which index builders must build, other
+// instances build them when their users search.
+func (i *searchIndex) unindexedOrgIDs(ctx context.Context) []int64 {
+	if i.indexTarget() != setting.SearchIndexTargetBuilder || i.orgLister == nil {
+		return nil
+	}
+	orgIDs, err := i.orgLister(ctx)
+	if err != nil {
+		i.logger.Error("Failed to list organizations to index", "error", err)
+		return nil
+	}
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	unindexed := make([]int64, 0)
+	for _, orgID := range orgIDs {
+		if _, ok := i.perOrgIndex[orgID]; !ok {
+			unindexed = append(unindexed, orgID)
+		}
+	}
+	return unindexed
+}
+
+// loadPublishedOrgIndex loads the index of the organization last published by the index builder.
+// Corrupted indexes are left to the index builder, which owns the published indexes.
+func (i *searchIndex) loadPublishedOrgIndex(ctx context.Context, orgID int64) error {
+	published, err := i.persister.modTime(orgID, indexTypeDashboard)
+	if err != nil {
+		if errors.Is(err, errPersistedIndexNotFound) {
+			return ErrIndexNotPublished
+		}
+		return err
+	}
+	dir, _, err := i.persister.load(ctx, orgID, indexTypeDashboard)
+	if err != nil {
+		if errors.Is(err, errPersistedIndexNotFound) {
+			return ErrIndexNotPublished
+		}
+		if errors.Is(err, errIndexCorrupted) {
+			countCorruption(corruptionSourceLoad)
+		}
+		return err
+	}
+	index, err := i.openPersistedOrgIndex(orgID, dir)
+	if err != nil {
+		return err
+	}
+	i.setPersistedOrgIndex(orgID, index)
+	i.mu.Lock()
+	i.published[orgID] = published
+	i.mu.Unlock()
+	i.logger.Info("Loaded published org index", "orgId", orgID, "published", published, "orgSearchIndexGeneration", index.generation)
+	return nil
+}
+
+// reloadPublishedOrgIndexes loads the indexes published since they were loaded.
+func (i *searchIndex) reloadPublishedOrgIndexes(ctx context.Context) {
+	i.mu.RLock()
+	loaded := make(map[int64]time.Time, len(i.perOrgIndex))
+	for orgID := range i.perOrgIndex {
+		loaded[orgID] = i.published[orgID]
+	}
+	i.mu.RUnlock()
+
+	for orgID, published := range loaded {
+		modTime, err := i.persister.modTime(orgID, indexTypeDashboard)
+		if err != nil {
+			i.logger.Warn("Failed to check published org index", "orgId", orgID, "error", err)
+			continue
+		}
+		if !modTime.After(published) {
+			continue
+		}
+		if err := i.loadPublishedOrgIndex(ctx, orgID); err != nil {
+			i.logger.Error("Failed to reload published org index", "orgId", orgID, "error", err)
+		}
+	}
+}
+
+// runQueryTarget serves searches with the indexes published by the index builder, reloading them
+// every IndexUpdateInterval when newer ones are published. Indexes are never built nor updated:
+// the index builder applies the entity events and re-indexes organizations.
+func (i *searchIndex) runQueryTarget(ctx context.Context, orgIDs []int64, reIndexSignalCh chan struct{}) error {
+	i.logger.Info("Initializing SearchV2 query target", "indexPath", i.settings.IndexPath, "indexUpdateInterval", i.settings.IndexUpdateInterval)
+
+	for _, orgID := range orgIDs {
+		if err := i.loadPublishedOrgIndex(ctx, orgID); err != nil && !errors.Is(err, ErrIndexNotPublished) {
+			i.logger.Error("Failed to load published org index", "orgId", orgID, "error", err)
+		}
+	}
+
+	reloadTicker := time.NewTicker(i.settings.IndexUpdateInterval)
+	defer reloadTicker.Stop()
+
+	var warmQueryCh <-chan time.Time
+	if i.settings.WarmQueryInterval > 0 {
+		warmQueryTicker := time.NewTicker(i.settings.WarmQueryInterval)
+		defer warmQueryTicker.Stop()
+		warmQueryCh = warmQueryTicker.C
+	}
+
+	var verifyCh <-chan time.Time
+	if i.settings.IndexVerifyInterval > 0 {
+		verifyTicker := time.NewTicker(i.settings.IndexVerifyInterval)
+		defer verifyTicker.Stop()
+		verifyCh = verifyTicker.C
+	}
+
+	i.initializationMutex.Lock()
+	i.initialIndexingComplete = true
+	i.notifyReadinessChanged()
+	i.initializationMutex.Unlock()
+
+	// Loading indexes may take a while, so it's done asynchronously one at a time for searches not
+	// to wait for it when they sync.
+	loadSemaphore := make(chan struct{}, 1)
+
+	for {
+		select {
+		case doneCh := <-i.syncCh:
+			close(doneCh)
+		case <-reloadTicker.C:
+			select {
+			case loadSemaphore <- struct{}{}:
+				go func() {
+					defer func() { <-loadSemaphore }()
+					i.reloadPublishedOrgIndexes(ctx)
+				}()
+			default:
+				// still loading, newer indexes are loaded on the next tick
+			}
+		case <-warmQueryCh:
+			i.warmOrgIndexes(WarmQueryTriggerSchedule)
+		case <-verifyCh:
+			go i.verifyOrgIndexes()
+		case <-reIndexSignalCh:
+			i.logger.Debug("Ignoring re-indexing signal, the index builder re-indexes")
+		case signal := <-i.buildSignals:
+			go func() {
+				loadSemaphore <- struct{}{}
+				defer func() { <-loadSemaphore }()
+				if _, ok := i.getOrgIndex(signal.orgID); ok {
+					close(signal.done)
+					return
+				}
+				signal.done <- i.loadPublishedOrgIndex(ctx, signal.orgID)
+			}()
+		case orgID := <-i.recoverSignals:
+			go func() {
+				loadSemaphore <- struct{}{}
+				defer func() { <-loadSemaphore }()
+				if err := i.loadPublishedOrgIndex(ctx, orgID); err != nil {
+					i.logger.Error("Failed to reload corrupted org index", "orgId", orgID, "error", err)
+					// the next verification reports the index again, retrying the reload
+					if index, ok := i.getOrgIndex(orgID); ok {
+						index.clearCorrupted()
+					}
+				}
+			}()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
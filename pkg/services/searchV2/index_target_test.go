@@ -0,0 +1,109 @@
+package searchV2
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/searchV2/extract"
+	"github.com/grafana/grafana/pkg/services/store"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestIndexTarget(t *testing.T) {
+	ctx := context.Background()
+	newIndex := func(loader dashboardLoader, target string, persister *indexPersister) *searchIndex {
+		settings := setting.SearchSettings{IndexTarget: target, IndexUpdateInterval: time.Hour, DiskIndexDashboardThreshold: 1}
+		return newSearchIndex(loader, &store.MockEntityEventsService{}, &NoopDocumentExtender{}, nil, tracing.InitializeTracerForTest(), featuremgmt.WithFeatures(), settings, persister)
+	}
+
+	t.Run("targets need an index path", func(t *testing.T) {
+		require.Equal(t, setting.SearchIndexTargetAll, newIndex(nil, setting.SearchIndexTargetQuery, nil).indexTarget())
+	})
+
+	t.Run("query instances load the indexes published by the index builder", func(t *testing.T) {
+		path := t.TempDir()
+		loader := &testDashboardLoader{dashboards: []dashboard{{id: 1, uid: "1", info: &extract.DashboardInfo{Title: "Latency"}}}}
+		builder := newIndex(loader, setting.SearchIndexTargetBuilder, newIndexPersister(path, false, nil))
+		query := newIndex(nil, setting.SearchIndexTargetQuery, newIndexPersister(path, false, nil))
+
+		require.ErrorIs(t, query.loadPublishedOrgIndex(ctx, testOrgID), ErrIndexNotPublished)
+
+		_, err := builder.buildOrgIndex(ctx, testOrgID)
+		require.NoError(t, err)
+		index, _ := builder.getOrgIndex(testOrgID)
+		require.Equal(t, indexPlacementMemory, index.placement, "published indexes are kept in memory")
+
+		require.NoError(t, query.loadPublishedOrgIndex(ctx, testOrgID))
+		index, ok := query.getOrgIndex(testOrgID)
+		require.True(t, ok)
+		require.Equal(t, uint64(1), countIndexDocs(t, index))
+
+		// indexes are only reloaded once published again
+		query.reloadPublishedOrgIndexes(ctx)
+		reloaded, _ := query.getOrgIndex(testOrgID)
+		require.Equal(t, index.generation, reloaded.generation)
+
+		loader.dashboards = append(loader.dashboards, dashboard{id: 2, uid: "2", info: &extract.DashboardInfo{Title: "Errors"}})
+		_, err = builder.buildOrgIndex(ctx, testOrgID)
+		require.NoError(t, err)
+		later := time.Now().Add(time.Minute)
+		require.NoError(t, os.Chtimes(builder.persister.fileName(testOrgID, indexTypeDashboard), later, later))
+
+		query.reloadPublishedOrgIndexes(ctx)
+		reloaded, _ = query.getOrgIndex(testOrgID)
+		require.Greater(t, reloaded.generation, index.generation)
+		require.Equal(t, uint64(2), countIndexDocs(t, reloaded))
+	})
+
+	t.Run("index builders publish updated indexes", func(t *testing.T) {
+		loader := &testDashboardLoader{dashboards: []dashboard{{id: 1, uid: "1", info: &extract.DashboardInfo{Title: "Latency"}}}}
+		builder := newIndex(loader, setting.SearchIndexTargetBuilder, newIndexPersister(t.TempDir(), false, nil))
+		_, err := builder.buildOrgIndex(ctx, testOrgID)
+		require.NoError(t, err)
+		published := builder.published[testOrgID]
+		require.False(t, published.IsZero())
+
+		builder.publishUpdatedOrgIndexes(ctx)
+		require.Equal(t, published, builder.published[testOrgID])
+
+		index, _ := builder.getOrgIndex(testOrgID)
+		index.markUpdated(time.Now().Add(time.Second))
+		builder.publishUpdatedOrgIndexes(ctx)
+		require.True(t, builder.published[testOrgID].After(published))
+	})
+
+	t.Run("index builders index all organizations", func(t *testing.T) {
+		builder := newIndex(&testDashboardLoader{}, setting.SearchIndexTargetBuilder, newIndexPersister(t.TempDir(), false, nil))
+		builder.orgLister = func(ctx context.Context) ([]int64, error) {
+			return []int64{testOrgID, 2}, nil
+		}
+		_, err := builder.buildOrgIndex(ctx, testOrgID)
+		require.NoError(t, err)
+		require.Equal(t, []int64{2}, builder.unindexedOrgIDs(ctx))
+
+		builder.reIndexFromScratch(ctx, false)
+		_, ok := builder.getOrgIndex(2)
+		require.True(t, ok)
+	})
+
+	t.Run("query instances don't build indexes", func(t *testing.T) {
+		query := newIndex(nil, setting.SearchIndexTargetQuery, newIndexPersister(t.TempDir(), false, nil))
+		runCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		done := make(chan error, 1)
+		go func() { done <- query.run(runCtx, []int64{testOrgID}, make(chan struct{})) }()
+
+		require.NoError(t, query.sync(ctx))
+		_, err := query.getOrCreateOrgIndex(ctx, testOrgID)
+		require.ErrorIs(t, err, ErrIndexNotPublished)
+
+		cancel()
+		require.ErrorIs(t, <-done, context.Canceled)
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/data"
@@ -41,7 +42,7 @@ var testDisallowAllFilter = func(uid string) bool {
 
 var testOrgID int64 = 1
 
-func initTestOrgIndexFromDashes(t *testing.T, dashboards []dashboard) *orgIndex {
+func initTestOrgIndexFromDashes(t testing.TB, dashboards []dashboard) *orgIndex {
 	t.Helper()
 	searchIdx := initTestIndexFromDashesExtended(t, dashboards, &NoopDocumentExtender{})
 	return searchIdx.perOrgIndex[testOrgID]
@@ -58,12 +59,12 @@ func initTestIndexFromDashes(t *testing.T, dashboards []dashboard) *searchIndex
 	return initTestIndexFromDashesExtended(t, dashboards, &NoopDocumentExtender{})
 }
 
-func initTestIndexFromDashesExtended(t *testing.T, dashboards []dashboard, extender DocumentExtender) *searchIndex {
+func initTestIndexFromDashesExtended(t testing.TB, dashboards []dashboard, extender DocumentExtender) *searchIndex {
 	t.Helper()
 	dashboardLoader := &testDashboardLoader{
 		dashboards: dashboards,
 	}
-	index := newSearchIndex(dashboardLoader, &store.MockEntityEventsService{}, extender, func(ctx context.Context, folderId int64) (string, error) { return "x", nil }, tracing.InitializeTracerForTest(), featuremgmt.WithFeatures(), setting.SearchSettings{})
+	index := newSearchIndex(dashboardLoader, &store.MockEntityEventsService{}, extender, func(ctx context.Context, folderId int64) (string, error) { return "x", nil }, tracing.InitializeTracerForTest(), featuremgmt.WithFeatures(), setting.SearchSettings{}, nil)
 	require.NotNil(t, index)
 	numDashboards, err := index.buildOrgIndex(context.Background(), testOrgID)
 	require.NoError(t, err)
@@ -79,6 +80,13 @@ func checkSearchResponse(t *testing.T, fileName string, index *orgIndex, filter
 func checkSearchResponseExtended(t *testing.T, fileName string, index *orgIndex, filter ResourceFilter, query DashboardQuery, extender QueryExtender) {
 	t.Helper()
 	resp := doSearchQuery(context.Background(), testLogger, index, filter, query, extender, "/pfix")
+	// the build of the index differs between runs
+	for _, frame := range resp.Frames {
+		if meta, ok := frame.Meta.Custom.(*customMeta); ok {
+			meta.IndexGeneration = 0
+			meta.IndexUpdated = nil
+		}
+	}
 	experimental.CheckGoldenJSONResponse(t, "testdata", fileName, resp, true)
 }
 
@@ -513,6 +521,114 @@ func TestDashboardIndex_Folders(t *testing.T) {
 	})
 }
 
+// testFolderDashboardLoader loads dashboards by UID and by folder like the SQL loader does.
+type testFolderDashboardLoader struct {
+	dashboards []dashboard
+}
+
+func (t *testFolderDashboardLoader) LoadDashboards(_ context.Context, _ int64, uid string) ([]dashboard, error) {
+	if uid == "" {
+		return t.dashboards, nil
+	}
+	for _, dash := range t.dashboards {
+		if dash.uid == uid {
+			return []dashboard{dash}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (t *testFolderDashboardLoader) LoadFolderDashboards(_ context.Context, _ int64, folderID int64) ([]dashboard, error) {
+	var dashboards []dashboard
+	for _, dash := range t.dashboards {
+		if !dash.isFolder && dash.folderID == folderID {
+			dashboards = append(dashboards, dash)
+		}
+	}
+	return dashboards, nil
+}
+
+func TestDashboardIndex_FolderMoves(t *testing.T) {
+	newIndex := func(t *testing.T) (*searchIndex, *testFolderDashboardLoader, *orgIndex) {
+		t.Helper()
+		loader := &testFolderDashboardLoader{dashboards: []dashboard{
+			{id: 0, uid: "", isFolder: true, info: &extract.DashboardInfo{Title: "General"}},
+			{id: 10, uid: "ops", isFolder: true, info: &extract.DashboardInfo{Title: "Ops"}},
+			{id: 11, uid: "nginx", folderID: 10, info: &extract.DashboardInfo{Title: "Nginx", Panels: []extract.PanelInfo{{ID: 1, Title: "Requests"}, {ID: 2, Title: "Errors"}}}},
+			{id: 12, uid: "mysql", folderID: 10, info: &extract.DashboardInfo{Title: "MySQL"}},
+		}}
+		index := newSearchIndex(loader, &store.MockEntityEventsService{}, &NoopDocumentExtender{}, func(ctx context.Context, folderId int64) (string, error) { return "ops", nil }, tracing.InitializeTracerForTest(), featuremgmt.WithFeatures(), setting.SearchSettings{}, nil)
+		_, err := index.buildOrgIndex(context.Background(), testOrgID)
+		require.NoError(t, err)
+		orgIdx, ok := index.getOrgIndex(testOrgID)
+		require.True(t, ok)
+		return index, loader, orgIdx
+	}
+
+	requireLocation := func(t *testing.T, orgIdx *orgIndex, uid string, expected string) {
+		t.Helper()
+		location, ok, err := getDashboardLocation(orgIdx, uid)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, expected, location)
+	}
+
+	t.Run("dashboards follow a folder whose UID changed", func(t *testing.T) {
+		index, loader, orgIdx := newIndex(t)
+		loader.dashboards[1].uid = "platform"
+
+		require.NoError(t, index.applyEvent(context.Background(), testOrgID, store.EntityTypeFolder, "platform", store.EntityEventTypeUpdate))
+
+		requireLocation(t, orgIdx, "nginx", "platform")
+		requireLocation(t, orgIdx, "mysql", "platform")
+		panelIDs, err := getDashboardPanelIDs(orgIdx, "platform/nginx")
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"nginx#1", "nginx#2"}, panelIDs)
+		panelIDs, err = getDashboardPanelIDs(orgIdx, "ops/nginx")
+		require.NoError(t, err)
+		require.Empty(t, panelIDs)
+
+		indexed, err := isFolderIndexed(orgIdx, "ops")
+		require.NoError(t, err)
+		require.False(t, indexed, "the folder is no longer indexed under its previous UID")
+	})
+
+	t.Run("renaming a folder only updates the folder", func(t *testing.T) {
+		index, loader, orgIdx := newIndex(t)
+		loader.dashboards[1].info = &extract.DashboardInfo{Title: "Operations"}
+		// dashboards are only re-indexed when the folder UID changed
+		loader.dashboards[2].info = &extract.DashboardInfo{Title: "Nginx renamed"}
+
+		require.NoError(t, index.applyEvent(context.Background(), testOrgID, store.EntityTypeFolder, "ops", store.EntityEventTypeUpdate))
+
+		resp := doSearchQuery(context.Background(), testLogger, orgIdx, testAllowAllFilter,
+			DashboardQuery{Query: "renamed", Kind: []string{string(entityKindDashboard)}}, &NoopQueryExtender{}, "")
+		rows, _ := resp.Frames[0].RowLen()
+		require.Zero(t, rows)
+		resp = doSearchQuery(context.Background(), testLogger, orgIdx, testAllowAllFilter,
+			DashboardQuery{Query: "Operations", Kind: []string{string(entityKindFolder)}}, &NoopQueryExtender{}, "")
+		rows, _ = resp.Frames[0].RowLen()
+		require.Equal(t, 1, rows)
+	})
+
+	t.Run("panels of a dashboard moved to another folder are not left behind", func(t *testing.T) {
+		index, _, orgIdx := newIndex(t)
+
+		require.NoError(t, index.updateDashboard(context.Background(), testOrgID, orgIdx, dashboard{
+			id: 11, uid: "nginx", folderID: 0,
+			info: &extract.DashboardInfo{Title: "Nginx", Panels: []extract.PanelInfo{{ID: 1, Title: "Requests"}}},
+		}))
+
+		requireLocation(t, orgIdx, "nginx", "general")
+		panelIDs, err := getDashboardPanelIDs(orgIdx, "general/nginx")
+		require.NoError(t, err)
+		require.Equal(t, []string{"nginx#1"}, panelIDs)
+		panelIDs, err = getDashboardPanelIDs(orgIdx, "ops/nginx")
+		require.NoError(t, err)
+		require.Empty(t, panelIDs)
+	})
+}
+
 var dashboardsWithPanels = []dashboard{
 	{
 		id:  1,
@@ -741,3 +857,119 @@ func TestDashboardIndex_MultiTermPrefixMatch(t *testing.T) {
 		})
 	}
 }
+
+func TestFullReindexSchedule(t *testing.T) {
+	settings := setting.SearchSettings{
+		FullReindexInterval:         5 * time.Minute,
+		LargeOrgDashboardThreshold:  2,
+		LargeOrgFullReindexInterval: 24 * time.Hour,
+	}
+
+	t.Run("interval depends on org size", func(t *testing.T) {
+		index := newSearchIndex(&testDashboardLoader{}, &store.MockEntityEventsService{}, &NoopDocumentExtender{}, nil, tracing.InitializeTracerForTest(), featuremgmt.WithFeatures(), settings, nil)
+		require.Equal(t, 5*time.Minute, index.fullReindexInterval(1))
+		require.Equal(t, 24*time.Hour, index.fullReindexInterval(2))
+		require.Equal(t, 5*time.Minute, index.fullReindexCheckInterval())
+	})
+
+	t.Run("status reports next full re-index", func(t *testing.T) {
+		loader := &testDashboardLoader{dashboards: testDashboards}
+		index := newSearchIndex(loader, &store.MockEntityEventsService{}, &NoopDocumentExtender{}, nil, tracing.InitializeTracerForTest(), featuremgmt.WithFeatures(), settings, nil)
+		_, err := index.buildOrgIndex(context.Background(), testOrgID)
+		require.NoError(t, err)
+
+		status := index.getOrgStatus(testOrgID)
+		require.Equal(t, len(testDashboards), status.DashboardCount)
+		require.Equal(t, "24h0m0s", status.FullReindexInterval)
+		require.Equal(t, status.LastFullReindex.Add(24*time.Hour), status.NextFullReindex)
+	})
+
+	t.Run("orgs which are not due are skipped unless forced", func(t *testing.T) {
+		loader := &testDashboardLoader{dashboards: testDashboards}
+		index := newSearchIndex(loader, &store.MockEntityEventsService{}, &NoopDocumentExtender{}, nil, tracing.InitializeTracerForTest(), featuremgmt.WithFeatures(), settings, nil)
+		_, err := index.buildOrgIndex(context.Background(), testOrgID)
+		require.NoError(t, err)
+		lastFullReindex := index.getOrgStatus(testOrgID).LastFullReindex
+
+		index.reIndexFromScratch(context.Background(), false)
+		require.Equal(t, lastFullReindex, index.getOrgStatus(testOrgID).LastFullReindex)
+
+		index.reIndexFromScratch(context.Background(), true)
+		require.True(t, index.getOrgStatus(testOrgID).LastFullReindex.After(lastFullReindex))
+	})
+}
+
+func TestWaitUntilReady(t *testing.T) {
+	newIndex := func() *searchIndex {
+		loader := &testDashboardLoader{dashboards: testDashboards}
+		return newSearchIndex(loader, &store.MockEntityEventsService{}, &NoopDocumentExtender{}, nil, tracing.InitializeTracerForTest(), featuremgmt.WithFeatures(), setting.SearchSettings{}, nil)
+	}
+
+	t.Run("returns once the initial indexing completes", func(t *testing.T) {
+		index := newIndex()
+		go func() {
+			if _, err := index.buildOrgIndex(context.Background(), testOrgID); err != nil {
+				t.Error(err)
+			}
+			index.initializationMutex.Lock()
+			index.initialIndexingComplete = true
+			index.notifyReadinessChanged()
+			index.initializationMutex.Unlock()
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		require.True(t, index.waitUntilReady(ctx, testOrgID).IsReady)
+	})
+
+	t.Run("gives up when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		resp := newIndex().waitUntilReady(ctx, testOrgID)
+		require.False(t, resp.IsReady)
+		require.Equal(t, "initial-indexing-ongoing", resp.Reason)
+	})
+}
+
+func TestIndexGeneration(t *testing.T) {
+	loader := &testFolderDashboardLoader{dashboards: []dashboard{
+		{id: 1, uid: "nginx", info: &extract.DashboardInfo{Title: "Nginx"}},
+	}}
+	index := newSearchIndex(loader, &store.MockEntityEventsService{}, &NoopDocumentExtender{}, nil, tracing.InitializeTracerForTest(), featuremgmt.WithFeatures(), setting.SearchSettings{}, nil)
+	_, err := index.buildOrgIndex(context.Background(), testOrgID)
+	require.NoError(t, err)
+
+	searchMeta := func(t *testing.T) *customMeta {
+		t.Helper()
+		orgIdx, ok := index.getOrgIndex(testOrgID)
+		require.True(t, ok)
+		resp := doSearchQuery(context.Background(), testLogger, orgIdx, testAllowAllFilter, DashboardQuery{}, &NoopQueryExtender{}, "")
+		require.NoError(t, resp.Error)
+		return resp.Frames[0].Meta.Custom.(*customMeta)
+	}
+
+	meta := searchMeta(t)
+	status := index.getOrgStatus(testOrgID)
+	require.Equal(t, int64(1), meta.IndexGeneration)
+	require.Equal(t, status.Generation, meta.IndexGeneration)
+	require.NotNil(t, meta.IndexUpdated)
+	require.Equal(t, status.LastUpdated, *meta.IndexUpdated)
+
+	t.Run("changes update the timestamp but keep the generation", func(t *testing.T) {
+		built := *meta.IndexUpdated
+		loader.dashboards[0].info = &extract.DashboardInfo{Title: "Nginx renamed"}
+		require.NoError(t, index.applyEvent(context.Background(), testOrgID, store.EntityTypeDashboard, "nginx", store.EntityEventTypeUpdate))
+
+		changed := searchMeta(t)
+		require.Equal(t, int64(1), changed.IndexGeneration)
+		require.True(t, changed.IndexUpdated.After(built))
+	})
+
+	t.Run("rebuilds get the next generation", func(t *testing.T) {
+		_, err := index.buildOrgIndex(context.Background(), testOrgID)
+		require.NoError(t, err)
+
+		require.Equal(t, int64(2), searchMeta(t).IndexGeneration)
+		require.Equal(t, int64(2), index.getOrgStatus(testOrgID).Generation)
+	})
+}
@@ -0,0 +1,132 @@
+package searchV2
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/blugelabs/bluge"
+	"github.com/blugelabs/bluge/analysis"
+	"github.com/blugelabs/bluge/analysis/lang/ar"
+	"github.com/blugelabs/bluge/analysis/lang/cjk"
+	"github.com/blugelabs/bluge/analysis/lang/de"
+	"github.com/blugelabs/bluge/analysis/lang/en"
+	"github.com/blugelabs/bluge/analysis/lang/es"
+	"github.com/blugelabs/bluge/analysis/lang/fr"
+	"github.com/blugelabs/bluge/analysis/lang/hi"
+	"github.com/blugelabs/bluge/analysis/lang/it"
+	"github.com/blugelabs/bluge/analysis/lang/nl"
+	"github.com/blugelabs/bluge/analysis/lang/pt"
+	"github.com/blugelabs/bluge/analysis/lang/ru"
+	"github.com/blugelabs/bluge/analysis/lang/sv"
+)
+
+// languageAnalyzers are the analyzers used for the language specific name field, keyed
+// by the language code stored in the language field.
+var languageAnalyzers = map[string]*analysis.Analyzer{
+	"ar":  ar.Analyzer(),
+	"cjk": cjk.Analyzer(),
+	"de":  de.Analyzer(),
+	"en":  en.NewAnalyzer(),
+	"es":  es.Analyzer(),
+	"fr":  fr.Analyzer(),
+	"hi":  hi.Analyzer(),
+	"it":  it.Analyzer(),
+	"nl":  nl.Analyzer(),
+	"pt":  pt.Analyzer(),
+	"ru":  ru.Analyzer(),
+	"sv":  sv.Analyzer(),
+}
+
+// Languages written in the Latin script are told apart by their stop words.
+var latinStopWords = map[string]analysis.TokenMap{
+	"de": de.StopWords(),
+	"en": en.StopWords(),
+	"es": es.StopWords(),
+	"fr": fr.StopWords(),
+	"it": it.StopWords(),
+	"nl": nl.StopWords(),
+	"pt": pt.StopWords(),
+	"sv": sv.StopWords(),
+}
+
+// minLanguageStopWords is the number of stop words needed to pick a Latin script language,
+// short texts such as most titles are left undetected.
+const minLanguageStopWords = 2
+
+// detectLanguage returns the dominant language of the texts, or an empty string when it
+// can't be told with enough confidence.
+func detectLanguage(texts ...string) string {
+	scripts := make(map[string]int)
+	letters := 0
+	for _, text := range texts {
+		for _, r := range text {
+			if !unicode.IsLetter(r) {
+				continue
+			}
+			letters++
+			switch {
+			case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+				scripts["cjk"]++
+			case unicode.Is(unicode.Cyrillic, r):
+				scripts["ru"]++
+			case unicode.Is(unicode.Arabic, r):
+				scripts["ar"]++
+			case unicode.Is(unicode.Devanagari, r):
+				scripts["hi"]++
+			}
+		}
+	}
+	for lang, count := range scripts {
+		if count*2 > letters {
+			return lang
+		}
+	}
+
+	hits := make(map[string]int, len(latinStopWords))
+	for _, text := range texts {
+		for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && r != '\''
+		}) {
+			for lang, stopWords := range latinStopWords {
+				if stopWords[word] {
+					hits[lang]++
+				}
+			}
+		}
+	}
+
+	best, bestHits, tied := "", 0, false
+	for lang, count := range hits {
+		switch {
+		case count > bestHits:
+			best, bestHits, tied = lang, count, false
+		case count == bestHits:
+			tied = true
+		}
+	}
+	if tied || bestHits < minLanguageStopWords {
+		return ""
+	}
+	return best
+}
+
+// dashboardLanguage detects the language of a dashboard from its own and its panels' text.
+func dashboardLanguage(dash dashboard) string {
+	texts := []string{dash.info.Title, dash.info.Description}
+	for _, panel := range dash.info.Panels {
+		texts = append(texts, panel.Title, panel.Description)
+	}
+	return detectLanguage(texts...)
+}
+
+// addLanguageFields indexes the language of a document, and its name with the analyzer of that language.
+func addLanguageFields(doc *bluge.Document, language string, name string) {
+	analyzer, ok := languageAnalyzers[language]
+	if !ok {
+		return
+	}
+	doc.AddField(bluge.NewKeywordField(documentFieldLanguage, language).Aggregatable().StoreValue())
+	if name != "" {
+		doc.AddField(bluge.NewTextField(documentFieldName_lang, name).WithAnalyzer(analyzer))
+	}
+}
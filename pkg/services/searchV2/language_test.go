@@ -0,0 +1,73 @@
+package searchV2
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/searchV2/extract"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		texts    []string
+		expected string
+	}{
+		{texts: []string{"Requests per second", "Shows the number of requests that are served by the API"}, expected: "en"},
+		{texts: []string{"Anfragen pro Sekunde", "Zeigt die Anzahl der Anfragen, die von der API bedient werden"}, expected: "de"},
+		{texts: []string{"Requêtes par seconde", "Affiche le nombre de requêtes qui sont servies par les serveurs"}, expected: "fr"},
+		{texts: []string{"Запросы в секунду"}, expected: "ru"},
+		{texts: []string{"每秒请求数"}, expected: "cjk"},
+		{texts: []string{"CPU"}, expected: ""},
+		{texts: []string{"Node exporter"}, expected: ""},
+	}
+	for _, tc := range tests {
+		require.Equal(t, tc.expected, detectLanguage(tc.texts...), tc.texts)
+	}
+}
+
+func TestDashboardIndex_Language(t *testing.T) {
+	index := initTestOrgIndexFromDashes(t, []dashboard{
+		{
+			id:  1,
+			uid: "english",
+			info: &extract.DashboardInfo{
+				Title:       "Running services",
+				Description: "The state of all the services that are running in the cluster",
+			},
+		},
+		{
+			id:  2,
+			uid: "german",
+			info: &extract.DashboardInfo{
+				Title:       "Laufende Dienste",
+				Description: "Der Zustand aller Dienste, die in dem Cluster laufen und die wir betreiben",
+			},
+		},
+		{
+			id:   3,
+			uid:  "unknown",
+			info: &extract.DashboardInfo{Title: "Services"},
+		},
+	})
+
+	search := func(t *testing.T, q DashboardQuery) []string {
+		t.Helper()
+		q.Kind = []string{string(entityKindDashboard)}
+		resp := doSearchQuery(context.Background(), testLogger, index, testAllowAllFilter, q, &NoopQueryExtender{}, "")
+		require.NoError(t, resp.Error)
+		uidField, _ := resp.Frames[0].FieldByName("uid")
+		uids := make([]string, 0, uidField.Len())
+		for i := 0; i < uidField.Len(); i++ {
+			uids = append(uids, uidField.At(i).(string))
+		}
+		return uids
+	}
+
+	require.Equal(t, []string{"german"}, search(t, DashboardQuery{Language: "de"}))
+	require.Equal(t, []string{"english"}, search(t, DashboardQuery{Language: "en"}))
+	// stemmed with the English analyzer, "runs" matches "Running"
+	require.Equal(t, []string{"english"}, search(t, DashboardQuery{Query: "service runs", Language: "en"}))
+	require.Empty(t, search(t, DashboardQuery{Query: "service runs"}))
+}
@@ -0,0 +1,265 @@
+package searchV2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+var (
+	dashboardSearchQueriesInFlight = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "dashboard_search_queries_in_flight",
+			Help:      "The number of dashboard search queries being executed",
+		})
+	dashboardSearchQueriesQueued = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "dashboard_search_queries_queued",
+			Help:      "The number of dashboard search queries waiting for a concurrency slot",
+		})
+	dashboardSearchQueriesRejectedCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "dashboard_search_queries_rejected_total",
+			Help:      "A counter for dashboard search queries rejected by the concurrency limiter",
+		},
+		[]string{"reason"},
+	)
+	dashboardSearchQueueDuration = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "dashboard_search_queue_duration_seconds",
+			Help:      "Time dashboard search queries waited for a concurrency slot",
+			Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+		})
+)
+
+// ErrTooManyQueries is returned when a search query is rejected by the concurrency limiter.
+var ErrTooManyQueries = errors.New("too many concurrent search queries")
+
+// queryLimitError is a rejection by the concurrency limiter, carrying when the query can be retried.
+type queryLimitError struct {
+	reason     string
+	retryAfter time.Duration
+}
+
+func (e *queryLimitError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrTooManyQueries, e.reason)
+}
+
+func (e *queryLimitError) Is(target error) bool {
+	return target == ErrTooManyQueries
+}
+
+// queryLimiter bounds the number of search queries executed at once, globally and per organization.
+// Queries over the limits wait in a queue per organization. Freed slots are handed to the queues in
+// turn, so that an organization with many queued queries can't starve the other ones.
+type queryLimiter struct {
+	maxGlobal    int
+	maxPerOrg    int
+	maxQueued    int
+	queueTimeout time.Duration
+
+	mu          sync.Mutex
+	inFlight    int
+	orgInFlight map[int64]int
+	queues      map[int64][]*queryWaiter
+	orgTurns    []int64 // organizations with queued queries, in the order they are served
+	queued      int
+}
+
+type queryWaiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+// newQueryLimiter returns nil, meaning no limits, when neither the global nor the per org limit is set.
+func newQueryLimiter(settings setting.SearchSettings) *queryLimiter {
+	if settings.MaxConcurrentQueries <= 0 && settings.MaxConcurrentQueriesPerOrg <= 0 {
+		return nil
+	}
+	return &queryLimiter{
+		maxGlobal:    settings.MaxConcurrentQueries,
+		maxPerOrg:    settings.MaxConcurrentQueriesPerOrg,
+		maxQueued:    settings.MaxQueuedQueries,
+		queueTimeout: settings.QueryQueueTimeout,
+		orgInFlight:  make(map[int64]int),
+		queues:       make(map[int64][]*queryWaiter),
+	}
+}
+
+// acquire waits for a slot to execute a query of the organization. The returned function must be
+// called once the query is done.
+func (l *queryLimiter) acquire(ctx context.Context, orgID int64) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	l.mu.Lock()
+	// queries of an organization are served in order, don't overtake the queued ones
+	if len(l.queues[orgID]) == 0 && l.canRun(orgID) {
+		l.start(orgID)
+		l.mu.Unlock()
+		return l.releaseFunc(orgID), nil
+	}
+	if l.queued >= l.maxQueued {
+		l.mu.Unlock()
+		return nil, l.reject("queue_full")
+	}
+
+	w := &queryWaiter{ready: make(chan struct{})}
+	if len(l.queues[orgID]) == 0 {
+		l.orgTurns = append(l.orgTurns, orgID)
+	}
+	l.queues[orgID] = append(l.queues[orgID], w)
+	l.queued++
+	dashboardSearchQueriesQueued.Inc()
+	l.mu.Unlock()
+
+	queuedAt := time.Now()
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+
+	var reason string
+	select {
+	case <-w.ready:
+		dashboardSearchQueueDuration.Observe(time.Since(queuedAt).Seconds())
+		return l.releaseFunc(orgID), nil
+	case <-timer.C:
+		reason = "queue_timeout"
+	case <-ctx.Done():
+		reason = "canceled"
+	}
+
+	l.mu.Lock()
+	if w.granted {
+		// the slot was handed over while giving up
+		l.mu.Unlock()
+		l.releaseFunc(orgID)()
+	} else {
+		l.removeWaiter(orgID, w)
+		l.mu.Unlock()
+	}
+	if reason == "canceled" {
+		return nil, ctx.Err()
+	}
+	return nil, l.reject(reason)
+}
+
+func (l *queryLimiter) reject(reason string) error {
+	dashboardSearchQueriesRejectedCounter.With(prometheus.Labels{"reason": reason}).Inc()
+	retryAfter := l.queueTimeout
+	if retryAfter < time.Second {
+		retryAfter = time.Second
+	}
+	return &queryLimitError{reason: reason, retryAfter: retryAfter}
+}
+
+func (l *queryLimiter) canRun(orgID int64) bool {
+	return (l.maxGlobal <= 0 || l.inFlight < l.maxGlobal) &&
+		(l.maxPerOrg <= 0 || l.orgInFlight[orgID] < l.maxPerOrg)
+}
+
+func (l *queryLimiter) start(orgID int64) {
+	l.inFlight++
+	l.orgInFlight[orgID]++
+	dashboardSearchQueriesInFlight.Inc()
+}
+
+func (l *queryLimiter) releaseFunc(orgID int64) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			l.inFlight--
+			l.orgInFlight[orgID]--
+			if l.orgInFlight[orgID] == 0 {
+				delete(l.orgInFlight, orgID)
+			}
+			dashboardSearchQueriesInFlight.Dec()
+			l.dispatch()
+		})
+	}
+}
+
+// dispatch hands free slots to the queued queries, taking the organizations in turn.
+func (l *queryLimiter) dispatch() {
+	for i := 0; i < len(l.orgTurns); {
+		orgID := l.orgTurns[i]
+		if !l.canRun(orgID) {
+			if l.maxGlobal > 0 && l.inFlight >= l.maxGlobal {
+				return
+			}
+			i++
+			continue
+		}
+
+		w := l.queues[orgID][0]
+		l.queues[orgID] = l.queues[orgID][1:]
+		l.queued--
+		dashboardSearchQueriesQueued.Dec()
+		w.granted = true
+		l.start(orgID)
+		close(w.ready)
+
+		// the organization goes to the back of the line
+		l.orgTurns = append(l.orgTurns[:i], l.orgTurns[i+1:]...)
+		if len(l.queues[orgID]) > 0 {
+			l.orgTurns = append(l.orgTurns, orgID)
+		} else {
+			delete(l.queues, orgID)
+		}
+	}
+}
+
+func (l *queryLimiter) removeWaiter(orgID int64, w *queryWaiter) {
+	queue := l.queues[orgID]
+	for i, queued := range queue {
+		if queued == w {
+			l.queues[orgID] = append(queue[:i], queue[i+1:]...)
+			l.queued--
+			dashboardSearchQueriesQueued.Dec()
+			break
+		}
+	}
+	if len(l.queues[orgID]) > 0 {
+		return
+	}
+	delete(l.queues, orgID)
+	for i, turn := range l.orgTurns {
+		if turn == orgID {
+			l.orgTurns = append(l.orgTurns[:i], l.orgTurns[i+1:]...)
+			break
+		}
+	}
+}
+
+// tooManyQueriesResponse is the 429 response of a query rejected by the limiter.
+func tooManyQueriesResponse(err error) *response.NormalResponse {
+	retryAfter := time.Second
+	var limitErr *queryLimitError
+	if errors.As(err, &limitErr) {
+		retryAfter = limitErr.retryAfter
+	}
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	return response.Error(http.StatusTooManyRequests, "too many concurrent search queries", err).
+		SetHeader("Retry-After", strconv.Itoa(seconds))
+}
@@ -0,0 +1,118 @@
+package searchV2
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func newTestQueryLimiter(maxGlobal, maxPerOrg, maxQueued int, queueTimeout time.Duration) *queryLimiter {
+	return newQueryLimiter(setting.SearchSettings{
+		MaxConcurrentQueries:       maxGlobal,
+		MaxConcurrentQueriesPerOrg: maxPerOrg,
+		MaxQueuedQueries:           maxQueued,
+		QueryQueueTimeout:          queueTimeout,
+	})
+}
+
+// acquireAsync queues a query in the background, the channel receives the release function once granted.
+func acquireAsync(t *testing.T, l *queryLimiter, orgID int64) chan func() {
+	t.Helper()
+	l.mu.Lock()
+	queued := l.queued
+	l.mu.Unlock()
+
+	granted := make(chan func(), 1)
+	go func() {
+		release, err := l.acquire(context.Background(), orgID)
+		if err == nil {
+			granted <- release
+		}
+	}()
+	require.Eventually(t, func() bool {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return l.queued > queued
+	}, time.Second, time.Millisecond)
+	return granted
+}
+
+func TestQueryLimiter(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("is disabled without limits", func(t *testing.T) {
+		require.Nil(t, newTestQueryLimiter(0, 0, 10, time.Second))
+		var l *queryLimiter
+		release, err := l.acquire(ctx, 1)
+		require.NoError(t, err)
+		release()
+	})
+
+	t.Run("limits queries per organization", func(t *testing.T) {
+		l := newTestQueryLimiter(0, 1, 0, time.Second)
+
+		release, err := l.acquire(ctx, 1)
+		require.NoError(t, err)
+		_, err = l.acquire(ctx, 1)
+		require.ErrorIs(t, err, ErrTooManyQueries)
+
+		other, err := l.acquire(ctx, 2)
+		require.NoError(t, err)
+		other()
+
+		release()
+		release() // releasing twice is a no-op
+		release, err = l.acquire(ctx, 1)
+		require.NoError(t, err)
+		release()
+	})
+
+	t.Run("queues queries until they time out", func(t *testing.T) {
+		l := newTestQueryLimiter(1, 0, 1, 20*time.Millisecond)
+
+		release, err := l.acquire(ctx, 1)
+		require.NoError(t, err)
+		defer release()
+
+		_, err = l.acquire(ctx, 2)
+		var limitErr *queryLimitError
+		require.True(t, errors.As(err, &limitErr))
+		require.Equal(t, "queue_timeout", limitErr.reason)
+		require.Zero(t, l.queued)
+	})
+
+	t.Run("serves queued organizations in turn", func(t *testing.T) {
+		l := newTestQueryLimiter(1, 0, 10, time.Minute)
+
+		release, err := l.acquire(ctx, 1)
+		require.NoError(t, err)
+
+		first := acquireAsync(t, l, 1)
+		second := acquireAsync(t, l, 1)
+		other := acquireAsync(t, l, 2)
+		release()
+
+		(<-first)()
+		// org 2 is served before the second query of org 1
+		(<-other)()
+		(<-second)()
+	})
+
+	t.Run("responds with Retry-After", func(t *testing.T) {
+		l := newTestQueryLimiter(1, 0, 0, 3*time.Second)
+		release, err := l.acquire(ctx, 1)
+		require.NoError(t, err)
+		defer release()
+
+		_, err = l.acquire(ctx, 1)
+		rsp := tooManyQueriesResponse(err)
+		require.Equal(t, http.StatusTooManyRequests, rsp.Status())
+		require.Equal(t, "3", rsp.Header().Get("Retry-After"))
+	})
+}
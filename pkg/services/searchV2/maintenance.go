@@ -0,0 +1,47 @@
+package searchV2
+
+import (
+	"fmt"
+
+	"github.com/blugelabs/bluge"
+)
+
+// Values of DashboardQuery.Report, each matches the documents indexed with the same integrity flag.
+const (
+	// ReportGeneralFolder lists the dashboards that are not in any folder.
+	ReportGeneralFolder = "general_folder"
+	// ReportMissingFolder lists the dashboards whose folder does not exist anymore.
+	ReportMissingFolder = "missing_folder"
+	// ReportMissingDatasource lists the dashboards and panels referencing datasources that do not exist.
+	ReportMissingDatasource = "missing_datasource"
+)
+
+func validateReport(report string) error {
+	switch report {
+	case "", ReportGeneralFolder, ReportMissingFolder, ReportMissingDatasource:
+		return nil
+	}
+	return fmt.Errorf("invalid report: %s", report)
+}
+
+// dashboardIntegrity returns the integrity flags of a dashboard indexed in the given folder location,
+// the location is empty when the folder of the dashboard could not be found.
+func dashboardIntegrity(dash dashboard, location string) []string {
+	var flags []string
+	switch {
+	case location == "general", location == "" && dash.folderID == 0:
+		flags = append(flags, ReportGeneralFolder)
+	case location == "":
+		flags = append(flags, ReportMissingFolder)
+	}
+	if len(dash.info.MissingDatasource) > 0 {
+		flags = append(flags, ReportMissingDatasource)
+	}
+	return flags
+}
+
+func addIntegrityFields(doc *bluge.Document, flags []string) {
+	for _, flag := range flags {
+		doc.AddField(bluge.NewKeywordField(documentFieldIntegrity, flag).Aggregatable())
+	}
+}
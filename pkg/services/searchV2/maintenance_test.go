@@ -0,0 +1,40 @@
+package searchV2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/searchV2/extract"
+)
+
+func TestDashboardIndex_Report(t *testing.T) {
+	index := initTestOrgIndexFromDashes(t, []dashboard{
+		{id: 1, uid: "folder", isFolder: true, info: &extract.DashboardInfo{Title: "Folder"}},
+		{id: 2, uid: "in-folder", folderID: 1, info: &extract.DashboardInfo{Title: "In folder"}},
+		{id: 3, uid: "in-general", info: &extract.DashboardInfo{Title: "In general"}},
+		{id: 4, uid: "orphaned", folderID: 100, info: &extract.DashboardInfo{Title: "Orphaned"}},
+		{
+			id:       5,
+			uid:      "missing-ds",
+			folderID: 1,
+			info: &extract.DashboardInfo{
+				Title:             "Missing datasource",
+				MissingDatasource: []string{"deleted"},
+				Panels: []extract.PanelInfo{
+					{ID: 1, Title: "Broken", Type: "timeseries", MissingDatasource: []string{"deleted"}},
+					{ID: 2, Title: "Working", Type: "timeseries"},
+				},
+			},
+		},
+	})
+
+	search := func(report string) []string {
+		return searchUIDs(t, index, testAllowAllFilter, DashboardQuery{Report: report})
+	}
+
+	require.Equal(t, []string{"in-general"}, search(ReportGeneralFolder))
+	require.Equal(t, []string{"orphaned"}, search(ReportMissingFolder))
+	require.ElementsMatch(t, []string{"missing-ds", "missing-ds#1"}, search(ReportMissingDatasource))
+	require.Error(t, validateReport("unknown"))
+}
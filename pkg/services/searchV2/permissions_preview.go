@@ -0,0 +1,95 @@
+package searchV2
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+var (
+	ErrInvalidPermissionsPreview  = errors.New("permissions preview needs either a user or a team")
+	ErrPermissionsPreviewDenied   = errors.New("not allowed to preview the permissions of the user or team")
+	ErrPermissionsPreviewNotFound = errors.New("user or team to preview the permissions of not found in the organization")
+	// ErrTeamPermissionsPreviewUnsupported is returned for team previews when access control is disabled, as
+	// legacy dashboard permissions can only be evaluated for users.
+	ErrTeamPermissionsPreviewUnsupported = errors.New("team permissions previews need role-based access control")
+)
+
+// PermissionsPreview makes the search evaluate the results with the permissions of a user or team
+// of the organization instead of the ones of the signed in user, e.g. to find out why a user can't
+// find a dashboard without signing in as them.
+//
+// Previewing a user requires the org.users:impersonate permission on the user. Previewing a team,
+// which shows what the team grants its members on top of their organization role, requires it on
+// all users and teams:read on the team. Without role-based access control only organization
+// admins can preview users.
+type PermissionsPreview struct {
+	UserID int64 `json:"userId,omitempty"`
+	TeamID int64 `json:"teamId,omitempty"`
+}
+
+func (p *PermissionsPreview) evaluator() accesscontrol.Evaluator {
+	if p.TeamID > 0 {
+		return accesscontrol.EvalAll(
+			accesscontrol.EvalPermission(accesscontrol.ActionOrgUsersImpersonate, accesscontrol.ScopeUsersAll),
+			accesscontrol.EvalPermission(accesscontrol.ActionTeamsRead, accesscontrol.Scope("teams", "id", strconv.FormatInt(p.TeamID, 10))),
+		)
+	}
+	return accesscontrol.EvalPermission(accesscontrol.ActionOrgUsersImpersonate, accesscontrol.Scope("users", "id", strconv.FormatInt(p.UserID, 10)))
+}
+
+// getPreviewUser returns the user the results of a permissions preview are evaluated for, after
+// checking that the signed in user may preview them.
+func (s *StandardSearchService) getPreviewUser(ctx context.Context, signedInUser *user.SignedInUser, orgID int64, preview *PermissionsPreview) (*user.SignedInUser, error) {
+	if (preview.UserID > 0) == (preview.TeamID > 0) {
+		return nil, ErrInvalidPermissionsPreview
+	}
+
+	if s.ac.IsDisabled() {
+		if preview.TeamID > 0 {
+			return nil, ErrTeamPermissionsPreviewUnsupported
+		}
+		if signedInUser.OrgRole != org.RoleAdmin && !signedInUser.IsGrafanaAdmin {
+			return nil, ErrPermissionsPreviewDenied
+		}
+	} else if !preview.evaluator().Evaluate(signedInUser.Permissions[orgID]) {
+		return nil, ErrPermissionsPreviewDenied
+	}
+
+	if preview.TeamID > 0 {
+		var exists bool
+		err := s.sql.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+			var err error
+			exists, err = sess.Where("org_id=? AND id=?", orgID, preview.TeamID).Exist(&models.Team{})
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, ErrPermissionsPreviewNotFound
+		}
+		// the permissions of a member without organization role
+		previewUser := &user.SignedInUser{OrgID: orgID, Teams: []int64{preview.TeamID}}
+		return previewUser, s.loadUserPermissions(ctx, previewUser, orgID)
+	}
+
+	query := &models.GetSignedInUserQuery{UserId: preview.UserID, OrgId: orgID}
+	if err := s.sql.GetSignedInUser(ctx, query); err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return nil, ErrPermissionsPreviewNotFound
+		}
+		return nil, err
+	}
+	// users who aren't members of the organization are returned with another one
+	if query.Result == nil || query.Result.OrgID != orgID {
+		return nil, ErrPermissionsPreviewNotFound
+	}
+	return query.Result, s.loadUserPermissions(ctx, query.Result, orgID)
+}
@@ -0,0 +1,103 @@
+package searchV2
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	accesscontrolmock "github.com/grafana/grafana/pkg/services/accesscontrol/mock"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationPermissionsPreview(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+	sql := sqlstore.InitTestDB(t)
+	// the first user creates the organization and is made its admin
+	_, err := sql.CreateUser(ctx, user.CreateUserCommand{Login: "admin"})
+	require.NoError(t, err)
+	sql.Cfg.AutoAssignOrg = true
+	t.Cleanup(func() { sql.Cfg.AutoAssignOrg = false })
+	viewer, err := sql.CreateUser(ctx, user.CreateUserCommand{Login: "viewer", OrgID: 1, DefaultOrgRole: string(org.RoleViewer)})
+	require.NoError(t, err)
+	team := &models.Team{OrgId: 1, Name: "SRE", Created: time.Now(), Updated: time.Now()}
+	require.NoError(t, sql.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		_, err := sess.Insert(team)
+		return err
+	}))
+
+	// the permissions the previewed users and teams get
+	previewed := []accesscontrol.Permission{{Action: dashboards.ActionDashboardsRead, Scope: "dashboards:uid:sre"}}
+	s := &StandardSearchService{sql: sql, ac: accesscontrolmock.New().WithPermissions(previewed)}
+	admin := func(permissions map[string][]string) *user.SignedInUser {
+		return &user.SignedInUser{UserID: 100, OrgID: 1, OrgRole: org.RoleAdmin, Permissions: map[int64]map[string][]string{1: permissions, 2: permissions}}
+	}
+	impersonateAll := admin(map[string][]string{
+		accesscontrol.ActionOrgUsersImpersonate: {accesscontrol.ScopeUsersAll},
+		accesscontrol.ActionTeamsRead:           {accesscontrol.ScopeTeamsAll},
+	})
+
+	t.Run("previews users of the organization with their permissions", func(t *testing.T) {
+		usr, err := s.getPreviewUser(ctx, impersonateAll, 1, &PermissionsPreview{UserID: viewer.ID})
+		require.NoError(t, err)
+		require.Equal(t, viewer.ID, usr.UserID)
+		require.Equal(t, org.RoleViewer, usr.OrgRole)
+		require.Equal(t, []string{"dashboards:uid:sre"}, usr.Permissions[1][dashboards.ActionDashboardsRead])
+
+		_, err = s.getPreviewUser(ctx, impersonateAll, 2, &PermissionsPreview{UserID: viewer.ID})
+		require.ErrorIs(t, err, ErrPermissionsPreviewNotFound, "not a member of the organization")
+		_, err = s.getPreviewUser(ctx, impersonateAll, 1, &PermissionsPreview{UserID: viewer.ID + 1000})
+		require.ErrorIs(t, err, ErrPermissionsPreviewNotFound)
+	})
+
+	t.Run("previews teams as a member without organization role", func(t *testing.T) {
+		usr, err := s.getPreviewUser(ctx, impersonateAll, 1, &PermissionsPreview{TeamID: team.Id})
+		require.NoError(t, err)
+		require.Zero(t, usr.UserID)
+		require.Empty(t, usr.OrgRole)
+		require.Equal(t, []int64{team.Id}, usr.Teams)
+
+		_, err = s.getPreviewUser(ctx, impersonateAll, 2, &PermissionsPreview{TeamID: team.Id})
+		require.ErrorIs(t, err, ErrPermissionsPreviewNotFound)
+	})
+
+	t.Run("previews need the impersonate permission", func(t *testing.T) {
+		onlyViewer := admin(map[string][]string{
+			accesscontrol.ActionOrgUsersImpersonate: {accesscontrol.Scope("users", "id", strconv.FormatInt(viewer.ID, 10))},
+			accesscontrol.ActionTeamsRead:           {accesscontrol.ScopeTeamsAll},
+		})
+		_, err := s.getPreviewUser(ctx, onlyViewer, 1, &PermissionsPreview{UserID: viewer.ID})
+		require.NoError(t, err)
+
+		_, err = s.getPreviewUser(ctx, admin(map[string][]string{}), 1, &PermissionsPreview{UserID: viewer.ID})
+		require.ErrorIs(t, err, ErrPermissionsPreviewDenied)
+		_, err = s.getPreviewUser(ctx, onlyViewer, 1, &PermissionsPreview{TeamID: team.Id})
+		require.ErrorIs(t, err, ErrPermissionsPreviewDenied, "team previews need the permission on all users")
+		_, err = s.getPreviewUser(ctx, impersonateAll, 1, &PermissionsPreview{UserID: viewer.ID, TeamID: team.Id})
+		require.ErrorIs(t, err, ErrInvalidPermissionsPreview)
+	})
+
+	t.Run("without access control only org admins preview users", func(t *testing.T) {
+		legacy := &StandardSearchService{sql: sql, ac: accesscontrolmock.New().WithDisabled()}
+
+		usr, err := legacy.getPreviewUser(ctx, &user.SignedInUser{UserID: 100, OrgID: 1, OrgRole: org.RoleAdmin}, 1, &PermissionsPreview{UserID: viewer.ID})
+		require.NoError(t, err)
+		require.Equal(t, viewer.ID, usr.UserID)
+
+		_, err = legacy.getPreviewUser(ctx, &user.SignedInUser{UserID: 100, OrgID: 1, OrgRole: org.RoleEditor}, 1, &PermissionsPreview{UserID: viewer.ID})
+		require.ErrorIs(t, err, ErrPermissionsPreviewDenied)
+		_, err = legacy.getPreviewUser(ctx, &user.SignedInUser{UserID: 100, OrgID: 1, OrgRole: org.RoleAdmin}, 1, &PermissionsPreview{TeamID: team.Id})
+		require.ErrorIs(t, err, ErrTeamPermissionsPreviewUnsupported)
+	})
+}
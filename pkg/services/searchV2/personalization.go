@@ -0,0 +1,257 @@
+package searchV2
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/blugelabs/bluge"
+
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const (
+	// maxPersonalizationClicks is the number of dashboards remembered per user, most recent first
+	maxPersonalizationClicks = 50
+	// maxPersonalizationFolders is the number of folders counted per user, the least opened folder
+	// is forgotten to count a new one
+	maxPersonalizationFolders = 10
+)
+
+var (
+	ErrPersonalizationDisabled          = errors.New("search personalization is disabled")
+	ErrPersonalizationDashboardNotFound = errors.New("dashboard not found in the search index")
+)
+
+// PersonalizationClick is a dashboard a user opened from search results.
+type PersonalizationClick struct {
+	DashboardUID string    `json:"dashboardUid"`
+	FolderUID    string    `json:"folderUid,omitempty"`
+	ClickedAt    time.Time `json:"clickedAt"`
+}
+
+// PersonalizationFolder counts the dashboards of a folder a user opened from search results.
+type PersonalizationFolder struct {
+	FolderUID     string    `json:"folderUid"`
+	Clicks        int       `json:"clicks"`
+	LastClickedAt time.Time `json:"lastClickedAt"`
+}
+
+// PersonalizationSignals are the signals stored for a user to personalize the ranking of their
+// results. They are kept in memory of the instance the user searched on, and expire once the user
+// hasn't opened a dashboard from search for the personalization TTL.
+type PersonalizationSignals struct {
+	RecentClicks    []PersonalizationClick  `json:"recentClicks"`
+	FrequentFolders []PersonalizationFolder `json:"frequentFolders"`
+}
+
+// RecordSearchClickCommand records that a user opened a dashboard from search results.
+type RecordSearchClickCommand struct {
+	OrgID        int64  `json:"-"`
+	UserID       int64  `json:"-"`
+	DashboardUID string `json:"dashboardUid"`
+}
+
+type personalizationKey struct {
+	orgID  int64
+	userID int64
+}
+
+type userSignals struct {
+	updated time.Time
+	clicks  []PersonalizationClick
+	folders []PersonalizationFolder
+}
+
+// personalizationStore keeps the signals of the users in memory, bounded per user and in number of
+// users. The least recently active users are forgotten first.
+type personalizationStore struct {
+	features featuremgmt.FeatureToggles
+	weight   float64
+	ttl      time.Duration
+	maxUsers int
+	now      func() time.Time
+
+	mu    sync.Mutex
+	users map[personalizationKey]*userSignals
+}
+
+func newPersonalizationStore(settings setting.SearchSettings, features featuremgmt.FeatureToggles) *personalizationStore {
+	return &personalizationStore{
+		features: features,
+		weight:   settings.PersonalizationWeight,
+		ttl:      settings.PersonalizationTTL,
+		maxUsers: settings.PersonalizationMaxUsers,
+		now:      time.Now,
+		users:    make(map[personalizationKey]*userSignals),
+	}
+}
+
+// enabled tells whether signals are recorded and used for ranking.
+func (s *personalizationStore) enabled() bool {
+	return s != nil && s.features != nil && s.features.IsEnabled(featuremgmt.FlagSearchPersonalization) &&
+		s.ttl > 0 && s.maxUsers > 0
+}
+
+// record adds a click of the user on a dashboard of the folder, folderUID is empty for dashboards
+// of the General folder.
+func (s *personalizationStore) record(key personalizationKey, dashboardUID string, folderUID string) {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	signals := s.signalsLocked(key, now)
+	if signals == nil {
+		s.evictLocked(now)
+		signals = &userSignals{}
+		s.users[key] = signals
+	}
+	signals.updated = now
+
+	clicks := make([]PersonalizationClick, 0, len(signals.clicks)+1)
+	clicks = append(clicks, PersonalizationClick{DashboardUID: dashboardUID, FolderUID: folderUID, ClickedAt: now})
+	for _, click := range signals.clicks {
+		if click.DashboardUID != dashboardUID && len(clicks) < maxPersonalizationClicks {
+			clicks = append(clicks, click)
+		}
+	}
+	signals.clicks = clicks
+
+	if folderUID == "" {
+		return
+	}
+	for i := range signals.folders {
+		if signals.folders[i].FolderUID == folderUID {
+			signals.folders[i].Clicks++
+			signals.folders[i].LastClickedAt = now
+			sortPersonalizationFolders(signals.folders)
+			return
+		}
+	}
+	if len(signals.folders) >= maxPersonalizationFolders {
+		signals.folders = signals.folders[:maxPersonalizationFolders-1]
+	}
+	signals.folders = append(signals.folders, PersonalizationFolder{FolderUID: folderUID, Clicks: 1, LastClickedAt: now})
+	sortPersonalizationFolders(signals.folders)
+}
+
+// get returns a copy of the signals of the user.
+func (s *personalizationStore) get(key personalizationKey) PersonalizationSignals {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := PersonalizationSignals{RecentClicks: []PersonalizationClick{}, FrequentFolders: []PersonalizationFolder{}}
+	if signals := s.signalsLocked(key, s.now()); signals != nil {
+		result.RecentClicks = append(result.RecentClicks, signals.clicks...)
+		result.FrequentFolders = append(result.FrequentFolders, signals.folders...)
+	}
+	return result
+}
+
+// clear forgets the signals of the user.
+func (s *personalizationStore) clear(key personalizationKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.users, key)
+}
+
+// boosts returns the boosts of the results of the user, nil when the user has no signals.
+func (s *personalizationStore) boosts(key personalizationKey) *personalizationBoosts {
+	if !s.enabled() || s.weight <= 0 {
+		return nil
+	}
+	signals := s.get(key)
+	if len(signals.RecentClicks) == 0 {
+		return nil
+	}
+
+	// the most recently opened dashboards get up to the weight, folders up to half of it
+	boosts := &personalizationBoosts{dashboards: map[string]float64{}, folders: map[string]float64{}}
+	for i, click := range signals.RecentClicks {
+		boosts.dashboards[click.DashboardUID] = s.weight * float64(maxPersonalizationClicks-i) / maxPersonalizationClicks
+	}
+	for _, folder := range signals.FrequentFolders {
+		boosts.folders[folder.FolderUID] = s.weight / 2 * float64(folder.Clicks) / float64(signals.FrequentFolders[0].Clicks)
+	}
+	return boosts
+}
+
+// signalsLocked returns the signals of the user without the expired ones, nil once they all expired.
+func (s *personalizationStore) signalsLocked(key personalizationKey, now time.Time) *userSignals {
+	signals, ok := s.users[key]
+	if !ok {
+		return nil
+	}
+	expired := now.Add(-s.ttl)
+	if !signals.updated.After(expired) {
+		delete(s.users, key)
+		return nil
+	}
+	clicks := signals.clicks[:0]
+	for _, click := range signals.clicks {
+		if click.ClickedAt.After(expired) {
+			clicks = append(clicks, click)
+		}
+	}
+	signals.clicks = clicks
+	folders := signals.folders[:0]
+	for _, folder := range signals.folders {
+		if folder.LastClickedAt.After(expired) {
+			folders = append(folders, folder)
+		}
+	}
+	signals.folders = folders
+	return signals
+}
+
+// evictLocked makes room for a new user, forgetting the expired users and then the least recently
+// active ones.
+func (s *personalizationStore) evictLocked(now time.Time) {
+	if len(s.users) < s.maxUsers {
+		return
+	}
+	expired := now.Add(-s.ttl)
+	for key, signals := range s.users {
+		if !signals.updated.After(expired) {
+			delete(s.users, key)
+		}
+	}
+	for len(s.users) >= s.maxUsers {
+		var oldest personalizationKey
+		var oldestUpdated time.Time
+		for key, signals := range s.users {
+			if oldestUpdated.IsZero() || signals.updated.Before(oldestUpdated) {
+				oldest, oldestUpdated = key, signals.updated
+			}
+		}
+		delete(s.users, oldest)
+	}
+}
+
+func sortPersonalizationFolders(folders []PersonalizationFolder) {
+	sort.SliceStable(folders, func(i, j int) bool {
+		if folders[i].Clicks != folders[j].Clicks {
+			return folders[i].Clicks > folders[j].Clicks
+		}
+		return folders[i].LastClickedAt.After(folders[j].LastClickedAt)
+	})
+}
+
+// personalizationBoosts are the scores added to the dashboards a user recently opened and to the
+// dashboards of the folders they open most.
+type personalizationBoosts struct {
+	dashboards map[string]float64
+	folders    map[string]float64
+}
+
+func (b *personalizationBoosts) query() bluge.Query {
+	bq := bluge.NewBooleanQuery()
+	for uid, boost := range b.dashboards {
+		bq.AddShould(bluge.NewTermQuery(uid).SetField(documentFieldUID).SetBoost(boost))
+	}
+	for uid, boost := range b.folders {
+		bq.AddShould(bluge.NewTermQuery(uid).SetField(documentFieldLocation).SetBoost(boost))
+	}
+	return bq
+}
@@ -0,0 +1,120 @@
+package searchV2
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/searchV2/extract"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func newTestPersonalizationStore(maxUsers int) (*personalizationStore, *time.Time) {
+	now := time.Now()
+	store := newPersonalizationStore(setting.SearchSettings{
+		PersonalizationWeight:   2,
+		PersonalizationTTL:      time.Hour,
+		PersonalizationMaxUsers: maxUsers,
+	}, featuremgmt.WithFeatures(featuremgmt.FlagSearchPersonalization))
+	store.now = func() time.Time { return now }
+	return store, &now
+}
+
+func TestPersonalizationStore(t *testing.T) {
+	ana := personalizationKey{orgID: 1, userID: 1}
+	bob := personalizationKey{orgID: 1, userID: 2}
+
+	t.Run("disabled without the feature flag", func(t *testing.T) {
+		store := newPersonalizationStore(setting.SearchSettings{PersonalizationTTL: time.Hour, PersonalizationMaxUsers: 1}, featuremgmt.WithFeatures())
+		require.False(t, store.enabled())
+		require.Nil(t, store.boosts(ana))
+		var nilStore *personalizationStore
+		require.False(t, nilStore.enabled())
+	})
+
+	t.Run("clicks and folders are bounded per user", func(t *testing.T) {
+		store, now := newTestPersonalizationStore(10)
+		for i := 0; i < maxPersonalizationClicks+10; i++ {
+			*now = now.Add(time.Second)
+			store.record(ana, fmt.Sprintf("dash-%d", i), fmt.Sprintf("folder-%d", i%(maxPersonalizationFolders+5)))
+		}
+		*now = now.Add(time.Second)
+		store.record(ana, "dash-20", "folder-5")
+
+		signals := store.get(ana)
+		require.Len(t, signals.RecentClicks, maxPersonalizationClicks)
+		require.Equal(t, "dash-20", signals.RecentClicks[0].DashboardUID)
+		require.Equal(t, "dash-59", signals.RecentClicks[1].DashboardUID)
+		require.Len(t, signals.FrequentFolders, maxPersonalizationFolders)
+		require.Equal(t, "folder-5", signals.FrequentFolders[0].FolderUID)
+		require.Empty(t, store.get(bob).RecentClicks)
+	})
+
+	t.Run("signals expire after the TTL", func(t *testing.T) {
+		store, now := newTestPersonalizationStore(10)
+		store.record(ana, "old", "ops")
+		*now = now.Add(40 * time.Minute)
+		store.record(ana, "recent", "")
+		*now = now.Add(30 * time.Minute)
+
+		signals := store.get(ana)
+		require.Len(t, signals.RecentClicks, 1)
+		require.Equal(t, "recent", signals.RecentClicks[0].DashboardUID)
+		require.Empty(t, signals.FrequentFolders)
+
+		*now = now.Add(time.Hour)
+		require.Empty(t, store.get(ana).RecentClicks)
+		require.Empty(t, store.users)
+	})
+
+	t.Run("the least recently active users are evicted", func(t *testing.T) {
+		store, now := newTestPersonalizationStore(2)
+		store.record(ana, "a", "")
+		*now = now.Add(time.Second)
+		store.record(bob, "b", "")
+		*now = now.Add(time.Second)
+		store.record(ana, "c", "")
+		*now = now.Add(time.Second)
+		store.record(personalizationKey{orgID: 2, userID: 1}, "d", "")
+
+		require.Len(t, store.users, 2)
+		require.Len(t, store.get(ana).RecentClicks, 2)
+		require.Empty(t, store.get(bob).RecentClicks)
+	})
+
+	t.Run("signals can be cleared", func(t *testing.T) {
+		store, _ := newTestPersonalizationStore(10)
+		store.record(ana, "a", "ops")
+		store.clear(ana)
+		require.Empty(t, store.get(ana).RecentClicks)
+		require.Nil(t, store.boosts(ana))
+	})
+}
+
+func TestDashboardIndex_Personalization(t *testing.T) {
+	index := initTestOrgIndexFromDashes(t, []dashboard{
+		{id: 1, uid: "latency", info: &extract.DashboardInfo{Title: "Latency"}},
+		{id: 2, uid: "latency-overview", info: &extract.DashboardInfo{Title: "Latency overview"}},
+		{id: 3, uid: "latency-ops", folderID: 1, folderUID: "ops", info: &extract.DashboardInfo{Title: "Latency overview of the service"}},
+	})
+	store, _ := newTestPersonalizationStore(10)
+	ana := personalizationKey{orgID: testOrgID, userID: 1}
+	search := func() []string {
+		q := DashboardQuery{Query: "latency", Kind: []string{string(entityKindDashboard)}, personalization: store.boosts(ana)}
+		return searchUIDs(t, index, testAllowAllFilter, q)
+	}
+
+	require.Equal(t, "latency", search()[0])
+
+	location, found, err := getDashboardLocation(index, "latency-ops")
+	require.NoError(t, err)
+	require.True(t, found)
+	store.record(ana, "other-ops", location)
+	require.Equal(t, "latency-ops", search()[0])
+
+	store.record(ana, "latency-overview", "")
+	require.Equal(t, "latency-overview", search()[0])
+}
@@ -0,0 +1,259 @@
+package searchV2
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/sqlstore/db"
+)
+
+const (
+	// promotedResultsCacheTTL is how long the promoted results of an organization are cached, changes
+	// made on other Grafana instances are picked up once it expires
+	promotedResultsCacheTTL = time.Minute
+	// maxPromotedTermLength is the length of the term column
+	maxPromotedTermLength = 190
+)
+
+var (
+	ErrPromotedResultNotFound = errors.New("promoted result not found")
+	ErrPromotedResultExists   = errors.New("dashboard is already promoted for the term")
+	ErrInvalidPromotedResult  = errors.New("promoted results need a term of at most 190 characters and a dashboard UID")
+)
+
+// PromotedResult pins a dashboard at the top of the results of the queries for a term, e.g. the
+// canonical SLO dashboard for "SLO".
+type PromotedResult struct {
+	ID           int64  `json:"id" xorm:"pk autoincr 'id'"`
+	OrgID        int64  `json:"orgId" xorm:"org_id"`
+	Term         string `json:"term" xorm:"term"`
+	DashboardUID string `json:"dashboardUid" xorm:"dashboard_uid"`
+	// Position orders the dashboards promoted for the same term, lowest first
+	Position int       `json:"position" xorm:"position"`
+	Created  time.Time `json:"created" xorm:"created"`
+}
+
+func (PromotedResult) TableName() string { return "search_promoted_result" }
+
+type AddPromotedResultCommand struct {
+	OrgID        int64  `json:"-"`
+	Term         string `json:"term"`
+	DashboardUID string `json:"dashboardUid"`
+	Position     int    `json:"position"`
+}
+
+// normalizePromotedTerm makes terms and queries match regardless of case and spacing.
+func normalizePromotedTerm(term string) string {
+	return strings.ToLower(strings.Join(strings.Fields(term), " "))
+}
+
+// promotedResults stores the promoted results and caches them per organization, as they are
+// looked up by every query.
+type promotedResults struct {
+	db  db.DB
+	now func() time.Time
+
+	mu     sync.Mutex
+	cached map[int64]*promotedResultsEntry
+}
+
+type promotedResultsEntry struct {
+	expires time.Time
+	// dashboard UIDs by normalized term, in position order
+	byTerm map[string][]string
+}
+
+func newPromotedResults(db db.DB) *promotedResults {
+	return &promotedResults{
+		db:     db,
+		now:    time.Now,
+		cached: map[int64]*promotedResultsEntry{},
+	}
+}
+
+func (p *promotedResults) list(ctx context.Context, orgID int64) ([]*PromotedResult, error) {
+	results := make([]*PromotedResult, 0)
+	err := p.db.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		return sess.Where("org_id=?", orgID).Asc("term", "position", "id").Find(&results)
+	})
+	return results, err
+}
+
+func (p *promotedResults) add(ctx context.Context, cmd *AddPromotedResultCommand) (*PromotedResult, error) {
+	term := normalizePromotedTerm(cmd.Term)
+	if term == "" || len(term) > maxPromotedTermLength || cmd.DashboardUID == "" {
+		return nil, ErrInvalidPromotedResult
+	}
+
+	result := &PromotedResult{
+		OrgID:        cmd.OrgID,
+		Term:         term,
+		DashboardUID: cmd.DashboardUID,
+		Position:     cmd.Position,
+		Created:      p.now(),
+	}
+	err := p.db.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		exists, err := sess.Where("org_id=? AND term=? AND dashboard_uid=?", result.OrgID, result.Term, result.DashboardUID).Exist(&PromotedResult{})
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrPromotedResultExists
+		}
+		if _, err := sess.Insert(result); err != nil {
+			if p.db.GetDialect().IsUniqueConstraintViolation(err) {
+				return ErrPromotedResultExists
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	p.invalidate(cmd.OrgID)
+	return result, nil
+}
+
+func (p *promotedResults) delete(ctx context.Context, orgID int64, id int64) error {
+	err := p.db.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		deleted, err := sess.Where("org_id=? AND id=?", orgID, id).Delete(&PromotedResult{})
+		if err != nil {
+			return err
+		}
+		if deleted == 0 {
+			return ErrPromotedResultNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	p.invalidate(orgID)
+	return nil
+}
+
+// dashboardsFor returns the UIDs of the dashboards promoted for the query, in position order.
+func (p *promotedResults) dashboardsFor(ctx context.Context, orgID int64, query string) ([]string, error) {
+	term := normalizePromotedTerm(query)
+	if p == nil || term == "" {
+		return nil, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	entry, ok := p.cached[orgID]
+	if !ok || p.now().After(entry.expires) {
+		results, err := p.list(ctx, orgID)
+		if err != nil {
+			return nil, err
+		}
+		entry = &promotedResultsEntry{expires: p.now().Add(promotedResultsCacheTTL), byTerm: map[string][]string{}}
+		// listed in position order
+		for _, result := range results {
+			entry.byTerm[result.Term] = append(entry.byTerm[result.Term], result.DashboardUID)
+		}
+		p.cached[orgID] = entry
+	}
+	return entry.byTerm[term], nil
+}
+
+func (p *promotedResults) invalidate(orgID int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.cached, orgID)
+}
+
+// promotedQuery returns the query looking up the promoted dashboards, with the filters of the
+// query they are merged in so that they don't show up in results they don't belong to. It returns
+// false when the query doesn't get promoted results: for other pages than the first one, for
+// explicit UID lookups and when dashboards aren't part of the requested kinds.
+func promotedQuery(q DashboardQuery, uids []string) (DashboardQuery, bool) {
+	if len(uids) == 0 || q.From > 0 || len(q.UIDs) > 0 {
+		return q, false
+	}
+	if len(q.Kind) > 0 && !containsString(q.Kind, string(entityKindDashboard)) {
+		return q, false
+	}
+
+	pq := q
+	pq.Query = ""
+	pq.UIDs = uids
+	pq.Kind = []string{string(entityKindDashboard)}
+	pq.Limit = len(uids)
+	pq.Sort = ""
+	pq.Facet = nil
+	pq.QualityBoost = 0
+	pq.Explain = false
+	return pq, true
+}
+
+// mergePromotedResults puts the promoted results at the top of the first frame of the response,
+// which already excludes the promoted dashboards, and flags them with a promoted field. The frame
+// keeps at most limit rows.
+func mergePromotedResults(response *backend.DataResponse, promoted *backend.DataResponse, limit int) *backend.DataResponse {
+	if response.Error != nil || len(response.Frames) == 0 || promoted.Error != nil || len(promoted.Frames) == 0 {
+		return response
+	}
+	frame := response.Frames[0]
+	promotedFrame := promoted.Frames[0]
+	if frame.Meta == nil || promotedFrame.Meta == nil {
+		return response
+	}
+	promotedRows, _ := promotedFrame.RowLen()
+	if promotedRows == 0 {
+		return response
+	}
+
+	merged := frame.EmptyCopy()
+	merged.Meta = frame.Meta
+	for i, f := range frame.Fields {
+		merged.Fields[i].Config = f.Config
+	}
+	fPromoted := data.NewFieldFromFieldType(data.FieldTypeBool, 0)
+	fPromoted.Name = "promoted"
+
+	rows, _ := frame.RowLen()
+	added := 0
+	for i := 0; i < promotedRows && added < limit; i++ {
+		merged.AppendRow(promotedFrame.RowCopy(i)...)
+		fPromoted.Append(true)
+		added++
+	}
+	for i := 0; i < rows && added < limit; i++ {
+		merged.AppendRow(frame.RowCopy(i)...)
+		fPromoted.Append(false)
+		added++
+	}
+	merged.Fields = append(merged.Fields, fPromoted)
+
+	if meta, ok := frame.Meta.Custom.(*customMeta); ok {
+		meta.Count += uint64(promotedRows)
+		if promotedMeta, ok := promotedFrame.Meta.Custom.(*customMeta); ok && len(promotedMeta.Locations) > 0 {
+			if meta.Locations == nil {
+				meta.Locations = make(map[string]locationItem, len(promotedMeta.Locations))
+			}
+			for uid, location := range promotedMeta.Locations {
+				meta.Locations[uid] = location
+			}
+		}
+	}
+	response.Frames[0] = merged
+	return response
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package searchV2
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/searchV2/extract"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+func TestIntegrationPromotedResults(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+	promoted := newPromotedResults(sqlstore.InitTestDB(t))
+
+	add := func(t *testing.T, orgID int64, term, uid string, position int) *PromotedResult {
+		t.Helper()
+		result, err := promoted.add(ctx, &AddPromotedResultCommand{OrgID: orgID, Term: term, DashboardUID: uid, Position: position})
+		require.NoError(t, err)
+		return result
+	}
+
+	first := add(t, 1, "SLO", "slo-overview", 1)
+	add(t, 1, "slo", "slo-canonical", 0)
+	add(t, 2, "slo", "other-org", 0)
+
+	t.Run("stores normalized terms", func(t *testing.T) {
+		require.Equal(t, "slo", first.Term)
+
+		_, err := promoted.add(ctx, &AddPromotedResultCommand{OrgID: 1, Term: "  Slo ", DashboardUID: "slo-overview"})
+		require.ErrorIs(t, err, ErrPromotedResultExists)
+		_, err = promoted.add(ctx, &AddPromotedResultCommand{OrgID: 1, Term: " ", DashboardUID: "slo-overview"})
+		require.ErrorIs(t, err, ErrInvalidPromotedResult)
+	})
+
+	t.Run("looks up the dashboards promoted for a query in position order", func(t *testing.T) {
+		uids, err := promoted.dashboardsFor(ctx, 1, " slo")
+		require.NoError(t, err)
+		require.Equal(t, []string{"slo-canonical", "slo-overview"}, uids)
+
+		uids, err = promoted.dashboardsFor(ctx, 1, "slo dashboards")
+		require.NoError(t, err)
+		require.Empty(t, uids)
+	})
+
+	t.Run("changes are not served stale", func(t *testing.T) {
+		added := add(t, 1, "latency", "latency-canonical", 0)
+		uids, err := promoted.dashboardsFor(ctx, 1, "Latency")
+		require.NoError(t, err)
+		require.Equal(t, []string{"latency-canonical"}, uids)
+
+		require.NoError(t, promoted.delete(ctx, 1, added.ID))
+		uids, err = promoted.dashboardsFor(ctx, 1, "Latency")
+		require.NoError(t, err)
+		require.Empty(t, uids)
+
+		require.ErrorIs(t, promoted.delete(ctx, 2, first.ID), ErrPromotedResultNotFound)
+	})
+}
+
+func TestMergePromotedResults(t *testing.T) {
+	index := initTestOrgIndexFromDashes(t, []dashboard{
+		{id: 1, uid: "slo-canonical", info: &extract.DashboardInfo{Title: "Service levels"}},
+		{id: 2, uid: "slo-api", info: &extract.DashboardInfo{Title: "API SLO"}},
+		{id: 3, uid: "slo-db", info: &extract.DashboardInfo{Title: "Database SLO"}},
+	})
+
+	search := func(t *testing.T, q DashboardQuery, uids []string) ([]string, []bool, uint64) {
+		t.Helper()
+		pq, ok := promotedQuery(q, uids)
+		require.True(t, ok)
+		q.excludedUIDs = uids
+
+		response := doSearchQuery(context.Background(), testLogger, index, testAllowAllFilter, q, &NoopQueryExtender{}, "")
+		promoted := doSearchQuery(context.Background(), testLogger, index, testAllowAllFilter, pq, &NoopQueryExtender{}, "")
+		limit := q.Limit
+		if limit <= 0 {
+			limit = defaultQueryLimit
+		}
+		response = mergePromotedResults(response, promoted, limit)
+		require.NoError(t, response.Error)
+
+		frame := response.Frames[0]
+		uidField, _ := frame.FieldByName("uid")
+		promotedField, _ := frame.FieldByName("promoted")
+		require.NotNil(t, promotedField)
+		resultUIDs := make([]string, 0, uidField.Len())
+		flags := make([]bool, 0, uidField.Len())
+		for i := 0; i < uidField.Len(); i++ {
+			resultUIDs = append(resultUIDs, uidField.At(i).(string))
+			flags = append(flags, promotedField.At(i).(bool))
+		}
+		return resultUIDs, flags, frame.Meta.Custom.(*customMeta).Count
+	}
+
+	t.Run("promoted dashboards come first even when they don't match the query", func(t *testing.T) {
+		uids, flags, count := search(t, DashboardQuery{Query: "slo"}, []string{"slo-canonical", "slo-db"})
+		require.Equal(t, []string{"slo-canonical", "slo-db", "slo-api"}, uids)
+		require.Equal(t, []bool{true, true, false}, flags)
+		require.Equal(t, uint64(3), count)
+	})
+
+	t.Run("limit includes promoted dashboards", func(t *testing.T) {
+		uids, _, _ := search(t, DashboardQuery{Query: "slo", Limit: 2}, []string{"slo-canonical"})
+		require.Len(t, uids, 2)
+		require.Equal(t, "slo-canonical", uids[0])
+	})
+
+	t.Run("promoted dashboards the user can't read are left out", func(t *testing.T) {
+		q := DashboardQuery{Query: "slo"}
+		pq, _ := promotedQuery(q, []string{"slo-canonical"})
+		promoted := doSearchQuery(context.Background(), testLogger, index, func(uid string) bool { return uid != "slo-canonical" }, pq, &NoopQueryExtender{}, "")
+		require.NoError(t, promoted.Error)
+		rows, _ := promoted.Frames[0].RowLen()
+		require.Zero(t, rows)
+	})
+
+	t.Run("only the first page of dashboard results gets promoted results", func(t *testing.T) {
+		_, ok := promotedQuery(DashboardQuery{Query: "slo", From: 10}, []string{"slo-canonical"})
+		require.False(t, ok)
+		_, ok = promotedQuery(DashboardQuery{Query: "slo", Kind: []string{string(entityKindPanel)}}, []string{"slo-canonical"})
+		require.False(t, ok)
+	})
+}
@@ -0,0 +1,70 @@
+package searchV2
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/blugelabs/bluge"
+
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+const (
+	// documentFieldProvisioned is "true" for the dashboards managed by a provisioner, "false" for the others
+	documentFieldProvisioned = "provisioned"
+	// documentFieldProvisioningSource is the name of the provisioner of the dashboard, facets on it
+	// count the dashboards each provisioner manages
+	documentFieldProvisioningSource = "provisioning_source"
+	// documentFieldProvisioningPath is the file the dashboard is provisioned from
+	documentFieldProvisioningPath = "provisioning_path"
+)
+
+// provisioningSource is where a provisioned dashboard comes from.
+type provisioningSource struct {
+	name string // name of the provisioner in the provisioning configuration
+	path string // file the dashboard is read from
+}
+
+// loadProvisioningSources returns where the provisioned dashboards of the organization come from,
+// keyed by dashboard ID.
+func loadProvisioningSources(ctx context.Context, sql *sqlstore.SQLStore, orgID int64) (map[int64]*provisioningSource, error) {
+	sources := make(map[int64]*provisioningSource)
+	err := sql.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		var rows []struct {
+			DashboardID int64  `xorm:"dashboard_id"`
+			Name        string `xorm:"name"`
+			ExternalID  string `xorm:"external_id"`
+		}
+		err := sess.SQL(`SELECT dashboard_provisioning.dashboard_id, dashboard_provisioning.name, dashboard_provisioning.external_id FROM dashboard_provisioning
+			INNER JOIN dashboard ON dashboard.id = dashboard_provisioning.dashboard_id
+			WHERE dashboard.org_id = ?`, orgID).Find(&rows)
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			sources[row.DashboardID] = &provisioningSource{name: row.Name, path: row.ExternalID}
+		}
+		return nil
+	})
+	return sources, err
+}
+
+// addProvisioningFields indexes whether the dashboard is provisioned and where from, so that
+// code-managed dashboards can be told apart from the ones edited in the UI.
+func addProvisioningFields(doc *bluge.Document, source *provisioningSource) {
+	doc.AddField(bluge.NewKeywordField(documentFieldProvisioned, strconv.FormatBool(source != nil)).Aggregatable())
+	if source == nil {
+		return
+	}
+	if source.name != "" {
+		doc.AddField(bluge.NewKeywordField(documentFieldProvisioningSource, source.name).Aggregatable().StoreValue())
+	}
+	if source.path != "" {
+		doc.AddField(bluge.NewKeywordField(documentFieldProvisioningPath, source.path).Aggregatable().StoreValue())
+	}
+}
+
+// newProvisionedFilter matches the dashboards which are, or aren't, provisioned.
+func newProvisionedFilter(provisioned bool) bluge.Query {
+	return bluge.NewTermQuery(strconv.FormatBool(provisioned)).SetField(documentFieldProvisioned)
+}
@@ -0,0 +1,85 @@
+package searchV2
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/searchV2/extract"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+func TestProvisionedDashboardsQuery(t *testing.T) {
+	ctx := context.Background()
+	index := initTestOrgIndexFromDashes(t, []dashboard{
+		{id: 1, uid: "gitops-latency", info: &extract.DashboardInfo{Title: "Latency"}, provisioning: &provisioningSource{name: "gitops", path: "/etc/dashboards/latency.json"}},
+		{id: 2, uid: "gitops-errors", info: &extract.DashboardInfo{Title: "Errors"}, provisioning: &provisioningSource{name: "gitops", path: "/etc/dashboards/errors.json"}},
+		{id: 3, uid: "mixins-latency", info: &extract.DashboardInfo{Title: "Latency mixin"}, provisioning: &provisioningSource{name: "mixins", path: "/var/mixins/latency.json"}},
+		{id: 4, uid: "hand-edited", info: &extract.DashboardInfo{Title: "Latency by hand"}},
+		{id: 5, uid: "folder", isFolder: true, info: &extract.DashboardInfo{Title: "Folder"}},
+	})
+	provisioned, notProvisioned := true, false
+	search := func(t *testing.T, q DashboardQuery) *backend.DataResponse {
+		t.Helper()
+		resp := doSearchQuery(ctx, testLogger, index, testAllowAllFilter, q, &NoopQueryExtender{}, "")
+		require.NoError(t, resp.Error)
+		return resp
+	}
+	uids := func(t *testing.T, q DashboardQuery) []string {
+		t.Helper()
+		field, _ := search(t, q).Frames[0].FieldByName("uid")
+		var result []string
+		for i := 0; i < field.Len(); i++ {
+			result = append(result, field.At(i).(string))
+		}
+		return result
+	}
+
+	require.ElementsMatch(t, []string{"gitops-latency", "gitops-errors", "mixins-latency"}, uids(t, DashboardQuery{Provisioned: &provisioned}))
+	require.ElementsMatch(t, []string{"hand-edited"}, uids(t, DashboardQuery{Provisioned: &notProvisioned}))
+	require.ElementsMatch(t, []string{"gitops-latency", "mixins-latency"}, uids(t, DashboardQuery{Query: "latency", Provisioned: &provisioned}))
+
+	resp := search(t, DashboardQuery{Provisioned: &provisioned, Facet: []FacetField{{Field: documentFieldProvisioningSource}}})
+	require.Len(t, resp.Frames, 2)
+	names, _ := resp.Frames[1].FieldByName("provisioning_source")
+	counts, _ := resp.Frames[1].FieldByName("Count")
+	facet := map[string]uint64{}
+	for i := 0; i < names.Len(); i++ {
+		facet[names.At(i).(string)] = counts.At(i).(uint64)
+	}
+	require.Equal(t, map[string]uint64{"gitops": 2, "mixins": 1}, facet)
+}
+
+func TestIntegrationLoadProvisioningSources(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	sql := sqlstore.InitTestDB(t)
+	now := time.Now()
+
+	err := sql.WithDbSession(context.Background(), func(sess *sqlstore.DBSession) error {
+		for _, d := range []*models.Dashboard{
+			{Uid: "provisioned", Title: "Provisioned", Slug: "provisioned", OrgId: 1, Created: now, Updated: now},
+			{Uid: "hand-edited", Title: "Hand edited", Slug: "hand-edited", OrgId: 1, Created: now, Updated: now},
+			{Uid: "other-org", Title: "Other org", Slug: "other-org", OrgId: 2, Created: now, Updated: now},
+		} {
+			if _, err := sess.Insert(d); err != nil {
+				return err
+			}
+		}
+		_, err := sess.Insert(
+			&models.DashboardProvisioning{DashboardId: 1, Name: "gitops", ExternalId: "/etc/dashboards/provisioned.json", Updated: now.Unix()},
+			&models.DashboardProvisioning{DashboardId: 3, Name: "gitops", ExternalId: "/etc/dashboards/other-org.json", Updated: now.Unix()},
+		)
+		return err
+	})
+	require.NoError(t, err)
+
+	sources, err := loadProvisioningSources(context.Background(), sql, 1)
+	require.NoError(t, err)
+	require.Equal(t, map[int64]*provisioningSource{1: {name: "gitops", path: "/etc/dashboards/provisioned.json"}}, sources)
+}
@@ -0,0 +1,89 @@
+package searchV2
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/blugelabs/bluge"
+
+	"github.com/grafana/grafana/pkg/services/searchV2/extract"
+)
+
+const (
+	// maxQualityScore is the quality score of a dashboard meeting all the quality criteria
+	maxQualityScore = 100
+	// qualityRecentlyUpdated is the age under which a dashboard gets all the points for being maintained,
+	// fewer points are given to older dashboards down to none at qualityStale
+	qualityRecentlyUpdated = 90 * 24 * time.Hour
+	qualityStale           = 365 * 24 * time.Hour
+	// qualityBoostSteps is the number of score ranges DashboardQuery.QualityBoost is spread over
+	qualityBoostSteps = 4
+)
+
+// dashboardQuality scores how well maintained a dashboard is, from 0 to maxQualityScore. Each of
+// these criteria is worth a quarter of the score:
+//   - it has a description
+//   - it uses template variables
+//   - none of its panels are broken, i.e. reference datasources that do not exist
+//   - it was updated recently
+//
+// The score depends on the time the dashboard is indexed at, so it is only refreshed when the
+// dashboard is updated or the index is rebuilt.
+func dashboardQuality(dash dashboard, now time.Time) int64 {
+	const criterion = maxQualityScore / 4
+
+	var score float64
+	if dash.info.Description != "" {
+		score += criterion
+	}
+	if len(dash.info.TemplateVars) > 0 {
+		score += criterion
+	}
+	if len(dash.info.MissingDatasource) == 0 && !hasBrokenPanels(dash.info.Panels) {
+		score += criterion
+	}
+
+	age := now.Sub(dash.updated)
+	switch {
+	case age <= qualityRecentlyUpdated:
+		score += criterion
+	case age < qualityStale:
+		score += criterion * float64(qualityStale-age) / float64(qualityStale-qualityRecentlyUpdated)
+	}
+
+	return int64(math.Round(score))
+}
+
+func hasBrokenPanels(panels []extract.PanelInfo) bool {
+	for _, panel := range panels {
+		if len(panel.MissingDatasource) > 0 || hasBrokenPanels(panel.Collapsed) {
+			return true
+		}
+	}
+	return false
+}
+
+func addQualityField(doc *bluge.Document, score int64) {
+	doc.AddField(bluge.NewNumericField(documentFieldQuality, float64(score)).Sortable().StoreValue())
+}
+
+// newQualityBoostQuery returns a query adding up to boost to the score of the dashboards, in
+// proportion to their quality score. Folders and panels are not boosted.
+func newQualityBoostQuery(boost float64) bluge.Query {
+	bq := bluge.NewBooleanQuery()
+	for step := 1; step <= qualityBoostSteps; step++ {
+		min := float64(maxQualityScore * step / qualityBoostSteps)
+		bq.AddShould(bluge.NewNumericRangeQuery(min, bluge.MaxNumeric).
+			SetField(documentFieldQuality).
+			SetBoost(boost / qualityBoostSteps))
+	}
+	return bq
+}
+
+func validateQualityBoost(boost float64) error {
+	if boost < 0 || math.IsNaN(boost) || math.IsInf(boost, 0) {
+		return fmt.Errorf("invalid quality boost: %v", boost)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package searchV2
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/searchV2/extract"
+)
+
+func TestDashboardQuality(t *testing.T) {
+	now := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	quality := func(updated time.Time, info *extract.DashboardInfo) int64 {
+		return dashboardQuality(dashboard{uid: "dash", updated: updated, info: info}, now)
+	}
+
+	require.Equal(t, int64(100), quality(now.Add(-24*time.Hour), &extract.DashboardInfo{
+		Description:  "Node metrics",
+		TemplateVars: []string{"node"},
+		Panels:       []extract.PanelInfo{{ID: 1, Title: "CPU"}},
+	}))
+	require.Equal(t, int64(25), quality(now.Add(-2*qualityStale), &extract.DashboardInfo{}), "not broken")
+	require.Equal(t, int64(0), quality(now.Add(-2*qualityStale), &extract.DashboardInfo{
+		Panels: []extract.PanelInfo{{ID: 1, Collapsed: []extract.PanelInfo{{ID: 2, MissingDatasource: []string{"deleted"}}}}},
+	}), "broken panel in a collapsed row")
+	require.Equal(t, int64(0), quality(now.Add(-2*qualityStale), &extract.DashboardInfo{MissingDatasource: []string{"deleted"}}))
+
+	halfway := now.Add(-(qualityRecentlyUpdated + qualityStale) / 2)
+	require.Equal(t, int64(38), quality(halfway, &extract.DashboardInfo{}), "older dashboards get fewer points")
+}
+
+func TestDashboardIndex_Quality(t *testing.T) {
+	index := initTestOrgIndexFromDashes(t, []dashboard{
+		{id: 1, uid: "folder", isFolder: true, info: &extract.DashboardInfo{Title: "Metrics folder"}},
+		{id: 2, uid: "neglected", folderID: 1, updated: time.Now().Add(-2 * qualityStale), info: &extract.DashboardInfo{
+			Title:             "Metrics",
+			MissingDatasource: []string{"deleted"},
+		}},
+		{id: 3, uid: "maintained", folderID: 1, updated: time.Now(), info: &extract.DashboardInfo{
+			Title:        "Metrics overview",
+			Description:  "Overview of the metrics",
+			TemplateVars: []string{"cluster"},
+		}},
+	})
+
+	t.Run("quality boost ranks well maintained dashboards first", func(t *testing.T) {
+		q := DashboardQuery{Query: "metrics", Kind: []string{string(entityKindDashboard)}}
+		require.Equal(t, []string{"neglected", "maintained"}, searchUIDs(t, index, testAllowAllFilter, q))
+
+		q.QualityBoost = 10
+		require.Equal(t, []string{"maintained", "neglected"}, searchUIDs(t, index, testAllowAllFilter, q))
+	})
+
+	t.Run("quality score is only returned when requested", func(t *testing.T) {
+		resp := doSearchQuery(context.Background(), testLogger, index, testAllowAllFilter, DashboardQuery{Query: "metrics"}, &NoopQueryExtender{}, "")
+		require.NoError(t, resp.Error)
+		_, idx := resp.Frames[0].FieldByName(resultFieldQuality)
+		require.Equal(t, -1, idx)
+
+		q := DashboardQuery{Query: "metrics", Sort: "-" + documentFieldQuality, Fields: []string{resultFieldUID, resultFieldQuality}}
+		resp = doSearchQuery(context.Background(), testLogger, index, testAllowAllFilter, q, &NoopQueryExtender{}, "")
+		require.NoError(t, resp.Error)
+		uids, _ := resp.Frames[0].FieldByName(resultFieldUID)
+		scores, _ := resp.Frames[0].FieldByName(resultFieldQuality)
+		require.Equal(t, 3, scores.Len())
+		require.Equal(t, "maintained", uids.At(0))
+		require.Equal(t, int64(100), *scores.At(0).(*int64))
+		require.Equal(t, "neglected", uids.At(1))
+		require.Equal(t, int64(0), *scores.At(1).(*int64))
+		require.Nil(t, scores.At(2), "folders have no quality score")
+	})
+
+	require.NoError(t, validateQualityBoost(0))
+	require.Error(t, validateQualityBoost(-1))
+}
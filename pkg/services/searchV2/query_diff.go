@@ -0,0 +1,189 @@
+package searchV2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+const (
+	// QueryDiffAgainstShadow compares with an index built from scratch for the comparison
+	QueryDiffAgainstShadow = "shadow"
+	// QueryDiffAgainstPersisted compares with the index last persisted to disk
+	QueryDiffAgainstPersisted = "persisted"
+)
+
+var (
+	ErrInvalidQueryDiff = errors.New("invalid query diff")
+	ErrNoPersistedIndex = errors.New("the organization has no persisted index")
+)
+
+// DiffQueryCommand runs Query against the current index of the organization and another index,
+// to validate changes to the indexer or investigate dashboards missing from results.
+type DiffQueryCommand struct {
+	OrgID   int64          `json:"-"`
+	Query   DashboardQuery `json:"query"`
+	Against string         `json:"against"` // shadow (default) or persisted
+}
+
+// QueryDiff reports the results which differ between the current index and the other index. The
+// query is run without permission filter, so that results don't depend on who runs it.
+type QueryDiff struct {
+	Against           string `json:"against"`
+	CurrentGeneration int64  `json:"currentGeneration"`
+	CurrentCount      uint64 `json:"currentCount"`
+	OtherCount        uint64 `json:"otherCount"`
+	Identical         bool   `json:"identical"`
+	// OnlyInCurrent and OnlyInOther are the results returned by one index only, Moved the results
+	// ranked differently
+	OnlyInCurrent []*QueryDiffResult `json:"onlyInCurrent"`
+	OnlyInOther   []*QueryDiffResult `json:"onlyInOther"`
+	Moved         []*QueryDiffResult `json:"moved"`
+}
+
+// QueryDiffResult is a result of the query, ranks start at 1 and are 0 when it isn't returned.
+type QueryDiffResult struct {
+	Kind        string `json:"kind"`
+	UID         string `json:"uid"`
+	Name        string `json:"name"`
+	CurrentRank int    `json:"currentRank,omitempty"`
+	OtherRank   int    `json:"otherRank,omitempty"`
+}
+
+// otherOrgIndex returns the index the current index of the organization is compared to, which the
+// caller must close.
+func (i *searchIndex) otherOrgIndex(ctx context.Context, orgID int64, against string) (*orgIndex, error) {
+	switch against {
+	case QueryDiffAgainstShadow:
+		return i.buildShadowOrgIndex(ctx, orgID)
+	case QueryDiffAgainstPersisted:
+		if i.persister == nil {
+			return nil, ErrNoPersistedIndex
+		}
+		dir, _, err := i.persister.load(ctx, orgID, indexTypeDashboard)
+		if errors.Is(err, errPersistedIndexNotFound) {
+			return nil, ErrNoPersistedIndex
+		}
+		if err != nil {
+			return nil, err
+		}
+		index, err := openOrgIndex(dir)
+		if err != nil {
+			return nil, err
+		}
+		if i.semanticSearchEnabled() {
+			if index.vectors, err = loadDashboardVectors(index); err != nil {
+				i.logger.Warn("Failed to load dashboard embeddings of persisted org index", "orgId", orgID, "error", err)
+			}
+		}
+		return index, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown index %q", ErrInvalidQueryDiff, against)
+	}
+}
+
+// buildShadowOrgIndex builds an index of the organization in memory as a full re-index would,
+// without replacing the current index nor saving checkpoints.
+func (i *searchIndex) buildShadowOrgIndex(ctx context.Context, orgID int64) (*orgIndex, error) {
+	dashboards, err := i.loader.LoadDashboards(ctx, orgID, "")
+	if err != nil {
+		return nil, fmt.Errorf("error loading dashboards: %w", err)
+	}
+	i.labelDashboards(ctx, orgID, dashboards)
+	i.enrichDashboards(ctx, orgID, dashboards)
+	i.embedDashboards(ctx, orgID, dashboards)
+
+	index, err := initOrgIndex(dashboards, i.logger, i.extender.GetDashboardExtender(orgID), i.indexModeFor(len(dashboards)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing index: %w", err)
+	}
+	index.vectors = newDashboardVectors(dashboards)
+	return index, nil
+}
+
+// diffQuery runs the query against both indexes and compares their results.
+func (i *searchIndex) diffQuery(ctx context.Context, current *orgIndex, other *orgIndex, q DashboardQuery, against string) (*QueryDiff, error) {
+	allowAll := func(uid string) bool { return true }
+	// the default fields are needed to identify the results
+	q.Fields = nil
+
+	currentResults, currentCount, err := queryDiffResults(doSearchQuery(ctx, i.logger, current, allowAll, q, &NoopQueryExtender{}, ""))
+	if err != nil {
+		return nil, fmt.Errorf("error querying the current index: %w", err)
+	}
+	otherResults, otherCount, err := queryDiffResults(doSearchQuery(ctx, i.logger, other, allowAll, q, &NoopQueryExtender{}, ""))
+	if err != nil {
+		return nil, fmt.Errorf("error querying the %s index: %w", against, err)
+	}
+
+	diff := &QueryDiff{
+		Against:           against,
+		CurrentGeneration: current.generation,
+		CurrentCount:      currentCount,
+		OtherCount:        otherCount,
+		OnlyInCurrent:     []*QueryDiffResult{},
+		OnlyInOther:       []*QueryDiffResult{},
+		Moved:             []*QueryDiffResult{},
+	}
+	// results of the other index, by kind and UID
+	otherByKey := make(map[string]*QueryDiffResult, len(otherResults))
+	for _, result := range otherResults {
+		result.OtherRank, result.CurrentRank = result.CurrentRank, 0
+		otherByKey[result.Kind+"/"+result.UID] = result
+	}
+	for _, result := range currentResults {
+		key := result.Kind + "/" + result.UID
+		otherResult, ok := otherByKey[key]
+		switch {
+		case !ok:
+			diff.OnlyInCurrent = append(diff.OnlyInCurrent, result)
+		case otherResult.OtherRank != result.CurrentRank:
+			result.OtherRank = otherResult.OtherRank
+			diff.Moved = append(diff.Moved, result)
+		}
+		delete(otherByKey, key)
+	}
+	for _, result := range otherResults {
+		if _, ok := otherByKey[result.Kind+"/"+result.UID]; ok {
+			diff.OnlyInOther = append(diff.OnlyInOther, result)
+		}
+	}
+	diff.Identical = currentCount == otherCount && len(diff.OnlyInCurrent) == 0 && len(diff.OnlyInOther) == 0 && len(diff.Moved) == 0
+	return diff, nil
+}
+
+// queryDiffResults returns the results of a query ranked with CurrentRank, and their total count.
+func queryDiffResults(response *backend.DataResponse) ([]*QueryDiffResult, uint64, error) {
+	if response.Error != nil {
+		return nil, 0, response.Error
+	}
+	if len(response.Frames) == 0 {
+		return nil, 0, nil
+	}
+	frame := response.Frames[0]
+	var count uint64
+	if frame.Meta != nil {
+		if meta, ok := frame.Meta.Custom.(*customMeta); ok {
+			count = meta.Count
+		}
+	}
+	kinds, _ := frame.FieldByName(resultFieldKind)
+	uids, _ := frame.FieldByName(resultFieldUID)
+	names, _ := frame.FieldByName(resultFieldName)
+	if kinds == nil || uids == nil || names == nil {
+		return nil, 0, errors.New("results miss the kind, uid or name field")
+	}
+
+	results := make([]*QueryDiffResult, 0, frame.Rows())
+	for row := 0; row < frame.Rows(); row++ {
+		results = append(results, &QueryDiffResult{
+			Kind:        kinds.At(row).(string),
+			UID:         uids.At(row).(string),
+			Name:        names.At(row).(string),
+			CurrentRank: row + 1,
+		})
+	}
+	return results, count, nil
+}
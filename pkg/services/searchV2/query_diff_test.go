@@ -0,0 +1,74 @@
+package searchV2
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/searchV2/extract"
+	"github.com/grafana/grafana/pkg/services/store"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestDiffQuery(t *testing.T) {
+	ctx := context.Background()
+	loader := &testDashboardLoader{dashboards: []dashboard{
+		{id: 1, uid: "latency", info: &extract.DashboardInfo{Title: "Latency"}},
+		{id: 2, uid: "latency-overview", info: &extract.DashboardInfo{Title: "Latency overview"}},
+	}}
+	persister := newIndexPersister(t.TempDir(), false, nil)
+	index := newSearchIndex(loader, &store.MockEntityEventsService{}, &NoopDocumentExtender{}, nil, tracing.InitializeTracerForTest(), featuremgmt.WithFeatures(), setting.SearchSettings{}, persister)
+	_, err := index.buildOrgIndex(ctx, testOrgID)
+	require.NoError(t, err)
+	current, _ := index.getOrgIndex(testOrgID)
+
+	diff := func(t *testing.T, against string) *QueryDiff {
+		t.Helper()
+		other, err := index.otherOrgIndex(ctx, testOrgID, against)
+		require.NoError(t, err)
+		defer other.close(testLogger)
+		result, err := index.diffQuery(ctx, current, other, DashboardQuery{Query: "latency", Kind: []string{string(entityKindDashboard)}}, against)
+		require.NoError(t, err)
+		return result
+	}
+
+	t.Run("identical indexes", func(t *testing.T) {
+		result := diff(t, QueryDiffAgainstShadow)
+		require.True(t, result.Identical)
+		require.Equal(t, uint64(2), result.CurrentCount)
+		require.Equal(t, current.generation, result.CurrentGeneration)
+	})
+
+	t.Run("reports results missing from either index", func(t *testing.T) {
+		loader.dashboards = []dashboard{
+			{id: 2, uid: "latency-overview", info: &extract.DashboardInfo{Title: "Latency overview"}},
+			{id: 3, uid: "latency-errors", info: &extract.DashboardInfo{Title: "Latency errors"}},
+		}
+		result := diff(t, QueryDiffAgainstShadow)
+		require.False(t, result.Identical)
+		require.Len(t, result.OnlyInCurrent, 1)
+		require.Equal(t, "latency", result.OnlyInCurrent[0].UID)
+		require.Equal(t, 1, result.OnlyInCurrent[0].CurrentRank)
+		require.Len(t, result.OnlyInOther, 1)
+		require.Equal(t, "latency-errors", result.OnlyInOther[0].UID)
+		require.Zero(t, result.OnlyInOther[0].CurrentRank)
+		require.NotZero(t, result.OnlyInOther[0].OtherRank)
+	})
+
+	t.Run("compares with the persisted index", func(t *testing.T) {
+		// builds persist the index
+		require.True(t, diff(t, QueryDiffAgainstPersisted).Identical)
+
+		index.persister = newIndexPersister(t.TempDir(), false, nil)
+		_, err := index.otherOrgIndex(ctx, testOrgID, QueryDiffAgainstPersisted)
+		require.ErrorIs(t, err, ErrNoPersistedIndex)
+	})
+
+	t.Run("rejects unknown indexes", func(t *testing.T) {
+		_, err := index.otherOrgIndex(ctx, testOrgID, "yesterday")
+		require.ErrorIs(t, err, ErrInvalidQueryDiff)
+	})
+}
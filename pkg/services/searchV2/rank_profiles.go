@@ -0,0 +1,43 @@
+package searchV2
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+var ErrUnknownRankProfile = errors.New("unknown search rank profile")
+
+// rankProfileFor returns the rank profile selected by the query, see setting.SearchSettings.RankProfiles.
+// Queries which don't select one get the api-default profile when it is configured.
+func rankProfileFor(name string, profiles map[string]setting.SearchRankProfile) (setting.SearchRankProfile, error) {
+	if name == "" {
+		return profiles[setting.SearchRankProfileAPIDefault], nil
+	}
+	profile, ok := profiles[name]
+	if !ok {
+		return setting.SearchRankProfile{}, fmt.Errorf("%w: %q", ErrUnknownRankProfile, name)
+	}
+	return profile, nil
+}
+
+// applyRankProfile sets the boosts and limit of the profile on the query unless it sets its own, and
+// caps its limit.
+func applyRankProfile(q *DashboardQuery, profile setting.SearchRankProfile) {
+	if q.QualityBoost == 0 {
+		q.QualityBoost = profile.QualityBoost
+	}
+	if len(q.GovernanceBoost) == 0 && len(profile.GovernanceBoost) > 0 {
+		q.GovernanceBoost = make(map[string]float64, len(profile.GovernanceBoost))
+		for label, boost := range profile.GovernanceBoost {
+			q.GovernanceBoost[label] = boost
+		}
+	}
+	if q.Limit <= 0 {
+		q.Limit = profile.Limit
+	}
+	if profile.MaxLimit > 0 && (q.Limit <= 0 || q.Limit > profile.MaxLimit) {
+		q.Limit = profile.MaxLimit
+	}
+}
@@ -0,0 +1,59 @@
+package searchV2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestRankProfiles(t *testing.T) {
+	profiles := map[string]setting.SearchRankProfile{
+		setting.SearchRankProfileCommandPalette: {QualityBoost: 2, GovernanceBoost: map[string]float64{GovernanceLabelCertified: 2}, Limit: 20, MaxLimit: 50},
+		setting.SearchRankProfileAPIDefault:     {MaxLimit: 1000},
+	}
+
+	t.Run("queries get the boosts and limit of their profile", func(t *testing.T) {
+		profile, err := rankProfileFor(setting.SearchRankProfileCommandPalette, profiles)
+		require.NoError(t, err)
+		q := DashboardQuery{Query: "latency"}
+		applyRankProfile(&q, profile)
+		require.Equal(t, 2.0, q.QualityBoost)
+		require.Equal(t, map[string]float64{GovernanceLabelCertified: 2}, q.GovernanceBoost)
+		require.Equal(t, 20, q.Limit)
+
+		// the profile isn't modified through the query
+		q.GovernanceBoost[GovernanceLabelCertified] = 5
+		require.Equal(t, 2.0, profiles[setting.SearchRankProfileCommandPalette].GovernanceBoost[GovernanceLabelCertified])
+	})
+
+	t.Run("queries keep their own parameters within the max limit", func(t *testing.T) {
+		profile, err := rankProfileFor(setting.SearchRankProfileCommandPalette, profiles)
+		require.NoError(t, err)
+		q := DashboardQuery{QualityBoost: 1, GovernanceBoost: map[string]float64{GovernanceLabelDraft: 1}, Limit: 200}
+		applyRankProfile(&q, profile)
+		require.Equal(t, 1.0, q.QualityBoost)
+		require.Equal(t, map[string]float64{GovernanceLabelDraft: 1}, q.GovernanceBoost)
+		require.Equal(t, 50, q.Limit)
+	})
+
+	t.Run("queries without profile get the api-default profile", func(t *testing.T) {
+		profile, err := rankProfileFor("", profiles)
+		require.NoError(t, err)
+		q := DashboardQuery{}
+		applyRankProfile(&q, profile)
+		require.Equal(t, 1000, q.Limit)
+
+		profile, err = rankProfileFor("", nil)
+		require.NoError(t, err)
+		q = DashboardQuery{}
+		applyRankProfile(&q, profile)
+		require.Equal(t, DashboardQuery{}, q)
+	})
+
+	t.Run("unknown profiles are rejected", func(t *testing.T) {
+		_, err := rankProfileFor("mobile", profiles)
+		require.ErrorIs(t, err, ErrUnknownRankProfile)
+	})
+}
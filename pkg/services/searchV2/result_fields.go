@@ -0,0 +1,91 @@
+package searchV2
+
+import "fmt"
+
+// Names of the result frame fields which can be requested with DashboardQuery.Fields.
+const (
+	resultFieldKind      = "kind"
+	resultFieldUID       = "uid"
+	resultFieldName      = "name"
+	resultFieldPanelType = "panel_type"
+	resultFieldURL       = "url"
+	resultFieldTags      = "tags"
+	resultFieldDSUID     = "ds_uid"
+	resultFieldLocation  = "location"
+	// resultFieldQuality is only included when requested, see dashboardQuality
+	resultFieldQuality = "quality_score"
+	// resultFieldHighlight is only included when requested, it marks the words of the name and of
+	// the search aliases matching the query, see highlightResult
+	resultFieldHighlight = "highlight"
+	// resultFieldGovernance is only included when requested, see GovernanceLabel
+	resultFieldGovernance = "governance"
+	// resultFieldIndexedAt and resultFieldSourceUpdatedAt are only included when requested, they
+	// tell when the result was indexed and when its dashboard or folder was last updated
+	resultFieldIndexedAt       = "indexed_at"
+	resultFieldSourceUpdatedAt = "source_updated_at"
+)
+
+var selectableResultFields = map[string]bool{
+	resultFieldKind:            true,
+	resultFieldUID:             true,
+	resultFieldName:            true,
+	resultFieldPanelType:       true,
+	resultFieldURL:             true,
+	resultFieldTags:            true,
+	resultFieldDSUID:           true,
+	resultFieldLocation:        true,
+	resultFieldQuality:         true,
+	resultFieldHighlight:       true,
+	resultFieldGovernance:      true,
+	resultFieldIndexedAt:       true,
+	resultFieldSourceUpdatedAt: true,
+}
+
+// optInResultFields are only included in the results when requested with DashboardQuery.Fields.
+var optInResultFields = map[string]bool{
+	resultFieldQuality:         true,
+	resultFieldHighlight:       true,
+	resultFieldGovernance:      true,
+	resultFieldIndexedAt:       true,
+	resultFieldSourceUpdatedAt: true,
+}
+
+func validateResultFields(fields []string) error {
+	for _, f := range fields {
+		if !selectableResultFields[f] {
+			return fmt.Errorf("invalid result field: %s", f)
+		}
+	}
+	return nil
+}
+
+// resultFieldSelection tells which of the base result fields the frame builder must fill.
+// Explain and datasource access fields are not affected, they are still controlled by their
+// own query options.
+type resultFieldSelection map[string]bool
+
+func newResultFieldSelection(q DashboardQuery) resultFieldSelection {
+	if len(q.Fields) == 0 {
+		return nil
+	}
+	selection := make(resultFieldSelection, len(q.Fields)+3)
+	for _, f := range q.Fields {
+		selection[f] = true
+	}
+	if q.WithAllowedActions {
+		// allowed actions are resolved from these
+		selection[resultFieldKind] = true
+		selection[resultFieldUID] = true
+		selection[resultFieldDSUID] = true
+	}
+	return selection
+}
+
+// includes returns true if the field was requested, a nil selection includes all fields but the
+// opt-in ones.
+func (s resultFieldSelection) includes(field string) bool {
+	if s == nil {
+		return !optInResultFields[field]
+	}
+	return s[field]
+}
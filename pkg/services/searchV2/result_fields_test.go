@@ -0,0 +1,50 @@
+package searchV2
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultFieldSelection(t *testing.T) {
+	index := initTestOrgIndexFromDashes(t, testDashboards)
+
+	fieldNames := func(t *testing.T, q DashboardQuery) []string {
+		t.Helper()
+		resp := doSearchQuery(context.Background(), testLogger, index, testAllowAllFilter, q, &NoopQueryExtender{}, "/pfix")
+		require.NoError(t, resp.Error)
+		names := make([]string, 0, len(resp.Frames[0].Fields))
+		for _, f := range resp.Frames[0].Fields {
+			require.Equal(t, 1, f.Len(), f.Name)
+			names = append(names, f.Name)
+		}
+		return names
+	}
+
+	t.Run("returns all fields by default", func(t *testing.T) {
+		require.Equal(t, []string{"kind", "uid", "name", "panel_type", "url", "tags", "ds_uid", "location"},
+			fieldNames(t, DashboardQuery{Query: "boom"}))
+	})
+
+	t.Run("returns only the requested fields", func(t *testing.T) {
+		require.Equal(t, []string{"uid", "name"},
+			fieldNames(t, DashboardQuery{Query: "boom", Fields: []string{"name", "uid"}}))
+	})
+
+	t.Run("keeps explain fields", func(t *testing.T) {
+		require.Equal(t, []string{"uid", "score", "explain"},
+			fieldNames(t, DashboardQuery{Query: "boom", Fields: []string{"uid"}, Explain: true}))
+	})
+
+	t.Run("keeps fields needed by allowed actions", func(t *testing.T) {
+		require.Equal(t, []string{"kind", "uid", "name", "ds_uid"},
+			fieldNames(t, DashboardQuery{Query: "boom", Fields: []string{"name"}, WithAllowedActions: true}))
+	})
+}
+
+func TestValidateResultFields(t *testing.T) {
+	require.NoError(t, validateResultFields(nil))
+	require.NoError(t, validateResultFields([]string{"uid", "name"}))
+	require.Error(t, validateResultFields([]string{"uid", "score"}))
+}
@@ -0,0 +1,379 @@
+package searchV2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blugelabs/bluge"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/sqlstore/db"
+)
+
+const (
+	// maxScoringScriptLength is bounded so that scripts stay readable and cheap to parse
+	maxScoringScriptLength = 512
+	// maxScoringScriptTerms bounds the number of clauses a script adds to each query
+	maxScoringScriptTerms = 16
+	// maxScoringWeight keeps scripts from drowning the relevance of the text query
+	maxScoringWeight = 10
+	// scoringScriptCacheTTL is how long the script of an organization is cached, changes made on
+	// another instance apply after at most this long
+	scoringScriptCacheTTL = time.Minute
+
+	// ScoringScriptNone in DashboardQuery.ScoringScript ranks the results without the script of the
+	// organization
+	ScoringScriptNone = "none"
+
+	scoringSignalRecency     = "recency"
+	scoringSignalQuality     = "quality"
+	scoringSignalProvisioned = "provisioned"
+	scoringSignalTag         = "tag"
+	scoringSignalGovernance  = "governance"
+)
+
+// scoringRecencySteps are the ages under which the last update of a dashboard adds a share of the
+// weight of the recency signal: dashboards updated this week get all of it.
+var scoringRecencySteps = []time.Duration{7 * 24 * time.Hour, 30 * 24 * time.Hour, 90 * 24 * time.Hour, 365 * 24 * time.Hour}
+
+// scoringSignalArgs tells which signals take an argument.
+var scoringSignalArgs = map[string]bool{
+	scoringSignalRecency:     false,
+	scoringSignalQuality:     false,
+	scoringSignalProvisioned: false,
+	scoringSignalTag:         true,
+	scoringSignalGovernance:  true,
+}
+
+var (
+	ErrScoringScriptNotFound = errors.New("organization has no scoring script")
+	ErrInvalidScoringScript  = errors.New("invalid scoring script")
+)
+
+// ScoringScript tailors the ranking of the search results of an organization. Scripts are a sum of
+// weighted signals, e.g. `2*recency + quality + 3*tag("slo") + governance("certified")`, which are
+// added to the score of the text query when results aren't sorted by a field. The signals are:
+//
+//   - recency: up to the weight for dashboards updated in the last year, all of it for the last week
+//   - quality: up to the weight in proportion to the quality score of the dashboard
+//   - provisioned: the weight for dashboards managed by a provisioner
+//   - tag("x"): the weight for dashboards with the tag
+//   - governance("x"): the weight for dashboards with the governance label
+//
+// Weights are between 0 and 10, 1 when left out.
+type ScoringScript struct {
+	ID      int64     `json:"id" xorm:"pk autoincr 'id'"`
+	OrgID   int64     `json:"orgId" xorm:"org_id"`
+	Script  string    `json:"script" xorm:"script"`
+	Updated time.Time `json:"updated" xorm:"updated"`
+}
+
+func (ScoringScript) TableName() string { return "search_scoring_script" }
+
+type SetScoringScriptCommand struct {
+	OrgID  int64  `json:"-"`
+	Script string `json:"script"`
+}
+
+// scoringTerm is a weighted signal of a scoring script.
+type scoringTerm struct {
+	weight float64
+	signal string
+	arg    string
+}
+
+// scoringScript is a parsed scoring script.
+type scoringScript struct {
+	terms []scoringTerm
+}
+
+// query returns the query adding the signals of the script to the score of the documents.
+func (s *scoringScript) query(now time.Time) bluge.Query {
+	bq := bluge.NewBooleanQuery()
+	for _, term := range s.terms {
+		switch term.signal {
+		case scoringSignalRecency:
+			recency := bluge.NewBooleanQuery()
+			for _, age := range scoringRecencySteps {
+				recency.AddShould(bluge.NewDateRangeQuery(now.Add(-age), time.Time{}).
+					SetField(DocumentFieldUpdatedAt).
+					SetBoost(term.weight / float64(len(scoringRecencySteps))))
+			}
+			bq.AddShould(recency)
+		case scoringSignalQuality:
+			bq.AddShould(newQualityBoostQuery(term.weight))
+		case scoringSignalProvisioned:
+			bq.AddShould(bluge.NewTermQuery("true").SetField(documentFieldProvisioned).SetBoost(term.weight))
+		case scoringSignalTag:
+			bq.AddShould(bluge.NewTermQuery(term.arg).SetField(documentFieldTag).SetBoost(term.weight))
+		case scoringSignalGovernance:
+			bq.AddShould(bluge.NewTermQuery(term.arg).SetField(documentFieldGovernance).SetBoost(term.weight))
+		}
+	}
+	return bq
+}
+
+// parseScoringScript parses a script of the grammar
+//
+//	script = term { "+" term }
+//	term   = [ number "*" ] signal
+//	signal = name [ "(" string ")" ]
+func parseScoringScript(script string) (*scoringScript, error) {
+	if len(script) > maxScoringScriptLength {
+		return nil, fmt.Errorf("%w: scripts have at most %d characters", ErrInvalidScoringScript, maxScoringScriptLength)
+	}
+	p := &scoringParser{input: script}
+	parsed := &scoringScript{}
+	for {
+		term, err := p.term()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidScoringScript, err)
+		}
+		parsed.terms = append(parsed.terms, term)
+		if len(parsed.terms) > maxScoringScriptTerms {
+			return nil, fmt.Errorf("%w: scripts have at most %d terms", ErrInvalidScoringScript, maxScoringScriptTerms)
+		}
+		p.skipSpaces()
+		if p.done() {
+			return parsed, nil
+		}
+		if !p.consume('+') {
+			return nil, fmt.Errorf("%w: expected + at position %d", ErrInvalidScoringScript, p.pos)
+		}
+	}
+}
+
+type scoringParser struct {
+	input string
+	pos   int
+}
+
+func (p *scoringParser) done() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *scoringParser) skipSpaces() {
+	for !p.done() && (p.input[p.pos] == ' ' || p.input[p.pos] == '\t' || p.input[p.pos] == '\n') {
+		p.pos++
+	}
+}
+
+func (p *scoringParser) consume(c byte) bool {
+	p.skipSpaces()
+	if p.done() || p.input[p.pos] != c {
+		return false
+	}
+	p.pos++
+	return true
+}
+
+func (p *scoringParser) scan(accept func(c byte) bool) string {
+	p.skipSpaces()
+	start := p.pos
+	for !p.done() && accept(p.input[p.pos]) {
+		p.pos++
+	}
+	return p.input[start:p.pos]
+}
+
+func (p *scoringParser) term() (scoringTerm, error) {
+	term := scoringTerm{weight: 1}
+	if number := p.scan(func(c byte) bool { return c == '.' || (c >= '0' && c <= '9') }); number != "" {
+		weight, err := strconv.ParseFloat(number, 64)
+		if err != nil || weight <= 0 || weight > maxScoringWeight {
+			return term, fmt.Errorf("weights are numbers above 0 and up to %d, got %s", maxScoringWeight, number)
+		}
+		if !p.consume('*') {
+			return term, fmt.Errorf("expected * after the weight at position %d", p.pos)
+		}
+		term.weight = weight
+	}
+
+	start := p.pos
+	term.signal = p.scan(func(c byte) bool { return c >= 'a' && c <= 'z' })
+	hasArg, ok := scoringSignalArgs[term.signal]
+	if !ok {
+		return term, fmt.Errorf("unknown signal %q at position %d, the signals are recency, quality, provisioned, tag and governance", term.signal, start)
+	}
+	if !hasArg {
+		return term, nil
+	}
+
+	if !p.consume('(') || !p.consume('"') {
+		return term, fmt.Errorf(`%s needs an argument, e.g. %s("name")`, term.signal, term.signal)
+	}
+	end := strings.IndexByte(p.input[p.pos:], '"')
+	if end < 0 {
+		return term, fmt.Errorf("unterminated string at position %d", p.pos)
+	}
+	term.arg = p.input[p.pos : p.pos+end]
+	p.pos += end + 1
+	if !p.consume(')') {
+		return term, fmt.Errorf("expected ) at position %d", p.pos)
+	}
+	if term.arg == "" {
+		return term, fmt.Errorf("%s needs a non-empty argument", term.signal)
+	}
+	if term.signal == scoringSignalGovernance && !governanceLabels[term.arg] {
+		return term, fmt.Errorf("invalid governance label: %s", term.arg)
+	}
+	return term, nil
+}
+
+type cachedScoringScript struct {
+	script *scoringScript
+	loaded time.Time
+}
+
+// scoringScripts stores the scoring scripts of the organizations and caches them parsed, so that
+// queries don't read the database.
+type scoringScripts struct {
+	db     db.DB
+	now    func() time.Time
+	logger log.Logger
+
+	mu    sync.Mutex
+	cache map[int64]cachedScoringScript
+}
+
+func newScoringScripts(db db.DB) *scoringScripts {
+	return &scoringScripts{
+		db:     db,
+		now:    time.Now,
+		logger: log.New("searchV2.scoringScripts"),
+		cache:  map[int64]cachedScoringScript{},
+	}
+}
+
+func (s *scoringScripts) get(ctx context.Context, orgID int64) (*ScoringScript, error) {
+	script := &ScoringScript{}
+	var exists bool
+	err := s.db.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		var err error
+		exists, err = sess.Where("org_id=?", orgID).Get(script)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrScoringScriptNotFound
+	}
+	return script, nil
+}
+
+func (s *scoringScripts) set(ctx context.Context, cmd *SetScoringScriptCommand) (*ScoringScript, error) {
+	text := strings.TrimSpace(cmd.Script)
+	parsed, err := parseScoringScript(text)
+	if err != nil {
+		return nil, err
+	}
+
+	script := &ScoringScript{
+		OrgID:   cmd.OrgID,
+		Script:  text,
+		Updated: s.now(),
+	}
+	err = s.db.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		existing := ScoringScript{}
+		exists, err := sess.Where("org_id=?", script.OrgID).Get(&existing)
+		if err != nil {
+			return err
+		}
+		if exists {
+			script.ID = existing.ID
+			_, err = sess.ID(existing.ID).Cols("script", "updated").Update(script)
+		} else {
+			_, err = sess.Insert(script)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cache[cmd.OrgID] = cachedScoringScript{script: parsed, loaded: s.now()}
+	return script, nil
+}
+
+func (s *scoringScripts) delete(ctx context.Context, orgID int64) error {
+	err := s.db.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		deleted, err := sess.Where("org_id=?", orgID).Delete(&ScoringScript{})
+		if err != nil {
+			return err
+		}
+		if deleted == 0 {
+			return ErrScoringScriptNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cache[orgID] = cachedScoringScript{loaded: s.now()}
+	return nil
+}
+
+// scriptFor returns the parsed scoring script of the organization, nil when it has none. Stored
+// scripts which no longer parse, e.g. after the limits of scripts were lowered, are ignored.
+func (s *scoringScripts) scriptFor(ctx context.Context, orgID int64) (*scoringScript, error) {
+	if s == nil {
+		return nil, nil
+	}
+	s.mu.Lock()
+	cached, ok := s.cache[orgID]
+	s.mu.Unlock()
+	if ok && s.now().Sub(cached.loaded) < scoringScriptCacheTTL {
+		return cached.script, nil
+	}
+
+	var parsed *scoringScript
+	stored, err := s.get(ctx, orgID)
+	switch {
+	case errors.Is(err, ErrScoringScriptNotFound):
+	case err != nil:
+		return nil, err
+	default:
+		if parsed, err = parseScoringScript(stored.Script); err != nil {
+			s.logger.Warn("Ignoring invalid scoring script", "orgId", orgID, "error", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cache[orgID] = cachedScoringScript{script: parsed, loaded: s.now()}
+	return parsed, nil
+}
+
+// resolveScoringScript returns the scoring script of a query: the one of the query when it has
+// one, otherwise the one of the organization. Sorted queries aren't ranked by score.
+func (s *scoringScripts) resolveScoringScript(ctx context.Context, orgID int64, q DashboardQuery) (*scoringScript, error) {
+	switch {
+	case q.Sort != "" || q.ScoringScript == ScoringScriptNone:
+		return nil, nil
+	case q.ScoringScript != "":
+		return parseScoringScript(q.ScoringScript)
+	default:
+		return s.scriptFor(ctx, orgID)
+	}
+}
+
+// validateScoringScript checks the scoring script of a query.
+func validateScoringScript(script string) error {
+	if script == "" || script == ScoringScriptNone {
+		return nil
+	}
+	_, err := parseScoringScript(script)
+	return err
+}
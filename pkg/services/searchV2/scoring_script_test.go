@@ -0,0 +1,141 @@
+package searchV2
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/searchV2/extract"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+func TestIntegrationScoringScripts(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+	scripts := newScoringScripts(sqlstore.InitTestDB(t))
+	now := time.Now()
+	scripts.now = func() time.Time { return now }
+
+	first, err := scripts.set(ctx, &SetScoringScriptCommand{OrgID: 1, Script: " recency "})
+	require.NoError(t, err)
+	require.Equal(t, "recency", first.Script)
+
+	t.Run("organizations have a single script", func(t *testing.T) {
+		updated, err := scripts.set(ctx, &SetScoringScriptCommand{OrgID: 1, Script: `2*recency + tag("slo")`})
+		require.NoError(t, err)
+		require.Equal(t, first.ID, updated.ID)
+
+		stored, err := scripts.get(ctx, 1)
+		require.NoError(t, err)
+		require.Equal(t, `2*recency + tag("slo")`, stored.Script)
+
+		_, err = scripts.get(ctx, 2)
+		require.ErrorIs(t, err, ErrScoringScriptNotFound)
+	})
+
+	t.Run("rejects invalid scripts", func(t *testing.T) {
+		_, err := scripts.set(ctx, &SetScoringScriptCommand{OrgID: 1, Script: "views"})
+		require.ErrorIs(t, err, ErrInvalidScoringScript)
+
+		stored, err := scripts.get(ctx, 1)
+		require.NoError(t, err)
+		require.Equal(t, `2*recency + tag("slo")`, stored.Script)
+	})
+
+	t.Run("caches scripts until they change", func(t *testing.T) {
+		script, err := scripts.scriptFor(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, script.terms, 2)
+
+		script, err = scripts.scriptFor(ctx, 2)
+		require.NoError(t, err)
+		require.Nil(t, script)
+
+		_, err = scripts.set(ctx, &SetScoringScriptCommand{OrgID: 2, Script: "quality"})
+		require.NoError(t, err)
+		script, err = scripts.scriptFor(ctx, 2)
+		require.NoError(t, err)
+		require.Equal(t, []scoringTerm{{weight: 1, signal: scoringSignalQuality}}, script.terms)
+	})
+
+	t.Run("deletes scripts", func(t *testing.T) {
+		require.NoError(t, scripts.delete(ctx, 2))
+		require.ErrorIs(t, scripts.delete(ctx, 2), ErrScoringScriptNotFound)
+
+		script, err := scripts.scriptFor(ctx, 2)
+		require.NoError(t, err)
+		require.Nil(t, script)
+	})
+
+	t.Run("queries override the script of the organization", func(t *testing.T) {
+		script, err := scripts.resolveScoringScript(ctx, 1, DashboardQuery{ScoringScript: "provisioned"})
+		require.NoError(t, err)
+		require.Equal(t, []scoringTerm{{weight: 1, signal: scoringSignalProvisioned}}, script.terms)
+
+		script, err = scripts.resolveScoringScript(ctx, 1, DashboardQuery{ScoringScript: ScoringScriptNone})
+		require.NoError(t, err)
+		require.Nil(t, script)
+
+		script, err = scripts.resolveScoringScript(ctx, 1, DashboardQuery{Sort: "name_sort"})
+		require.NoError(t, err)
+		require.Nil(t, script)
+	})
+}
+
+func TestParseScoringScript(t *testing.T) {
+	script, err := parseScoringScript(`2.5 * recency + quality+provisioned + 3*tag("on call") + governance("certified")`)
+	require.NoError(t, err)
+	require.Equal(t, []scoringTerm{
+		{weight: 2.5, signal: scoringSignalRecency},
+		{weight: 1, signal: scoringSignalQuality},
+		{weight: 1, signal: scoringSignalProvisioned},
+		{weight: 3, signal: scoringSignalTag, arg: "on call"},
+		{weight: 1, signal: scoringSignalGovernance, arg: GovernanceLabelCertified},
+	}, script.terms)
+
+	for _, invalid := range []string{
+		"",
+		"views",
+		"recency +",
+		"recency quality",
+		"0*recency",
+		"11*recency",
+		"2 recency",
+		"tag",
+		`tag("")`,
+		`tag("slo"`,
+		`tag("slo)`,
+		`governance("gold")`,
+		strings.Repeat("recency + ", maxScoringScriptTerms) + "quality",
+		strings.Repeat(" ", maxScoringScriptLength) + "recency",
+	} {
+		_, err := parseScoringScript(invalid)
+		require.ErrorIs(t, err, ErrInvalidScoringScript, invalid)
+	}
+}
+
+func TestDashboardIndex_ScoringScript(t *testing.T) {
+	now := time.Now()
+	index := initTestOrgIndexFromDashes(t, []dashboard{
+		{id: 1, uid: "latency-old", updated: now.AddDate(-2, 0, 0), info: &extract.DashboardInfo{Title: "Latency"}},
+		{id: 2, uid: "latency-recent", updated: now.Add(-time.Hour), info: &extract.DashboardInfo{Title: "Latency overview"}},
+		{id: 3, uid: "latency-slo", updated: now.AddDate(-2, 0, 0), info: &extract.DashboardInfo{Title: "Latency draft", Tags: []string{"slo"}}},
+	})
+	search := func(script string) []string {
+		parsed, err := parseScoringScript(script)
+		require.NoError(t, err)
+		q := DashboardQuery{Query: "latency", Kind: []string{string(entityKindDashboard)}, scoring: parsed}
+		return searchUIDs(t, index, testAllowAllFilter, q)
+	}
+
+	require.Equal(t, "latency-old", searchUIDs(t, index, testAllowAllFilter, DashboardQuery{Query: "latency", Kind: []string{string(entityKindDashboard)}})[0])
+	require.Equal(t, "latency-recent", search("10*recency")[0])
+	require.Equal(t, "latency-slo", search(`10*tag("slo")`)[0])
+	require.Equal(t, "latency-recent", search(`10*recency + tag("slo")`)[0])
+}
@@ -0,0 +1,106 @@
+package searchV2
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SearchDebugHeader is the HTTP response header carrying the search debug info.
+const SearchDebugHeader = "X-Search-Debug"
+
+// searchDebugInfo describes how a search query was executed. It is attached
+// to the frame metadata when DashboardQuery.Debug is set, so that slow or
+// unexpected searches can be investigated without access to the server logs.
+type searchDebugInfo struct {
+	OrgID      int64               `json:"orgId"`
+	Indexes    []string            `json:"indexes"`
+	IndexCache string              `json:"indexCache"` // hit if the org index was already loaded
+	Filters    []string            `json:"filters"`
+	Timings    []searchDebugTiming `json:"timings"`
+}
+
+type searchDebugTiming struct {
+	Step       string  `json:"step"`
+	DurationMs float64 `json:"durationMs"`
+}
+
+func newSearchDebugInfo(orgID int64) *searchDebugInfo {
+	return &searchDebugInfo{
+		OrgID:   orgID,
+		Indexes: []string{},
+		Filters: []string{},
+		Timings: []searchDebugTiming{},
+	}
+}
+
+// track records the time spent in a step since start. It is a no-op on a nil receiver,
+// so callers don't need to check whether debugging is enabled.
+func (d *searchDebugInfo) track(step string, start time.Time) {
+	if d == nil {
+		return
+	}
+	d.Timings = append(d.Timings, searchDebugTiming{
+		Step:       step,
+		DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+	})
+}
+
+// describeSearchQuery lists the filters applied by doSearchQuery for the query.
+func describeSearchQuery(q DashboardQuery) []string {
+	filters := []string{"permissions"}
+	if len(q.Kind) > 0 {
+		filters = append(filters, "kind="+strings.Join(q.Kind, ","))
+	}
+	if len(q.UIDs) > 0 {
+		filters = append(filters, fmt.Sprintf("uid(%d)", len(q.UIDs)))
+	}
+	if q.DashboardUIDs != nil {
+		filters = append(filters, fmt.Sprintf("dashboard_uid(%d)", len(q.DashboardUIDs)))
+	}
+	if len(q.Tags) > 0 {
+		filters = append(filters, "tag="+strings.Join(q.Tags, ","))
+	}
+	if q.PanelType != "" {
+		filters = append(filters, "panel_type="+q.PanelType)
+	}
+	if q.Datasource != "" {
+		filters = append(filters, "ds_uid="+q.Datasource)
+	}
+	if q.TeamID > 0 {
+		filters = append(filters, fmt.Sprintf("team=%d", q.TeamID))
+	}
+	if q.Language != "" {
+		filters = append(filters, "language="+q.Language)
+	}
+	if len(q.Custom) > 0 {
+		names := make([]string, 0, len(q.Custom))
+		for name := range q.Custom {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			filters = append(filters, customFieldPrefix+name+"="+strings.Join(q.Custom[name], ","))
+		}
+	}
+	if q.Report != "" {
+		filters = append(filters, "report="+q.Report)
+	}
+	if q.Location != "" {
+		filters = append(filters, "location="+q.Location)
+	}
+	if q.DatasourceAccess != "" {
+		filters = append(filters, fmt.Sprintf("datasource_access=%s(%d denied)", q.DatasourceAccess, len(q.deniedDatasources)))
+	}
+
+	switch {
+	case q.Query == "*" || q.Query == "":
+		filters = append(filters, "match_all")
+	case shouldUseNgram(q):
+		filters = append(filters, "name_substring", "name_ngram")
+	default:
+		filters = append(filters, "name_substring")
+	}
+	return filters
+}
@@ -0,0 +1,46 @@
+package searchV2
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchDebugInfo(t *testing.T) {
+	t.Run("tracking is a no-op when debugging is disabled", func(t *testing.T) {
+		var debug *searchDebugInfo
+		require.NotPanics(t, func() { debug.track("search", time.Now()) })
+	})
+
+	t.Run("tracks steps in order", func(t *testing.T) {
+		debug := newSearchDebugInfo(testOrgID)
+		debug.track("permissions", time.Now())
+		debug.track("search", time.Now())
+		require.Len(t, debug.Timings, 2)
+		require.Equal(t, "permissions", debug.Timings[0].Step)
+		require.Equal(t, "search", debug.Timings[1].Step)
+	})
+
+	t.Run("describes applied filters", func(t *testing.T) {
+		require.Equal(t, []string{"permissions", "match_all"}, describeSearchQuery(DashboardQuery{}))
+
+		q := DashboardQuery{
+			Query:             "cpu",
+			Kind:              []string{"dashboard", "folder"},
+			Tags:              []string{"prod"},
+			Location:          "general",
+			DatasourceAccess:  DatasourceAccessFilter,
+			deniedDatasources: map[string]bool{"ds": true},
+		}
+		require.Equal(t, []string{
+			"permissions",
+			"kind=dashboard,folder",
+			"tag=prod",
+			"location=general",
+			"datasource_access=filter(1 denied)",
+			"name_substring",
+			"name_ngram",
+		}, describeSearchQuery(q))
+	})
+}
@@ -17,6 +17,145 @@ type MockSearchService struct {
 	mock.Mock
 }
 
+// AddPromotedResult provides a mock function with given fields: ctx, cmd
+func (_m *MockSearchService) AddPromotedResult(ctx context.Context, cmd *AddPromotedResultCommand) (*PromotedResult, error) {
+	ret := _m.Called(ctx, cmd)
+
+	var r0 *PromotedResult
+	if rf, ok := ret.Get(0).(func(context.Context, *AddPromotedResultCommand) *PromotedResult); ok {
+		r0 = rf(ctx, cmd)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*PromotedResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *AddPromotedResultCommand) error); ok {
+		r1 = rf(ctx, cmd)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AddWarmQuery provides a mock function with given fields: ctx, cmd
+func (_m *MockSearchService) AddWarmQuery(ctx context.Context, cmd *AddWarmQueryCommand) (*WarmQuery, error) {
+	ret := _m.Called(ctx, cmd)
+
+	var r0 *WarmQuery
+	if rf, ok := ret.Get(0).(func(context.Context, *AddWarmQueryCommand) *WarmQuery); ok {
+		r0 = rf(ctx, cmd)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*WarmQuery)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *AddWarmQueryCommand) error); ok {
+		r1 = rf(ctx, cmd)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteGovernanceLabel provides a mock function with given fields: ctx, orgID, dashboardUID
+func (_m *MockSearchService) DeleteGovernanceLabel(ctx context.Context, orgID int64, dashboardUID string) error {
+	ret := _m.Called(ctx, orgID, dashboardUID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) error); ok {
+		r0 = rf(ctx, orgID, dashboardUID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ClearPersonalization provides a mock function with given fields: ctx, orgID, userID
+func (_m *MockSearchService) ClearPersonalization(ctx context.Context, orgID int64, userID int64) error {
+	ret := _m.Called(ctx, orgID, userID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) error); ok {
+		r0 = rf(ctx, orgID, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteScoringScript provides a mock function with given fields: ctx, orgID
+func (_m *MockSearchService) DeleteScoringScript(ctx context.Context, orgID int64) error {
+	ret := _m.Called(ctx, orgID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, orgID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeletePromotedResult provides a mock function with given fields: ctx, orgID, id
+func (_m *MockSearchService) DeletePromotedResult(ctx context.Context, orgID int64, id int64) error {
+	ret := _m.Called(ctx, orgID, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) error); ok {
+		r0 = rf(ctx, orgID, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteWarmQuery provides a mock function with given fields: ctx, orgID, id
+func (_m *MockSearchService) DeleteWarmQuery(ctx context.Context, orgID int64, id int64) error {
+	ret := _m.Called(ctx, orgID, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) error); ok {
+		r0 = rf(ctx, orgID, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DiffQuery provides a mock function with given fields: ctx, cmd
+func (_m *MockSearchService) DiffQuery(ctx context.Context, cmd *DiffQueryCommand) (*QueryDiff, error) {
+	ret := _m.Called(ctx, cmd)
+
+	var r0 *QueryDiff
+	if rf, ok := ret.Get(0).(func(context.Context, *DiffQueryCommand) *QueryDiff); ok {
+		r0 = rf(ctx, cmd)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*QueryDiff)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *DiffQueryCommand) error); ok {
+		r1 = rf(ctx, cmd)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // DoDashboardQuery provides a mock function with given fields: ctx, _a1, orgId, query
 func (_m *MockSearchService) DoDashboardQuery(ctx context.Context, _a1 *backend.User, orgId int64, query DashboardQuery) *backend.DataResponse {
 	ret := _m.Called(ctx, _a1, orgId, query)
@@ -33,6 +172,89 @@ func (_m *MockSearchService) DoDashboardQuery(ctx context.Context, _a1 *backend.
 	return r0
 }
 
+// EstimateIndex provides a mock function with given fields: ctx, orgID
+func (_m *MockSearchService) EstimateIndex(ctx context.Context, orgID int64) (*IndexEstimate, error) {
+	ret := _m.Called(ctx, orgID)
+
+	var r0 *IndexEstimate
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *IndexEstimate); ok {
+		r0 = rf(ctx, orgID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*IndexEstimate)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, orgID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetIndexStatus provides a mock function with given fields: ctx, orgId
+func (_m *MockSearchService) GetIndexStatus(ctx context.Context, orgId int64) IndexStatus {
+	ret := _m.Called(ctx, orgId)
+
+	var r0 IndexStatus
+	if rf, ok := ret.Get(0).(func(context.Context, int64) IndexStatus); ok {
+		r0 = rf(ctx, orgId)
+	} else {
+		r0 = ret.Get(0).(IndexStatus)
+	}
+
+	return r0
+}
+
+// GetPersonalization provides a mock function with given fields: ctx, orgID, userID
+func (_m *MockSearchService) GetPersonalization(ctx context.Context, orgID int64, userID int64) (*PersonalizationSignals, error) {
+	ret := _m.Called(ctx, orgID, userID)
+
+	var r0 *PersonalizationSignals
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) *PersonalizationSignals); ok {
+		r0 = rf(ctx, orgID, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*PersonalizationSignals)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64) error); ok {
+		r1 = rf(ctx, orgID, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetScoringScript provides a mock function with given fields: ctx, orgID
+func (_m *MockSearchService) GetScoringScript(ctx context.Context, orgID int64) (*ScoringScript, error) {
+	ret := _m.Called(ctx, orgID)
+
+	var r0 *ScoringScript
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *ScoringScript); ok {
+		r0 = rf(ctx, orgID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*ScoringScript)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, orgID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // IsDisabled provides a mock function with given fields:
 func (_m *MockSearchService) IsDisabled() bool {
 	ret := _m.Called()
@@ -61,11 +283,104 @@ func (_m *MockSearchService) IsReady(ctx context.Context, orgId int64) IsSearchR
 	return r0
 }
 
+// ListGovernanceLabels provides a mock function with given fields: ctx, orgID
+func (_m *MockSearchService) ListGovernanceLabels(ctx context.Context, orgID int64) ([]*GovernanceLabel, error) {
+	ret := _m.Called(ctx, orgID)
+
+	var r0 []*GovernanceLabel
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []*GovernanceLabel); ok {
+		r0 = rf(ctx, orgID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*GovernanceLabel)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, orgID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListPromotedResults provides a mock function with given fields: ctx, orgID
+func (_m *MockSearchService) ListPromotedResults(ctx context.Context, orgID int64) ([]*PromotedResult, error) {
+	ret := _m.Called(ctx, orgID)
+
+	var r0 []*PromotedResult
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []*PromotedResult); ok {
+		r0 = rf(ctx, orgID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*PromotedResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, orgID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListWarmQueries provides a mock function with given fields: ctx, orgID
+func (_m *MockSearchService) ListWarmQueries(ctx context.Context, orgID int64) ([]*WarmQuery, error) {
+	ret := _m.Called(ctx, orgID)
+
+	var r0 []*WarmQuery
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []*WarmQuery); ok {
+		r0 = rf(ctx, orgID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*WarmQuery)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, orgID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RecordSearchClick provides a mock function with given fields: ctx, cmd
+func (_m *MockSearchService) RecordSearchClick(ctx context.Context, cmd *RecordSearchClickCommand) error {
+	ret := _m.Called(ctx, cmd)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *RecordSearchClickCommand) error); ok {
+		r0 = rf(ctx, cmd)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RegisterDocumentEnricher provides a mock function with given fields: name, enricher
+func (_m *MockSearchService) RegisterDocumentEnricher(name string, enricher DocumentEnricher) {
+	_m.Called(name, enricher)
+}
+
 // RegisterDashboardIndexExtender provides a mock function with given fields: ext
 func (_m *MockSearchService) RegisterDashboardIndexExtender(ext DashboardIndexExtender) {
 	_m.Called(ext)
 }
 
+// RegisterEmbeddingProvider provides a mock function with given fields: provider
+func (_m *MockSearchService) RegisterEmbeddingProvider(provider EmbeddingProvider) {
+	_m.Called(provider)
+}
+
 // Run provides a mock function with given fields: ctx
 func (_m *MockSearchService) Run(ctx context.Context) error {
 	ret := _m.Called(ctx)
@@ -80,11 +395,94 @@ func (_m *MockSearchService) Run(ctx context.Context) error {
 	return r0
 }
 
+// RunWarmQueries provides a mock function with given fields: ctx, orgID
+func (_m *MockSearchService) RunWarmQueries(ctx context.Context, orgID int64) ([]*WarmQueryRun, error) {
+	ret := _m.Called(ctx, orgID)
+
+	var r0 []*WarmQueryRun
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []*WarmQueryRun); ok {
+		r0 = rf(ctx, orgID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*WarmQueryRun)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, orgID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetGovernanceLabel provides a mock function with given fields: ctx, cmd
+func (_m *MockSearchService) SetGovernanceLabel(ctx context.Context, cmd *SetGovernanceLabelCommand) (*GovernanceLabel, error) {
+	ret := _m.Called(ctx, cmd)
+
+	var r0 *GovernanceLabel
+	if rf, ok := ret.Get(0).(func(context.Context, *SetGovernanceLabelCommand) *GovernanceLabel); ok {
+		r0 = rf(ctx, cmd)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*GovernanceLabel)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *SetGovernanceLabelCommand) error); ok {
+		r1 = rf(ctx, cmd)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetScoringScript provides a mock function with given fields: ctx, cmd
+func (_m *MockSearchService) SetScoringScript(ctx context.Context, cmd *SetScoringScriptCommand) (*ScoringScript, error) {
+	ret := _m.Called(ctx, cmd)
+
+	var r0 *ScoringScript
+	if rf, ok := ret.Get(0).(func(context.Context, *SetScoringScriptCommand) *ScoringScript); ok {
+		r0 = rf(ctx, cmd)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*ScoringScript)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *SetScoringScriptCommand) error); ok {
+		r1 = rf(ctx, cmd)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // TriggerReIndex provides a mock function with given fields:
 func (_m *MockSearchService) TriggerReIndex() {
 	_m.Called()
 }
 
+// WaitUntilReady provides a mock function with given fields: ctx, orgId
+func (_m *MockSearchService) WaitUntilReady(ctx context.Context, orgId int64) IsSearchReadyResponse {
+	ret := _m.Called(ctx, orgId)
+
+	var r0 IsSearchReadyResponse
+	if rf, ok := ret.Get(0).(func(context.Context, int64) IsSearchReadyResponse); ok {
+		r0 = rf(ctx, orgId)
+	} else {
+		r0 = ret.Get(0).(IsSearchReadyResponse)
+	}
+
+	return r0
+}
+
 // doDashboardQuery provides a mock function with given fields: ctx, _a1, orgId, query
 func (_m *MockSearchService) doDashboardQuery(ctx context.Context, _a1 *user.SignedInUser, orgId int64, query DashboardQuery) *backend.DataResponse {
 	ret := _m.Called(ctx, _a1, orgId, query)
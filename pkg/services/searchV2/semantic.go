@@ -0,0 +1,291 @@
+package searchV2
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/blugelabs/bluge"
+
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+)
+
+const (
+	// documentFieldEmbedding stores the embedding of dashboards, so that indexes restored from disk
+	// don't need to compute them again
+	documentFieldEmbedding = "_embedding"
+	// embeddingBatchSize is the number of dashboards embedded in one call of the provider
+	embeddingBatchSize = 100
+	// maxSemanticMatches is the number of most similar dashboards blended with the keyword results
+	maxSemanticMatches = 50
+)
+
+// EmbeddingProvider computes the embeddings of texts for semantic search, for example with a
+// sentence embedding model. The embeddings of a provider must all have the same dimension.
+type EmbeddingProvider interface {
+	// Embed returns the embeddings of the texts, in the same order.
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// semanticSearchEnabled tells whether the similarity of embeddings is blended with keyword scores.
+func (i *searchIndex) semanticSearchEnabled() bool {
+	return i.embeddings != nil && i.features != nil && i.features.IsEnabled(featuremgmt.FlagSearchSemantic)
+}
+
+// embeddingText is the text of a dashboard its embedding is computed from.
+func embeddingText(dash dashboard) string {
+	return strings.TrimSpace(dash.info.Title + "\n" + dash.info.Description)
+}
+
+// embedDashboards sets the embeddings of the dashboards. A failing provider doesn't fail indexing,
+// the dashboards are indexed without embeddings and are only found by keywords.
+func (i *searchIndex) embedDashboards(ctx context.Context, orgID int64, dashboards []dashboard) {
+	if !i.semanticSearchEnabled() {
+		return
+	}
+	var batch []*dashboard
+	flush := func() bool {
+		if len(batch) == 0 {
+			return true
+		}
+		texts := make([]string, 0, len(batch))
+		for _, dash := range batch {
+			texts = append(texts, embeddingText(*dash))
+		}
+		embeddings, err := i.embeddings.Embed(ctx, texts)
+		if err == nil && len(embeddings) != len(texts) {
+			err = fmt.Errorf("got %d embeddings for %d texts", len(embeddings), len(texts))
+		}
+		if err != nil {
+			i.logger.Warn("Failed to embed dashboards", "orgId", orgID, "error", err)
+			return false
+		}
+		for idx, dash := range batch {
+			dash.embedding = normalizeEmbedding(embeddings[idx])
+		}
+		batch = batch[:0]
+		return true
+	}
+
+	for idx := range dashboards {
+		dash := &dashboards[idx]
+		if dash.isFolder || dash.info == nil || embeddingText(*dash) == "" {
+			continue
+		}
+		batch = append(batch, dash)
+		if len(batch) == embeddingBatchSize && !flush() {
+			return
+		}
+	}
+	flush()
+}
+
+// normalizeEmbedding scales the embedding to a length of 1, so that the cosine similarity of two
+// embeddings is their dot product. Empty embeddings and embeddings of length 0 are dropped.
+func normalizeEmbedding(embedding []float32) []float32 {
+	var norm float64
+	for _, v := range embedding {
+		norm += float64(v) * float64(v)
+	}
+	if norm == 0 || math.IsNaN(norm) || math.IsInf(norm, 0) {
+		return nil
+	}
+	norm = math.Sqrt(norm)
+	normalized := make([]float32, len(embedding))
+	for idx, v := range embedding {
+		normalized[idx] = float32(float64(v) / norm)
+	}
+	return normalized
+}
+
+func addEmbeddingField(doc *bluge.Document, embedding []float32) {
+	if len(embedding) == 0 {
+		return
+	}
+	doc.AddField(bluge.NewStoredOnlyField(documentFieldEmbedding, encodeEmbedding(embedding)))
+}
+
+func encodeEmbedding(embedding []float32) []byte {
+	b := make([]byte, 4*len(embedding))
+	for idx, v := range embedding {
+		binary.LittleEndian.PutUint32(b[4*idx:], math.Float32bits(v))
+	}
+	return b
+}
+
+func decodeEmbedding(b []byte) ([]float32, error) {
+	if len(b)%4 != 0 {
+		return nil, errors.New("invalid embedding length")
+	}
+	embedding := make([]float32, len(b)/4)
+	for idx := range embedding {
+		embedding[idx] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*idx:]))
+	}
+	return embedding, nil
+}
+
+// dashboardVectors holds the embeddings of the dashboards of an organization index, keyed by
+// dashboard UID. They are compared with the embedding of each query, so they are kept in memory
+// next to the index which stores them in the documents of the dashboards.
+type dashboardVectors struct {
+	mu      sync.RWMutex
+	vectors map[string][]float32
+}
+
+func newDashboardVectors(dashboards []dashboard) *dashboardVectors {
+	v := &dashboardVectors{vectors: map[string][]float32{}}
+	for _, dash := range dashboards {
+		if len(dash.embedding) > 0 {
+			v.vectors[dash.uid] = dash.embedding
+		}
+	}
+	return v
+}
+
+// loadDashboardVectors reads the embeddings stored in the dashboard documents of an index.
+func loadDashboardVectors(index *orgIndex) (*dashboardVectors, error) {
+	reader, cancel, err := index.readerForIndex(indexTypeDashboard)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	query := bluge.NewTermQuery(string(entityKindDashboard)).SetField(documentFieldKind)
+	count, err := reader.Count()
+	if err != nil {
+		return nil, err
+	}
+	matches, err := reader.Search(context.Background(), bluge.NewTopNSearch(int(count), query))
+	if err != nil {
+		return nil, err
+	}
+
+	v := &dashboardVectors{vectors: map[string][]float32{}}
+	match, err := matches.Next()
+	for err == nil && match != nil {
+		var uid string
+		var embedding []float32
+		var decodeErr error
+		err = match.VisitStoredFields(func(field string, value []byte) bool {
+			switch field {
+			case documentFieldUID:
+				uid = string(value)
+			case documentFieldEmbedding:
+				embedding, decodeErr = decodeEmbedding(value)
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+		if decodeErr != nil {
+			return nil, fmt.Errorf("dashboard %s: %w", uid, decodeErr)
+		}
+		if len(embedding) > 0 {
+			v.vectors[uid] = embedding
+		}
+		match, err = matches.Next()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (v *dashboardVectors) set(uid string, embedding []float32) {
+	if v == nil {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if len(embedding) == 0 {
+		delete(v.vectors, uid)
+		return
+	}
+	v.vectors[uid] = embedding
+}
+
+func (v *dashboardVectors) remove(uids ...string) {
+	if v == nil {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for _, uid := range uids {
+		delete(v.vectors, uid)
+	}
+}
+
+// nearest returns the cosine similarity of the dashboards most similar to the normalized query
+// embedding, leaving out the ones under minSimilarity.
+func (v *dashboardVectors) nearest(query []float32, minSimilarity float64, limit int) map[string]float64 {
+	if v == nil || len(query) == 0 {
+		return nil
+	}
+	type scored struct {
+		uid        string
+		similarity float64
+	}
+	var candidates []scored
+
+	v.mu.RLock()
+	for uid, embedding := range v.vectors {
+		if len(embedding) != len(query) {
+			continue
+		}
+		var similarity float64
+		for idx := range query {
+			similarity += float64(query[idx]) * float64(embedding[idx])
+		}
+		if similarity >= minSimilarity {
+			candidates = append(candidates, scored{uid: uid, similarity: similarity})
+		}
+	}
+	v.mu.RUnlock()
+
+	sort.Slice(candidates, func(a, b int) bool {
+		if candidates[a].similarity != candidates[b].similarity {
+			return candidates[a].similarity > candidates[b].similarity
+		}
+		return candidates[a].uid < candidates[b].uid
+	})
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	similarities := make(map[string]float64, len(candidates))
+	for _, c := range candidates {
+		similarities[c.uid] = c.similarity
+	}
+	return similarities
+}
+
+// semanticBoosts returns the score added to the dashboards similar in meaning to the query, so that
+// they match it even without keywords in common. Queries are answered by keywords only when the
+// query can't be embedded.
+func (i *searchIndex) semanticBoosts(ctx context.Context, index *orgIndex, query string) map[string]float64 {
+	embeddings, err := i.embeddings.Embed(ctx, []string{query})
+	if err != nil || len(embeddings) != 1 {
+		i.logger.Warn("Failed to embed search query, searching by keywords only", "error", err)
+		return nil
+	}
+	similarities := index.vectors.nearest(normalizeEmbedding(embeddings[0]), i.settings.SemanticMinSimilarity, maxSemanticMatches)
+	boosts := make(map[string]float64, len(similarities))
+	for uid, similarity := range similarities {
+		boosts[uid] = similarity * i.settings.SemanticWeight
+	}
+	return boosts
+}
+
+// newSemanticQuery matches the dashboards similar in meaning to the query, scored by their boost.
+func newSemanticQuery(boosts map[string]float64) bluge.Query {
+	bq := bluge.NewBooleanQuery()
+	for uid, boost := range boosts {
+		bq.AddShould(bluge.NewTermQuery(uid).SetField(documentFieldUID).SetBoost(boost))
+	}
+	return bq
+}
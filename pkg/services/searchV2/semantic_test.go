@@ -0,0 +1,143 @@
+package searchV2
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/searchV2/extract"
+	"github.com/grafana/grafana/pkg/services/store"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// conceptEmbeddings embeds texts with a dimension per concept, counting the words of each concept.
+type conceptEmbeddings struct {
+	err   error
+	calls int
+}
+
+var testConcepts = [][]string{
+	{"checkout", "shop", "cart", "payment", "customer"},
+	{"errors", "failures", "5xx", "exceptions"},
+	{"cpu", "memory", "node", "disk"},
+}
+
+func (e *conceptEmbeddings) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	e.calls++
+	if e.err != nil {
+		return nil, e.err
+	}
+	embeddings := make([][]float32, 0, len(texts))
+	for _, text := range texts {
+		embedding := make([]float32, len(testConcepts))
+		for _, word := range strings.Fields(strings.ToLower(text)) {
+			for dim, concept := range testConcepts {
+				if stringInSlice(word, concept) {
+					embedding[dim]++
+				}
+			}
+		}
+		embeddings = append(embeddings, embedding)
+	}
+	return embeddings, nil
+}
+
+func TestDashboardIndex_SemanticSearch(t *testing.T) {
+	dashboards := []dashboard{
+		{id: 1, uid: "shop", info: &extract.DashboardInfo{Title: "Web shop", Description: "cart failures and 5xx"}},
+		{id: 2, uid: "nodes", info: &extract.DashboardInfo{Title: "Node overview", Description: "cpu and memory"}},
+		{id: 3, uid: "empty", info: &extract.DashboardInfo{}},
+	}
+	setup := func(t *testing.T, embeddings EmbeddingProvider, features featuremgmt.FeatureToggles) (*searchIndex, *orgIndex) {
+		settings := setting.SearchSettings{SemanticWeight: 3, SemanticMinSimilarity: 0.5}
+		// embeddings are set on the loaded dashboards
+		loaded := append([]dashboard(nil), dashboards...)
+		i := newSearchIndex(&testDashboardLoader{dashboards: loaded}, &store.MockEntityEventsService{}, &NoopDocumentExtender{}, nil, tracing.InitializeTracerForTest(), features, settings, nil)
+		i.embeddings = embeddings
+		_, err := i.buildOrgIndex(context.Background(), testOrgID)
+		require.NoError(t, err)
+		return i, i.perOrgIndex[testOrgID]
+	}
+	search := func(i *searchIndex, index *orgIndex, query string) []string {
+		q := DashboardQuery{Query: query, Kind: []string{string(entityKindDashboard)}}
+		if i.semanticSearchEnabled() {
+			q.semanticBoosts = i.semanticBoosts(context.Background(), index, query)
+		}
+		return searchUIDs(t, index, testAllowAllFilter, q)
+	}
+
+	t.Run("dashboards similar in meaning match without keywords in common", func(t *testing.T) {
+		i, index := setup(t, &conceptEmbeddings{}, featuremgmt.WithFeatures(featuremgmt.FlagSearchSemantic))
+
+		require.Equal(t, []string{"shop"}, search(i, index, "customer checkout errors"))
+		require.Equal(t, []string{"nodes"}, search(i, index, "disk"))
+		// keywords still match
+		require.Equal(t, []string{"nodes"}, search(i, index, "overview"))
+	})
+
+	t.Run("embeddings are stored with the index", func(t *testing.T) {
+		_, index := setup(t, &conceptEmbeddings{}, featuremgmt.WithFeatures(featuremgmt.FlagSearchSemantic))
+
+		loaded, err := loadDashboardVectors(index)
+		require.NoError(t, err)
+		require.Equal(t, index.vectors.vectors, loaded.vectors)
+		require.Len(t, loaded.vectors, 2)
+	})
+
+	t.Run("removed dashboards don't match", func(t *testing.T) {
+		i, index := setup(t, &conceptEmbeddings{}, featuremgmt.WithFeatures(featuremgmt.FlagSearchSemantic))
+
+		require.NoError(t, i.removeDashboard(context.Background(), index, "shop"))
+		require.Empty(t, search(i, index, "customer checkout errors"))
+		require.NotContains(t, index.vectors.vectors, "shop")
+	})
+
+	t.Run("failing embeddings fall back to keywords", func(t *testing.T) {
+		embeddings := &conceptEmbeddings{err: errors.New("model unavailable")}
+		i, index := setup(t, embeddings, featuremgmt.WithFeatures(featuremgmt.FlagSearchSemantic))
+
+		require.Empty(t, index.vectors.vectors)
+		require.Empty(t, search(i, index, "customer checkout errors"))
+		require.Equal(t, []string{"shop"}, search(i, index, "shop"))
+	})
+
+	t.Run("disabled without the feature toggle", func(t *testing.T) {
+		embeddings := &conceptEmbeddings{}
+		i, index := setup(t, embeddings, featuremgmt.WithFeatures())
+
+		require.False(t, i.semanticSearchEnabled())
+		require.Empty(t, search(i, index, "customer checkout errors"))
+		require.Zero(t, embeddings.calls)
+	})
+}
+
+func TestDashboardVectors_Nearest(t *testing.T) {
+	v := &dashboardVectors{vectors: map[string][]float32{
+		"a": normalizeEmbedding([]float32{1, 0}),
+		"b": normalizeEmbedding([]float32{1, 1}),
+		"c": normalizeEmbedding([]float32{0, 1}),
+		"d": normalizeEmbedding([]float32{1, 0, 0}),
+	}}
+	query := normalizeEmbedding([]float32{2, 0})
+
+	nearest := v.nearest(query, 0.5, 10)
+	require.Len(t, nearest, 2)
+	require.InDelta(t, 1, nearest["a"], 1e-6)
+	require.InDelta(t, 0.707, nearest["b"], 1e-3)
+
+	require.Equal(t, []string{"a"}, mapKeys(v.nearest(query, 0.5, 1)))
+	require.Nil(t, normalizeEmbedding([]float32{0, 0}))
+}
+
+func mapKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/accesscontrol"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/secrets"
 	"github.com/grafana/grafana/pkg/services/sqlstore"
 	"github.com/grafana/grafana/pkg/services/store"
 	"github.com/grafana/grafana/pkg/services/user"
@@ -44,6 +45,15 @@ var (
 		},
 		[]string{"reason"},
 	)
+	dashboardSearchQueryAPIVersionCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "dashboard_search_query_api_version_total",
+			Help:      "A counter for dashboard search requests by version of the query API, to tell when deprecated versions are no longer used",
+		},
+		[]string{"version"},
+	)
 	dashboardSearchSuccessRequestsDuration = promauto.NewHistogram(
 		prometheus.HistogramOpts{
 			Name:      "dashboard_search_successes_duration_seconds",
@@ -69,18 +79,47 @@ type StandardSearchService struct {
 	ac         accesscontrol.Service
 	orgService org.Service
 
-	logger         log.Logger
-	dashboardIndex *searchIndex
-	extender       DashboardIndexExtender
-	reIndexCh      chan struct{}
+	logger          log.Logger
+	dashboardIndex  *searchIndex
+	extender        DashboardIndexExtender
+	reIndexCh       chan struct{}
+	limiter         *queryLimiter
+	coalescer       *queryCoalescer
+	deniedCache     *deniedCache
+	federation      *federation
+	promoted        *promotedResults
+	warmQueries     *warmQueries
+	governance      *governanceLabelStore
+	scoring         *scoringScripts
+	personalization *personalizationStore
 }
 
 func (s *StandardSearchService) IsReady(ctx context.Context, orgId int64) IsSearchReadyResponse {
 	return s.dashboardIndex.isInitialized(ctx, orgId)
 }
 
-func ProvideService(cfg *setting.Cfg, sql *sqlstore.SQLStore, entityEventStore store.EntityEventsService, ac accesscontrol.Service, tracer tracing.Tracer, features featuremgmt.FeatureToggles, orgService org.Service) SearchService {
+func (s *StandardSearchService) WaitUntilReady(ctx context.Context, orgId int64) IsSearchReadyResponse {
+	return s.dashboardIndex.waitUntilReady(ctx, orgId)
+}
+
+func (s *StandardSearchService) GetIndexStatus(_ context.Context, orgId int64) IndexStatus {
+	return s.dashboardIndex.getOrgStatus(orgId)
+}
+
+func (s *StandardSearchService) EstimateIndex(ctx context.Context, orgID int64) (*IndexEstimate, error) {
+	return s.dashboardIndex.estimateOrgIndex(ctx, orgID)
+}
+
+func ProvideService(cfg *setting.Cfg, sql *sqlstore.SQLStore, entityEventStore store.EntityEventsService, ac accesscontrol.Service, tracer tracing.Tracer, features featuremgmt.FeatureToggles, orgService org.Service, secretsService secrets.Service, storageService store.StorageService) SearchService {
 	extender := &NoopExtender{}
+	var persister *indexPersister
+	if cfg.Search.IndexPath != "" {
+		persister = newIndexPersister(cfg.Search.IndexPath, cfg.Search.IndexEncryptionEnabled, secretsService)
+	}
+	var loader dashboardLoader = newSQLDashboardLoader(sql, tracer, cfg.Search)
+	if features.IsEnabled(featuremgmt.FlagDashboardsFromStorage) {
+		loader = newStorageDashboardLoader(storageService, sql, tracer, cfg.Search)
+	}
 	s := &StandardSearchService{
 		cfg: cfg,
 		sql: sql,
@@ -90,19 +129,33 @@ func ProvideService(cfg *setting.Cfg, sql *sqlstore.SQLStore, entityEventStore s
 			ac:  ac,
 		},
 		dashboardIndex: newSearchIndex(
-			newSQLDashboardLoader(sql, tracer, cfg.Search),
+			loader,
 			entityEventStore,
 			extender.GetDocumentExtender(),
 			newFolderIDLookup(sql),
 			tracer,
 			features,
 			cfg.Search,
+			persister,
 		),
 		logger:     log.New("searchV2"),
 		extender:   extender,
 		reIndexCh:  make(chan struct{}, 1),
 		orgService: orgService,
+		limiter:    newQueryLimiter(cfg.Search),
+		coalescer:  newQueryCoalescer(cfg.Search),
+		federation: newFederation(cfg.Search),
+		promoted:   newPromotedResults(sql),
+		scoring:    newScoringScripts(sql),
 	}
+	s.personalization = newPersonalizationStore(cfg.Search, features)
+	s.deniedCache = newDeniedCache(cfg.Search.DeniedCacheTTL)
+	s.dashboardIndex.deniedCache = s.deniedCache
+	s.warmQueries = newWarmQueries(sql)
+	s.dashboardIndex.warmQueries = s.warmQueries
+	s.governance = newGovernanceLabelStore(sql)
+	s.dashboardIndex.governance = s.governance
+	s.dashboardIndex.orgLister = s.listOrgIDs
 	return s
 }
 
@@ -114,16 +167,145 @@ func (s *StandardSearchService) IsDisabled() bool {
 }
 
 func (s *StandardSearchService) Run(ctx context.Context) error {
+	orgIDs, err := s.listOrgIDs(ctx)
+	if err != nil {
+		return err
+	}
+	return s.dashboardIndex.run(ctx, orgIDs, s.reIndexCh)
+}
+
+func (s *StandardSearchService) listOrgIDs(ctx context.Context) ([]int64, error) {
 	orgQuery := &models.SearchOrgsQuery{}
 	err := s.sql.SearchOrgs(ctx, orgQuery)
 	if err != nil {
-		return fmt.Errorf("can't get org list: %w", err)
+		return nil, fmt.Errorf("can't get org list: %w", err)
 	}
 	orgIDs := make([]int64, 0, len(orgQuery.Result))
 	for _, org := range orgQuery.Result {
 		orgIDs = append(orgIDs, org.Id)
 	}
-	return s.dashboardIndex.run(ctx, orgIDs, s.reIndexCh)
+	return orgIDs, nil
+}
+
+func (s *StandardSearchService) ListPromotedResults(ctx context.Context, orgID int64) ([]*PromotedResult, error) {
+	return s.promoted.list(ctx, orgID)
+}
+
+func (s *StandardSearchService) AddPromotedResult(ctx context.Context, cmd *AddPromotedResultCommand) (*PromotedResult, error) {
+	return s.promoted.add(ctx, cmd)
+}
+
+func (s *StandardSearchService) DeletePromotedResult(ctx context.Context, orgID int64, id int64) error {
+	return s.promoted.delete(ctx, orgID, id)
+}
+
+func (s *StandardSearchService) ListWarmQueries(ctx context.Context, orgID int64) ([]*WarmQuery, error) {
+	return s.warmQueries.list(ctx, orgID)
+}
+
+func (s *StandardSearchService) AddWarmQuery(ctx context.Context, cmd *AddWarmQueryCommand) (*WarmQuery, error) {
+	return s.warmQueries.add(ctx, cmd)
+}
+
+func (s *StandardSearchService) DeleteWarmQuery(ctx context.Context, orgID int64, id int64) error {
+	return s.warmQueries.delete(ctx, orgID, id)
+}
+
+// RunWarmQueries runs the warm queries of the organization now, building its index if needed.
+func (s *StandardSearchService) RunWarmQueries(ctx context.Context, orgID int64) ([]*WarmQueryRun, error) {
+	index, err := s.dashboardIndex.getOrCreateOrgIndex(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	return s.warmQueries.warm(ctx, orgID, index, WarmQueryTriggerManual)
+}
+
+// DiffQuery compares the results of a query on the current index of the organization with another
+// index, see DiffQueryCommand. Shadow indexes are built on demand, which takes as long as a full
+// re-index of the organization.
+func (s *StandardSearchService) DiffQuery(ctx context.Context, cmd *DiffQueryCommand) (*QueryDiff, error) {
+	if cmd.Against == "" {
+		cmd.Against = QueryDiffAgainstShadow
+	}
+	if err := validateWarmQuery(cmd.Query); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidQueryDiff, err)
+	}
+
+	index, err := s.dashboardIndex.getOrCreateOrgIndex(ctx, cmd.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.dashboardIndex.sync(ctx); err != nil {
+		return nil, err
+	}
+	other, err := s.dashboardIndex.otherOrgIndex(ctx, cmd.OrgID, cmd.Against)
+	if err != nil {
+		return nil, err
+	}
+	defer other.close(s.logger)
+
+	return s.dashboardIndex.diffQuery(ctx, index, other, warmQueryFor(cmd.Query), cmd.Against)
+}
+
+func (s *StandardSearchService) ListGovernanceLabels(ctx context.Context, orgID int64) ([]*GovernanceLabel, error) {
+	return s.governance.list(ctx, orgID)
+}
+
+// SetGovernanceLabel labels a dashboard, replacing its current label. The dashboard is re-indexed
+// once the index applies the entity event saved with the label.
+func (s *StandardSearchService) SetGovernanceLabel(ctx context.Context, cmd *SetGovernanceLabelCommand) (*GovernanceLabel, error) {
+	return s.governance.set(ctx, cmd)
+}
+
+func (s *StandardSearchService) DeleteGovernanceLabel(ctx context.Context, orgID int64, dashboardUID string) error {
+	return s.governance.delete(ctx, orgID, dashboardUID)
+}
+
+func (s *StandardSearchService) GetScoringScript(ctx context.Context, orgID int64) (*ScoringScript, error) {
+	return s.scoring.get(ctx, orgID)
+}
+
+// SetScoringScript validates and saves the scoring script of the organization, replacing its
+// current script. Queries on this instance use it right away, other instances within a minute.
+func (s *StandardSearchService) SetScoringScript(ctx context.Context, cmd *SetScoringScriptCommand) (*ScoringScript, error) {
+	return s.scoring.set(ctx, cmd)
+}
+
+func (s *StandardSearchService) DeleteScoringScript(ctx context.Context, orgID int64) error {
+	return s.scoring.delete(ctx, orgID)
+}
+
+// RecordSearchClick records that the user opened a dashboard from search results, to rank it and
+// the dashboards of its folder higher in their next searches.
+func (s *StandardSearchService) RecordSearchClick(ctx context.Context, cmd *RecordSearchClickCommand) error {
+	if !s.personalization.enabled() {
+		return ErrPersonalizationDisabled
+	}
+	index, err := s.dashboardIndex.getOrCreateOrgIndex(ctx, cmd.OrgID)
+	if err != nil {
+		return err
+	}
+	location, found, err := getDashboardLocation(index, cmd.DashboardUID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrPersonalizationDashboardNotFound
+	}
+	s.personalization.record(personalizationKey{orgID: cmd.OrgID, userID: cmd.UserID}, cmd.DashboardUID, location)
+	return nil
+}
+
+// GetPersonalization returns the signals stored for the user, even once personalization is
+// disabled so that users can still see and clear them.
+func (s *StandardSearchService) GetPersonalization(_ context.Context, orgID int64, userID int64) (*PersonalizationSignals, error) {
+	signals := s.personalization.get(personalizationKey{orgID: orgID, userID: userID})
+	return &signals, nil
+}
+
+func (s *StandardSearchService) ClearPersonalization(_ context.Context, orgID int64, userID int64) error {
+	s.personalization.clear(personalizationKey{orgID: orgID, userID: userID})
+	return nil
 }
 
 func (s *StandardSearchService) TriggerReIndex() {
@@ -139,6 +321,19 @@ func (s *StandardSearchService) RegisterDashboardIndexExtender(ext DashboardInde
 	s.dashboardIndex.extender = ext.GetDocumentExtender()
 }
 
+// RegisterDocumentEnricher adds custom fields to dashboards indexed from now on, it is meant to
+// be called before the search service runs.
+func (s *StandardSearchService) RegisterDocumentEnricher(name string, enricher DocumentEnricher) {
+	s.dashboardIndex.enrichers = append(s.dashboardIndex.enrichers, namedEnricher{name: name, enricher: enricher})
+}
+
+// RegisterEmbeddingProvider computes the embeddings used for semantic search with the provider,
+// when the searchSemantic feature toggle is enabled. It is meant to be called before the search
+// service runs.
+func (s *StandardSearchService) RegisterEmbeddingProvider(provider EmbeddingProvider) {
+	s.dashboardIndex.embeddings = provider
+}
+
 func (s *StandardSearchService) getUser(ctx context.Context, backendUser *backend.User, orgId int64) (*user.SignedInUser, error) {
 	// TODO: get user & user's permissions from the request context
 
@@ -176,8 +371,18 @@ func (s *StandardSearchService) getUser(ctx context.Context, backendUser *backen
 		usr = getSignedInUserQuery.Result
 	}
 
+	if err := s.loadUserPermissions(ctx, usr, orgId); err != nil {
+		s.logger.Error("failed to retrieve user permissions", "error", err, "email", backendUser.Email)
+		return nil, errors.New("auth error")
+	}
+	return usr, nil
+}
+
+// loadUserPermissions sets the permissions of the user in the organization, unless access control
+// is disabled or they are already set.
+func (s *StandardSearchService) loadUserPermissions(ctx context.Context, usr *user.SignedInUser, orgId int64) error {
 	if s.ac.IsDisabled() {
-		return usr, nil
+		return nil
 	}
 
 	if usr.Permissions == nil {
@@ -186,19 +391,18 @@ func (s *StandardSearchService) getUser(ctx context.Context, backendUser *backen
 
 	if _, ok := usr.Permissions[orgId]; ok {
 		// permissions as part of the `s.sql.GetSignedInUser` query - return early
-		return usr, nil
+		return nil
 	}
 
 	// TODO: ensure this is cached
 	permissions, err := s.ac.GetUserPermissions(ctx, usr,
 		accesscontrol.Options{ReloadCache: false})
 	if err != nil {
-		s.logger.Error("failed to retrieve user permissions", "error", err, "email", backendUser.Email)
-		return nil, errors.New("auth error")
+		return err
 	}
 
 	usr.Permissions[orgId] = accesscontrol.GroupScopesByAction(permissions)
-	return usr, nil
+	return nil
 }
 
 func (s *StandardSearchService) DoDashboardQuery(ctx context.Context, user *backend.User, orgID int64, q DashboardQuery) *backend.DataResponse {
@@ -229,10 +433,98 @@ func (s *StandardSearchService) DoDashboardQuery(ctx context.Context, user *back
 	return query
 }
 
+// getDashboardReadFilter returns the filter of the dashboards the user can read. When the auth
+// service can list them, the readable UIDs are cached and set on the query so that the check is
+// done by the search query itself. Otherwise the filter is evaluated for each document, and the
+// denials are cached.
+func (s *StandardSearchService) getDashboardReadFilter(signedInUser *user.SignedInUser, q *DashboardQuery) (ResourceFilter, error) {
+	auth, ok := s.auth.(readableUIDsAuthService)
+	if !ok {
+		filter, err := s.auth.GetDashboardReadFilter(signedInUser)
+		if err != nil {
+			return nil, err
+		}
+		return s.deniedCache.wrap(signedInUser, filter), nil
+	}
+
+	readable, err := s.deniedCache.readable(signedInUser, func() (map[string]bool, error) {
+		return auth.GetDashboardReadUIDs(signedInUser)
+	})
+	if err != nil {
+		return nil, err
+	}
+	uids := readable.uids
+	q.readableUIDs = uids
+	return func(uid string) bool {
+		return uids[uid]
+	}, nil
+}
+
+// getCapabilityChecker falls back to the organization role when the auth service can't tell what
+// the user can do with dashboards.
+func (s *StandardSearchService) getCapabilityChecker(signedInUser *user.SignedInUser) (capabilityChecker, error) {
+	if auth, ok := s.auth.(capabilitiesAuthService); ok {
+		return auth.GetDashboardCapabilities(signedInUser)
+	}
+	return newRoleCapabilityChecker(signedInUser), nil
+}
+
+// doDashboardQuery executes the query, or shares the response of an identical query in flight, see
+// queryCoalescer.
 func (s *StandardSearchService) doDashboardQuery(ctx context.Context, signedInUser *user.SignedInUser, orgID int64, q DashboardQuery) *backend.DataResponse {
+	if key, ok := s.coalescingKey(orgID, signedInUser, q); ok {
+		return s.coalescer.do(ctx, key, func() *backend.DataResponse {
+			return s.executeDashboardQuery(ctx, signedInUser, orgID, q)
+		})
+	}
+	return s.executeDashboardQuery(ctx, signedInUser, orgID, q)
+}
+
+func (s *StandardSearchService) executeDashboardQuery(ctx context.Context, signedInUser *user.SignedInUser, orgID int64, q DashboardQuery) *backend.DataResponse {
+	if q.PreviewAs != nil {
+		previewUser, err := s.getPreviewUser(ctx, signedInUser, orgID, q.PreviewAs)
+		if err != nil {
+			return &backend.DataResponse{Error: err}
+		}
+		signedInUser = previewUser
+		q.PreviewAs = nil
+		// federated instances don't know the previewed user
+		q.Federated = false
+	}
+
+	// the counts are of this instance only
+	if q.Federated && !q.Stats && s.federation != nil {
+		if !canSearchFederated(s.ac, signedInUser, orgID) {
+			return &backend.DataResponse{Error: ErrFederatedSearchDenied}
+		}
+		return s.federation.query(ctx, orgID, q, func(q DashboardQuery) *backend.DataResponse {
+			return s.doDashboardQuery(ctx, signedInUser, orgID, q)
+		})
+	}
+
 	rsp := &backend.DataResponse{}
 
-	filter, err := s.auth.GetDashboardReadFilter(signedInUser)
+	var debug *searchDebugInfo
+	if q.Debug {
+		debug = newSearchDebugInfo(orgID)
+	}
+	queryStart := time.Now()
+
+	start := time.Now()
+	release, err := s.limiter.acquire(ctx, orgID)
+	debug.track("queue", start)
+	if err != nil {
+		dashboardSearchFailureRequestsCounter.With(prometheus.Labels{
+			"reason": "query_limit",
+		}).Inc()
+		rsp.Error = err
+		return rsp
+	}
+	defer release()
+
+	start = time.Now()
+	filter, err := s.getDashboardReadFilter(signedInUser, &q)
+	debug.track("permissions", start)
 	if err != nil {
 		dashboardSearchFailureRequestsCounter.With(prometheus.Labels{
 			"reason": "get_dashboard_filter_error",
@@ -241,7 +533,26 @@ func (s *StandardSearchService) doDashboardQuery(ctx context.Context, signedInUs
 		return rsp
 	}
 
+	start = time.Now()
+	q, filter, err = resolveVirtualKinds(ctx, q, filter, s.virtualKindLookup(signedInUser, orgID))
+	debug.track("virtual_kinds", start)
+	if err != nil {
+		dashboardSearchFailureRequestsCounter.With(prometheus.Labels{
+			"reason": "resolve_virtual_kinds_error",
+		}).Inc()
+		rsp.Error = err
+		return rsp
+	}
+
+	if debug != nil {
+		debug.IndexCache = "miss"
+		if _, ok := s.dashboardIndex.getOrgIndex(orgID); ok {
+			debug.IndexCache = "hit"
+		}
+	}
+	start = time.Now()
 	index, err := s.dashboardIndex.getOrCreateOrgIndex(ctx, orgID)
+	debug.track("get_index", start)
 	if err != nil {
 		dashboardSearchFailureRequestsCounter.With(prometheus.Labels{
 			"reason": "get_index_error",
@@ -250,7 +561,9 @@ func (s *StandardSearchService) doDashboardQuery(ctx context.Context, signedInUs
 		return rsp
 	}
 
+	start = time.Now()
 	err = s.dashboardIndex.sync(ctx)
+	debug.track("sync", start)
 	if err != nil {
 		dashboardSearchFailureRequestsCounter.With(prometheus.Labels{
 			"reason": "dashboard_index_sync_error",
@@ -259,18 +572,151 @@ func (s *StandardSearchService) doDashboardQuery(ctx context.Context, signedInUs
 		return rsp
 	}
 
+	if q.Stats {
+		start = time.Now()
+		rsp = doStatsQuery(ctx, s.logger, index, filter, q)
+		debug.track("stats", start)
+		return rsp
+	}
+
+	if err := validateResultFields(q.Fields); err != nil {
+		rsp.Error = err
+		return rsp
+	}
+
+	if err := validateReport(q.Report); err != nil {
+		rsp.Error = err
+		return rsp
+	}
+
+	if err := validateVariables(q.Variables); err != nil {
+		rsp.Error = err
+		return rsp
+	}
+
+	profile, err := rankProfileFor(q.Profile, s.cfg.Search.RankProfiles)
+	if err != nil {
+		rsp.Error = err
+		return rsp
+	}
+	applyRankProfile(&q, profile)
+
+	if err := validateQualityBoost(q.QualityBoost); err != nil {
+		rsp.Error = err
+		return rsp
+	}
+
+	if err := validateGovernance(q.Governance, q.GovernanceBoost); err != nil {
+		rsp.Error = err
+		return rsp
+	}
+
+	if err := validateExperiments(q.Experiments, s.cfg.Search.AllowedQueryExperiments); err != nil {
+		rsp.Error = err
+		return rsp
+	}
+	applyExperiments(&q)
+
+	start = time.Now()
+	q.scoring, err = s.scoring.resolveScoringScript(ctx, orgID, q)
+	debug.track("scoring", start)
+	if errors.Is(err, ErrInvalidScoringScript) {
+		rsp.Error = err
+		return rsp
+	}
+	if err != nil {
+		// the query is ranked without the scoring script of the organization
+		s.logger.Warn("error getting scoring script", "orgId", orgID, "err", err)
+	}
+
+	// explicit sorts are kept as asked, personalization only changes the ranking by score
+	if q.Sort == "" && signedInUser.UserID > 0 {
+		q.personalization = s.personalization.boosts(personalizationKey{orgID: orgID, userID: signedInUser.UserID})
+	}
+
+	if q.DatasourceAccess != "" {
+		if err := validateDatasourceAccess(q.DatasourceAccess); err != nil {
+			rsp.Error = err
+			return rsp
+		}
+		start = time.Now()
+		q.deniedDatasources, err = s.getDeniedDatasources(ctx, signedInUser, orgID, index)
+		debug.track("datasource_permissions", start)
+		if err != nil {
+			dashboardSearchFailureRequestsCounter.With(prometheus.Labels{
+				"reason": "get_datasource_permissions_error",
+			}).Inc()
+			rsp.Error = err
+			return rsp
+		}
+	}
+
+	if q.WithCapabilities {
+		start = time.Now()
+		q.capabilities, err = s.getCapabilityChecker(signedInUser)
+		debug.track("capabilities", start)
+		if err != nil {
+			dashboardSearchFailureRequestsCounter.With(prometheus.Labels{
+				"reason": "get_capabilities_error",
+			}).Inc()
+			rsp.Error = err
+			return rsp
+		}
+	}
+
+	if s.dashboardIndex.semanticSearchEnabled() && q.Query != "" && q.Query != "*" {
+		start = time.Now()
+		q.semanticBoosts = s.dashboardIndex.semanticBoosts(ctx, index, q.Query)
+		debug.track("semantic", start)
+	}
+
+	start = time.Now()
+	promotedUIDs, err := s.promoted.dashboardsFor(ctx, orgID, q.Query)
+	debug.track("promoted", start)
+	if err != nil {
+		// the query is answered without its promoted results
+		s.logger.Warn("error getting promoted results", "orgId", orgID, "err", err)
+	}
+	pq, hasPromoted := promotedQuery(q, promotedUIDs)
+	if hasPromoted {
+		q.excludedUIDs = promotedUIDs
+	}
+
+	start = time.Now()
 	response := doSearchQuery(ctx, s.logger, index, filter, q, s.extender.GetQueryExtender(q), s.cfg.AppSubURL)
+	if hasPromoted {
+		promoted := doSearchQuery(ctx, s.logger, index, filter, pq, s.extender.GetQueryExtender(pq), s.cfg.AppSubURL)
+		limit := q.Limit
+		if limit <= 0 {
+			limit = defaultQueryLimit
+		}
+		response = mergePromotedResults(response, promoted, limit)
+	}
+	debug.track("search", start)
 
 	if q.WithAllowedActions {
+		start = time.Now()
 		if err := s.addAllowedActionsField(ctx, orgID, signedInUser, response); err != nil {
 			s.logger.Error("error when adding the allowedActions field", "err", err)
 		}
+		debug.track("allowed_actions", start)
+	}
+
+	if debug != nil && len(response.Frames) > 0 {
+		debug.Indexes = append(debug.Indexes, string(indexTypeDashboard))
+		debug.Filters = describeSearchQuery(q)
+		debug.track("total", queryStart)
+		if meta, ok := response.Frames[0].Meta.Custom.(*customMeta); ok {
+			meta.Debug = debug
+		}
 	}
 
 	if response.Error != nil {
 		dashboardSearchFailureRequestsCounter.With(prometheus.Labels{
 			"reason": "search_query_error",
 		}).Inc()
+		// queries fail on corrupted indexes, which are rebuilt once detected
+		s.dashboardIndex.checkOrgIndex(orgID, index, corruptionSourceQuery)
 	}
 
 	return response
@@ -0,0 +1,51 @@
+package searchV2
+
+import (
+	"github.com/blugelabs/bluge"
+)
+
+// indexMode tells which fields of dashboards an organization index holds.
+type indexMode string
+
+const (
+	indexModeFull indexMode = "full"
+	// indexModeSparse indexes only the title, tags, folder and UID of dashboards, without panels,
+	// for deployments which can't afford the memory of full indexes.
+	indexModeSparse indexMode = "sparse"
+)
+
+// indexModeFor returns the mode of the index of an organization with the given number of dashboards.
+func (i *searchIndex) indexModeFor(dashboardCount int) indexMode {
+	if indexMode(i.settings.IndexMode) == indexModeSparse {
+		return indexModeSparse
+	}
+	threshold := i.settings.SparseIndexDashboardThreshold
+	if threshold > 0 && dashboardCount >= threshold {
+		return indexModeSparse
+	}
+	return indexModeFull
+}
+
+func getDashboardDoc(dash dashboard, location string, mode indexMode) *bluge.Document {
+	if mode == indexModeSparse {
+		return getSparseDashboardDoc(dash, location)
+	}
+	return getNonFolderDashboardDoc(dash, location)
+}
+
+// getSparseDashboardDoc returns a dashboard document with the fields listing and searching
+// dashboards by title, tag and folder rely on only.
+func getSparseDashboardDoc(dash dashboard, location string) *bluge.Document {
+	doc := newSearchDocument(dash.uid, dash.info.Title, "", dashboardURL(dash)).
+		AddField(bluge.NewKeywordField(documentFieldKind, string(entityKindDashboard)).Aggregatable().StoreValue()).
+		AddField(bluge.NewKeywordField(documentFieldAuthzUID, dash.uid)).
+		AddField(bluge.NewKeywordField(documentFieldLocation, location).Aggregatable().StoreValue())
+
+	for _, tag := range dash.info.Tags {
+		doc.AddField(bluge.NewKeywordField(documentFieldTag, tag).
+			StoreValue().
+			Aggregatable().
+			SearchTermPositions())
+	}
+	return doc
+}
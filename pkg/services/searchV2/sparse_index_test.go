@@ -0,0 +1,66 @@
+package searchV2
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/searchV2/extract"
+	"github.com/grafana/grafana/pkg/services/store"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestSparseIndex(t *testing.T) {
+	dashboards := testPermissionDashboards(10)
+	dashboards[2].info.Tags = []string{"prod"}
+
+	sparseIndex := func(t *testing.T, dashboards []dashboard) (*searchIndex, *orgIndex) {
+		t.Helper()
+		loader := &testDashboardLoader{dashboards: dashboards}
+		index := newSearchIndex(loader, &store.MockEntityEventsService{}, &NoopDocumentExtender{}, nil, tracing.InitializeTracerForTest(), featuremgmt.WithFeatures(), setting.SearchSettings{IndexMode: "sparse"}, nil)
+		_, err := index.buildOrgIndex(context.Background(), testOrgID)
+		require.NoError(t, err)
+		orgIdx, _ := index.getOrgIndex(testOrgID)
+		return index, orgIdx
+	}
+
+	t.Run("indexes titles, tags and folders but no panels", func(t *testing.T) {
+		index, orgIdx := sparseIndex(t, dashboards)
+		require.Equal(t, "sparse", index.getOrgStatus(testOrgID).Mode)
+
+		require.Equal(t, []string{"dash-7"}, searchUIDs(t, orgIdx, testAllowAllFilter, DashboardQuery{Query: "dashboard 7"}))
+		require.Equal(t, []string{"dash-0"}, searchUIDs(t, orgIdx, testAllowAllFilter, DashboardQuery{Tags: []string{"prod"}}))
+		require.Len(t, searchUIDs(t, orgIdx, testAllowAllFilter, DashboardQuery{Location: "folder-a", Limit: 100}), 5)
+		require.Empty(t, searchUIDs(t, orgIdx, testAllowAllFilter, DashboardQuery{Kind: []string{string(entityKindPanel)}}))
+	})
+
+	t.Run("updates keep the index sparse", func(t *testing.T) {
+		index, orgIdx := sparseIndex(t, dashboards)
+
+		dash := dashboards[3]
+		dash.folderUID = "folder-b"
+		dash.info = &extract.DashboardInfo{
+			Title:  "Renamed",
+			Panels: []extract.PanelInfo{{ID: 1, Title: "Panel", Type: "timeseries"}},
+		}
+		require.NoError(t, index.updateDashboard(context.Background(), testOrgID, orgIdx, dash))
+
+		require.Equal(t, []string{"dash-1"}, searchUIDs(t, orgIdx, testAllowAllFilter, DashboardQuery{Query: "renamed"}))
+		require.Empty(t, searchUIDs(t, orgIdx, testAllowAllFilter, DashboardQuery{Kind: []string{string(entityKindPanel)}}))
+	})
+}
+
+func TestIndexModeFor(t *testing.T) {
+	index := &searchIndex{settings: setting.SearchSettings{IndexMode: "full", SparseIndexDashboardThreshold: 100}}
+	require.Equal(t, indexModeFull, index.indexModeFor(99))
+	require.Equal(t, indexModeSparse, index.indexModeFor(100))
+
+	index.settings.SparseIndexDashboardThreshold = 0
+	require.Equal(t, indexModeFull, index.indexModeFor(100000))
+
+	index.settings.IndexMode = "sparse"
+	require.Equal(t, indexModeSparse, index.indexModeFor(1))
+}
@@ -0,0 +1,94 @@
+package searchV2
+
+import (
+	"context"
+
+	"github.com/blugelabs/bluge"
+	"github.com/blugelabs/bluge/search"
+	"github.com/blugelabs/bluge/search/aggregations"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+)
+
+// doStatsQuery counts the dashboards and folders the user can read, and tells whether the
+// organization has any, so that clients can tell an empty organization from one whose dashboards
+// the user can't see. Only the counts are read from the index, no document is loaded. The other
+// filters of the query are ignored.
+func doStatsQuery(ctx context.Context, logger log.Logger, index *orgIndex, filter ResourceFilter, q DashboardQuery) *backend.DataResponse {
+	response := &backend.DataResponse{}
+	header := &customMeta{IndexGeneration: index.generation, Degraded: index.isCorrupted(), StaleReason: index.staleReason()}
+	header.Stale = header.StaleReason != ""
+	if updated := index.lastUpdated(); !updated.IsZero() {
+		header.IndexUpdated = &updated
+	}
+
+	reader, cancel, err := index.readerForIndex(indexTypeDashboard)
+	if err != nil {
+		logger.Error("error getting reader for dashboard index: %v", err)
+		response.Error = err
+		return response
+	}
+	defer cancel()
+
+	var access bluge.Query
+	if q.readableUIDs != nil {
+		access = newReadableUIDsFilter(q.readableUIDs)
+	} else {
+		access = newPermissionFilter(filter, logger)
+	}
+	visible, err := countKinds(ctx, reader, access)
+	if err != nil {
+		response.Error = err
+		return response
+	}
+	orgHasDashboards := visible[entityKindDashboard]+visible[entityKindFolder] > 0
+	if !orgHasDashboards {
+		all, err := countKinds(ctx, reader, bluge.NewMatchAllQuery())
+		if err != nil {
+			response.Error = err
+			return response
+		}
+		orgHasDashboards = all[entityKindDashboard]+all[entityKindFolder] > 0
+	}
+	header.Count = visible[entityKindDashboard] + visible[entityKindFolder]
+
+	fDashboards := data.NewField("dashboard_count", nil, []uint64{visible[entityKindDashboard]})
+	fFolders := data.NewField("folder_count", nil, []uint64{visible[entityKindFolder]})
+	fOrgHasDashboards := data.NewField("org_has_dashboards", nil, []bool{orgHasDashboards})
+	frame := data.NewFrame("Stats", fDashboards, fFolders, fOrgHasDashboards)
+	frame.SetMeta(&data.FrameMeta{
+		Type:   "search-stats",
+		Custom: header,
+	})
+	response.Frames = append(response.Frames, frame)
+	return response
+}
+
+// countKinds counts the dashboards and folders matching the query.
+func countKinds(ctx context.Context, reader *bluge.Reader, query bluge.Query) (map[entityKind]uint64, error) {
+	kinds := bluge.NewBooleanQuery().
+		AddShould(bluge.NewTermQuery(string(entityKindDashboard)).SetField(documentFieldKind)).
+		AddShould(bluge.NewTermQuery(string(entityKindFolder)).SetField(documentFieldKind))
+	req := bluge.NewTopNSearch(1, bluge.NewBooleanQuery().AddMust(query).AddMust(kinds))
+	req.AddAggregation(documentFieldKind, aggregations.NewTermsAggregation(search.Field(documentFieldKind), 2))
+
+	iterator, err := reader.Search(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	match, err := iterator.Next()
+	for err == nil && match != nil {
+		match, err = iterator.Next()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[entityKind]uint64, 2)
+	for _, bucket := range iterator.Aggregations().Buckets(documentFieldKind) {
+		counts[entityKind(bucket.Name())] = bucket.Count()
+	}
+	return counts, nil
+}
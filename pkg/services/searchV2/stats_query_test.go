@@ -0,0 +1,47 @@
+package searchV2
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+)
+
+func TestStatsQuery(t *testing.T) {
+	stats := func(t *testing.T, index *orgIndex, filter ResourceFilter, q DashboardQuery) (uint64, uint64, bool) {
+		t.Helper()
+		q.Stats = true
+		rsp := doStatsQuery(context.Background(), log.New("test"), index, filter, q)
+		require.NoError(t, rsp.Error)
+		require.Len(t, rsp.Frames, 1)
+		frame := rsp.Frames[0]
+		return frame.Fields[0].At(0).(uint64), frame.Fields[1].At(0).(uint64), frame.Fields[2].At(0).(bool)
+	}
+
+	index := initTestOrgIndexFromDashes(t, testPermissionDashboards(20))
+
+	t.Run("counts the dashboards and folders the user can see", func(t *testing.T) {
+		dashboards, folders, exist := stats(t, index, testAllowAllFilter, DashboardQuery{Query: "ignored", Kind: []string{string(entityKindPanel)}})
+		assert.Equal(t, uint64(20), dashboards)
+		assert.Equal(t, uint64(2), folders)
+		assert.True(t, exist)
+
+		dashboards, folders, exist = stats(t, index, testAllowAllFilter, DashboardQuery{readableUIDs: testReadableUIDs(20)})
+		assert.Equal(t, uint64(2), dashboards)
+		assert.Equal(t, uint64(1), folders)
+		assert.True(t, exist)
+	})
+
+	t.Run("tells whether the organization has dashboards the user can't see", func(t *testing.T) {
+		dashboards, folders, exist := stats(t, index, testDisallowAllFilter, DashboardQuery{})
+		assert.Zero(t, dashboards)
+		assert.Zero(t, folders)
+		assert.True(t, exist)
+
+		_, _, exist = stats(t, initTestOrgIndexFromDashes(t, nil), testAllowAllFilter, DashboardQuery{})
+		assert.False(t, exist)
+	})
+}
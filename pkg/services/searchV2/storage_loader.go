@@ -0,0 +1,156 @@
+package searchV2
+
+import (
+	"context"
+	"path"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/grafana/grafana/pkg/infra/filestorage"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/searchV2/dslookup"
+	"github.com/grafana/grafana/pkg/services/searchV2/extract"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/store"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// storageDashboardLoader loads dashboards saved as JSON files under the content root of
+// the storage service instead of the dashboard table. Dashboards and folders are identified
+// by their storage path, which is also what entity events of the storage service refer to.
+type storageDashboardLoader struct {
+	storage  dashboardStorage
+	sql      *sqlstore.SQLStore
+	logger   log.Logger
+	tracer   tracing.Tracer
+	settings setting.SearchSettings
+}
+
+// dashboardStorage is the part of store.StorageService used to load dashboards.
+type dashboardStorage interface {
+	List(ctx context.Context, user *user.SignedInUser, path string) (*store.StorageListFrame, error)
+	Read(ctx context.Context, user *user.SignedInUser, path string) (*filestorage.File, error)
+}
+
+func newStorageDashboardLoader(storage dashboardStorage, sql *sqlstore.SQLStore, tracer tracing.Tracer, settings setting.SearchSettings) *storageDashboardLoader {
+	return &storageDashboardLoader{storage: storage, sql: sql, logger: log.New("storageDashboardLoader"), tracer: tracer, settings: settings}
+}
+
+// storageIndexerUser is the identity used to read the content root of an organization.
+func storageIndexerUser(orgID int64) *user.SignedInUser {
+	return &user.SignedInUser{OrgID: orgID, OrgRole: org.RoleViewer, Login: "search-indexer"}
+}
+
+func (l *storageDashboardLoader) LoadDashboards(ctx context.Context, orgID int64, dashboardUID string) ([]dashboard, error) {
+	ctx, span := l.tracer.Start(ctx, "storageDashboardLoader LoadDashboards")
+	span.SetAttributes("orgID", orgID, attribute.Key("orgID").Int64(orgID))
+	defer span.End()
+
+	lookup, err := dslookup.LoadDatasourceLookup(ctx, orgID, l.sql)
+	if err != nil {
+		return nil, err
+	}
+
+	signedInUser := storageIndexerUser(orgID)
+	if dashboardUID != "" {
+		dash, ok, err := l.loadDashboard(ctx, signedInUser, dashboardUID, path.Dir(dashboardUID), lookup)
+		if err != nil || !ok {
+			return nil, err
+		}
+		return []dashboard{dash}, nil
+	}
+
+	// Add the root folder, dashboards directly under the content root belong to it.
+	dashboards := []dashboard{{
+		uid:      "",
+		isFolder: true,
+		created:  time.Now(),
+		updated:  time.Now(),
+		info:     &extract.DashboardInfo{Title: "General"},
+	}}
+	err = l.loadFolder(ctx, signedInUser, store.RootContent, lookup, &dashboards)
+	return dashboards, err
+}
+
+func (l *storageDashboardLoader) loadFolder(ctx context.Context, signedInUser *user.SignedInUser, folderPath string, lookup dslookup.DatasourceLookup, dashboards *[]dashboard) error {
+	frame, err := l.storage.List(ctx, signedInUser, folderPath)
+	if err != nil || frame == nil {
+		return err
+	}
+
+	names, _ := frame.FieldByName("name")
+	mediaTypes, _ := frame.FieldByName("mediaType")
+	if names == nil || mediaTypes == nil {
+		return nil
+	}
+
+	for i := 0; i < names.Len(); i++ {
+		name, _ := names.At(i).(string)
+		mediaType, _ := mediaTypes.At(i).(string)
+		childPath := folderPath + "/" + name
+
+		if mediaType == filestorage.DirectoryMimeType {
+			*dashboards = append(*dashboards, dashboard{
+				uid:      childPath,
+				isFolder: true,
+				slug:     name,
+				url:      "/admin/storage/" + childPath,
+				created:  time.Now(),
+				updated:  time.Now(),
+				info:     &extract.DashboardInfo{Title: name},
+			})
+			if err := l.loadFolder(ctx, signedInUser, childPath, lookup, dashboards); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		dash, ok, err := l.loadDashboard(ctx, signedInUser, childPath, folderPath, lookup)
+		if err != nil {
+			return err
+		}
+		if ok {
+			*dashboards = append(*dashboards, dash)
+		}
+	}
+	return nil
+}
+
+func (l *storageDashboardLoader) loadDashboard(ctx context.Context, signedInUser *user.SignedInUser, dashboardPath string, folderPath string, lookup dslookup.DatasourceLookup) (dashboard, bool, error) {
+	file, err := l.storage.Read(ctx, signedInUser, dashboardPath)
+	if err != nil || file == nil || file.IsFolder() {
+		return dashboard{}, false, err
+	}
+
+	info, indexErr := readDashboardInfo(file.Contents, strings.TrimSuffix(path.Base(dashboardPath), ".json"), lookup, l.settings.MaxDashboardSize)
+	if indexErr != nil {
+		l.logger.Warn("Error indexing dashboard data", "error", indexErr.message, "errorType", indexErr.kind, "path", dashboardPath)
+	}
+	return dashboard{
+		uid:       dashboardPath,
+		folderUID: folderUIDForPath(folderPath),
+		slug:      strings.TrimSuffix(path.Base(dashboardPath), ".json"),
+		url:       "/g/" + dashboardPath,
+		created:   file.Created,
+		updated:   file.Modified,
+		info:      info,
+		indexErr:  indexErr,
+	}, true, nil
+}
+
+// folderUIDForPath returns the UID of the folder indexed for a storage folder path.
+// The content root itself is the general folder.
+func folderUIDForPath(folderPath string) string {
+	if folderPath == store.RootContent || folderPath == "." {
+		return "general"
+	}
+	return folderPath
+}
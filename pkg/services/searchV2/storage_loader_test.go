@@ -0,0 +1,133 @@
+package searchV2
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/filestorage"
+	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/store"
+	"github.com/grafana/grafana/pkg/services/user"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// fakeDashboardStorage holds files by their full storage path.
+type fakeDashboardStorage struct {
+	files map[string]string
+}
+
+func (f *fakeDashboardStorage) List(_ context.Context, _ *user.SignedInUser, folderPath string) (*store.StorageListFrame, error) {
+	entries := map[string]string{}
+	for filePath := range f.files {
+		if !strings.HasPrefix(filePath, folderPath+"/") {
+			continue
+		}
+		rest := strings.TrimPrefix(filePath, folderPath+"/")
+		if idx := strings.Index(rest, "/"); idx > 0 {
+			entries[rest[:idx]] = filestorage.DirectoryMimeType
+		} else {
+			entries[rest] = "application/json"
+		}
+	}
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	mediaTypes := make([]string, 0, len(names))
+	for _, name := range names {
+		mediaTypes = append(mediaTypes, entries[name])
+	}
+	return &store.StorageListFrame{Frame: data.NewFrame("", data.NewField("name", nil, names), data.NewField("mediaType", nil, mediaTypes))}, nil
+}
+
+func (f *fakeDashboardStorage) Read(_ context.Context, _ *user.SignedInUser, filePath string) (*filestorage.File, error) {
+	contents, ok := f.files[filePath]
+	if !ok {
+		return nil, nil
+	}
+	return &filestorage.File{
+		Contents:     []byte(contents),
+		FileMetadata: filestorage.FileMetadata{Name: path.Base(filePath), FullPath: filePath, MimeType: "application/json"},
+	}, nil
+}
+
+func TestIntegrationStorageDashboardLoader(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	storage := &fakeDashboardStorage{files: map[string]string{
+		"content/top.json":         `{"title": "Top"}`,
+		"content/team/nested.json": `{"title": "Nested", "tags": ["team"]}`,
+		"content/team/notes.txt":   `not a dashboard`,
+	}}
+	loader := newStorageDashboardLoader(storage, sqlstore.InitTestDB(t), tracing.InitializeTracerForTest(), setting.SearchSettings{})
+
+	t.Run("loads folders and dashboards by path", func(t *testing.T) {
+		dashboards, err := loader.LoadDashboards(context.Background(), testOrgID, "")
+		require.NoError(t, err)
+		require.Len(t, dashboards, 4)
+
+		byUID := map[string]dashboard{}
+		for _, dash := range dashboards {
+			byUID[dash.uid] = dash
+		}
+		require.True(t, byUID[""].isFolder)
+		require.True(t, byUID["content/team"].isFolder)
+		require.Equal(t, "general", byUID["content/top.json"].folderUID)
+		require.Equal(t, "content/team", byUID["content/team/nested.json"].folderUID)
+		require.Equal(t, "Nested", byUID["content/team/nested.json"].info.Title)
+		require.Equal(t, "/g/content/team/nested.json", byUID["content/team/nested.json"].url)
+	})
+
+	t.Run("loads a single dashboard", func(t *testing.T) {
+		dashboards, err := loader.LoadDashboards(context.Background(), testOrgID, "content/team/nested.json")
+		require.NoError(t, err)
+		require.Len(t, dashboards, 1)
+		require.Equal(t, "content/team", dashboards[0].folderUID)
+
+		dashboards, err = loader.LoadDashboards(context.Background(), testOrgID, "content/missing.json")
+		require.NoError(t, err)
+		require.Empty(t, dashboards)
+	})
+
+	t.Run("applies storage events", func(t *testing.T) {
+		index := newSearchIndex(loader, &store.MockEntityEventsService{}, &NoopDocumentExtender{}, newFolderIDLookup(nil), tracing.InitializeTracerForTest(), featuremgmt.WithFeatures(), setting.SearchSettings{}, nil)
+		_, err := index.buildOrgIndex(context.Background(), testOrgID)
+		require.NoError(t, err)
+
+		search := func() []string {
+			resp := doSearchQuery(context.Background(), testLogger, index.perOrgIndex[testOrgID], testAllowAllFilter, DashboardQuery{Location: "content/team"}, &NoopQueryExtender{}, "")
+			require.NoError(t, resp.Error)
+			uidField, _ := resp.Frames[0].FieldByName("uid")
+			uids := []string{}
+			for i := 0; i < uidField.Len(); i++ {
+				uids = append(uids, uidField.At(i).(string))
+			}
+			return uids
+		}
+		require.Equal(t, []string{"content/team/nested.json"}, search())
+
+		storage.files["content/team/other.json"] = `{"title": "Other"}`
+		require.NoError(t, index.applyEventOnIndex(context.Background(), &store.EntityEvent{
+			EventType: store.EntityEventTypeUpdate,
+			EntityId:  store.CreateStorageEntityId(testOrgID, "content/team/other.json"),
+		}))
+		require.ElementsMatch(t, []string{"content/team/nested.json", "content/team/other.json"}, search())
+
+		delete(storage.files, "content/team/nested.json")
+		require.NoError(t, index.applyEventOnIndex(context.Background(), &store.EntityEvent{
+			EventType: store.EntityEventTypeDelete,
+			EntityId:  store.CreateStorageEntityId(testOrgID, "content/team/nested.json"),
+		}))
+		require.Equal(t, []string{"content/team/other.json"}, search())
+	})
+}
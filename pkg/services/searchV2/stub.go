@@ -2,6 +2,7 @@ package searchV2
 
 import (
 	"context"
+	"errors"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/data"
@@ -19,6 +20,18 @@ func (s *stubSearchService) IsReady(ctx context.Context, orgId int64) IsSearchRe
 	return IsSearchReadyResponse{}
 }
 
+func (s *stubSearchService) WaitUntilReady(ctx context.Context, orgId int64) IsSearchReadyResponse {
+	return IsSearchReadyResponse{}
+}
+
+func (s *stubSearchService) GetIndexStatus(_ context.Context, orgId int64) IndexStatus {
+	return IndexStatus{OrgID: orgId}
+}
+
+func (s *stubSearchService) EstimateIndex(_ context.Context, orgID int64) (*IndexEstimate, error) {
+	return &IndexEstimate{OrgID: orgID}, nil
+}
+
 func (s *stubSearchService) IsDisabled() bool {
 	return true
 }
@@ -27,6 +40,74 @@ func (s *stubSearchService) TriggerReIndex() {
 	// noop.
 }
 
+func (s *stubSearchService) ListPromotedResults(ctx context.Context, orgID int64) ([]*PromotedResult, error) {
+	return []*PromotedResult{}, nil
+}
+
+func (s *stubSearchService) AddPromotedResult(ctx context.Context, cmd *AddPromotedResultCommand) (*PromotedResult, error) {
+	return nil, errors.New("search is disabled")
+}
+
+func (s *stubSearchService) DeletePromotedResult(ctx context.Context, orgID int64, id int64) error {
+	return ErrPromotedResultNotFound
+}
+
+func (s *stubSearchService) ListWarmQueries(ctx context.Context, orgID int64) ([]*WarmQuery, error) {
+	return []*WarmQuery{}, nil
+}
+
+func (s *stubSearchService) AddWarmQuery(ctx context.Context, cmd *AddWarmQueryCommand) (*WarmQuery, error) {
+	return nil, errors.New("search is disabled")
+}
+
+func (s *stubSearchService) DeleteWarmQuery(ctx context.Context, orgID int64, id int64) error {
+	return ErrWarmQueryNotFound
+}
+
+func (s *stubSearchService) RunWarmQueries(ctx context.Context, orgID int64) ([]*WarmQueryRun, error) {
+	return []*WarmQueryRun{}, nil
+}
+
+func (s *stubSearchService) DiffQuery(ctx context.Context, cmd *DiffQueryCommand) (*QueryDiff, error) {
+	return nil, errors.New("search is disabled")
+}
+
+func (s *stubSearchService) ListGovernanceLabels(ctx context.Context, orgID int64) ([]*GovernanceLabel, error) {
+	return []*GovernanceLabel{}, nil
+}
+
+func (s *stubSearchService) SetGovernanceLabel(ctx context.Context, cmd *SetGovernanceLabelCommand) (*GovernanceLabel, error) {
+	return nil, errors.New("search is disabled")
+}
+
+func (s *stubSearchService) DeleteGovernanceLabel(ctx context.Context, orgID int64, dashboardUID string) error {
+	return ErrGovernanceLabelNotFound
+}
+
+func (s *stubSearchService) GetScoringScript(ctx context.Context, orgID int64) (*ScoringScript, error) {
+	return nil, ErrScoringScriptNotFound
+}
+
+func (s *stubSearchService) SetScoringScript(ctx context.Context, cmd *SetScoringScriptCommand) (*ScoringScript, error) {
+	return nil, errors.New("search is disabled")
+}
+
+func (s *stubSearchService) DeleteScoringScript(ctx context.Context, orgID int64) error {
+	return ErrScoringScriptNotFound
+}
+
+func (s *stubSearchService) RecordSearchClick(ctx context.Context, cmd *RecordSearchClickCommand) error {
+	return ErrPersonalizationDisabled
+}
+
+func (s *stubSearchService) GetPersonalization(ctx context.Context, orgID int64, userID int64) (*PersonalizationSignals, error) {
+	return &PersonalizationSignals{RecentClicks: []PersonalizationClick{}, FrequentFolders: []PersonalizationFolder{}}, nil
+}
+
+func (s *stubSearchService) ClearPersonalization(ctx context.Context, orgID int64, userID int64) error {
+	return nil
+}
+
 func NewStubSearchService() SearchService {
 	return &stubSearchService{}
 }
@@ -50,6 +131,14 @@ func (s *stubSearchService) RegisterDashboardIndexExtender(ext DashboardIndexExt
 	// noop
 }
 
+func (s *stubSearchService) RegisterDocumentEnricher(name string, enricher DocumentEnricher) {
+	// noop
+}
+
+func (s *stubSearchService) RegisterEmbeddingProvider(provider EmbeddingProvider) {
+	// noop
+}
+
 func (s *stubSearchService) Run(_ context.Context) error {
 	return nil
 }
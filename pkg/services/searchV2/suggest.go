@@ -0,0 +1,277 @@
+package searchV2
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/blugelabs/bluge"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+const (
+	// suggestBelowCount is the number of results below which spelling suggestions are computed
+	suggestBelowCount = 3
+	// maxSuggestions is the maximum number of suggestions returned for a query
+	maxSuggestions = 5
+	// minSuggestWordLength is the length below which words aren't corrected, as too many terms are close to them
+	minSuggestWordLength = 3
+	// suggestionsFrameName is the name of the frame of the suggestions
+	suggestionsFrameName = "Suggestions"
+)
+
+// querySuggestion is a correction of the misspelled words of a query.
+type querySuggestion struct {
+	query string
+	// count is the number of dashboards, folders and panels readable by the user matching all its words
+	count uint64
+}
+
+type suggestionCandidate struct {
+	term     string
+	distance int
+	count    uint64
+}
+
+// suggestQuery returns corrections of the query built from the terms of the names indexed in the
+// reader, the closest and most frequent first. Words of the query which are indexed or prefixes are kept,
+// the others are replaced by indexed terms a few edits away. Only suggestions matching documents
+// readable through access are returned, so that the names of other dashboards don't leak.
+func suggestQuery(ctx context.Context, reader *bluge.Reader, access bluge.Query, query string) ([]querySuggestion, error) {
+	words := strings.Fields(strings.ToLower(query))
+	if len(words) == 0 {
+		return nil, nil
+	}
+
+	candidates, err := suggestionCandidates(reader, words)
+	if err != nil || len(candidates) == 0 {
+		return nil, err
+	}
+
+	// rank the candidates of each word by the documents the user can read
+	for i, wordCandidates := range candidates {
+		readable := wordCandidates[:0]
+		for _, c := range wordCandidates {
+			if c.count, err = countReadable(ctx, reader, access, []string{c.term}); err != nil {
+				return nil, err
+			}
+			if c.count > 0 {
+				readable = append(readable, c)
+			}
+		}
+		sortCandidates(readable)
+		if len(readable) == 0 {
+			delete(candidates, i)
+			continue
+		}
+		candidates[i] = readable
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	best := make([]string, len(words))
+	copy(best, words)
+	misspelled := make([]int, 0, len(candidates))
+	for i := range words {
+		if wordCandidates, ok := candidates[i]; ok {
+			best[i] = wordCandidates[0].term
+			misspelled = append(misspelled, i)
+		}
+	}
+
+	// the best correction, then alternatives for each misspelled word
+	variants := [][]string{best}
+	for _, i := range misspelled {
+		for _, c := range candidates[i][1:] {
+			variant := make([]string, len(best))
+			copy(variant, best)
+			variant[i] = c.term
+			variants = append(variants, variant)
+		}
+	}
+
+	suggestions := make([]querySuggestion, 0, maxSuggestions)
+	for _, variant := range variants {
+		if len(suggestions) == maxSuggestions {
+			break
+		}
+		count, err := countReadable(ctx, reader, access, variant)
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			suggestions = append(suggestions, querySuggestion{query: strings.Join(variant, " "), count: count})
+		}
+	}
+	return suggestions, nil
+}
+
+// suggestionCandidates returns the indexed name terms close to each word which isn't indexed nor
+// the prefix of an indexed term, by position of the word.
+func suggestionCandidates(reader *bluge.Reader, words []string) (map[int][]suggestionCandidate, error) {
+	it, err := reader.DictionaryIterator(documentFieldName, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = it.Close() }()
+
+	indexed := make(map[int]bool, len(words))
+	candidates := make(map[int][]suggestionCandidate, len(words))
+	entry, err := it.Next()
+	for err == nil && entry != nil {
+		term := entry.Term()
+		for i, word := range words {
+			// words being typed are prefixes of indexed terms, and matched by the ngram field
+			if strings.HasPrefix(term, word) {
+				indexed[i] = true
+				continue
+			}
+			if len([]rune(word)) < minSuggestWordLength {
+				continue
+			}
+			if distance := editDistance(word, term, maxEditsFor(word)); distance >= 0 {
+				candidates[i] = append(candidates[i], suggestionCandidate{term: term, distance: distance, count: entry.Count()})
+			}
+		}
+		entry, err = it.Next()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i, wordCandidates := range candidates {
+		if indexed[i] {
+			delete(candidates, i)
+			continue
+		}
+		// the terms are counted again against the permissions of the user, only keep the likeliest
+		sortCandidates(wordCandidates)
+		if len(wordCandidates) > maxSuggestions {
+			candidates[i] = wordCandidates[:maxSuggestions]
+		}
+	}
+	return candidates, nil
+}
+
+func sortCandidates(candidates []suggestionCandidate) {
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		if candidates[i].count != candidates[j].count {
+			return candidates[i].count > candidates[j].count
+		}
+		return candidates[i].term < candidates[j].term
+	})
+}
+
+// countReadable returns the number of documents readable through access whose name has all the terms.
+func countReadable(ctx context.Context, reader *bluge.Reader, access bluge.Query, terms []string) (uint64, error) {
+	q := bluge.NewBooleanQuery()
+	q.AddMust(access)
+	for _, term := range terms {
+		q.AddMust(bluge.NewTermQuery(term).SetField(documentFieldName))
+	}
+
+	req := bluge.NewTopNSearch(1, q)
+	req.WithStandardAggregations()
+	it, err := reader.Search(ctx, req)
+	if err != nil {
+		return 0, err
+	}
+	match, err := it.Next()
+	for err == nil && match != nil {
+		match, err = it.Next()
+	}
+	if err != nil {
+		return 0, err
+	}
+	return it.Aggregations().Count(), nil
+}
+
+// maxEditsFor returns how many typos are corrected in a word, more in longer words.
+func maxEditsFor(word string) int {
+	if len([]rune(word)) < 6 {
+		return 1
+	}
+	return 2
+}
+
+// editDistance returns the Levenshtein distance between a and b, or -1 if it is greater than max.
+func editDistance(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	if diff := len(ra) - len(rb); diff > max || -diff > max {
+		return -1
+	}
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if curr[j] < rowMin {
+				rowMin = curr[j]
+			}
+		}
+		if rowMin > max {
+			return -1
+		}
+		prev, curr = curr, prev
+	}
+	if prev[len(rb)] > max {
+		return -1
+	}
+	return prev[len(rb)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+// suggestionsFrame returns the frame of the suggestions, sent after the results and facets.
+func suggestionsFrame(suggestions []querySuggestion) *data.Frame {
+	fSuggestion := data.NewFieldFromFieldType(data.FieldTypeString, len(suggestions))
+	fSuggestion.Name = "suggestion"
+	fCount := data.NewFieldFromFieldType(data.FieldTypeUint64, len(suggestions))
+	fCount.Name = "Count"
+	for i, s := range suggestions {
+		fSuggestion.Set(i, s.query)
+		fCount.Set(i, s.count)
+	}
+	return data.NewFrame(suggestionsFrameName, fSuggestion, fCount)
+}
+
+// moveSuggestionsToMeta removes the suggestions frame from the frames of a response and lists the
+// suggestions in the meta of the results frame.
+func moveSuggestionsToMeta(frames data.Frames) data.Frames {
+	if len(frames) < 2 || frames[len(frames)-1].Name != suggestionsFrameName {
+		return frames
+	}
+	suggestions := frames[len(frames)-1]
+	frames = frames[:len(frames)-1]
+	if frames[0].Meta == nil {
+		return frames
+	}
+	if meta, ok := frames[0].Meta.Custom.(*customMeta); ok && len(suggestions.Fields) > 0 {
+		for i := 0; i < suggestions.Fields[0].Len(); i++ {
+			meta.Suggestions = append(meta.Suggestions, suggestions.Fields[0].At(i).(string))
+		}
+	}
+	return frames
+}
@@ -0,0 +1,78 @@
+package searchV2
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/searchV2/extract"
+)
+
+var suggestTestDashboards = []dashboard{
+	{id: 1, uid: "kube-cluster", info: &extract.DashboardInfo{Title: "Kubernetes cluster"}},
+	{id: 2, uid: "kube-nodes", info: &extract.DashboardInfo{Title: "Kubernetes nodes"}},
+	{id: 3, uid: "postgres", info: &extract.DashboardInfo{Title: "Postgres overview"}},
+	{id: 4, uid: "billing", info: &extract.DashboardInfo{Title: "Billing secrets"}},
+}
+
+func searchSuggestions(t *testing.T, index *orgIndex, filter ResourceFilter, query string) []string {
+	t.Helper()
+	resp := doSearchQuery(context.Background(), testLogger, index, filter, DashboardQuery{Query: query}, &NoopQueryExtender{}, "")
+	require.NoError(t, resp.Error)
+	frames := moveSuggestionsToMeta(resp.Frames)
+	require.Len(t, frames, 1)
+	return frames[0].Meta.Custom.(*customMeta).Suggestions
+}
+
+func TestSearchSuggestions(t *testing.T) {
+	index := initTestOrgIndexFromDashes(t, suggestTestDashboards)
+
+	t.Run("corrects misspelled words", func(t *testing.T) {
+		require.Equal(t, []string{"kubernetes"}, searchSuggestions(t, index, testAllowAllFilter, "kubernets"))
+		require.Equal(t, []string{"kubernetes cluster"}, searchSuggestions(t, index, testAllowAllFilter, "kubernets clustr"))
+		require.Equal(t, []string{"postgres overview"}, searchSuggestions(t, index, testAllowAllFilter, "postgres overveiw"))
+	})
+
+	t.Run("doesn't suggest for correct or short words", func(t *testing.T) {
+		require.Empty(t, searchSuggestions(t, index, testAllowAllFilter, "kubernetes"))
+		require.Empty(t, searchSuggestions(t, index, testAllowAllFilter, "xy"))
+		require.Empty(t, searchSuggestions(t, index, testAllowAllFilter, "zzzzzzzz"))
+	})
+
+	t.Run("only suggests names the user can read", func(t *testing.T) {
+		noBilling := func(uid string) bool { return uid != "billing" }
+		require.Empty(t, searchSuggestions(t, index, noBilling, "biling"))
+		require.Equal(t, []string{"billing"}, searchSuggestions(t, index, testAllowAllFilter, "biling"))
+	})
+
+	t.Run("returns the suggestions in a frame", func(t *testing.T) {
+		resp := doSearchQuery(context.Background(), testLogger, index, testAllowAllFilter, DashboardQuery{Query: "kubernets"}, &NoopQueryExtender{}, "")
+		require.NoError(t, resp.Error)
+		require.Len(t, resp.Frames, 2)
+		frame := resp.Frames[1]
+		require.Equal(t, suggestionsFrameName, frame.Name)
+		require.Equal(t, "kubernetes", frame.Fields[0].At(0))
+		require.Equal(t, uint64(2), frame.Fields[1].At(0))
+	})
+}
+
+func TestEditDistance(t *testing.T) {
+	require.Equal(t, 0, editDistance("grafana", "grafana", 2))
+	require.Equal(t, 1, editDistance("grafna", "grafana", 2))
+	require.Equal(t, 2, editDistance("grafnaa", "grafana", 2))
+	require.Equal(t, -1, editDistance("loki", "grafana", 2))
+	require.Equal(t, 1, editDistance("übersicht", "ubersicht", 1))
+}
+
+func TestMoveSuggestionsToMeta(t *testing.T) {
+	results := data.NewFrame("Query results")
+	results.SetMeta(&data.FrameMeta{Custom: &customMeta{}})
+	frames := moveSuggestionsToMeta(data.Frames{results, suggestionsFrame([]querySuggestion{{query: "kubernetes", count: 2}})})
+	require.Len(t, frames, 1)
+	require.Equal(t, []string{"kubernetes"}, frames[0].Meta.Custom.(*customMeta).Suggestions)
+
+	facet := data.NewFrame("Facet: tag")
+	require.Len(t, moveSuggestionsToMeta(data.Frames{results, facet}), 2)
+}
@@ -0,0 +1,86 @@
+package searchV2
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+// loadFolderTeams returns the IDs of the teams allowed to edit or administer each folder
+// of the organization, keyed by folder ID. Both legacy folder ACLs and managed team
+// permissions are considered, so the result does not depend on access control being enabled.
+//
+// Permission changes don't produce entity events, so team tokens in the index are only
+// refreshed when a dashboard is updated or on full re-index.
+func loadFolderTeams(ctx context.Context, sql *sqlstore.SQLStore, orgID int64) (map[int64][]int64, error) {
+	folderTeams := make(map[int64][]int64)
+	seen := make(map[[2]int64]bool)
+	add := func(folderID, teamID int64) {
+		key := [2]int64{folderID, teamID}
+		if folderID == 0 || teamID == 0 || seen[key] {
+			return
+		}
+		seen[key] = true
+		folderTeams[folderID] = append(folderTeams[folderID], teamID)
+	}
+
+	err := sql.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		var aclRows []struct {
+			DashboardID int64 `xorm:"dashboard_id"`
+			TeamID      int64 `xorm:"team_id"`
+		}
+		err := sess.SQL(`SELECT dashboard_acl.dashboard_id, dashboard_acl.team_id FROM dashboard_acl
+			INNER JOIN dashboard ON dashboard.id = dashboard_acl.dashboard_id
+			WHERE dashboard_acl.org_id = ? AND dashboard_acl.team_id > 0 AND dashboard_acl.permission >= ? AND dashboard.is_folder = ?`,
+			orgID, models.PERMISSION_EDIT, sql.Dialect.BooleanStr(true)).Find(&aclRows)
+		if err != nil {
+			return err
+		}
+		for _, row := range aclRows {
+			add(row.DashboardID, row.TeamID)
+		}
+
+		var permissionRows []struct {
+			Scope  string
+			TeamID int64 `xorm:"team_id"`
+		}
+		err = sess.SQL(`SELECT permission.scope, team_role.team_id FROM permission
+			INNER JOIN team_role ON team_role.role_id = permission.role_id
+			WHERE team_role.org_id = ? AND permission.action = ? AND permission.scope LIKE ?`,
+			orgID, dashboards.ActionFoldersWrite, dashboards.ScopeFoldersPrefix+"%").Find(&permissionRows)
+		if err != nil {
+			return err
+		}
+		if len(permissionRows) == 0 {
+			return nil
+		}
+
+		var folders []struct {
+			ID  int64  `xorm:"id"`
+			UID string `xorm:"uid"`
+		}
+		err = sess.SQL("SELECT id, uid FROM dashboard WHERE org_id = ? AND is_folder = ?",
+			orgID, sql.Dialect.BooleanStr(true)).Find(&folders)
+		if err != nil {
+			return err
+		}
+		folderIDs := make(map[string]int64, len(folders))
+		for _, f := range folders {
+			folderIDs[f.UID] = f.ID
+		}
+		for _, row := range permissionRows {
+			add(folderIDs[strings.TrimPrefix(row.Scope, dashboards.ScopeFoldersPrefix)], row.TeamID)
+		}
+		return nil
+	})
+	return folderTeams, err
+}
+
+// teamTerm is the token indexed for a team in the team field.
+func teamTerm(teamID int64) string {
+	return strconv.FormatInt(teamID, 10)
+}
@@ -0,0 +1,111 @@
+package searchV2
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/searchV2/extract"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+var testTeamDashboards = []dashboard{
+	{
+		id:       1,
+		uid:      "team-folder",
+		isFolder: true,
+		teams:    []int64{7},
+		info:     &extract.DashboardInfo{Title: "Team folder"},
+	},
+	{
+		id:       2,
+		uid:      "other-folder",
+		isFolder: true,
+		info:     &extract.DashboardInfo{Title: "Other folder"},
+	},
+	{
+		id:       3,
+		uid:      "owned",
+		folderID: 1,
+		teams:    []int64{7},
+		info:     &extract.DashboardInfo{Title: "Owned"},
+	},
+	{
+		id:       4,
+		uid:      "not-owned",
+		folderID: 2,
+		info:     &extract.DashboardInfo{Title: "Not owned"},
+	},
+}
+
+func TestTeamDashboardsQuery(t *testing.T) {
+	index := initTestOrgIndexFromDashes(t, testTeamDashboards)
+
+	search := func(t *testing.T, q DashboardQuery) []string {
+		t.Helper()
+		resp := doSearchQuery(context.Background(), testLogger, index, testAllowAllFilter, q, &NoopQueryExtender{}, "/pfix")
+		require.NoError(t, resp.Error)
+		uidField, _ := resp.Frames[0].FieldByName("uid")
+		uids := make([]string, 0, uidField.Len())
+		for i := 0; i < uidField.Len(); i++ {
+			uids = append(uids, uidField.At(i).(string))
+		}
+		return uids
+	}
+
+	require.ElementsMatch(t, []string{"team-folder", "owned"}, search(t, DashboardQuery{TeamID: 7}))
+	require.ElementsMatch(t, []string{"owned"}, search(t, DashboardQuery{TeamID: 7, Kind: []string{string(entityKindDashboard)}}))
+	require.Empty(t, search(t, DashboardQuery{TeamID: 8}))
+}
+
+func TestIntegrationLoadFolderTeams(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	sql := sqlstore.InitTestDB(t)
+	now := time.Now()
+
+	err := sql.WithDbSession(context.Background(), func(sess *sqlstore.DBSession) error {
+		for _, d := range []*models.Dashboard{
+			{Uid: "acl-folder", Title: "ACL folder", Slug: "acl-folder", OrgId: 1, IsFolder: true, Created: now, Updated: now},
+			{Uid: "rbac-folder", Title: "RBAC folder", Slug: "rbac-folder", OrgId: 1, IsFolder: true, Created: now, Updated: now},
+		} {
+			if _, err := sess.Insert(d); err != nil {
+				return err
+			}
+		}
+
+		acl := []*models.DashboardACL{
+			{OrgID: 1, DashboardID: 1, TeamID: 1, Permission: models.PERMISSION_EDIT, Created: now, Updated: now},
+			{OrgID: 1, DashboardID: 2, TeamID: 2, Permission: models.PERMISSION_VIEW, Created: now, Updated: now},
+		}
+		for _, a := range acl {
+			if _, err := sess.Insert(a); err != nil {
+				return err
+			}
+		}
+
+		role := accesscontrol.Role{OrgID: 1, UID: "team-3", Name: accesscontrol.ManagedTeamRoleName(3), Created: now, Updated: now}
+		if _, err := sess.Insert(&role); err != nil {
+			return err
+		}
+		if _, err := sess.Insert(&accesscontrol.TeamRole{OrgID: 1, RoleID: role.ID, TeamID: 3, Created: now}); err != nil {
+			return err
+		}
+		_, err := sess.Insert(
+			&accesscontrol.Permission{RoleID: role.ID, Action: dashboards.ActionFoldersWrite, Scope: dashboards.ScopeFoldersPrefix + "rbac-folder", Created: now, Updated: now},
+			&accesscontrol.Permission{RoleID: role.ID, Action: dashboards.ActionFoldersRead, Scope: dashboards.ScopeFoldersPrefix + "acl-folder", Created: now, Updated: now},
+		)
+		return err
+	})
+	require.NoError(t, err)
+
+	folderTeams, err := loadFolderTeams(context.Background(), sql, 1)
+	require.NoError(t, err)
+	require.Equal(t, map[int64][]int64{1: {1}, 2: {3}}, folderTeams)
+}
@@ -0,0 +1,82 @@
+package searchV2
+
+import (
+	"context"
+
+	"github.com/blugelabs/bluge"
+	"github.com/blugelabs/bluge/search"
+)
+
+// scoreNone disables scoring in bluge: term frequencies and norms aren't read and conjunctions of
+// filters are intersected without scoring each clause.
+const scoreNone = "none"
+
+// canTerminateEarly tells whether the top results of the query are the first documents matching
+// it, so that the search can stop once it has them instead of visiting every match. This is the
+// case of queries which only filter documents, e.g. listing a folder or a tag, which are also the
+// ones matching the most documents. Text queries, boosts, sorts and facets need every match.
+func canTerminateEarly(q DashboardQuery, isMatchAllQuery bool) bool {
+	return q.topK && isMatchAllQuery && q.Sort == "" && len(q.Facet) == 0 && !q.Explain &&
+		q.QualityBoost <= 0 && len(q.GovernanceBoost) == 0 && len(q.semanticBoosts) == 0 && q.scoring == nil &&
+		q.personalization == nil
+}
+
+// earlyTerminationSearch is a top N search which stops after the first from+N matches. Their
+// scores aren't computed, all matches rank alike and come in index order.
+type earlyTerminationSearch struct {
+	*bluge.TopNSearch
+	stopAfter int
+	collector *earlyTerminationCollector
+}
+
+// newEarlyTerminationSearch stops req after its first from+N matches.
+func newEarlyTerminationSearch(req *bluge.TopNSearch) *earlyTerminationSearch {
+	req.SetScore(scoreNone)
+	return &earlyTerminationSearch{TopNSearch: req, stopAfter: req.Size() + req.From()}
+}
+
+func (s *earlyTerminationSearch) Collector() search.Collector {
+	s.collector = &earlyTerminationCollector{Collector: s.TopNSearch.Collector(), stopAfter: s.stopAfter}
+	return s.collector
+}
+
+// terminated tells whether the search stopped before visiting all the matches, in which case
+// the count of its aggregations is a lower bound.
+func (s *earlyTerminationSearch) terminated() bool {
+	return s.collector != nil && s.collector.terminated
+}
+
+type earlyTerminationCollector struct {
+	search.Collector
+	stopAfter  int
+	terminated bool
+}
+
+func (c *earlyTerminationCollector) Collect(ctx context.Context, aggs search.Aggregations, searcher search.Collectible) (search.DocumentMatchIterator, error) {
+	return c.Collector.Collect(ctx, aggs, &cappedCollectible{Collectible: searcher, remaining: c.stopAfter, terminated: &c.terminated})
+}
+
+// cappedCollectible ends the matches of a searcher after the first ones.
+type cappedCollectible struct {
+	search.Collectible
+	remaining  int
+	terminated *bool
+}
+
+func (c *cappedCollectible) Next(ctx *search.Context) (*search.DocumentMatch, error) {
+	if c.remaining > 0 {
+		c.remaining--
+		return c.Collectible.Next(ctx)
+	}
+	if *c.terminated {
+		return nil, nil
+	}
+	// look ahead once to tell whether matches are left out
+	next, err := c.Collectible.Next(ctx)
+	if err != nil || next == nil {
+		return nil, err
+	}
+	ctx.DocumentMatchPool.Put(next)
+	*c.terminated = true
+	return nil, nil
+}
@@ -0,0 +1,81 @@
+package searchV2
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/searchV2/extract"
+)
+
+func TestTopKEarlyTermination(t *testing.T) {
+	var dashboards []dashboard
+	var uids []string
+	for i := 1; i <= 30; i++ {
+		uid := fmt.Sprintf("dash-%d", i)
+		tags := []string{"even"}
+		if i%2 == 1 {
+			tags = []string{"odd", "other"}
+		}
+		dashboards = append(dashboards, dashboard{id: int64(i), uid: uid, info: &extract.DashboardInfo{Title: "Dashboard " + uid, Tags: tags}})
+		uids = append(uids, uid)
+	}
+	index := initTestOrgIndexFromDashes(t, dashboards)
+	search := func(t *testing.T, q DashboardQuery) ([]string, *customMeta) {
+		t.Helper()
+		q.Experiments = []string{ExperimentTopK}
+		applyExperiments(&q)
+		resp := doSearchQuery(context.Background(), testLogger, index, testAllowAllFilter, q, &NoopQueryExtender{}, "")
+		require.NoError(t, resp.Error)
+		field, _ := resp.Frames[0].FieldByName("uid")
+		var result []string
+		for i := 0; i < field.Len(); i++ {
+			result = append(result, field.At(i).(string))
+		}
+		return result, resp.Frames[0].Meta.Custom.(*customMeta)
+	}
+
+	t.Run("filter queries stop once they have their results", func(t *testing.T) {
+		kind := []string{string(entityKindDashboard)}
+		var all []string
+		for from := 0; from < 30; from += 10 {
+			page, meta := search(t, DashboardQuery{Kind: kind, Limit: 10, From: from})
+			require.Len(t, page, 10)
+			require.Equal(t, uint64(from+10), meta.Count)
+			require.Equal(t, from+10 < 30, meta.CountIsLowerBound)
+			all = append(all, page...)
+		}
+		require.ElementsMatch(t, uids, all, "pages don't overlap")
+
+		page, meta := search(t, DashboardQuery{Kind: kind, Tags: []string{"odd"}, Limit: 5})
+		require.Len(t, page, 5)
+		require.True(t, meta.CountIsLowerBound)
+		page, meta = search(t, DashboardQuery{Kind: kind, Tags: []string{"odd"}, Limit: 15})
+		require.Len(t, page, 15)
+		require.False(t, meta.CountIsLowerBound, "no more documents match")
+	})
+
+	t.Run("queries needing every match aren't stopped", func(t *testing.T) {
+		for name, q := range map[string]DashboardQuery{
+			"text":    {Query: "dashboard", Limit: 5},
+			"sort":    {Kind: []string{string(entityKindDashboard)}, Sort: documentFieldName_sort, Limit: 5},
+			"facet":   {Kind: []string{string(entityKindDashboard)}, Facet: []FacetField{{Field: documentFieldTag}}, Limit: 5},
+			"boosted": {Kind: []string{string(entityKindDashboard)}, QualityBoost: 1, Limit: 5},
+		} {
+			page, meta := search(t, q)
+			require.Len(t, page, 5, name)
+			require.Equal(t, uint64(30), meta.Count, name)
+			require.False(t, meta.CountIsLowerBound, name)
+		}
+	})
+
+	t.Run("queries without the experiment aren't stopped", func(t *testing.T) {
+		resp := doSearchQuery(context.Background(), testLogger, index, testAllowAllFilter, DashboardQuery{Kind: []string{string(entityKindDashboard)}, Limit: 5}, &NoopQueryExtender{}, "")
+		require.NoError(t, resp.Error)
+		meta := resp.Frames[0].Meta.Custom.(*customMeta)
+		require.Equal(t, uint64(30), meta.Count)
+		require.False(t, meta.CountIsLowerBound)
+	})
+}
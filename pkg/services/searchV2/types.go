@@ -2,6 +2,7 @@ package searchV2
 
 import (
 	"context"
+	"time"
 
 	"github.com/grafana/grafana/pkg/registry"
 	"github.com/grafana/grafana/pkg/services/user"
@@ -17,7 +18,7 @@ type FacetField struct {
 type DashboardQuery struct {
 	Query              string       `json:"query"`
 	Location           string       `json:"location,omitempty"` // parent folder ID
-	Sort               string       `json:"sort,omitempty"`     // field ASC/DESC
+	Sort               string       `json:"sort,omitempty"`     // field, prefixed with - for descending order
 	Datasource         string       `json:"ds_uid,omitempty"`   // "datasource" collides with the JSON value at the same leel :()
 	Tags               []string     `json:"tags,omitempty"`
 	Kind               []string     `json:"kind,omitempty"`
@@ -27,9 +28,105 @@ type DashboardQuery struct {
 	WithAllowedActions bool         `json:"withAllowedActions,omitempty"` // adds allowed actions per entity
 	Facet              []FacetField `json:"facet,omitempty"`
 	SkipLocation       bool         `json:"skipLocation,omitempty"`
-	HasPreview         string       `json:"hasPreview,omitempty"` // the light|dark theme
+	HasPreview         string       `json:"hasPreview,omitempty"` // deprecated, ignored
 	Limit              int          `json:"limit,omitempty"`      // explicit page size
 	From               int          `json:"from,omitempty"`       // for paging
+
+	// filter|annotate results referencing datasources the user can't query
+	DatasourceAccess string `json:"datasourceAccess,omitempty"`
+	// include execution details of the query in the response
+	Debug bool `json:"debug,omitempty"`
+	// only dashboards and folders in folders the team can edit or administer
+	TeamID int64 `json:"teamId,omitempty"`
+	// only dashboards and panels detected to be in this language, e.g. "en" or "cjk"
+	Language string `json:"language,omitempty"`
+	// only dashboards and panels with this referential integrity problem, see the Report* constants
+	Report string `json:"report,omitempty"`
+	// only dashboards with one of the values of each field added by document enrichers
+	Custom map[string][]string `json:"custom,omitempty"`
+	// only these dashboards and their panels, e.g. the dashboards of a playlist or dashboard list panel.
+	// Unlike UIDs, results are ranked by the query and not by their position in the list
+	DashboardUIDs []string `json:"dashboardUids,omitempty"`
+	// only return these result fields, all of them when empty
+	Fields []string `json:"fields,omitempty"`
+	// adds the can_edit, can_admin and can_star fields telling which actions to offer per result
+	WithCapabilities bool `json:"withCapabilities,omitempty"`
+	// also search the federated Grafana instances of the organization, see setting.SearchSettings.FederatedInstances.
+	// Only users who can read all dashboards and folders of the organization may set it
+	Federated bool `json:"federated,omitempty"`
+	// adds up to this much to the score of dashboards, in proportion to their quality score, so that
+	// well maintained dashboards rank first
+	QualityBoost float64 `json:"qualityBoost,omitempty"`
+	// evaluate the results with the permissions of another user or team, for admins investigating
+	// why they can't find a dashboard
+	PreviewAs *PermissionsPreview `json:"previewAs,omitempty"`
+	// only dashboards with one of these governance labels, see GovernanceLabel
+	Governance []string `json:"governance,omitempty"`
+	// adds the boost of their governance label to the score of dashboards, e.g. {"certified": 2} to
+	// rank certified dashboards first
+	GovernanceBoost map[string]float64 `json:"governanceBoost,omitempty"`
+	// only dashboards which are (true) or aren't (false) managed by a provisioner, facet on
+	// "provisioning_source" to count the dashboards of each provisioner
+	Provisioned *bool `json:"provisioned,omitempty"`
+	// enables search behaviors which are not yet the default, see the Experiment* constants
+	Experiments []string `json:"experiments,omitempty"`
+	// tunes the boosts and limit for a client surface, e.g. "command-palette", see
+	// setting.SearchSettings.RankProfiles
+	Profile string `json:"profile,omitempty"`
+	// the version of the query API the client was written for, see the QueryAPIVersion* constants
+	APIVersion int `json:"apiVersion,omitempty"`
+	// ranks the results with this scoring script instead of the one of the organization, e.g. to
+	// try a script before saving it, or without one when "none". See ScoringScript
+	ScoringScript string `json:"scoringScript,omitempty"`
+	// only dashboards with all these template variables, given as a name, e.g. "cluster", or as a
+	// name and one of the static values of the variable, e.g. "cluster=prod". Facet on "variable"
+	// to count the dashboards using each variable
+	Variables []string `json:"variables,omitempty"`
+	// only count the dashboards and folders the user can see, and tell whether the organization
+	// has any, e.g. to render empty states. The other filters are ignored
+	Stats bool `json:"stats,omitempty"`
+
+	// resolved by the search service from the user's datasource permissions
+	deniedDatasources map[string]bool
+	// resolved by the search service when the auth service can list readable dashboards,
+	// replaces the resource filter
+	readableUIDs map[string]bool
+	// resolved by the search service when capabilities are requested
+	capabilities capabilityChecker
+	// resolved by the search service, dashboards promoted for the query which are merged at the top
+	// of the results
+	excludedUIDs []string
+	// resolved from the experiments, leaves out the documents with integrity problems
+	excludeIntegrityProblems bool
+	// resolved from the experiments, stops the queries which only filter documents once they have
+	// their results
+	topK bool
+	// resolved by the search service when semantic search is enabled, the score added to the
+	// dashboards similar in meaning to the query
+	semanticBoosts map[string]float64
+	// resolved by the search service from the scoring script of the query or of the organization
+	scoring *scoringScript
+	// resolved by the search service when personalization is enabled, the score added to the
+	// dashboards the user recently opened from search
+	personalization *personalizationBoosts
+}
+
+// IndexStatus describes the state and re-indexing schedule of an organization index.
+type IndexStatus struct {
+	OrgID               int64     `json:"orgId"`
+	Ready               bool      `json:"ready"`
+	Target              string    `json:"target"`              // see setting.SearchSettings.IndexTarget
+	Mode                string    `json:"mode,omitempty"`      // full or sparse, see setting.SearchSettings.IndexMode
+	Placement           string    `json:"placement,omitempty"` // memory or disk, see setting.SearchSettings.DiskIndexDashboardThreshold
+	DashboardCount      int       `json:"dashboardCount"`
+	FullReindexInterval string    `json:"fullReindexInterval,omitempty"`
+	LastFullReindex     time.Time `json:"lastFullReindex"`
+	NextFullReindex     time.Time `json:"nextFullReindex"`
+	// Generation identifies the build of the index, search results carry it in their frame metadata
+	Generation  int64     `json:"generation,omitempty"`
+	LastUpdated time.Time `json:"lastUpdated"` // last build or change of the index
+	// IndexErrors are the dashboards which couldn't be fully indexed, nil until the index is built
+	IndexErrors *IndexErrorReport `json:"indexErrors,omitempty"`
 }
 
 type IsSearchReadyResponse struct {
@@ -44,6 +141,31 @@ type SearchService interface {
 	DoDashboardQuery(ctx context.Context, user *backend.User, orgId int64, query DashboardQuery) *backend.DataResponse
 	doDashboardQuery(ctx context.Context, user *user.SignedInUser, orgId int64, query DashboardQuery) *backend.DataResponse
 	IsReady(ctx context.Context, orgId int64) IsSearchReadyResponse
+	// WaitUntilReady blocks until the index of the organization is ready or the context is done.
+	WaitUntilReady(ctx context.Context, orgId int64) IsSearchReadyResponse
+	GetIndexStatus(ctx context.Context, orgId int64) IndexStatus
+	// EstimateIndex estimates the size and build time of the index of the organization in each index
+	// mode and placement, without building it.
+	EstimateIndex(ctx context.Context, orgID int64) (*IndexEstimate, error)
 	RegisterDashboardIndexExtender(ext DashboardIndexExtender)
+	RegisterDocumentEnricher(name string, enricher DocumentEnricher)
+	RegisterEmbeddingProvider(provider EmbeddingProvider)
 	TriggerReIndex()
+	ListPromotedResults(ctx context.Context, orgID int64) ([]*PromotedResult, error)
+	AddPromotedResult(ctx context.Context, cmd *AddPromotedResultCommand) (*PromotedResult, error)
+	DeletePromotedResult(ctx context.Context, orgID int64, id int64) error
+	ListWarmQueries(ctx context.Context, orgID int64) ([]*WarmQuery, error)
+	AddWarmQuery(ctx context.Context, cmd *AddWarmQueryCommand) (*WarmQuery, error)
+	DeleteWarmQuery(ctx context.Context, orgID int64, id int64) error
+	RunWarmQueries(ctx context.Context, orgID int64) ([]*WarmQueryRun, error)
+	DiffQuery(ctx context.Context, cmd *DiffQueryCommand) (*QueryDiff, error)
+	ListGovernanceLabels(ctx context.Context, orgID int64) ([]*GovernanceLabel, error)
+	SetGovernanceLabel(ctx context.Context, cmd *SetGovernanceLabelCommand) (*GovernanceLabel, error)
+	DeleteGovernanceLabel(ctx context.Context, orgID int64, dashboardUID string) error
+	GetScoringScript(ctx context.Context, orgID int64) (*ScoringScript, error)
+	SetScoringScript(ctx context.Context, cmd *SetScoringScriptCommand) (*ScoringScript, error)
+	DeleteScoringScript(ctx context.Context, orgID int64) error
+	RecordSearchClick(ctx context.Context, cmd *RecordSearchClickCommand) error
+	GetPersonalization(ctx context.Context, orgID int64, userID int64) (*PersonalizationSignals, error)
+	ClearPersonalization(ctx context.Context, orgID int64, userID int64) error
 }
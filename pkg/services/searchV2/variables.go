@@ -0,0 +1,63 @@
+package searchV2
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blugelabs/bluge"
+)
+
+const (
+	// documentFieldVariable holds the names of the template variables of dashboards
+	documentFieldVariable = "variable"
+	// documentFieldVariableValue holds the static values of the template variables of dashboards,
+	// as name=value
+	documentFieldVariableValue = "variable_value"
+
+	// maxQueryVariables bounds the template variables a query filters on
+	maxQueryVariables = 20
+)
+
+func addVariableFields(doc *bluge.Document, names []string, values map[string][]string) {
+	for _, name := range names {
+		if name != "" {
+			doc.AddField(bluge.NewKeywordField(documentFieldVariable, name).Aggregatable().StoreValue())
+		}
+	}
+	for name, nameValues := range values {
+		for _, value := range nameValues {
+			doc.AddField(bluge.NewKeywordField(documentFieldVariableValue, variableValueTerm(name, value)).Aggregatable())
+		}
+	}
+}
+
+func variableValueTerm(name, value string) string {
+	return name + "=" + value
+}
+
+// newVariablesFilter matches the dashboards with all the template variables, given as a name or
+// as name=value for variables with that static value.
+func newVariablesFilter(variables []string) bluge.Query {
+	bq := bluge.NewBooleanQuery()
+	for _, variable := range variables {
+		if name, value, ok := strings.Cut(variable, "="); ok {
+			bq.AddMust(bluge.NewTermQuery(variableValueTerm(strings.TrimSpace(name), strings.TrimSpace(value))).SetField(documentFieldVariableValue))
+		} else {
+			bq.AddMust(bluge.NewTermQuery(strings.TrimSpace(variable)).SetField(documentFieldVariable))
+		}
+	}
+	return bq
+}
+
+func validateVariables(variables []string) error {
+	if len(variables) > maxQueryVariables {
+		return fmt.Errorf("queries filter on at most %d template variables", maxQueryVariables)
+	}
+	for _, variable := range variables {
+		name, _, _ := strings.Cut(variable, "=")
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("invalid template variable: %q", variable)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package searchV2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/searchV2/extract"
+)
+
+func TestDashboardIndex_Variables(t *testing.T) {
+	index := initTestOrgIndexFromDashes(t, []dashboard{
+		{id: 1, uid: "k8s", info: &extract.DashboardInfo{
+			Title:             "Kubernetes",
+			TemplateVars:      []string{"cluster", "namespace"},
+			TemplateVarValues: map[string][]string{"cluster": {"prod", "staging"}},
+		}},
+		{id: 2, uid: "nodes", info: &extract.DashboardInfo{
+			Title:             "Nodes",
+			TemplateVars:      []string{"cluster"},
+			TemplateVarValues: map[string][]string{"cluster": {"dev"}},
+		}},
+		{id: 3, uid: "home", info: &extract.DashboardInfo{Title: "Home"}},
+	})
+	search := func(variables ...string) []string {
+		return searchUIDs(t, index, testAllowAllFilter, DashboardQuery{Variables: variables})
+	}
+
+	t.Run("filters by variable name", func(t *testing.T) {
+		require.ElementsMatch(t, []string{"k8s", "nodes"}, search("cluster"))
+		require.Equal(t, []string{"k8s"}, search("cluster", "namespace"))
+		require.Empty(t, search("datasource"))
+	})
+
+	t.Run("filters by static variable value", func(t *testing.T) {
+		require.Equal(t, []string{"k8s"}, search("cluster=prod"))
+		require.Equal(t, []string{"nodes"}, search(" cluster = dev "))
+		require.Empty(t, search("namespace=prod"))
+	})
+}
+
+func TestValidateVariables(t *testing.T) {
+	require.NoError(t, validateVariables([]string{"cluster", "cluster=prod", "cluster="}))
+	require.Error(t, validateVariables([]string{""}))
+	require.Error(t, validateVariables([]string{"=prod"}))
+	require.Error(t, validateVariables(make([]string, maxQueryVariables+1)))
+}
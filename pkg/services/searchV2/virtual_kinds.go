@@ -0,0 +1,127 @@
+package searchV2
+
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+// Virtual kinds are not stored in the index. They are resolved per user to a
+// list of dashboard UIDs before the index is queried, so that the results go
+// through the same permission filter and use the same frame as any other search.
+const (
+	virtualKindRecent  = "recent"
+	virtualKindStarred = "starred"
+)
+
+// maxRecentDashboards limits how many recently saved dashboards are returned for the `recent` kind.
+const maxRecentDashboards = 50
+
+// virtualKindLookup returns the UIDs of the dashboards belonging to a virtual kind, most relevant first.
+type virtualKindLookup func(ctx context.Context, kind string) ([]string, error)
+
+func isVirtualKind(kind string) bool {
+	return kind == virtualKindRecent || kind == virtualKindStarred
+}
+
+// resolveVirtualKinds replaces virtual kinds in the query with an explicit list of dashboard UIDs.
+// The returned filter only allows the resolved dashboards, so a virtual kind that resolves to
+// no dashboards yields an empty result rather than matching everything.
+func resolveVirtualKinds(ctx context.Context, q DashboardQuery, filter ResourceFilter, lookup virtualKindLookup) (DashboardQuery, ResourceFilter, error) {
+	kinds := make([]string, 0, len(q.Kind))
+	var virtualKinds []string
+	for _, k := range q.Kind {
+		if isVirtualKind(k) {
+			virtualKinds = append(virtualKinds, k)
+		} else {
+			kinds = append(kinds, k)
+		}
+	}
+	if len(virtualKinds) == 0 {
+		return q, filter, nil
+	}
+
+	var requested map[string]bool
+	if len(q.UIDs) > 0 {
+		requested = make(map[string]bool, len(q.UIDs))
+		for _, uid := range q.UIDs {
+			requested[uid] = true
+		}
+	}
+
+	allowed := make(map[string]bool)
+	uids := make([]string, 0)
+	for _, k := range virtualKinds {
+		res, err := lookup(ctx, k)
+		if err != nil {
+			return q, filter, err
+		}
+		for _, uid := range res {
+			if allowed[uid] || (requested != nil && !requested[uid]) {
+				continue
+			}
+			allowed[uid] = true
+			uids = append(uids, uid)
+		}
+	}
+
+	if len(kinds) == 0 {
+		kinds = []string{string(entityKindDashboard)}
+	}
+	q.Kind = kinds
+	q.UIDs = uids
+	if q.readableUIDs != nil {
+		readable := make(map[string]bool, len(allowed))
+		for uid := range allowed {
+			if q.readableUIDs[uid] {
+				readable[uid] = true
+			}
+		}
+		q.readableUIDs = readable
+	}
+
+	return q, func(uid string) bool {
+		return allowed[uid] && filter(uid)
+	}, nil
+}
+
+type virtualKindQueryResult struct {
+	UID string `xorm:"uid"`
+}
+
+func (s *StandardSearchService) virtualKindLookup(signedInUser *user.SignedInUser, orgID int64) virtualKindLookup {
+	return func(ctx context.Context, kind string) ([]string, error) {
+		if signedInUser.IsAnonymous || signedInUser.UserID == 0 {
+			return []string{}, nil
+		}
+
+		rows := make([]*virtualKindQueryResult, 0)
+		err := s.sql.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+			switch kind {
+			case virtualKindStarred:
+				return sess.SQL(`SELECT dashboard.uid FROM star
+					INNER JOIN dashboard ON dashboard.id = star.dashboard_id
+					WHERE star.user_id = ? AND dashboard.org_id = ?
+					ORDER BY star.id DESC`, signedInUser.UserID, orgID).Find(&rows)
+			case virtualKindRecent:
+				return sess.SQL(`SELECT dashboard.uid FROM dashboard_version
+					INNER JOIN dashboard ON dashboard.id = dashboard_version.dashboard_id
+					WHERE dashboard_version.created_by = ? AND dashboard.org_id = ?
+					GROUP BY dashboard.uid
+					ORDER BY MAX(dashboard_version.created) DESC
+					LIMIT ?`, signedInUser.UserID, orgID, maxRecentDashboards).Find(&rows)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		uids := make([]string, 0, len(rows))
+		for _, row := range rows {
+			uids = append(uids, row.UID)
+		}
+		return uids, nil
+	}
+}
@@ -0,0 +1,84 @@
+package searchV2
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/searchV2/extract"
+)
+
+var testVirtualKindDashboards = []dashboard{
+	{id: 1, uid: "1", info: &extract.DashboardInfo{Title: "first"}},
+	{id: 2, uid: "2", info: &extract.DashboardInfo{Title: "second"}},
+	{id: 3, uid: "3", info: &extract.DashboardInfo{Title: "third"}},
+	{id: 4, uid: "4", isFolder: true, info: &extract.DashboardInfo{Title: "folder"}},
+}
+
+func testVirtualKindLookup(lists map[string][]string) virtualKindLookup {
+	return func(_ context.Context, kind string) ([]string, error) {
+		return lists[kind], nil
+	}
+}
+
+func searchVirtualKind(t *testing.T, index *orgIndex, filter ResourceFilter, q DashboardQuery, lookup virtualKindLookup) []string {
+	t.Helper()
+	q, filter, err := resolveVirtualKinds(context.Background(), q, filter, lookup)
+	require.NoError(t, err)
+
+	resp := doSearchQuery(context.Background(), testLogger, index, filter, q, &NoopQueryExtender{}, "/pfix")
+	require.NoError(t, resp.Error)
+	require.Len(t, resp.Frames, 1)
+
+	names := make([]string, 0)
+	field, _ := resp.Frames[0].FieldByName(documentFieldName)
+	for i := 0; i < field.Len(); i++ {
+		names = append(names, field.At(i).(string))
+	}
+	return names
+}
+
+func TestVirtualKinds(t *testing.T) {
+	index := initTestOrgIndexFromDashes(t, testVirtualKindDashboards)
+	lookup := testVirtualKindLookup(map[string][]string{
+		virtualKindStarred: {"3", "1"},
+		virtualKindRecent:  {"2", "3"},
+	})
+
+	t.Run("starred keeps the lookup order", func(t *testing.T) {
+		names := searchVirtualKind(t, index, testAllowAllFilter, DashboardQuery{Kind: []string{virtualKindStarred}}, lookup)
+		require.Equal(t, []string{"third", "first"}, names)
+	})
+
+	t.Run("recent and starred are merged without duplicates", func(t *testing.T) {
+		names := searchVirtualKind(t, index, testAllowAllFilter, DashboardQuery{Kind: []string{virtualKindRecent, virtualKindStarred}}, lookup)
+		require.Equal(t, []string{"second", "third", "first"}, names)
+	})
+
+	t.Run("permissions are applied", func(t *testing.T) {
+		filter := func(uid string) bool { return uid != "3" }
+		names := searchVirtualKind(t, index, filter, DashboardQuery{Kind: []string{virtualKindStarred}}, lookup)
+		require.Equal(t, []string{"first"}, names)
+	})
+
+	t.Run("query narrows the result", func(t *testing.T) {
+		names := searchVirtualKind(t, index, testAllowAllFilter, DashboardQuery{Query: "fir", Kind: []string{virtualKindStarred}}, lookup)
+		require.Equal(t, []string{"first"}, names)
+	})
+
+	t.Run("empty list returns no results", func(t *testing.T) {
+		names := searchVirtualKind(t, index, testAllowAllFilter, DashboardQuery{Kind: []string{virtualKindStarred}}, testVirtualKindLookup(nil))
+		require.Empty(t, names)
+	})
+
+	t.Run("queries without virtual kinds are unchanged", func(t *testing.T) {
+		q := DashboardQuery{Kind: []string{string(entityKindFolder)}}
+		resolved, _, err := resolveVirtualKinds(context.Background(), q, testAllowAllFilter, func(context.Context, string) ([]string, error) {
+			return nil, errors.New("should not be called")
+		})
+		require.NoError(t, err)
+		require.Equal(t, q, resolved)
+	})
+}
@@ -0,0 +1,273 @@
+package searchV2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/sqlstore/db"
+)
+
+const (
+	// maxWarmQueryNameLength is the length of the name column
+	maxWarmQueryNameLength = 190
+	// maxWarmQueriesPerOrg bounds the time spent warming the index of an organization
+	maxWarmQueriesPerOrg = 20
+	// warmQueryTimeout bounds each run of the warm queries of an organization
+	warmQueryTimeout = time.Minute
+
+	WarmQueryTriggerIndexBuild = "index_build"
+	WarmQueryTriggerSchedule   = "schedule"
+	WarmQueryTriggerManual     = "manual"
+)
+
+var (
+	ErrWarmQueryNotFound  = errors.New("warm query not found")
+	ErrWarmQueryExists    = errors.New("a warm query with the same name already exists")
+	ErrInvalidWarmQuery   = errors.New("invalid warm query")
+	ErrTooManyWarmQueries = errors.New("organizations can have at most 20 warm queries")
+)
+
+// WarmQuery is a search query run after each build of the index of an organization and
+// periodically, so that the first queries of users hit warm caches, e.g. the query of the
+// dashboard search page.
+type WarmQuery struct {
+	ID        int64          `json:"id" xorm:"pk autoincr 'id'"`
+	OrgID     int64          `json:"orgId" xorm:"org_id"`
+	Name      string         `json:"name" xorm:"name"`
+	Query     DashboardQuery `json:"query" xorm:"-"`
+	QueryJSON string         `json:"-" xorm:"query"`
+	Created   time.Time      `json:"created" xorm:"created"`
+	// LastRun is the report of the last run of the query on this instance
+	LastRun *WarmQueryRun `json:"lastRun,omitempty" xorm:"-"`
+}
+
+func (WarmQuery) TableName() string { return "search_warm_query" }
+
+// WarmQueryRun reports how long a warm query took to run.
+type WarmQueryRun struct {
+	WarmQueryID int64     `json:"warmQueryId"`
+	Name        string    `json:"name"`
+	Trigger     string    `json:"trigger"`
+	Started     time.Time `json:"started"`
+	DurationMs  float64   `json:"durationMs"`
+	Count       uint64    `json:"count"`
+	Error       string    `json:"error,omitempty"`
+}
+
+type AddWarmQueryCommand struct {
+	OrgID int64          `json:"-"`
+	Name  string         `json:"name"`
+	Query DashboardQuery `json:"query"`
+}
+
+// warmQueries stores the warm queries of the organizations, runs them and keeps the report of
+// their last run.
+type warmQueries struct {
+	db     db.DB
+	now    func() time.Time
+	logger log.Logger
+
+	mu      sync.Mutex
+	lastRun map[int64]map[int64]*WarmQueryRun
+}
+
+func newWarmQueries(db db.DB) *warmQueries {
+	return &warmQueries{
+		db:      db,
+		now:     time.Now,
+		logger:  log.New("searchV2.warmQueries"),
+		lastRun: map[int64]map[int64]*WarmQueryRun{},
+	}
+}
+
+func (w *warmQueries) list(ctx context.Context, orgID int64) ([]*WarmQuery, error) {
+	queries := make([]*WarmQuery, 0)
+	err := w.db.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		return sess.Where("org_id=?", orgID).Asc("name").Find(&queries)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, query := range queries {
+		if err := json.Unmarshal([]byte(query.QueryJSON), &query.Query); err != nil {
+			return nil, err
+		}
+		if run, ok := w.lastRun[orgID][query.ID]; ok {
+			copied := *run
+			query.LastRun = &copied
+		}
+	}
+	return queries, nil
+}
+
+func (w *warmQueries) add(ctx context.Context, cmd *AddWarmQueryCommand) (*WarmQuery, error) {
+	name := strings.TrimSpace(cmd.Name)
+	if name == "" || len(name) > maxWarmQueryNameLength {
+		return nil, fmt.Errorf("%w: the name must have between 1 and %d characters", ErrInvalidWarmQuery, maxWarmQueryNameLength)
+	}
+	if err := validateWarmQuery(cmd.Query); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidWarmQuery, err)
+	}
+	js, err := json.Marshal(cmd.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	query := &WarmQuery{
+		OrgID:     cmd.OrgID,
+		Name:      name,
+		Query:     cmd.Query,
+		QueryJSON: string(js),
+		Created:   w.now(),
+	}
+	err = w.db.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		count, err := sess.Where("org_id=?", query.OrgID).Count(&WarmQuery{})
+		if err != nil {
+			return err
+		}
+		if count >= maxWarmQueriesPerOrg {
+			return ErrTooManyWarmQueries
+		}
+		exists, err := sess.Where("org_id=? AND name=?", query.OrgID, query.Name).Exist(&WarmQuery{})
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrWarmQueryExists
+		}
+		if _, err := sess.Insert(query); err != nil {
+			if w.db.GetDialect().IsUniqueConstraintViolation(err) {
+				return ErrWarmQueryExists
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return query, nil
+}
+
+func (w *warmQueries) delete(ctx context.Context, orgID int64, id int64) error {
+	err := w.db.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		deleted, err := sess.Where("org_id=? AND id=?", orgID, id).Delete(&WarmQuery{})
+		if err != nil {
+			return err
+		}
+		if deleted == 0 {
+			return ErrWarmQueryNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.lastRun[orgID], id)
+	return nil
+}
+
+// validateWarmQuery checks the options of a warm query which are validated for user queries.
+func validateWarmQuery(q DashboardQuery) error {
+	if err := validateResultFields(q.Fields); err != nil {
+		return err
+	}
+	if err := validateReport(q.Report); err != nil {
+		return err
+	}
+	if err := validateVariables(q.Variables); err != nil {
+		return err
+	}
+	if err := validateQualityBoost(q.QualityBoost); err != nil {
+		return err
+	}
+	if err := validateGovernance(q.Governance, q.GovernanceBoost); err != nil {
+		return err
+	}
+	return validateScoringScript(q.ScoringScript)
+}
+
+// warmQueryFor returns the query actually run to warm the index: warm queries don't run on behalf
+// of a user, so the options depending on the permissions of the user are left out.
+func warmQueryFor(q DashboardQuery) DashboardQuery {
+	q.PreviewAs = nil
+	q.Federated = false
+	q.WithAllowedActions = false
+	q.WithCapabilities = false
+	q.DatasourceAccess = ""
+	q.Explain = false
+	q.Debug = false
+	applyExperiments(&q)
+	return q
+}
+
+// warm runs the warm queries of an organization against its index, and returns the report of
+// each run. The results are discarded, so that the queries are run without permission filter.
+func (w *warmQueries) warm(ctx context.Context, orgID int64, index *orgIndex, trigger string) ([]*WarmQueryRun, error) {
+	if w == nil {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, warmQueryTimeout)
+	defer cancel()
+
+	queries, err := w.list(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	allowAll := func(uid string) bool { return true }
+	runs := make([]*WarmQueryRun, 0, len(queries))
+	for _, query := range queries {
+		if ctx.Err() != nil {
+			break
+		}
+		run := &WarmQueryRun{WarmQueryID: query.ID, Name: query.Name, Trigger: trigger, Started: w.now()}
+		started := time.Now()
+		response := doSearchQuery(ctx, w.logger, index, allowAll, warmQueryFor(query.Query), &NoopQueryExtender{}, "")
+		run.DurationMs = float64(time.Since(started).Microseconds()) / 1000
+		if response.Error != nil {
+			run.Error = response.Error.Error()
+		} else if len(response.Frames) > 0 && response.Frames[0].Meta != nil {
+			if meta, ok := response.Frames[0].Meta.Custom.(*customMeta); ok {
+				run.Count = meta.Count
+			}
+		}
+		runs = append(runs, run)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.lastRun[orgID] == nil {
+		w.lastRun[orgID] = map[int64]*WarmQueryRun{}
+	}
+	for _, run := range runs {
+		w.lastRun[orgID][run.WarmQueryID] = run
+	}
+	return runs, nil
+}
+
+// warmInBackground runs the warm queries of an organization without blocking the caller, failures
+// are only logged.
+func (w *warmQueries) warmInBackground(orgID int64, index *orgIndex, trigger string) {
+	if w == nil {
+		return
+	}
+	go func() {
+		if _, err := w.warm(context.Background(), orgID, index, trigger); err != nil {
+			w.logger.Warn("Failed to run warm queries", "orgId", orgID, "trigger", trigger, "error", err)
+		}
+	}()
+}
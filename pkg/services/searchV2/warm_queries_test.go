@@ -0,0 +1,99 @@
+package searchV2
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/searchV2/extract"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+func TestIntegrationWarmQueries(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+	warmQueries := newWarmQueries(sqlstore.InitTestDB(t))
+
+	add := func(t *testing.T, orgID int64, name string, q DashboardQuery) *WarmQuery {
+		t.Helper()
+		query, err := warmQueries.add(ctx, &AddWarmQueryCommand{OrgID: orgID, Name: name, Query: q})
+		require.NoError(t, err)
+		return query
+	}
+
+	browse := add(t, 1, "browse", DashboardQuery{Query: "*", Sort: "name_sort"})
+	add(t, 1, "cpu", DashboardQuery{Query: "cpu", Kind: []string{"dashboard"}})
+	add(t, 2, "browse", DashboardQuery{Query: "*"})
+
+	t.Run("validates warm queries", func(t *testing.T) {
+		_, err := warmQueries.add(ctx, &AddWarmQueryCommand{OrgID: 1, Name: " browse ", Query: DashboardQuery{Query: "*"}})
+		require.ErrorIs(t, err, ErrWarmQueryExists)
+		_, err = warmQueries.add(ctx, &AddWarmQueryCommand{OrgID: 1, Name: " ", Query: DashboardQuery{Query: "*"}})
+		require.ErrorIs(t, err, ErrInvalidWarmQuery)
+		_, err = warmQueries.add(ctx, &AddWarmQueryCommand{OrgID: 1, Name: "report", Query: DashboardQuery{Report: "unknown"}})
+		require.ErrorIs(t, err, ErrInvalidWarmQuery)
+	})
+
+	t.Run("limits the warm queries of an organization", func(t *testing.T) {
+		for i := 0; i < maxWarmQueriesPerOrg; i++ {
+			add(t, 3, strings.Repeat("q", i+1), DashboardQuery{Query: "*"})
+		}
+		_, err := warmQueries.add(ctx, &AddWarmQueryCommand{OrgID: 3, Name: "one too many", Query: DashboardQuery{Query: "*"}})
+		require.ErrorIs(t, err, ErrTooManyWarmQueries)
+	})
+
+	t.Run("runs the warm queries and reports their last run", func(t *testing.T) {
+		index := initTestOrgIndexFromDashes(t, []dashboard{
+			{id: 1, uid: "cpu", info: &extract.DashboardInfo{Title: "CPU usage"}},
+			{id: 2, uid: "memory", info: &extract.DashboardInfo{Title: "Memory usage"}},
+		})
+
+		runs, err := warmQueries.warm(ctx, 1, index, WarmQueryTriggerManual)
+		require.NoError(t, err)
+		require.Len(t, runs, 2)
+		require.Equal(t, "browse", runs[0].Name)
+		require.Equal(t, uint64(2), runs[0].Count)
+		require.Equal(t, "cpu", runs[1].Name)
+		require.Equal(t, uint64(1), runs[1].Count)
+		require.Empty(t, runs[1].Error)
+
+		queries, err := warmQueries.list(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, queries, 2)
+		require.Equal(t, DashboardQuery{Query: "*", Sort: "name_sort"}, queries[0].Query)
+		require.NotNil(t, queries[0].LastRun)
+		require.Equal(t, WarmQueryTriggerManual, queries[0].LastRun.Trigger)
+
+		queries, err = warmQueries.list(ctx, 2)
+		require.NoError(t, err)
+		require.Nil(t, queries[0].LastRun)
+	})
+
+	t.Run("deletes warm queries", func(t *testing.T) {
+		require.ErrorIs(t, warmQueries.delete(ctx, 2, browse.ID), ErrWarmQueryNotFound)
+		require.NoError(t, warmQueries.delete(ctx, 1, browse.ID))
+
+		queries, err := warmQueries.list(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, queries, 1)
+		require.Equal(t, "cpu", queries[0].Name)
+	})
+}
+
+func TestWarmQueryFor(t *testing.T) {
+	q := warmQueryFor(DashboardQuery{
+		Query:              "cpu",
+		Federated:          true,
+		PreviewAs:          &PermissionsPreview{UserID: 2},
+		WithAllowedActions: true,
+		WithCapabilities:   true,
+		DatasourceAccess:   "filter",
+		Debug:              true,
+	})
+	require.Equal(t, DashboardQuery{Query: "cpu"}, q)
+}
@@ -0,0 +1,29 @@
+package filters
+
+import (
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+// ExternalIDFilter matches the users with one of the external IDs, see user.User.ExternalID.
+type ExternalIDFilter struct {
+	externalIDs []string
+}
+
+func NewExternalIDFilter(params []string) (user.Filter, error) {
+	return &ExternalIDFilter{externalIDs: params}, nil
+}
+
+func (f *ExternalIDFilter) WhereCondition() *user.WhereCondition {
+	return nil
+}
+
+func (f *ExternalIDFilter) JoinCondition() *user.JoinCondition {
+	return nil
+}
+
+func (f *ExternalIDFilter) InCondition() *user.InCondition {
+	return &user.InCondition{
+		Condition: "u.external_id",
+		Params:    f.externalIDs,
+	}
+}
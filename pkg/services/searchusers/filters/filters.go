@@ -5,7 +5,10 @@ import (
 	"github.com/grafana/grafana/pkg/services/user"
 )
 
-const activeLast30Days = "activeLast30Days"
+const (
+	activeLast30Days = "activeLast30Days"
+	externalID       = "externalId"
+)
 
 type OSSSearchUserFilter struct {
 	filters map[string]user.FilterHandler
@@ -16,6 +19,7 @@ var fltlog = log.New("filters")
 func ProvideOSSSearchUserFilter() *OSSSearchUserFilter {
 	filters := make(map[string]user.FilterHandler)
 	filters[activeLast30Days] = NewActiveLast30DaysFilter
+	filters[externalID] = NewExternalIDFilter
 	return &OSSSearchUserFilter{
 		filters: filters,
 	}
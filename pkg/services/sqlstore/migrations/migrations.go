@@ -98,6 +98,12 @@ func (*OSSMigrations) AddMigration(mg *Migrator) {
 	ualert.UpdateRuleGroupIndexMigration(mg)
 	accesscontrol.AddManagedFolderAlertActionsRepeatMigration(mg)
 	accesscontrol.AddAdminOnlyMigration(mg)
+
+	addOnboardingEventMigrations(mg)
+	addSearchPromotedResultMigrations(mg)
+	addSearchWarmQueryMigrations(mg)
+	addSearchScoringScriptMigrations(mg)
+	addSearchGovernanceLabelMigrations(mg)
 }
 
 func addMigrationLogMigrations(mg *Migrator) {
@@ -0,0 +1,23 @@
+package migrations
+
+import . "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+
+func addOnboardingEventMigrations(mg *Migrator) {
+	onboardingEventV1 := Table{
+		Name: "onboarding_event",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "user_id", Type: DB_BigInt, Nullable: false},
+			{Name: "type", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "invite_id", Type: DB_BigInt, Nullable: false, Default: "0"},
+			{Name: "created", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id", "user_id", "type"}, Type: UniqueIndex},
+		},
+	}
+
+	mg.AddMigration("create onboarding_event table", NewAddTableMigration(onboardingEventV1))
+	mg.AddMigration("add unique index onboarding_event.org_id_user_id_type", NewAddIndexMigration(onboardingEventV1, onboardingEventV1.Indices[0]))
+}
@@ -0,0 +1,22 @@
+package migrations
+
+import . "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+
+func addSearchGovernanceLabelMigrations(mg *Migrator) {
+	searchGovernanceLabelV1 := Table{
+		Name: "search_governance_label",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "dashboard_uid", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "label", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "updated", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id", "dashboard_uid"}, Type: UniqueIndex},
+		},
+	}
+
+	mg.AddMigration("create search_governance_label table", NewAddTableMigration(searchGovernanceLabelV1))
+	mg.AddMigration("add unique index search_governance_label.org_id_dashboard_uid", NewAddIndexMigration(searchGovernanceLabelV1, searchGovernanceLabelV1.Indices[0]))
+}
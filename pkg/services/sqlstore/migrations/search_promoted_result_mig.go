@@ -0,0 +1,23 @@
+package migrations
+
+import . "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+
+func addSearchPromotedResultMigrations(mg *Migrator) {
+	searchPromotedResultV1 := Table{
+		Name: "search_promoted_result",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "term", Type: DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "dashboard_uid", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "position", Type: DB_Int, Nullable: false, Default: "0"},
+			{Name: "created", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id", "term", "dashboard_uid"}, Type: UniqueIndex},
+		},
+	}
+
+	mg.AddMigration("create search_promoted_result table", NewAddTableMigration(searchPromotedResultV1))
+	mg.AddMigration("add unique index search_promoted_result.org_id_term_dashboard_uid", NewAddIndexMigration(searchPromotedResultV1, searchPromotedResultV1.Indices[0]))
+}
@@ -0,0 +1,21 @@
+package migrations
+
+import . "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+
+func addSearchScoringScriptMigrations(mg *Migrator) {
+	searchScoringScriptV1 := Table{
+		Name: "search_scoring_script",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "script", Type: DB_Text, Nullable: false},
+			{Name: "updated", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id"}, Type: UniqueIndex},
+		},
+	}
+
+	mg.AddMigration("create search_scoring_script table", NewAddTableMigration(searchScoringScriptV1))
+	mg.AddMigration("add unique index search_scoring_script.org_id", NewAddIndexMigration(searchScoringScriptV1, searchScoringScriptV1.Indices[0]))
+}
@@ -0,0 +1,22 @@
+package migrations
+
+import . "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+
+func addSearchWarmQueryMigrations(mg *Migrator) {
+	searchWarmQueryV1 := Table{
+		Name: "search_warm_query",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "name", Type: DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "query", Type: DB_Text, Nullable: false},
+			{Name: "created", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id", "name"}, Type: UniqueIndex},
+		},
+	}
+
+	mg.AddMigration("create search_warm_query table", NewAddTableMigration(searchWarmQueryV1))
+	mg.AddMigration("add unique index search_warm_query.org_id_name", NewAddIndexMigration(searchWarmQueryV1, searchWarmQueryV1.Indices[0]))
+}
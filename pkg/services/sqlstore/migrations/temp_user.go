@@ -93,6 +93,168 @@ func addTempUserMigrations(mg *Migrator) {
 
 	// Ensure outstanding invites are given a valid lifetime post-migration
 	mg.AddMigration("Set created for temp users that will otherwise prematurely expire", &SetCreatedForOutstandingInvites{})
+
+	mg.AddMigration("Add column opened_on to temp_user", NewAddColumnMigration(tempUserV2, &Column{
+		Name: "opened_on", Type: DB_DateTime, Nullable: true,
+	}))
+
+	mg.AddMigration("Add column delivery to temp_user", NewAddColumnMigration(tempUserV2, &Column{
+		Name: "delivery", Type: DB_Varchar, Length: 20, Nullable: false, Default: "'email'",
+	}))
+
+	mg.AddMigration("Add column email_match to temp_user", NewAddColumnMigration(tempUserV2, &Column{
+		Name: "email_match", Type: DB_Varchar, Length: 20, Nullable: false, Default: "'any'",
+	}))
+
+	// invite listings and duplicate checks filter on these
+	mg.AddMigration("Add index temp_user.org_id_status", NewAddIndexMigration(tempUserV2, &Index{
+		Cols: []string{"org_id", "status"}, Type: IndexType,
+	}))
+	mg.AddMigration("Add index temp_user.email_org_id_status", NewAddIndexMigration(tempUserV2, &Index{
+		Cols: []string{"email", "org_id", "status"}, Type: IndexType,
+	}))
+
+	mg.AddMigration("Add column archived to temp_user", NewAddColumnMigration(tempUserV2, &Column{
+		Name: "archived", Type: DB_Bool, Nullable: false, Default: "0",
+	}))
+
+	// pending_key is only set for pending invites which must be unique, the unique index allows
+	// any number of NULL values
+	mg.AddMigration("Add column pending_key to temp_user", NewAddColumnMigration(tempUserV2, &Column{
+		Name: "pending_key", Type: DB_NVarchar, Length: 190, Nullable: true,
+	}))
+	mg.AddMigration("Add unique index temp_user.pending_key", NewAddIndexMigration(tempUserV2, &Index{
+		Cols: []string{"pending_key"}, Type: UniqueIndex,
+	}))
+
+	// viewer tokens give a membership of the organization until access_expires
+	mg.AddMigration("Add column access_expires to temp_user", NewAddColumnMigration(tempUserV2, &Column{
+		Name: "access_expires", Type: DB_DateTime, Nullable: true,
+	}))
+	mg.AddMigration("Add column access_user_id to temp_user", NewAddColumnMigration(tempUserV2, &Column{
+		Name: "access_user_id", Type: DB_BigInt, Nullable: false, Default: "0",
+	}))
+
+	mg.AddMigration("Add column invited_by_api_key_id to temp_user", NewAddColumnMigration(tempUserV2, &Column{
+		Name: "invited_by_api_key_id", Type: DB_BigInt, Nullable: false, Default: "0",
+	}))
+
+	// where invites were completed from, for security reviews
+	mg.AddMigration("Add column completed_remote_addr to temp_user", NewAddColumnMigration(tempUserV2, &Column{
+		Name: "completed_remote_addr", Type: DB_Varchar, Length: 255, Nullable: true,
+	}))
+	mg.AddMigration("Add column completed_user_agent to temp_user", NewAddColumnMigration(tempUserV2, &Column{
+		Name: "completed_user_agent", Type: DB_NVarchar, Length: 255, Nullable: true,
+	}))
+	mg.AddMigration("Add column completed_country to temp_user", NewAddColumnMigration(tempUserV2, &Column{
+		Name: "completed_country", Type: DB_Varchar, Length: 2, Nullable: true,
+	}))
+	mg.AddMigration("Add column completed_anomaly to temp_user", NewAddColumnMigration(tempUserV2, &Column{
+		Name: "completed_anomaly", Type: DB_Bool, Nullable: false, Default: "0",
+	}))
+	mg.AddMigration("Add column external_id to temp_user", NewAddColumnMigration(tempUserV2, &Column{
+		Name: "external_id", Type: DB_NVarchar, Length: 190, Nullable: true,
+	}))
+	mg.AddMigration("Add column completed_on to temp_user", NewAddColumnMigration(tempUserV2, &Column{
+		Name: "completed_on", Type: DB_Int, Nullable: true,
+	}))
+	mg.AddMigration("Add column completed_user_id to temp_user", NewAddColumnMigration(tempUserV2, &Column{
+		Name: "completed_user_id", Type: DB_BigInt, Nullable: false, Default: "0",
+	}))
+	// invites are not updated after their completion, until completed_on was added
+	mg.AddMigration("Set completed_on for completed temp users", NewRawSQLMigration(
+		"UPDATE temp_user SET completed_on = updated WHERE status = 'Completed'"))
+
+	tempUserGrantV1 := Table{
+		Name: "temp_user_grant",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "temp_user_id", Type: DB_BigInt, Nullable: false},
+			{Name: "resource_kind", Type: DB_Varchar, Length: 20, Nullable: false},
+			{Name: "resource_uid", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "permission", Type: DB_Varchar, Length: 20, Nullable: false},
+			{Name: "created", Type: DB_Int, Default: "0", Nullable: false},
+			{Name: "updated", Type: DB_Int, Default: "0", Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"temp_user_id", "resource_kind", "resource_uid"}, Type: UniqueIndex},
+		},
+	}
+
+	mg.AddMigration("create temp_user_grant table", NewAddTableMigration(tempUserGrantV1))
+	mg.AddMigration("add unique index temp_user_grant.temp_user_id_resource_kind_resource_uid", NewAddIndexMigration(tempUserGrantV1, tempUserGrantV1.Indices[0]))
+
+	emailSuppressionV1 := Table{
+		Name: "email_suppression",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "email", Type: DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "reason", Type: DB_Varchar, Length: 20, Nullable: false},
+			{Name: "created", Type: DB_Int, Default: "0", Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"email"}, Type: UniqueIndex},
+		},
+	}
+
+	mg.AddMigration("create email_suppression table", NewAddTableMigration(emailSuppressionV1))
+	mg.AddMigration("add unique index email_suppression.email", NewAddIndexMigration(emailSuppressionV1, emailSuppressionV1.Indices[0]))
+
+	inviteNotificationV1 := Table{
+		Name: "invite_notification",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "user_id", Type: DB_BigInt, Nullable: false},
+			{Name: "temp_user_id", Type: DB_BigInt, Nullable: false},
+			{Name: "event", Type: DB_Varchar, Length: 20, Nullable: false},
+			{Name: "is_read", Type: DB_Bool, Nullable: false, Default: "0"},
+			{Name: "created", Type: DB_Int, Default: "0", Nullable: false},
+		},
+		Indices: []*Index{
+			// users are notified once of each event of an invite
+			{Cols: []string{"user_id", "temp_user_id", "event"}, Type: UniqueIndex},
+			{Cols: []string{"org_id", "user_id", "created"}, Type: IndexType},
+		},
+	}
+
+	mg.AddMigration("create invite_notification table", NewAddTableMigration(inviteNotificationV1))
+	addTableIndicesMigrations(mg, "v1", inviteNotificationV1)
+
+	inviteNotificationMuteV1 := Table{
+		Name: "invite_notification_mute",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "user_id", Type: DB_BigInt, Nullable: false},
+			{Name: "event", Type: DB_Varchar, Length: 20, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"user_id", "event"}, Type: UniqueIndex},
+		},
+	}
+
+	mg.AddMigration("create invite_notification_mute table", NewAddTableMigration(inviteNotificationMuteV1))
+	mg.AddMigration("add unique index invite_notification_mute.user_id_event", NewAddIndexMigration(inviteNotificationMuteV1, inviteNotificationMuteV1.Indices[0]))
+
+	tempUserEventV1 := Table{
+		Name: "temp_user_event",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "temp_user_id", Type: DB_BigInt, Nullable: false},
+			{Name: "event", Type: DB_Varchar, Length: 20, Nullable: false},
+			{Name: "actor_user_id", Type: DB_BigInt, Nullable: false, Default: "0"},
+			{Name: "created", Type: DB_Int, Default: "0", Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"temp_user_id", "created"}, Type: IndexType},
+			{Cols: []string{"org_id"}, Type: IndexType},
+		},
+	}
+
+	mg.AddMigration("create temp_user_event table", NewAddTableMigration(tempUserEventV1))
+	addTableIndicesMigrations(mg, "v1", tempUserEventV1)
 }
 
 type SetCreatedForOutstandingInvites struct {
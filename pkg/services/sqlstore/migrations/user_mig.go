@@ -139,6 +139,13 @@ func addUserMigrations(mg *Migrator) {
 			SQLite(migSQLITEisServiceAccountNullable).
 			Postgres("ALTER TABLE `user` ALTER COLUMN is_service_account DROP NOT NULL;").
 			Mysql("ALTER TABLE user MODIFY is_service_account BOOLEAN DEFAULT 0;"))
+
+	mg.AddMigration("Add external_id column to user", NewAddColumnMigration(userV2, &Column{
+		Name: "external_id", Type: DB_NVarchar, Length: 190, Nullable: true,
+	}))
+	mg.AddMigration("Add index user.external_id", NewAddIndexMigration(userV2, &Index{
+		Cols: []string{"external_id"},
+	}))
 }
 
 const migSQLITEisServiceAccountNullable = `ALTER TABLE user ADD COLUMN tmp_service_account BOOLEAN DEFAULT 0;
@@ -318,6 +318,14 @@ func (ss *SQLStore) UpdateUser(ctx context.Context, cmd *models.UpdateUserComman
 			Updated: TimeNow(),
 		}
 
+		// nobody has verified owning a new email yet
+		if cmd.Email != "" {
+			rawSQL := "UPDATE " + ss.Dialect.Quote("user") + " SET email_verified = ? WHERE id = ? AND email <> ?"
+			if _, err := sess.Exec(rawSQL, false, cmd.UserId, cmd.Email); err != nil {
+				return err
+			}
+		}
+
 		if _, err := sess.ID(cmd.UserId).Where(notServiceAccountFilter(ss)).Update(&user); err != nil {
 			return err
 		}
@@ -707,7 +715,7 @@ func (ss *SQLStore) SearchUsers(ctx context.Context, query *models.SearchUsersQu
 			sess.Limit(query.Limit, offset)
 		}
 
-		sess.Cols("u.id", "u.email", "u.name", "u.login", "u.is_admin", "u.is_disabled", "u.last_seen_at", "user_auth.auth_module")
+		sess.Cols("u.id", "u.email", "u.name", "u.login", "u.is_admin", "u.is_disabled", "u.last_seen_at", "u.external_id", "user_auth.auth_module")
 		sess.Asc("u.login", "u.email")
 		if err := sess.Find(&query.Result.Users); err != nil {
 			return err
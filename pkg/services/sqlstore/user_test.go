@@ -78,6 +78,35 @@ func TestIntegrationUserUpdate(t *testing.T) {
 	})
 
 	ss.Cfg.CaseInsensitiveLogin = false
+
+	t.Run("Testing DB - changing the email resets its verification", func(t *testing.T) {
+		verified, err := ss.CreateUser(context.Background(), user.CreateUserCommand{
+			Email:         "verified@test.com",
+			Login:         "verified",
+			EmailVerified: true,
+		})
+		require.NoError(t, err)
+
+		err = ss.UpdateUser(context.Background(), &models.UpdateUserCommand{
+			Login:  "verified",
+			Name:   "Change Name",
+			UserId: verified.ID,
+		})
+		require.NoError(t, err)
+		query := models.GetUserByIdQuery{Id: verified.ID}
+		require.NoError(t, ss.GetUserById(context.Background(), &query))
+		require.True(t, query.Result.EmailVerified)
+
+		err = ss.UpdateUser(context.Background(), &models.UpdateUserCommand{
+			Login:  "verified",
+			Email:  "someone-else@test.com",
+			UserId: verified.ID,
+		})
+		require.NoError(t, err)
+		require.NoError(t, ss.GetUserById(context.Background(), &query))
+		require.Equal(t, "someone-else@test.com", query.Result.Email)
+		require.False(t, query.Result.EmailVerified)
+	})
 }
 
 func TestIntegrationUserDataAccess(t *testing.T) {
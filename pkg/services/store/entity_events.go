@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/grafana/grafana/pkg/infra/log"
@@ -42,6 +43,11 @@ func CreateDatabaseEntityId(internalId interface{}, orgId int64, entityType Enti
 	return fmt.Sprintf("database/%d/%s/%s", orgId, entityType, internalIdAsString)
 }
 
+// CreateStorageEntityId creates entityId for entities stored as files in the storage service
+func CreateStorageEntityId(orgId int64, path string) string {
+	return fmt.Sprintf("storage/%d/%s", orgId, strings.TrimPrefix(path, "/"))
+}
+
 type EntityEvent struct {
 	Id        int64
 	EventType EntityEventType
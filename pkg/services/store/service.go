@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/grafana/grafana/pkg/api/routing"
 	"github.com/grafana/grafana/pkg/infra/filestorage"
@@ -17,6 +19,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/services/org"
 	"github.com/grafana/grafana/pkg/services/quota"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
 	"github.com/grafana/grafana/pkg/services/sqlstore/db"
 	"github.com/grafana/grafana/pkg/services/user"
 	"github.com/grafana/grafana/pkg/setting"
@@ -390,6 +393,9 @@ func (s *standardStorageService) Upload(ctx context.Context, user *user.SignedIn
 		return ErrUploadInternalError
 	}
 
+	if isDashboardPath(req.Path) {
+		s.saveEntityEvent(ctx, getOrgId(user), req.Path, EntityEventTypeUpdate)
+	}
 	return nil
 }
 
@@ -431,7 +437,13 @@ func (s *standardStorageService) DeleteFolder(ctx context.Context, user *user.Si
 	if storagePath == "" {
 		storagePath = filestorage.Delimiter
 	}
-	return root.Store().DeleteFolder(ctx, storagePath, &filestorage.DeleteFolderOptions{Force: cmd.Force, AccessFilter: guardian.getPathFilter(ActionFilesDelete)})
+	err := root.Store().DeleteFolder(ctx, storagePath, &filestorage.DeleteFolderOptions{Force: cmd.Force, AccessFilter: guardian.getPathFilter(ActionFilesDelete)})
+	if err != nil {
+		return err
+	}
+
+	s.saveEntityEvent(ctx, getOrgId(user), cmd.Path, EntityEventTypeDelete)
+	return nil
 }
 
 func (s *standardStorageService) CreateFolder(ctx context.Context, user *user.SignedInUser, cmd *CreateFolderCmd) error {
@@ -495,6 +507,10 @@ func (s *standardStorageService) Delete(ctx context.Context, user *user.SignedIn
 	if err != nil {
 		return err
 	}
+
+	if isDashboardPath(path) {
+		s.saveEntityEvent(ctx, getOrgId(user), path, EntityEventTypeDelete)
+	}
 	return nil
 }
 
@@ -526,9 +542,40 @@ func (s *standardStorageService) write(ctx context.Context, user *user.SignedInU
 	req.Body = prettyJSON.Bytes()
 
 	// Modify the save request
+	path := req.Path
 	req.Path = storagePath
 	req.User = user
-	return root.Write(ctx, req)
+	rsp, err := root.Write(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	s.saveEntityEvent(ctx, getOrgId(user), path, EntityEventTypeUpdate)
+	return rsp, nil
+}
+
+// isDashboardPath returns true for files that may hold a dashboard.
+func isDashboardPath(path string) bool {
+	return strings.HasSuffix(path, ".json")
+}
+
+// saveEntityEvent records a change of a dashboard or folder in the content root, so that
+// the search index can follow changes of dashboards loaded from storage.
+func (s *standardStorageService) saveEntityEvent(ctx context.Context, orgID int64, path string, eventType EntityEventType) {
+	if getFirstSegment(path) != RootContent {
+		return
+	}
+	err := s.sql.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		_, err := sess.Insert(&EntityEvent{
+			EventType: eventType,
+			EntityId:  CreateStorageEntityId(orgID, path),
+			Created:   time.Now().Unix(),
+		})
+		return err
+	})
+	if err != nil {
+		grafanaStorageLogger.Warn("failed to save entity event", "path", path, "error", err)
+	}
 }
 
 type workflowInfo struct {
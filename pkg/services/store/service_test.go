@@ -174,6 +174,30 @@ func TestShouldDelegateFileDeletion(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestShouldSaveEntityEventsForDashboardsInContentRoot(t *testing.T) {
+	sql := sqlstore.InitTestDB(t)
+	mockStorage := &filestorage.MockFileStorage{}
+	contentStorage := newSQLStorage(RootStorageMeta{}, RootContent, "Content", "dummy descr", &StorageSQLConfig{}, sql, 1, false)
+	contentStorage.store = mockStorage
+	service := newStandardStorageService(sql, []storageRuntime{contentStorage}, func(orgId int64) []storageRuntime {
+		return make([]storageRuntime, 0)
+	}, allowAllAuthService, cfg)
+
+	mockStorage.On("Delete", mock.Anything, "/dash.json").Return(nil)
+	mockStorage.On("Delete", mock.Anything, "/image.jpg").Return(nil)
+	require.NoError(t, service.Delete(context.Background(), dummyUser, RootContent+"/dash.json"))
+	require.NoError(t, service.Delete(context.Background(), dummyUser, RootContent+"/image.jpg"))
+
+	var events []EntityEvent
+	err := sql.WithDbSession(context.Background(), func(sess *sqlstore.DBSession) error {
+		return sess.Find(&events)
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, CreateStorageEntityId(getOrgId(dummyUser), RootContent+"/dash.json"), events[0].EntityId)
+	require.Equal(t, EntityEventTypeDelete, events[0].EventType)
+}
+
 func TestShouldDelegateFolderCreation(t *testing.T) {
 	service, mockStorage, storageName := setupUploadStore(t, nil)
 
@@ -0,0 +1,100 @@
+package invitetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+// InviteBuilder builds an invite of a test scenario, see Stack.Invite.
+type InviteBuilder struct {
+	stack     *Stack
+	cmd       models.CreateTempUserCommand
+	emailSent bool
+}
+
+func (b *InviteBuilder) InOrg(orgID int64) *InviteBuilder {
+	b.cmd.OrgId = orgID
+	return b
+}
+
+func (b *InviteBuilder) WithRole(role org.RoleType) *InviteBuilder {
+	b.cmd.Role = role
+	return b
+}
+
+func (b *InviteBuilder) WithName(name string) *InviteBuilder {
+	b.cmd.Name = name
+	return b
+}
+
+// WithCode sets the code of the invite, a random code is generated otherwise.
+func (b *InviteBuilder) WithCode(code string) *InviteBuilder {
+	b.cmd.Code = code
+	return b
+}
+
+func (b *InviteBuilder) WithStatus(status models.TempUserStatus) *InviteBuilder {
+	b.cmd.Status = status
+	return b
+}
+
+func (b *InviteBuilder) WithEmailMatch(match models.InviteEmailMatch) *InviteBuilder {
+	b.cmd.EmailMatch = match
+	return b
+}
+
+func (b *InviteBuilder) WithExternalID(externalID string) *InviteBuilder {
+	b.cmd.ExternalId = externalID
+	return b
+}
+
+func (b *InviteBuilder) WithDelivery(delivery models.InviteDelivery) *InviteBuilder {
+	b.cmd.Delivery = delivery
+	return b
+}
+
+func (b *InviteBuilder) InvitedBy(userID int64) *InviteBuilder {
+	b.cmd.InvitedByUserId = userID
+	return b
+}
+
+func (b *InviteBuilder) InvitedByAPIKey(apiKeyID int64) *InviteBuilder {
+	b.cmd.InvitedByApiKeyId = apiKeyID
+	return b
+}
+
+// AsViewerToken makes the invite a viewer token giving access until expires.
+func (b *InviteBuilder) AsViewerToken(expires time.Time) *InviteBuilder {
+	b.cmd.AccessExpires = &expires
+	return b
+}
+
+// EmailSent records that the email of the invite was sent, without sending it.
+func (b *InviteBuilder) EmailSent() *InviteBuilder {
+	b.emailSent = true
+	return b
+}
+
+// Create saves the invite and returns it.
+func (b *InviteBuilder) Create(t *testing.T) *models.TempUserDTO {
+	t.Helper()
+	ctx := context.Background()
+	cmd := b.cmd
+	if cmd.Code == "" {
+		code, err := util.GetRandomString(30)
+		require.NoError(t, err)
+		cmd.Code = code
+	}
+	require.NoError(t, b.stack.TempUsers.CreateTempUser(ctx, &cmd))
+	if b.emailSent {
+		require.NoError(t, b.stack.TempUsers.UpdateTempUserWithEmailSent(ctx, &models.UpdateTempUserWithEmailSentCommand{Code: cmd.Code}))
+	}
+	return b.stack.Get(t, cmd.Code)
+}
@@ -0,0 +1,51 @@
+package invitetest
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/login"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+var _ login.Service = (*Login)(nil)
+
+// Login creates the users completing invites in the test database of the stack.
+type Login struct {
+	store sqlstore.Store
+
+	mu      sync.Mutex
+	created []*user.User
+}
+
+func (l *Login) CreateUser(cmd user.CreateUserCommand) (*user.User, error) {
+	usr, err := l.store.CreateUser(context.Background(), cmd)
+	if err != nil {
+		return nil, err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.created = append(l.created, usr)
+	return usr, nil
+}
+
+// UpsertUser is not supported, invites are completed by new users or by signed in users.
+func (l *Login) UpsertUser(ctx context.Context, cmd *models.UpsertUserCommand) error {
+	return errors.New("upserting users is not supported by the invite test stack")
+}
+
+func (l *Login) DisableExternalUser(ctx context.Context, username string) error {
+	return nil
+}
+
+func (l *Login) SetTeamSyncFunc(login.TeamSyncFunc) {}
+
+// Created returns the users created by completing invites.
+func (l *Login) Created() []*user.User {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]*user.User{}, l.created...)
+}
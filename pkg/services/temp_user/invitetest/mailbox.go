@@ -0,0 +1,93 @@
+package invitetest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/notifications"
+)
+
+var _ notifications.Service = (*Mailbox)(nil)
+
+// Mailbox captures the emails sent by the invite stack instead of sending them over SMTP.
+type Mailbox struct {
+	mu     sync.Mutex
+	emails []models.SendEmailCommand
+	err    error
+}
+
+func NewMailbox() *Mailbox {
+	return &Mailbox{}
+}
+
+func (m *Mailbox) SendEmailCommandHandler(ctx context.Context, cmd *models.SendEmailCommand) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.emails = append(m.emails, *cmd)
+	return nil
+}
+
+func (m *Mailbox) SendEmailCommandHandlerSync(ctx context.Context, cmd *models.SendEmailCommandSync) error {
+	return m.SendEmailCommandHandler(ctx, &cmd.SendEmailCommand)
+}
+
+func (m *Mailbox) SendWebhookSync(ctx context.Context, cmd *models.SendWebhookSync) error {
+	return nil
+}
+
+// FailWith makes the emails sent from now on fail with err, e.g. models.ErrSmtpNotEnabled. Emails
+// are captured again once err is nil.
+func (m *Mailbox) FailWith(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.err = err
+}
+
+// Emails returns the captured emails in the order they were sent.
+func (m *Mailbox) Emails() []models.SendEmailCommand {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]models.SendEmailCommand{}, m.emails...)
+}
+
+// SentTo returns the captured emails sent to the address.
+func (m *Mailbox) SentTo(address string) []models.SendEmailCommand {
+	var sent []models.SendEmailCommand
+	for _, email := range m.Emails() {
+		for _, to := range email.To {
+			if strings.EqualFold(to, address) {
+				sent = append(sent, email)
+				break
+			}
+		}
+	}
+	return sent
+}
+
+// InviteCode returns the code of the link of the last invite sent to the address.
+func (m *Mailbox) InviteCode(address string) (string, error) {
+	sent := m.SentTo(address)
+	for i := len(sent) - 1; i >= 0; i-- {
+		link, ok := sent[i].Data["LinkUrl"].(string)
+		if !ok {
+			continue
+		}
+		if idx := strings.LastIndex(link, "invite/"); idx >= 0 && idx+len("invite/") < len(link) {
+			return link[idx+len("invite/"):], nil
+		}
+	}
+	return "", fmt.Errorf("no invite was sent to %s", address)
+}
+
+// Reset forgets the captured emails.
+func (m *Mailbox) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.emails = nil
+}
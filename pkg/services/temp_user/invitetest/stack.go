@@ -0,0 +1,118 @@
+// Package invitetest wires the invite stack in memory, so that invite features can be covered by
+// fast integration tests of the service and API layers. Invites are stored in a test database,
+// emails are captured in a Mailbox instead of being sent, and users completing invites are created
+// in the same database.
+//
+// API tests plug the stack into their HTTP server, e.g. the temp user service and the mailbox as
+// the notification service, and build the invites of their scenario with Stack.Invite.
+package invitetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/models"
+	accesscontrolmock "github.com/grafana/grafana/pkg/services/accesscontrol/mock"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	tempuser "github.com/grafana/grafana/pkg/services/temp_user"
+	"github.com/grafana/grafana/pkg/services/temp_user/tempuserimpl"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+// Stack is an in-memory invite stack.
+type Stack struct {
+	SQLStore      sqlstore.Store
+	TempUsers     tempuser.Service
+	Mail          *Mailbox
+	AccessControl *accesscontrolmock.Mock
+	Login         *Login
+}
+
+// New returns an invite stack on a new test database.
+func New(t *testing.T) *Stack {
+	t.Helper()
+	return NewWithStore(sqlstore.InitTestDB(t))
+}
+
+// NewWithStore returns an invite stack on the given test database, e.g. the one of the HTTP
+// server of an API test.
+func NewWithStore(sqlStore sqlstore.Store) *Stack {
+	return &Stack{
+		SQLStore:      sqlStore,
+		TempUsers:     tempuserimpl.ProvideService(sqlStore),
+		Mail:          NewMailbox(),
+		AccessControl: accesscontrolmock.New(),
+		Login:         &Login{store: sqlStore},
+	}
+}
+
+// Org creates an organization and returns its ID.
+func (s *Stack) Org(t *testing.T, name string) int64 {
+	t.Helper()
+	cmd := models.CreateOrgCommand{Name: name}
+	require.NoError(t, s.SQLStore.CreateOrg(context.Background(), &cmd))
+	return cmd.Result.Id
+}
+
+// User creates a user with the role in the organization.
+func (s *Stack) User(t *testing.T, login string, orgID int64, role org.RoleType) *user.User {
+	t.Helper()
+	usr, err := s.SQLStore.CreateUser(context.Background(), user.CreateUserCommand{
+		Login:          login,
+		Email:          login + "@example.com",
+		OrgID:          orgID,
+		DefaultOrgRole: string(role),
+	})
+	require.NoError(t, err)
+	return usr
+}
+
+// Invite returns a builder of a pending invite to the email, for a viewer of organization 1.
+func (s *Stack) Invite(email string) *InviteBuilder {
+	return &InviteBuilder{
+		stack: s,
+		cmd: models.CreateTempUserCommand{
+			Email:  email,
+			OrgId:  1,
+			Role:   org.RoleViewer,
+			Status: models.TmpUserInvitePending,
+		},
+	}
+}
+
+// Get returns the invite with the code.
+func (s *Stack) Get(t *testing.T, code string) *models.TempUserDTO {
+	t.Helper()
+	query := models.GetTempUserByCodeQuery{Code: code}
+	require.NoError(t, s.TempUsers.GetTempUserByCode(context.Background(), &query))
+	return query.Result
+}
+
+// Pending returns the pending invites of the organization.
+func (s *Stack) Pending(t *testing.T, orgID int64) []*models.TempUserDTO {
+	t.Helper()
+	query := models.GetTempUsersQuery{OrgId: orgID, Status: models.TmpUserInvitePending}
+	require.NoError(t, s.TempUsers.GetTempUsersQuery(context.Background(), &query))
+	return query.Result
+}
+
+// Complete marks the invite with the code as completed.
+func (s *Stack) Complete(t *testing.T, code string) {
+	t.Helper()
+	s.setStatus(t, code, models.TmpUserCompleted)
+}
+
+// Revoke revokes the invite with the code.
+func (s *Stack) Revoke(t *testing.T, code string) {
+	t.Helper()
+	s.setStatus(t, code, models.TmpUserRevoked)
+}
+
+func (s *Stack) setStatus(t *testing.T, code string, status models.TempUserStatus) {
+	t.Helper()
+	cmd := models.UpdateTempUserStatusCommand{Code: code, Status: status}
+	require.NoError(t, s.TempUsers.UpdateTempUserStatus(context.Background(), &cmd))
+}
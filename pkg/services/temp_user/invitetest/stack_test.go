@@ -0,0 +1,85 @@
+package invitetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestIntegrationStack(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	stack := New(t)
+	orgID := stack.Org(t, "invites")
+	admin := stack.User(t, "admin", orgID, org.RoleAdmin)
+
+	t.Run("builds invites", func(t *testing.T) {
+		invite := stack.Invite("invitee@example.com").InOrg(orgID).WithRole(org.RoleEditor).InvitedBy(admin.ID).EmailSent().Create(t)
+		require.Len(t, invite.Code, 30)
+		require.Equal(t, orgID, invite.OrgId)
+		require.Equal(t, org.RoleEditor, invite.Role)
+		require.Equal(t, admin.Login, invite.InvitedByLogin)
+		require.True(t, invite.EmailSent)
+
+		token := stack.Invite("viewer@example.com").InOrg(orgID).WithCode("viewer-token").AsViewerToken(time.Now().Add(time.Hour)).Create(t)
+		require.Equal(t, "viewer-token", token.Code)
+		require.NotNil(t, token.AccessExpires)
+		require.Len(t, stack.Pending(t, orgID), 2)
+	})
+
+	t.Run("changes the status of invites", func(t *testing.T) {
+		completed := stack.Invite("completed@example.com").InOrg(orgID).Create(t)
+		stack.Complete(t, completed.Code)
+		require.Equal(t, models.TmpUserCompleted, stack.Get(t, completed.Code).Status)
+
+		revoked := stack.Invite("revoked@example.com").InOrg(orgID).Create(t)
+		stack.Revoke(t, revoked.Code)
+		require.Equal(t, models.TmpUserRevoked, stack.Get(t, revoked.Code).Status)
+	})
+
+	t.Run("creates the users completing invites", func(t *testing.T) {
+		usr, err := stack.Login.CreateUser(user.CreateUserCommand{Login: "invitee", Email: "invitee@example.com", OrgID: orgID})
+		require.NoError(t, err)
+		require.Equal(t, []*user.User{usr}, stack.Login.Created())
+	})
+}
+
+func TestMailbox(t *testing.T) {
+	ctx := context.Background()
+	mailbox := NewMailbox()
+	send := func(to, link string) error {
+		return mailbox.SendEmailCommandHandler(ctx, &models.SendEmailCommand{
+			To:       []string{to},
+			Template: "new_user_invite",
+			Data:     map[string]interface{}{"LinkUrl": link},
+		})
+	}
+
+	require.NoError(t, send("invitee@example.com", "http://localhost:3000/invite/first"))
+	require.NoError(t, send("Invitee@example.com", "invite/second"))
+	require.NoError(t, mailbox.SendEmailCommandHandlerSync(ctx, &models.SendEmailCommandSync{
+		SendEmailCommand: models.SendEmailCommand{To: []string{"other@example.com"}},
+	}))
+
+	require.Len(t, mailbox.Emails(), 3)
+	require.Len(t, mailbox.SentTo("invitee@example.com"), 2)
+	code, err := mailbox.InviteCode("invitee@example.com")
+	require.NoError(t, err)
+	require.Equal(t, "second", code)
+	_, err = mailbox.InviteCode("other@example.com")
+	require.Error(t, err)
+
+	mailbox.FailWith(models.ErrSmtpNotEnabled)
+	require.ErrorIs(t, send("invitee@example.com", ""), models.ErrSmtpNotEnabled)
+	mailbox.FailWith(nil)
+	mailbox.Reset()
+	require.Empty(t, mailbox.Emails())
+}
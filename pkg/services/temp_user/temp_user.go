@@ -10,7 +10,32 @@ type Service interface {
 	UpdateTempUserStatus(ctx context.Context, cmd *models.UpdateTempUserStatusCommand) error
 	CreateTempUser(ctx context.Context, cmd *models.CreateTempUserCommand) error
 	UpdateTempUserWithEmailSent(ctx context.Context, cmd *models.UpdateTempUserWithEmailSentCommand) error
+	MarkTempUserOpened(ctx context.Context, cmd *models.MarkTempUserOpenedCommand) error
+	MarkTempUserEmailQueued(ctx context.Context, cmd *models.MarkTempUserEmailQueuedCommand) error
 	GetTempUsersQuery(ctx context.Context, query *models.GetTempUsersQuery) error
 	GetTempUserByCode(ctx context.Context, query *models.GetTempUserByCodeQuery) error
+	// InvalidateInviteOrgName forgets the cached name of the org shown with its invites, when the org is renamed
+	InvalidateInviteOrgName(orgID int64)
 	ExpireOldUserInvites(ctx context.Context, cmd *models.ExpireTempUsersCommand) error
+	ArchiveTempUsers(ctx context.Context, cmd *models.ArchiveTempUsersCommand) error
+	GetExpiredTempUserAccess(ctx context.Context, query *models.GetExpiredTempUserAccessQuery) error
+	GetTempUsersForUser(ctx context.Context, query *models.GetTempUsersForUserQuery) error
+	EraseTempUserData(ctx context.Context, cmd *models.EraseTempUserDataCommand) error
+	GetTempUserByID(ctx context.Context, query *models.GetTempUserByIDQuery) error
+	SearchTempUsers(ctx context.Context, query *models.SearchTempUsersQuery) error
+	UpdateTempUser(ctx context.Context, cmd *models.UpdateTempUserCommand) error
+	AddTempUserGrant(ctx context.Context, cmd *models.AddTempUserGrantCommand) error
+	GetTempUserGrants(ctx context.Context, query *models.GetTempUserGrantsQuery) error
+	GetInviteCompletionCountries(ctx context.Context, query *models.GetInviteCompletionCountriesQuery) error
+	GetInviteAudit(ctx context.Context, query *models.GetInviteAuditQuery) error
+	GetTempUserHistory(ctx context.Context, query *models.GetTempUserHistoryQuery) error
+	AddEmailSuppression(ctx context.Context, cmd *models.AddEmailSuppressionCommand) error
+	RemoveEmailSuppression(ctx context.Context, cmd *models.RemoveEmailSuppressionCommand) error
+	GetEmailSuppressions(ctx context.Context, query *models.GetEmailSuppressionsQuery) error
+	GetEmailSuppression(ctx context.Context, query *models.GetEmailSuppressionQuery) error
+	AddExpiringInviteNotifications(ctx context.Context, cmd *models.AddExpiringInviteNotificationsCommand) error
+	GetInviteNotifications(ctx context.Context, query *models.GetInviteNotificationsQuery) error
+	MarkInviteNotificationsRead(ctx context.Context, cmd *models.MarkInviteNotificationsReadCommand) error
+	GetInviteNotificationPreferences(ctx context.Context, query *models.GetInviteNotificationPreferencesQuery) error
+	SetInviteNotificationPreferences(ctx context.Context, cmd *models.SetInviteNotificationPreferencesCommand) error
 }
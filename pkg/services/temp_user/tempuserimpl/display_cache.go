@@ -0,0 +1,92 @@
+package tempuserimpl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/localcache"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+const inviteDisplayCacheTTL = 5 * time.Minute
+
+// inviteDisplayCache caches the org names and inviter details shown with invites, which would
+// otherwise be looked up every time an invite link is opened. Entries are local to the instance:
+// orgs renamed through another instance keep their former name here until their entry expires.
+type inviteDisplayCache struct {
+	store store
+	cache *localcache.CacheService
+}
+
+func newInviteDisplayCache(store store) *inviteDisplayCache {
+	return &inviteDisplayCache{
+		store: store,
+		cache: localcache.New(inviteDisplayCacheTTL, 2*inviteDisplayCacheTTL),
+	}
+}
+
+func inviteOrgCacheKey(orgID int64) string {
+	return fmt.Sprintf("invite-org-%d", orgID)
+}
+
+func inviterCacheKey(userID int64) string {
+	return fmt.Sprintf("inviter-%d", userID)
+}
+
+// orgName returns the name of the org, which is empty when the org doesn't exist.
+func (c *inviteDisplayCache) orgName(ctx context.Context, orgID int64) (string, error) {
+	if name, ok := c.cache.Get(inviteOrgCacheKey(orgID)); ok {
+		return name.(string), nil
+	}
+	name, err := c.store.GetInviteOrgName(ctx, orgID)
+	if err != nil && !errors.Is(err, models.ErrOrgNotFound) {
+		return "", err
+	}
+	c.cache.SetDefault(inviteOrgCacheKey(orgID), name)
+	return name, nil
+}
+
+// inviter returns the details of the user who created an invite, which are empty when the user
+// doesn't exist anymore.
+func (c *inviteDisplayCache) inviter(ctx context.Context, userID int64) (models.Inviter, error) {
+	if inviter, ok := c.cache.Get(inviterCacheKey(userID)); ok {
+		return inviter.(models.Inviter), nil
+	}
+	inviter, err := c.store.GetInviter(ctx, userID)
+	if errors.Is(err, user.ErrUserNotFound) {
+		inviter, err = &models.Inviter{}, nil
+	}
+	if err != nil {
+		return models.Inviter{}, err
+	}
+	c.cache.SetDefault(inviterCacheKey(userID), *inviter)
+	return *inviter, nil
+}
+
+func (c *inviteDisplayCache) invalidateOrg(orgID int64) {
+	c.cache.Delete(inviteOrgCacheKey(orgID))
+}
+
+// fill sets the org name and inviter details of the invite.
+func (c *inviteDisplayCache) fill(ctx context.Context, invite *models.TempUserDTO) error {
+	name, err := c.orgName(ctx, invite.OrgId)
+	if err != nil {
+		return err
+	}
+	invite.OrgName = name
+	if invite.InvitedByUserId == 0 {
+		return nil
+	}
+	inviter, err := c.inviter(ctx, invite.InvitedByUserId)
+	if err != nil {
+		return err
+	}
+	invite.InvitedByLogin = inviter.Login
+	invite.InvitedByName = inviter.Name
+	invite.InvitedByEmail = inviter.Email
+	invite.InvitedByServiceAccount = inviter.IsServiceAccount
+	return nil
+}
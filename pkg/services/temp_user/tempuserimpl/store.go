@@ -2,58 +2,154 @@ package tempuserimpl
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/org"
 	"github.com/grafana/grafana/pkg/services/sqlstore"
 	"github.com/grafana/grafana/pkg/services/sqlstore/db"
+	"github.com/grafana/grafana/pkg/services/user"
 )
 
 type store interface {
-	UpdateTempUserStatus(ctx context.Context, cmd *models.UpdateTempUserStatusCommand) error
+	UpdateTempUserStatus(ctx context.Context, cmd *models.UpdateTempUserStatusCommand, from models.TempUserStatus) error
 	CreateTempUser(ctx context.Context, cmd *models.CreateTempUserCommand) error
 	UpdateTempUserWithEmailSent(ctx context.Context, cmd *models.UpdateTempUserWithEmailSentCommand) error
+	MarkTempUserOpened(ctx context.Context, cmd *models.MarkTempUserOpenedCommand) error
+	MarkTempUserEmailQueued(ctx context.Context, cmd *models.MarkTempUserEmailQueuedCommand) error
 	GetTempUsersQuery(ctx context.Context, query *models.GetTempUsersQuery) error
 	GetTempUserByCode(ctx context.Context, query *models.GetTempUserByCodeQuery) error
 	ExpireOldUserInvites(ctx context.Context, cmd *models.ExpireTempUsersCommand) error
+	ArchiveTempUsers(ctx context.Context, cmd *models.ArchiveTempUsersCommand) error
+	GetExpiredTempUserAccess(ctx context.Context, query *models.GetExpiredTempUserAccessQuery) error
+	GetTempUsersForUser(ctx context.Context, query *models.GetTempUsersForUserQuery) error
+	EraseTempUserData(ctx context.Context, cmd *models.EraseTempUserDataCommand) error
+	GetTempUserByID(ctx context.Context, query *models.GetTempUserByIDQuery) error
+	SearchTempUsers(ctx context.Context, query *models.SearchTempUsersQuery) error
+	UpdateTempUser(ctx context.Context, cmd *models.UpdateTempUserCommand, version int) error
+	AddTempUserGrant(ctx context.Context, cmd *models.AddTempUserGrantCommand) error
+	GetTempUserGrants(ctx context.Context, query *models.GetTempUserGrantsQuery) error
+	GetInviteCompletionCountries(ctx context.Context, query *models.GetInviteCompletionCountriesQuery) error
+	GetInviteAudit(ctx context.Context, query *models.GetInviteAuditQuery) error
+	GetTempUserHistory(ctx context.Context, query *models.GetTempUserHistoryQuery) error
+	AddEmailSuppression(ctx context.Context, cmd *models.AddEmailSuppressionCommand) error
+	RemoveEmailSuppression(ctx context.Context, cmd *models.RemoveEmailSuppressionCommand) error
+	GetEmailSuppressions(ctx context.Context, query *models.GetEmailSuppressionsQuery) error
+	GetEmailSuppression(ctx context.Context, query *models.GetEmailSuppressionQuery) error
+	AddInviteNotifications(ctx context.Context, cmd *models.AddInviteNotificationsCommand) error
+	AddExpiringInviteNotifications(ctx context.Context, cmd *models.AddExpiringInviteNotificationsCommand) error
+	GetInviteNotifications(ctx context.Context, query *models.GetInviteNotificationsQuery) error
+	MarkInviteNotificationsRead(ctx context.Context, cmd *models.MarkInviteNotificationsReadCommand) error
+	GetInviteNotificationMutes(ctx context.Context, userID int64) ([]models.InviteNotificationEvent, error)
+	SetInviteNotificationMutes(ctx context.Context, userID int64, muted []models.InviteNotificationEvent) error
+	GetInviteOrgName(ctx context.Context, orgID int64) (string, error)
+	GetInviter(ctx context.Context, userID int64) (*models.Inviter, error)
 }
 
 type xormStore struct {
 	db db.DB
 }
 
-func (ss *xormStore) UpdateTempUserStatus(ctx context.Context, cmd *models.UpdateTempUserStatusCommand) error {
+// UpdateTempUserStatus moves the temp user from the status from to the one of the command, it
+// fails with ErrTempUserInvalidTransition when the temp user isn't in the status from anymore.
+func (ss *xormStore) UpdateTempUserStatus(ctx context.Context, cmd *models.UpdateTempUserStatusCommand, from models.TempUserStatus) error {
 	return ss.db.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
-		var rawSQL = "UPDATE temp_user SET status=? WHERE code=?"
-		_, err := sess.Exec(rawSQL, string(cmd.Status), cmd.Code)
-		return err
+		now := time.Now().Unix()
+		var rawSQL = "UPDATE temp_user SET status=?, version=version+1, updated=?"
+		params := []interface{}{string(cmd.Status), now}
+		if cmd.Status != models.TmpUserInvitePending {
+			rawSQL += ", pending_key=NULL"
+		}
+		if cmd.Status == models.TmpUserCompleted {
+			rawSQL += ", completed_on=?"
+			params = append(params, now)
+		}
+		if cmd.AccessUserID > 0 {
+			rawSQL += ", access_user_id=?"
+			params = append(params, cmd.AccessUserID)
+		}
+		if c := cmd.Completion; c != nil {
+			rawSQL += ", completed_user_id=?, completed_remote_addr=?, completed_user_agent=?, completed_country=?, completed_anomaly=?"
+			params = append(params, c.UserID, c.RemoteAddr, c.UserAgent, c.Country, c.Anomaly)
+		}
+		rawSQL += " WHERE code=? AND status=?"
+		params = append(params, cmd.Code, string(from))
+
+		// recorded before the update, which is rolled back with them when it fails
+		if event, ok := models.TempUserStatusEvent(cmd.Status); ok {
+			actor := cmd.AccessUserID
+			if cmd.Completion != nil {
+				actor = cmd.Completion.UserID
+			}
+			if err := addTempUserEvents(sess, event, actor, now, "code = ? AND status = ?", cmd.Code, string(from)); err != nil {
+				return err
+			}
+		}
+		result, err := sess.Exec(append([]interface{}{rawSQL}, params...)...)
+		if err != nil {
+			return err
+		}
+		updated, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if updated == 0 {
+			return fmt.Errorf("%w: the invite is not %s anymore", models.ErrTempUserInvalidTransition, from)
+		}
+		return nil
 	})
 }
 
 func (ss *xormStore) CreateTempUser(ctx context.Context, cmd *models.CreateTempUserCommand) error {
+	if cmd.Delivery == "" {
+		cmd.Delivery = models.InviteDeliveryEmail
+	}
+	if cmd.EmailMatch == "" {
+		cmd.EmailMatch = models.InviteEmailMatchAny
+	}
 	return ss.db.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
 		// create user
 		user := &models.TempUser{
-			Email:           cmd.Email,
-			Name:            cmd.Name,
-			OrgId:           cmd.OrgId,
-			Code:            cmd.Code,
-			Role:            cmd.Role,
-			Status:          cmd.Status,
-			RemoteAddr:      cmd.RemoteAddr,
-			InvitedByUserId: cmd.InvitedByUserId,
-			EmailSentOn:     time.Now(),
-			Created:         time.Now().Unix(),
-			Updated:         time.Now().Unix(),
+			Email:             cmd.Email,
+			Name:              cmd.Name,
+			OrgId:             cmd.OrgId,
+			Code:              cmd.Code,
+			Role:              cmd.Role,
+			Status:            cmd.Status,
+			RemoteAddr:        cmd.RemoteAddr,
+			InvitedByUserId:   cmd.InvitedByUserId,
+			InvitedByApiKeyId: cmd.InvitedByApiKeyId,
+			Delivery:          cmd.Delivery,
+			EmailMatch:        cmd.EmailMatch,
+			AccessExpires:     cmd.AccessExpires,
+			ExternalId:        cmd.ExternalId,
+			EmailSentOn:       time.Now(),
+			Created:           time.Now().Unix(),
+			Updated:           time.Now().Unix(),
+		}
+		if cmd.UniquePending && cmd.Status == models.TmpUserInvitePending {
+			key := models.TempUserPendingKey(cmd.OrgId, cmd.Email)
+			user.PendingKey = &key
 		}
 
 		if _, err := sess.Insert(user); err != nil {
+			if user.PendingKey != nil && ss.db.GetDialect().IsUniqueConstraintViolation(err) {
+				return models.ErrTempUserPendingExists
+			}
 			return err
 		}
 
 		cmd.Result = user
 
-		return nil
+		_, err := sess.Insert(&models.TempUserEvent{
+			OrgId:       user.OrgId,
+			TempUserId:  user.Id,
+			Event:       models.TempUserEventCreated,
+			ActorUserId: user.InvitedByUserId,
+			Created:     user.Created,
+		})
+		return err
 	})
 }
 
@@ -64,9 +160,37 @@ func (ss *xormStore) UpdateTempUserWithEmailSent(ctx context.Context, cmd *model
 			EmailSentOn: time.Now(),
 		}
 
-		_, err := sess.Where("code = ?", cmd.Code).Cols("email_sent", "email_sent_on").Update(user)
+		_, err := sess.Where("code = ?", cmd.Code).Cols("email_sent", "email_sent_on").Incr("version").Update(user)
+		if err != nil {
+			return err
+		}
 
-		return err
+		return addTempUserEvents(sess, models.TempUserEventEmailSent, 0, user.EmailSentOn.Unix(), "code = ?", cmd.Code)
+	})
+}
+
+func (ss *xormStore) MarkTempUserOpened(ctx context.Context, cmd *models.MarkTempUserOpenedCommand) error {
+	return ss.db.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		var rawSQL = "UPDATE temp_user SET opened_on = ?, version = version + 1, updated = ? WHERE code = ? AND opened_on IS NULL"
+		result, err := sess.Exec(rawSQL, time.Now(), time.Now().Unix(), cmd.Code)
+		if err != nil {
+			return err
+		}
+		opened, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		cmd.Opened = opened > 0
+		if !cmd.Opened {
+			return nil
+		}
+		return addTempUserEvents(sess, models.TempUserEventViewed, 0, time.Now().Unix(), "code = ?", cmd.Code)
+	})
+}
+
+func (ss *xormStore) MarkTempUserEmailQueued(ctx context.Context, cmd *models.MarkTempUserEmailQueuedCommand) error {
+	return ss.db.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		return addTempUserEvents(sess, models.TempUserEventEmailQueued, 0, time.Now().Unix(), "code = ?", cmd.Code)
 	})
 }
 
@@ -75,6 +199,7 @@ func (ss *xormStore) GetTempUsersQuery(ctx context.Context, query *models.GetTem
 		rawSQL := `SELECT
 	                tu.id             as id,
 	                tu.org_id         as org_id,
+	                o.name            as org_name,
 	                tu.email          as email,
 									tu.name           as name,
 									tu.role           as role,
@@ -82,14 +207,25 @@ func (ss *xormStore) GetTempUsersQuery(ctx context.Context, query *models.GetTem
 									tu.status         as status,
 									tu.email_sent     as email_sent,
 									tu.email_sent_on  as email_sent_on,
+									tu.opened_on      as opened_on,
+									tu.delivery       as delivery,
+									tu.email_match    as email_match,
+									tu.access_expires as access_expires,
+									tu.external_id    as external_id,
 									tu.created				as created,
+									tu.version				as version,
 									u.login						as invited_by_login,
 									u.name						as invited_by_name,
-									u.email						as invited_by_email
+									u.email						as invited_by_email,
+									u.is_service_account	as invited_by_service_account,
+									tu.invited_by_api_key_id	as invited_by_api_key_id,
+									ak.name						as invited_by_api_key_name
 	                FROM ` + ss.db.GetDialect().Quote("temp_user") + ` as tu
 									LEFT OUTER JOIN ` + ss.db.GetDialect().Quote("user") + ` as u on u.id = tu.invited_by_user_id
-									WHERE tu.status=?`
-		params := []interface{}{string(query.Status)}
+									LEFT OUTER JOIN ` + ss.db.GetDialect().Quote("api_key") + ` as ak on ak.id = tu.invited_by_api_key_id
+									LEFT OUTER JOIN ` + ss.db.GetDialect().Quote("org") + ` as o on o.id = tu.org_id
+									WHERE tu.status=? AND tu.archived=?`
+		params := []interface{}{string(query.Status), false}
 
 		if query.OrgId > 0 {
 			rawSQL += ` AND tu.org_id=?`
@@ -101,6 +237,29 @@ func (ss *xormStore) GetTempUsersQuery(ctx context.Context, query *models.GetTem
 			params = append(params, query.Email)
 		}
 
+		if query.Delivery != "" {
+			rawSQL += ` AND tu.delivery=?`
+			params = append(params, string(query.Delivery))
+		}
+
+		if query.InvitedByUserID > 0 {
+			rawSQL += ` AND tu.invited_by_user_id=?`
+			params = append(params, query.InvitedByUserID)
+		}
+
+		if query.InvitedBy != "" {
+			invitedBySQL, invitedByParams := invitedByFilter(query.InvitedBy)
+			rawSQL += invitedBySQL
+			params = append(params, invitedByParams...)
+		}
+
+		if query.EmailQueued {
+			// the email was queued after it was last sent, if ever
+			rawSQL += ` AND EXISTS (SELECT 1 FROM temp_user_event as q WHERE q.temp_user_id = tu.id AND q.event = ?
+				AND NOT EXISTS (SELECT 1 FROM temp_user_event as s WHERE s.temp_user_id = tu.id AND s.event = ? AND s.id > q.id))`
+			params = append(params, string(models.TempUserEventEmailQueued), string(models.TempUserEventEmailSent))
+		}
+
 		rawSQL += " ORDER BY tu.created desc"
 
 		query.Result = make([]*models.TempUserDTO, 0)
@@ -110,6 +269,8 @@ func (ss *xormStore) GetTempUsersQuery(ctx context.Context, query *models.GetTem
 	})
 }
 
+// GetTempUserByCode leaves out the org name and inviter details, which the service resolves through
+// its display cache.
 func (ss *xormStore) GetTempUserByCode(ctx context.Context, query *models.GetTempUserByCodeQuery) error {
 	return ss.db.WithDbSession(ctx, func(dbSess *sqlstore.DBSession) error {
 		var rawSQL = `SELECT
@@ -122,12 +283,18 @@ func (ss *xormStore) GetTempUserByCode(ctx context.Context, query *models.GetTem
 									tu.status         as status,
 									tu.email_sent     as email_sent,
 									tu.email_sent_on  as email_sent_on,
+									tu.opened_on      as opened_on,
+									tu.delivery       as delivery,
+									tu.email_match    as email_match,
+									tu.access_expires as access_expires,
+									tu.external_id    as external_id,
 									tu.created				as created,
-									u.login						as invited_by_login,
-									u.name						as invited_by_name,
-									u.email						as invited_by_email
+									tu.version				as version,
+									tu.invited_by_user_id	as invited_by_user_id,
+									tu.invited_by_api_key_id	as invited_by_api_key_id,
+									ak.name						as invited_by_api_key_name
 	                FROM ` + ss.db.GetDialect().Quote("temp_user") + ` as tu
-									LEFT OUTER JOIN ` + ss.db.GetDialect().Quote("user") + ` as u on u.id = tu.invited_by_user_id
+									LEFT OUTER JOIN ` + ss.db.GetDialect().Quote("api_key") + ` as ak on ak.id = tu.invited_by_api_key_id
 	                WHERE tu.code=?`
 
 		var tempUser models.TempUserDTO
@@ -147,12 +314,815 @@ func (ss *xormStore) GetTempUserByCode(ctx context.Context, query *models.GetTem
 
 func (ss *xormStore) ExpireOldUserInvites(ctx context.Context, cmd *models.ExpireTempUsersCommand) error {
 	return ss.db.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
-		var rawSQL = "UPDATE temp_user SET status = ?, updated = ? WHERE created <= ? AND status in (?, ?)"
-		if result, err := sess.Exec(rawSQL, string(models.TmpUserExpired), time.Now().Unix(), cmd.OlderThan.Unix(), string(models.TmpUserSignUpStarted), string(models.TmpUserInvitePending)); err != nil {
+		now := time.Now().Unix()
+		if err := addTempUserEvents(sess, models.TempUserEventExpired, 0, now, "created <= ? AND status in (?, ?)",
+			cmd.OlderThan.Unix(), string(models.TmpUserSignUpStarted), string(models.TmpUserInvitePending)); err != nil {
+			return err
+		}
+		var rawSQL = "UPDATE temp_user SET status = ?, pending_key = NULL, version = version + 1, updated = ? WHERE created <= ? AND status in (?, ?)"
+		if result, err := sess.Exec(rawSQL, string(models.TmpUserExpired), now, cmd.OlderThan.Unix(), string(models.TmpUserSignUpStarted), string(models.TmpUserInvitePending)); err != nil {
 			return err
 		} else if cmd.NumExpired, err = result.RowsAffected(); err != nil {
 			return err
 		}
+
+		// viewer tokens can't be used once the access they give has ended
+		accessExpired := time.Now()
+		if err := addTempUserEvents(sess, models.TempUserEventExpired, 0, now, "access_expires <= ? AND status = ?",
+			accessExpired, string(models.TmpUserInvitePending)); err != nil {
+			return err
+		}
+		rawSQL = "UPDATE temp_user SET status = ?, pending_key = NULL, version = version + 1, updated = ? WHERE access_expires <= ? AND status = ?"
+		result, err := sess.Exec(rawSQL, string(models.TmpUserExpired), now, accessExpired, string(models.TmpUserInvitePending))
+		if err != nil {
+			return err
+		}
+		expiredTokens, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		cmd.NumExpired += expiredTokens
+		return nil
+	})
+}
+
+// ArchiveTempUsers archives closed invites and sign ups, which keeps the rows listings go through
+// to the pending and recently closed ones. The update time is kept as the time they were closed.
+func (ss *xormStore) ArchiveTempUsers(ctx context.Context, cmd *models.ArchiveTempUsersCommand) error {
+	return ss.db.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		var rawSQL = "UPDATE temp_user SET archived = ?, version = version + 1 WHERE archived = ? AND updated <= ? AND status NOT IN (?, ?)"
+		result, err := sess.Exec(rawSQL, true, false, cmd.OlderThan.Unix(), string(models.TmpUserSignUpStarted), string(models.TmpUserInvitePending))
+		if err != nil {
+			return err
+		}
+		cmd.NumArchived, err = result.RowsAffected()
+		return err
+	})
+}
+
+func (ss *xormStore) GetExpiredTempUserAccess(ctx context.Context, query *models.GetExpiredTempUserAccessQuery) error {
+	return ss.db.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		query.Result = make([]*models.TempUser, 0)
+		return sess.Where("status = ? AND access_expires <= ? AND access_user_id > ?", string(models.TmpUserCompleted), query.Now, 0).
+			Asc("access_expires").Find(&query.Result)
+	})
+}
+
+func (ss *xormStore) GetTempUsersForUser(ctx context.Context, query *models.GetTempUsersForUserQuery) error {
+	return ss.db.WithDbSession(ctx, func(dbSess *sqlstore.DBSession) error {
+		// invite codes are left out as they grant access to the organization
+		rawSQL := `SELECT
+	                tu.id             as id,
+	                tu.org_id         as org_id,
+	                o.name            as org_name,
+	                tu.email          as email,
+									tu.name           as name,
+									tu.role           as role,
+									tu.status         as status,
+									tu.email_sent     as email_sent,
+									tu.email_sent_on  as email_sent_on,
+									tu.opened_on      as opened_on,
+									tu.delivery       as delivery,
+									tu.email_match    as email_match,
+									tu.access_expires as access_expires,
+									tu.created				as created,
+									tu.version				as version,
+									u.login						as invited_by_login,
+									u.name						as invited_by_name,
+									u.email						as invited_by_email,
+									u.is_service_account	as invited_by_service_account,
+									tu.invited_by_api_key_id	as invited_by_api_key_id,
+									ak.name						as invited_by_api_key_name
+	                FROM ` + ss.db.GetDialect().Quote("temp_user") + ` as tu
+									LEFT OUTER JOIN ` + ss.db.GetDialect().Quote("user") + ` as u on u.id = tu.invited_by_user_id
+									LEFT OUTER JOIN ` + ss.db.GetDialect().Quote("api_key") + ` as ak on ak.id = tu.invited_by_api_key_id
+									LEFT OUTER JOIN ` + ss.db.GetDialect().Quote("org") + ` as o on o.id = tu.org_id
+									WHERE (tu.invited_by_user_id=?`
+		params := []interface{}{query.UserID}
+
+		if query.Email != "" {
+			// emails are matched case insensitively, as they are when invites are completed
+			rawSQL += ` OR LOWER(tu.email)=LOWER(?)`
+			params = append(params, query.Email)
+		}
+		rawSQL += ")"
+
+		rawSQL += " ORDER BY tu.created desc"
+
+		query.Result = make([]*models.TempUserDTO, 0)
+		return dbSess.SQL(rawSQL, params...).Find(&query.Result)
+	})
+}
+
+func (ss *xormStore) EraseTempUserData(ctx context.Context, cmd *models.EraseTempUserDataCommand) error {
+	return ss.db.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		now := time.Now().Unix()
+		cmd.NumErased = 0
+
+		if cmd.Email != "" {
+			// open invites and sign ups can't be completed once the email is gone
+			closeSQL := "UPDATE temp_user SET status = ?, pending_key = NULL, version = version + 1, updated = ? WHERE LOWER(email) = LOWER(?) AND status = ?"
+			for _, transition := range []struct{ from, to models.TempUserStatus }{
+				{from: models.TmpUserInvitePending, to: models.TmpUserRevoked},
+				{from: models.TmpUserSignUpStarted, to: models.TmpUserExpired},
+			} {
+				event, _ := models.TempUserStatusEvent(transition.to)
+				if err := addTempUserEvents(sess, event, 0, now, "LOWER(email) = LOWER(?) AND status = ?", cmd.Email, string(transition.from)); err != nil {
+					return err
+				}
+				if _, err := sess.Exec(closeSQL, string(transition.to), now, cmd.Email, string(transition.from)); err != nil {
+					return err
+				}
+			}
+
+			result, err := sess.Exec("UPDATE temp_user SET email = ?, name = ?, remote_addr = ?, completed_remote_addr = ?, completed_user_agent = ?, version = version + 1, updated = ? WHERE LOWER(email) = LOWER(?)", "", "", "", "", "", now, cmd.Email)
+			if err != nil {
+				return err
+			}
+			erased, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			cmd.NumErased += erased
+		}
+
+		result, err := sess.Exec("UPDATE temp_user SET invited_by_user_id = ?, updated = ? WHERE invited_by_user_id = ?", 0, now, cmd.UserID)
+		if err != nil {
+			return err
+		}
+		unlinked, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		cmd.NumErased += unlinked
+		return nil
+	})
+}
+
+// invitedByFilter returns the condition restricting the invites to the ones created by automation,
+// with an API key or by a service account, or by users. The user of the inviter is joined as u.
+func invitedByFilter(creator models.InviteCreator) (string, []interface{}) {
+	if creator == models.InviteCreatorAutomation {
+		return " AND (tu.invited_by_api_key_id > 0 OR u.is_service_account = ?)", []interface{}{true}
+	}
+	return " AND tu.invited_by_api_key_id = 0 AND (u.is_service_account IS NULL OR u.is_service_account = ?)", []interface{}{false}
+}
+
+// tempUserDTOSelect selects temp users as models.TempUserDTO, invite codes included.
+func (ss *xormStore) tempUserDTOSelect() string {
+	return `SELECT
+		tu.id                    as id,
+		tu.org_id                as org_id,
+		o.name                   as org_name,
+		tu.email                 as email,
+		tu.name                  as name,
+		tu.role                  as role,
+		tu.code                  as code,
+		tu.status                as status,
+		tu.email_sent            as email_sent,
+		tu.email_sent_on         as email_sent_on,
+		tu.opened_on             as opened_on,
+		tu.delivery              as delivery,
+		tu.email_match           as email_match,
+		tu.access_expires        as access_expires,
+		tu.created               as created,
+		tu.version               as version,
+		tu.invited_by_user_id    as invited_by_user_id,
+		u.login                  as invited_by_login,
+		u.name                   as invited_by_name,
+		u.email                  as invited_by_email,
+		u.is_service_account     as invited_by_service_account,
+		tu.invited_by_api_key_id as invited_by_api_key_id,
+		ak.name                  as invited_by_api_key_name,
+		tu.completed_remote_addr as completed_remote_addr,
+		tu.completed_user_agent  as completed_user_agent,
+		tu.completed_country     as completed_country,
+		tu.completed_anomaly     as completed_anomaly
+		FROM ` + ss.db.GetDialect().Quote("temp_user") + ` as tu
+		LEFT OUTER JOIN ` + ss.db.GetDialect().Quote("user") + ` as u on u.id = tu.invited_by_user_id
+		LEFT OUTER JOIN ` + ss.db.GetDialect().Quote("api_key") + ` as ak on ak.id = tu.invited_by_api_key_id
+		LEFT OUTER JOIN ` + ss.db.GetDialect().Quote("org") + ` as o on o.id = tu.org_id`
+}
+
+func (ss *xormStore) GetTempUserByID(ctx context.Context, query *models.GetTempUserByIDQuery) error {
+	return ss.db.WithDbSession(ctx, func(dbSess *sqlstore.DBSession) error {
+		var tempUser models.TempUserDTO
+		has, err := dbSess.SQL(ss.tempUserDTOSelect()+" WHERE tu.org_id=? AND tu.id=?", query.OrgID, query.ID).Get(&tempUser)
+		if err != nil {
+			return err
+		} else if !has {
+			return models.ErrTempUserNotFound
+		}
+
+		query.Result = &tempUser
+		return nil
+	})
+}
+
+func (ss *xormStore) SearchTempUsers(ctx context.Context, query *models.SearchTempUsersQuery) error {
+	return ss.db.WithDbSession(ctx, func(dbSess *sqlstore.DBSession) error {
+		whereSQL := " WHERE tu.org_id=? AND tu.status<>? AND tu.archived=?"
+		params := []interface{}{query.OrgID, string(models.TmpUserSignUpStarted), false}
+
+		switch query.Status {
+		case "":
+		case models.TmpUserInviteEmailed:
+			whereSQL += " AND tu.status=? AND tu.email_sent=?"
+			params = append(params, string(models.TmpUserInvitePending), true)
+		default:
+			whereSQL += " AND tu.status=?"
+			params = append(params, string(query.Status))
+		}
+
+		if query.Delivery != "" {
+			whereSQL += " AND tu.delivery=?"
+			params = append(params, string(query.Delivery))
+		}
+
+		if query.Query != "" {
+			like := ss.db.GetDialect().LikeStr()
+			whereSQL += " AND (tu.email " + like + " ? OR tu.name " + like + " ?)"
+			params = append(params, "%"+query.Query+"%", "%"+query.Query+"%")
+		}
+
+		if query.InvitedBy != "" {
+			invitedBySQL, invitedByParams := invitedByFilter(query.InvitedBy)
+			whereSQL += invitedBySQL
+			params = append(params, invitedByParams...)
+		}
+
+		if query.Anomaly {
+			whereSQL += " AND tu.completed_anomaly=?"
+			params = append(params, true)
+		}
+
+		if query.InvitedByUserID > 0 {
+			whereSQL += " AND tu.invited_by_user_id=?"
+			params = append(params, query.InvitedByUserID)
+		}
+
+		var count struct {
+			Count int64
+		}
+		countSQL := "SELECT COUNT(*) as count FROM " + ss.db.GetDialect().Quote("temp_user") + " as tu" +
+			" LEFT OUTER JOIN " + ss.db.GetDialect().Quote("user") + " as u on u.id = tu.invited_by_user_id" + whereSQL
+		if _, err := dbSess.SQL(countSQL, params...).Get(&count); err != nil {
+			return err
+		}
+		if query.CountOnly {
+			query.Result = models.SearchTempUsersQueryResult{TotalCount: count.Count, Invites: []*models.TempUserDTO{}}
+			return nil
+		}
+
+		rawSQL := ss.tempUserDTOSelect() + whereSQL + " ORDER BY tu.created desc, tu.id desc"
+		if query.Limit > 0 {
+			offset := query.Limit * (query.Page - 1)
+			rawSQL += ss.db.GetDialect().LimitOffset(int64(query.Limit), int64(offset))
+		}
+
+		invites := make([]*models.TempUserDTO, 0)
+		if err := dbSess.SQL(rawSQL, params...).Find(&invites); err != nil {
+			return err
+		}
+
+		query.Result = models.SearchTempUsersQueryResult{
+			TotalCount: count.Count,
+			Invites:    invites,
+			Page:       query.Page,
+			PerPage:    query.Limit,
+		}
+		return nil
+	})
+}
+
+// UpdateTempUser updates the invite if it is still at the given version.
+func (ss *xormStore) UpdateTempUser(ctx context.Context, cmd *models.UpdateTempUserCommand, version int) error {
+	return ss.db.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		now := time.Now().Unix()
+		rawSQL := "UPDATE temp_user SET version=version+1, updated=?"
+		params := []interface{}{now}
+		if cmd.Name != nil {
+			rawSQL += ", name=?"
+			params = append(params, *cmd.Name)
+		}
+		if cmd.Role != nil {
+			rawSQL += ", role=?"
+			params = append(params, string(*cmd.Role))
+		}
+		if cmd.Status != nil {
+			rawSQL += ", status=?"
+			params = append(params, string(*cmd.Status))
+		}
+		if cmd.Email != nil {
+			rawSQL += ", email=?"
+			params = append(params, *cmd.Email)
+		}
+		switch {
+		case cmd.Status != nil && *cmd.Status != models.TmpUserInvitePending:
+			rawSQL += ", pending_key=NULL"
+		case cmd.Email != nil:
+			// unique pending invites stay unique for the new email
+			rawSQL += ", pending_key=CASE WHEN pending_key IS NULL THEN NULL ELSE ? END"
+			params = append(params, models.TempUserPendingKey(cmd.OrgID, *cmd.Email))
+		}
+		if cmd.Code != nil {
+			// the new link hasn't been sent nor opened yet
+			rawSQL += ", code=?, email_sent=?, opened_on=NULL"
+			params = append(params, *cmd.Code, false)
+		}
+		rawSQL += " WHERE org_id=? AND id=? AND version=?"
+		params = append(params, cmd.OrgID, cmd.ID, version)
+
+		// recorded before the update, which is rolled back with them when it fails
+		if cmd.Name != nil || cmd.Role != nil || cmd.Email != nil {
+			if err := addTempUserEvents(sess, models.TempUserEventUpdated, 0, now, "org_id = ? AND id = ? AND version = ?", cmd.OrgID, cmd.ID, version); err != nil {
+				return err
+			}
+		}
+		if cmd.Status != nil {
+			if event, ok := models.TempUserStatusEvent(*cmd.Status); ok {
+				if err := addTempUserEvents(sess, event, 0, now, "org_id = ? AND id = ? AND version = ?", cmd.OrgID, cmd.ID, version); err != nil {
+					return err
+				}
+			}
+		}
+
+		result, err := sess.Exec(append([]interface{}{rawSQL}, params...)...)
+		if err != nil {
+			if ss.db.GetDialect().IsUniqueConstraintViolation(err) {
+				return models.ErrTempUserPendingExists
+			}
+			return err
+		}
+		updated, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if updated == 0 {
+			return models.ErrTempUserVersionMismatch
+		}
+		return nil
+	})
+}
+
+func (ss *xormStore) AddTempUserGrant(ctx context.Context, cmd *models.AddTempUserGrantCommand) error {
+	return ss.db.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		now := time.Now().Unix()
+		existing := models.TempUserGrant{}
+		has, err := sess.Where("temp_user_id = ? AND resource_kind = ? AND resource_uid = ?", cmd.TempUserID, cmd.ResourceKind, cmd.ResourceUID).Get(&existing)
+		if err != nil {
+			return err
+		}
+		if has {
+			_, err := sess.Exec("UPDATE temp_user_grant SET permission = ?, updated = ? WHERE id = ?", cmd.Permission, now, existing.Id)
+			return err
+		}
+
+		_, err = sess.Insert(&models.TempUserGrant{
+			OrgId:        cmd.OrgID,
+			TempUserId:   cmd.TempUserID,
+			ResourceKind: cmd.ResourceKind,
+			ResourceUid:  cmd.ResourceUID,
+			Permission:   cmd.Permission,
+			Created:      now,
+			Updated:      now,
+		})
+		return err
+	})
+}
+
+func (ss *xormStore) GetTempUserGrants(ctx context.Context, query *models.GetTempUserGrantsQuery) error {
+	return ss.db.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		query.Result = make([]*models.TempUserGrant, 0)
+		return sess.Where("temp_user_id = ?", query.TempUserID).OrderBy("id").Find(&query.Result)
+	})
+}
+
+func (ss *xormStore) GetInviteCompletionCountries(ctx context.Context, query *models.GetInviteCompletionCountriesQuery) error {
+	return ss.db.WithDbSession(ctx, func(dbSess *sqlstore.DBSession) error {
+		rows := make([]struct {
+			CompletedCountry string
+			Count            int64
+		}, 0)
+		rawSQL := "SELECT completed_country, COUNT(*) as count FROM " + ss.db.GetDialect().Quote("temp_user") +
+			" WHERE org_id=? AND status=? AND completed_country IS NOT NULL AND completed_country<>? GROUP BY completed_country"
+		if err := dbSess.SQL(rawSQL, query.OrgID, string(models.TmpUserCompleted), "").Find(&rows); err != nil {
+			return err
+		}
+
+		query.Result = make(map[string]int64, len(rows))
+		for _, row := range rows {
+			query.Result[row.CompletedCountry] = row.Count
+		}
+		return nil
+	})
+}
+
+func (ss *xormStore) GetInviteAudit(ctx context.Context, query *models.GetInviteAuditQuery) error {
+	return ss.db.WithDbSession(ctx, func(dbSess *sqlstore.DBSession) error {
+		rows := make([]struct {
+			Id                  int64
+			Email               string
+			Name                string
+			ExternalId          string
+			Role                org.RoleType
+			AccessExpires       *time.Time
+			Created             int64
+			InvitedByUserId     int64
+			InvitedByLogin      string
+			InvitedByApiKeyId   int64
+			InvitedByApiKeyName string
+			CompletedOn         int64
+			CompletedUserId     int64
+			CompletedLogin      string
+			CompletedRemoteAddr string
+			CompletedUserAgent  string
+			CompletedCountry    string
+			CompletedAnomaly    bool
+		}, 0)
+		dialect := ss.db.GetDialect()
+		rawSQL := `SELECT
+			tu.id, tu.email, tu.name, tu.external_id, tu.role, tu.access_expires, tu.created,
+			tu.invited_by_user_id, u.login as invited_by_login, tu.invited_by_api_key_id, ak.name as invited_by_api_key_name,
+			tu.completed_on, tu.completed_user_id, cu.login as completed_login,
+			tu.completed_remote_addr, tu.completed_user_agent, tu.completed_country, tu.completed_anomaly
+			FROM ` + dialect.Quote("temp_user") + ` as tu
+			LEFT OUTER JOIN ` + dialect.Quote("user") + ` as u on u.id = tu.invited_by_user_id
+			LEFT OUTER JOIN ` + dialect.Quote("api_key") + ` as ak on ak.id = tu.invited_by_api_key_id
+			LEFT OUTER JOIN ` + dialect.Quote("user") + ` as cu on cu.id = tu.completed_user_id
+			WHERE tu.org_id=? AND tu.completed_on>=? AND tu.completed_on<?
+			AND (tu.invited_by_user_id > 0 OR tu.invited_by_api_key_id > 0)`
+		params := []interface{}{query.OrgID, query.From.Unix(), query.To.Unix()}
+		if query.InvitedByUserID > 0 {
+			rawSQL += ` AND tu.invited_by_user_id=?`
+			params = append(params, query.InvitedByUserID)
+		}
+		rawSQL += ` ORDER BY tu.completed_on, tu.id`
+		if err := dbSess.SQL(rawSQL, params...).Find(&rows); err != nil {
+			return err
+		}
+
+		query.Result = make([]*models.InviteAuditEntry, 0, len(rows))
+		for _, row := range rows {
+			role := row.Role
+			if row.AccessExpires != nil {
+				// viewer tokens only give read-only access whatever the role of the invite
+				role = org.RoleViewer
+			}
+			query.Result = append(query.Result, &models.InviteAuditEntry{
+				InviteID:            row.Id,
+				Email:               row.Email,
+				Name:                row.Name,
+				ExternalID:          row.ExternalId,
+				Role:                role,
+				AccessExpires:       row.AccessExpires,
+				Created:             time.Unix(row.Created, 0).UTC(),
+				ApproverUserID:      row.InvitedByUserId,
+				ApproverLogin:       row.InvitedByLogin,
+				ApproverAPIKeyID:    row.InvitedByApiKeyId,
+				ApproverAPIKeyName:  row.InvitedByApiKeyName,
+				Completed:           time.Unix(row.CompletedOn, 0).UTC(),
+				CompletedUserID:     row.CompletedUserId,
+				CompletedLogin:      row.CompletedLogin,
+				CompletedRemoteAddr: row.CompletedRemoteAddr,
+				CompletedUserAgent:  row.CompletedUserAgent,
+				CompletedCountry:    row.CompletedCountry,
+				CompletedAnomaly:    row.CompletedAnomaly,
+			})
+		}
+		return nil
+	})
+}
+
+// addTempUserEvents records the event for the temp users matching the condition. Events of status
+// changes are recorded before the update, while the condition still matches the temp users.
+func addTempUserEvents(sess *sqlstore.DBSession, event models.TempUserEventType, actorUserID int64, created int64, condition string, params ...interface{}) error {
+	tempUsers := make([]struct {
+		Id    int64
+		OrgId int64
+	}, 0)
+	if err := sess.SQL("SELECT id, org_id FROM temp_user WHERE "+condition, params...).Find(&tempUsers); err != nil {
+		return err
+	}
+	for _, tempUser := range tempUsers {
+		if _, err := sess.Insert(&models.TempUserEvent{
+			OrgId:       tempUser.OrgId,
+			TempUserId:  tempUser.Id,
+			Event:       event,
+			ActorUserId: actorUserID,
+			Created:     created,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ss *xormStore) GetTempUserHistory(ctx context.Context, query *models.GetTempUserHistoryQuery) error {
+	return ss.db.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		tempUser := models.TempUser{}
+		has, err := sess.Where("org_id = ? AND code = ?", query.OrgID, query.Code).Get(&tempUser)
+		if err != nil {
+			return err
+		}
+		if !has || (query.InvitedByUserID > 0 && tempUser.InvitedByUserId != query.InvitedByUserID) {
+			return models.ErrTempUserNotFound
+		}
+
+		rows := make([]struct {
+			Event       models.TempUserEventType
+			ActorUserId int64
+			ActorLogin  string
+			Created     int64
+		}, 0)
+		rawSQL := `SELECT e.event, e.actor_user_id, u.login as actor_login, e.created
+			FROM temp_user_event as e
+			LEFT OUTER JOIN ` + ss.db.GetDialect().Quote("user") + ` as u on u.id = e.actor_user_id
+			WHERE e.temp_user_id = ?
+			ORDER BY e.created, e.id`
+		if err := sess.SQL(rawSQL, tempUser.Id).Find(&rows); err != nil {
+			return err
+		}
+
+		query.Result = make([]*models.TempUserEventDTO, 0, len(rows))
+		for _, row := range rows {
+			query.Result = append(query.Result, &models.TempUserEventDTO{
+				Event:       row.Event,
+				ActorUserId: row.ActorUserId,
+				ActorLogin:  row.ActorLogin,
+				Created:     time.Unix(row.Created, 0),
+			})
+		}
+		return nil
+	})
+}
+
+func (ss *xormStore) AddEmailSuppression(ctx context.Context, cmd *models.AddEmailSuppressionCommand) error {
+	return ss.db.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		existing := models.EmailSuppression{}
+		has, err := sess.Where("email = ?", cmd.Email).Get(&existing)
+		if err != nil {
+			return err
+		}
+		if has {
+			existing.Reason = cmd.Reason
+			if _, err := sess.ID(existing.Id).Cols("reason").Update(&existing); err != nil {
+				return err
+			}
+			cmd.Result = &existing
+			return nil
+		}
+
+		suppression := &models.EmailSuppression{
+			Email:   cmd.Email,
+			Reason:  cmd.Reason,
+			Created: time.Now().Unix(),
+		}
+		if _, err := sess.Insert(suppression); err != nil {
+			return err
+		}
+		cmd.Result = suppression
+		return nil
+	})
+}
+
+func (ss *xormStore) RemoveEmailSuppression(ctx context.Context, cmd *models.RemoveEmailSuppressionCommand) error {
+	return ss.db.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		deleted, err := sess.Where("email = ?", cmd.Email).Delete(&models.EmailSuppression{})
+		if err != nil {
+			return err
+		}
+		if deleted == 0 {
+			return models.ErrEmailSuppressionNotFound
+		}
+		return nil
+	})
+}
+
+func (ss *xormStore) GetEmailSuppressions(ctx context.Context, query *models.GetEmailSuppressionsQuery) error {
+	return ss.db.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		query.Result = make([]*models.EmailSuppression, 0)
+		return sess.OrderBy("email").Find(&query.Result)
+	})
+}
+
+func (ss *xormStore) GetEmailSuppression(ctx context.Context, query *models.GetEmailSuppressionQuery) error {
+	return ss.db.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		suppression := models.EmailSuppression{}
+		has, err := sess.Where("email = ?", query.Email).Get(&suppression)
+		if err != nil {
+			return err
+		}
+		if !has {
+			return models.ErrEmailSuppressionNotFound
+		}
+		query.Result = &suppression
+		return nil
+	})
+}
+
+func (ss *xormStore) GetInviteOrgName(ctx context.Context, orgID int64) (string, error) {
+	var name string
+	err := ss.db.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		has, err := sess.Table("org").Where("id = ?", orgID).Cols("name").Get(&name)
+		if err != nil {
+			return err
+		} else if !has {
+			return models.ErrOrgNotFound
+		}
+		return nil
+	})
+	return name, err
+}
+
+func (ss *xormStore) GetInviter(ctx context.Context, userID int64) (*models.Inviter, error) {
+	var inviter models.Inviter
+	err := ss.db.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		has, err := sess.SQL("SELECT login, name, email, is_service_account FROM "+ss.db.GetDialect().Quote("user")+" WHERE id = ?", userID).Get(&inviter)
+		if err != nil {
+			return err
+		} else if !has {
+			return user.ErrUserNotFound
+		}
+		return nil
+	})
+	return &inviter, err
+}
+
+func (ss *xormStore) AddInviteNotifications(ctx context.Context, cmd *models.AddInviteNotificationsCommand) error {
+	return ss.db.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		_, err := ss.addInviteNotifications(sess, cmd.TempUserID, cmd.Event, cmd.ActorUserID, time.Now())
+		return err
+	})
+}
+
+func (ss *xormStore) AddExpiringInviteNotifications(ctx context.Context, cmd *models.AddExpiringInviteNotificationsCommand) error {
+	return ss.db.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		var ids []int64
+		err := sess.SQL(`SELECT id FROM temp_user WHERE status = ? AND archived = ? AND created >= ? AND created < ?
+			AND id NOT IN (SELECT temp_user_id FROM invite_notification WHERE event = ?)`,
+			string(models.TmpUserInvitePending), false, cmd.CreatedAfter.Unix(), cmd.CreatedBefore.Unix(),
+			string(models.InviteNotificationExpiring)).Find(&ids)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, id := range ids {
+			added, err := ss.addInviteNotifications(sess, id, models.InviteNotificationExpiring, 0, now)
+			if err != nil {
+				return err
+			}
+			cmd.NumNotified += added
+		}
+		return nil
+	})
+}
+
+// addInviteNotifications notifies the admins of the organization of the invite and the user who
+// created it of the event, except actorUserID and the users who muted the event. Users are
+// notified once of each event of an invite. It returns the number of notified users.
+func (ss *xormStore) addInviteNotifications(sess *sqlstore.DBSession, tempUserID int64, event models.InviteNotificationEvent, actorUserID int64, now time.Time) (int64, error) {
+	var invite struct {
+		OrgId                   int64
+		InvitedByUserId         int64
+		InvitedByApiKeyId       int64
+		InvitedByServiceAccount bool
+	}
+	has, err := sess.SQL(`SELECT tu.org_id, tu.invited_by_user_id, tu.invited_by_api_key_id, u.is_service_account as invited_by_service_account
+		FROM temp_user as tu
+		LEFT OUTER JOIN `+ss.db.GetDialect().Quote("user")+` as u on u.id = tu.invited_by_user_id
+		WHERE tu.id = ?`, tempUserID).Get(&invite)
+	if err != nil {
+		return 0, err
+	}
+	if !has {
+		return 0, models.ErrTempUserNotFound
+	}
+	automated := invite.InvitedByApiKeyId > 0 || invite.InvitedByServiceAccount
+	if event == models.InviteNotificationCreated && !automated {
+		// admins know about the invites they create
+		return 0, nil
+	}
+
+	var recipients []int64
+	if err := sess.SQL("SELECT user_id FROM org_user WHERE org_id = ? AND role = ?", invite.OrgId, string(org.RoleAdmin)).Find(&recipients); err != nil {
+		return 0, err
+	}
+	if invite.InvitedByUserId > 0 && !invite.InvitedByServiceAccount {
+		recipients = append(recipients, invite.InvitedByUserId)
+	}
+
+	var excluded []int64
+	if err := sess.SQL("SELECT user_id FROM invite_notification_mute WHERE event = ?", string(event)).Find(&excluded); err != nil {
+		return 0, err
+	}
+	var notified []int64
+	if err := sess.SQL("SELECT user_id FROM invite_notification WHERE temp_user_id = ? AND event = ?", tempUserID, string(event)).Find(&notified); err != nil {
+		return 0, err
+	}
+	skip := map[int64]bool{actorUserID: true}
+	for _, id := range append(excluded, notified...) {
+		skip[id] = true
+	}
+
+	var added int64
+	for _, userID := range recipients {
+		if skip[userID] {
+			continue
+		}
+		skip[userID] = true
+		notification := &models.InviteNotification{
+			OrgId:      invite.OrgId,
+			UserId:     userID,
+			TempUserId: tempUserID,
+			Event:      event,
+			Created:    now.Unix(),
+		}
+		if _, err := sess.Insert(notification); err != nil {
+			return added, err
+		}
+		added++
+	}
+	return added, nil
+}
+
+func (ss *xormStore) GetInviteNotifications(ctx context.Context, query *models.GetInviteNotificationsQuery) error {
+	return ss.db.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		rows := make([]struct {
+			Id         int64
+			Event      models.InviteNotificationEvent
+			IsRead     bool
+			Created    int64
+			TempUserId int64
+			Email      string
+			Name       string
+			Role       org.RoleType
+			Status     models.TempUserStatus
+		}, 0)
+		rawSQL := `SELECT n.id, n.event, n.is_read, n.created, n.temp_user_id, tu.email, tu.name, tu.role, tu.status
+			FROM invite_notification as n
+			INNER JOIN temp_user as tu on tu.id = n.temp_user_id
+			WHERE n.org_id = ? AND n.user_id = ?`
+		params := []interface{}{query.OrgID, query.UserID}
+		if query.UnreadOnly {
+			rawSQL += " AND n.is_read = ?"
+			params = append(params, false)
+		}
+		rawSQL += " ORDER BY n.created DESC, n.id DESC"
+		if query.Limit > 0 {
+			rawSQL += ss.db.GetDialect().Limit(int64(query.Limit))
+		}
+		if err := sess.SQL(rawSQL, params...).Find(&rows); err != nil {
+			return err
+		}
+
+		query.Result = make([]*models.InviteNotificationDTO, 0, len(rows))
+		for _, row := range rows {
+			query.Result = append(query.Result, &models.InviteNotificationDTO{
+				Id:       row.Id,
+				Event:    row.Event,
+				Read:     row.IsRead,
+				Created:  time.Unix(row.Created, 0),
+				InviteID: row.TempUserId,
+				Email:    row.Email,
+				Name:     row.Name,
+				Role:     row.Role,
+				Status:   row.Status,
+			})
+		}
+		return nil
+	})
+}
+
+func (ss *xormStore) MarkInviteNotificationsRead(ctx context.Context, cmd *models.MarkInviteNotificationsReadCommand) error {
+	return ss.db.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		sess.Table("invite_notification").Where("org_id = ? AND user_id = ?", cmd.OrgID, cmd.UserID)
+		if len(cmd.IDs) > 0 {
+			sess.In("id", cmd.IDs)
+		}
+		_, err := sess.Cols("is_read").Update(map[string]interface{}{"is_read": true})
+		return err
+	})
+}
+
+func (ss *xormStore) GetInviteNotificationMutes(ctx context.Context, userID int64) ([]models.InviteNotificationEvent, error) {
+	var muted []models.InviteNotificationEvent
+	err := ss.db.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		return sess.SQL("SELECT event FROM invite_notification_mute WHERE user_id = ?", userID).Find(&muted)
+	})
+	return muted, err
+}
+
+func (ss *xormStore) SetInviteNotificationMutes(ctx context.Context, userID int64, muted []models.InviteNotificationEvent) error {
+	return ss.db.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		if _, err := sess.Where("user_id = ?", userID).Delete(&models.InviteNotificationMute{}); err != nil {
+			return err
+		}
+		for _, event := range muted {
+			if _, err := sess.Insert(&models.InviteNotificationMute{UserId: userID, Event: event}); err != nil {
+				return err
+			}
+		}
 		return nil
 	})
 }
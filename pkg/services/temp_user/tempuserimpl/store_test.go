@@ -2,11 +2,15 @@ package tempuserimpl
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/apikey"
+	"github.com/grafana/grafana/pkg/services/org"
 	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/user"
 	"github.com/stretchr/testify/require"
 )
 
@@ -47,6 +51,24 @@ func TestIntegrationTempUserCommandsAndQueries(t *testing.T) {
 		require.Equal(t, 1, len(query.Result))
 	})
 
+	t.Run("Should be able to get temp users by delivery", func(t *testing.T) {
+		setup(t)
+		manual := models.CreateTempUserCommand{OrgId: 2256, Code: "manual", Email: "m@as.co", Status: models.TmpUserInvitePending, Delivery: models.InviteDeliveryManual}
+		require.Nil(t, store.CreateTempUser(context.Background(), &manual))
+
+		query := models.GetTempUsersQuery{OrgId: 2256, Status: models.TmpUserInvitePending, Delivery: models.InviteDeliveryManual}
+		err := store.GetTempUsersQuery(context.Background(), &query)
+		require.Nil(t, err)
+		require.Equal(t, 1, len(query.Result))
+		require.Equal(t, "manual", query.Result[0].Code)
+
+		query = models.GetTempUsersQuery{OrgId: 2256, Status: models.TmpUserInvitePending, Delivery: models.InviteDeliveryEmail}
+		err = store.GetTempUsersQuery(context.Background(), &query)
+		require.Nil(t, err)
+		require.Equal(t, 1, len(query.Result))
+		require.Equal(t, "asd", query.Result[0].Code)
+	})
+
 	t.Run("Should be able to get temp users by code", func(t *testing.T) {
 		setup(t)
 		query := models.GetTempUserByCodeQuery{Code: "asd"}
@@ -59,8 +81,14 @@ func TestIntegrationTempUserCommandsAndQueries(t *testing.T) {
 	t.Run("Should be able update status", func(t *testing.T) {
 		setup(t)
 		cmd2 := models.UpdateTempUserStatusCommand{Code: "asd", Status: models.TmpUserRevoked}
-		err := store.UpdateTempUserStatus(context.Background(), &cmd2)
+		err := store.UpdateTempUserStatus(context.Background(), &cmd2, models.TmpUserInvitePending)
 		require.Nil(t, err)
+
+		err = store.UpdateTempUserStatus(context.Background(), &models.UpdateTempUserStatusCommand{Code: "asd", Status: models.TmpUserCompleted}, models.TmpUserInvitePending)
+		require.ErrorIs(t, err, models.ErrTempUserInvalidTransition, "the status changed since it was read")
+		query := models.GetTempUserByCodeQuery{Code: "asd"}
+		require.NoError(t, store.GetTempUserByCode(context.Background(), &query))
+		require.Equal(t, models.TmpUserRevoked, query.Result.Status)
 	})
 
 	t.Run("Should be able update email sent and email sent on", func(t *testing.T) {
@@ -77,6 +105,49 @@ func TestIntegrationTempUserCommandsAndQueries(t *testing.T) {
 		require.False(t, query.Result[0].EmailSentOn.UTC().Before(query.Result[0].Created.UTC()))
 	})
 
+	t.Run("Should get the temp users whose queued email wasn't sent", func(t *testing.T) {
+		setup(t)
+		ctx := context.Background()
+		queued := func() []*models.TempUserDTO {
+			query := models.GetTempUsersQuery{Status: models.TmpUserInvitePending, EmailQueued: true}
+			require.NoError(t, store.GetTempUsersQuery(ctx, &query))
+			return query.Result
+		}
+		require.Empty(t, queued())
+
+		require.NoError(t, store.MarkTempUserEmailQueued(ctx, &models.MarkTempUserEmailQueuedCommand{Code: cmd.Result.Code}))
+		require.Len(t, queued(), 1)
+		require.NoError(t, store.UpdateTempUserWithEmailSent(ctx, &models.UpdateTempUserWithEmailSentCommand{Code: cmd.Result.Code}))
+		require.Empty(t, queued())
+
+		// the email is queued again when resent
+		require.NoError(t, store.MarkTempUserEmailQueued(ctx, &models.MarkTempUserEmailQueuedCommand{Code: cmd.Result.Code}))
+		require.Len(t, queued(), 1)
+	})
+
+	t.Run("Should record when the invite was first opened", func(t *testing.T) {
+		setup(t)
+		query := models.GetTempUserByCodeQuery{Code: "asd"}
+		require.NoError(t, store.GetTempUserByCode(context.Background(), &query))
+		require.Nil(t, query.Result.OpenedOn)
+
+		opened := models.MarkTempUserOpenedCommand{Code: "asd"}
+		require.NoError(t, store.MarkTempUserOpened(context.Background(), &opened))
+		require.True(t, opened.Opened)
+
+		list := models.GetTempUsersQuery{OrgId: 2256, Status: models.TmpUserInvitePending}
+		require.NoError(t, store.GetTempUsersQuery(context.Background(), &list))
+		require.NotNil(t, list.Result[0].OpenedOn)
+		firstOpened := *list.Result[0].OpenedOn
+
+		reopened := models.MarkTempUserOpenedCommand{Code: "asd"}
+		require.NoError(t, store.MarkTempUserOpened(context.Background(), &reopened))
+		require.False(t, reopened.Opened)
+
+		require.NoError(t, store.GetTempUserByCode(context.Background(), &query))
+		require.Equal(t, firstOpened.Unix(), query.Result.OpenedOn.Unix())
+	})
+
 	t.Run("Should be able expire temp user", func(t *testing.T) {
 		setup(t)
 		createdAt := time.Unix(cmd.Result.Created, 0)
@@ -93,4 +164,352 @@ func TestIntegrationTempUserCommandsAndQueries(t *testing.T) {
 			require.Equal(t, int64(0), cmd2.NumExpired)
 		})
 	})
+
+	t.Run("Should not create a second pending invite for the same email", func(t *testing.T) {
+		setup(t)
+		first := models.CreateTempUserCommand{OrgId: 2256, Code: "first", Email: "u@as.co", Status: models.TmpUserInvitePending, UniquePending: true}
+		require.NoError(t, store.CreateTempUser(context.Background(), &first))
+
+		second := models.CreateTempUserCommand{OrgId: 2256, Code: "second", Email: "U@as.co", Status: models.TmpUserInvitePending, UniquePending: true}
+		require.ErrorIs(t, store.CreateTempUser(context.Background(), &second), models.ErrTempUserPendingExists)
+		other := models.CreateTempUserCommand{OrgId: 2257, Code: "other", Email: "u@as.co", Status: models.TmpUserInvitePending, UniquePending: true}
+		require.NoError(t, store.CreateTempUser(context.Background(), &other))
+
+		t.Run("Should allow a new invite once the pending one is revoked", func(t *testing.T) {
+			require.NoError(t, store.UpdateTempUserStatus(context.Background(), &models.UpdateTempUserStatusCommand{Code: "first", Status: models.TmpUserRevoked}, models.TmpUserInvitePending))
+			require.NoError(t, store.CreateTempUser(context.Background(), &second))
+		})
+	})
+
+	t.Run("Should be able to archive closed temp users", func(t *testing.T) {
+		setup(t)
+		require.NoError(t, store.UpdateTempUserStatus(context.Background(), &models.UpdateTempUserStatusCommand{Code: cmd.Code, Status: models.TmpUserRevoked}, models.TmpUserInvitePending))
+		pending := models.CreateTempUserCommand{OrgId: 2256, Code: "pending", Email: "p@as.co", Status: models.TmpUserInvitePending}
+		require.NoError(t, store.CreateTempUser(context.Background(), &pending))
+
+		archive := models.ArchiveTempUsersCommand{OlderThan: time.Now().Add(time.Second)}
+		require.NoError(t, store.ArchiveTempUsers(context.Background(), &archive))
+		require.Equal(t, int64(1), archive.NumArchived)
+
+		query := models.SearchTempUsersQuery{OrgID: 2256}
+		require.NoError(t, store.SearchTempUsers(context.Background(), &query))
+		require.Equal(t, int64(1), query.Result.TotalCount)
+		require.Equal(t, "pending", query.Result.Invites[0].Code)
+
+		require.NoError(t, store.ArchiveTempUsers(context.Background(), &archive))
+		require.Equal(t, int64(0), archive.NumArchived)
+	})
+
+	t.Run("Should expire viewer tokens once their access ends", func(t *testing.T) {
+		setup(t)
+		ctx := context.Background()
+		ended := time.Now().Add(-time.Minute)
+		active := time.Now().Add(time.Hour)
+		for _, token := range []models.CreateTempUserCommand{
+			{OrgId: 2256, Code: "ended-pending", Email: "a@as.co", Status: models.TmpUserInvitePending, AccessExpires: &ended},
+			{OrgId: 2256, Code: "ended-joined", Email: "b@as.co", Status: models.TmpUserInvitePending, AccessExpires: &ended},
+			{OrgId: 2256, Code: "ended-member", Email: "c@as.co", Status: models.TmpUserInvitePending, AccessExpires: &ended},
+			{OrgId: 2256, Code: "active-joined", Email: "d@as.co", Status: models.TmpUserInvitePending, AccessExpires: &active},
+		} {
+			token := token
+			require.NoError(t, store.CreateTempUser(ctx, &token))
+		}
+		require.NoError(t, store.UpdateTempUserStatus(ctx, &models.UpdateTempUserStatusCommand{Code: "ended-joined", Status: models.TmpUserCompleted, AccessUserID: 10}, models.TmpUserInvitePending))
+		require.NoError(t, store.UpdateTempUserStatus(ctx, &models.UpdateTempUserStatusCommand{Code: "ended-member", Status: models.TmpUserCompleted}, models.TmpUserInvitePending))
+		require.NoError(t, store.UpdateTempUserStatus(ctx, &models.UpdateTempUserStatusCommand{Code: "active-joined", Status: models.TmpUserCompleted, AccessUserID: 11}, models.TmpUserInvitePending))
+
+		expire := models.ExpireTempUsersCommand{OlderThan: time.Now().Add(-time.Hour)}
+		require.NoError(t, store.ExpireOldUserInvites(ctx, &expire))
+		require.Equal(t, int64(1), expire.NumExpired)
+		byCode := models.GetTempUserByCodeQuery{Code: "ended-pending"}
+		require.NoError(t, store.GetTempUserByCode(ctx, &byCode))
+		require.Equal(t, models.TmpUserExpired, byCode.Result.Status)
+		require.NotNil(t, byCode.Result.AccessExpires)
+
+		query := models.GetExpiredTempUserAccessQuery{Now: time.Now()}
+		require.NoError(t, store.GetExpiredTempUserAccess(ctx, &query))
+		require.Len(t, query.Result, 1)
+		require.Equal(t, "ended-joined", query.Result[0].Code)
+		require.Equal(t, int64(10), query.Result[0].AccessUserId)
+
+		require.NoError(t, store.UpdateTempUserStatus(ctx, &models.UpdateTempUserStatusCommand{Code: "ended-joined", Status: models.TmpUserAccessExpired}, models.TmpUserCompleted))
+		require.NoError(t, store.GetExpiredTempUserAccess(ctx, &query))
+		require.Empty(t, query.Result)
+	})
+
+	t.Run("Should attribute invites created by automation", func(t *testing.T) {
+		db := sqlstore.InitTestDB(t)
+		store = &xormStore{db: db}
+		ctx := context.Background()
+		human, err := db.CreateUser(ctx, user.CreateUserCommand{Login: "human"})
+		require.NoError(t, err)
+		sa, err := db.CreateUser(ctx, user.CreateUserCommand{Login: "sa-ci", IsServiceAccount: true})
+		require.NoError(t, err)
+		key := apikey.APIKey{OrgId: 2256, Name: "provisioning", Key: "hashed", Role: org.RoleAdmin, Created: time.Now(), Updated: time.Now()}
+		require.NoError(t, db.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+			_, err := sess.Insert(&key)
+			return err
+		}))
+		for _, invite := range []models.CreateTempUserCommand{
+			{OrgId: 2256, Code: "by-human", Email: "a@as.co", Status: models.TmpUserInvitePending, InvitedByUserId: human.ID},
+			{OrgId: 2256, Code: "by-sa", Email: "b@as.co", Status: models.TmpUserInvitePending, InvitedByUserId: sa.ID},
+			{OrgId: 2256, Code: "by-key", Email: "c@as.co", Status: models.TmpUserInvitePending, InvitedByApiKeyId: key.Id},
+		} {
+			invite := invite
+			require.NoError(t, store.CreateTempUser(ctx, &invite))
+		}
+
+		byCode := models.GetTempUserByCodeQuery{Code: "by-key"}
+		require.NoError(t, store.GetTempUserByCode(ctx, &byCode))
+		require.Equal(t, key.Id, byCode.Result.InvitedByApiKeyId)
+		require.Equal(t, "provisioning", byCode.Result.InvitedByApiKeyName)
+		byCode = models.GetTempUserByCodeQuery{Code: "by-sa"}
+		require.NoError(t, store.GetTempUserByCode(ctx, &byCode))
+		require.Equal(t, sa.ID, byCode.Result.InvitedByUserId)
+		inviter, err := store.GetInviter(ctx, byCode.Result.InvitedByUserId)
+		require.NoError(t, err)
+		require.True(t, inviter.IsServiceAccount)
+		require.Equal(t, "sa-ci", inviter.Login)
+
+		codes := func(invites []*models.TempUserDTO) []string {
+			result := make([]string, 0, len(invites))
+			for _, invite := range invites {
+				result = append(result, invite.Code)
+			}
+			return result
+		}
+		query := models.GetTempUsersQuery{OrgId: 2256, Status: models.TmpUserInvitePending, InvitedBy: models.InviteCreatorAutomation}
+		require.NoError(t, store.GetTempUsersQuery(ctx, &query))
+		require.ElementsMatch(t, []string{"by-sa", "by-key"}, codes(query.Result))
+		query.InvitedBy = models.InviteCreatorUser
+		require.NoError(t, store.GetTempUsersQuery(ctx, &query))
+		require.ElementsMatch(t, []string{"by-human"}, codes(query.Result))
+
+		search := models.SearchTempUsersQuery{OrgID: 2256, InvitedBy: models.InviteCreatorAutomation}
+		require.NoError(t, store.SearchTempUsers(ctx, &search))
+		require.Equal(t, int64(2), search.Result.TotalCount)
+		require.ElementsMatch(t, []string{"by-sa", "by-key"}, codes(search.Result.Invites))
+
+		search = models.SearchTempUsersQuery{OrgID: 2256, InvitedBy: models.InviteCreatorAutomation, CountOnly: true}
+		require.NoError(t, store.SearchTempUsers(ctx, &search))
+		require.Equal(t, int64(2), search.Result.TotalCount)
+		require.Empty(t, search.Result.Invites)
+	})
+	t.Run("Should record where invites were completed from", func(t *testing.T) {
+		setup(t)
+		ctx := context.Background()
+		for i, country := range []string{"FR", "FR", "DE", ""} {
+			invite := models.CreateTempUserCommand{OrgId: 2256, Code: fmt.Sprintf("completed-%d", i), Email: fmt.Sprintf("c%d@as.co", i), Status: models.TmpUserInvitePending}
+			require.NoError(t, store.CreateTempUser(ctx, &invite))
+			completion := &models.InviteCompletion{RemoteAddr: "10.0.0.1", UserAgent: "Firefox", Country: country, Anomaly: country == "DE"}
+			require.NoError(t, store.UpdateTempUserStatus(ctx, &models.UpdateTempUserStatusCommand{Code: invite.Code, Status: models.TmpUserCompleted, Completion: completion}, models.TmpUserInvitePending))
+		}
+
+		countries := models.GetInviteCompletionCountriesQuery{OrgID: 2256}
+		require.NoError(t, store.GetInviteCompletionCountries(ctx, &countries))
+		require.Equal(t, map[string]int64{"FR": 2, "DE": 1}, countries.Result)
+
+		search := models.SearchTempUsersQuery{OrgID: 2256, Anomaly: true}
+		require.NoError(t, store.SearchTempUsers(ctx, &search))
+		require.Len(t, search.Result.Invites, 1)
+		invite := search.Result.Invites[0]
+		require.Equal(t, "completed-2", invite.Code)
+		require.Equal(t, "10.0.0.1", invite.CompletedRemoteAddr)
+		require.Equal(t, "Firefox", invite.CompletedUserAgent)
+		require.Equal(t, "DE", invite.CompletedCountry)
+		require.True(t, invite.CompletedAnomaly)
+
+		erase := models.EraseTempUserDataCommand{Email: "c2@as.co"}
+		require.NoError(t, store.EraseTempUserData(ctx, &erase))
+		byID := models.GetTempUserByIDQuery{OrgID: 2256, ID: invite.Id}
+		require.NoError(t, store.GetTempUserByID(ctx, &byID))
+		require.Empty(t, byID.Result.CompletedRemoteAddr)
+		require.Empty(t, byID.Result.CompletedUserAgent)
+	})
+
+	t.Run("Should report the memberships given by invites", func(t *testing.T) {
+		db := sqlstore.InitTestDB(t)
+		store = &xormStore{db: db}
+		ctx := context.Background()
+		inviter, err := db.CreateUser(ctx, user.CreateUserCommand{Login: "inviter"})
+		require.NoError(t, err)
+		invitee, err := db.CreateUser(ctx, user.CreateUserCommand{Login: "invitee"})
+		require.NoError(t, err)
+		accessExpires := time.Now().Add(time.Hour)
+		for _, invite := range []models.CreateTempUserCommand{
+			{OrgId: 2256, Code: "by-user", Email: "a@as.co", Role: org.RoleEditor, Status: models.TmpUserInvitePending, InvitedByUserId: inviter.ID, ExternalId: "E-1"},
+			{OrgId: 2256, Code: "by-key", Email: "b@as.co", Role: org.RoleAdmin, Status: models.TmpUserInvitePending, InvitedByApiKeyId: 42, AccessExpires: &accessExpires},
+			{OrgId: 2256, Code: "pending", Email: "c@as.co", Role: org.RoleEditor, Status: models.TmpUserInvitePending, InvitedByUserId: inviter.ID},
+			{OrgId: 2256, Code: "sign-up", Email: "d@as.co", Status: models.TmpUserSignUpStarted},
+			{OrgId: 2257, Code: "other-org", Email: "e@as.co", Role: org.RoleEditor, Status: models.TmpUserInvitePending, InvitedByUserId: inviter.ID},
+		} {
+			invite := invite
+			require.NoError(t, store.CreateTempUser(ctx, &invite))
+		}
+		for code, from := range map[string]models.TempUserStatus{
+			"by-user": models.TmpUserInvitePending, "by-key": models.TmpUserInvitePending,
+			"sign-up": models.TmpUserSignUpStarted, "other-org": models.TmpUserInvitePending,
+		} {
+			completion := &models.InviteCompletion{UserID: invitee.ID, RemoteAddr: "10.0.0.1", UserAgent: "Firefox", Country: "FR"}
+			require.NoError(t, store.UpdateTempUserStatus(ctx, &models.UpdateTempUserStatusCommand{Code: code, Status: models.TmpUserCompleted, Completion: completion}, from))
+		}
+		// completed first, reported first
+		completedOn := time.Now().Add(-time.Minute).Unix()
+		require.NoError(t, db.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+			_, err := sess.Exec("UPDATE temp_user SET completed_on = ? WHERE code = ?", completedOn, "by-key")
+			return err
+		}))
+
+		query := models.GetInviteAuditQuery{OrgID: 2256, From: time.Now().Add(-time.Hour), To: time.Now().Add(time.Hour)}
+		require.NoError(t, store.GetInviteAudit(ctx, &query))
+		require.Len(t, query.Result, 2)
+		byKey, byUser := query.Result[0], query.Result[1]
+		require.Equal(t, "b@as.co", byKey.Email)
+		require.Equal(t, org.RoleViewer, byKey.Role, "viewer tokens only grant the viewer role")
+		require.Equal(t, int64(42), byKey.ApproverAPIKeyID)
+		require.Equal(t, completedOn, byKey.Completed.Unix())
+		require.Equal(t, "a@as.co", byUser.Email)
+		require.Equal(t, org.RoleEditor, byUser.Role)
+		require.Equal(t, "E-1", byUser.ExternalID)
+		require.Equal(t, inviter.ID, byUser.ApproverUserID)
+		require.Equal(t, "inviter", byUser.ApproverLogin)
+		require.Equal(t, invitee.ID, byUser.CompletedUserID)
+		require.Equal(t, "invitee", byUser.CompletedLogin)
+		require.Equal(t, "10.0.0.1", byUser.CompletedRemoteAddr)
+		require.Equal(t, "FR", byUser.CompletedCountry)
+
+		query = models.GetInviteAuditQuery{OrgID: 2256, From: time.Now().Add(-time.Hour), To: time.Unix(completedOn, 0)}
+		require.NoError(t, store.GetInviteAudit(ctx, &query))
+		require.Empty(t, query.Result)
+
+		query = models.GetInviteAuditQuery{OrgID: 2256, From: time.Now().Add(-time.Hour), To: time.Now().Add(time.Hour), InvitedByUserID: inviter.ID}
+		require.NoError(t, store.GetInviteAudit(ctx, &query))
+		require.Len(t, query.Result, 1)
+		require.Equal(t, "a@as.co", query.Result[0].Email)
+	})
+
+	t.Run("Should record the history of invites", func(t *testing.T) {
+		db := sqlstore.InitTestDB(t)
+		store = &xormStore{db: db}
+		ctx := context.Background()
+		inviter, err := db.CreateUser(ctx, user.CreateUserCommand{Login: "inviter"})
+		require.NoError(t, err)
+		history := func(t *testing.T, code string) []models.TempUserEventType {
+			t.Helper()
+			query := models.GetTempUserHistoryQuery{OrgID: 2256, Code: code}
+			require.NoError(t, store.GetTempUserHistory(ctx, &query))
+			events := make([]models.TempUserEventType, 0, len(query.Result))
+			for _, e := range query.Result {
+				events = append(events, e.Event)
+			}
+			return events
+		}
+
+		invite := models.CreateTempUserCommand{OrgId: 2256, Code: "history", Email: "h@as.co", Status: models.TmpUserInvitePending, InvitedByUserId: inviter.ID}
+		require.NoError(t, store.CreateTempUser(ctx, &invite))
+		require.NoError(t, store.UpdateTempUserWithEmailSent(ctx, &models.UpdateTempUserWithEmailSentCommand{Code: "history"}))
+		for i := 0; i < 2; i++ {
+			require.NoError(t, store.MarkTempUserOpened(ctx, &models.MarkTempUserOpenedCommand{Code: "history"}))
+		}
+		current := models.GetTempUserByIDQuery{OrgID: 2256, ID: invite.Result.Id}
+		require.NoError(t, store.GetTempUserByID(ctx, &current))
+		name := "renamed"
+		require.NoError(t, store.UpdateTempUser(ctx, &models.UpdateTempUserCommand{OrgID: 2256, ID: invite.Result.Id, Name: &name}, current.Result.Version))
+		require.ErrorIs(t, store.UpdateTempUser(ctx, &models.UpdateTempUserCommand{OrgID: 2256, ID: invite.Result.Id, Name: &name}, 0), models.ErrTempUserVersionMismatch)
+		require.NoError(t, store.UpdateTempUserStatus(ctx, &models.UpdateTempUserStatusCommand{Code: "history", Status: models.TmpUserRevoked}, models.TmpUserInvitePending))
+		require.Equal(t, []models.TempUserEventType{
+			models.TempUserEventCreated, models.TempUserEventEmailSent, models.TempUserEventViewed,
+			models.TempUserEventUpdated, models.TempUserEventRevoked,
+		}, history(t, "history"))
+
+		query := models.GetTempUserHistoryQuery{OrgID: 2256, Code: "history"}
+		require.NoError(t, store.GetTempUserHistory(ctx, &query))
+		require.Equal(t, inviter.ID, query.Result[0].ActorUserId)
+		require.Equal(t, "inviter", query.Result[0].ActorLogin)
+
+		old := models.CreateTempUserCommand{OrgId: 2256, Code: "old", Email: "o@as.co", Status: models.TmpUserInvitePending}
+		require.NoError(t, store.CreateTempUser(ctx, &old))
+		require.NoError(t, store.ExpireOldUserInvites(ctx, &models.ExpireTempUsersCommand{OlderThan: time.Now().Add(time.Minute)}))
+		require.Equal(t, []models.TempUserEventType{models.TempUserEventCreated, models.TempUserEventExpired}, history(t, "old"))
+
+		query = models.GetTempUserHistoryQuery{OrgID: 2256, Code: "history", InvitedByUserID: inviter.ID + 1}
+		require.ErrorIs(t, store.GetTempUserHistory(ctx, &query), models.ErrTempUserNotFound)
+		query = models.GetTempUserHistoryQuery{OrgID: 1, Code: "history"}
+		require.ErrorIs(t, store.GetTempUserHistory(ctx, &query), models.ErrTempUserNotFound)
+	})
+
+	t.Run("Should notify admins and inviters of invite events", func(t *testing.T) {
+		db := sqlstore.InitTestDB(t)
+		store = &xormStore{db: db}
+		ctx := context.Background()
+		var admins []int64
+		for _, login := range []string{"admin1", "admin2"} {
+			u, err := db.CreateUser(ctx, user.CreateUserCommand{Login: login})
+			require.NoError(t, err)
+			admins = append(admins, u.ID)
+		}
+		inviter, err := db.CreateUser(ctx, user.CreateUserCommand{Login: "editor"})
+		require.NoError(t, err)
+		sa, err := db.CreateUser(ctx, user.CreateUserCommand{Login: "sa-scim", IsServiceAccount: true})
+		require.NoError(t, err)
+		require.NoError(t, db.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+			for _, id := range admins {
+				if _, err := sess.Insert(&org.OrgUser{OrgID: 2256, UserID: id, Role: org.RoleAdmin, Created: time.Now(), Updated: time.Now()}); err != nil {
+					return err
+				}
+			}
+			_, err := sess.Insert(&org.OrgUser{OrgID: 2256, UserID: inviter.ID, Role: org.RoleEditor, Created: time.Now(), Updated: time.Now()})
+			return err
+		}))
+		notifications := func(t *testing.T, userID int64) []models.InviteNotificationEvent {
+			t.Helper()
+			query := models.GetInviteNotificationsQuery{OrgID: 2256, UserID: userID}
+			require.NoError(t, store.GetInviteNotifications(ctx, &query))
+			events := make([]models.InviteNotificationEvent, 0, len(query.Result))
+			for _, n := range query.Result {
+				events = append(events, n.Event)
+			}
+			return events
+		}
+
+		byUser := models.CreateTempUserCommand{OrgId: 2256, Code: "by-user", Email: "a@as.co", Status: models.TmpUserInvitePending, InvitedByUserId: inviter.ID}
+		bySA := models.CreateTempUserCommand{OrgId: 2256, Code: "by-sa", Email: "b@as.co", Status: models.TmpUserInvitePending, InvitedByUserId: sa.ID}
+		for _, invite := range []*models.CreateTempUserCommand{&byUser, &bySA} {
+			require.NoError(t, store.CreateTempUser(ctx, invite))
+			require.NoError(t, store.AddInviteNotifications(ctx, &models.AddInviteNotificationsCommand{TempUserID: invite.Result.Id, Event: models.InviteNotificationCreated, ActorUserID: invite.InvitedByUserId}))
+		}
+		require.Equal(t, []models.InviteNotificationEvent{models.InviteNotificationCreated}, notifications(t, admins[0]), "only invites created by automation are notified")
+		require.Empty(t, notifications(t, inviter.ID))
+		require.Empty(t, notifications(t, sa.ID))
+
+		require.NoError(t, store.SetInviteNotificationMutes(ctx, admins[1], []models.InviteNotificationEvent{models.InviteNotificationCompleted}))
+		muted, err := store.GetInviteNotificationMutes(ctx, admins[1])
+		require.NoError(t, err)
+		require.Equal(t, []models.InviteNotificationEvent{models.InviteNotificationCompleted}, muted)
+		for i := 0; i < 2; i++ {
+			require.NoError(t, store.AddInviteNotifications(ctx, &models.AddInviteNotificationsCommand{TempUserID: byUser.Result.Id, Event: models.InviteNotificationCompleted, ActorUserID: admins[0]}))
+		}
+		require.Equal(t, []models.InviteNotificationEvent{models.InviteNotificationCompleted}, notifications(t, inviter.ID), "users are notified once")
+		require.Equal(t, []models.InviteNotificationEvent{models.InviteNotificationCreated}, notifications(t, admins[0]), "the actor isn't notified")
+		require.Equal(t, []models.InviteNotificationEvent{models.InviteNotificationCreated}, notifications(t, admins[1]), "muted events aren't notified")
+
+		expiring := models.AddExpiringInviteNotificationsCommand{CreatedAfter: time.Now().Add(-time.Hour), CreatedBefore: time.Now().Add(time.Minute)}
+		require.NoError(t, store.AddExpiringInviteNotifications(ctx, &expiring))
+		require.Equal(t, int64(5), expiring.NumNotified, "both admins of both invites, and the inviter")
+		expiring.NumNotified = 0
+		require.NoError(t, store.AddExpiringInviteNotifications(ctx, &expiring))
+		require.Zero(t, expiring.NumNotified)
+
+		query := models.GetInviteNotificationsQuery{OrgID: 2256, UserID: admins[0], UnreadOnly: true, Limit: 2}
+		require.NoError(t, store.GetInviteNotifications(ctx, &query))
+		require.Len(t, query.Result, 2)
+		require.Equal(t, models.InviteNotificationExpiring, query.Result[0].Event, "latest first")
+		require.NoError(t, store.MarkInviteNotificationsRead(ctx, &models.MarkInviteNotificationsReadCommand{OrgID: 2256, UserID: admins[0], IDs: []int64{query.Result[0].Id}}))
+		query.Limit = 0
+		require.NoError(t, store.GetInviteNotifications(ctx, &query))
+		require.Len(t, query.Result, 2)
+		require.NoError(t, store.MarkInviteNotificationsRead(ctx, &models.MarkInviteNotificationsReadCommand{OrgID: 2256, UserID: admins[0]}))
+		require.NoError(t, store.GetInviteNotifications(ctx, &query))
+		require.Empty(t, query.Result)
+	})
 }
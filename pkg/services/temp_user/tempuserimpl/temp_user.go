@@ -2,29 +2,49 @@ package tempuserimpl
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 
+	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/services/sqlstore/db"
 	tempuser "github.com/grafana/grafana/pkg/services/temp_user"
+	"github.com/grafana/grafana/pkg/util"
 )
 
 type Service struct {
-	store store
+	store        store
+	displayCache *inviteDisplayCache
+	log          log.Logger
 }
 
 func ProvideService(
 	db db.DB,
 ) tempuser.Service {
+	store := &xormStore{db: db}
 	return &Service{
-		store: &xormStore{db: db},
+		store:        store,
+		displayCache: newInviteDisplayCache(store),
+		log:          log.New("tempuser"),
 	}
 }
 
 func (s *Service) UpdateTempUserStatus(ctx context.Context, cmd *models.UpdateTempUserStatusCommand) error {
-	err := s.store.UpdateTempUserStatus(ctx, cmd)
+	tempUser, err := s.validateStatusUpdate(ctx, cmd.Code, cmd.Status)
 	if err != nil {
 		return err
 	}
+	if err := s.store.UpdateTempUserStatus(ctx, cmd, tempUser.Status); err != nil {
+		return err
+	}
+	if cmd.Status == models.TmpUserCompleted {
+		actor := cmd.AccessUserID
+		if cmd.Completion != nil {
+			actor = cmd.Completion.UserID
+		}
+		s.notify(ctx, tempUser.Id, models.InviteNotificationCompleted, actor)
+	}
 	return nil
 }
 
@@ -33,10 +53,32 @@ func (s *Service) CreateTempUser(ctx context.Context, cmd *models.CreateTempUser
 	if err != nil {
 		return err
 	}
+	if cmd.Result.Status == models.TmpUserInvitePending {
+		s.notify(ctx, cmd.Result.Id, models.InviteNotificationCreated, cmd.InvitedByUserId)
+	}
 	return nil
 }
 
+// notify adds the notifications of an invite event. The event already happened, failing to
+// notify about it doesn't fail it.
+func (s *Service) notify(ctx context.Context, tempUserID int64, event models.InviteNotificationEvent, actorUserID int64) {
+	cmd := models.AddInviteNotificationsCommand{TempUserID: tempUserID, Event: event, ActorUserID: actorUserID}
+	if err := s.store.AddInviteNotifications(ctx, &cmd); err != nil {
+		s.log.Warn("Failed to add invite notifications", "tempUserId", tempUserID, "event", event, "error", err)
+	}
+}
+
 func (s *Service) UpdateTempUserWithEmailSent(ctx context.Context, cmd *models.UpdateTempUserWithEmailSentCommand) error {
+	query := models.GetTempUserByCodeQuery{Code: cmd.Code}
+	if err := s.store.GetTempUserByCode(ctx, &query); err != nil {
+		return err
+	}
+	// sign up verification emails don't change the state of the temp user
+	if query.Result.Status != models.TmpUserSignUpStarted {
+		if err := checkTransition(query.Result, models.TmpUserInviteEmailed); err != nil {
+			return err
+		}
+	}
 	err := s.store.UpdateTempUserWithEmailSent(ctx, cmd)
 	if err != nil {
 		return err
@@ -44,6 +86,14 @@ func (s *Service) UpdateTempUserWithEmailSent(ctx context.Context, cmd *models.U
 	return nil
 }
 
+func (s *Service) MarkTempUserOpened(ctx context.Context, cmd *models.MarkTempUserOpenedCommand) error {
+	return s.store.MarkTempUserOpened(ctx, cmd)
+}
+
+func (s *Service) MarkTempUserEmailQueued(ctx context.Context, cmd *models.MarkTempUserEmailQueuedCommand) error {
+	return s.store.MarkTempUserEmailQueued(ctx, cmd)
+}
+
 func (s *Service) GetTempUsersQuery(ctx context.Context, cmd *models.GetTempUsersQuery) error {
 	err := s.store.GetTempUsersQuery(ctx, cmd)
 	if err != nil {
@@ -57,7 +107,11 @@ func (s *Service) GetTempUserByCode(ctx context.Context, cmd *models.GetTempUser
 	if err != nil {
 		return err
 	}
-	return nil
+	return s.displayCache.fill(ctx, cmd.Result)
+}
+
+func (s *Service) InvalidateInviteOrgName(orgID int64) {
+	s.displayCache.invalidateOrg(orgID)
 }
 
 func (s *Service) ExpireOldUserInvites(ctx context.Context, cmd *models.ExpireTempUsersCommand) error {
@@ -67,3 +121,212 @@ func (s *Service) ExpireOldUserInvites(ctx context.Context, cmd *models.ExpireTe
 	}
 	return nil
 }
+
+func (s *Service) ArchiveTempUsers(ctx context.Context, cmd *models.ArchiveTempUsersCommand) error {
+	return s.store.ArchiveTempUsers(ctx, cmd)
+}
+
+func (s *Service) GetExpiredTempUserAccess(ctx context.Context, query *models.GetExpiredTempUserAccessQuery) error {
+	return s.store.GetExpiredTempUserAccess(ctx, query)
+}
+
+func (s *Service) GetTempUsersForUser(ctx context.Context, query *models.GetTempUsersForUserQuery) error {
+	err := s.store.GetTempUsersForUser(ctx, query)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *Service) EraseTempUserData(ctx context.Context, cmd *models.EraseTempUserDataCommand) error {
+	err := s.store.EraseTempUserData(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *Service) GetTempUserByID(ctx context.Context, query *models.GetTempUserByIDQuery) error {
+	err := s.store.GetTempUserByID(ctx, query)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *Service) SearchTempUsers(ctx context.Context, query *models.SearchTempUsersQuery) error {
+	err := s.store.SearchTempUsers(ctx, query)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *Service) UpdateTempUser(ctx context.Context, cmd *models.UpdateTempUserCommand) error {
+	query := models.GetTempUserByIDQuery{OrgID: cmd.OrgID, ID: cmd.ID}
+	if err := s.store.GetTempUserByID(ctx, &query); err != nil {
+		return err
+	}
+	if cmd.Version != nil && *cmd.Version != query.Result.Version {
+		return models.ErrTempUserVersionMismatch
+	}
+	if cmd.Status != nil {
+		// the membership given by viewer tokens is ended by the cleanup job, which records it
+		if *cmd.Status == models.TmpUserInviteEmailed || *cmd.Status == models.TmpUserAccessExpired {
+			return models.TempUserTransitionError{From: query.Result.State(), To: *cmd.Status}
+		}
+		if err := checkTransition(query.Result, *cmd.Status); err != nil {
+			return err
+		}
+	}
+	if cmd.Email != nil || cmd.Code != nil {
+		if cmd.Email == nil || cmd.Code == nil {
+			return errors.New("the email and code of an invite are changed together")
+		}
+		if query.Result.Status != models.TmpUserInvitePending {
+			return fmt.Errorf("%w: the email of %s invites can't be changed", models.ErrTempUserInvalidTransition, query.Result.State())
+		}
+	}
+
+	if err := s.store.UpdateTempUser(ctx, cmd, query.Result.Version); err != nil {
+		return err
+	}
+	if err := s.store.GetTempUserByID(ctx, &query); err != nil {
+		return err
+	}
+	cmd.Result = query.Result
+	return nil
+}
+
+func (s *Service) validateStatusUpdate(ctx context.Context, code string, to models.TempUserStatus) (*models.TempUserDTO, error) {
+	query := models.GetTempUserByCodeQuery{Code: code}
+	if err := s.store.GetTempUserByCode(ctx, &query); err != nil {
+		return nil, err
+	}
+	// the emailed state is recorded with UpdateTempUserWithEmailSent
+	if to == models.TmpUserInviteEmailed {
+		return nil, models.TempUserTransitionError{From: query.Result.State(), To: to}
+	}
+	return query.Result, checkTransition(query.Result, to)
+}
+
+func checkTransition(tempUser *models.TempUserDTO, to models.TempUserStatus) error {
+	from := tempUser.State()
+	if !from.CanTransitionTo(to) || (to == models.TmpUserAccessExpired && tempUser.AccessExpires == nil) {
+		return models.TempUserTransitionError{From: from, To: to}
+	}
+	return nil
+}
+
+func (s *Service) AddTempUserGrant(ctx context.Context, cmd *models.AddTempUserGrantCommand) error {
+	query := models.GetTempUserByIDQuery{OrgID: cmd.OrgID, ID: cmd.TempUserID}
+	if err := s.store.GetTempUserByID(ctx, &query); err != nil {
+		return err
+	}
+	// grants are applied when the invite is accepted
+	if query.Result.Status != models.TmpUserInvitePending {
+		return models.TempUserTransitionError{From: query.Result.State(), To: models.TmpUserCompleted}
+	}
+	return s.store.AddTempUserGrant(ctx, cmd)
+}
+
+func (s *Service) GetTempUserGrants(ctx context.Context, query *models.GetTempUserGrantsQuery) error {
+	return s.store.GetTempUserGrants(ctx, query)
+}
+
+func (s *Service) GetInviteCompletionCountries(ctx context.Context, query *models.GetInviteCompletionCountriesQuery) error {
+	return s.store.GetInviteCompletionCountries(ctx, query)
+}
+
+// GetInviteAudit returns the memberships given by the invites of an organization over a period,
+// for compliance exports.
+func (s *Service) GetInviteAudit(ctx context.Context, query *models.GetInviteAuditQuery) error {
+	return s.store.GetInviteAudit(ctx, query)
+}
+
+// GetTempUserHistory returns the lifecycle events of an invite, recorded with the changes of the
+// invite by the store.
+func (s *Service) GetTempUserHistory(ctx context.Context, query *models.GetTempUserHistoryQuery) error {
+	return s.store.GetTempUserHistory(ctx, query)
+}
+
+// AddEmailSuppression adds the email to the suppression list, emails are matched case-insensitively.
+func (s *Service) AddEmailSuppression(ctx context.Context, cmd *models.AddEmailSuppressionCommand) error {
+	cmd.Email = normalizeSuppressedEmail(cmd.Email)
+	if !util.IsEmail(cmd.Email) {
+		return models.ErrInvalidEmailSuppression
+	}
+	switch cmd.Reason {
+	case models.EmailSuppressionUnsubscribed, models.EmailSuppressionBounced, models.EmailSuppressionManual:
+	default:
+		return models.ErrInvalidEmailSuppression
+	}
+	return s.store.AddEmailSuppression(ctx, cmd)
+}
+
+func (s *Service) RemoveEmailSuppression(ctx context.Context, cmd *models.RemoveEmailSuppressionCommand) error {
+	cmd.Email = normalizeSuppressedEmail(cmd.Email)
+	return s.store.RemoveEmailSuppression(ctx, cmd)
+}
+
+func (s *Service) GetEmailSuppressions(ctx context.Context, query *models.GetEmailSuppressionsQuery) error {
+	return s.store.GetEmailSuppressions(ctx, query)
+}
+
+func (s *Service) GetEmailSuppression(ctx context.Context, query *models.GetEmailSuppressionQuery) error {
+	query.Email = normalizeSuppressedEmail(query.Email)
+	return s.store.GetEmailSuppression(ctx, query)
+}
+
+func normalizeSuppressedEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// AddExpiringInviteNotifications notifies about the pending invites about to expire, it is called
+// by the cleanup job.
+func (s *Service) AddExpiringInviteNotifications(ctx context.Context, cmd *models.AddExpiringInviteNotificationsCommand) error {
+	return s.store.AddExpiringInviteNotifications(ctx, cmd)
+}
+
+func (s *Service) GetInviteNotifications(ctx context.Context, query *models.GetInviteNotificationsQuery) error {
+	return s.store.GetInviteNotifications(ctx, query)
+}
+
+func (s *Service) MarkInviteNotificationsRead(ctx context.Context, cmd *models.MarkInviteNotificationsReadCommand) error {
+	return s.store.MarkInviteNotificationsRead(ctx, cmd)
+}
+
+// GetInviteNotificationPreferences returns the events the user is notified of, all of them
+// unless muted.
+func (s *Service) GetInviteNotificationPreferences(ctx context.Context, query *models.GetInviteNotificationPreferencesQuery) error {
+	muted, err := s.store.GetInviteNotificationMutes(ctx, query.UserID)
+	if err != nil {
+		return err
+	}
+	query.Result = &models.InviteNotificationPreferences{Created: true, Completed: true, Expiring: true}
+	for _, event := range muted {
+		switch event {
+		case models.InviteNotificationCreated:
+			query.Result.Created = false
+		case models.InviteNotificationCompleted:
+			query.Result.Completed = false
+		case models.InviteNotificationExpiring:
+			query.Result.Expiring = false
+		}
+	}
+	return nil
+}
+
+func (s *Service) SetInviteNotificationPreferences(ctx context.Context, cmd *models.SetInviteNotificationPreferencesCommand) error {
+	var muted []models.InviteNotificationEvent
+	for event, enabled := range map[models.InviteNotificationEvent]bool{
+		models.InviteNotificationCreated:   cmd.Preferences.Created,
+		models.InviteNotificationCompleted: cmd.Preferences.Completed,
+		models.InviteNotificationExpiring:  cmd.Preferences.Expiring,
+	} {
+		if !enabled {
+			muted = append(muted, event)
+		}
+	}
+	return s.store.SetInviteNotificationMutes(ctx, cmd.UserID, muted)
+}
@@ -0,0 +1,368 @@
+package tempuserimpl
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/org"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/user"
+)
+
+func TestTempUserStatusTransitions(t *testing.T) {
+	tests := []struct {
+		from    models.TempUserStatus
+		to      models.TempUserStatus
+		allowed bool
+	}{
+		{from: models.TmpUserInvitePending, to: models.TmpUserInviteEmailed, allowed: true},
+		{from: models.TmpUserInvitePending, to: models.TmpUserCompleted, allowed: true},
+		{from: models.TmpUserInviteEmailed, to: models.TmpUserInviteEmailed, allowed: true},
+		{from: models.TmpUserInviteEmailed, to: models.TmpUserRevoked, allowed: true},
+		{from: models.TmpUserInviteEmailed, to: models.TmpUserDeclined, allowed: true},
+		{from: models.TmpUserSignUpStarted, to: models.TmpUserCompleted, allowed: true},
+		{from: models.TmpUserSignUpStarted, to: models.TmpUserRevoked, allowed: false},
+		{from: models.TmpUserCompleted, to: models.TmpUserRevoked, allowed: false},
+		{from: models.TmpUserRevoked, to: models.TmpUserCompleted, allowed: false},
+		{from: models.TmpUserExpired, to: models.TmpUserInvitePending, allowed: false},
+		{from: models.TmpUserDeclined, to: models.TmpUserCompleted, allowed: false},
+		{from: models.TmpUserCompleted, to: models.TmpUserAccessExpired, allowed: true},
+		{from: models.TmpUserAccessExpired, to: models.TmpUserCompleted, allowed: false},
+	}
+	for _, tc := range tests {
+		require.Equal(t, tc.allowed, tc.from.CanTransitionTo(tc.to), "%s -> %s", tc.from, tc.to)
+	}
+}
+
+func TestInviteEmailMatch(t *testing.T) {
+	tests := []struct {
+		match   models.InviteEmailMatch
+		email   string
+		matches bool
+	}{
+		{match: models.InviteEmailMatchAny, email: "someone@elsewhere.com", matches: true},
+		{match: models.InviteEmailMatchExact, email: "Invitee@Example.com", matches: true},
+		{match: models.InviteEmailMatchExact, email: "colleague@example.com", matches: false},
+		{match: models.InviteEmailMatchDomain, email: "colleague@EXAMPLE.com", matches: true},
+		{match: models.InviteEmailMatchDomain, email: "invitee@example.com.evil.com", matches: false},
+		{match: models.InviteEmailMatchDomain, email: "invitee", matches: false},
+	}
+	for _, tc := range tests {
+		require.Equal(t, tc.matches, tc.match.Matches("invitee@example.com", tc.email), "%s %s", tc.match, tc.email)
+	}
+	require.False(t, models.InviteEmailMatchDomain.Matches("invitee", "invitee"))
+}
+
+func TestIntegrationTempUserServiceTransitions(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+
+	setup := func(t *testing.T, status models.TempUserStatus) *Service {
+		s := ProvideService(sqlstore.InitTestDB(t)).(*Service)
+		cmd := models.CreateTempUserCommand{OrgId: 1, Email: "e@as.co", Code: "code", Status: status}
+		require.NoError(t, s.CreateTempUser(ctx, &cmd))
+		return s
+	}
+
+	t.Run("pending invite can be emailed and revoked", func(t *testing.T) {
+		s := setup(t, models.TmpUserInvitePending)
+		require.NoError(t, s.UpdateTempUserWithEmailSent(ctx, &models.UpdateTempUserWithEmailSentCommand{Code: "code"}))
+
+		query := models.GetTempUserByCodeQuery{Code: "code"}
+		require.NoError(t, s.GetTempUserByCode(ctx, &query))
+		require.Equal(t, models.TmpUserInviteEmailed, query.Result.State())
+
+		require.NoError(t, s.UpdateTempUserStatus(ctx, &models.UpdateTempUserStatusCommand{Code: "code", Status: models.TmpUserRevoked}))
+	})
+
+	t.Run("completed invite can't be revoked or emailed", func(t *testing.T) {
+		s := setup(t, models.TmpUserInvitePending)
+		require.NoError(t, s.UpdateTempUserStatus(ctx, &models.UpdateTempUserStatusCommand{Code: "code", Status: models.TmpUserCompleted}))
+
+		err := s.UpdateTempUserStatus(ctx, &models.UpdateTempUserStatusCommand{Code: "code", Status: models.TmpUserRevoked})
+		require.ErrorIs(t, err, models.ErrTempUserInvalidTransition)
+		var transitionErr models.TempUserTransitionError
+		require.True(t, errors.As(err, &transitionErr))
+		require.Equal(t, models.TmpUserCompleted, transitionErr.From)
+		require.Equal(t, models.TmpUserRevoked, transitionErr.To)
+
+		err = s.UpdateTempUserWithEmailSent(ctx, &models.UpdateTempUserWithEmailSentCommand{Code: "code"})
+		require.ErrorIs(t, err, models.ErrTempUserInvalidTransition)
+	})
+
+	t.Run("only the access of completed viewer tokens can expire", func(t *testing.T) {
+		s := setup(t, models.TmpUserInvitePending)
+		require.NoError(t, s.UpdateTempUserStatus(ctx, &models.UpdateTempUserStatusCommand{Code: "code", Status: models.TmpUserCompleted}))
+		err := s.UpdateTempUserStatus(ctx, &models.UpdateTempUserStatusCommand{Code: "code", Status: models.TmpUserAccessExpired})
+		require.ErrorIs(t, err, models.ErrTempUserInvalidTransition)
+
+		expires := time.Now().Add(time.Hour)
+		token := models.CreateTempUserCommand{OrgId: 1, Email: "t@as.co", Code: "token", Status: models.TmpUserInvitePending, AccessExpires: &expires}
+		require.NoError(t, s.CreateTempUser(ctx, &token))
+		err = s.UpdateTempUserStatus(ctx, &models.UpdateTempUserStatusCommand{Code: "token", Status: models.TmpUserAccessExpired})
+		require.ErrorIs(t, err, models.ErrTempUserInvalidTransition, "pending tokens expire instead")
+		require.NoError(t, s.UpdateTempUserStatus(ctx, &models.UpdateTempUserStatusCommand{Code: "token", Status: models.TmpUserCompleted, AccessUserID: 2}))
+		require.NoError(t, s.UpdateTempUserStatus(ctx, &models.UpdateTempUserStatusCommand{Code: "token", Status: models.TmpUserAccessExpired}))
+	})
+
+	t.Run("emailed state can't be set as a status", func(t *testing.T) {
+		s := setup(t, models.TmpUserInvitePending)
+		err := s.UpdateTempUserStatus(ctx, &models.UpdateTempUserStatusCommand{Code: "code", Status: models.TmpUserInviteEmailed})
+		require.ErrorIs(t, err, models.ErrTempUserInvalidTransition)
+	})
+
+	t.Run("unknown code", func(t *testing.T) {
+		s := setup(t, models.TmpUserInvitePending)
+		err := s.UpdateTempUserStatus(ctx, &models.UpdateTempUserStatusCommand{Code: "unknown", Status: models.TmpUserRevoked})
+		require.ErrorIs(t, err, models.ErrTempUserNotFound)
+	})
+}
+
+func TestIntegrationTempUserDataErasure(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	s := ProvideService(sqlstore.InitTestDB(t)).(*Service)
+
+	for _, cmd := range []models.CreateTempUserCommand{
+		{OrgId: 1, Email: "invitee@as.co", Name: "Invitee", Code: "to-user", Status: models.TmpUserInvitePending, InvitedByUserId: 2, RemoteAddr: "10.0.0.1"},
+		{OrgId: 1, Email: "other@as.co", Name: "Other", Code: "by-user", Status: models.TmpUserInvitePending, InvitedByUserId: 1},
+		{OrgId: 1, Email: "unrelated@as.co", Code: "unrelated", Status: models.TmpUserInvitePending, InvitedByUserId: 2},
+		{OrgId: 2, Email: "Invitee@AS.co", Code: "archived", Status: models.TmpUserRevoked, InvitedByUserId: 2},
+	} {
+		cmd := cmd
+		require.NoError(t, s.CreateTempUser(ctx, &cmd))
+	}
+	// archived temp users are left out of listings, not out of the personal data of the user
+	archive := models.ArchiveTempUsersCommand{OlderThan: time.Now().Add(time.Second)}
+	require.NoError(t, s.ArchiveTempUsers(ctx, &archive))
+	require.Equal(t, int64(1), archive.NumArchived)
+
+	exportQuery := models.GetTempUsersForUserQuery{UserID: 1, Email: "invitee@as.co"}
+	require.NoError(t, s.GetTempUsersForUser(ctx, &exportQuery))
+	require.Len(t, exportQuery.Result, 3)
+	for _, tu := range exportQuery.Result {
+		require.Empty(t, tu.Code)
+	}
+
+	eraseCmd := models.EraseTempUserDataCommand{UserID: 1, Email: "invitee@as.co"}
+	require.NoError(t, s.EraseTempUserData(ctx, &eraseCmd))
+	require.Equal(t, int64(3), eraseCmd.NumErased)
+
+	query := models.GetTempUserByCodeQuery{Code: "to-user"}
+	require.NoError(t, s.GetTempUserByCode(ctx, &query))
+	require.Empty(t, query.Result.Email)
+	require.Empty(t, query.Result.Name)
+	require.Equal(t, models.TmpUserRevoked, query.Result.Status)
+
+	query = models.GetTempUserByCodeQuery{Code: "unrelated"}
+	require.NoError(t, s.GetTempUserByCode(ctx, &query))
+	require.Equal(t, "unrelated@as.co", query.Result.Email)
+	require.Equal(t, models.TmpUserInvitePending, query.Result.Status)
+
+	exportQuery = models.GetTempUsersForUserQuery{UserID: 1, Email: "invitee@as.co"}
+	require.NoError(t, s.GetTempUsersForUser(ctx, &exportQuery))
+	require.Empty(t, exportQuery.Result)
+}
+
+func TestIntegrationTempUserSearchAndUpdate(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	s := ProvideService(sqlstore.InitTestDB(t)).(*Service)
+
+	ids := map[string]int64{}
+	for _, cmd := range []models.CreateTempUserCommand{
+		{OrgId: 1, Email: "alice@as.co", Name: "Alice", Code: "alice", Role: org.RoleViewer, Status: models.TmpUserInvitePending},
+		{OrgId: 1, Email: "bob@as.co", Name: "Bob", Code: "bob", Role: org.RoleViewer, Status: models.TmpUserInvitePending},
+		{OrgId: 1, Email: "carol@as.co", Code: "carol", Status: models.TmpUserSignUpStarted},
+		{OrgId: 2, Email: "alice@as.co", Code: "other-org", Role: org.RoleViewer, Status: models.TmpUserInvitePending},
+	} {
+		cmd := cmd
+		require.NoError(t, s.CreateTempUser(ctx, &cmd))
+		ids[cmd.Code] = cmd.Result.Id
+	}
+	require.NoError(t, s.UpdateTempUserWithEmailSent(ctx, &models.UpdateTempUserWithEmailSentCommand{Code: "bob"}))
+
+	search := func(t *testing.T, query models.SearchTempUsersQuery) []string {
+		t.Helper()
+		query.OrgID = 1
+		require.NoError(t, s.SearchTempUsers(ctx, &query))
+		codes := []string{}
+		for _, invite := range query.Result.Invites {
+			codes = append(codes, invite.Code)
+		}
+		return codes
+	}
+
+	t.Run("searches invites of the organization", func(t *testing.T) {
+		require.ElementsMatch(t, []string{"alice", "bob"}, search(t, models.SearchTempUsersQuery{}))
+		require.Equal(t, []string{"alice"}, search(t, models.SearchTempUsersQuery{Query: "ali"}))
+		require.Equal(t, []string{"bob"}, search(t, models.SearchTempUsersQuery{Status: models.TmpUserInviteEmailed}))
+
+		query := models.SearchTempUsersQuery{OrgID: 1, Page: 2, Limit: 1}
+		require.NoError(t, s.SearchTempUsers(ctx, &query))
+		require.Equal(t, int64(2), query.Result.TotalCount)
+		require.Len(t, query.Result.Invites, 1)
+	})
+
+	t.Run("updates invites of the organization by ID", func(t *testing.T) {
+		query := models.GetTempUserByIDQuery{OrgID: 1, ID: ids["alice"]}
+		require.NoError(t, s.GetTempUserByID(ctx, &query))
+		version := query.Result.Version
+
+		role := org.RoleEditor
+		cmd := models.UpdateTempUserCommand{OrgID: 1, ID: ids["alice"], Version: &version, Role: &role}
+		require.NoError(t, s.UpdateTempUser(ctx, &cmd))
+		require.Equal(t, org.RoleEditor, cmd.Result.Role)
+		require.Equal(t, "Alice", cmd.Result.Name)
+		require.Equal(t, version+1, cmd.Result.Version)
+
+		cmd = models.UpdateTempUserCommand{OrgID: 1, ID: ids["alice"], Version: &version, Role: &role}
+		require.ErrorIs(t, s.UpdateTempUser(ctx, &cmd), models.ErrTempUserVersionMismatch)
+
+		require.ErrorIs(t, s.GetTempUserByID(ctx, &models.GetTempUserByIDQuery{OrgID: 1, ID: ids["other-org"]}), models.ErrTempUserNotFound)
+	})
+
+	t.Run("status changes bump the version", func(t *testing.T) {
+		query := models.GetTempUserByIDQuery{OrgID: 1, ID: ids["bob"]}
+		require.NoError(t, s.GetTempUserByID(ctx, &query))
+		version := query.Result.Version
+
+		require.NoError(t, s.UpdateTempUserStatus(ctx, &models.UpdateTempUserStatusCommand{Code: "bob", Status: models.TmpUserRevoked}))
+		require.NoError(t, s.GetTempUserByID(ctx, &query))
+		require.Equal(t, version+1, query.Result.Version)
+
+		status := models.TmpUserRevoked
+		require.ErrorIs(t, s.UpdateTempUser(ctx, &models.UpdateTempUserCommand{OrgID: 1, ID: ids["bob"], Status: &status}), models.ErrTempUserInvalidTransition)
+	})
+
+	t.Run("email changes replace the code of pending invites", func(t *testing.T) {
+		require.NoError(t, s.UpdateTempUserWithEmailSent(ctx, &models.UpdateTempUserWithEmailSentCommand{Code: "alice"}))
+		require.NoError(t, s.MarkTempUserOpened(ctx, &models.MarkTempUserOpenedCommand{Code: "alice"}))
+
+		email, code := "alice@as.org", "alice-2"
+		cmd := models.UpdateTempUserCommand{OrgID: 1, ID: ids["alice"], Email: &email, Code: &code}
+		require.NoError(t, s.UpdateTempUser(ctx, &cmd))
+		require.Equal(t, ids["alice"], cmd.Result.Id)
+		require.Equal(t, "alice@as.org", cmd.Result.Email)
+		require.Equal(t, "alice-2", cmd.Result.Code)
+		require.False(t, cmd.Result.EmailSent)
+		require.Nil(t, cmd.Result.OpenedOn)
+		require.Equal(t, models.TmpUserInvitePending, cmd.Result.Status)
+
+		require.ErrorIs(t, s.GetTempUserByCode(ctx, &models.GetTempUserByCodeQuery{Code: "alice"}), models.ErrTempUserNotFound)
+
+		// bob's invite is revoked
+		code = "bob-2"
+		cmd = models.UpdateTempUserCommand{OrgID: 1, ID: ids["bob"], Email: &email, Code: &code}
+		require.ErrorIs(t, s.UpdateTempUser(ctx, &cmd), models.ErrTempUserInvalidTransition)
+
+		cmd = models.UpdateTempUserCommand{OrgID: 1, ID: ids["alice"], Email: &email}
+		require.Error(t, s.UpdateTempUser(ctx, &cmd))
+	})
+}
+
+func TestIntegrationEmailSuppressions(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	service := ProvideService(sqlstore.InitTestDB(t))
+
+	add := models.AddEmailSuppressionCommand{Email: " Opted.Out@Example.com", Reason: models.EmailSuppressionUnsubscribed}
+	require.NoError(t, service.AddEmailSuppression(ctx, &add))
+	require.Equal(t, "opted.out@example.com", add.Result.Email)
+
+	t.Run("emails are matched case-insensitively", func(t *testing.T) {
+		query := models.GetEmailSuppressionQuery{Email: "OPTED.OUT@example.com"}
+		require.NoError(t, service.GetEmailSuppression(ctx, &query))
+		require.Equal(t, models.EmailSuppressionUnsubscribed, query.Result.Reason)
+
+		err := service.GetEmailSuppression(ctx, &models.GetEmailSuppressionQuery{Email: "other@example.com"})
+		require.ErrorIs(t, err, models.ErrEmailSuppressionNotFound)
+	})
+
+	t.Run("suppressing an email again changes its reason", func(t *testing.T) {
+		again := models.AddEmailSuppressionCommand{Email: "opted.out@example.com", Reason: models.EmailSuppressionBounced}
+		require.NoError(t, service.AddEmailSuppression(ctx, &again))
+		require.Equal(t, add.Result.Id, again.Result.Id)
+
+		list := models.GetEmailSuppressionsQuery{}
+		require.NoError(t, service.GetEmailSuppressions(ctx, &list))
+		require.Len(t, list.Result, 1)
+		require.Equal(t, models.EmailSuppressionBounced, list.Result[0].Reason)
+	})
+
+	t.Run("rejects invalid emails and reasons", func(t *testing.T) {
+		err := service.AddEmailSuppression(ctx, &models.AddEmailSuppressionCommand{Email: "not an email", Reason: models.EmailSuppressionManual})
+		require.ErrorIs(t, err, models.ErrInvalidEmailSuppression)
+		err = service.AddEmailSuppression(ctx, &models.AddEmailSuppressionCommand{Email: "spam@example.com", Reason: "spam"})
+		require.ErrorIs(t, err, models.ErrInvalidEmailSuppression)
+	})
+
+	t.Run("removes emails", func(t *testing.T) {
+		require.NoError(t, service.RemoveEmailSuppression(ctx, &models.RemoveEmailSuppressionCommand{Email: "Opted.Out@example.com"}))
+		err := service.RemoveEmailSuppression(ctx, &models.RemoveEmailSuppressionCommand{Email: "opted.out@example.com"})
+		require.ErrorIs(t, err, models.ErrEmailSuppressionNotFound)
+	})
+}
+
+func TestIntegrationInviteDisplayCache(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	db := sqlstore.InitTestDB(t)
+	service := ProvideService(db)
+
+	inviter, err := db.CreateUser(ctx, user.CreateUserCommand{Login: "inviter", Email: "inviter@example.com", Name: "Inviter"})
+	require.NoError(t, err)
+	o, err := db.CreateOrgWithMember("Onboarding", inviter.ID)
+	require.NoError(t, err)
+	cmd := models.CreateTempUserCommand{OrgId: o.Id, Email: "invitee@example.com", Code: "code", Status: models.TmpUserInvitePending, InvitedByUserId: inviter.ID}
+	require.NoError(t, service.CreateTempUser(ctx, &cmd))
+
+	getInvite := func(t *testing.T) *models.TempUserDTO {
+		t.Helper()
+		query := models.GetTempUserByCodeQuery{Code: "code"}
+		require.NoError(t, service.GetTempUserByCode(ctx, &query))
+		return query.Result
+	}
+	rename := func(t *testing.T, table string, id int64, name string) {
+		t.Helper()
+		err := db.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+			_, err := sess.Exec("UPDATE "+db.GetDialect().Quote(table)+" SET name = ? WHERE id = ?", name, id)
+			return err
+		})
+		require.NoError(t, err)
+	}
+
+	invite := getInvite(t)
+	require.Equal(t, "Onboarding", invite.OrgName)
+	require.Equal(t, "inviter", invite.InvitedByLogin)
+	require.Equal(t, "Inviter", invite.InvitedByName)
+	require.Equal(t, "inviter@example.com", invite.InvitedByEmail)
+
+	t.Run("org names and inviters are cached", func(t *testing.T) {
+		rename(t, "org", o.Id, "Renamed")
+		rename(t, "user", inviter.ID, "Renamed inviter")
+		invite := getInvite(t)
+		require.Equal(t, "Onboarding", invite.OrgName)
+		require.Equal(t, "Inviter", invite.InvitedByName)
+	})
+
+	t.Run("renamed orgs are invalidated", func(t *testing.T) {
+		service.InvalidateInviteOrgName(o.Id)
+		require.Equal(t, "Renamed", getInvite(t).OrgName)
+	})
+}
@@ -0,0 +1,202 @@
+package tempusertest
+
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/models"
+)
+
+type FakeTempUserService struct {
+	ExpectedTempUser  *models.TempUserDTO
+	ExpectedTempUsers []*models.TempUserDTO
+	ExpectedError     error
+
+	ErasedData  []models.EraseTempUserDataCommand
+	OpenedCodes []string
+	Grants      []*models.TempUserGrant
+
+	CompletionCountries map[string]int64
+	AuditEntries        []*models.InviteAuditEntry
+	History             []*models.TempUserEventDTO
+	// Suppressions are the suppressed emails, keyed by email
+	Suppressions map[string]*models.EmailSuppression
+
+	Notifications           []*models.InviteNotificationDTO
+	NotificationPreferences *models.InviteNotificationPreferences
+}
+
+func NewFakeTempUserService() *FakeTempUserService {
+	return &FakeTempUserService{}
+}
+
+func (f *FakeTempUserService) UpdateTempUserStatus(ctx context.Context, cmd *models.UpdateTempUserStatusCommand) error {
+	return f.ExpectedError
+}
+
+func (f *FakeTempUserService) CreateTempUser(ctx context.Context, cmd *models.CreateTempUserCommand) error {
+	return f.ExpectedError
+}
+
+func (f *FakeTempUserService) UpdateTempUserWithEmailSent(ctx context.Context, cmd *models.UpdateTempUserWithEmailSentCommand) error {
+	return f.ExpectedError
+}
+
+func (f *FakeTempUserService) MarkTempUserOpened(ctx context.Context, cmd *models.MarkTempUserOpenedCommand) error {
+	f.OpenedCodes = append(f.OpenedCodes, cmd.Code)
+	return f.ExpectedError
+}
+
+func (f *FakeTempUserService) MarkTempUserEmailQueued(ctx context.Context, cmd *models.MarkTempUserEmailQueuedCommand) error {
+	return f.ExpectedError
+}
+
+func (f *FakeTempUserService) GetTempUsersQuery(ctx context.Context, query *models.GetTempUsersQuery) error {
+	query.Result = f.ExpectedTempUsers
+	return f.ExpectedError
+}
+
+func (f *FakeTempUserService) GetTempUserByCode(ctx context.Context, query *models.GetTempUserByCodeQuery) error {
+	query.Result = f.ExpectedTempUser
+	return f.ExpectedError
+}
+
+func (f *FakeTempUserService) InvalidateInviteOrgName(orgID int64) {
+}
+
+func (f *FakeTempUserService) ExpireOldUserInvites(ctx context.Context, cmd *models.ExpireTempUsersCommand) error {
+	return f.ExpectedError
+}
+
+func (f *FakeTempUserService) ArchiveTempUsers(ctx context.Context, cmd *models.ArchiveTempUsersCommand) error {
+	return f.ExpectedError
+}
+
+func (f *FakeTempUserService) GetExpiredTempUserAccess(ctx context.Context, query *models.GetExpiredTempUserAccessQuery) error {
+	return f.ExpectedError
+}
+
+func (f *FakeTempUserService) GetTempUsersForUser(ctx context.Context, query *models.GetTempUsersForUserQuery) error {
+	query.Result = f.ExpectedTempUsers
+	return f.ExpectedError
+}
+
+func (f *FakeTempUserService) EraseTempUserData(ctx context.Context, cmd *models.EraseTempUserDataCommand) error {
+	f.ErasedData = append(f.ErasedData, *cmd)
+	return f.ExpectedError
+}
+
+func (f *FakeTempUserService) GetTempUserByID(ctx context.Context, query *models.GetTempUserByIDQuery) error {
+	query.Result = f.ExpectedTempUser
+	return f.ExpectedError
+}
+
+func (f *FakeTempUserService) SearchTempUsers(ctx context.Context, query *models.SearchTempUsersQuery) error {
+	query.Result = models.SearchTempUsersQueryResult{
+		TotalCount: int64(len(f.ExpectedTempUsers)),
+		Invites:    f.ExpectedTempUsers,
+		Page:       query.Page,
+		PerPage:    query.Limit,
+	}
+	if query.CountOnly {
+		query.Result.Invites = []*models.TempUserDTO{}
+	}
+	return f.ExpectedError
+}
+
+func (f *FakeTempUserService) UpdateTempUser(ctx context.Context, cmd *models.UpdateTempUserCommand) error {
+	cmd.Result = f.ExpectedTempUser
+	return f.ExpectedError
+}
+
+func (f *FakeTempUserService) AddTempUserGrant(ctx context.Context, cmd *models.AddTempUserGrantCommand) error {
+	f.Grants = append(f.Grants, &models.TempUserGrant{
+		OrgId:        cmd.OrgID,
+		TempUserId:   cmd.TempUserID,
+		ResourceKind: cmd.ResourceKind,
+		ResourceUid:  cmd.ResourceUID,
+		Permission:   cmd.Permission,
+	})
+	return f.ExpectedError
+}
+
+func (f *FakeTempUserService) GetTempUserGrants(ctx context.Context, query *models.GetTempUserGrantsQuery) error {
+	query.Result = f.Grants
+	return f.ExpectedError
+}
+
+func (f *FakeTempUserService) GetInviteCompletionCountries(ctx context.Context, query *models.GetInviteCompletionCountriesQuery) error {
+	query.Result = f.CompletionCountries
+	return f.ExpectedError
+}
+
+func (f *FakeTempUserService) GetInviteAudit(ctx context.Context, query *models.GetInviteAuditQuery) error {
+	query.Result = make([]*models.InviteAuditEntry, 0, len(f.AuditEntries))
+	for _, entry := range f.AuditEntries {
+		if query.InvitedByUserID == 0 || entry.ApproverUserID == query.InvitedByUserID {
+			query.Result = append(query.Result, entry)
+		}
+	}
+	return f.ExpectedError
+}
+
+func (f *FakeTempUserService) GetTempUserHistory(ctx context.Context, query *models.GetTempUserHistoryQuery) error {
+	query.Result = f.History
+	return f.ExpectedError
+}
+
+func (f *FakeTempUserService) AddEmailSuppression(ctx context.Context, cmd *models.AddEmailSuppressionCommand) error {
+	if f.Suppressions == nil {
+		f.Suppressions = map[string]*models.EmailSuppression{}
+	}
+	cmd.Result = &models.EmailSuppression{Email: cmd.Email, Reason: cmd.Reason}
+	f.Suppressions[cmd.Email] = cmd.Result
+	return f.ExpectedError
+}
+
+func (f *FakeTempUserService) RemoveEmailSuppression(ctx context.Context, cmd *models.RemoveEmailSuppressionCommand) error {
+	if _, ok := f.Suppressions[cmd.Email]; !ok {
+		return models.ErrEmailSuppressionNotFound
+	}
+	delete(f.Suppressions, cmd.Email)
+	return f.ExpectedError
+}
+
+func (f *FakeTempUserService) GetEmailSuppressions(ctx context.Context, query *models.GetEmailSuppressionsQuery) error {
+	query.Result = make([]*models.EmailSuppression, 0, len(f.Suppressions))
+	for _, suppression := range f.Suppressions {
+		query.Result = append(query.Result, suppression)
+	}
+	return f.ExpectedError
+}
+
+func (f *FakeTempUserService) GetEmailSuppression(ctx context.Context, query *models.GetEmailSuppressionQuery) error {
+	suppression, ok := f.Suppressions[query.Email]
+	if !ok {
+		return models.ErrEmailSuppressionNotFound
+	}
+	query.Result = suppression
+	return f.ExpectedError
+}
+
+func (f *FakeTempUserService) AddExpiringInviteNotifications(ctx context.Context, cmd *models.AddExpiringInviteNotificationsCommand) error {
+	return f.ExpectedError
+}
+
+func (f *FakeTempUserService) GetInviteNotifications(ctx context.Context, query *models.GetInviteNotificationsQuery) error {
+	query.Result = f.Notifications
+	return f.ExpectedError
+}
+
+func (f *FakeTempUserService) MarkInviteNotificationsRead(ctx context.Context, cmd *models.MarkInviteNotificationsReadCommand) error {
+	return f.ExpectedError
+}
+
+func (f *FakeTempUserService) GetInviteNotificationPreferences(ctx context.Context, query *models.GetInviteNotificationPreferencesQuery) error {
+	query.Result = f.NotificationPreferences
+	return f.ExpectedError
+}
+
+func (f *FakeTempUserService) SetInviteNotificationPreferences(ctx context.Context, cmd *models.SetInviteNotificationPreferencesCommand) error {
+	f.NotificationPreferences = &cmd.Preferences
+	return f.ExpectedError
+}
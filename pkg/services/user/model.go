@@ -47,6 +47,8 @@ type User struct {
 	IsAdmin          bool
 	IsServiceAccount bool
 	OrgID            int64 `xorm:"org_id"`
+	// ExternalID identifies the user in another system, e.g. the employee number in an HR system
+	ExternalID string `xorm:"external_id"`
 
 	Created    time.Time
 	Updated    time.Time
@@ -102,6 +104,15 @@ type SetUsingOrgCommand struct {
 	OrgID  int64
 }
 
+// SetExternalIDCommand sets the external ID of a user which has none, see User.ExternalID.
+// Updated tells whether the user has the external ID, it is false when the user keeps another one.
+type SetExternalIDCommand struct {
+	UserID     int64
+	ExternalID string
+
+	Updated bool
+}
+
 type SearchUsersQuery struct {
 	SignedInUser *SignedInUser
 	OrgID        int64
@@ -133,6 +144,7 @@ type UserSearchHitDTO struct {
 	LastSeenAtAge string               `json:"lastSeenAtAge"`
 	AuthLabels    []string             `json:"authLabels"`
 	AuthModule    AuthModuleConversion `json:"-"`
+	ExternalID    string               `json:"externalId,omitempty"`
 }
 
 type GetUserProfileQuery struct {
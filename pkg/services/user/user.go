@@ -14,6 +14,7 @@ type Service interface {
 	ChangePassword(context.Context, *ChangeUserPasswordCommand) error
 	UpdateLastSeenAt(context.Context, *UpdateUserLastSeenAtCommand) error
 	SetUsingOrg(context.Context, *SetUsingOrgCommand) error
+	SetExternalID(context.Context, *SetExternalIDCommand) error
 	GetSignedInUserWithCacheCtx(context.Context, *GetSignedInUserQuery) (*SignedInUser, error)
 	GetSignedInUser(context.Context, *GetSignedInUserQuery) (*SignedInUser, error)
 	Search(context.Context, *SearchUsersQuery) (*SearchUserQueryResult, error)
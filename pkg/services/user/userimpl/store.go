@@ -3,6 +3,7 @@ package userimpl
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/grafana/grafana/pkg/events"
 	"github.com/grafana/grafana/pkg/services/sqlstore"
@@ -18,6 +19,7 @@ type store interface {
 	GetNotServiceAccount(context.Context, int64) (*user.User, error)
 	Delete(context.Context, int64) error
 	CaseInsensitiveLoginConflict(context.Context, string, string) error
+	SetExternalID(context.Context, *user.SetExternalIDCommand) error
 }
 
 type sqlStore struct {
@@ -132,3 +134,19 @@ func (ss *sqlStore) CaseInsensitiveLoginConflict(ctx context.Context, login, ema
 	})
 	return err
 }
+
+func (ss *sqlStore) SetExternalID(ctx context.Context, cmd *user.SetExternalIDCommand) error {
+	return ss.db.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		result, err := sess.Exec("UPDATE "+ss.dialect.Quote("user")+" SET external_id = ?, updated = ? WHERE id = ? AND (external_id IS NULL OR external_id = ? OR external_id = ?)",
+			cmd.ExternalID, time.Now(), cmd.UserID, "", cmd.ExternalID)
+		if err != nil {
+			return err
+		}
+		updated, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		cmd.Updated = updated > 0
+		return nil
+	})
+}
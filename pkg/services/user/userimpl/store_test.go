@@ -5,6 +5,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/searchusers/filters"
 	"github.com/grafana/grafana/pkg/services/sqlstore"
 	"github.com/grafana/grafana/pkg/services/user"
 
@@ -54,3 +56,47 @@ func TestIntegrationUserDataAccess(t *testing.T) {
 		require.NoError(t, err)
 	})
 }
+
+func TestIntegrationUserExternalID(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+	ss := sqlstore.InitTestDB(t)
+	userStore := sqlStore{db: ss, dialect: ss.GetDialect()}
+
+	var ids []int64
+	for _, login := range []string{"alice", "bob"} {
+		id, err := userStore.Insert(ctx, &user.User{Email: login + "@example.com", Login: login, Created: time.Now(), Updated: time.Now()})
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	t.Run("sets the external ID of users without one", func(t *testing.T) {
+		cmd := user.SetExternalIDCommand{UserID: ids[0], ExternalID: "E-1001"}
+		require.NoError(t, userStore.SetExternalID(ctx, &cmd))
+		require.True(t, cmd.Updated)
+
+		// setting the same ID again is a no-op rather than a conflict
+		require.NoError(t, userStore.SetExternalID(ctx, &cmd))
+		require.True(t, cmd.Updated)
+	})
+
+	t.Run("doesn't replace another external ID", func(t *testing.T) {
+		cmd := user.SetExternalIDCommand{UserID: ids[0], ExternalID: "E-2002"}
+		require.NoError(t, userStore.SetExternalID(ctx, &cmd))
+		require.False(t, cmd.Updated)
+	})
+
+	t.Run("users are searchable by external ID", func(t *testing.T) {
+		filter, err := filters.NewExternalIDFilter([]string{"E-1001", "E-3003"})
+		require.NoError(t, err)
+		query := models.SearchUsersQuery{Filters: []user.Filter{filter}, SignedInUser: &user.SignedInUser{OrgID: 1, Permissions: map[int64]map[string][]string{1: {"users:read": {"global.users:*"}}}}}
+		require.NoError(t, ss.SearchUsers(ctx, &query))
+		require.Len(t, query.Result.Users, 1)
+		require.Equal(t, "alice", query.Result.Users[0].Login)
+		require.Equal(t, "E-1001", query.Result.Users[0].ExternalId)
+		require.EqualValues(t, 1, query.Result.TotalCount)
+	})
+}
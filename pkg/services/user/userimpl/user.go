@@ -15,6 +15,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/sqlstore/db"
 	"github.com/grafana/grafana/pkg/services/star"
 	"github.com/grafana/grafana/pkg/services/teamguardian"
+	tempuser "github.com/grafana/grafana/pkg/services/temp_user"
 	"github.com/grafana/grafana/pkg/services/user"
 	"github.com/grafana/grafana/pkg/services/userauth"
 	"github.com/grafana/grafana/pkg/setting"
@@ -33,6 +34,7 @@ type Service struct {
 	userAuthService    userauth.Service
 	quotaService       quota.Service
 	accessControlStore accesscontrol.Service
+	tempUserService    tempuser.Service
 	// TODO remove sqlstore
 	sqlStore *sqlstore.SQLStore
 
@@ -49,6 +51,7 @@ func ProvideService(
 	userAuthService userauth.Service,
 	quotaService quota.Service,
 	accessControlStore accesscontrol.Service,
+	tempUserService tempuser.Service,
 	cfg *setting.Cfg,
 	ss *sqlstore.SQLStore,
 ) user.Service {
@@ -65,6 +68,7 @@ func ProvideService(
 		userAuthService:    userAuthService,
 		quotaService:       quotaService,
 		accessControlStore: accessControlStore,
+		tempUserService:    tempUserService,
 		cfg:                cfg,
 		sqlStore:           ss,
 	}
@@ -164,7 +168,7 @@ func (s *Service) Create(ctx context.Context, cmd *user.CreateUserCommand) (*use
 }
 
 func (s *Service) Delete(ctx context.Context, cmd *user.DeleteUserCommand) error {
-	_, err := s.store.GetNotServiceAccount(ctx, cmd.UserID)
+	usr, err := s.store.GetNotServiceAccount(ctx, cmd.UserID)
 	if err != nil {
 		return err
 	}
@@ -228,6 +232,13 @@ func (s *Service) Delete(ctx context.Context, cmd *user.DeleteUserCommand) error
 		}
 		return nil
 	})
+	g.Go(func() error {
+		// invites are kept for auditing, without the personal data of the user
+		if err := s.tempUserService.EraseTempUserData(ctx, &models.EraseTempUserDataCommand{UserID: cmd.UserID, Email: usr.Email}); err != nil {
+			return err
+		}
+		return nil
+	})
 	if err := g.Wait(); err != nil {
 		return err
 	}
@@ -307,6 +318,10 @@ func (s *Service) SetUsingOrg(ctx context.Context, cmd *user.SetUsingOrgCommand)
 	return s.sqlStore.SetUsingOrg(ctx, q)
 }
 
+func (s *Service) SetExternalID(ctx context.Context, cmd *user.SetExternalIDCommand) error {
+	return s.store.SetExternalID(ctx, cmd)
+}
+
 // TODO: remove wrapper around sqlstore
 func (s *Service) GetSignedInUserWithCacheCtx(ctx context.Context, query *user.GetSignedInUserQuery) (*user.SignedInUser, error) {
 	q := &models.GetSignedInUserQuery{
@@ -367,6 +382,7 @@ func (s *Service) Search(ctx context.Context, query *user.SearchUsersQuery) (*us
 			LastSeenAtAge: usrSearch.LastSeenAtAge,
 			AuthLabels:    usrSearch.AuthLabels,
 			AuthModule:    user.AuthModuleConversion(usrSearch.AuthModule),
+			ExternalID:    usrSearch.ExternalId,
 		})
 	}
 
@@ -12,6 +12,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/quota/quotatest"
 	"github.com/grafana/grafana/pkg/services/star/startest"
 	"github.com/grafana/grafana/pkg/services/teamguardian/manager"
+	"github.com/grafana/grafana/pkg/services/temp_user/tempusertest"
 	"github.com/grafana/grafana/pkg/services/user"
 	"github.com/grafana/grafana/pkg/services/userauth/userauthtest"
 	"github.com/grafana/grafana/pkg/setting"
@@ -29,6 +30,7 @@ func TestUserService(t *testing.T) {
 	userAuthService := userauthtest.NewFakeUserAuthService()
 	quotaService := quotatest.NewQuotaServiceFake()
 	accessControlStore := mock.New()
+	tempUserService := tempusertest.NewFakeTempUserService()
 	userService := Service{
 		store:              userStore,
 		orgService:         orgService,
@@ -39,6 +41,7 @@ func TestUserService(t *testing.T) {
 		userAuthService:    userAuthService,
 		quotaService:       quotaService,
 		accessControlStore: accessControlStore,
+		tempUserService:    tempUserService,
 	}
 
 	t.Run("create user", func(t *testing.T) {
@@ -139,6 +142,16 @@ func TestUserService(t *testing.T) {
 		err := userService.Delete(context.Background(), &user.DeleteUserCommand{UserID: 1})
 		require.NoError(t, err)
 	})
+
+	t.Run("delete user erases invite data", func(t *testing.T) {
+		userStore.ExpectedUser = &user.User{ID: 1, Email: "email", Login: "login", Name: "name"}
+		tempUserService.ErasedData = nil
+		err := userService.Delete(context.Background(), &user.DeleteUserCommand{UserID: 1})
+		require.NoError(t, err)
+		require.Len(t, tempUserService.ErasedData, 1)
+		require.Equal(t, int64(1), tempUserService.ErasedData[0].UserID)
+		require.Equal(t, "email", tempUserService.ErasedData[0].Email)
+	})
 }
 
 type FakeUserStore struct {
@@ -174,3 +187,7 @@ func (f *FakeUserStore) GetByID(context.Context, int64) (*user.User, error) {
 func (f *FakeUserStore) CaseInsensitiveLoginConflict(context.Context, string, string) error {
 	return f.ExpectedError
 }
+
+func (f *FakeUserStore) SetExternalID(context.Context, *user.SetExternalIDCommand) error {
+	return f.ExpectedError
+}
@@ -55,6 +55,10 @@ func (f *FakeUserService) SetUsingOrg(ctx context.Context, cmd *user.SetUsingOrg
 	return f.ExpectedSetUsingOrgError
 }
 
+func (f *FakeUserService) SetExternalID(ctx context.Context, cmd *user.SetExternalIDCommand) error {
+	return f.ExpectedError
+}
+
 func (f *FakeUserService) GetSignedInUserWithCacheCtx(ctx context.Context, query *user.GetSignedInUserQuery) (*user.SignedInUser, error) {
 	return f.GetSignedInUser(ctx, query)
 }
@@ -369,8 +369,37 @@ type Cfg struct {
 
 	// User
 	UserInviteMaxLifetime time.Duration
-	HiddenUsers           map[string]struct{}
-	CaseInsensitiveLogin  bool // Login and Email will be considered case insensitive
+	// Organizations in which opening invite links is not recorded
+	InviteTrackingDisabledOrgs map[int64]struct{}
+	// Maximum time invite requests may take, including sending the invite emails, 0 for no limit
+	InviteHandlerTimeout time.Duration
+	// Maximum number of days viewer tokens can give access to the organization for
+	ViewerTokenMaxDays int
+	// Request header holding the country of the client, set by a proxy or CDN, recorded when invites
+	// are completed
+	InviteCountryHeader string
+	// Reject invites to emails which already have a pending invite to the organization
+	UniquePendingInvites bool
+	// Closed invites are archived once they haven't been updated for this long, 0 to keep them listed
+	InviteArchiveAfter time.Duration
+	// Pending invites are notified as expiring this long before they expire, 0 to not notify them
+	InviteExpiryNotice   time.Duration
+	HiddenUsers          map[string]struct{}
+	CaseInsensitiveLogin bool // Login and Email will be considered case insensitive
+
+	// Invite rate limits, the maximum number of invites an organization and an inviter can create
+	// per window, 0 for no limit
+	InviteRateLimitPerOrg     int
+	InviteRateLimitPerInviter int
+	InviteRateLimitWindow     time.Duration
+
+	// Invite policy, overriding how the login and sign up settings apply to invites
+	InviteWithLoginFormDisabled bool // new users can be invited although the login form is disabled
+	InviteRequiresSignUp        bool // new users can only be invited when sign up is allowed
+	InviteAllowUnverifiedEmail  bool // invites can be completed with other emails than the invited one when email verification is enabled
+	// Maximum roles of invites handed over as join links and of emailed invites, no maximum when empty
+	InviteLinkMaxRole  string
+	InviteEmailMaxRole string
 
 	// Annotations
 	AnnotationCleanupJobBatchSize      int64
@@ -457,6 +486,15 @@ type Cfg struct {
 
 	Search SearchSettings
 
+	// CAPTCHA verification of unauthenticated sign up and invite flows
+	Captcha CaptchaSettings
+
+	// Slack slash command creating org invites
+	SlackInvites SlackInvitesSettings
+
+	// Fault injection in the invite paths, for development and staging
+	InviteTesting InviteTestingSettings
+
 	// Access Control
 	RBACEnabled         bool
 	RBACPermissionCache bool
@@ -1030,7 +1068,13 @@ func (cfg *Cfg) Load(args CommandLineArgs) error {
 
 	cfg.DashboardPreviews = readDashboardPreviewsSettings(iniFile)
 	cfg.Storage = readStorageSettings(iniFile)
-	cfg.Search = readSearchSettings(iniFile)
+	cfg.Search = readSearchSettings(iniFile, cfg.DataPath)
+	cfg.Captcha = readCaptchaSettings(iniFile)
+	cfg.SlackInvites = readSlackInvitesSettings(iniFile)
+	cfg.InviteTesting, err = readInviteTestingSettings(iniFile, cfg.Env)
+	if err != nil {
+		return err
+	}
 
 	if VerifyEmailEnabled && !cfg.Smtp.Enabled {
 		cfg.Logger.Warn("require_email_validation is enabled but smtp is disabled")
@@ -1413,6 +1457,50 @@ func readUserSettings(iniFile *ini.File, cfg *Cfg) error {
 		return errors.New("the minimum supported value for the `user_invite_max_lifetime_duration` configuration is 15m (15 minutes)")
 	}
 
+	cfg.InviteTrackingDisabledOrgs = make(map[int64]struct{})
+	for _, org := range util.SplitString(valueAsString(users, "invite_tracking_disabled_orgs", "")) {
+		orgID, err := strconv.ParseInt(org, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid org ID %q in invite_tracking_disabled_orgs: %w", org, err)
+		}
+		cfg.InviteTrackingDisabledOrgs[orgID] = struct{}{}
+	}
+
+	cfg.InviteHandlerTimeout, err = gtime.ParseDuration(valueAsString(users, "invite_handler_timeout", "30s"))
+	if err != nil {
+		return fmt.Errorf("invalid invite_handler_timeout: %w", err)
+	}
+	if cfg.InviteHandlerTimeout < 0 {
+		return errors.New("invite_handler_timeout can't be negative")
+	}
+
+	cfg.UniquePendingInvites = users.Key("unique_pending_invites").MustBool(false)
+	cfg.InviteArchiveAfter, err = gtime.ParseDuration(valueAsString(users, "invite_archive_after", "30d"))
+	if err != nil {
+		return fmt.Errorf("invalid invite_archive_after: %w", err)
+	}
+	cfg.InviteExpiryNotice, err = gtime.ParseDuration(valueAsString(users, "invite_expiry_notice", "6h"))
+	if err != nil {
+		return fmt.Errorf("invalid invite_expiry_notice: %w", err)
+	}
+	cfg.InviteRateLimitPerOrg = users.Key("invite_rate_limit_per_org").MustInt(0)
+	cfg.InviteRateLimitPerInviter = users.Key("invite_rate_limit_per_inviter").MustInt(0)
+	cfg.InviteRateLimitWindow, err = gtime.ParseDuration(valueAsString(users, "invite_rate_limit_window", "1h"))
+	if err != nil {
+		return fmt.Errorf("invalid invite_rate_limit_window: %w", err)
+	}
+	if cfg.InviteRateLimitWindow < time.Second {
+		return errors.New("invite_rate_limit_window must be at least 1s")
+	}
+	cfg.ViewerTokenMaxDays = users.Key("viewer_token_max_days").MustInt(30)
+	cfg.InviteCountryHeader = valueAsString(users, "invite_country_header", "")
+
+	cfg.InviteWithLoginFormDisabled = users.Key("invite_with_login_form_disabled").MustBool(false)
+	cfg.InviteRequiresSignUp = users.Key("invite_requires_sign_up").MustBool(false)
+	cfg.InviteAllowUnverifiedEmail = users.Key("invite_allow_unverified_email").MustBool(false)
+	cfg.InviteLinkMaxRole = users.Key("invite_link_max_role").In("", []string{"Viewer", "Editor", "Admin"})
+	cfg.InviteEmailMaxRole = users.Key("invite_email_max_role").In("", []string{"Viewer", "Editor", "Admin"})
+
 	cfg.HiddenUsers = make(map[string]struct{})
 	hiddenUsers := users.Key("hidden_users").MustString("")
 	for _, user := range strings.Split(hiddenUsers, ",") {
@@ -0,0 +1,34 @@
+package setting
+
+import (
+	"gopkg.in/ini.v1"
+)
+
+const (
+	CaptchaProviderReCAPTCHA = "recaptcha"
+	CaptchaProviderHCaptcha  = "hcaptcha"
+)
+
+type CaptchaSettings struct {
+	Enabled   bool
+	Provider  string
+	SiteKey   string
+	SecretKey string
+	// VerifyURL overrides the verification endpoint of the provider.
+	VerifyURL string
+	// MinScore is the minimal score accepted for providers returning one, such as reCAPTCHA v3.
+	MinScore float64
+}
+
+func readCaptchaSettings(iniFile *ini.File) CaptchaSettings {
+	s := CaptchaSettings{}
+
+	captchaSection := iniFile.Section("captcha")
+	s.Enabled = captchaSection.Key("enabled").MustBool(false)
+	s.Provider = captchaSection.Key("provider").In(CaptchaProviderReCAPTCHA, []string{CaptchaProviderReCAPTCHA, CaptchaProviderHCaptcha})
+	s.SiteKey = captchaSection.Key("site_key").MustString("")
+	s.SecretKey = captchaSection.Key("secret_key").MustString("")
+	s.VerifyURL = captchaSection.Key("verify_url").MustString("")
+	s.MinScore = captchaSection.Key("min_score").MustFloat64(0)
+	return s
+}
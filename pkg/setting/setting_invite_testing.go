@@ -0,0 +1,46 @@
+package setting
+
+import (
+	"errors"
+	"time"
+
+	"gopkg.in/ini.v1"
+)
+
+// InviteTestingSettings inject faults in the invite paths, so that end-to-end and load tests can
+// exercise how the onboarding funnel copes with them. Meant for development and staging only.
+type InviteTestingSettings struct {
+	Enabled bool
+	// SMTPFailureRate is the share of invite emails failing as if the SMTP server was down, from 0 to 1.
+	SMTPFailureRate float64
+	// DBLatency is added to the database calls of the invite paths.
+	DBLatency time.Duration
+	// DuplicateSubmissionRate is the share of invite creations and completions submitted twice, as
+	// when users double click, from 0 to 1.
+	DuplicateSubmissionRate float64
+	// DeterministicCodes makes invite codes a sequence tests can predict instead of random strings.
+	DeterministicCodes bool
+	// Seed of the fault injection, so that test runs inject the same faults. 0 picks a random seed.
+	Seed int64
+}
+
+func readInviteTestingSettings(iniFile *ini.File, env string) (InviteTestingSettings, error) {
+	s := InviteTestingSettings{}
+
+	section := iniFile.Section("invite_testing")
+	s.Enabled = section.Key("enabled").MustBool(false)
+	s.SMTPFailureRate = section.Key("smtp_failure_rate").MustFloat64(0)
+	s.DBLatency = section.Key("db_latency").MustDuration(0)
+	s.DuplicateSubmissionRate = section.Key("duplicate_submission_rate").MustFloat64(0)
+	s.DeterministicCodes = section.Key("deterministic_codes").MustBool(false)
+	s.Seed = section.Key("seed").MustInt64(0)
+
+	if s.SMTPFailureRate < 0 || s.SMTPFailureRate > 1 || s.DuplicateSubmissionRate < 0 || s.DuplicateSubmissionRate > 1 {
+		return s, errors.New("invite_testing failure rates must be between 0 and 1")
+	}
+	if s.Enabled && s.DeterministicCodes && env == Prod {
+		// predictable codes let anyone complete the invites of others
+		return s, errors.New("invite_testing deterministic_codes can't be enabled when app_mode is production")
+	}
+	return s, nil
+}
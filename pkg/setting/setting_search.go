@@ -1,23 +1,231 @@
 package setting
 
 import (
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/ini.v1"
+
+	"github.com/grafana/grafana/pkg/util"
 )
 
 type SearchSettings struct {
 	FullReindexInterval       time.Duration
 	IndexUpdateInterval       time.Duration
 	DashboardLoadingBatchSize int
+	// Organizations with at least LargeOrgDashboardThreshold dashboards are fully
+	// re-indexed every LargeOrgFullReindexInterval instead of FullReindexInterval.
+	// They still receive incremental updates every IndexUpdateInterval.
+	LargeOrgDashboardThreshold  int
+	LargeOrgFullReindexInterval time.Duration
+	IndexPath                   string
+	IndexEncryptionEnabled      bool
+	// IndexCheckpointInterval is how often the progress of index builds is saved to IndexPath, so
+	// that builds interrupted by a crash resume from the last checkpoint. 0 disables checkpoints.
+	IndexCheckpointInterval time.Duration
+	// Limits of search queries executed at once, 0 means unlimited. Queries over the limits are
+	// queued up to MaxQueuedQueries for at most QueryQueueTimeout, and rejected otherwise.
+	MaxConcurrentQueries       int
+	MaxConcurrentQueriesPerOrg int
+	MaxQueuedQueries           int
+	QueryQueueTimeout          time.Duration
+	// Identical queries of users who can read the same dashboards received while the first one is
+	// executed wait for it and share its response, up to MaxCoalescedQueries for each query. 0
+	// disables it.
+	MaxCoalescedQueries int
+	// IndexMode is "full" or "sparse". Sparse indexes only hold the titles, tags, folders and UIDs
+	// of dashboards, organizations with at least SparseIndexDashboardThreshold dashboards get one
+	// as well when the threshold is set.
+	IndexMode                     string
+	SparseIndexDashboardThreshold int
+	// Organizations with at least DiskIndexDashboardThreshold dashboards get an index on disk in
+	// DiskIndexPath instead of memory. Its files are memory-mapped, so that only the parts in use
+	// are kept in memory. 0 keeps all indexes in memory.
+	DiskIndexDashboardThreshold int
+	DiskIndexPath               string
+	// DeniedCacheTTL is how long the dashboards and folders a user can read or was denied access to
+	// are remembered, to avoid evaluating the same permissions again. It bounds how long changes of
+	// dashboard, folder, team and role permissions take to apply to search. 0 disables the cache.
+	DeniedCacheTTL time.Duration
+	// AllowedQueryExperiments are the experiments search queries may enable, so that new search
+	// behaviors can be rolled out to a part of the users without a restart.
+	AllowedQueryExperiments []string
+	// FederationEnabled lets queries search FederatedInstances together with this instance.
+	// Results are labeled with FederationLocalName or the name of the instance they come from.
+	FederationEnabled   bool
+	FederationLocalName string
+	FederationTimeout   time.Duration
+	FederatedInstances  []SearchFederatedInstance
+	// With the searchSemantic feature toggle, dashboards whose embedding has at least a cosine
+	// similarity of SemanticMinSimilarity with the embedding of the query match it, and up to
+	// SemanticWeight is added to their score in proportion to the similarity.
+	SemanticWeight        float64
+	SemanticMinSimilarity float64
+	// With the searchPersonalization feature toggle, the dashboards users recently opened from
+	// search and the folders they open most are ranked higher in their results, by up to
+	// PersonalizationWeight. Signals are kept in memory for PersonalizationTTL, for at most
+	// PersonalizationMaxUsers users, the least recently active users are forgotten first.
+	PersonalizationWeight   float64
+	PersonalizationTTL      time.Duration
+	PersonalizationMaxUsers int
+	// WarmQueryInterval is how often the warm queries of the organizations are run besides after
+	// each build of their index, 0 only runs them after builds.
+	WarmQueryInterval time.Duration
+	// IndexVerifyInterval is how often the checksums of the indexes in memory are verified, so that
+	// corrupted indexes are rebuilt, 0 disables the verification.
+	IndexVerifyInterval time.Duration
+	// MaxDashboardSize is the size in bytes over which dashboards are only indexed with their slug
+	// as title, and reported in the index errors of their organization. 0 means unlimited.
+	MaxDashboardSize int
+	// RankProfiles are the rank profiles queries select by name, so that each client surface gets
+	// ranking tuned server-side. Built-in profiles can be overridden in the configuration.
+	RankProfiles map[string]SearchRankProfile
+	// IndexTarget is the part of the indexing this instance runs, so that indexes can be maintained by
+	// a dedicated instance: index builders build the indexes and publish them to IndexPath, query
+	// instances load them from IndexPath instead of building them. Both need a shared IndexPath.
+	IndexTarget string
+}
+
+// Index targets, see SearchSettings.IndexTarget.
+const (
+	SearchIndexTargetAll     = "all"
+	SearchIndexTargetBuilder = "index-builder"
+	SearchIndexTargetQuery   = "query"
+)
+
+// SearchRankProfile tunes the queries selecting it. The boosts and limit apply to the queries which
+// don't set their own, MaxLimit caps the limit of all of them. Zero values leave queries unchanged.
+type SearchRankProfile struct {
+	QualityBoost float64
+	// GovernanceBoost is the boost of each governance label, e.g. certified
+	GovernanceBoost map[string]float64
+	Limit           int
+	MaxLimit        int
+}
+
+// Built-in rank profiles, api-default applies to the queries which don't select a profile.
+const (
+	SearchRankProfileCommandPalette = "command-palette"
+	SearchRankProfileBrowsePage     = "browse-page"
+	SearchRankProfileAPIDefault     = "api-default"
+)
+
+func defaultSearchRankProfiles() map[string]SearchRankProfile {
+	return map[string]SearchRankProfile{
+		SearchRankProfileCommandPalette: {QualityBoost: 2, GovernanceBoost: map[string]float64{"certified": 2}, Limit: 20, MaxLimit: 50},
+		SearchRankProfileBrowsePage:     {Limit: 50, MaxLimit: 500},
+		SearchRankProfileAPIDefault:     {},
+	}
 }
 
-func readSearchSettings(iniFile *ini.File) SearchSettings {
+// SearchFederatedInstance is a remote Grafana instance searched by federated queries of the users
+// of an organization. It is queried with a service account token of one of its organizations, so
+// its results are the dashboards and folders the service account can read.
+type SearchFederatedInstance struct {
+	Name  string
+	URL   string
+	Token string
+	// OrgID is the organization of this instance whose users search the remote instance
+	OrgID int64
+}
+
+func readSearchSettings(iniFile *ini.File, dataPath string) SearchSettings {
 	s := SearchSettings{}
 
 	searchSection := iniFile.Section("search")
 	s.DashboardLoadingBatchSize = searchSection.Key("dashboard_loading_batch_size").MustInt(200)
 	s.FullReindexInterval = searchSection.Key("full_reindex_interval").MustDuration(5 * time.Minute)
 	s.IndexUpdateInterval = searchSection.Key("index_update_interval").MustDuration(10 * time.Second)
+	s.LargeOrgDashboardThreshold = searchSection.Key("large_org_dashboard_threshold").MustInt(10000)
+	s.LargeOrgFullReindexInterval = searchSection.Key("large_org_full_reindex_interval").MustDuration(24 * time.Hour)
+	s.IndexPath = searchSection.Key("index_path").MustString("")
+	s.IndexEncryptionEnabled = searchSection.Key("index_encryption_enabled").MustBool(true)
+	s.IndexCheckpointInterval = searchSection.Key("index_checkpoint_interval").MustDuration(30 * time.Second)
+	s.MaxConcurrentQueries = searchSection.Key("max_concurrent_queries").MustInt(0)
+	s.MaxConcurrentQueriesPerOrg = searchSection.Key("max_concurrent_queries_per_org").MustInt(0)
+	s.MaxQueuedQueries = searchSection.Key("max_queued_queries").MustInt(100)
+	s.QueryQueueTimeout = searchSection.Key("query_queue_timeout").MustDuration(10 * time.Second)
+	s.MaxCoalescedQueries = searchSection.Key("max_coalesced_queries").MustInt(100)
+	s.IndexMode = searchSection.Key("index_mode").In("full", []string{"full", "sparse"})
+	s.SparseIndexDashboardThreshold = searchSection.Key("sparse_index_dashboard_threshold").MustInt(0)
+	s.DiskIndexDashboardThreshold = searchSection.Key("disk_index_dashboard_threshold").MustInt(0)
+	s.DiskIndexPath = searchSection.Key("disk_index_path").MustString(filepath.Join(dataPath, "search", "disk"))
+	s.DeniedCacheTTL = searchSection.Key("denied_cache_ttl").MustDuration(10 * time.Second)
+	s.AllowedQueryExperiments = util.SplitString(searchSection.Key("allowed_query_experiments").MustString(""))
+	s.FederationEnabled = searchSection.Key("federation_enabled").MustBool(false)
+	s.FederationLocalName = searchSection.Key("federation_local_name").MustString("local")
+	s.FederationTimeout = searchSection.Key("federation_timeout").MustDuration(5 * time.Second)
+	s.FederatedInstances = readSearchFederatedInstances(iniFile.Sections())
+	s.SemanticWeight = searchSection.Key("semantic_weight").MustFloat64(3)
+	s.SemanticMinSimilarity = searchSection.Key("semantic_min_similarity").MustFloat64(0.5)
+	s.PersonalizationWeight = searchSection.Key("personalization_weight").MustFloat64(2)
+	s.PersonalizationTTL = searchSection.Key("personalization_ttl").MustDuration(30 * 24 * time.Hour)
+	s.PersonalizationMaxUsers = searchSection.Key("personalization_max_users").MustInt(10000)
+	s.WarmQueryInterval = searchSection.Key("warm_query_interval").MustDuration(10 * time.Minute)
+	s.IndexVerifyInterval = searchSection.Key("index_verify_interval").MustDuration(10 * time.Minute)
+	s.MaxDashboardSize = searchSection.Key("max_dashboard_size").MustInt(0)
+	s.RankProfiles = readSearchRankProfiles(iniFile.Sections())
+	s.IndexTarget = searchSection.Key("index_target").In(SearchIndexTargetAll, []string{SearchIndexTargetAll, SearchIndexTargetBuilder, SearchIndexTargetQuery})
 	return s
 }
+
+// readSearchFederatedInstances reads the [search.federation.<name>] sections.
+func readSearchFederatedInstances(sections []*ini.Section) []SearchFederatedInstance {
+	var instances []SearchFederatedInstance
+	for _, section := range sections {
+		name := strings.TrimPrefix(section.Name(), "search.federation.")
+		if name == section.Name() || name == "" {
+			continue
+		}
+		url := section.Key("url").MustString("")
+		if url == "" {
+			continue
+		}
+		instances = append(instances, SearchFederatedInstance{
+			Name:  name,
+			URL:   strings.TrimSuffix(url, "/"),
+			Token: section.Key("token").MustString(""),
+			OrgID: section.Key("org_id").MustInt64(1),
+		})
+	}
+	return instances
+}
+
+// readSearchRankProfiles reads the [search.rank_profile.<name>] sections over the built-in profiles.
+func readSearchRankProfiles(sections []*ini.Section) map[string]SearchRankProfile {
+	profiles := defaultSearchRankProfiles()
+	for _, section := range sections {
+		name := strings.TrimPrefix(section.Name(), "search.rank_profile.")
+		if name == section.Name() || name == "" {
+			continue
+		}
+		profile := profiles[name]
+		profile.QualityBoost = section.Key("quality_boost").MustFloat64(profile.QualityBoost)
+		profile.Limit = section.Key("limit").MustInt(profile.Limit)
+		profile.MaxLimit = section.Key("max_limit").MustInt(profile.MaxLimit)
+		if section.HasKey("governance_boost") {
+			profile.GovernanceBoost = readSearchGovernanceBoost(section.Key("governance_boost").String())
+		}
+		profiles[name] = profile
+	}
+	return profiles
+}
+
+// readSearchGovernanceBoost reads a list of label:boost pairs, ignoring the invalid ones.
+func readSearchGovernanceBoost(value string) map[string]float64 {
+	boosts := map[string]float64{}
+	for _, pair := range util.SplitString(value) {
+		label, boost, ok := strings.Cut(pair, ":")
+		if !ok {
+			continue
+		}
+		b, err := strconv.ParseFloat(boost, 64)
+		if err != nil {
+			continue
+		}
+		boosts[strings.TrimSpace(label)] = b
+	}
+	return boosts
+}
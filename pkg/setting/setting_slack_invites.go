@@ -0,0 +1,28 @@
+package setting
+
+import (
+	"time"
+
+	"gopkg.in/ini.v1"
+)
+
+type SlackInvitesSettings struct {
+	Enabled bool
+	// SigningSecret verifies that slash command requests are sent by Slack.
+	SigningSecret string
+	// ServiceAccountID is the service account invites are created as, its organization is the one users are invited to.
+	ServiceAccountID int64
+	// MaxRequestAge is how old the timestamp of a signed request can be, to prevent replays.
+	MaxRequestAge time.Duration
+}
+
+func readSlackInvitesSettings(iniFile *ini.File) SlackInvitesSettings {
+	s := SlackInvitesSettings{}
+
+	section := iniFile.Section("slack_invites")
+	s.Enabled = section.Key("enabled").MustBool(false)
+	s.SigningSecret = section.Key("signing_secret").MustString("")
+	s.ServiceAccountID = section.Key("service_account_id").MustInt64(0)
+	s.MaxRequestAge = section.Key("max_request_age").MustDuration(5 * time.Minute)
+	return s
+}
@@ -744,3 +744,21 @@ func TestAlertingEnabled(t *testing.T) {
 		})
 	}
 }
+
+func TestSearchRankProfileSettings(t *testing.T) {
+	f, err := ini.Load([]byte(`
+[search.rank_profile.command-palette]
+limit = 10
+governance_boost = certified:3, draft:invalid
+
+[search.rank_profile.alerting-page]
+quality_boost = 1.5
+`))
+	require.NoError(t, err)
+
+	profiles := readSearchSettings(f, "").RankProfiles
+	require.Equal(t, SearchRankProfile{QualityBoost: 2, GovernanceBoost: map[string]float64{"certified": 3}, Limit: 10, MaxLimit: 50}, profiles[SearchRankProfileCommandPalette])
+	require.Equal(t, SearchRankProfile{Limit: 50, MaxLimit: 500}, profiles[SearchRankProfileBrowsePage])
+	require.Equal(t, SearchRankProfile{QualityBoost: 1.5}, profiles["alerting-page"])
+	require.Contains(t, profiles, SearchRankProfileAPIDefault)
+}
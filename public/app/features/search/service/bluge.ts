@@ -19,6 +19,15 @@ const loadingFrameName = 'Loading';
 
 const searchURI = 'api/search-v2';
 
+// Version of the search query API the searcher is written for, responses are a list of frames:
+// the results followed by a frame per facet
+const searchAPIVersion = 2;
+
+async function postSearchQuery(req: object): Promise<DataFrame[]> {
+  const rsp = await getBackendSrv().post(searchURI, { ...req, apiVersion: searchAPIVersion });
+  return Array.isArray(rsp) ? rsp.map((frame) => toDataFrame(frame)) : [];
+}
+
 export class BlugeSearcher implements GrafanaSearcher {
   constructor(private fallbackSearcher: GrafanaSearcher) {}
 
@@ -51,13 +60,14 @@ export class BlugeSearcher implements GrafanaSearcher {
       limit: 1, // 0 would be better, but is ignored by the backend
     };
 
-    const frame = toDataFrame(await getBackendSrv().post(searchURI, req));
+    const frames = await postSearchQuery(req);
 
-    if (frame?.name === loadingFrameName) {
+    if (frames[0]?.name === loadingFrameName) {
       return this.fallbackSearcher.tags(query);
     }
 
-    if (frame.fields[0].name === 'tag') {
+    const frame = frames.find((f) => f.fields[0]?.name === 'tag');
+    if (frame) {
       return getTermCountsFrom(frame);
     }
     return [];
@@ -92,9 +102,9 @@ export class BlugeSearcher implements GrafanaSearcher {
       limit: query.limit ?? firstPageSize,
     };
 
-    const rsp = await getBackendSrv().post(searchURI, req);
+    const frames = await postSearchQuery(req);
 
-    const first = rsp ? toDataFrame(rsp) : { fields: [], length: 0 };
+    const first = frames[0] ?? { fields: [], length: 0 };
 
     if (first.name === loadingFrameName) {
       return this.fallbackSearcher.search(query);
@@ -138,13 +148,11 @@ export class BlugeSearcher implements GrafanaSearcher {
         if (from >= meta.count) {
           return;
         }
-        const frame = toDataFrame(
-          await getBackendSrv().post(searchURI, {
-            ...(req ?? {}),
-            from,
-            limit: nextPageSizes,
-          })
-        );
+        const [frame] = await postSearchQuery({
+          ...(req ?? {}),
+          from,
+          limit: nextPageSizes,
+        });
 
         if (!frame) {
           console.log('no results', frame);
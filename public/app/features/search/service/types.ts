@@ -51,6 +51,9 @@ export interface SearchResultMeta {
   max_score: number;
   locationInfo: Record<string, LocationInfo>;
   sortBy?: string;
+  apiVersion?: number;
+  /** deprecated parts of the search API used by the query */
+  warnings?: string[];
 }
 
 export interface QueryResponse {